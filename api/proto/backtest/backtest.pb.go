@@ -0,0 +1,759 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/proto/backtest/backtest.proto
+
+package backtest
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StrategyConfig mirrors strategy.Config. Decimals are strings.
+type StrategyConfig struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Interval      string                 `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	Quantity      string                 `protobuf:"bytes,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	FastPeriod    int32                  `protobuf:"varint,4,opt,name=fast_period,json=fastPeriod,proto3" json:"fast_period,omitempty"`
+	SlowPeriod    int32                  `protobuf:"varint,5,opt,name=slow_period,json=slowPeriod,proto3" json:"slow_period,omitempty"`
+	RsiPeriod     int32                  `protobuf:"varint,6,opt,name=rsi_period,json=rsiPeriod,proto3" json:"rsi_period,omitempty"`
+	Oversold      float64                `protobuf:"fixed64,7,opt,name=oversold,proto3" json:"oversold,omitempty"`
+	Overbought    float64                `protobuf:"fixed64,8,opt,name=overbought,proto3" json:"overbought,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StrategyConfig) Reset() {
+	*x = StrategyConfig{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StrategyConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StrategyConfig) ProtoMessage() {}
+
+func (x *StrategyConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StrategyConfig.ProtoReflect.Descriptor instead.
+func (*StrategyConfig) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StrategyConfig) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *StrategyConfig) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *StrategyConfig) GetQuantity() string {
+	if x != nil {
+		return x.Quantity
+	}
+	return ""
+}
+
+func (x *StrategyConfig) GetFastPeriod() int32 {
+	if x != nil {
+		return x.FastPeriod
+	}
+	return 0
+}
+
+func (x *StrategyConfig) GetSlowPeriod() int32 {
+	if x != nil {
+		return x.SlowPeriod
+	}
+	return 0
+}
+
+func (x *StrategyConfig) GetRsiPeriod() int32 {
+	if x != nil {
+		return x.RsiPeriod
+	}
+	return 0
+}
+
+func (x *StrategyConfig) GetOversold() float64 {
+	if x != nil {
+		return x.Oversold
+	}
+	return 0
+}
+
+func (x *StrategyConfig) GetOverbought() float64 {
+	if x != nil {
+		return x.Overbought
+	}
+	return 0
+}
+
+type RunBacktestRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Exchange string                 `protobuf:"bytes,1,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	Symbol   string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Config   *StrategyConfig        `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	// Signals are simulated for [from, to); warm-up candles before from are
+	// loaded as needed
+	From          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=to,proto3" json:"to,omitempty"`
+	SlippageBps   float64                `protobuf:"fixed64,6,opt,name=slippage_bps,json=slippageBps,proto3" json:"slippage_bps,omitempty"`
+	Seed          int64                  `protobuf:"varint,7,opt,name=seed,proto3" json:"seed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunBacktestRequest) Reset() {
+	*x = RunBacktestRequest{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunBacktestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunBacktestRequest) ProtoMessage() {}
+
+func (x *RunBacktestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunBacktestRequest.ProtoReflect.Descriptor instead.
+func (*RunBacktestRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RunBacktestRequest) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *RunBacktestRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *RunBacktestRequest) GetConfig() *StrategyConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *RunBacktestRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *RunBacktestRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *RunBacktestRequest) GetSlippageBps() float64 {
+	if x != nil {
+		return x.SlippageBps
+	}
+	return 0
+}
+
+func (x *RunBacktestRequest) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type BacktestEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*BacktestEvent_Progress
+	//	*BacktestEvent_Equity
+	//	*BacktestEvent_Trade
+	//	*BacktestEvent_Result
+	Event         isBacktestEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BacktestEvent) Reset() {
+	*x = BacktestEvent{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BacktestEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BacktestEvent) ProtoMessage() {}
+
+func (x *BacktestEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BacktestEvent.ProtoReflect.Descriptor instead.
+func (*BacktestEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BacktestEvent) GetEvent() isBacktestEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *BacktestEvent) GetProgress() *Progress {
+	if x != nil {
+		if x, ok := x.Event.(*BacktestEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *BacktestEvent) GetEquity() *EquityChunk {
+	if x != nil {
+		if x, ok := x.Event.(*BacktestEvent_Equity); ok {
+			return x.Equity
+		}
+	}
+	return nil
+}
+
+func (x *BacktestEvent) GetTrade() *Trade {
+	if x != nil {
+		if x, ok := x.Event.(*BacktestEvent_Trade); ok {
+			return x.Trade
+		}
+	}
+	return nil
+}
+
+func (x *BacktestEvent) GetResult() *BacktestResult {
+	if x != nil {
+		if x, ok := x.Event.(*BacktestEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isBacktestEvent_Event interface {
+	isBacktestEvent_Event()
+}
+
+type BacktestEvent_Progress struct {
+	Progress *Progress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type BacktestEvent_Equity struct {
+	Equity *EquityChunk `protobuf:"bytes,2,opt,name=equity,proto3,oneof"`
+}
+
+type BacktestEvent_Trade struct {
+	Trade *Trade `protobuf:"bytes,3,opt,name=trade,proto3,oneof"`
+}
+
+type BacktestEvent_Result struct {
+	Result *BacktestResult `protobuf:"bytes,4,opt,name=result,proto3,oneof"`
+}
+
+func (*BacktestEvent_Progress) isBacktestEvent_Event() {}
+
+func (*BacktestEvent_Equity) isBacktestEvent_Event() {}
+
+func (*BacktestEvent_Trade) isBacktestEvent_Event() {}
+
+func (*BacktestEvent_Result) isBacktestEvent_Event() {}
+
+// Progress is sent whenever another whole percent of the candles has been
+// simulated.
+type Progress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Percent       int32                  `protobuf:"varint,1,opt,name=percent,proto3" json:"percent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Progress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type EquityPoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Equity        string                 `protobuf:"bytes,2,opt,name=equity,proto3" json:"equity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EquityPoint) Reset() {
+	*x = EquityPoint{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EquityPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EquityPoint) ProtoMessage() {}
+
+func (x *EquityPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EquityPoint.ProtoReflect.Descriptor instead.
+func (*EquityPoint) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EquityPoint) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *EquityPoint) GetEquity() string {
+	if x != nil {
+		return x.Equity
+	}
+	return ""
+}
+
+// EquityChunk is the next part of the equity curve, one point per candle.
+type EquityChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Points        []*EquityPoint         `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EquityChunk) Reset() {
+	*x = EquityChunk{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EquityChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EquityChunk) ProtoMessage() {}
+
+func (x *EquityChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EquityChunk.ProtoReflect.Descriptor instead.
+func (*EquityChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EquityChunk) GetPoints() []*EquityPoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+// Trade is a simulated order and the price it filled at.
+type Trade struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Side          string                 `protobuf:"bytes,2,opt,name=side,proto3" json:"side,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Price         string                 `protobuf:"bytes,4,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      string                 `protobuf:"bytes,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	FillPrice     string                 `protobuf:"bytes,6,opt,name=fill_price,json=fillPrice,proto3" json:"fill_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trade) Reset() {
+	*x = Trade{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trade) ProtoMessage() {}
+
+func (x *Trade) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trade.ProtoReflect.Descriptor instead.
+func (*Trade) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Trade) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Trade) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *Trade) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Trade) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *Trade) GetQuantity() string {
+	if x != nil {
+		return x.Quantity
+	}
+	return ""
+}
+
+func (x *Trade) GetFillPrice() string {
+	if x != nil {
+		return x.FillPrice
+	}
+	return ""
+}
+
+type BacktestResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seed          int64                  `protobuf:"varint,1,opt,name=seed,proto3" json:"seed,omitempty"`
+	RoundTrips    int32                  `protobuf:"varint,2,opt,name=round_trips,json=roundTrips,proto3" json:"round_trips,omitempty"`
+	Wins          int32                  `protobuf:"varint,3,opt,name=wins,proto3" json:"wins,omitempty"`
+	Pnl           string                 `protobuf:"bytes,4,opt,name=pnl,proto3" json:"pnl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BacktestResult) Reset() {
+	*x = BacktestResult{}
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BacktestResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BacktestResult) ProtoMessage() {}
+
+func (x *BacktestResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_backtest_backtest_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BacktestResult.ProtoReflect.Descriptor instead.
+func (*BacktestResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_backtest_backtest_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BacktestResult) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *BacktestResult) GetRoundTrips() int32 {
+	if x != nil {
+		return x.RoundTrips
+	}
+	return 0
+}
+
+func (x *BacktestResult) GetWins() int32 {
+	if x != nil {
+		return x.Wins
+	}
+	return 0
+}
+
+func (x *BacktestResult) GetPnl() string {
+	if x != nil {
+		return x.Pnl
+	}
+	return ""
+}
+
+var File_api_proto_backtest_backtest_proto protoreflect.FileDescriptor
+
+const file_api_proto_backtest_backtest_proto_rawDesc = "" +
+	"\n" +
+	"!api/proto/backtest/backtest.proto\x12\vbacktest.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf9\x01\n" +
+	"\x0eStrategyConfig\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1a\n" +
+	"\binterval\x18\x02 \x01(\tR\binterval\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\tR\bquantity\x12\x1f\n" +
+	"\vfast_period\x18\x04 \x01(\x05R\n" +
+	"fastPeriod\x12\x1f\n" +
+	"\vslow_period\x18\x05 \x01(\x05R\n" +
+	"slowPeriod\x12\x1d\n" +
+	"\n" +
+	"rsi_period\x18\x06 \x01(\x05R\trsiPeriod\x12\x1a\n" +
+	"\boversold\x18\a \x01(\x01R\boversold\x12\x1e\n" +
+	"\n" +
+	"overbought\x18\b \x01(\x01R\n" +
+	"overbought\"\x90\x02\n" +
+	"\x12RunBacktestRequest\x12\x1a\n" +
+	"\bexchange\x18\x01 \x01(\tR\bexchange\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x123\n" +
+	"\x06config\x18\x03 \x01(\v2\x1b.backtest.v1.StrategyConfigR\x06config\x12.\n" +
+	"\x04from\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12!\n" +
+	"\fslippage_bps\x18\x06 \x01(\x01R\vslippageBps\x12\x12\n" +
+	"\x04seed\x18\a \x01(\x03R\x04seed\"\xe4\x01\n" +
+	"\rBacktestEvent\x123\n" +
+	"\bprogress\x18\x01 \x01(\v2\x15.backtest.v1.ProgressH\x00R\bprogress\x122\n" +
+	"\x06equity\x18\x02 \x01(\v2\x18.backtest.v1.EquityChunkH\x00R\x06equity\x12*\n" +
+	"\x05trade\x18\x03 \x01(\v2\x12.backtest.v1.TradeH\x00R\x05trade\x125\n" +
+	"\x06result\x18\x04 \x01(\v2\x1b.backtest.v1.BacktestResultH\x00R\x06resultB\a\n" +
+	"\x05event\"$\n" +
+	"\bProgress\x12\x18\n" +
+	"\apercent\x18\x01 \x01(\x05R\apercent\"U\n" +
+	"\vEquityPoint\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x16\n" +
+	"\x06equity\x18\x02 \x01(\tR\x06equity\"?\n" +
+	"\vEquityChunk\x120\n" +
+	"\x06points\x18\x01 \x03(\v2\x18.backtest.v1.EquityPointR\x06points\"\xb0\x01\n" +
+	"\x05Trade\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04side\x18\x02 \x01(\tR\x04side\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x14\n" +
+	"\x05price\x18\x04 \x01(\tR\x05price\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\tR\bquantity\x12\x1d\n" +
+	"\n" +
+	"fill_price\x18\x06 \x01(\tR\tfillPrice\"k\n" +
+	"\x0eBacktestResult\x12\x12\n" +
+	"\x04seed\x18\x01 \x01(\x03R\x04seed\x12\x1f\n" +
+	"\vround_trips\x18\x02 \x01(\x05R\n" +
+	"roundTrips\x12\x12\n" +
+	"\x04wins\x18\x03 \x01(\x05R\x04wins\x12\x10\n" +
+	"\x03pnl\x18\x04 \x01(\tR\x03pnl2_\n" +
+	"\x0fBacktestService\x12L\n" +
+	"\vRunBacktest\x12\x1f.backtest.v1.RunBacktestRequest\x1a\x1a.backtest.v1.BacktestEvent0\x01B6Z4github.com/tradingbothub/platform/api/proto/backtestb\x06proto3"
+
+var (
+	file_api_proto_backtest_backtest_proto_rawDescOnce sync.Once
+	file_api_proto_backtest_backtest_proto_rawDescData []byte
+)
+
+func file_api_proto_backtest_backtest_proto_rawDescGZIP() []byte {
+	file_api_proto_backtest_backtest_proto_rawDescOnce.Do(func() {
+		file_api_proto_backtest_backtest_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_backtest_backtest_proto_rawDesc), len(file_api_proto_backtest_backtest_proto_rawDesc)))
+	})
+	return file_api_proto_backtest_backtest_proto_rawDescData
+}
+
+var file_api_proto_backtest_backtest_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_api_proto_backtest_backtest_proto_goTypes = []any{
+	(*StrategyConfig)(nil),        // 0: backtest.v1.StrategyConfig
+	(*RunBacktestRequest)(nil),    // 1: backtest.v1.RunBacktestRequest
+	(*BacktestEvent)(nil),         // 2: backtest.v1.BacktestEvent
+	(*Progress)(nil),              // 3: backtest.v1.Progress
+	(*EquityPoint)(nil),           // 4: backtest.v1.EquityPoint
+	(*EquityChunk)(nil),           // 5: backtest.v1.EquityChunk
+	(*Trade)(nil),                 // 6: backtest.v1.Trade
+	(*BacktestResult)(nil),        // 7: backtest.v1.BacktestResult
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+}
+var file_api_proto_backtest_backtest_proto_depIdxs = []int32{
+	0,  // 0: backtest.v1.RunBacktestRequest.config:type_name -> backtest.v1.StrategyConfig
+	8,  // 1: backtest.v1.RunBacktestRequest.from:type_name -> google.protobuf.Timestamp
+	8,  // 2: backtest.v1.RunBacktestRequest.to:type_name -> google.protobuf.Timestamp
+	3,  // 3: backtest.v1.BacktestEvent.progress:type_name -> backtest.v1.Progress
+	5,  // 4: backtest.v1.BacktestEvent.equity:type_name -> backtest.v1.EquityChunk
+	6,  // 5: backtest.v1.BacktestEvent.trade:type_name -> backtest.v1.Trade
+	7,  // 6: backtest.v1.BacktestEvent.result:type_name -> backtest.v1.BacktestResult
+	8,  // 7: backtest.v1.EquityPoint.time:type_name -> google.protobuf.Timestamp
+	4,  // 8: backtest.v1.EquityChunk.points:type_name -> backtest.v1.EquityPoint
+	8,  // 9: backtest.v1.Trade.time:type_name -> google.protobuf.Timestamp
+	1,  // 10: backtest.v1.BacktestService.RunBacktest:input_type -> backtest.v1.RunBacktestRequest
+	2,  // 11: backtest.v1.BacktestService.RunBacktest:output_type -> backtest.v1.BacktestEvent
+	11, // [11:12] is the sub-list for method output_type
+	10, // [10:11] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_backtest_backtest_proto_init() }
+func file_api_proto_backtest_backtest_proto_init() {
+	if File_api_proto_backtest_backtest_proto != nil {
+		return
+	}
+	file_api_proto_backtest_backtest_proto_msgTypes[2].OneofWrappers = []any{
+		(*BacktestEvent_Progress)(nil),
+		(*BacktestEvent_Equity)(nil),
+		(*BacktestEvent_Trade)(nil),
+		(*BacktestEvent_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_backtest_backtest_proto_rawDesc), len(file_api_proto_backtest_backtest_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_backtest_backtest_proto_goTypes,
+		DependencyIndexes: file_api_proto_backtest_backtest_proto_depIdxs,
+		MessageInfos:      file_api_proto_backtest_backtest_proto_msgTypes,
+	}.Build()
+	File_api_proto_backtest_backtest_proto = out.File
+	file_api_proto_backtest_backtest_proto_goTypes = nil
+	file_api_proto_backtest_backtest_proto_depIdxs = nil
+}
@@ -0,0 +1,130 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: api/proto/backtest/backtest.proto
+
+package backtest
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BacktestService_RunBacktest_FullMethodName = "/backtest.v1.BacktestService/RunBacktest"
+)
+
+// BacktestServiceClient is the client API for BacktestService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BacktestServiceClient interface {
+	// RunBacktest simulates one strategy config and streams progress, the
+	// equity curve and fills as they are simulated. The last event is the
+	// result.
+	RunBacktest(ctx context.Context, in *RunBacktestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BacktestEvent], error)
+}
+
+type backtestServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBacktestServiceClient(cc grpc.ClientConnInterface) BacktestServiceClient {
+	return &backtestServiceClient{cc}
+}
+
+func (c *backtestServiceClient) RunBacktest(ctx context.Context, in *RunBacktestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BacktestEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BacktestService_ServiceDesc.Streams[0], BacktestService_RunBacktest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunBacktestRequest, BacktestEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BacktestService_RunBacktestClient = grpc.ServerStreamingClient[BacktestEvent]
+
+// BacktestServiceServer is the server API for BacktestService service.
+// All implementations must embed UnimplementedBacktestServiceServer
+// for forward compatibility.
+type BacktestServiceServer interface {
+	// RunBacktest simulates one strategy config and streams progress, the
+	// equity curve and fills as they are simulated. The last event is the
+	// result.
+	RunBacktest(*RunBacktestRequest, grpc.ServerStreamingServer[BacktestEvent]) error
+	mustEmbedUnimplementedBacktestServiceServer()
+}
+
+// UnimplementedBacktestServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBacktestServiceServer struct{}
+
+func (UnimplementedBacktestServiceServer) RunBacktest(*RunBacktestRequest, grpc.ServerStreamingServer[BacktestEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method RunBacktest not implemented")
+}
+func (UnimplementedBacktestServiceServer) mustEmbedUnimplementedBacktestServiceServer() {}
+func (UnimplementedBacktestServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeBacktestServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BacktestServiceServer will
+// result in compilation errors.
+type UnsafeBacktestServiceServer interface {
+	mustEmbedUnimplementedBacktestServiceServer()
+}
+
+func RegisterBacktestServiceServer(s grpc.ServiceRegistrar, srv BacktestServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBacktestServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BacktestService_ServiceDesc, srv)
+}
+
+func _BacktestService_RunBacktest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunBacktestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BacktestServiceServer).RunBacktest(m, &grpc.GenericServerStream[RunBacktestRequest, BacktestEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BacktestService_RunBacktestServer = grpc.ServerStreamingServer[BacktestEvent]
+
+// BacktestService_ServiceDesc is the grpc.ServiceDesc for BacktestService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BacktestService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "backtest.v1.BacktestService",
+	HandlerType: (*BacktestServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RunBacktest",
+			Handler:       _BacktestService_RunBacktest_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/proto/backtest/backtest.proto",
+}
@@ -0,0 +1,977 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: api/proto/events/events.proto
+
+package events
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Envelope wraps every event published on NATS.
+type Envelope struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Fully qualified payload message name, e.g. events.v1.BotStatusChanged
+	Type    string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Version uint32 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// Service that published the event
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_api_proto_events_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Envelope) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Envelope) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Envelope) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Envelope) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Envelope) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *Envelope) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type BuildInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Commit        string                 `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
+	BuildTime     string                 `protobuf:"bytes,3,opt,name=build_time,json=buildTime,proto3" json:"build_time,omitempty"`
+	GoVersion     string                 `protobuf:"bytes,4,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildInfo) Reset() {
+	*x = BuildInfo{}
+	mi := &file_api_proto_events_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildInfo) ProtoMessage() {}
+
+func (x *BuildInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildInfo.ProtoReflect.Descriptor instead.
+func (*BuildInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BuildInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *BuildInfo) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *BuildInfo) GetBuildTime() string {
+	if x != nil {
+		return x.BuildTime
+	}
+	return ""
+}
+
+func (x *BuildInfo) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+// ServiceAnnounced is the periodic heartbeat of a running service instance.
+type ServiceAnnounced struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	InstanceId    string                 `protobuf:"bytes,2,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	Build         *BuildInfo             `protobuf:"bytes,3,opt,name=build,proto3" json:"build,omitempty"`
+	Region        string                 `protobuf:"bytes,4,opt,name=region,proto3" json:"region,omitempty"`
+	Address       string                 `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Dependencies  []string               `protobuf:"bytes,8,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceAnnounced) Reset() {
+	*x = ServiceAnnounced{}
+	mi := &file_api_proto_events_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceAnnounced) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceAnnounced) ProtoMessage() {}
+
+func (x *ServiceAnnounced) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceAnnounced.ProtoReflect.Descriptor instead.
+func (*ServiceAnnounced) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ServiceAnnounced) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ServiceAnnounced) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *ServiceAnnounced) GetBuild() *BuildInfo {
+	if x != nil {
+		return x.Build
+	}
+	return nil
+}
+
+func (x *ServiceAnnounced) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *ServiceAnnounced) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ServiceAnnounced) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ServiceAnnounced) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *ServiceAnnounced) GetDependencies() []string {
+	if x != nil {
+		return x.Dependencies
+	}
+	return nil
+}
+
+// ServiceLeft is published by an instance shutting down cleanly.
+type ServiceLeft struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	InstanceId    string                 `protobuf:"bytes,2,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceLeft) Reset() {
+	*x = ServiceLeft{}
+	mi := &file_api_proto_events_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceLeft) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceLeft) ProtoMessage() {}
+
+func (x *ServiceLeft) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceLeft.ProtoReflect.Descriptor instead.
+func (*ServiceLeft) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ServiceLeft) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ServiceLeft) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+// BotStatusChanged is published when a bot is started, stopped or fails.
+type BotStatusChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BotId         string                 `protobuf:"bytes,1,opt,name=bot_id,json=botId,proto3" json:"bot_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BotStatusChanged) Reset() {
+	*x = BotStatusChanged{}
+	mi := &file_api_proto_events_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BotStatusChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BotStatusChanged) ProtoMessage() {}
+
+func (x *BotStatusChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BotStatusChanged.ProtoReflect.Descriptor instead.
+func (*BotStatusChanged) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BotStatusChanged) GetBotId() string {
+	if x != nil {
+		return x.BotId
+	}
+	return ""
+}
+
+func (x *BotStatusChanged) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *BotStatusChanged) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// LoginFailed is an audit event published for every rejected login. The
+// user ID is empty when no account uses the email.
+type LoginFailed struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Email    string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	ClientIp string                 `protobuf:"bytes,3,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	// Failures of the account within the lockout window, this one included
+	Failures      uint32 `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginFailed) Reset() {
+	*x = LoginFailed{}
+	mi := &file_api_proto_events_events_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginFailed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginFailed) ProtoMessage() {}
+
+func (x *LoginFailed) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginFailed.ProtoReflect.Descriptor instead.
+func (*LoginFailed) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LoginFailed) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LoginFailed) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *LoginFailed) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *LoginFailed) GetFailures() uint32 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+// LoginLockedOut is an audit event published when repeated failures lock
+// out logins to an account or from a client IP.
+type LoginLockedOut struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "account" or "ip"
+	Scope         string                 `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	ClientIp      string                 `protobuf:"bytes,4,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	LockedUntil   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=locked_until,json=lockedUntil,proto3" json:"locked_until,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginLockedOut) Reset() {
+	*x = LoginLockedOut{}
+	mi := &file_api_proto_events_events_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginLockedOut) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginLockedOut) ProtoMessage() {}
+
+func (x *LoginLockedOut) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginLockedOut.ProtoReflect.Descriptor instead.
+func (*LoginLockedOut) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LoginLockedOut) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *LoginLockedOut) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LoginLockedOut) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *LoginLockedOut) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *LoginLockedOut) GetLockedUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LockedUntil
+	}
+	return nil
+}
+
+// ConsumerLagAlert is published when a JetStream consumer stops keeping
+// up with its stream, and again when it recovers.
+type ConsumerLagAlert struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Stream   string                 `protobuf:"bytes,1,opt,name=stream,proto3" json:"stream,omitempty"`
+	Consumer string                 `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// False once the consumer recovered
+	Stuck bool `protobuf:"varint,3,opt,name=stuck,proto3" json:"stuck,omitempty"`
+	// "no_progress" or "backlog"; empty on recovery
+	Reason      string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Pending     uint64 `protobuf:"varint,5,opt,name=pending,proto3" json:"pending,omitempty"`
+	AckPending  int64  `protobuf:"varint,6,opt,name=ack_pending,json=ackPending,proto3" json:"ack_pending,omitempty"`
+	Redelivered int64  `protobuf:"varint,7,opt,name=redelivered,proto3" json:"redelivered,omitempty"`
+	// When the consumer last acknowledged progress
+	ProgressedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=progressed_at,json=progressedAt,proto3" json:"progressed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerLagAlert) Reset() {
+	*x = ConsumerLagAlert{}
+	mi := &file_api_proto_events_events_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerLagAlert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerLagAlert) ProtoMessage() {}
+
+func (x *ConsumerLagAlert) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerLagAlert.ProtoReflect.Descriptor instead.
+func (*ConsumerLagAlert) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ConsumerLagAlert) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *ConsumerLagAlert) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *ConsumerLagAlert) GetStuck() bool {
+	if x != nil {
+		return x.Stuck
+	}
+	return false
+}
+
+func (x *ConsumerLagAlert) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ConsumerLagAlert) GetPending() uint64 {
+	if x != nil {
+		return x.Pending
+	}
+	return 0
+}
+
+func (x *ConsumerLagAlert) GetAckPending() int64 {
+	if x != nil {
+		return x.AckPending
+	}
+	return 0
+}
+
+func (x *ConsumerLagAlert) GetRedelivered() int64 {
+	if x != nil {
+		return x.Redelivered
+	}
+	return 0
+}
+
+func (x *ConsumerLagAlert) GetProgressedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProgressedAt
+	}
+	return nil
+}
+
+type OrderBookLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      float64                `protobuf:"fixed64,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderBookLevel) Reset() {
+	*x = OrderBookLevel{}
+	mi := &file_api_proto_events_events_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBookLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBookLevel) ProtoMessage() {}
+
+func (x *OrderBookLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBookLevel.ProtoReflect.Descriptor instead.
+func (*OrderBookLevel) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *OrderBookLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *OrderBookLevel) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// OrderBookUpdated carries a full order book or a delta from an exchange
+// feed. A zero quantity in a delta removes the level.
+type OrderBookUpdated struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Exchange string                 `protobuf:"bytes,1,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	Symbol   string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// True for a full book that replaces the held one
+	Snapshot bool   `protobuf:"varint,3,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	Sequence uint64 `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Sequence the delta follows, when the exchange provides it
+	PrevSequence  uint64                 `protobuf:"varint,5,opt,name=prev_sequence,json=prevSequence,proto3" json:"prev_sequence,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`
+	Bids          []*OrderBookLevel      `protobuf:"bytes,7,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks          []*OrderBookLevel      `protobuf:"bytes,8,rep,name=asks,proto3" json:"asks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderBookUpdated) Reset() {
+	*x = OrderBookUpdated{}
+	mi := &file_api_proto_events_events_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBookUpdated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBookUpdated) ProtoMessage() {}
+
+func (x *OrderBookUpdated) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_events_events_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBookUpdated.ProtoReflect.Descriptor instead.
+func (*OrderBookUpdated) Descriptor() ([]byte, []int) {
+	return file_api_proto_events_events_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OrderBookUpdated) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *OrderBookUpdated) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *OrderBookUpdated) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+func (x *OrderBookUpdated) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *OrderBookUpdated) GetPrevSequence() uint64 {
+	if x != nil {
+		return x.PrevSequence
+	}
+	return 0
+}
+
+func (x *OrderBookUpdated) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *OrderBookUpdated) GetBids() []*OrderBookLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *OrderBookUpdated) GetAsks() []*OrderBookLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+var file_api_proto_events_events_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MessageOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50001,
+		Name:          "events.v1.subject",
+		Tag:           "bytes,50001,opt,name=subject",
+		Filename:      "api/proto/events/events.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MessageOptions)(nil),
+		ExtensionType: (*uint32)(nil),
+		Field:         50002,
+		Name:          "events.v1.version",
+		Tag:           "varint,50002,opt,name=version",
+		Filename:      "api/proto/events/events.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MessageOptions.
+var (
+	// optional string subject = 50001;
+	E_Subject = &file_api_proto_events_events_proto_extTypes[0]
+	// optional uint32 version = 50002;
+	E_Version = &file_api_proto_events_events_proto_extTypes[1]
+)
+
+var File_api_proto_events_events_proto protoreflect.FileDescriptor
+
+const file_api_proto_events_events_proto_rawDesc = "" +
+	"\n" +
+	"\x1dapi/proto/events/events.proto\x12\tevents.v1\x1a google/protobuf/descriptor.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb7\x01\n" +
+	"\bEnvelope\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\rR\aversion\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12;\n" +
+	"\voccurred_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x18\n" +
+	"\apayload\x18\x06 \x01(\fR\apayload\"{\n" +
+	"\tBuildInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x16\n" +
+	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x1d\n" +
+	"\n" +
+	"build_time\x18\x03 \x01(\tR\tbuildTime\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x04 \x01(\tR\tgoVersion\"\xbd\x02\n" +
+	"\x10ServiceAnnounced\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x1f\n" +
+	"\vinstance_id\x18\x02 \x01(\tR\n" +
+	"instanceId\x12*\n" +
+	"\x05build\x18\x03 \x01(\v2\x14.events.v1.BuildInfoR\x05build\x12\x16\n" +
+	"\x06region\x18\x04 \x01(\tR\x06region\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"started_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12\"\n" +
+	"\fdependencies\x18\b \x03(\tR\fdependencies:\x19\x8a\xb5\x18\x11registry.announce\x90\xb5\x18\x01\"`\n" +
+	"\vServiceLeft\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x1f\n" +
+	"\vinstance_id\x18\x02 \x01(\tR\n" +
+	"instanceId:\x16\x8a\xb5\x18\x0eregistry.leave\x90\xb5\x18\x01\"o\n" +
+	"\x10BotStatusChanged\x12\x15\n" +
+	"\x06bot_id\x18\x01 \x01(\tR\x05botId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status:\x13\x8a\xb5\x18\vbots.status\x90\xb5\x18\x01\"\x96\x01\n" +
+	"\vLoginFailed\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1b\n" +
+	"\tclient_ip\x18\x03 \x01(\tR\bclientIp\x12\x1a\n" +
+	"\bfailures\x18\x04 \x01(\rR\bfailures:\x1f\x8a\xb5\x18\x17audit.auth.login_failed\x90\xb5\x18\x01\"\xd0\x01\n" +
+	"\x0eLoginLockedOut\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x1b\n" +
+	"\tclient_ip\x18\x04 \x01(\tR\bclientIp\x12=\n" +
+	"\flocked_until\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vlockedUntil:\x1d\x8a\xb5\x18\x15audit.auth.locked_out\x90\xb5\x18\x01\"\xb1\x02\n" +
+	"\x10ConsumerLagAlert\x12\x16\n" +
+	"\x06stream\x18\x01 \x01(\tR\x06stream\x12\x1a\n" +
+	"\bconsumer\x18\x02 \x01(\tR\bconsumer\x12\x14\n" +
+	"\x05stuck\x18\x03 \x01(\bR\x05stuck\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12\x18\n" +
+	"\apending\x18\x05 \x01(\x04R\apending\x12\x1f\n" +
+	"\vack_pending\x18\x06 \x01(\x03R\n" +
+	"ackPending\x12 \n" +
+	"\vredelivered\x18\a \x01(\x03R\vredelivered\x12?\n" +
+	"\rprogressed_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\fprogressedAt:\x1d\x8a\xb5\x18\x15ops.nats.consumer_lag\x90\xb5\x18\x01\"B\n" +
+	"\x0eOrderBookLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x01R\bquantity\"\xcf\x02\n" +
+	"\x10OrderBookUpdated\x12\x1a\n" +
+	"\bexchange\x18\x01 \x01(\tR\bexchange\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\bsnapshot\x18\x03 \x01(\bR\bsnapshot\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x04R\bsequence\x12#\n" +
+	"\rprev_sequence\x18\x05 \x01(\x04R\fprevSequence\x12.\n" +
+	"\x04time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12-\n" +
+	"\x04bids\x18\a \x03(\v2\x19.events.v1.OrderBookLevelR\x04bids\x12-\n" +
+	"\x04asks\x18\b \x03(\v2\x19.events.v1.OrderBookLevelR\x04asks:\x1c\x8a\xb5\x18\x14marketdata.orderbook\x90\xb5\x18\x01:;\n" +
+	"\asubject\x12\x1f.google.protobuf.MessageOptions\x18ц\x03 \x01(\tR\asubject:;\n" +
+	"\aversion\x12\x1f.google.protobuf.MessageOptions\x18҆\x03 \x01(\rR\aversionB4Z2github.com/tradingbothub/platform/api/proto/eventsb\x06proto3"
+
+var (
+	file_api_proto_events_events_proto_rawDescOnce sync.Once
+	file_api_proto_events_events_proto_rawDescData []byte
+)
+
+func file_api_proto_events_events_proto_rawDescGZIP() []byte {
+	file_api_proto_events_events_proto_rawDescOnce.Do(func() {
+		file_api_proto_events_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_events_events_proto_rawDesc), len(file_api_proto_events_events_proto_rawDesc)))
+	})
+	return file_api_proto_events_events_proto_rawDescData
+}
+
+var file_api_proto_events_events_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_api_proto_events_events_proto_goTypes = []any{
+	(*Envelope)(nil),                    // 0: events.v1.Envelope
+	(*BuildInfo)(nil),                   // 1: events.v1.BuildInfo
+	(*ServiceAnnounced)(nil),            // 2: events.v1.ServiceAnnounced
+	(*ServiceLeft)(nil),                 // 3: events.v1.ServiceLeft
+	(*BotStatusChanged)(nil),            // 4: events.v1.BotStatusChanged
+	(*LoginFailed)(nil),                 // 5: events.v1.LoginFailed
+	(*LoginLockedOut)(nil),              // 6: events.v1.LoginLockedOut
+	(*ConsumerLagAlert)(nil),            // 7: events.v1.ConsumerLagAlert
+	(*OrderBookLevel)(nil),              // 8: events.v1.OrderBookLevel
+	(*OrderBookUpdated)(nil),            // 9: events.v1.OrderBookUpdated
+	(*timestamppb.Timestamp)(nil),       // 10: google.protobuf.Timestamp
+	(*descriptorpb.MessageOptions)(nil), // 11: google.protobuf.MessageOptions
+}
+var file_api_proto_events_events_proto_depIdxs = []int32{
+	10, // 0: events.v1.Envelope.occurred_at:type_name -> google.protobuf.Timestamp
+	1,  // 1: events.v1.ServiceAnnounced.build:type_name -> events.v1.BuildInfo
+	10, // 2: events.v1.ServiceAnnounced.started_at:type_name -> google.protobuf.Timestamp
+	10, // 3: events.v1.LoginLockedOut.locked_until:type_name -> google.protobuf.Timestamp
+	10, // 4: events.v1.ConsumerLagAlert.progressed_at:type_name -> google.protobuf.Timestamp
+	10, // 5: events.v1.OrderBookUpdated.time:type_name -> google.protobuf.Timestamp
+	8,  // 6: events.v1.OrderBookUpdated.bids:type_name -> events.v1.OrderBookLevel
+	8,  // 7: events.v1.OrderBookUpdated.asks:type_name -> events.v1.OrderBookLevel
+	11, // 8: events.v1.subject:extendee -> google.protobuf.MessageOptions
+	11, // 9: events.v1.version:extendee -> google.protobuf.MessageOptions
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	8,  // [8:10] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_events_events_proto_init() }
+func file_api_proto_events_events_proto_init() {
+	if File_api_proto_events_events_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_events_events_proto_rawDesc), len(file_api_proto_events_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_api_proto_events_events_proto_goTypes,
+		DependencyIndexes: file_api_proto_events_events_proto_depIdxs,
+		MessageInfos:      file_api_proto_events_events_proto_msgTypes,
+		ExtensionInfos:    file_api_proto_events_events_proto_extTypes,
+	}.Build()
+	File_api_proto_events_events_proto = out.File
+	file_api_proto_events_events_proto_goTypes = nil
+	file_api_proto_events_events_proto_depIdxs = nil
+}
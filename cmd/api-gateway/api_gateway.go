@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,9 +12,15 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tradingbothub/platform/internal/auth"
 	"github.com/tradingbothub/platform/internal/config"
 	"github.com/tradingbothub/platform/internal/gateway"
 	"github.com/tradingbothub/platform/internal/middleware"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"github.com/tradingbothub/platform/pkg/objectstore"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func main() {
@@ -30,21 +37,20 @@ func main() {
 	}
 
 	// Setup Gin router
-	router := setupRouter(gw)
+	router := setupRouter(cfg, gw)
 
 	// Create HTTP server
-	srv := &http.Server{
-		Addr:         cfg.Server.Port,
-		Handler:      router,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+	srv := newServer(cfg.Server, router)
+	listenConfig := net.ListenConfig{KeepAlive: cfg.Server.TCPKeepAlive}
+	listener, err := listenConfig.Listen(context.Background(), "tcp", cfg.Server.Port)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("API Gateway listening on %s", cfg.Server.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("API Gateway %s (%s) listening on %s (region %s)", buildinfo.Version, buildinfo.ShortCommit(), cfg.Server.Port, cfg.Region)
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
@@ -56,79 +62,345 @@ func main() {
 
 	log.Println("Shutting down API Gateway...")
 
+	// Enter lame-duck mode: fail health checks, refuse new streams and tell
+	// open streams to reconnect elsewhere
+	gw.Drainer.StartDraining()
+
+	// Give load balancers time to notice the failing health check
+	time.Sleep(cfg.Server.DrainDelay)
+
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Hijacked connections are not covered by Shutdown
+	if err := gw.Drainer.Wait(ctx); err != nil {
+		log.Printf("%d streaming connections still open at shutdown deadline", gw.Drainer.ActiveStreams())
+	}
+
+	// Backend connections are closed only after in-flight requests finished
 	gw.Close()
 	log.Println("API Gateway stopped")
 }
 
-func setupRouter(gw *gateway.Gateway) *gin.Engine {
+// newServer applies the connection tuning and instruments connections.
+func newServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              cfg.Port,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ConnState:         middleware.NewConnTracker().ConnState,
+		ErrorLog:          middleware.ServerErrorLog(),
+	}
+
+	h2s := &http2.Server{
+		MaxConcurrentStreams: uint32(cfg.HTTP2.MaxConcurrentStreams),
+		IdleTimeout:          cfg.IdleTimeout,
+		ReadIdleTimeout:      cfg.HTTP2.ReadIdleTimeout,
+		PingTimeout:          cfg.HTTP2.PingTimeout,
+		CountError:           middleware.CountHTTP2Error,
+	}
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		log.Fatalf("Failed to configure HTTP/2: %v", err)
+	}
+
+	if cfg.HTTP2.H2C {
+		handler = middleware.TrackH2C(h2c.NewHandler(handler, h2s))
+	}
+	srv.Handler = handler
+	return srv
+}
+
+func setupRouter(cfg *config.Config, gw *gateway.Gateway) *gin.Engine {
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
+	if err := middleware.ConfigureClientIP(router, middleware.ClientIPConfig{
+		TrustedProxies: cfg.Server.Proxies.TrustedProxies,
+		Headers:        cfg.Server.Proxies.Headers,
+		Platform:       cfg.Server.Proxies.Platform,
+	}); err != nil {
+		log.Fatalf("Invalid proxy config: %v", err)
+	}
 
 	// Middleware
+	router.Use(middleware.JoinForwardedFor())
+	router.Use(middleware.ForwardClient())
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORS())
-	router.Use(middleware.RateLimit())
+	router.Use(middleware.Metrics())
+	router.Use(middleware.RequestQueueing())
+	// Long-lived connections are told apart by route, never by headers
+	streams := []string{"/api/v1/stream", "/api/v1/stream/ws"}
+	router.Use(middleware.LameDuck(gw.Drainer, streams))
+	router.Use(middleware.RouteTimeouts(middleware.Timeouts{
+		Default: cfg.Server.Timeouts.Default,
+		Routes:  cfg.Server.Timeouts.Routes,
+		Streams: streams,
+	}))
+	if cfg.LoadShedding.Enabled {
+		shedder := middleware.NewLoadShedder(middleware.LoadShedLimits{
+			MaxInFlight:   cfg.LoadShedding.MaxInFlight,
+			LatencyTarget: cfg.LoadShedding.LatencyTarget,
+			NormalFactor:  cfg.LoadShedding.NormalFactor,
+			RetryAfter:    cfg.LoadShedding.RetryAfter,
+		})
+		router.Use(middleware.LoadShed(shedder, middleware.ShedPriorities{
+			Critical: []string{
+				"/health", "/metrics",
+				"/api/v1/auth/", "/api/v1/orders/", "/api/v1/positions/", "/api/v1/admin/",
+			},
+			Low: []string{
+				"/api/v1/market/", "/api/v1/shared/:token", "/api/v1/changelog",
+				"/api/v1/bots", "/api/v1/strategies", "/api/v1/approvals", "/api/v1/shares",
+				"/api/v1/trash", "/api/v1/filters", "/api/v1/search",
+				"/api/v1/copy/follows", "/api/v1/copy/leaderboard",
+				"/api/v1/portfolio/orders", "/api/v1/portfolio/trades", "/api/v1/bots/:id/signals",
+			},
+			Streams: streams,
+		}))
+	}
+	if cfg.RequestLanes.Enabled {
+		lanes := middleware.NewLaneScheduler(middleware.LaneLimits{
+			Orders:     middleware.Lane(cfg.RequestLanes.Orders),
+			Default:    middleware.Lane(cfg.RequestLanes.Default),
+			Analytics:  middleware.Lane(cfg.RequestLanes.Analytics),
+			Shared:     cfg.RequestLanes.Shared,
+			MaxWait:    cfg.RequestLanes.MaxWait,
+			RetryAfter: cfg.RequestLanes.RetryAfter,
+		})
+		router.Use(middleware.RequestLanes(lanes, middleware.LaneRoutes{
+			Orders: []string{
+				"/api/v1/orders/", "/api/v1/positions/",
+				"/api/v1/bots/:id/stop", "/api/v1/admin/bots/halt",
+			},
+			Analytics: []string{
+				"/api/v1/portfolio/", "/api/v1/portfolio", "/api/v1/market/candles/:symbol", "/api/v1/market/chart/:symbol",
+				"/api/v1/copy/leaderboard", "/api/v1/copy/payouts", "/api/v1/search",
+				"/api/v1/bots/:id/signals", "/api/v1/bots/:id/logs", "/api/v1/user/usage/api",
+			},
+			Streams: streams,
+		}))
+	}
+	ipLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	userLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	tradingLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	demoLimiter := middleware.NewRateLimiter(cfg.Demo.Requests, cfg.Demo.Window)
+	router.Use(middleware.Metering(gw.Metering, gw.Keys))
+	// The trading routes queue on the per-address limit as they do on their
+	// own, see the trading group below
+	router.Use(middleware.RateLimitWithQueuedRoutes(ipLimiter, gw.AccessList, cfg.RateLimit.QueueMaxWait, []string{
+		"/api/v1/orders/", "/api/v1/positions/",
+	}))
+	router.Use(middleware.JSONBodyLimits(middleware.JSONLimits{
+		MaxBodyBytes:   cfg.RequestLimits.MaxBodyBytes,
+		MaxDepth:       cfg.RequestLimits.MaxDepth,
+		MaxArrayLength: cfg.RequestLimits.MaxArrayLength,
+		MaxStringBytes: cfg.RequestLimits.MaxStringBytes,
+		UploadRoutes:   []string{"/api/v1/user/avatar"},
+	}))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
+		if gw.Drainer.Draining() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "draining",
+				"timestamp": time.Now().Unix(),
+				"service":   "api-gateway",
+				"region":    cfg.Region,
+			})
+			return
+		}
+		if !gw.Warmer.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "warming",
+				"timestamp": time.Now().Unix(),
+				"service":   "api-gateway",
+				"region":    cfg.Region,
+				"warmup":    gw.Warmer.Status(),
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"status":    "healthy",
 			"timestamp": time.Now().Unix(),
 			"service":   "api-gateway",
+			"region":    cfg.Region,
 		})
 	})
 
+	// Build info
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildinfo.Get())
+	})
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Public keys that verify access tokens
+	router.GET("/.well-known/jwks.json", gw.GetJWKS)
+
+	// Signed object URLs are served here when artifacts live on local disk
+	if local, ok := gw.Objects.(*objectstore.Local); ok {
+		files := gin.WrapH(http.StripPrefix("/files", local.Handler()))
+		router.GET("/files/*key", files)
+		router.PUT("/files/*key", files)
+	}
+
+	// Deprecated routes register themselves here where they are declared
+	changelog := middleware.NewChangelog()
+
 	// API versioning
 	v1 := router.Group("/api/v1")
 	{
+		v1.GET("/changelog", changelog.Handler())
+
 		// Authentication routes (no auth required)
-		auth := v1.Group("/auth")
+		authRoutes := v1.Group("/auth")
 		{
-			auth.POST("/register", gw.Register)
-			auth.POST("/login", gw.Login)
-			auth.POST("/refresh", gw.RefreshToken)
+			authRoutes.POST("/register", gw.Register)
+			authRoutes.GET("/availability", gw.CheckAvailability)
+			authRoutes.POST("/login", gw.Login)
+			authRoutes.POST("/refresh", gw.RefreshToken)
+			authRoutes.POST("/verify-email", gw.VerifyEmail)
+			authRoutes.POST("/forgot-password", gw.ForgotPassword)
+			authRoutes.POST("/reset-password", gw.ResetPassword)
+			authRoutes.POST("/email-change/confirm", gw.ConfirmEmailChange)
+			authRoutes.POST("/email-change/undo", gw.UndoEmailChange)
+			authRoutes.POST("/magic-link", gw.RequestMagicLink)
+			authRoutes.POST("/magic-link/consume", gw.ConsumeMagicLink)
+			authRoutes.POST("/sso/start", gw.StartSSO)
+			authRoutes.GET("/sso/start", gw.StartSSORedirect)
+			authRoutes.POST("/sso/callback", gw.CompleteSSO)
 		}
 
+		// Demo sandbox for the API docs (no auth required)
+		v1.POST("/demo/session", gw.CreateDemoSession)
+
+		// Public share links (no auth required)
+		v1.GET("/shared/:token", gw.GetSharedResource)
+
 		// Protected routes
-		protected := v1.Group("")
-		protected.Use(middleware.JWTAuth(gw.AuthClient))
+		authenticated := v1.Group("")
+		// Browsers signed in with a cookie present its session's token
+		authenticated.Use(middleware.CookieAuth(gw.Cookies))
+		authenticated.Use(middleware.JWTAuth(gw.AuthClient, gw.Tokens, gw.Keys))
+		// Scoped tokens only reach the route groups their scopes cover
+		authenticated.Use(middleware.RequireScopes(middleware.ScopeRoutes{
+			middleware.AnyScope:    {"/api/v1/auth/logout"},
+			auth.ResourceAccount:   {"/api/v1/user/", "/api/v1/orgs", "/api/v1/orgs/", "/api/v1/invitations", "/api/v1/invitations/"},
+			auth.ResourceBots:      {"/api/v1/bots", "/api/v1/bots/", "/api/v1/strategies", "/api/v1/strategies/", "/api/v1/stream", "/api/v1/stream/ws"},
+			auth.ResourceOrders:    {"/api/v1/orders/", "/api/v1/positions/", "/api/v1/paper/"},
+			auth.ResourcePortfolio: {"/api/v1/portfolio", "/api/v1/portfolio/"},
+			auth.ResourceMarket:    {"/api/v1/market/"},
+		}))
+		if cfg.Demo.Enabled {
+			// The demo account explores bots, strategies, paper trading and
+			// market data; account, organization, key and billing routes
+			// stay closed to it
+			authenticated.Use(middleware.Sandbox(demoLimiter, cfg.Demo.Email, middleware.SandboxRoutes{
+				Read: []string{
+					"/api/v1/user/profile", "/api/v1/bots", "/api/v1/bots/", "/api/v1/strategies", "/api/v1/strategies/",
+					"/api/v1/backtest/jobs", "/api/v1/backtest/jobs/", "/api/v1/filters", "/api/v1/search",
+					"/api/v1/market/", "/api/v1/portfolio", "/api/v1/portfolio/", "/api/v1/orders/", "/api/v1/stream",
+					"/api/v1/stream/ws", "/api/v1/copy/leaderboard",
+				},
+				Write: []string{
+					"/api/v1/bots", "/api/v1/bots/:id", "/api/v1/bots/:id/start", "/api/v1/bots/:id/stop",
+					"/api/v1/bots/:id/tags", "/api/v1/bots/:id/preview", "/api/v1/bots/:id/backtest", "/api/v1/bots/:id/sweep",
+					"/api/v1/strategies", "/api/v1/strategies/:id", "/api/v1/strategies/:id/tags", "/api/v1/strategies/:id/backtest",
+					"/api/v1/backtest/jobs/:id", "/api/v1/filters", "/api/v1/filters/:id",
+					"/api/v1/orders/", "/api/v1/positions/",
+				},
+			}))
+		}
+
+		protected := authenticated.Group("")
+		protected.Use(middleware.RateLimitWithAccessList(userLimiter, gw.AccessList))
 		{
+			protected.POST("/auth/logout", gw.Logout)
+			protected.POST("/auth/resend-verification", gw.ResendVerification)
+			protected.POST("/auth/revoke-sessions", gw.RevokeSessions)
+
 			// User routes
 			user := protected.Group("/user")
 			{
 				user.GET("/profile", gw.GetProfile)
 				user.PUT("/profile", gw.UpdateProfile)
 				user.POST("/change-password", gw.ChangePassword)
+				user.POST("/change-email", gw.RequestEmailChange)
+				user.GET("/avatar", gw.GetAvatar)
+				user.POST("/avatar", gw.UploadAvatar)
+				user.DELETE("/avatar", gw.DeleteAvatar)
+				user.GET("/sessions", gw.ListSessions)
+				user.DELETE("/sessions/:id", gw.RevokeSession)
+				user.GET("/security-events", gw.ListSecurityEvents)
+				user.GET("/logins", gw.ListLogins)
+				user.GET("/security/methods", gw.ListAuthMethods)
+				user.PUT("/security/methods/password", gw.SetPassword)
+				user.DELETE("/security/methods/password", gw.RemovePassword)
+				user.POST("/security/methods/sso", gw.LinkSSO)
+				user.DELETE("/security/methods/sso/:connection_id", gw.UnlinkSSO)
+				user.GET("/security/ip-allowlist", gw.GetIPAllowlist)
+				user.PUT("/security/ip-allowlist/mode", middleware.RequireAllowedIP(), gw.SetIPAllowlistMode)
+				user.POST("/security/ip-allowlist/networks", middleware.RequireAllowedIP(), gw.AddAllowedNetwork)
+				user.DELETE("/security/ip-allowlist/networks/:id", middleware.RequireAllowedIP(), gw.RemoveAllowedNetwork)
+				user.GET("/data-region", gw.ListDataRegions)
+				user.PUT("/data-region", gw.SetDataRegion)
+				user.GET("/usage/api", gw.GetAPIUsage)
+				user.GET("/exchange-keys", gw.ListAPIKeys)
+				user.POST("/exchange-keys", middleware.RequireAllowedIP(), gw.CreateAPIKey)
+				user.DELETE("/exchange-keys/:id", middleware.RequireAllowedIP(), gw.DeleteAPIKey)
 			}
 
 			// Bot routes
 			bots := protected.Group("/bots")
+			bots.Use(middleware.OrgContext(gw.Orgs))
 			{
 				bots.GET("", gw.ListBots)
-				bots.POST("", gw.CreateBot)
+				if cfg.Auth.EmailVerification.Required {
+					bots.POST("", middleware.RequireVerifiedEmail(), gw.CreateBot)
+				} else {
+					bots.POST("", gw.CreateBot)
+				}
 				bots.GET("/:id", gw.GetBot)
-				bots.PUT("/:id", gw.UpdateBot)
+				bots.PUT("/:id", middleware.RequireAllowedIP(), gw.UpdateBot)
 				bots.DELETE("/:id", gw.DeleteBot)
-				bots.POST("/:id/start", gw.StartBot)
-				bots.POST("/:id/stop", gw.StopBot)
-				bots.GET("/:id/logs", gw.GetBotLogs)
+				bots.POST("/:id/start", middleware.RequireAllowedIP(), gw.StartBot)
+				bots.POST("/:id/stop", middleware.RequireAllowedIP(), gw.StopBot)
+				bots.GET("/:id/logs", changelog.Deprecate(http.MethodGet, "/api/v1/bots/:id/logs",
+					"Replaced by the signal history and the bot events of GET /api/v1/stream",
+					middleware.Deprecation{
+						Since:       time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+						Sunset:      time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+						Replacement: "GET /api/v1/bots/:id/signals",
+					}), gw.GetBotLogs)
+				bots.PUT("/:id/tags", gw.SetBotTags)
+				bots.PUT("/:id/rate-limits", gw.SetBotRateLimits)
+				bots.PUT("/:id/exchange-key", middleware.RequireAllowedIP(), gw.SetBotAPIKey)
+				bots.POST("/:id/preview", gw.PreviewBot)
+				bots.POST("/:id/sweep", gw.SweepBot)
+				bots.POST("/:id/backtest", gw.BacktestBot)
+				bots.GET("/:id/signals", gw.ListBotSignals)
+				bots.GET("/:id/signals/:signal_id/trace", gw.GetSignalTrace)
+				bots.PUT("/:id/org", gw.MoveBot)
 			}
 
 			// Strategy routes
 			strategies := protected.Group("/strategies")
+			strategies.Use(middleware.OrgContext(gw.Orgs))
 			{
 				strategies.GET("", gw.ListStrategies)
 				strategies.POST("", gw.CreateStrategy)
@@ -136,6 +408,138 @@ func setupRouter(gw *gateway.Gateway) *gin.Engine {
 				strategies.PUT("/:id", gw.UpdateStrategy)
 				strategies.DELETE("/:id", gw.DeleteStrategy)
 				strategies.POST("/:id/backtest", gw.BacktestStrategy)
+				strategies.PUT("/:id/tags", gw.SetStrategyTags)
+				strategies.PUT("/:id/org", gw.MoveStrategy)
+			}
+
+			// Parameter sweeps wait in a fair queue across users
+			sweeps := protected.Group("/backtest/jobs")
+			{
+				sweeps.GET("", gw.ListSweepJobs)
+				sweeps.GET("/:id", gw.GetSweepJob)
+				sweeps.DELETE("/:id", gw.CancelSweepJob)
+			}
+
+			// Organizations share bots and strategies among their members
+			orgs := protected.Group("/orgs")
+			{
+				orgs.GET("", gw.ListOrgs)
+				orgs.POST("", gw.CreateOrg)
+				orgs.GET("/:id", gw.GetOrg)
+				orgs.DELETE("/:id", gw.DeleteOrg)
+				orgs.GET("/:id/members", gw.ListOrgMembers)
+				orgs.POST("/:id/members", gw.AddOrgMember)
+				orgs.PUT("/:id/members/:user_id", gw.SetOrgMemberRole)
+				orgs.DELETE("/:id/members/:user_id", gw.RemoveOrgMember)
+				orgs.GET("/:id/invitations", gw.ListOrgInvitations)
+				orgs.POST("/:id/invitations", gw.InviteToOrg)
+				orgs.DELETE("/:id/invitations/:invitation_id", gw.RevokeOrgInvitation)
+				orgs.GET("/:id/exchange-keys", gw.ListOrgAPIKeys)
+				orgs.POST("/:id/exchange-keys", middleware.RequireAllowedIP(), gw.CreateOrgAPIKey)
+				orgs.DELETE("/:id/exchange-keys/:key_id", middleware.RequireAllowedIP(), gw.DeleteOrgAPIKey)
+				orgs.GET("/:id/exchange-keys/:key_id/grants", gw.ListOrgAPIKeyGrants)
+				orgs.POST("/:id/exchange-keys/:key_id/grants", middleware.RequireAllowedIP(), gw.GrantOrgAPIKey)
+				orgs.DELETE("/:id/exchange-keys/:key_id/grants/:grant_id", gw.RevokeOrgAPIKeyGrant)
+				orgs.GET("/:id/exchange-key-events", gw.ListOrgAPIKeyEvents)
+				orgs.GET("/:id/withdrawal-addresses", gw.ListWithdrawalAddresses)
+				orgs.POST("/:id/withdrawal-addresses", middleware.RequireAllowedIP(), gw.AddWithdrawalAddress)
+				orgs.DELETE("/:id/withdrawal-addresses/:address_id", middleware.RequireAllowedIP(), gw.DeleteWithdrawalAddress)
+				orgs.GET("/:id/sso/connections", gw.ListOrgSSOConnections)
+				orgs.POST("/:id/sso/connections", gw.CreateOrgSSOConnection)
+				orgs.DELETE("/:id/sso/connections/:connection_id", gw.DeleteOrgSSOConnection)
+			}
+
+			// Invitations to the user's email address
+			invitations := protected.Group("/invitations")
+			invitations.Use(middleware.RequireVerifiedEmail())
+			{
+				invitations.GET("", gw.ListInvitations)
+				invitations.POST("/:id/accept", gw.AcceptInvitation)
+				invitations.POST("/:id/decline", gw.DeclineInvitation)
+			}
+
+			// Approval requests
+			approvals := protected.Group("/approvals")
+			{
+				approvals.GET("", gw.ListApprovals)
+				approvals.POST("/:id/approve", gw.ApproveRequest)
+				approvals.POST("/:id/reject", gw.RejectRequest)
+			}
+
+			// Copy trading
+			copyRoutes := protected.Group("/copy/follows")
+			{
+				copyRoutes.GET("", gw.ListFollows)
+				copyRoutes.POST("", middleware.RequireAllowedIP(), gw.CreateFollow)
+				copyRoutes.PUT("/:id/allocation", middleware.RequireAllowedIP(), gw.UpdateFollowAllocation)
+				copyRoutes.GET("/:id/allocation/changes", gw.ListAllocationChanges)
+				copyRoutes.GET("/:id/settlements", gw.ListFollowSettlements)
+				copyRoutes.DELETE("/:id", gw.DeleteFollow)
+			}
+			protected.GET("/copy/leaderboard", gw.GetLeaderboard)
+			protected.GET("/copy/payouts", gw.GetPayoutStatement)
+
+			// Invoices and receipts
+			invoices := protected.Group("/billing/invoices")
+			{
+				invoices.GET("", gw.ListInvoices)
+				invoices.GET("/:id", gw.GetInvoice)
+				invoices.GET("/:id/pdf", gw.DownloadInvoice)
+			}
+
+			// Share links
+			shares := protected.Group("/shares")
+			shares.Use(middleware.OrgContext(gw.Orgs))
+			{
+				shares.GET("", gw.ListShareLinks)
+				shares.POST("", gw.CreateShareLink)
+				shares.DELETE("/:id", gw.RevokeShareLink)
+			}
+
+			// Recycle bin
+			trash := protected.Group("/trash")
+			trash.Use(middleware.OrgContext(gw.Orgs))
+			{
+				trash.GET("", gw.ListTrash)
+				trash.POST("/:id/restore", gw.RestoreTrash)
+			}
+
+			// Global search
+			protected.GET("/search", gw.Search)
+
+			// Staff routes are authorized by the user's roles rather than
+			// the admin API key
+			staff := protected.Group("/admin")
+			{
+				staff.GET("/users", middleware.RequirePermission(auth.PermissionUsersRead), gw.ListUsers)
+				staff.PUT("/users/:id/roles", middleware.RequirePermission(auth.PermissionUsersManage), gw.SetUserRoles)
+				staff.PUT("/users/:id/plan", middleware.RequirePermission(auth.PermissionUsersManage), gw.SetUserPlan)
+				staff.POST("/users/:id/deactivate", middleware.RequirePermission(auth.PermissionUsersManage), gw.DeactivateUser)
+				staff.POST("/users/:id/reactivate", middleware.RequirePermission(auth.PermissionUsersManage), gw.ReactivateUser)
+				staff.POST("/users/:id/password-reset", middleware.RequirePermission(auth.PermissionUsersManage), gw.ForcePasswordReset)
+				staff.GET("/users/:id/sessions", middleware.RequirePermission(auth.PermissionUsersRead), gw.ListUserSessions)
+				staff.GET("/users/:id/audit-events", middleware.RequirePermission(auth.PermissionAuditRead), gw.ListAuditEvents)
+				staff.POST("/bots/halt", middleware.RequirePermission(auth.PermissionBotsHalt), gw.HaltBots)
+				staff.GET("/audit-events", middleware.RequirePermission(auth.PermissionAuditRead), gw.ListAuditEvents)
+				staff.GET("/sso/connections", middleware.RequirePermission(auth.PermissionSSOManage), gw.ListSSOConnections)
+				staff.DELETE("/sso/connections/:id", middleware.RequirePermission(auth.PermissionSSOManage), gw.DeleteSSOConnection)
+				// Flags are decided by a signed-in reviewer, who is recorded
+				// and cannot decide flags on their own account
+				staff.GET("/copy/flags", middleware.RequirePermission(auth.PermissionCopyFlagsReview), gw.ListLeaderFlags)
+				staff.POST("/copy/flags/:id/confirm", middleware.RequirePermission(auth.PermissionCopyFlagsReview), gw.ConfirmLeaderFlag)
+				staff.POST("/copy/flags/:id/dismiss", middleware.RequirePermission(auth.PermissionCopyFlagsReview), gw.DismissLeaderFlag)
+			}
+
+			// Server-sent events of the user's bots
+			protected.GET("/stream", gw.Stream)
+			protected.GET("/stream/ws", gw.StreamWebSocket)
+
+			// Saved tag filters
+			filters := protected.Group("/filters")
+			{
+				filters.GET("", gw.ListSavedFilters)
+				filters.POST("", gw.CreateSavedFilter)
+				filters.DELETE("/:id", gw.DeleteSavedFilter)
 			}
 
 			// Market data routes
@@ -144,19 +548,69 @@ func setupRouter(gw *gateway.Gateway) *gin.Engine {
 				market.GET("/symbols", gw.GetSymbols)
 				market.GET("/ticker/:symbol", gw.GetTicker)
 				market.GET("/candles/:symbol", gw.GetCandles)
+				market.GET("/chart/:symbol", gw.GetChart)
 				market.GET("/orderbook/:symbol", gw.GetOrderBook)
 			}
 
 			// Portfolio routes
 			portfolio := protected.Group("/portfolio")
+			portfolio.Use(middleware.OrgContext(gw.Orgs))
 			{
 				portfolio.GET("", gw.GetPortfolio)
 				portfolio.GET("/positions", gw.GetPositions)
 				portfolio.GET("/orders", gw.GetOrders)
 				portfolio.GET("/trades", gw.GetTrades)
+				portfolio.GET("/trades/export", gw.ExportTrades)
 				portfolio.GET("/performance", gw.GetPerformance)
 			}
 		}
+
+		// Trading routes queue briefly instead of failing when a user
+		// bursts over the limit, and are closed to addresses outside the
+		// user's IP allowlist whatever its mode
+		trading := authenticated.Group("")
+		trading.Use(middleware.RequireAllowedIP())
+		trading.Use(middleware.RateLimitWithQueue(tradingLimiter, gw.AccessList, cfg.RateLimit.QueueMaxWait))
+		{
+			// Bulk order routes
+			orderRoutes := trading.Group("/orders")
+			orderRoutes.Use(middleware.OrgContext(gw.Orgs))
+			{
+				orderRoutes.POST("/cancel-all", gw.CancelAllOrders)
+				orderRoutes.POST("/groups", gw.CreateOrderGroup)
+				orderRoutes.GET("/groups", gw.ListOrderGroups)
+				orderRoutes.GET("/groups/:id", gw.GetOrderGroup)
+				orderRoutes.DELETE("/groups/:id", gw.CancelOrderGroup)
+			}
+
+			positions := trading.Group("/positions")
+			positions.Use(middleware.OrgContext(gw.Orgs))
+			{
+				positions.POST("/flatten", gw.FlattenPositions)
+			}
+
+			// Paper accounts can be made to misbehave like real exchanges
+			paper := trading.Group("/paper")
+			paper.Use(middleware.OrgContext(gw.Orgs))
+			{
+				paper.GET("/chaos/:exchange", gw.GetPaperChaos)
+				paper.PUT("/chaos/:exchange", gw.SetPaperChaos)
+			}
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AdminAuth(cfg.Admin.APIKey))
+		{
+			admin.GET("/ratelimit/lists", gw.GetAccessLists)
+			admin.POST("/ratelimit/lists/:list", gw.AddAccessListEntry)
+			admin.DELETE("/ratelimit/lists/:list", gw.RemoveAccessListEntry)
+			admin.GET("/exchanges/latency", gw.GetExchangeLatencies)
+			admin.GET("/topology", gw.GetTopology)
+			admin.POST("/tokens/introspect", gw.IntrospectToken)
+			admin.GET("/copy/commissions", gw.GetPlatformCommission)
+			admin.POST("/billing/invoices/:id/payments", gw.RecordInvoicePayment)
+		}
 	}
 
 	return router
@@ -167,32 +621,156 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
 	"github.com/tradingbothub/platform/api/proto/auth"
+	backtestpb "github.com/tradingbothub/platform/api/proto/backtest"
+	"github.com/tradingbothub/platform/internal/approval"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/avatar"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/billing"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/cache"
 	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/copytrade"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/email"
+	"github.com/tradingbothub/platform/internal/equity"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/faults"
+	"github.com/tradingbothub/platform/internal/hub"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/metering"
+	"github.com/tradingbothub/platform/internal/middleware"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"github.com/tradingbothub/platform/internal/orderbook"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/internal/residency"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/internal/search"
+	"github.com/tradingbothub/platform/internal/share"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/tags"
+	"github.com/tradingbothub/platform/internal/warmup"
+	"github.com/tradingbothub/platform/pkg/objectstore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type Gateway struct {
-	config     *config.Config
-	AuthClient authpb.AuthServiceClient
-	authConn   *grpc.ClientConn
+	config      *config.Config
+	AuthClient  authpb.AuthServiceClient
+	authConn    *grpc.ClientConn
+	redis       redis.UniversalClient
+	Tokens      *cache.TokenCache
+	Keys        *auth.KeySet
+	canary      *CanaryRouter
+	AccessList  *middleware.AccessList
+	Drainer     *middleware.Drainer
+	Metering    *metering.Recorder
+	Exchanges   *exchange.Router
+	clients     *exchange.Registry
+	oms         *orders.OMS
+	apiKeys     exchange.KeyRepository
+	keyGrants   exchange.GrantRepository
+	withdrawals exchange.WithdrawalAddressRepository
+	keyAuth     *exchange.KeyAuthorizer
+	auditor     *auth.Auditor
+	follows     copytrade.Repository
+	flags       copytrade.FlagRepository
+	settlements copytrade.SettlementRepository
+	invoices    *billing.Service
+	bulk        *orders.BulkService
+	groups      *orders.GroupService
+	db          *gorm.DB
+	residency   *residency.Router
+	influx      *marketdata.InfluxStore
+	candles     marketdata.CandleStore
+	orderBooks  *orderbook.Store
+	bots        bot.Repository
+	signals     bot.SignalStore
+	strategies  strategy.Repository
+	Orgs        *org.Service
+	tags        tags.Repository
+	search      *search.Service
+	approvals   *approval.Service
+	shares      *share.Service
+	equity      *equity.InfluxStore
+	Objects     objectstore.Store
+	avatars     *avatar.Service
+
+	// Cookies keeps the sessions of cookie clients; nil when disabled
+	Cookies *middleware.CookieSessions
+
+	// BacktestClient streams single backtests from the backtest service
+	BacktestClient backtestpb.BacktestServiceClient
+	backtestConn   *grpc.ClientConn
+	// sweeps queues parameter sweeps, which run on the backtest service
+	sweeps *backtest.Queue
+
+	// Warmer gates readiness on the startup tasks
+	Warmer *warmup.Warmer
+
+	nats     *nats.Conn
+	registry *registry.Registry
+	// stopAnnouncing ends the heartbeat and waits for the leave message
+	stopAnnouncing func()
+	// stopGroups ends the order group sync loop
+	stopGroups func()
+	// stopFills ends the fill reconciliation loop
+	stopFills func()
+	// stopKeys ends the JWKS refresh loop
+	stopKeys func()
+	// stopProbes ends the exchange connector probes
+	stopProbes func()
+
+	// Streaming endpoints fan out through the hub
+	stream     *hub.Hub
+	streamSubs []*nats.Subscription
+
+	// Demo paper wallets are refilled once per reset generation
+	demoMutex      sync.Mutex
+	demoGeneration int64
+	demoFunded     bool
 }
 
 func New(cfg *config.Config) (*Gateway, error) {
 	gw := &Gateway{
-		config: cfg,
+		config:    cfg,
+		Drainer:   middleware.NewDrainer(cfg.Server.DrainDelay),
+		Exchanges: exchange.NewRouter(cfg.Exchanges, cfg.Trading.ConnectorTimeout),
 	}
 
+	// Fault injection for resilience testing; a no-op unless enabled
+	injector := faults.New(cfg.Faults)
+	events.WrapConsumers(injector.WrapHandler)
+
 	// Connect to Auth Service
 	authConn, err := grpc.Dial(
 		"localhost"+cfg.Auth.Port,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		append(rpc.DialOptions(cfg.GRPC, "auth"),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(injector.UnaryClientInterceptor()),
+		)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
@@ -200,57 +778,383 @@ func New(cfg *config.Config) (*Gateway, error) {
 
 	gw.authConn = authConn
 	gw.AuthClient = authpb.NewAuthServiceClient(authConn)
+	gw.Keys = auth.NewKeySet()
+	gw.stopKeys = gw.syncKeys(cfg.Auth.JWKSRefreshInterval)
+
+	// Connect to canary backends
+	canary, err := NewCanaryRouter(cfg.Canary, cfg.GRPC, grpc.WithUnaryInterceptor(injector.UnaryClientInterceptor()))
+	if err != nil {
+		authConn.Close()
+		return nil, err
+	}
+	gw.canary = canary
+
+	// Exchange clients reach the connectors, which in paper mode are the
+	// paper exchange service
+	precisions, err := exchange.NewPrecisions(cfg.Exchanges)
+	if err != nil {
+		authConn.Close()
+		canary.Close()
+		return nil, fmt.Errorf("invalid exchange precision: %w", err)
+	}
+	gw.clients = exchange.NewRegistry()
+	for name := range cfg.Exchanges {
+		policy := exchange.RetryPolicy(name, cfg.Trading.ExchangeRetry)
+		gw.clients.Register(name, exchange.WithRetry(gw.Exchanges.Client(name, false), policy))
+		gw.clients.RegisterTestnet(name, exchange.WithRetry(gw.Exchanges.Client(name, true), policy))
+	}
+	probeCtx, stopProbes := context.WithCancel(context.Background())
+	go gw.Exchanges.Probe(probeCtx, cfg.Trading.ConnectorProbeInterval)
+	gw.stopProbes = stopProbes
+
+	// Connect to Redis
+	redisClient, err := cache.Connect(cfg.Redis)
+	if err != nil {
+		authConn.Close()
+		canary.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	// Connect to database for history and user data queries
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		authConn.Close()
+		canary.Close()
+		redisClient.Close()
+		return nil, err
+	}
+
+	gw.db = db
+
+	// Trade data lives in the user's data region
+	gw.residency, err = residency.NewRouter(cfg.DataResidency, db)
+	if err != nil {
+		authConn.Close()
+		canary.Close()
+		redisClient.Close()
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+
+	gw.bots = bot.NewRepository(db)
+	gw.signals = bot.NewSignalStore(db)
+	gw.strategies = strategy.NewRepository(db)
+
+	// Organizations mail their invitations
+	mailer, err := email.New(cfg.Email)
+	if err != nil {
+		authConn.Close()
+		canary.Close()
+		redisClient.Close()
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+	gw.Orgs = org.NewService(org.NewRepository(db), org.NewInvitationRepository(db), mailer, cfg.Orgs.InvitationTTL, cfg.Orgs.InvitationURL)
+
+	gw.tags = tags.NewRepository(db)
+	gw.apiKeys = exchange.NewKeyRepository(db)
+	gw.keyGrants = exchange.NewGrantRepository(db)
+	gw.withdrawals = exchange.NewWithdrawalAddressRepository(db)
+	gw.keyAuth = exchange.NewKeyAuthorizer(gw.apiKeys, gw.keyGrants, org.NewRepository(db))
+	gw.auditor = auth.NewAuditor(auth.NewAuditRepository(db))
+	gw.follows = copytrade.NewRepository(db)
+	gw.flags = copytrade.NewFlagRepository(db)
+	gw.settlements = copytrade.NewSettlementRepository(db)
+
+	// Bulk operations and order group legs go through the OMS, which
+	// records their orders and fills. Order groups are driven here since
+	// the gateway owns the exchange clients their legs are placed with
+	gw.oms = orders.NewOMS(gw.clients, precisions, orders.NewRepository(db))
+	gw.bulk = orders.NewBulkService(gw.oms, cfg.Trading.BulkConcurrency)
+	gw.groups = orders.NewGroupService(db, gw.oms)
+	gw.stopGroups = gw.syncGroups(cfg.Trading.GroupSyncInterval)
+	gw.stopFills = gw.syncFills(cfg.Trading.FillSyncInterval)
+
+	symbols := make(map[string][]string, len(cfg.Exchanges))
+	for name, exchangeCfg := range cfg.Exchanges {
+		symbols[name] = exchangeCfg.Symbols
+	}
+	gw.search = search.NewService(db, symbols)
+
+	// Two-person approvals
+	gw.approvals = approval.NewService(approval.NewRepository(db), approval.OrgAdmins{
+		Orgs:   gw.Orgs,
+		Static: approval.StaticAdmins(cfg.Approvals.Organizations),
+	}, cfg.Approvals.TTL)
+	gw.approvals.Register(approval.ActionDeployLiveBot, gw.deployLiveBot)
+	gw.approvals.Register(approval.ActionRaiseBotLimits, gw.raiseBotLimits)
+	gw.approvals.Register(approval.ActionAddWithdrawalAddress, gw.addWithdrawalAddress)
+
+	// Public share links
+	gw.shares = share.NewService(db, cfg.Share.Secret)
+	gw.equity = equity.NewInfluxStore(cfg.InfluxDB)
+
+	// Market data
+	gw.influx = marketdata.NewInfluxStore(cfg.InfluxDB)
+	// Previews replay the same history over and over
+	gw.candles = marketdata.NewCandleCache(gw.influx, cfg.CandleCache.MaxBytes)
+
+	// Connect to Backtest Service
+	gw.backtestConn, err = grpc.Dial(
+		"localhost"+cfg.Backtest.Port,
+		append(rpc.DialOptions(cfg.GRPC, "backtest"),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(injector.UnaryClientInterceptor()),
+		)...,
+	)
+	if err != nil {
+		gw.Close()
+		return nil, fmt.Errorf("failed to connect to backtest service: %w", err)
+	}
+	gw.BacktestClient = backtestpb.NewBacktestServiceClient(gw.backtestConn)
+	gw.sweeps = backtest.NewQueue(db, cfg.Backtest)
+
+	gw.redis = redisClient
+	gw.Tokens = cache.NewTokenCache(redisClient, cfg.Auth.TokenCacheTTL)
+	gw.invoices = billing.NewService(billing.NewRepository(db), billing.NewAuthPlans(gw.AuthClient, cfg.Auth.ServiceKey))
+	gw.Cookies, err = newCookieSessions(cfg.Auth.CookieSessions, redisClient, gw.AuthClient)
+	if err != nil {
+		gw.Close()
+		return nil, err
+	}
+	gw.AccessList = middleware.NewAccessList(
+		middleware.NewRedisAccessListStore(redisClient),
+		cfg.RateLimit.Allowlist,
+		cfg.RateLimit.Denylist,
+		cfg.RateLimit.SyncInterval,
+	)
+	gw.Metering = metering.NewRecorder(redisClient, cfg.Metering.Retention, cfg.Metering.BufferSize)
+
+	// Service registry: announce ourselves and collect the topology
+	natsConn, err := messaging.Connect(cfg.NATS, "api-gateway")
+	if err != nil {
+		gw.Close()
+		return nil, err
+	}
+	gw.nats = natsConn
+	gw.registry = registry.NewRegistry(cfg.Registry.TTL)
+	if err := gw.registry.Subscribe(natsConn); err != nil {
+		gw.Close()
+		return nil, fmt.Errorf("failed to subscribe to service registry: %w", err)
+	}
+	gw.stopAnnouncing = gw.announce(cfg)
+
+	// Artifact storage
+	gw.Objects, err = objectstore.New(context.Background(), cfg.ObjectStore)
+	if err != nil {
+		gw.Close()
+		return nil, fmt.Errorf("failed to open object store: %w", err)
+	}
+	gw.avatars = avatar.NewService(gw.Objects, cfg.Avatars)
+
+	// Streaming hub
+	gw.stream = hub.New("user-events", hub.Config{
+		BufferSize:   cfg.Streaming.BufferSize,
+		WriteTimeout: cfg.Streaming.WriteTimeout,
+	})
+	if err := gw.startStreams(); err != nil {
+		gw.Close()
+		return nil, err
+	}
+	gw.orderBooks = orderbook.NewStore(cfg.OrderBooks.Shards, cfg.OrderBooks.MaxDepth)
+	if err := gw.startOrderBooks(); err != nil {
+		gw.Close()
+		return nil, err
+	}
+
+	gw.warmUp(cfg)
 
 	return gw, nil
 }
 
 func (gw *Gateway) Close() {
+	if gw.Warmer != nil {
+		gw.Warmer.Stop()
+	}
+	if gw.stopAnnouncing != nil {
+		gw.stopAnnouncing()
+	}
+	if gw.stopGroups != nil {
+		gw.stopGroups()
+	}
+	if gw.stopFills != nil {
+		gw.stopFills()
+	}
+	if gw.stopKeys != nil {
+		gw.stopKeys()
+	}
+	if gw.stopProbes != nil {
+		gw.stopProbes()
+	}
+	for _, sub := range gw.streamSubs {
+		sub.Unsubscribe()
+	}
+	if gw.stream != nil {
+		gw.stream.Close()
+	}
+	if gw.registry != nil {
+		gw.registry.Close()
+	}
+	if gw.Objects != nil {
+		gw.Objects.Close()
+	}
+	if gw.nats != nil {
+		gw.nats.Close()
+	}
 	if gw.authConn != nil {
 		gw.authConn.Close()
 	}
+	if gw.backtestConn != nil {
+		gw.backtestConn.Close()
+	}
+	if gw.canary != nil {
+		gw.canary.Close()
+	}
+	if gw.Metering != nil {
+		gw.Metering.Close()
+	}
+	if gw.redis != nil {
+		gw.redis.Close()
+	}
+	if gw.influx != nil {
+		gw.influx.Close()
+	}
+	if gw.equity != nil {
+		gw.equity.Close()
+	}
+	if gw.residency != nil {
+		gw.residency.Close()
+	}
+	if gw.db != nil {
+		if sqlDB, err := gw.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
 }
 
 // Auth handlers
 func (gw *Gateway) Register(c *gin.Context) {
-	var req authpb.RegisterRequest
+	var req openapi.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := gw.AuthClient.Register(context.Background(), &req)
+	resp, err := gw.AuthClient.Register(c.Request.Context(), &authpb.RegisterRequest{
+		Email:     string(req.Email),
+		Username:  req.Username,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		ClientIp:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+		Country:   gw.clientCountry(c),
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, resp)
+	gw.signedIn(c, http.StatusCreated, resp)
+}
+
+// CheckAvailability tells a signup form whether the email and username
+// query parameters are still free. Only the values given are checked.
+func (gw *Gateway) CheckAvailability(c *gin.Context) {
+	email, username := c.Query("email"), c.Query("username")
+	if email == "" && username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email or username query parameter required"})
+		return
+	}
+
+	resp, err := gw.AuthClient.CheckAvailability(c.Request.Context(), &authpb.CheckAvailabilityRequest{
+		Email:    email,
+		Username: username,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func (gw *Gateway) Login(c *gin.Context) {
-	var req authpb.LoginRequest
+	var req openapi.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := gw.AuthClient.Login(context.Background(), &req)
+	resp, err := gw.AuthClient.Login(c.Request.Context(), &authpb.LoginRequest{
+		Email:     string(req.Email),
+		Password:  req.Password,
+		Scopes:    loginScopes(req.Scopes),
+		ClientIp:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+		Country:   gw.clientCountry(c),
+	})
+	if status.Code(err) == codes.ResourceExhausted {
+		// Locked out after too many failures
+		for _, detail := range status.Convert(err).Details() {
+			if retry, ok := detail.(*errdetails.RetryInfo); ok {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.RetryDelay.AsDuration().Seconds()))))
+			}
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
+	if status.Code(err) == codes.InvalidArgument {
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
+	if status.Code(err) == codes.FailedPrecondition || status.Code(err) == codes.PermissionDenied {
+		// Password reset required or account deactivated
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	gw.signedIn(c, http.StatusOK, resp)
+}
+
+// loginScopes converts the requested scopes for the auth service, which
+// rejects unknown ones.
+func loginScopes(scopes []openapi.LoginRequestScopes) []string {
+	out := make([]string, len(scopes))
+	for i, scope := range scopes {
+		out[i] = string(scope)
+	}
+	return out
 }
 
 func (gw *Gateway) RefreshToken(c *gin.Context) {
-	var req authpb.RefreshTokenRequest
+	var req openapi.RefreshTokenJSONBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := gw.AuthClient.RefreshToken(context.Background(), &req)
+	resp, err := gw.AuthClient.RefreshToken(c.Request.Context(), &authpb.RefreshTokenRequest{
+		RefreshToken: req.RefreshToken,
+	})
+	if status.Code(err) == codes.PermissionDenied {
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
@@ -259,6 +1163,163 @@ func (gw *Gateway) RefreshToken(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// Logout revokes the caller's access token, which also drops it from the
+// token cache.
+func (gw *Gateway) Logout(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	resp, err := gw.authClientFor(c).Logout(c.Request.Context(), &authpb.LogoutRequest{AccessToken: token})
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+	if gw.Cookies != nil {
+		gw.Cookies.End(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+// RevokeSessions signs the caller out of every session.
+func (gw *Gateway) RevokeSessions(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	resp, err := gw.authClientFor(c).RevokeSessions(c.Request.Context(), &authpb.RevokeSessionsRequest{AccessToken: token})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+func (gw *Gateway) VerifyEmail(c *gin.Context) {
+	var req openapi.VerifyEmailJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.VerifyEmail(c.Request.Context(), &authpb.VerifyEmailRequest{Token: req.Token})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+func (gw *Gateway) ResendVerification(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	resp, err := gw.authClientFor(c).ResendVerification(c.Request.Context(), &authpb.ResendVerificationRequest{AccessToken: token})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.FailedPrecondition:
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+		case codes.Unauthenticated:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": resp.Message})
+}
+
+func (gw *Gateway) ForgotPassword(c *gin.Context) {
+	var req openapi.ForgotPasswordJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.ForgotPassword(c.Request.Context(), &authpb.ForgotPasswordRequest{Email: string(req.Email)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request password reset"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": resp.Message})
+}
+
+func (gw *Gateway) ResetPassword(c *gin.Context) {
+	var req openapi.ResetPasswordJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.ResetPassword(c.Request.Context(), &authpb.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+func (gw *Gateway) RequestMagicLink(c *gin.Context) {
+	var req openapi.RequestMagicLinkJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.RequestMagicLink(c.Request.Context(), &authpb.RequestMagicLinkRequest{Email: string(req.Email)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request sign-in link"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": resp.Message})
+}
+
+// ConsumeMagicLink signs in with the token of an emailed sign-in link and
+// answers like Login.
+func (gw *Gateway) ConsumeMagicLink(c *gin.Context) {
+	var req openapi.ConsumeMagicLinkJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.MagicLinkLogin(c.Request.Context(), &authpb.MagicLinkLoginRequest{
+		Token:     req.Token,
+		ClientIp:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+		Country:   gw.clientCountry(c),
+	})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+			return
+		}
+		if status.Code(err) == codes.FailedPrecondition || status.Code(err) == codes.PermissionDenied {
+			c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	gw.signedIn(c, http.StatusOK, resp)
+}
+
 // User handlers (placeholder implementations)
 func (gw *Gateway) GetProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -273,34 +1334,50 @@ func (gw *Gateway) UpdateProfile(c *gin.Context) {
 }
 
 func (gw *Gateway) ChangePassword(c *gin.Context) {
-	var req authpb.ChangePasswordRequest
+	var req openapi.ChangePasswordJSONBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get token from header
-	token := c.GetHeader("Authorization")
-	if len(token) > 7 && token[:7] == "Bearer " {
-		req.AccessToken = token[7:]
-	}
-
-	resp, err := gw.AuthClient.ChangePassword(context.Background(), &req)
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	resp, err := gw.authClientFor(c).ChangePassword(c.Request.Context(), &authpb.ChangePasswordRequest{
+		AccessToken: token,
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+		case codes.PermissionDenied:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		case codes.Unauthenticated:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
 }
 
 // Bot handlers (placeholder implementations)
 func (gw *Gateway) ListBots(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "List bots - implementation needed"})
-}
+	filter, err := gw.tagFilter(c, tags.ResourceBot)
+	if err != nil {
+		gw.tagsError(c, err)
+		return
+	}
 
-func (gw *Gateway) CreateBot(c *gin.Context) {
-	c.JSON(http.StatusCreated, gin.H{"message": "Create bot - implementation needed"})
+	bots, err := gw.bots.List(c.Request.Context(), workspace(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bots": bots})
 }
 
 func (gw *Gateway) GetBot(c *gin.Context) {
@@ -316,15 +1393,86 @@ func (gw *Gateway) UpdateBot(c *gin.Context) {
 }
 
 func (gw *Gateway) DeleteBot(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Delete bot - implementation needed"})
+	ctx := c.Request.Context()
+	owner := workspace(c)
+
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !owner.Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+	if b.Status == bot.StatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stop the bot before deleting it"})
+		return
+	}
+
+	if err := gw.bots.Delete(ctx, owner, b.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bot moved to trash"})
 }
 
 func (gw *Gateway) StartBot(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Start bot - implementation needed"})
+	ctx := c.Request.Context()
+	owner := workspace(c)
+
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !owner.Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+	if b.Status == bot.StatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bot is already running"})
+		return
+	}
+	if !gw.authorizeBotKey(c, b) {
+		return
+	}
+
+	// Large live bots of organizations need a second admin
+	req, err := gw.requestLiveBotApproval(c, b)
+	if errors.Is(err, errNoApprover) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Live bots of this size must belong to an organization, whose admins approve them"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request approval"})
+		return
+	}
+	if req != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "Starting this bot requires approval by another admin",
+			"approval": req,
+		})
+		return
+	}
+
+	if err := gw.bots.Start(ctx, owner, b.ID, c.GetString("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start bot"})
+		return
+	}
+	gw.publishBotStatus(b.UserID, b.ID, bot.StatusRunning)
+	gw.recordBotStart(ctx, b, c.GetString("user_id"))
+
+	c.JSON(http.StatusOK, gin.H{"id": b.ID, "status": bot.StatusRunning})
 }
 
 func (gw *Gateway) StopBot(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Stop bot - implementation needed"})
+	userID := c.GetString("user_id")
+	err := gw.bots.SetStatus(c.Request.Context(), workspace(c), c.Param("id"), bot.StatusStopped)
+	if errors.Is(err, bot.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop bot"})
+		return
+	}
+	gw.publishBotStatus(userID, c.Param("id"), bot.StatusStopped)
+
+	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "status": bot.StatusStopped})
 }
 
 func (gw *Gateway) GetBotLogs(c *gin.Context) {
@@ -333,7 +1481,19 @@ func (gw *Gateway) GetBotLogs(c *gin.Context) {
 
 // Strategy handlers (placeholder implementations)
 func (gw *Gateway) ListStrategies(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "List strategies - implementation needed"})
+	filter, err := gw.tagFilter(c, tags.ResourceStrategy)
+	if err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	strategies, err := gw.strategies.List(c.Request.Context(), workspace(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list strategies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"strategies": strategies})
 }
 
 func (gw *Gateway) CreateStrategy(c *gin.Context) {
@@ -349,7 +1509,17 @@ func (gw *Gateway) UpdateStrategy(c *gin.Context) {
 }
 
 func (gw *Gateway) DeleteStrategy(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Delete strategy - implementation needed"})
+	err := gw.strategies.Delete(c.Request.Context(), workspace(c), c.Param("id"))
+	if errors.Is(err, strategy.ErrStrategyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete strategy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strategy moved to trash"})
 }
 
 func (gw *Gateway) BacktestStrategy(c *gin.Context) {
@@ -371,30 +1541,142 @@ func (gw *Gateway) GetTicker(c *gin.Context) {
 
 func (gw *Gateway) GetCandles(c *gin.Context) {
 	symbol := c.Param("symbol")
-	interval := c.DefaultQuery("interval", "1h")
-	limit := c.DefaultQuery("limit", "100")
-	
-	limitInt, _ := strconv.Atoi(limit)
-	
-	c.JSON(http.StatusOK, gin.H{
+	exchangeName := c.DefaultQuery("exchange", "binance")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	interval, err := marketdata.ParseInterval(c.DefaultQuery("interval", "1h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := gw.sessionLocation(c, exchangeName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+	}
+
+	// A page holds the newest limit buckets before to; older buckets of a
+	// longer range are fetched by passing next_to back as to
+	from := marketdata.BucketsBefore(to, interval, loc, limit-1)
+	scanFrom := from
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		if from.After(scanFrom) {
+			scanFrom = from
+		}
+	}
+	if !scanFrom.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+
+	base := marketdata.BaseInterval(interval, loc, to)
+	if to.Sub(scanFrom)/base.Duration > maxCandleScanPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query too expensive; request fewer candles"})
+		return
+	}
+
+	var candles []marketdata.Candle
+	if base.Name == interval.Name {
+		candles, err = gw.candles.Candles(c.Request.Context(), exchangeName, symbol, interval, scanFrom, to)
+		if n := len(candles); n > limit {
+			candles = candles[n-limit:]
+		}
+	} else {
+		candles, err = gw.influx.AggregatedCandles(c.Request.Context(), exchangeName, symbol, base, interval, loc, scanFrom, to, limit)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load candles"})
+		return
+	}
+
+	var nextTo string
+	if from.Before(scanFrom) {
+		next := scanFrom
+		if len(candles) > 0 {
+			next = candles[0].Time
+		}
+		nextTo = next.UTC().Format(time.RFC3339)
+	}
+
+	c.Header("Vary", "Accept")
+	if wantsBinaryCandles(c) {
+		c.Header("X-Candle-Interval", interval.Name)
+		c.Header("X-Candle-Timezone", loc.String())
+		if nextTo != "" {
+			c.Header("X-Next-To", nextTo)
+		}
+		c.Data(http.StatusOK, marketdata.CandlesMIME, marketdata.EncodeCandles(candles))
+		return
+	}
+
+	response := gin.H{
 		"symbol":   symbol,
-		"interval": interval,
-		"limit":    limitInt,
-		"message":  "Get candles - implementation needed",
-	})
+		"exchange": exchangeName,
+		"interval": interval.Name,
+		"timezone": loc.String(),
+		"candles":  candles,
+	}
+	if nextTo != "" {
+		response["next_to"] = nextTo
+	}
+	c.JSON(http.StatusOK, response)
 }
 
+// GetOrderBook returns the latest snapshot of a live order book, cut to
+// the requested depth per side.
 func (gw *Gateway) GetOrderBook(c *gin.Context) {
 	symbol := c.Param("symbol")
-	c.JSON(http.StatusOK, gin.H{
-		"symbol":  symbol,
-		"message": "Get order book - implementation needed",
-	})
+	exchangeName := c.DefaultQuery("exchange", "binance")
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", strconv.Itoa(defaultOrderBookDepth)))
+	if err != nil || depth <= 0 || depth > maxOrderBookDepth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxOrderBookDepth)})
+		return
+	}
+
+	snapshot, ok := gw.orderBooks.Snapshot(exchangeName, symbol)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No live order book for the symbol"})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot.Top(depth))
 }
 
 // Portfolio handlers (placeholder implementations)
+
+// GetPortfolio reads the user's precomputed holdings; nothing is
+// aggregated from trade history on the request path.
 func (gw *Gateway) GetPortfolio(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get portfolio - implementation needed"})
+	repo, err := gw.readOrdersFor(c)
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	holdings, err := repo.ListHoldings(c.Request.Context(), account(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load portfolio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders.NewPortfolio(holdings))
 }
 
 func (gw *Gateway) GetPositions(c *gin.Context) {
@@ -402,11 +1684,47 @@ func (gw *Gateway) GetPositions(c *gin.Context) {
 }
 
 func (gw *Gateway) GetOrders(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get orders - implementation needed"})
+	query, err := historyQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repo, err := gw.readOrdersFor(c)
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	page, err := repo.SearchOrders(c.Request.Context(), query)
+	if err != nil {
+		gw.historyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 func (gw *Gateway) GetTrades(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get trades - implementation needed"})
+	query, err := historyQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repo, err := gw.readOrdersFor(c)
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	page, err := repo.SearchTrades(c.Request.Context(), query)
+	if err != nil {
+		gw.historyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 func (gw *Gateway) GetPerformance(c *gin.Context) {
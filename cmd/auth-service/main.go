@@ -5,14 +5,23 @@ import (
 	"context"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	authpb "github.com/tradingbothub/platform/api/proto/auth"
 	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/cache"
 	"github.com/tradingbothub/platform/internal/config"
 	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/email"
+	"github.com/tradingbothub/platform/internal/faults"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/internal/warmup"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -30,14 +39,71 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Revocations and the gateway's token cache live in redis
+	redisClient, err := cache.Connect(cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to connect to redis: %v", err)
+	}
+	defer redisClient.Close()
+	tokens := cache.NewTokenCache(redisClient, cfg.Auth.TokenCacheTTL)
+
+	// Audit events and the service registry go over nats
+	natsConn, err := messaging.Connect(cfg.NATS, "auth-service")
+	if err != nil {
+		log.Fatalf("Failed to connect to nats: %v", err)
+	}
+	defer natsConn.Close()
+
 	// Initialize auth service
 	authRepo := auth.NewRepository(db)
-	tokenService := auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.ExpirationTime)
-	authService := auth.NewService(authRepo, tokenService)
+	tokenService, err := auth.NewJWTService(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to set up token signing: %v", err)
+	}
+	logins := auth.NewLoginWriter(db, cfg.WriteBatching)
+	audits := auth.NewAuditWriter(db, cfg.WriteBatching)
+	mailer, err := email.New(cfg.Email)
+	if err != nil {
+		log.Fatalf("Failed to set up email: %v", err)
+	}
+	hasher, err := auth.NewPasswordHasher(cfg.Auth.PasswordHashing)
+	if err != nil {
+		log.Fatalf("Failed to set up password hashing: %v", err)
+	}
+	verifier := auth.NewVerifier(auth.NewVerificationRepository(db), mailer,
+		cfg.Auth.EmailVerification.TTL, cfg.Auth.EmailVerification.URL)
+	resetter := auth.NewPasswordResetter(auth.NewPasswordResetRepository(db), mailer,
+		cfg.Auth.PasswordReset.TTL, cfg.Auth.PasswordReset.URL)
+	emailChanges := auth.NewEmailChanger(auth.NewEmailChangeRepository(db), mailer,
+		cfg.Auth.EmailChange.TTL, cfg.Auth.EmailChange.UndoTTL, cfg.Auth.EmailChange.URL, cfg.Auth.EmailChange.UndoURL)
+	magicLinks := auth.NewMagicLinker(auth.NewMagicLinkRepository(db), mailer,
+		cfg.Auth.MagicLink.TTL, cfg.Auth.MagicLink.URL)
+	sso := auth.NewSSO(auth.NewSSORepository(db), auth.NewOIDCClient(cfg.Auth.SSO.HTTPTimeout, cfg.Auth.SSO.CacheTTL),
+		cfg.Auth.SSO.StateTTL, cfg.Auth.SSO.RedirectURL)
+	// Email and username checks consult bloom filters in redis first
+	existence := auth.NewExistence(authRepo, redisClient, cfg.Auth.ExistenceFilter)
+	authService := auth.NewService(authRepo, tokenService, auth.NewSessionRepository(db), auth.NewRoleRepository(db),
+		existence, auth.NewLockout(redisClient, natsConn, cfg.Auth.Lockout), logins, verifier, resetter,
+		emailChanges, magicLinks, sso, auth.NewAuditor(audits),
+		auth.NewLoginMonitor(auth.NewLoginEventRepository(db), mailer, cfg.Auth.LoginAlerts),
+		auth.NewAuthMethodRepository(db), auth.NewIPAllowlistRepository(db),
+		hasher, cfg.Auth.ReauthWindow)
+	if err := authService.BootstrapRoles(context.Background(), cfg.Auth.Admins); err != nil {
+		log.Fatalf("Failed to set up roles: %v", err)
+	}
 
 	// Create gRPC server
-	s := grpc.NewServer()
-	authpb.RegisterAuthServiceServer(s, auth.NewGRPCServer(authService))
+	serverOptions := append(rpc.ServerOptions(cfg.GRPC), grpc.UnaryInterceptor(faults.New(cfg.Faults).UnaryServerInterceptor()))
+	s := grpc.NewServer(serverOptions...)
+	authpb.RegisterAuthServiceServer(s, auth.NewGRPCServer(authService, tokens, cfg.Auth.ServiceKey))
+
+	// gRPC health reports serving once the backends every call needs are
+	// reachable
+	warmer := warmup.New(cfg.Warmup)
+	warmer.Register(warmup.Postgres(db))
+	warmer.Register(warmup.Redis(redisClient))
+	warmer.Start(context.Background())
+	healthServer := rpc.ServeHealth(context.Background(), s, warmer)
 
 	// Enable reflection for development
 	reflection.Register(s)
@@ -48,7 +114,7 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	log.Printf("Auth service listening on %s", cfg.Auth.Port)
+	log.Printf("Auth service %s (%s) listening on %s (region %s)", buildinfo.Version, buildinfo.ShortCommit(), cfg.Auth.Port, cfg.Region)
 
 	// Graceful shutdown
 	go func() {
@@ -57,11 +123,63 @@ func main() {
 		}
 	}()
 
+	// Third parties verify tokens with the published public keys
+	jwks := http.NewServeMux()
+	jwks.HandleFunc("GET /.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(authService.JWKS())
+	})
+	jwksServer := &http.Server{
+		Addr:         cfg.Auth.JWKSPort,
+		Handler:      jwks,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	go func() {
+		log.Printf("Auth service serving JWKS on %s", cfg.Auth.JWKSPort)
+		if err := jwksServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve JWKS: %v", err)
+		}
+	}()
+
+	// Announce ourselves to the service registry
+	announceCtx, stopAnnouncing := context.WithCancel(context.Background())
+	announced := make(chan struct{})
+	go func() {
+		registry.NewAnnouncer(natsConn, registry.Instance{
+			Service:      "auth-service",
+			Region:       cfg.Region,
+			Address:      cfg.Auth.Port,
+			Dependencies: []string{"postgres", "redis", "nats"},
+		}, cfg.Registry.Interval, warmer.Health).Run(announceCtx)
+		close(announced)
+	}()
+
 	// Wait for interrupt signal
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
 	log.Println("Shutting down auth service...")
+	stopAnnouncing()
+	<-announced
+	healthServer.Shutdown()
+	warmer.Stop()
 	s.GracefulStop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := jwksServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to stop JWKS server: %v", err)
+	}
+
+	// Logins recorded by the last requests are still queued
+	if err := logins.Close(shutdownCtx); err != nil {
+		log.Printf("Failed to flush last-login updates: %v", err)
+	}
+	if err := audits.Close(shutdownCtx); err != nil {
+		log.Printf("Failed to flush audit events: %v", err)
+	}
 }
@@ -0,0 +1,118 @@
+// cmd/backtest-service/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	backtestpb "github.com/tradingbothub/platform/api/proto/backtest"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/faults"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/internal/warmup"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Sweeps are queued in the database by the gateway
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	candleStore := marketdata.NewInfluxStore(cfg.InfluxDB)
+	defer candleStore.Close()
+	// Backtests replay the same history over and over
+	candles := marketdata.NewCandleCache(candleStore, cfg.CandleCache.MaxBytes)
+
+	// Create gRPC server
+	serverOptions := append(rpc.ServerOptions(cfg.GRPC), grpc.UnaryInterceptor(faults.New(cfg.Faults).UnaryServerInterceptor()))
+	s := grpc.NewServer(serverOptions...)
+	backtestpb.RegisterBacktestServiceServer(s, backtest.NewGRPCServer(candles))
+
+	// Every backtest reads candles, so health waits for InfluxDB
+	warmer := warmup.New(cfg.Warmup)
+	warmer.Register(warmup.Postgres(db))
+	warmer.Register(warmup.Task{Name: "influxdb", Run: candleStore.Ping})
+	warmer.Start(context.Background())
+	healthServer := rpc.ServeHealth(context.Background(), s, warmer)
+
+	// Sweeps are only claimed once the stores they run on are reachable;
+	// on shutdown the ones still running go back to the queue
+	sweepCtx, stopSweeps := context.WithCancel(context.Background())
+	sweeps := make(chan struct{})
+	go func() {
+		defer close(sweeps)
+		if warmer.Wait(sweepCtx) != nil {
+			return
+		}
+		backtest.NewQueue(db, cfg.Backtest).Run(sweepCtx, registry.InstanceID(), candles)
+	}()
+
+	// Enable reflection for development
+	reflection.Register(s)
+
+	lis, err := net.Listen("tcp", cfg.Backtest.Port)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	log.Printf("Backtest service %s (%s) listening on %s (region %s)", buildinfo.Version, buildinfo.ShortCommit(), cfg.Backtest.Port, cfg.Region)
+
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	// Announce ourselves to the service registry
+	natsConn, err := messaging.Connect(cfg.NATS, "backtest-service")
+	if err != nil {
+		log.Fatalf("Failed to connect to nats: %v", err)
+	}
+	defer natsConn.Close()
+
+	announceCtx, stopAnnouncing := context.WithCancel(context.Background())
+	announced := make(chan struct{})
+	go func() {
+		registry.NewAnnouncer(natsConn, registry.Instance{
+			Service:      "backtest-service",
+			Region:       cfg.Region,
+			Address:      cfg.Backtest.Port,
+			Dependencies: []string{"postgres", "influxdb", "nats"},
+		}, cfg.Registry.Interval, warmer.Health).Run(announceCtx)
+		close(announced)
+	}()
+
+	// Wait for interrupt signal
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+
+	log.Println("Shutting down backtest service...")
+	stopAnnouncing()
+	<-announced
+	stopSweeps()
+	<-sweeps
+	healthServer.Shutdown()
+	warmer.Stop()
+	// Running backtests finish and stream their results first
+	s.GracefulStop()
+}
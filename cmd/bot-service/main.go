@@ -0,0 +1,213 @@
+// cmd/bot-service/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/copytrade"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/internal/warmup"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	redisClient, err := cache.Connect(cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to connect to redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	exchanges := exchange.NewRegistry()
+	connectors := exchange.NewRouter(cfg.Exchanges, cfg.Trading.ConnectorTimeout)
+	for name := range cfg.Exchanges {
+		policy := exchange.RetryPolicy(name, cfg.Trading.ExchangeRetry)
+		exchanges.Register(name, exchange.WithRetry(connectors.Client(name, false), policy))
+		exchanges.RegisterTestnet(name, exchange.WithRetry(connectors.Client(name, true), policy))
+	}
+
+	candleStore := marketdata.NewInfluxStore(cfg.InfluxDB)
+	defer candleStore.Close()
+
+	precisions, err := exchange.NewPrecisions(cfg.Exchanges)
+	if err != nil {
+		log.Fatalf("Invalid exchange precision: %v", err)
+	}
+
+	// Bot runtime; leaders' trades are copied to their followers as they
+	// are placed
+	oms := orders.NewOMS(exchanges, precisions, orders.NewRepository(db))
+	mirror := copytrade.NewEngine(copytrade.NewRepository(db), oms, cfg.CopyTrading.MirrorConcurrency)
+	bots := bot.NewRepository(db)
+	runner := bot.NewRunner(bots, bot.NewCheckpointStore(db), bot.NewSignalStore(db), candleStore, oms, bot.RunnerOptions{
+		PollInterval:       cfg.BotRuntime.PollInterval,
+		CheckpointInterval: cfg.BotRuntime.CheckpointInterval,
+		Mirror:             mirror,
+		Keys:               exchange.NewKeyAuthorizer(exchange.NewKeyRepository(db), exchange.NewGrantRepository(db), org.NewRepository(db)),
+		OrderRate: orders.RatePolicy{
+			OrdersPerMinute:  cfg.Trading.BotOrderRate.OrdersPerMinute,
+			CancelsPerMinute: cfg.Trading.BotOrderRate.CancelsPerMinute,
+			Burst:            cfg.Trading.BotOrderRate.Burst,
+			MaxWait:          cfg.Trading.BotOrderRate.MaxWait,
+		},
+	})
+	active := func(ctx context.Context) ([]string, error) {
+		running, err := bots.ListRunning(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(running))
+		for i, b := range running {
+			ids[i] = b.ID
+		}
+		return ids, nil
+	}
+	distributor := bot.NewDistributor(redisClient, registry.InstanceID(), active, runner, bot.DistributorOptions{
+		HeartbeatInterval: cfg.BotRuntime.HeartbeatInterval,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go connectors.Probe(ctx, cfg.Trading.ConnectorProbeInterval)
+
+	// Bots are only claimed once the stores they run on are reachable, so
+	// a cold replica does not take bots it would fail right away
+	warmer := warmup.New(cfg.Warmup)
+	warmer.Register(warmup.Postgres(db))
+	warmer.Register(warmup.Redis(redisClient))
+	warmer.Register(warmup.Task{Name: "influxdb", Run: candleStore.Ping})
+	warmer.Start(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if warmer.Wait(ctx) != nil {
+			return
+		}
+		distributor.Run(ctx)
+	}()
+
+	// Announce ourselves to the service registry
+	natsConn, err := messaging.Connect(cfg.NATS, "bot-service")
+	if err != nil {
+		log.Fatalf("Failed to connect to nats: %v", err)
+	}
+	defer natsConn.Close()
+
+	announced := make(chan struct{})
+	go func() {
+		registry.NewAnnouncer(natsConn, registry.Instance{
+			Service:      "bot-service",
+			Region:       cfg.Region,
+			Address:      cfg.BotRuntime.Port,
+			Dependencies: []string{"postgres", "redis", "influxdb", "nats"},
+		}, cfg.Registry.Interval, warmer.Health).Run(ctx)
+		close(announced)
+	}()
+
+	router := setupRouter(cfg, distributor, warmer)
+	srv := &http.Server{
+		Addr:         cfg.BotRuntime.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	go func() {
+		log.Printf("Bot service %s (%s) listening on %s", buildinfo.Version, buildinfo.ShortCommit(), cfg.BotRuntime.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down bot service...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Stop bots so they checkpoint, then hand them to the other replicas
+	cancel()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for bots to stop")
+	}
+	<-announced
+	warmer.Stop()
+
+	log.Println("Bot service stopped")
+}
+
+func setupRouter(cfg *config.Config, distributor *bot.Distributor, warmer *warmup.Warmer) *gin.Engine {
+	if os.Getenv("GIN_MODE") == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		if !warmer.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "warming",
+				"timestamp": time.Now().Unix(),
+				"service":   "bot-service",
+				"region":    cfg.Region,
+				"warmup":    warmer.Status(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().Unix(),
+			"service":   "bot-service",
+			"region":    cfg.Region,
+			"bots":      len(distributor.Owned()),
+		})
+	})
+
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildinfo.Get())
+	})
+
+	return router
+}
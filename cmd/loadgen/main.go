@@ -0,0 +1,141 @@
+// Command loadgen puts a gateway under the load of simulated traders. Each
+// trader registers an account, keeps event streams open, as server-sent
+// events, over a WebSocket or half of each (-transport), and then loops
+// over a weighted mix of actions: reading its dashboard, bursts of order
+// groups placed and cancelled, and signing in again. At the end it prints
+// latency percentiles per endpoint and what the streams received.
+//
+//	go run ./cmd/loadgen -traders 200 -duration 5m -mix reads=6,orders=3,logins=1
+//
+// Given -nats, it also publishes bot status events for the traders at
+// -fanout-rate, which the gateway fans out to every open stream of their
+// user; with many streams per trader (-streams) this saturates the fan-out
+// and the report includes how long events took to arrive.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// config is what the flags set.
+type config struct {
+	gateway    string
+	nats       string
+	traders    int
+	duration   time.Duration
+	ramp       time.Duration
+	think      time.Duration
+	streams    int
+	transport  string
+	burst      int
+	fanoutRate float64
+	exchange   string
+	symbol     string
+	mix        mix
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg := config{mix: mix{{"reads", 6}, {"orders", 3}, {"logins", 1}}}
+	flag.StringVar(&cfg.gateway, "gateway", "http://localhost:8080", "base URL of the gateway")
+	flag.StringVar(&cfg.nats, "nats", "", "NATS URL to publish stream events on; none are published if empty")
+	flag.IntVar(&cfg.traders, "traders", 50, "simulated traders")
+	flag.DurationVar(&cfg.duration, "duration", time.Minute, "how long to apply load, ramp included")
+	flag.DurationVar(&cfg.ramp, "ramp", 10*time.Second, "time over which traders join")
+	flag.DurationVar(&cfg.think, "think", time.Second, "mean pause between a trader's actions")
+	flag.IntVar(&cfg.streams, "streams", 1, "event streams each trader keeps open")
+	flag.StringVar(&cfg.transport, "transport", "both", "how streams are read: sse, ws or both, alternating")
+	flag.IntVar(&cfg.burst, "burst", 5, "order groups per order burst")
+	flag.Float64Var(&cfg.fanoutRate, "fanout-rate", 100, "stream events published per second, spread over the traders")
+	flag.StringVar(&cfg.exchange, "exchange", "binance", "exchange orders are placed on; use one in paper mode")
+	flag.StringVar(&cfg.symbol, "symbol", "BTCUSDT", "symbol orders are placed and tickers read for")
+	flag.Var(&cfg.mix, "mix", "relative weights of trader actions: reads, orders and logins")
+	flag.Parse()
+
+	if cfg.traders < 1 || cfg.streams < 0 || cfg.burst < 1 || cfg.fanoutRate <= 0 || cfg.mix.total() == 0 {
+		log.Print("Need at least one trader, a burst of at least one order, a positive fan-out rate and a mix with a positive weight")
+		return 2
+	}
+	switch cfg.transport {
+	case "sse", "ws", "both":
+	default:
+		log.Printf("Unknown -transport %q; use sse, ws or both", cfg.transport)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, stop := context.WithTimeout(ctx, cfg.duration)
+	defer stop()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Every trader and each of its streams holds a connection
+	transport.MaxIdleConnsPerHost = cfg.traders * (cfg.streams + 1)
+	client := &http.Client{Transport: transport}
+
+	stats := newStats()
+	users := &userList{}
+	var wg sync.WaitGroup
+	if cfg.nats != "" {
+		publisher, err := newPublisher(cfg.nats, cfg.fanoutRate, users, stats)
+		if err != nil {
+			log.Printf("Failed to connect to NATS: %v", err)
+			return 1
+		}
+		defer publisher.close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publisher.run(ctx)
+		}()
+	}
+
+	tag := uuid.New().String()[:8]
+	start := time.Now()
+	for i := 0; i < cfg.traders; i++ {
+		delay := time.Duration(int64(cfg.ramp) * int64(i) / int64(cfg.traders))
+		t := &trader{
+			cfg:      &cfg,
+			client:   client,
+			stats:    stats,
+			users:    users,
+			rng:      rand.New(rand.NewSource(int64(i))),
+			email:    fmt.Sprintf("loadgen-%s-%d@example.com", tag, i),
+			username: fmt.Sprintf("lg%s%d", tag, i),
+			password: "Loadgen-password-" + tag,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			if err := t.run(ctx); err != nil && ctx.Err() == nil {
+				stats.traderFailed(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats.print(os.Stdout, time.Since(start))
+	if stats.failedTraders() == cfg.traders {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Latencies are counted in buckets whose bounds grow by bucketGrowth from
+// bucketMin up to bucketMax, so a run of any length takes the same memory
+// and a percentile is off by at most one bucket, 2%. Anything below
+// bucketMin shares the first bucket and anything above bucketMax the last.
+const (
+	bucketMin    = time.Microsecond
+	bucketMax    = 10 * time.Minute
+	bucketGrowth = 1.02
+)
+
+var bucketCount = int(math.Ceil(math.Log(float64(bucketMax/bucketMin))/math.Log(bucketGrowth))) + 1
+
+// histogram counts latencies in logarithmic buckets.
+type histogram struct {
+	buckets []int
+	count   int
+	max     time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make([]int, bucketCount)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	i := 0
+	if d > bucketMin {
+		i = int(math.Log(float64(d)/float64(bucketMin)) / math.Log(bucketGrowth))
+		i = min(i+1, len(h.buckets)-1)
+	}
+	h.buckets[i]++
+	h.count++
+	h.max = max(h.max, d)
+}
+
+// percentile returns the upper bound of the bucket holding the nearest
+// rank, capped at the largest latency seen, which ranks past bucketMax
+// report as well.
+func (h *histogram) percentile(p float64) time.Duration {
+	rank := max(int(math.Ceil(p*float64(h.count))), 1)
+	seen := 0
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank && i < len(h.buckets)-1 {
+			upper := time.Duration(float64(bucketMin) * math.Pow(bucketGrowth, float64(i)))
+			return min(upper, h.max)
+		}
+	}
+	return h.max
+}
+
+// endpoint holds the latencies measured for one route.
+type endpoint struct {
+	latencies *histogram
+	// errors counts transport failures and responses of 400 and above
+	errors   int
+	statuses map[int]int
+}
+
+// stats is what the traders, their streams and the publisher measured.
+type stats struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+
+	streamsOpen     int
+	streamsPeak     int
+	streamsOpened   int
+	streamEvents    int
+	streamsClosed   map[string]int
+	deliveries      *histogram
+	publishedEvents int
+	publishErrors   int
+
+	traderErrors map[string]int
+	failed       int
+}
+
+func newStats() *stats {
+	return &stats{
+		endpoints:     make(map[string]*endpoint),
+		streamsClosed: make(map[string]int),
+		deliveries:    newHistogram(),
+		traderErrors:  make(map[string]int),
+	}
+}
+
+// request records a call to the route name; status is 0 if the request
+// failed before a response.
+func (s *stats) request(name string, latency time.Duration, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.endpoints[name]
+	if e == nil {
+		e = &endpoint{latencies: newHistogram(), statuses: make(map[int]int)}
+		s.endpoints[name] = e
+	}
+	e.latencies.record(latency)
+	e.statuses[status]++
+	if status == 0 || status >= 400 {
+		e.errors++
+	}
+}
+
+func (s *stats) streamOpened() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streamsOpened++
+	s.streamsOpen++
+	if s.streamsOpen > s.streamsPeak {
+		s.streamsPeak = s.streamsOpen
+	}
+}
+
+func (s *stats) streamDone() {
+	s.mu.Lock()
+	s.streamsOpen--
+	s.mu.Unlock()
+}
+
+func (s *stats) streamClosed(reason string) {
+	s.mu.Lock()
+	s.streamsClosed[reason]++
+	s.mu.Unlock()
+}
+
+func (s *stats) streamEvent() {
+	s.mu.Lock()
+	s.streamEvents++
+	s.mu.Unlock()
+}
+
+func (s *stats) delivered(latency time.Duration) {
+	s.mu.Lock()
+	s.deliveries.record(latency)
+	s.mu.Unlock()
+}
+
+func (s *stats) published(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.publishErrors++
+		return
+	}
+	s.publishedEvents++
+}
+
+// traderFailed records a trader that could not start, grouping the
+// reasons so a misconfigured run prints one line, not one per trader.
+func (s *stats) traderFailed(err error) {
+	s.mu.Lock()
+	s.traderErrors[err.Error()]++
+	s.failed++
+	s.mu.Unlock()
+}
+
+func (s *stats) failedTraders() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// print writes the report: a latency table per route and the streams'
+// totals.
+func (s *stats) print(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.endpoints))
+	for name := range s.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%-36s %8s %7s %8s %9s %9s %9s %9s\n", "ENDPOINT", "REQUESTS", "ERRORS", "RPS", "P50", "P90", "P99", "MAX")
+	for _, name := range names {
+		e := s.endpoints[name]
+		fmt.Fprintf(w, "%-36s %8d %7d %8.1f %s\n", name, e.latencies.count, e.errors,
+			float64(e.latencies.count)/elapsed.Seconds(), percentiles(e.latencies))
+	}
+	for _, name := range names {
+		e := s.endpoints[name]
+		if e.errors == 0 {
+			continue
+		}
+		codes := make([]int, 0, len(e.statuses))
+		for code := range e.statuses {
+			if code == 0 || code >= 400 {
+				codes = append(codes, code)
+			}
+		}
+		sort.Ints(codes)
+		fmt.Fprintf(w, "%s errors:", name)
+		for _, code := range codes {
+			label := fmt.Sprint(code)
+			if code == 0 {
+				label = "failed"
+			}
+			fmt.Fprintf(w, " %s=%d", label, e.statuses[code])
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "\nstreams: %d opened, %d at peak, %d events received\n", s.streamsOpened, s.streamsPeak, s.streamEvents)
+	if len(s.streamsClosed) > 0 {
+		reasons := make([]string, 0, len(s.streamsClosed))
+		for reason := range s.streamsClosed {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		fmt.Fprint(w, "streams ended:")
+		for _, reason := range reasons {
+			fmt.Fprintf(w, " %s=%d", reason, s.streamsClosed[reason])
+		}
+		fmt.Fprintln(w)
+	}
+	if s.publishedEvents > 0 || s.publishErrors > 0 {
+		fmt.Fprintf(w, "fan-out: %d events published, %d failed, %d deliveries\n",
+			s.publishedEvents, s.publishErrors, s.deliveries.count)
+		fmt.Fprintf(w, "%-36s %25s %s\n", "delivery latency", "", percentiles(s.deliveries))
+	}
+
+	if s.failed > 0 {
+		fmt.Fprintf(w, "\n%d traders failed to start:\n", s.failed)
+		for reason, n := range s.traderErrors {
+			fmt.Fprintf(w, "  %s (%d)\n", reason, n)
+		}
+	}
+}
+
+// percentiles formats p50, p90, p99 and the maximum of h.
+func percentiles(h *histogram) string {
+	if h.count == 0 {
+		return fmt.Sprintf("%9s %9s %9s %9s", "-", "-", "-", "-")
+	}
+	return fmt.Sprintf("%9s %9s %9s %9s", round(h.percentile(0.5)), round(h.percentile(0.9)), round(h.percentile(0.99)), round(h.max))
+}
+
+// round drops digits below what a latency column needs.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
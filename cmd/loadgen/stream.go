@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"golang.org/x/net/websocket"
+)
+
+// loadgenBot marks the events the publisher sends, so their delivery time
+// can be told apart from real bot status changes.
+const loadgenBot = "loadgen"
+
+// reconnectDelay is how long a stream waits before reconnecting after the
+// gateway closed it.
+const reconnectDelay = time.Second
+
+// stream keeps one event stream open until ctx is done, reconnecting like
+// a client would when the gateway drops it. It reads server-sent events,
+// or the WebSocket stream if ws is set.
+func (t *trader) stream(ctx context.Context, ws bool) {
+	once := t.streamOnce
+	if ws {
+		once = t.webSocketOnce
+	}
+	for ctx.Err() == nil {
+		reason := once(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		t.stats.streamClosed(reason)
+		select {
+		case <-time.After(reconnectDelay):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// streamOnce reads one stream connection and returns why it ended: the
+// final control event the gateway sent, "refused" if it answered with an
+// error, "eof" or "error".
+func (t *trader) streamOnce(ctx context.Context) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.cfg.gateway+"/api/v1/stream", nil)
+	if err != nil {
+		return "error"
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+t.token())
+
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.stats.request("GET /api/v1/stream", time.Since(start), 0)
+		}
+		return "error"
+	}
+	defer resp.Body.Close()
+	t.stats.request("GET /api/v1/stream", time.Since(start), resp.StatusCode)
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.login(ctx)
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "refused"
+	}
+
+	t.stats.streamOpened()
+	defer t.stats.streamDone()
+	reader := bufio.NewReader(resp.Body)
+	var event string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return "eof"
+			}
+			return "error"
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if t.event(event, strings.TrimPrefix(line, "data: ")) {
+				// The gateway closes the stream after a control event
+				io.Copy(io.Discard, reader)
+				return event
+			}
+		case line == "":
+			event = ""
+		}
+	}
+}
+
+// webSocketOnce is streamOnce for the WebSocket stream, whose frames hold
+// {"type": ..., "data": ...}.
+func (t *trader) webSocketOnce(ctx context.Context) string {
+	config, err := websocket.NewConfig(
+		strings.Replace(t.cfg.gateway, "http", "ws", 1)+"/api/v1/stream/ws",
+		// The gateway admits its own origin, like a browser on the web app
+		t.cfg.gateway,
+	)
+	if err != nil {
+		return "error"
+	}
+	config.Header = http.Header{"Authorization": {"Bearer " + t.token()}}
+
+	start := time.Now()
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "error"
+		}
+		// The handshake does not expose the status the gateway refused with
+		t.stats.request("GET /api/v1/stream/ws", time.Since(start), 0)
+		var dialErr *websocket.DialError
+		if errors.As(err, &dialErr) && dialErr.Err == websocket.ErrBadStatus {
+			t.login(ctx)
+			return "refused"
+		}
+		return "error"
+	}
+	defer conn.Close()
+	t.stats.request("GET /api/v1/stream/ws", time.Since(start), http.StatusSwitchingProtocols)
+
+	// Receive blocks without a deadline, so the connection is closed when
+	// the run ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	t.stats.streamOpened()
+	defer t.stats.streamDone()
+	for {
+		var frame struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := websocket.JSON.Receive(conn, &frame); err != nil {
+			if err == io.EOF {
+				return "eof"
+			}
+			return "error"
+		}
+		if t.event(frame.Type, string(frame.Data)) {
+			return frame.Type
+		}
+	}
+}
+
+// event counts an event read from a stream and reports whether it was a
+// control event, after which the gateway closes the stream.
+func (t *trader) event(name, data string) bool {
+	switch name {
+	case "slow_client", "reconnect":
+		return true
+	case "bot_status":
+		t.received(data)
+	}
+	t.stats.streamEvent()
+	return false
+}
+
+// received records how long a published event took to arrive.
+func (t *trader) received(data string) {
+	var event struct {
+		BotID      string    `json:"bot_id"`
+		OccurredAt time.Time `json:"occurred_at"`
+	}
+	if json.Unmarshal([]byte(data), &event) != nil || event.BotID != loadgenBot {
+		return
+	}
+	t.stats.delivered(time.Since(event.OccurredAt))
+}
+
+// publisher sends bot status events to the traders in turn, for the
+// gateway to fan out to their streams.
+type publisher struct {
+	nc    *nats.Conn
+	rate  float64
+	users *userList
+	stats *stats
+}
+
+func newPublisher(url string, rate float64, users *userList, stats *stats) (*publisher, error) {
+	nc, err := nats.Connect(url, nats.Name("loadgen"))
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{nc: nc, rate: rate, users: users, stats: stats}, nil
+}
+
+func (p *publisher) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / p.rate))
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		userID := p.users.at(i)
+		if userID == "" {
+			continue
+		}
+		err := events.Publish(p.nc, "loadgen", &eventspb.BotStatusChanged{
+			BotId:  loadgenBot,
+			UserId: userID,
+			Status: "running",
+		})
+		p.stats.published(err)
+	}
+}
+
+func (p *publisher) close() {
+	p.nc.Close()
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestTimeout bounds each REST call; streams are only bounded by the
+// run.
+const requestTimeout = 10 * time.Second
+
+// action is a weighted kind of trader activity.
+type action struct {
+	name   string
+	weight int
+}
+
+// mix is the -mix flag: name=weight pairs separated by commas.
+type mix []action
+
+func (m mix) String() string {
+	parts := make([]string, len(m))
+	for i, a := range m {
+		parts[i] = fmt.Sprintf("%s=%d", a.name, a.weight)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m *mix) Set(value string) error {
+	var parsed mix
+	for _, part := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("%q is not name=weight", part)
+		}
+		switch name {
+		case "reads", "orders", "logins":
+		default:
+			return fmt.Errorf("unknown action %q", name)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return fmt.Errorf("invalid weight %q for %s", weight, name)
+		}
+		parsed = append(parsed, action{name: name, weight: w})
+	}
+	*m = parsed
+	return nil
+}
+
+func (m mix) total() int {
+	total := 0
+	for _, a := range m {
+		total += a.weight
+	}
+	return total
+}
+
+// pick draws an action in proportion to the weights.
+func (m mix) pick(rng *rand.Rand) string {
+	n := rng.Intn(m.total())
+	for _, a := range m {
+		if n < a.weight {
+			return a.name
+		}
+		n -= a.weight
+	}
+	return m[len(m)-1].name
+}
+
+// userList collects the IDs of registered traders for the publisher.
+type userList struct {
+	mu  sync.RWMutex
+	ids []string
+}
+
+func (l *userList) add(id string) {
+	l.mu.Lock()
+	l.ids = append(l.ids, id)
+	l.mu.Unlock()
+}
+
+// at returns the i-th user, wrapping around, or "" before any registered.
+func (l *userList) at(i int) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.ids) == 0 {
+		return ""
+	}
+	return l.ids[i%len(l.ids)]
+}
+
+type authResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+// trader is one simulated user with its own account.
+type trader struct {
+	cfg    *config
+	client *http.Client
+	stats  *stats
+	users  *userList
+	rng    *rand.Rand
+
+	email    string
+	username string
+	password string
+
+	mu          sync.Mutex
+	accessToken string
+}
+
+// run registers the trader, opens its streams and acts until ctx is done.
+func (t *trader) run(ctx context.Context) error {
+	var resp authResponse
+	code, err := t.call(ctx, "POST /api/v1/auth/register", http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email":      t.email,
+		"username":   t.username,
+		"password":   t.password,
+		"first_name": "Load",
+		"last_name":  "Generator",
+	}, &resp)
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	if code != http.StatusCreated {
+		return fmt.Errorf("register: status %d", code)
+	}
+	t.setTokens(resp)
+	t.users.add(resp.User.ID)
+
+	var wg sync.WaitGroup
+	// With both transports, a trader with a single stream still uses
+	// either, so the traders split between them
+	offset := t.rng.Intn(2)
+	for i := 0; i < t.cfg.streams; i++ {
+		ws := t.cfg.transport == "ws" || (t.cfg.transport == "both" && (i+offset)%2 == 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.stream(ctx, ws)
+		}()
+	}
+	defer wg.Wait()
+
+	for {
+		// Pauses vary by half the mean either way, so traders drift apart
+		pause := t.cfg.think/2 + time.Duration(t.rng.Int63n(int64(t.cfg.think)+1))
+		select {
+		case <-time.After(pause):
+		case <-ctx.Done():
+			return nil
+		}
+
+		switch t.cfg.mix.pick(t.rng) {
+		case "reads":
+			t.reads(ctx)
+		case "orders":
+			t.orders(ctx)
+		case "logins":
+			t.login(ctx)
+		}
+	}
+}
+
+// reads loads what a dashboard shows.
+func (t *trader) reads(ctx context.Context) {
+	for _, path := range []struct{ name, path string }{
+		{"GET /api/v1/user/profile", "/api/v1/user/profile"},
+		{"GET /api/v1/bots", "/api/v1/bots"},
+		{"GET /api/v1/portfolio", "/api/v1/portfolio"},
+		{"GET /api/v1/market/ticker/:symbol", "/api/v1/market/ticker/" + t.cfg.symbol},
+	} {
+		t.authorized(ctx, path.name, http.MethodGet, path.path, nil, nil)
+	}
+}
+
+// orders places a burst of one-cancels-other groups at once, far enough
+// from the market that neither leg fills, and then cancels them.
+func (t *trader) orders(ctx context.Context) {
+	ids := make(chan string, t.cfg.burst)
+	var wg sync.WaitGroup
+	for i := 0; i < t.cfg.burst; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var group struct {
+				ID string `json:"id"`
+			}
+			code := t.authorized(ctx, "POST /api/v1/orders/groups", http.MethodPost, "/api/v1/orders/groups", map[string]any{
+				"type":     "oco",
+				"exchange": t.cfg.exchange,
+				"symbol":   t.cfg.symbol,
+				"legs": []map[string]string{
+					{"side": "sell", "type": "limit", "price": "1000000", "quantity": "0.001"},
+					{"side": "buy", "type": "limit", "price": "10", "quantity": "1"},
+				},
+			}, &group)
+			if code == http.StatusCreated && group.ID != "" {
+				ids <- group.ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	for id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			t.authorized(ctx, "DELETE /api/v1/orders/groups/:id", http.MethodDelete, "/api/v1/orders/groups/"+id, nil, nil)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// login signs in again and rotates the refresh token, as a returning
+// trader would.
+func (t *trader) login(ctx context.Context) bool {
+	var resp authResponse
+	code, err := t.call(ctx, "POST /api/v1/auth/login", http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    t.email,
+		"password": t.password,
+	}, &resp)
+	if err != nil || code != http.StatusOK {
+		return false
+	}
+	t.setTokens(resp)
+
+	code, err = t.call(ctx, "POST /api/v1/auth/refresh", http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": resp.RefreshToken,
+	}, &resp)
+	if err == nil && code == http.StatusOK {
+		t.setTokens(resp)
+	}
+	return true
+}
+
+func (t *trader) setTokens(resp authResponse) {
+	t.mu.Lock()
+	t.accessToken = resp.AccessToken
+	t.mu.Unlock()
+}
+
+func (t *trader) token() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accessToken
+}
+
+// authorized calls the gateway with the trader's access token, signing in
+// again once if it expired. It returns the status code, 0 on failure.
+func (t *trader) authorized(ctx context.Context, name, method, path string, body, out any) int {
+	code, err := t.call(ctx, name, method, path, t.token(), body, out)
+	if err == nil && code == http.StatusUnauthorized && t.login(ctx) {
+		code, err = t.call(ctx, name, method, path, t.token(), body, out)
+	}
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+// call sends body as JSON, decodes the response into out, if given, and
+// records the latency under name.
+func (t *trader) call(ctx context.Context, name, method, path, token string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, method, t.cfg.gateway+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Requests cut off by the end of the run are not the gateway's fault
+		if ctx.Err() == nil {
+			t.stats.request(name, time.Since(start), 0)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	t.stats.request(name, time.Since(start), resp.StatusCode)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if out != nil && len(data) > 0 && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("%s %s: invalid response %q: %w", method, path, data, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
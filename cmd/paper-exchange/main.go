@@ -0,0 +1,143 @@
+// cmd/paper-exchange/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/faults"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+// Paper accounts live in this one process, so the gateway, the bot
+// runtime and the scheduler all see the same balances, orders and fills.
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Trading.Mode != "paper" {
+		log.Fatalf("The paper exchange only runs in paper trading mode")
+	}
+
+	clients := exchange.NewRegistry()
+	papers := make(map[string][]*exchange.PaperClient, len(cfg.Exchanges))
+	for name := range cfg.Exchanges {
+		mainnet := exchange.NewPaperClient(name).WithChaos(exchange.NewChaos(name, cfg.Trading.Chaos))
+		testnet := exchange.NewTestnetPaperClient(name).WithChaos(exchange.NewChaos(name, cfg.Trading.Chaos))
+		clients.Register(name, mainnet)
+		clients.RegisterTestnet(name, testnet)
+		papers[name] = []*exchange.PaperClient{mainnet, testnet}
+	}
+
+	candleStore := marketdata.NewInfluxStore(cfg.InfluxDB)
+	defer candleStore.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feedPrices(ctx, candleStore, cfg, papers)
+
+	// Announce ourselves to the service registry
+	natsConn, err := messaging.Connect(cfg.NATS, "paper-exchange")
+	if err != nil {
+		log.Fatalf("Failed to connect to nats: %v", err)
+	}
+	defer natsConn.Close()
+
+	announced := make(chan struct{})
+	go func() {
+		registry.NewAnnouncer(natsConn, registry.Instance{
+			Service:      "paper-exchange",
+			Region:       cfg.Region,
+			Address:      cfg.PaperExchange.Port,
+			Dependencies: []string{"influxdb", "nats"},
+		}, cfg.Registry.Interval, nil).Run(ctx)
+		close(announced)
+	}()
+
+	srv := &http.Server{
+		Addr:         cfg.PaperExchange.Port,
+		Handler:      faults.New(cfg.Faults).WrapHTTP(exchange.NewConnectorHandler(clients)),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	go func() {
+		log.Printf("Paper exchange %s (%s) listening on %s", buildinfo.Version, buildinfo.ShortCommit(), cfg.PaperExchange.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down paper exchange...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	cancel()
+	<-announced
+
+	log.Println("Paper exchange stopped")
+}
+
+// feedPrices marks every configured symbol at the close of its latest
+// minute candle, which also fills the resting limit orders the move
+// crosses. Symbols without recent candles keep their last price.
+func feedPrices(ctx context.Context, candles marketdata.CandleStore, cfg *config.Config, papers map[string][]*exchange.PaperClient) {
+	minute, err := marketdata.ParseInterval("1m")
+	if err != nil {
+		log.Fatalf("Invalid price interval: %v", err)
+	}
+
+	ticker := time.NewTicker(cfg.PaperExchange.PriceInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		for name, exchangeCfg := range cfg.Exchanges {
+			for _, symbol := range exchangeCfg.Symbols {
+				recent, err := candles.Candles(ctx, name, symbol, minute, now.Add(-10*time.Minute), now)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("Failed to load %s %s prices: %v", name, symbol, err)
+					}
+					continue
+				}
+				if len(recent) == 0 {
+					continue
+				}
+				price := money.FromFloat(recent[len(recent)-1].Close)
+				for _, paper := range papers[name] {
+					paper.SetPrice(symbol, price)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,440 @@
+// cmd/scheduler-service/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/billing"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/copytrade"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/demo"
+	"github.com/tradingbothub/platform/internal/email"
+	"github.com/tradingbothub/platform/internal/equity"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/messaging"
+	"github.com/tradingbothub/platform/internal/metering"
+	"github.com/tradingbothub/platform/internal/middleware"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/registry"
+	"github.com/tradingbothub/platform/internal/residency"
+	"github.com/tradingbothub/platform/internal/retention"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/internal/scheduler"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/warmup"
+	"github.com/tradingbothub/platform/internal/webhook"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"github.com/tradingbothub/platform/pkg/objectstore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Initialize scheduler
+	repo := scheduler.NewRepository(db)
+	sched, err := scheduler.New(db, repo, cfg.Scheduler.PollInterval)
+	if err != nil {
+		log.Fatalf("Failed to initialize scheduler: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Equity snapshots
+	// Balances come from the connectors, which in paper mode are the paper
+	// exchange service every other service trades on
+	exchanges := exchange.NewRegistry()
+	connectors := exchange.NewRouter(cfg.Exchanges, cfg.Trading.ConnectorTimeout)
+	for name := range cfg.Exchanges {
+		policy := exchange.RetryPolicy(name, cfg.Trading.ExchangeRetry)
+		exchanges.Register(name, exchange.WithRetry(connectors.Client(name, false), policy))
+		exchanges.RegisterTestnet(name, exchange.WithRetry(connectors.Client(name, true), policy))
+	}
+	go connectors.Probe(ctx, cfg.Trading.ConnectorProbeInterval)
+	equityStore := equity.NewInfluxStore(cfg.InfluxDB)
+	defer equityStore.Close()
+	snapshotter := equity.NewSnapshotter(db, bot.NewRepository(db), exchanges, equityStore)
+	if err := sched.Register(ctx, "equity-snapshot", cfg.Equity.SnapshotSchedule, snapshotter.Run); err != nil {
+		log.Fatalf("Failed to register equity snapshot job: %v", err)
+	}
+
+	// Portfolio holdings live with the trades in each data region
+	regions, err := residency.NewRouter(cfg.DataResidency, db)
+	if err != nil {
+		log.Fatalf("Failed to connect to regional databases: %v", err)
+	}
+	defer regions.Close()
+
+	valuer := orders.NewHoldingsValuer(regions.DBs(), exchanges)
+	if err := sched.Register(ctx, "portfolio-valuation", cfg.Portfolio.ValuationSchedule, valuer.Run); err != nil {
+		log.Fatalf("Failed to register portfolio valuation job: %v", err)
+	}
+	checker := orders.NewHoldingsChecker(regions.DBs())
+	if err := sched.Register(ctx, "portfolio-check", cfg.Portfolio.CheckSchedule, checker.Run); err != nil {
+		log.Fatalf("Failed to register portfolio check job: %v", err)
+	}
+
+	// Dynamic copy trading allocation
+	allocator := copytrade.NewAllocator(copytrade.NewRepository(db), equityStore, copytrade.AllocationPolicy{
+		Window:        cfg.CopyTrading.AllocationWindow,
+		Step:          cfg.CopyTrading.AllocationStep,
+		DrawdownLimit: cfg.CopyTrading.DrawdownLimit,
+	})
+	if err := sched.Register(ctx, "copy-allocation", cfg.CopyTrading.AllocationSchedule, allocator.Run); err != nil {
+		log.Fatalf("Failed to register copy allocation job: %v", err)
+	}
+
+	// Copy trading profit sharing
+	settler := copytrade.NewSettler(copytrade.NewRepository(db), copytrade.NewSettlementRepository(db), cfg.CopyTrading.PlatformCommission)
+	if err := sched.Register(ctx, "copy-settlement", cfg.CopyTrading.SettlementSchedule, settler.Run); err != nil {
+		log.Fatalf("Failed to register copy settlement job: %v", err)
+	}
+
+	// Wash-trade and churn detection on copy trading leaders
+	abuse := cfg.CopyTrading.Abuse
+	detector := copytrade.NewDetector(db, copytrade.AbusePolicy{
+		Window:          abuse.Window,
+		MatchWindow:     abuse.MatchWindow,
+		WashRatio:       abuse.WashRatio,
+		MinMatchedPairs: abuse.MinMatchedPairs,
+		ChurnMinTrades:  abuse.ChurnMinTrades,
+		ChurnFeeRatio:   abuse.ChurnFeeRatio,
+	})
+	if err := sched.Register(ctx, "copy-abuse-scan", abuse.Schedule, detector.Run); err != nil {
+		log.Fatalf("Failed to register copy abuse scan job: %v", err)
+	}
+
+	// Expired refresh tokens
+	sessionPurger := auth.NewSessionPurger(auth.NewSessionRepository(db))
+	if err := sched.Register(ctx, "refresh-token-purge", cfg.Auth.RefreshTokenPurgeSchedule, sessionPurger.Run); err != nil {
+		log.Fatalf("Failed to register refresh token purge job: %v", err)
+	}
+
+	// Redis backs the demo reset, the existence filters and billing
+	var redisClient redis.UniversalClient
+	if cfg.Demo.Enabled || cfg.Auth.ExistenceFilter.Enabled || cfg.Billing.Enabled {
+		redisClient, err = cache.Connect(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to connect to redis: %v", err)
+		}
+		defer redisClient.Close()
+	}
+
+	// Email and username bloom filters, rebuilt to drop deleted accounts
+	if cfg.Auth.ExistenceFilter.Enabled {
+		existence := auth.NewExistence(auth.NewRepository(db), redisClient, cfg.Auth.ExistenceFilter)
+		if err := sched.Register(ctx, "existence-filter-rebuild", cfg.Auth.ExistenceFilter.RebuildSchedule, existence.Rebuild); err != nil {
+			log.Fatalf("Failed to register existence filter rebuild job: %v", err)
+		}
+	}
+
+	// Invoices from metered usage, and dunning of failed payments
+	if cfg.Billing.Enabled {
+		usage := metering.NewRecorder(redisClient, cfg.Metering.Retention, 0)
+		defer usage.Close()
+
+		invoices := billing.NewRepository(db)
+		invoicer, err := billing.NewInvoicer(invoices, usage, cfg.Billing)
+		if err != nil {
+			log.Fatalf("Failed to configure billing: %v", err)
+		}
+		if err := sched.Register(ctx, "billing-usage-rollup", cfg.Billing.UsageSchedule, invoicer.RollUp); err != nil {
+			log.Fatalf("Failed to register billing usage rollup job: %v", err)
+		}
+		if err := sched.Register(ctx, "billing-invoices", cfg.Billing.InvoiceSchedule, invoicer.Run); err != nil {
+			log.Fatalf("Failed to register billing invoice job: %v", err)
+		}
+
+		mailer, err := email.New(cfg.Email)
+		if err != nil {
+			log.Fatalf("Failed to configure email: %v", err)
+		}
+		// Plans are changed through the auth service
+		authConn, err := grpc.Dial("localhost"+cfg.Auth.Port,
+			append(rpc.DialOptions(cfg.GRPC, "auth"), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+		if err != nil {
+			log.Fatalf("Failed to connect to auth service: %v", err)
+		}
+		defer authConn.Close()
+		plans := billing.NewAuthPlans(authpb.NewAuthServiceClient(authConn), cfg.Auth.ServiceKey)
+		billingService := billing.NewService(invoices, plans)
+		dunner := billing.NewDunner(billingService, mailer, cfg.Billing.Dunning)
+		if err := sched.Register(ctx, "billing-dunning", cfg.Billing.Dunning.Schedule, dunner.Run); err != nil {
+			log.Fatalf("Failed to register billing dunning job: %v", err)
+		}
+	}
+
+	// Trash retention
+	purger := retention.NewPurger(bot.NewRepository(db), strategy.NewRepository(db), cfg.Retention.TrashTTL)
+	if err := sched.Register(ctx, "trash-purge", cfg.Retention.Schedule, purger.Run); err != nil {
+		log.Fatalf("Failed to register trash purge job: %v", err)
+	}
+	if cfg.Retention.AuditTTL > 0 {
+		auditPurger := auth.NewAuditPurger(auth.NewAuditRepository(db), cfg.Retention.AuditTTL)
+		if err := sched.Register(ctx, "audit-purge", cfg.Retention.Schedule, auditPurger.Run); err != nil {
+			log.Fatalf("Failed to register audit purge job: %v", err)
+		}
+	}
+
+	// Object store lifecycle
+	if len(cfg.ObjectStore.Lifecycle) > 0 {
+		objects, err := objectstore.New(ctx, cfg.ObjectStore)
+		if err != nil {
+			log.Fatalf("Failed to open object store: %v", err)
+		}
+		defer objects.Close()
+
+		cleaner := objectstore.NewCleaner(objects, cfg.ObjectStore.Lifecycle)
+		if err := sched.Register(ctx, "object-cleanup", cfg.ObjectStore.CleanupSchedule, cleaner.Run); err != nil {
+			log.Fatalf("Failed to register object cleanup job: %v", err)
+		}
+	}
+
+	// Nightly demo sandbox reset
+	if cfg.Demo.Enabled {
+		tradeDB, err := regions.DB("")
+		if err != nil {
+			log.Fatalf("Failed to resolve demo data region: %v", err)
+		}
+
+		resetter := demo.NewResetter(db, tradeDB, redisClient, cfg.Demo.Email)
+		if err := sched.Register(ctx, "demo-reset", cfg.Demo.ResetSchedule, resetter.Run); err != nil {
+			log.Fatalf("Failed to register demo reset job: %v", err)
+		}
+	}
+
+	natsConn, err := messaging.Connect(cfg.NATS, "scheduler-service")
+	if err != nil {
+		log.Fatalf("Failed to connect to nats: %v", err)
+	}
+	defer natsConn.Close()
+
+	// JetStream consumer lag, polled by the leader only
+	var consumers *messaging.LagMonitor
+	if cfg.NATS.ConsumerLag.Enabled {
+		consumers, err = messaging.NewLagMonitor(natsConn, cfg.NATS.ConsumerLag, publishConsumerLag(natsConn, cfg.NATS.ConsumerLag.Webhook))
+		if err != nil {
+			log.Fatalf("Failed to monitor consumers: %v", err)
+		}
+		if err := sched.Register(ctx, "consumer-lag", cfg.NATS.ConsumerLag.Schedule, consumers.Run); err != nil {
+			log.Fatalf("Failed to register consumer lag job: %v", err)
+		}
+		acks, err := consumers.ObserveAcks("scheduler-service")
+		if err != nil {
+			log.Fatalf("Failed to subscribe to consumer ack samples: %v", err)
+		}
+		defer acks.Unsubscribe()
+	}
+
+	// Jobs only start once the stores they use are reachable
+	warmer := warmup.New(cfg.Warmup)
+	warmer.Register(warmup.Postgres(db))
+	if redisClient != nil {
+		warmer.Register(warmup.Redis(redisClient))
+	}
+	warmer.Start(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if warmer.Wait(ctx) != nil {
+			return
+		}
+		sched.Run(ctx)
+	}()
+
+	// Announce ourselves to the service registry
+	dependencies := []string{"postgres", "influxdb", "nats"}
+	if redisClient != nil {
+		dependencies = append(dependencies, "redis")
+	}
+	announced := make(chan struct{})
+	go func() {
+		registry.NewAnnouncer(natsConn, registry.Instance{
+			Service:      "scheduler-service",
+			Region:       cfg.Region,
+			Address:      cfg.Scheduler.Port,
+			Dependencies: dependencies,
+		}, cfg.Registry.Interval, warmer.Health).Run(ctx)
+		close(announced)
+	}()
+
+	// Admin API
+	router := setupRouter(cfg, scheduler.NewHandler(sched), warmer, sched, consumers)
+	srv := &http.Server{
+		Addr:         cfg.Scheduler.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	go func() {
+		log.Printf("Scheduler service %s (%s) listening on %s", buildinfo.Version, buildinfo.ShortCommit(), cfg.Scheduler.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down scheduler service...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Let running jobs finish and release leadership
+	cancel()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for running jobs")
+	}
+	<-announced
+	warmer.Stop()
+
+	log.Println("Scheduler service stopped")
+}
+
+func setupRouter(cfg *config.Config, h *scheduler.Handler, warmer *warmup.Warmer, sched *scheduler.Scheduler, consumers *messaging.LagMonitor) *gin.Engine {
+	if os.Getenv("GIN_MODE") == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		if !warmer.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "warming",
+				"timestamp": time.Now().Unix(),
+				"service":   "scheduler-service",
+				"region":    cfg.Region,
+				"warmup":    warmer.Status(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().Unix(),
+			"service":   "scheduler-service",
+			"region":    cfg.Region,
+		})
+	})
+
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildinfo.Get())
+	})
+
+	admin := router.Group("/api/v1/admin/jobs")
+	admin.Use(middleware.AdminAuth(cfg.Admin.APIKey))
+	{
+		admin.GET("", h.ListJobs)
+		admin.POST("/:name/pause", h.PauseJob)
+		admin.POST("/:name/resume", h.ResumeJob)
+		admin.POST("/:name/trigger", h.TriggerJob)
+	}
+	consumersAdmin := router.Group("/api/v1/admin/consumers")
+	consumersAdmin.Use(middleware.AdminAuth(cfg.Admin.APIKey))
+	consumersAdmin.GET("", listConsumers(sched, consumers))
+
+	return router
+}
+
+// publishConsumerLag alerts subscribers such as the on-call notifier that
+// a consumer got stuck or recovered, and posts the alert to the webhook
+// if one is configured.
+func publishConsumerLag(conn *nats.Conn, hook config.WebhookConfig) func(messaging.ConsumerLag) {
+	var sender *webhook.Sender
+	if hook.URL != "" {
+		sender = webhook.New("consumer_lag", hook)
+	}
+	return func(lag messaging.ConsumerLag) {
+		err := events.Publish(conn, "scheduler-service", &eventspb.ConsumerLagAlert{
+			Stream:       lag.Stream,
+			Consumer:     lag.Consumer,
+			Stuck:        lag.Stuck,
+			Reason:       lag.Reason,
+			Pending:      lag.Pending,
+			AckPending:   int64(lag.AckPending),
+			Redelivered:  int64(lag.Redelivered),
+			ProgressedAt: timestamppb.New(lag.Progressed),
+		})
+		if err != nil {
+			log.Printf("Failed to publish lag alert of consumer %s/%s: %v", lag.Stream, lag.Consumer, err)
+		}
+		if sender == nil {
+			return
+		}
+		if err := sender.Send(context.Background(), "consumer_lag", lag); err != nil {
+			log.Printf("Failed to post lag alert of consumer %s/%s: %v", lag.Stream, lag.Consumer, err)
+		}
+	}
+}
+
+// listConsumers returns the JetStream consumers with their backlog as of
+// the last poll, stuck ones first. ?stuck=true lists only those. Only the
+// leader polls, so other replicas have nothing to show.
+func listConsumers(sched *scheduler.Scheduler, monitor *messaging.LagMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if monitor == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consumer monitoring is disabled"})
+			return
+		}
+		if !sched.IsLeader() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consumers are polled by the leader replica"})
+			return
+		}
+
+		consumers := monitor.Consumers()
+		if c.Query("stuck") == "true" {
+			stuck := consumers[:0]
+			for _, lag := range consumers {
+				if lag.Stuck {
+					stuck = append(stuck, lag)
+				}
+			}
+			consumers = stuck
+		}
+
+		c.JSON(http.StatusOK, gin.H{"consumers": consumers})
+	}
+}
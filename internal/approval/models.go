@@ -0,0 +1,50 @@
+package approval
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusRejected = "rejected"
+	StatusExecuted = "executed"
+	// StatusFailed means the request was approved but its action failed
+	StatusFailed  = "failed"
+	StatusExpired = "expired"
+)
+
+// High-risk actions of organizations. Each registers its executor with the
+// Service.
+const (
+	// ActionDeployLiveBot starts a live bot whose capital is above the
+	// approval threshold
+	ActionDeployLiveBot = "bot.deploy_live"
+	// ActionRaiseBotLimits raises the order rate limits of a bot
+	ActionRaiseBotLimits = "bot.raise_limits"
+	// ActionAddWithdrawalAddress adds an address funds may be withdrawn to
+	ActionAddWithdrawalAddress = "withdrawal_address.add"
+)
+
+// Request is a high-risk action waiting for a second admin.
+type Request struct {
+	ID             string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OrganizationID string          `json:"organization_id" gorm:"type:varchar(36);not null;index:idx_approval_requests_org_status,priority:1"`
+	Action         string          `json:"action" gorm:"not null"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:jsonb;serializer:json"`
+	Summary        string          `json:"summary"`
+	RequestedBy    string          `json:"requested_by" gorm:"type:varchar(36);not null"`
+	Status         string          `json:"status" gorm:"not null;index:idx_approval_requests_org_status,priority:2"`
+	DecidedBy      string          `json:"decided_by,omitempty" gorm:"type:varchar(36)"`
+	DecidedAt      *time.Time      `json:"decided_at,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Request) TableName() string {
+	return "approval_requests"
+}
@@ -0,0 +1,80 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrRequestNotFound = errors.New("approval request not found")
+
+type Repository interface {
+	Create(ctx context.Context, req *Request) error
+	Get(ctx context.Context, id string) (*Request, error)
+	List(ctx context.Context, organizationID, status string) ([]Request, error)
+	// Decide moves a pending request to status. It fails with
+	// ErrNotPending when another admin decided first.
+	Decide(ctx context.Context, id, status, decidedBy, reason string) error
+	SetResult(ctx context.Context, id, status, errMsg string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, req *Request) error {
+	return r.db.WithContext(ctx).Create(req).Error
+}
+
+func (r *repository) Get(ctx context.Context, id string) (*Request, error) {
+	var req Request
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *repository) List(ctx context.Context, organizationID, status string) ([]Request, error) {
+	tx := r.db.WithContext(ctx).Where("organization_id = ?", organizationID)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	var reqs []Request
+	err := tx.Order("created_at DESC").Find(&reqs).Error
+	return reqs, err
+}
+
+func (r *repository) Decide(ctx context.Context, id, status, decidedBy, reason string) error {
+	result := r.db.WithContext(ctx).Model(&Request{}).
+		Where("id = ? AND status = ?", id, StatusPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decided_by": decidedBy,
+			"decided_at": time.Now(),
+			"reason":     reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotPending
+	}
+	return nil
+}
+
+func (r *repository) SetResult(ctx context.Context, id, status, errMsg string) error {
+	return r.db.WithContext(ctx).Model(&Request{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "error": errMsg}).Error
+}
@@ -0,0 +1,251 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+var (
+	ErrNotPending    = errors.New("approval request is no longer pending")
+	ErrSelfApproval  = errors.New("requests must be approved by a different admin")
+	ErrNotAdmin      = errors.New("only organization admins can decide approval requests")
+	ErrUnknownAction = errors.New("unknown approval action")
+)
+
+// Executor performs an approved action. The payload is the one stored with
+// the request, so execution does not depend on the approver's input;
+// DecidedBy is the approver.
+type Executor func(ctx context.Context, req *Request) error
+
+// Admins tells which users administer an organization.
+type Admins interface {
+	IsAdmin(ctx context.Context, organizationID, userID string) (bool, error)
+	// OrganizationOf returns the organization a user administers per
+	// configuration, or ""
+	OrganizationOf(ctx context.Context, userID string) (string, error)
+}
+
+// Service implements the two-person rule: a high-risk action is stored as a
+// pending request and only executed once a second admin of the same
+// organization approves it.
+type Service struct {
+	repo      Repository
+	admins    Admins
+	ttl       time.Duration
+	executors map[string]Executor
+}
+
+func NewService(repo Repository, admins Admins, ttl time.Duration) *Service {
+	return &Service{
+		repo:      repo,
+		admins:    admins,
+		ttl:       ttl,
+		executors: make(map[string]Executor),
+	}
+}
+
+// Register sets the executor of an action. Call before serving requests.
+func (s *Service) Register(action string, executor Executor) {
+	s.executors[action] = executor
+}
+
+// OrganizationOf returns the organization whose rules apply to the user,
+// or "" for personal accounts, which need no approvals.
+func (s *Service) OrganizationOf(ctx context.Context, userID string) (string, error) {
+	return s.admins.OrganizationOf(ctx, userID)
+}
+
+func (s *Service) Submit(ctx context.Context, organizationID, userID, action, summary string, payload interface{}) (*Request, error) {
+	if _, ok := s.executors[action]; !ok {
+		return nil, ErrUnknownAction
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req := &Request{
+		ID:             uuid.New().String(),
+		OrganizationID: organizationID,
+		Action:         action,
+		Payload:        data,
+		Summary:        summary,
+		RequestedBy:    userID,
+		Status:         StatusPending,
+		ExpiresAt:      time.Now().Add(s.ttl),
+	}
+	if err := s.repo.Create(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// List returns the requests of the organization, which the user must
+// administer. Without an organization it lists those of the one the user
+// administers per configuration.
+func (s *Service) List(ctx context.Context, organizationID, userID, status string) ([]Request, error) {
+	if organizationID == "" {
+		var err error
+		if organizationID, err = s.admins.OrganizationOf(ctx, userID); err != nil {
+			return nil, err
+		}
+		if organizationID == "" {
+			return nil, ErrNotAdmin
+		}
+	}
+
+	admin, err := s.admins.IsAdmin(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		return nil, ErrNotAdmin
+	}
+	return s.repo.List(ctx, organizationID, status)
+}
+
+// Approve records the decision and runs the action. The request is marked
+// executed or failed according to the executor's result.
+func (s *Service) Approve(ctx context.Context, id, userID string) (*Request, error) {
+	req, err := s.decidable(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	executor, ok := s.executors[req.Action]
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+
+	// The conditional update makes concurrent approvals execute only once
+	if err := s.repo.Decide(ctx, id, StatusExecuted, userID, ""); err != nil {
+		return nil, err
+	}
+	req.Status, req.DecidedBy = StatusExecuted, userID
+
+	status, errMsg := StatusExecuted, ""
+	if err := executor(ctx, req); err != nil {
+		status, errMsg = StatusFailed, err.Error()
+		if err := s.repo.SetResult(ctx, id, status, errMsg); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.Get(ctx, id)
+}
+
+func (s *Service) Reject(ctx context.Context, id, userID, reason string) (*Request, error) {
+	if _, err := s.decidable(ctx, id, userID); err != nil {
+		// The requester may withdraw their own request
+		if !errors.Is(err, ErrSelfApproval) {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Decide(ctx, id, StatusRejected, userID, reason); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+// decidable loads a pending request the user is allowed to decide.
+func (s *Service) decidable(ctx context.Context, id, userID string) (*Request, error) {
+	req, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.admins.IsAdmin(ctx, req.OrganizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		// Do not reveal requests of other organizations
+		return nil, ErrRequestNotFound
+	}
+
+	if req.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if time.Now().After(req.ExpiresAt) {
+		if err := s.repo.SetResult(ctx, id, StatusExpired, ""); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotPending
+	}
+	if req.RequestedBy == userID {
+		return req, ErrSelfApproval
+	}
+	return req, nil
+}
+
+// StaticAdmins maps organization IDs to their admin user IDs from
+// configuration.
+type StaticAdmins map[string][]string
+
+func (a StaticAdmins) IsAdmin(ctx context.Context, organizationID, userID string) (bool, error) {
+	for _, id := range a[organizationID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// OrganizationOf returns the first organization in ID order the user is
+// configured to administer, so a user listed in several always gets the
+// same one.
+func (a StaticAdmins) OrganizationOf(ctx context.Context, userID string) (string, error) {
+	organizationIDs := make([]string, 0, len(a))
+	for organizationID := range a {
+		organizationIDs = append(organizationIDs, organizationID)
+	}
+	sort.Strings(organizationIDs)
+
+	for _, organizationID := range organizationIDs {
+		if ok, _ := a.IsAdmin(ctx, organizationID, userID); ok {
+			return organizationID, nil
+		}
+	}
+	return "", nil
+}
+
+// OrgAdmins are the admins and owners of organizations, plus the admins
+// configured statically. Static entries also decide which organization a
+// personal account's approvals go to.
+type OrgAdmins struct {
+	Orgs   Memberships
+	Static StaticAdmins
+}
+
+// Memberships looks up a user's role in an organization, returning
+// org.ErrNotMember for non-members.
+type Memberships interface {
+	MemberOf(ctx context.Context, orgID, userID string) (*org.Membership, error)
+}
+
+func (a OrgAdmins) IsAdmin(ctx context.Context, organizationID, userID string) (bool, error) {
+	if ok, _ := a.Static.IsAdmin(ctx, organizationID, userID); ok {
+		return true, nil
+	}
+	m, err := a.Orgs.MemberOf(ctx, organizationID, userID)
+	if errors.Is(err, org.ErrNotMember) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return m.Role.AtLeast(org.RoleAdmin), nil
+}
+
+func (a OrgAdmins) OrganizationOf(ctx context.Context, userID string) (string, error) {
+	return a.Static.OrganizationOf(ctx, userID)
+}
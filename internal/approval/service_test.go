@@ -0,0 +1,108 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+type fakeMemberships map[string]org.Role
+
+func (f fakeMemberships) MemberOf(ctx context.Context, orgID, userID string) (*org.Membership, error) {
+	if userID == "broken" {
+		return nil, errors.New("database is down")
+	}
+	role, ok := f[orgID+"/"+userID]
+	if !ok {
+		return nil, org.ErrNotMember
+	}
+	return &org.Membership{Organization: org.Organization{ID: orgID}, Role: role}, nil
+}
+
+func TestOrgAdmins_IsAdmin(t *testing.T) {
+	admins := OrgAdmins{
+		Orgs: fakeMemberships{
+			"org-1/owner":  org.RoleOwner,
+			"org-1/admin":  org.RoleAdmin,
+			"org-1/member": org.RoleMember,
+		},
+		Static: StaticAdmins{"org-2": {"configured"}},
+	}
+
+	tests := []struct {
+		org, user string
+		want      bool
+	}{
+		{"org-1", "owner", true},
+		{"org-1", "admin", true},
+		{"org-1", "member", false},
+		{"org-1", "stranger", false},
+		{"org-2", "configured", true},
+		{"org-1", "configured", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.org+"/"+tt.user, func(t *testing.T) {
+			ok, err := admins.IsAdmin(context.Background(), tt.org, tt.user)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+
+	_, err := admins.IsAdmin(context.Background(), "org-1", "broken")
+	assert.Error(t, err)
+}
+
+func TestStaticAdmins_OrganizationOf(t *testing.T) {
+	admins := StaticAdmins{"org-b": {"both"}, "org-a": {"both"}, "org-c": {"one"}}
+
+	for i := 0; i < 20; i++ {
+		organizationID, err := admins.OrganizationOf(context.Background(), "both")
+		require.NoError(t, err)
+		assert.Equal(t, "org-a", organizationID)
+	}
+	organizationID, _ := admins.OrganizationOf(context.Background(), "stranger")
+	assert.Empty(t, organizationID)
+}
+
+type listingRepository struct {
+	Repository
+}
+
+func (listingRepository) List(ctx context.Context, organizationID, status string) ([]Request, error) {
+	return []Request{{OrganizationID: organizationID, Status: status}}, nil
+}
+
+func TestService_List(t *testing.T) {
+	service := NewService(listingRepository{}, OrgAdmins{
+		Orgs:   fakeMemberships{"org-1/admin": org.RoleAdmin, "org-1/member": org.RoleMember},
+		Static: StaticAdmins{"org-2": {"configured"}},
+	}, 0)
+
+	tests := []struct {
+		name, org, user string
+		want            string
+		err             error
+	}{
+		{"organization admin", "org-1", "admin", "org-1", nil},
+		{"member", "org-1", "member", "", ErrNotAdmin},
+		{"configured admin", "", "configured", "org-2", nil},
+		{"configured admin of another organization", "org-1", "configured", "", ErrNotAdmin},
+		{"no organization", "", "admin", "", ErrNotAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := service.List(context.Background(), tt.org, tt.user, StatusPending)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, reqs, 1)
+			assert.Equal(t, tt.want, reqs[0].OrganizationID)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+var (
+	ErrPasswordResetRequired = errors.New("password reset required")
+	// ErrAccountDeactivated refuses every login and token of a deactivated
+	// user, once their credentials or token checked out
+	ErrAccountDeactivated = errors.New("account deactivated")
+	ErrUnknownPlan        = errors.New("unknown plan")
+)
+
+// Subscription plans. Features such as backtest scheduling are tiered by
+// the user's plan.
+const (
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+// ValidPlan tells whether plan is one of the subscription plans.
+func ValidPlan(plan string) bool {
+	return plan == PlanFree || plan == PlanPro || plan == PlanEnterprise
+}
+
+// PlanChange records a user moving to another plan; billing prorates their
+// invoices by it.
+type PlanChange struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"user_id" gorm:"type:varchar(36);not null;index:idx_plan_changes_user,priority:1"`
+	From   string `json:"from" gorm:"column:from_plan;type:varchar(32);not null"`
+	To     string `json:"to" gorm:"column:to_plan;type:varchar(32);not null"`
+	// ChangedAt is when the new plan took effect
+	ChangedAt time.Time `json:"changed_at" gorm:"not null;index:idx_plan_changes_user,priority:2"`
+}
+
+// TableName sets the table name for GORM
+func (PlanChange) TableName() string {
+	return "plan_changes"
+}
+
+// SetUserActive deactivates or reactivates the user's account on behalf of
+// actorID. Deactivating signs the user out everywhere.
+func (s *Service) SetUserActive(ctx context.Context, actorID, userID string, active bool) (*User, error) {
+	if err := s.repo.SetActive(ctx, userID, active, time.Now()); err != nil {
+		return nil, err
+	}
+	eventType := AuditAccountReactivated
+	if !active {
+		eventType = AuditAccountDeactivated
+	}
+	s.audit(ctx, eventType, userID, actorID, nil)
+	return s.repo.GetByID(ctx, userID)
+}
+
+// SetUserPlan moves the user to another subscription plan on behalf of
+// actorID.
+func (s *Service) SetUserPlan(ctx context.Context, actorID, userID, plan string) (*User, error) {
+	if !ValidPlan(plan) {
+		return nil, ErrUnknownPlan
+	}
+	if _, err := s.repo.SetPlan(ctx, userID, plan, "", time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditPlanChanged, userID, actorID, map[string]string{"plan": plan})
+	return s.repo.GetByID(ctx, userID)
+}
+
+// ChangePlan moves the user to plan for billing if they are on from, or on
+// any plan when from is empty. It returns the user with the change, nil
+// when nothing changed.
+func (s *Service) ChangePlan(ctx context.Context, userID, plan, from string) (*User, *PlanChange, error) {
+	if !ValidPlan(plan) || from != "" && !ValidPlan(from) {
+		return nil, nil, ErrUnknownPlan
+	}
+	change, err := s.repo.SetPlan(ctx, userID, plan, from, time.Now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	if change != nil {
+		s.audit(ctx, AuditPlanChanged, userID, "", map[string]string{"plan": plan, "from": change.From, "by": "billing"})
+	}
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, change, nil
+}
+
+// ForcePasswordReset signs the user out everywhere and mails them a reset
+// link on behalf of actorID. Password logins fail until the user resets
+// their password, so a leaked one stops working.
+func (s *Service) ForcePasswordReset(ctx context.Context, actorID, userID string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RequirePasswordReset(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditPasswordResetForced, userID, actorID, nil)
+
+	go func() {
+		if err := s.resetter.Send(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("Failed to send forced password reset email to user %s: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// UserSessions lists the signed-in devices of any user, for staff.
+func (s *Service) UserSessions(ctx context.Context, userID string) ([]Session, error) {
+	if _, err := s.repo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.Sessions(ctx, userID)
+}
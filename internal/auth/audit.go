@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/batch"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/keyset"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Security events recorded in the audit log
+const (
+	AuditRegistered           = "registered"
+	AuditLoginSucceeded       = "login_succeeded"
+	AuditLoginFailed          = "login_failed"
+	AuditLoginStepUp          = "login_step_up"
+	AuditTokenRefreshed       = "token_refreshed"
+	AuditRefreshReused        = "refresh_token_reused"
+	AuditPasswordChanged      = "password_changed"
+	AuditPasswordReset        = "password_reset"
+	AuditSessionRevoked       = "session_revoked"
+	AuditSessionsRevoked      = "sessions_revoked"
+	AuditRolesChanged         = "roles_changed"
+	AuditAccountDeactivated   = "account_deactivated"
+	AuditAccountReactivated   = "account_reactivated"
+	AuditPasswordResetForced  = "password_reset_forced"
+	AuditAPIKeyCreated        = "api_key_created"
+	AuditAPIKeyDeleted        = "api_key_deleted"
+	AuditSSOConnectionCreated = "sso_connection_created"
+	AuditSSOConnectionDeleted = "sso_connection_deleted"
+	AuditAuthMethodLinked     = "auth_method_linked"
+	AuditAuthMethodRemoved    = "auth_method_removed"
+	AuditIPAllowlistChanged   = "ip_allowlist_changed"
+	AuditPlanChanged          = "plan_changed"
+	AuditEmailChangeRequested = "email_change_requested"
+	AuditEmailChanged         = "email_changed"
+	AuditEmailChangeUndone    = "email_change_undone"
+)
+
+const (
+	defaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+	auditPurgeBatchSize  = 10000
+)
+
+var ErrInvalidAuditCursor = keyset.ErrInvalidCursor
+
+// AuditEvent is a security relevant action on an account. UserID is the
+// account it concerns; ActorID is who acted, which differs from UserID
+// when staff change someone else's account. Failed logins with an unknown
+// email have neither.
+type AuditEvent struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	UserID    string `gorm:"type:varchar(36);index:idx_audit_events_user,priority:1"`
+	ActorID   string `gorm:"type:varchar(36)"`
+	Type      string `gorm:"type:varchar(50);not null;index:idx_audit_events_type,priority:1"`
+	IPAddress string `gorm:"type:varchar(45)"`
+	UserAgent string `gorm:"type:varchar(500)"`
+	// Details holds event specific values such as the failure reason
+	Details   map[string]string `gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time         `gorm:"not null;index:idx_audit_events_user,priority:2,sort:desc;index:idx_audit_events_type,priority:2,sort:desc;index:idx_audit_events_time,sort:desc"`
+}
+
+// TableName sets the table name for GORM
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// AuditQuery selects audit events, newest first. Empty fields match
+// everything.
+type AuditQuery struct {
+	UserID string
+	Type   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Cursor string
+}
+
+type AuditPage struct {
+	Events     []AuditEvent
+	NextCursor string
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, event *AuditEvent) error
+	// List returns a page of the events matching q. It returns
+	// ErrInvalidAuditCursor if the cursor was issued for another query.
+	List(ctx context.Context, q AuditQuery) (*AuditPage, error)
+	// DeleteBefore deletes the events recorded before cutoff
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Record(ctx context.Context, event *AuditEvent) error {
+	event.prepare()
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// prepare fills in the ID and time and fits the client fields to their
+// columns.
+func (e *AuditEvent) prepare() {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.IPAddress = truncate(e.IPAddress, 45)
+	e.UserAgent = truncate(e.UserAgent, 500)
+}
+
+func (r *auditRepository) List(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > MaxAuditPageSize {
+		limit = MaxAuditPageSize
+	}
+
+	tx := r.db.WithContext(ctx)
+	if q.UserID != "" {
+		tx = tx.Where("user_id = ?", q.UserID)
+	}
+	if q.Type != "" {
+		tx = tx.Where("type = ?", q.Type)
+	}
+	if !q.From.IsZero() {
+		tx = tx.Where("created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		tx = tx.Where("created_at < ?", q.To)
+	}
+	if q.Cursor != "" {
+		c, err := keyset.Decode(q.Cursor, "audit", auditFilters(q))
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("(created_at, id) < (?, ?)", c.Time, c.ID)
+	}
+
+	// One extra row tells whether another page exists
+	var events []AuditEvent
+	if err := tx.Order("created_at DESC").Order("id DESC").Limit(limit + 1).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		last := page.Events[limit-1]
+		page.NextCursor = keyset.Encode("audit", auditFilters(q), last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+// DeleteBefore deletes in batches, so purging a backlog of events such as
+// a credential stuffing burst does not hold one long transaction.
+func (r *auditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	for {
+		ids := r.db.WithContext(ctx).Model(&AuditEvent{}).
+			Select("id").Where("created_at < ?", cutoff).Limit(auditPurgeBatchSize)
+		result := r.db.WithContext(ctx).Where("id IN (?)", ids).Delete(&AuditEvent{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < auditPurgeBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// auditFilters lists what selects the events of a listing.
+func auditFilters(q AuditQuery) []string {
+	return []string{q.UserID, q.Type, keyset.Bound(q.From), keyset.Bound(q.To)}
+}
+
+// AuditWriter is an AuditRepository that records events with bulk inserts
+// through a batch writer, so bursts such as credential stuffing cost one
+// insert per flush rather than one per event. Recorded events show up in
+// listings once flushed. Record blocks while the writer is backed up.
+type AuditWriter struct {
+	AuditRepository
+	db     *gorm.DB
+	writer *batch.Writer[AuditEvent]
+}
+
+func NewAuditWriter(db *gorm.DB, cfg config.WriteBatchConfig) *AuditWriter {
+	w := &AuditWriter{AuditRepository: NewAuditRepository(db), db: db}
+	w.writer = batch.NewWriter("audit_events", cfg, w.flush)
+	return w
+}
+
+func (w *AuditWriter) Record(ctx context.Context, event *AuditEvent) error {
+	event.prepare()
+	return w.writer.Add(ctx, *event)
+}
+
+// Close writes the queued events before ctx is done.
+func (w *AuditWriter) Close(ctx context.Context) error {
+	return w.writer.Close(ctx)
+}
+
+func (w *AuditWriter) flush(ctx context.Context, events []AuditEvent) error {
+	// Events keep their IDs across retries, so a batch that was partly
+	// written is not recorded twice
+	err := w.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error
+	if err != nil {
+		return fmt.Errorf("failed to insert audit events: %w", err)
+	}
+	return nil
+}
+
+// AuditPurger deletes audit events once they are older than the
+// retention period.
+type AuditPurger struct {
+	audits AuditRepository
+	ttl    time.Duration
+}
+
+func NewAuditPurger(audits AuditRepository, ttl time.Duration) *AuditPurger {
+	return &AuditPurger{audits: audits, ttl: ttl}
+}
+
+// Run matches scheduler.JobFunc.
+func (p *AuditPurger) Run(ctx context.Context) error {
+	cutoff := time.Now().Add(-p.ttl)
+	deleted, err := p.audits.DeleteBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to purge audit events: %w", err)
+	}
+	log.Printf("Purged %d audit events recorded before %s", deleted, cutoff.Format(time.RFC3339))
+	return nil
+}
+
+// Auditor records security events. Failing to record never fails the
+// action itself.
+type Auditor struct {
+	repo AuditRepository
+}
+
+func NewAuditor(repo AuditRepository) *Auditor {
+	return &Auditor{repo: repo}
+}
+
+// Record stores the event, or logs why it could not.
+func (a *Auditor) Record(ctx context.Context, event *AuditEvent) {
+	// The action already happened; record it even if the caller went away
+	if err := a.repo.Record(context.WithoutCancel(ctx), event); err != nil {
+		log.Printf("Failed to record %s audit event of user %s: %v", event.Type, event.UserID, err)
+	}
+}
+
+// List pages through the recorded events.
+func (a *Auditor) List(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	return a.repo.List(ctx, q)
+}
+
+// audit records that actorID did eventType to userID's account, from the
+// client the gateway forwarded with the call. Details may be nil.
+func (s *Service) audit(ctx context.Context, eventType, userID, actorID string, details map[string]string) {
+	ip, userAgent := rpc.Client(ctx)
+	s.auditor.Record(ctx, &AuditEvent{
+		UserID:    userID,
+		ActorID:   actorID,
+		Type:      eventType,
+		IPAddress: ip,
+		UserAgent: userAgent,
+		Details:   details,
+	})
+}
+
+// SecurityEvents pages through the audit events of the user's account.
+func (s *Service) SecurityEvents(ctx context.Context, userID string, limit int, cursor string) (*AuditPage, error) {
+	return s.auditor.List(ctx, AuditQuery{UserID: userID, Limit: limit, Cursor: cursor})
+}
+
+// AuditEvents pages through the audit log for staff.
+func (s *Service) AuditEvents(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	return s.auditor.List(ctx, q)
+}
@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrSameEmail          = errors.New("new email matches the current one")
+	ErrEmailTaken         = errors.New("email already in use")
+	ErrInvalidEmailChange = errors.New("invalid email change token")
+	ErrEmailChangeExpired = errors.New("email change token expired")
+)
+
+// EmailChange moves a user to another email address. The address is only
+// written to the user once confirmed from the new mailbox, so the unique
+// index on users.email never holds an unconfirmed address, and a pending
+// change reserves nothing. The old mailbox gets a link that cancels the
+// change, or reverts it once confirmed; until the link expires no other
+// account may take the old address. Only hashes of the emailed tokens
+// are stored.
+type EmailChange struct {
+	ID          string `gorm:"primaryKey;type:varchar(36)"`
+	UserID      string `gorm:"type:varchar(36);not null;index"`
+	OldEmail    string `gorm:"not null;index"`
+	NewEmail    string `gorm:"not null"`
+	ConfirmHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	UndoHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	// ExpiresAt bounds confirming; UndoExpiresAt undoing
+	ExpiresAt     time.Time `gorm:"not null"`
+	UndoExpiresAt time.Time `gorm:"not null"`
+	ConfirmedAt   *time.Time
+	UndoneAt      *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (EmailChange) TableName() string {
+	return "email_changes"
+}
+
+type EmailChangeRepository interface {
+	// Create replaces the user's unconfirmed changes; confirmed ones stay
+	// undoable
+	Create(ctx context.Context, change *EmailChange) error
+	// Confirm moves the token's user to the new address, marks it
+	// verified and revokes their sessions
+	Confirm(ctx context.Context, confirmHash string, now time.Time) (*User, *EmailChange, error)
+	// Undo cancels the token's change, or if it was confirmed moves the
+	// user back to the old address, requires a password reset and revokes
+	// their sessions
+	Undo(ctx context.Context, undoHash string, now time.Time) (*User, *EmailChange, error)
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) Create(ctx context.Context, change *EmailChange) error {
+	if change.ID == "" {
+		change.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND confirmed_at IS NULL", change.UserID).Delete(&EmailChange{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *emailChangeRepository) Confirm(ctx context.Context, confirmHash string, now time.Time) (*User, *EmailChange, error) {
+	var user User
+	var change EmailChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("confirm_hash = ?", confirmHash).First(&change).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidEmailChange
+		}
+		if err != nil {
+			return err
+		}
+		if change.ConfirmedAt != nil || change.UndoneAt != nil {
+			return ErrInvalidEmailChange
+		}
+		if now.After(change.ExpiresAt) {
+			return ErrEmailChangeExpired
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", change.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		// The address may have been taken since the change was requested
+		if err := emailFree(tx, change.NewEmail, user.ID, now); err != nil {
+			return err
+		}
+
+		err = tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email":          change.NewEmail,
+			"email_verified": true,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, user.ID, now); err != nil {
+			return err
+		}
+		if err := tx.Model(&change).Update("confirmed_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", user.ID).First(&user).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, &change, nil
+}
+
+func (r *emailChangeRepository) Undo(ctx context.Context, undoHash string, now time.Time) (*User, *EmailChange, error) {
+	var user User
+	var change EmailChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("undo_hash = ?", undoHash).First(&change).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidEmailChange
+		}
+		if err != nil {
+			return err
+		}
+		if change.UndoneAt != nil {
+			return ErrInvalidEmailChange
+		}
+		if now.After(change.UndoExpiresAt) {
+			return ErrEmailChangeExpired
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", change.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&change).Update("undone_at", now).Error; err != nil {
+			return err
+		}
+		if change.ConfirmedAt == nil {
+			return nil
+		}
+
+		// Whoever changed the address knew the password, so it goes too.
+		// Later changes are reverted along with this one.
+		if err := emailFree(tx, change.OldEmail, user.ID, now); err != nil {
+			return err
+		}
+		err = tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email":                   change.OldEmail,
+			"email_verified":          true,
+			"password_reset_required": true,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&EmailChange{}).Where("user_id = ? AND undone_at IS NULL", user.ID).Update("undone_at", now).Error
+		if err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, user.ID, now); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", user.ID).First(&user).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, &change, nil
+}
+
+// emailFree fails with ErrEmailTaken when another user has the address or
+// it is reserved for them.
+func emailFree(tx *gorm.DB, address, userID string, now time.Time) error {
+	var count int64
+	if err := tx.Model(&User{}).Where("email = ? AND id <> ?", address, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return emailReserved(tx, address, userID, now)
+}
+
+// emailReserved fails with ErrEmailTaken when the address is the old one
+// of another user's confirmed change that can still be undone, so the
+// undo can give it back.
+func emailReserved(tx *gorm.DB, address, userID string, now time.Time) error {
+	var count int64
+	err := tx.Model(&EmailChange{}).
+		Where("old_email = ? AND user_id <> ? AND confirmed_at IS NOT NULL AND undone_at IS NULL AND undo_expires_at >= ?", address, userID, now).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// EmailChanger issues email change tokens and mails them to both
+// addresses.
+type EmailChanger struct {
+	repo    EmailChangeRepository
+	sender  email.Sender
+	ttl     time.Duration
+	undoTTL time.Duration
+	// link and undoLink are the pages the emailed links open
+	link     string
+	undoLink string
+}
+
+func NewEmailChanger(repo EmailChangeRepository, sender email.Sender, ttl, undoTTL time.Duration, link, undoLink string) *EmailChanger {
+	return &EmailChanger{repo: repo, sender: sender, ttl: ttl, undoTTL: undoTTL, link: link, undoLink: undoLink}
+}
+
+// Send issues a change of the user's address to newEmail, replacing
+// earlier unconfirmed ones, mails the confirmation link to the new address
+// and the undo link to the current one.
+func (c *EmailChanger) Send(ctx context.Context, user *User, newEmail string) error {
+	confirmToken, confirmHash, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+	undoToken, undoHash, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = c.repo.Create(ctx, &EmailChange{
+		UserID:        user.ID,
+		OldEmail:      user.Email,
+		NewEmail:      newEmail,
+		ConfirmHash:   confirmHash,
+		UndoHash:      undoHash,
+		ExpiresAt:     now.Add(c.ttl),
+		UndoExpiresAt: now.Add(c.undoTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store email change: %w", err)
+	}
+
+	confirmLink, err := tokenLink(c.link, confirmToken)
+	if err != nil {
+		return fmt.Errorf("invalid email change url: %w", err)
+	}
+	undoLink, err := tokenLink(c.undoLink, undoToken)
+	if err != nil {
+		return fmt.Errorf("invalid email change undo url: %w", err)
+	}
+
+	err = c.sender.Send(ctx, email.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that your account should use this address from now on by opening the link below. It expires in %s. You will be signed out everywhere once it is confirmed.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.FirstName, c.ttl, confirmLink),
+	})
+	if err != nil {
+		return err
+	}
+	return c.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone signed in to your account asked to change its email address to %s. The change takes effect once confirmed from that address.\n\nIf this was not you, open the link below within %s to cancel the change, or to undo it if it was already confirmed. Undoing signs you out everywhere and asks you to choose a new password.\n\n%s\n",
+			user.FirstName, newEmail, c.undoTTL, undoLink),
+	})
+}
+
+// Confirm consumes the confirmation token and returns the moved user and
+// their change.
+func (c *EmailChanger) Confirm(ctx context.Context, token string) (*User, *EmailChange, error) {
+	return c.repo.Confirm(ctx, hashVerificationToken(token), time.Now())
+}
+
+// Undo consumes the undo token and returns the user and their change.
+func (c *EmailChanger) Undo(ctx context.Context, token string) (*User, *EmailChange, error) {
+	return c.repo.Undo(ctx, hashVerificationToken(token), time.Now())
+}
+
+// RequestEmailChange starts moving the user to newEmail after checking
+// their password, since whoever controls the address controls the
+// account. Wrong passwords count toward the login lockout.
+func (s *Service) RequestEmailChange(ctx context.Context, user *User, password, newEmail, clientIP string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if address, err := mail.ParseAddress(newEmail); err != nil || address.Address != newEmail {
+		return ErrInvalidEmail
+	}
+	if newEmail == user.Email {
+		return ErrSameEmail
+	}
+	if err := s.lockout.Check(ctx, user.Email, clientIP); err != nil {
+		return err
+	}
+	if ok, _, err := s.hasher.Verify(user.PasswordHash, password); err != nil || !ok {
+		s.lockout.Failed(ctx, user.Email, clientIP, user.ID)
+		return ErrInvalidCredentials
+	}
+	s.lockout.Succeeded(ctx, user.Email)
+
+	switch _, err := s.repo.GetByEmail(ctx, newEmail); {
+	case err == nil:
+		return ErrEmailTaken
+	case !errors.Is(err, ErrUserNotFound):
+		return err
+	}
+
+	if err := s.emailChanges.Send(ctx, user, newEmail); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditEmailChangeRequested, user.ID, user.ID, map[string]string{"new_email": newEmail})
+	return nil
+}
+
+// ConfirmEmailChange moves the user to the address the token was sent to
+// and signs them out everywhere.
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) (*User, error) {
+	user, change, err := s.emailChanges.Confirm(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	s.existence.Added(ctx, user)
+	s.audit(ctx, AuditEmailChanged, user.ID, user.ID, map[string]string{
+		"old_email": change.OldEmail,
+		"new_email": change.NewEmail,
+	})
+	return user, nil
+}
+
+// UndoEmailChange cancels the change the token was sent about. A confirmed
+// change is reverted: the user gets the old address back, is signed out
+// everywhere and is mailed a password reset link there.
+func (s *Service) UndoEmailChange(ctx context.Context, token string) (*User, error) {
+	user, change, err := s.emailChanges.Undo(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditEmailChangeUndone, user.ID, user.ID, map[string]string{
+		"old_email": change.OldEmail,
+		"new_email": change.NewEmail,
+	})
+	if change.ConfirmedAt == nil {
+		return user, nil
+	}
+
+	go func() {
+		if err := s.resetter.Send(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("Failed to send password reset email to user %s: %v", user.ID, err)
+		}
+	}()
+	return user, nil
+}
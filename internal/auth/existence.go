@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+// rebuildBatchSize is how many users are read per query while rebuilding.
+const rebuildBatchSize = 5000
+
+var ErrUsernameTaken = errors.New("username already taken")
+
+var existenceChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_existence_checks_total",
+	Help: "Email and username existence checks, by field and how they were answered.",
+}, []string{"field", "result"})
+
+// Existence tells whether an email or username is taken. Bloom filters
+// answer most checks for free values without a query; values the filters
+// may contain, and every check while the filters are disabled, missing or
+// failing, are looked up in the database.
+type Existence struct {
+	repo      Repository
+	emails    *cache.BloomFilter
+	usernames *cache.BloomFilter
+}
+
+// NewExistence returns the checker. client may be nil when the filters
+// are disabled.
+func NewExistence(repo Repository, client redis.UniversalClient, cfg config.ExistenceFilterConfig) *Existence {
+	e := &Existence{repo: repo}
+	if cfg.Enabled {
+		e.emails = cache.NewBloomFilter(client, "auth:emails", cfg.ExpectedUsers, cfg.FalsePositiveRate)
+		e.usernames = cache.NewBloomFilter(client, "auth:usernames", cfg.ExpectedUsers, cfg.FalsePositiveRate)
+	}
+	return e
+}
+
+// EmailTaken reports whether an account uses the email.
+func (e *Existence) EmailTaken(ctx context.Context, email string) (bool, error) {
+	return e.taken(ctx, "email", e.emails, email, e.repo.GetByEmail)
+}
+
+// UsernameTaken reports whether an account uses the username.
+func (e *Existence) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	return e.taken(ctx, "username", e.usernames, username, e.repo.GetByUsername)
+}
+
+func (e *Existence) taken(ctx context.Context, field string, filter *cache.BloomFilter, value string, lookup func(context.Context, string) (*User, error)) (bool, error) {
+	// free is how a free value found in the database is counted: as a
+	// false positive only if the filter answered
+	free := "free"
+	if filter != nil {
+		maybe, err := filter.MightContain(ctx, value)
+		switch {
+		case err == nil && !maybe:
+			existenceChecks.WithLabelValues(field, "absent").Inc()
+			return false, nil
+		case err == nil:
+			free = "false_positive"
+		case !errors.Is(err, cache.ErrBloomNotBuilt):
+			log.Printf("Failed to check %s filter: %v", field, err)
+		}
+	}
+
+	_, err := lookup(ctx, value)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		existenceChecks.WithLabelValues(field, free).Inc()
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	existenceChecks.WithLabelValues(field, "taken").Inc()
+	return true, nil
+}
+
+// Added records a new account in the filters. A failure is only logged:
+// the next rebuild picks the account up, and until then its values may be
+// reported free, which the unique indexes still enforce.
+func (e *Existence) Added(ctx context.Context, user *User) {
+	if e.emails == nil {
+		return
+	}
+	if err := e.emails.Add(ctx, user.Email); err != nil {
+		log.Printf("Failed to add user %s to the email filter: %v", user.ID, err)
+	}
+	if err := e.usernames.Add(ctx, user.Username); err != nil {
+		log.Printf("Failed to add user %s to the username filter: %v", user.ID, err)
+	}
+}
+
+// Rebuild refills the filters from the users table, which drops deleted
+// and changed values. It matches scheduler.JobFunc.
+func (e *Existence) Rebuild(ctx context.Context) error {
+	if e.emails == nil {
+		return nil
+	}
+
+	emails, err := e.emails.Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+	usernames, err := e.usernames.Rebuild(ctx)
+	if err != nil {
+		emails.Abort(ctx)
+		return err
+	}
+
+	err = e.repo.Identities(ctx, rebuildBatchSize, func(users []User) error {
+		batchEmails := make([]string, len(users))
+		batchUsernames := make([]string, len(users))
+		for i, user := range users {
+			batchEmails[i] = user.Email
+			batchUsernames[i] = user.Username
+		}
+		if err := emails.Add(ctx, batchEmails...); err != nil {
+			return err
+		}
+		return usernames.Add(ctx, batchUsernames...)
+	})
+	if err != nil {
+		emails.Abort(ctx)
+		usernames.Abort(ctx)
+		return err
+	}
+
+	if err := emails.Commit(ctx); err != nil {
+		usernames.Abort(ctx)
+		return err
+	}
+	return usernames.Commit(ctx)
+}
+
+// Availability reports whether the email and username are free. Empty
+// values are not checked and come back nil.
+func (s *Service) Availability(ctx context.Context, email, username string) (*bool, *bool, error) {
+	var emailFree, usernameFree *bool
+	if email != "" {
+		taken, err := s.existence.EmailTaken(ctx, email)
+		if err != nil {
+			return nil, nil, err
+		}
+		free := !taken
+		emailFree = &free
+	}
+	if username != "" {
+		taken, err := s.existence.UsernameTaken(ctx, username)
+		if err != nil {
+			return nil, nil, err
+		}
+		free := !taken
+		usernameFree = &free
+	}
+	return emailFree, usernameFree, nil
+}
@@ -2,20 +2,45 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
 
 	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const (
+	defaultUsersPage = 50
+	maxUsersPage     = 500
+)
+
+// errAccountDeactivated answers logins and tokens of deactivated users.
+// Login, refresh and token validation use PermissionDenied for nothing
+// else, so clients can tell it apart from bad credentials.
+var errAccountDeactivated = status.Error(codes.PermissionDenied, "Account deactivated")
+
 type GRPCServer struct {
 	authpb.UnimplementedAuthServiceServer
 	service *Service
+	// tokens is shared with the gateway, which caches validations in it
+	tokens *cache.TokenCache
+	// serviceKey authenticates the callers of service RPCs
+	serviceKey string
 }
 
-func NewGRPCServer(service *Service) *GRPCServer {
-	return &GRPCServer{service: service}
+func NewGRPCServer(service *Service, tokens *cache.TokenCache, serviceKey string) *GRPCServer {
+	return &GRPCServer{service: service, tokens: tokens, serviceKey: serviceKey}
 }
 
 func (s *GRPCServer) Register(ctx context.Context, req *authpb.RegisterRequest) (*authpb.AuthResponse, error) {
@@ -26,6 +51,12 @@ func (s *GRPCServer) Register(ctx context.Context, req *authpb.RegisterRequest)
 		Password:  req.Password,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Client: ClientInfo{
+			Device:    req.Device,
+			IP:        req.ClientIp,
+			UserAgent: req.UserAgent,
+			Country:   req.Country,
+		},
 	}
 
 	// Call service
@@ -34,6 +65,8 @@ func (s *GRPCServer) Register(ctx context.Context, req *authpb.RegisterRequest)
 		switch err {
 		case ErrUserExists:
 			return nil, status.Error(codes.AlreadyExists, "User already exists")
+		case ErrUsernameTaken:
+			return nil, status.Error(codes.AlreadyExists, "Username already taken")
 		default:
 			return nil, status.Error(codes.Internal, "Internal server error")
 		}
@@ -51,16 +84,37 @@ func (s *GRPCServer) Register(ctx context.Context, req *authpb.RegisterRequest)
 func (s *GRPCServer) Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.AuthResponse, error) {
 	// Convert protobuf request to internal request
 	loginReq := &LoginRequest{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:     req.Email,
+		Password:  req.Password,
+		Scopes:    req.Scopes,
+		ClientIP:  req.ClientIp,
+		UserAgent: req.UserAgent,
+		Device:    req.Device,
+		Country:   req.Country,
 	}
 
 	// Call service
 	resp, err := s.service.Login(ctx, loginReq)
+	var lockedOut *LockedOutError
+	if errors.As(err, &lockedOut) {
+		st, _ := status.New(codes.ResourceExhausted, lockedOut.Error()).WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(lockedOut.RetryAfter),
+		})
+		return nil, st.Err()
+	}
+	if errors.Is(err, ErrInvalidScope) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		switch err {
 		case ErrInvalidCredentials:
 			return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		case ErrPasswordResetRequired:
+			return nil, status.Error(codes.FailedPrecondition, "Password reset required; check your email for the link")
+		case ErrStepUpRequired:
+			return nil, status.Error(codes.FailedPrecondition, "Sign-in from a new country or device; check your email for the link to confirm it")
+		case ErrAccountDeactivated:
+			return nil, errAccountDeactivated
 		default:
 			return nil, status.Error(codes.Internal, "Internal server error")
 		}
@@ -77,19 +131,29 @@ func (s *GRPCServer) Login(ctx context.Context, req *authpb.LoginRequest) (*auth
 
 func (s *GRPCServer) RefreshToken(ctx context.Context, req *authpb.RefreshTokenRequest) (*authpb.AuthResponse, error) {
 	resp, err := s.service.RefreshToken(ctx, req.RefreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		log.Printf("Refresh token reused; revoked its session")
+	}
+	if errors.Is(err, ErrAccountDeactivated) {
+		return nil, errAccountDeactivated
+	}
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
 	}
 
 	return &authpb.AuthResponse{
-		AccessToken: resp.AccessToken,
-		User:        s.userToProto(resp.User),
-		ExpiresIn:   resp.ExpiresIn,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		User:         s.userToProto(resp.User),
+		ExpiresIn:    resp.ExpiresIn,
 	}, nil
 }
 
 func (s *GRPCServer) ValidateToken(ctx context.Context, req *authpb.ValidateTokenRequest) (*authpb.ValidateTokenResponse, error) {
-	user, err := s.service.ValidateToken(ctx, req.AccessToken)
+	user, claims, err := s.authenticate(ctx, req.AccessToken)
+	if errors.Is(err, ErrAccountDeactivated) {
+		return nil, errAccountDeactivated
+	}
 	if err != nil {
 		return &authpb.ValidateTokenResponse{
 			Valid: false,
@@ -97,14 +161,43 @@ func (s *GRPCServer) ValidateToken(ctx context.Context, req *authpb.ValidateToke
 		}, nil
 	}
 
+	// The gateway checks admin routes against these
+	roles, permissions, err := s.service.Access(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to load roles")
+	}
+	// The gateway checks cached validations, and trading routes in
+	// either mode, against the allowlist
+	allowlist, err := s.service.IPAllowlist(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to load IP allowlist")
+	}
+	if ip, _ := rpc.Client(ctx); ip != "" && allowlist.Check(ip) != nil {
+		return nil, status.Error(codes.PermissionDenied, ErrIPNotAllowed.Error())
+	}
+	pbUser := s.userToProto(user)
+	pbUser.Roles = roles
+	pbUser.Permissions = permissions
+	pbUser.Scopes = claims.Scopes
+	pbUser.AllowedNetworks = allowlist.CIDRs()
+	pbUser.IpAllowlistMode = allowlist.Mode
+
 	return &authpb.ValidateTokenResponse{
 		Valid: true,
-		User:  s.userToProto(user),
+		User:  pbUser,
 	}, nil
 }
 
 func (s *GRPCServer) Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error) {
-	// TODO: Implement token blacklisting in Redis
+	_, claims, err := s.authenticate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	// The token only has to stay blacklisted until it would have expired
+	if err := s.tokens.Revoke(ctx, req.AccessToken, claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to revoke token")
+	}
+
 	return &authpb.LogoutResponse{
 		Success: true,
 		Message: "Logged out successfully",
@@ -112,17 +205,1039 @@ func (s *GRPCServer) Logout(ctx context.Context, req *authpb.LogoutRequest) (*au
 }
 
 func (s *GRPCServer) ChangePassword(ctx context.Context, req *authpb.ChangePasswordRequest) (*authpb.ChangePasswordResponse, error) {
-	// TODO: Implement password change logic
-	// 1. Validate access token
-	// 2. Get user from token
-	// 3. Verify old password
-	// 4. Update password
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	err = s.service.ChangePassword(ctx, user, req.OldPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return nil, status.Error(codes.PermissionDenied, "Current password is incorrect")
+	case errors.Is(err, ErrPasswordTooShort):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to change password")
+	}
+	// The change revoked the user's sessions, but cached validations would
+	// keep their access tokens working until they expire
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
 	return &authpb.ChangePasswordResponse{
 		Success: true,
-		Message: "Password changed successfully",
+		Message: "Password changed; sign in again with the new password",
+	}, nil
+}
+
+func (s *GRPCServer) SetDataRegion(ctx context.Context, req *authpb.SetDataRegionRequest) (*authpb.SetDataRegionResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	user, err = s.service.SetDataRegion(ctx, user.ID, req.Region)
+	if errors.Is(err, ErrInvalidRegion) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to update data region")
+	}
+	// Cached validations carry the old region
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.SetDataRegionResponse{User: s.userToProto(user)}, nil
+}
+
+func (s *GRPCServer) SetAvatar(ctx context.Context, req *authpb.SetAvatarRequest) (*authpb.SetAvatarResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	user, previous, err := s.service.SetAvatar(ctx, user.ID, req.Avatar)
+	if errors.Is(err, ErrInvalidAvatar) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to update avatar")
+	}
+	// Cached validations carry the old avatar
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.SetAvatarResponse{User: s.userToProto(user), PreviousAvatar: previous}, nil
+}
+
+func (s *GRPCServer) VerifyEmail(ctx context.Context, req *authpb.VerifyEmailRequest) (*authpb.VerifyEmailResponse, error) {
+	user, err := s.service.VerifyEmail(ctx, req.Token)
+	switch {
+	case errors.Is(err, ErrInvalidVerification), errors.Is(err, ErrVerificationExpired):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to verify email")
+	}
+	// Cached validations still say the address is unverified
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.VerifyEmailResponse{User: s.userToProto(user)}, nil
+}
+
+func (s *GRPCServer) ResendVerification(ctx context.Context, req *authpb.ResendVerificationRequest) (*authpb.ResendVerificationResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	err = s.service.ResendVerification(ctx, user)
+	switch {
+	case errors.Is(err, ErrAlreadyVerified):
+		return nil, status.Error(codes.FailedPrecondition, "Email already verified")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to send verification email")
+	}
+
+	return &authpb.ResendVerificationResponse{
+		Success: true,
+		Message: "Verification email sent",
+	}, nil
+}
+
+func (s *GRPCServer) ForgotPassword(ctx context.Context, req *authpb.ForgotPasswordRequest) (*authpb.ForgotPasswordResponse, error) {
+	// The answer is the same whether or not the account exists
+	s.service.ForgotPassword(ctx, req.Email)
+
+	return &authpb.ForgotPasswordResponse{
+		Success: true,
+		Message: "If an account uses this email, a password reset link has been sent",
+	}, nil
+}
+
+func (s *GRPCServer) ResetPassword(ctx context.Context, req *authpb.ResetPasswordRequest) (*authpb.ResetPasswordResponse, error) {
+	user, err := s.service.ResetPassword(ctx, req.Token, req.NewPassword)
+	switch {
+	case errors.Is(err, ErrInvalidPasswordReset), errors.Is(err, ErrPasswordResetExpired), errors.Is(err, ErrPasswordTooShort):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+	// The reset revoked the user's sessions, but cached validations would
+	// keep their access tokens working until they expire
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.ResetPasswordResponse{
+		Success: true,
+		Message: "Password reset successfully",
+	}, nil
+}
+
+func (s *GRPCServer) RequestEmailChange(ctx context.Context, req *authpb.RequestEmailChangeRequest) (*authpb.RequestEmailChangeResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	err = s.service.RequestEmailChange(ctx, user, req.Password, req.NewEmail, req.ClientIp)
+	var lockedOut *LockedOutError
+	switch {
+	case errors.As(err, &lockedOut):
+		st, _ := status.New(codes.ResourceExhausted, lockedOut.Error()).WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(lockedOut.RetryAfter),
+		})
+		return nil, st.Err()
+	case errors.Is(err, ErrInvalidCredentials):
+		return nil, status.Error(codes.PermissionDenied, "Password is incorrect")
+	case errors.Is(err, ErrInvalidEmail), errors.Is(err, ErrSameEmail):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrEmailTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to request email change")
+	}
+
+	return &authpb.RequestEmailChangeResponse{
+		Success: true,
+		Message: "Confirmation link sent to the new address",
+	}, nil
+}
+
+func (s *GRPCServer) ConfirmEmailChange(ctx context.Context, req *authpb.ConfirmEmailChangeRequest) (*authpb.ConfirmEmailChangeResponse, error) {
+	user, err := s.service.ConfirmEmailChange(ctx, req.Token)
+	switch {
+	case errors.Is(err, ErrInvalidEmailChange), errors.Is(err, ErrEmailChangeExpired):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrEmailTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to change email")
+	}
+	// The change revoked the user's sessions, but cached validations would
+	// keep their access tokens working until they expire
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.ConfirmEmailChangeResponse{User: s.userToProto(user)}, nil
+}
+
+func (s *GRPCServer) UndoEmailChange(ctx context.Context, req *authpb.UndoEmailChangeRequest) (*authpb.UndoEmailChangeResponse, error) {
+	user, err := s.service.UndoEmailChange(ctx, req.Token)
+	switch {
+	case errors.Is(err, ErrInvalidEmailChange), errors.Is(err, ErrEmailChangeExpired):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrEmailTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to undo email change")
+	}
+	// Reverting a confirmed change revoked the user's sessions
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.UndoEmailChangeResponse{User: s.userToProto(user)}, nil
+}
+
+func (s *GRPCServer) RequestMagicLink(ctx context.Context, req *authpb.RequestMagicLinkRequest) (*authpb.RequestMagicLinkResponse, error) {
+	// The answer is the same whether or not the account exists
+	s.service.RequestMagicLink(ctx, req.Email)
+
+	return &authpb.RequestMagicLinkResponse{
+		Success: true,
+		Message: "If an account uses this email, a sign-in link has been sent",
+	}, nil
+}
+
+func (s *GRPCServer) MagicLinkLogin(ctx context.Context, req *authpb.MagicLinkLoginRequest) (*authpb.AuthResponse, error) {
+	resp, err := s.service.MagicLinkLogin(ctx, req.Token, ClientInfo{
+		Device:    req.Device,
+		IP:        req.ClientIp,
+		UserAgent: req.UserAgent,
+		Country:   req.Country,
+	})
+	switch {
+	case errors.Is(err, ErrInvalidMagicLink), errors.Is(err, ErrMagicLinkExpired):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrAccountDeactivated):
+		return nil, errAccountDeactivated
+	case errors.Is(err, ErrPasswordResetRequired):
+		return nil, status.Error(codes.FailedPrecondition, "Password reset required; check your email for the link")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &authpb.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		User:         s.userToProto(resp.User),
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+func (s *GRPCServer) StartSSO(ctx context.Context, req *authpb.StartSSORequest) (*authpb.StartSSOResponse, error) {
+	authorizationURL, err := s.service.StartSSO(ctx, req.Email, req.Binding)
+	switch {
+	case errors.Is(err, ErrInvalidSSOState):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrSSOConnectionNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrSSOProviderFailed):
+		log.Printf("Failed to start single sign-on: %v", err)
+		return nil, status.Error(codes.Unavailable, "Identity provider unavailable")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &authpb.StartSSOResponse{AuthorizationUrl: authorizationURL}, nil
+}
+
+func (s *GRPCServer) CompleteSSO(ctx context.Context, req *authpb.CompleteSSORequest) (*authpb.AuthResponse, error) {
+	resp, err := s.service.CompleteSSO(ctx, req.State, req.Binding, req.Code, ClientInfo{
+		Device:    req.Device,
+		IP:        req.ClientIp,
+		UserAgent: req.UserAgent,
+		Country:   req.Country,
+	})
+	switch {
+	case errors.Is(err, ErrInvalidSSOState), errors.Is(err, ErrSSOStateExpired):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrSSOLoginRejected):
+		log.Printf("Single sign-on rejected: %v", err)
+		return nil, status.Error(codes.Unauthenticated, ErrSSOLoginRejected.Error())
+	case errors.Is(err, ErrSSOProviderFailed):
+		log.Printf("Failed to complete single sign-on: %v", err)
+		return nil, status.Error(codes.Unavailable, "Identity provider unavailable")
+	case errors.Is(err, ErrAccountDeactivated):
+		return nil, errAccountDeactivated
+	case errors.Is(err, ErrPasswordResetRequired):
+		// FailedPrecondition is taken by accounts the provider is not linked to
+		return nil, status.Error(codes.PermissionDenied, "Password reset required; check your email for the link")
+	case errors.Is(err, ErrIdentityLinked):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrSSOAccountExists):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &authpb.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		User:         s.userToProto(resp.User),
+		ExpiresIn:    resp.ExpiresIn,
+		OrgId:        resp.OrgID,
+		OrgRoles:     resp.OrgRoles,
+	}, nil
+}
+
+func (s *GRPCServer) RevokeSessions(ctx context.Context, req *authpb.RevokeSessionsRequest) (*authpb.RevokeSessionsResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	if err := s.service.RevokeSessions(ctx, user.ID); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to revoke sessions")
+	}
+	// Cached validations would keep the revoked access tokens working
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.RevokeSessionsResponse{
+		Success: true,
+		Message: "Signed out of all sessions",
+	}, nil
+}
+
+func (s *GRPCServer) ListSessions(ctx context.Context, req *authpb.ListSessionsRequest) (*authpb.ListSessionsResponse, error) {
+	user, claims, err := s.authenticate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	sessions, err := s.service.Sessions(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to list sessions")
+	}
+
+	resp := &authpb.ListSessionsResponse{}
+	for i := range sessions {
+		session := sessionToProto(&sessions[i])
+		session.Current = sessions[i].ID == claims.SessionID
+		resp.Sessions = append(resp.Sessions, session)
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) RevokeSession(ctx context.Context, req *authpb.RevokeSessionRequest) (*authpb.RevokeSessionResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id required")
+	}
+
+	err = s.service.RevokeSession(ctx, user.ID, req.SessionId)
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		return nil, status.Error(codes.NotFound, "Session not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to revoke session")
+	}
+	// Cached validations would keep the session's access tokens working
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.RevokeSessionResponse{
+		Success: true,
+		Message: "Session signed out",
+	}, nil
+}
+
+func (s *GRPCServer) ListSecurityEvents(ctx context.Context, req *authpb.ListSecurityEventsRequest) (*authpb.ListSecurityEventsResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	page, err := s.service.SecurityEvents(ctx, user.ID, int(req.Limit), req.Cursor)
+	switch {
+	case errors.Is(err, ErrInvalidAuditCursor):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to list security events")
+	}
+
+	resp := &authpb.ListSecurityEventsResponse{NextCursor: page.NextCursor}
+	for i := range page.Events {
+		resp.Events = append(resp.Events, auditEventToProto(&page.Events[i]))
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) ListLogins(ctx context.Context, req *authpb.ListLoginsRequest) (*authpb.ListLoginsResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	logins, err := s.service.LoginHistory(ctx, user.ID, int(req.Limit))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to list logins")
+	}
+
+	resp := &authpb.ListLoginsResponse{}
+	for i := range logins {
+		resp.Logins = append(resp.Logins, loginEventToProto(&logins[i]))
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) ListAuthMethods(ctx context.Context, req *authpb.ListAuthMethodsRequest) (*authpb.ListAuthMethodsResponse, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	methods, err := s.service.AuthMethods(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to list sign-in methods")
+	}
+
+	resp := &authpb.ListAuthMethodsResponse{}
+	for i := range methods {
+		resp.Methods = append(resp.Methods, authMethodToProto(&methods[i]))
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) SetPassword(ctx context.Context, req *authpb.SetPasswordRequest) (*authpb.SetPasswordResponse, error) {
+	user, claims, err := s.authenticate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	err = s.service.SetPassword(ctx, user.ID, claims.SessionID, req.Password)
+	switch {
+	case errors.Is(err, ErrReauthRequired):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrPasswordTooShort):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrPasswordSet):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to set password")
+	}
+
+	return &authpb.SetPasswordResponse{
+		Success: true,
+		Message: "Password set",
+	}, nil
+}
+
+func (s *GRPCServer) LinkSSO(ctx context.Context, req *authpb.LinkSSORequest) (*authpb.StartSSOResponse, error) {
+	user, claims, err := s.authenticate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	authorizationURL, err := s.service.LinkSSO(ctx, user.ID, claims.SessionID, req.Email, req.Binding)
+	switch {
+	case errors.Is(err, ErrReauthRequired):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrInvalidSSOState):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrSSOConnectionNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrSSOProviderFailed):
+		log.Printf("Failed to start single sign-on: %v", err)
+		return nil, status.Error(codes.Unavailable, "Identity provider unavailable")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &authpb.StartSSOResponse{AuthorizationUrl: authorizationURL}, nil
+}
+
+func (s *GRPCServer) UnlinkAuthMethod(ctx context.Context, req *authpb.UnlinkAuthMethodRequest) (*authpb.UnlinkAuthMethodResponse, error) {
+	user, claims, err := s.authenticate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	err = s.service.UnlinkAuthMethod(ctx, user.ID, claims.SessionID, req.Type, req.ConnectionId)
+	switch {
+	case errors.Is(err, ErrReauthRequired):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrAuthMethodNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrLastAuthMethod):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to remove sign-in method")
+	}
+
+	return &authpb.UnlinkAuthMethodResponse{
+		Success: true,
+		Message: "Sign-in method removed",
+	}, nil
+}
+
+func (s *GRPCServer) GetIPAllowlist(ctx context.Context, req *authpb.GetIPAllowlistRequest) (*authpb.IPAllowlist, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	allowlist, err := s.service.IPAllowlist(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to load IP allowlist")
+	}
+	return ipAllowlistToProto(allowlist), nil
+}
+
+func (s *GRPCServer) AddAllowedNetwork(ctx context.Context, req *authpb.AddAllowedNetworkRequest) (*authpb.IPAllowlist, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	allowlist, err := s.service.AddAllowedNetwork(ctx, user.ID, req.Cidr, req.Label)
+	return s.allowlistChanged(ctx, user.ID, allowlist, err)
+}
+
+func (s *GRPCServer) RemoveAllowedNetwork(ctx context.Context, req *authpb.RemoveAllowedNetworkRequest) (*authpb.IPAllowlist, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	allowlist, err := s.service.RemoveAllowedNetwork(ctx, user.ID, req.Id)
+	return s.allowlistChanged(ctx, user.ID, allowlist, err)
+}
+
+func (s *GRPCServer) SetIPAllowlistMode(ctx context.Context, req *authpb.SetIPAllowlistModeRequest) (*authpb.IPAllowlist, error) {
+	user, err := s.validate(ctx, req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	allowlist, err := s.service.SetIPAllowlistMode(ctx, user.ID, req.Mode)
+	return s.allowlistChanged(ctx, user.ID, allowlist, err)
+}
+
+// allowlistChanged answers a change of the user's IP allowlist, dropping
+// the cached validations that carry the old one.
+func (s *GRPCServer) allowlistChanged(ctx context.Context, userID string, allowlist *IPAllowlist, err error) (*authpb.IPAllowlist, error) {
+	switch {
+	case errors.Is(err, ErrInvalidNetwork), errors.Is(err, ErrInvalidAllowlistMode):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrAllowedNetworkNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrTooManyNetworks), errors.Is(err, ErrAllowlistLockout):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to change IP allowlist")
+	}
+	if err := s.tokens.InvalidateUser(ctx, userID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", userID, err)
+	}
+	return ipAllowlistToProto(allowlist), nil
+}
+
+func (s *GRPCServer) CheckAvailability(ctx context.Context, req *authpb.CheckAvailabilityRequest) (*authpb.CheckAvailabilityResponse, error) {
+	if req.Email == "" && req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "email or username required")
+	}
+
+	emailFree, usernameFree, err := s.service.Availability(ctx, req.Email, req.Username)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to check availability")
+	}
+
+	return &authpb.CheckAvailabilityResponse{
+		EmailAvailable:    emailFree,
+		UsernameAvailable: usernameFree,
+	}, nil
+}
+
+func (s *GRPCServer) ListUsers(ctx context.Context, req *authpb.ListUsersRequest) (*authpb.ListUsersResponse, error) {
+	if _, err := s.authorize(ctx, req.AccessToken, PermissionUsersRead); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultUsersPage
+	}
+	if limit > maxUsersPage {
+		limit = maxUsersPage
+	}
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	users, roles, total, err := s.service.ListUsers(ctx, req.Query, limit, int(req.Offset))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to list users")
+	}
+
+	resp := &authpb.ListUsersResponse{Total: total}
+	for i := range users {
+		user := s.userToProto(&users[i])
+		user.Roles = roles[users[i].ID]
+		resp.Users = append(resp.Users, user)
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) SetUserRoles(ctx context.Context, req *authpb.SetUserRolesRequest) (*authpb.SetUserRolesResponse, error) {
+	caller, err := s.authorize(ctx, req.AccessToken, PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+	// Otherwise the last admin could lock everyone out of user management
+	if req.UserId == caller.ID && !slices.Contains(req.Roles, RoleAdmin) {
+		return nil, status.Error(codes.FailedPrecondition, "Admins cannot remove their own admin role")
+	}
+
+	user, err := s.service.SetUserRoles(ctx, caller.ID, req.UserId, req.Roles)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case errors.Is(err, ErrUnknownRole):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to set roles")
+	}
+	// Cached validations carry the old permissions
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	pbUser := s.userToProto(user)
+	pbUser.Roles = dedupe(req.Roles)
+	return &authpb.SetUserRolesResponse{User: pbUser}, nil
+}
+
+func (s *GRPCServer) ListAuditEvents(ctx context.Context, req *authpb.ListAuditEventsRequest) (*authpb.ListAuditEventsResponse, error) {
+	if _, err := s.authorize(ctx, req.AccessToken, PermissionAuditRead); err != nil {
+		return nil, err
+	}
+
+	q := AuditQuery{
+		UserID: req.UserId,
+		Type:   req.Type,
+		Limit:  int(req.Limit),
+		Cursor: req.Cursor,
+	}
+	if req.From != nil {
+		q.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		q.To = req.To.AsTime()
+	}
+
+	page, err := s.service.AuditEvents(ctx, q)
+	switch {
+	case errors.Is(err, ErrInvalidAuditCursor):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to list audit events")
+	}
+
+	resp := &authpb.ListAuditEventsResponse{NextCursor: page.NextCursor}
+	for i := range page.Events {
+		resp.Events = append(resp.Events, auditEventToProto(&page.Events[i]))
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) SetUserActive(ctx context.Context, req *authpb.SetUserActiveRequest) (*authpb.SetUserActiveResponse, error) {
+	caller, err := s.authorize(ctx, req.AccessToken, PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == caller.ID && !req.Active {
+		return nil, status.Error(codes.FailedPrecondition, "Admins cannot deactivate their own account")
+	}
+
+	user, err := s.service.SetUserActive(ctx, caller.ID, req.UserId, req.Active)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to update user")
+	}
+	// Cached validations would outlive the revoked sessions
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.SetUserActiveResponse{User: s.userToProto(user)}, nil
+}
+
+func (s *GRPCServer) SetUserPlan(ctx context.Context, req *authpb.SetUserPlanRequest) (*authpb.SetUserPlanResponse, error) {
+	caller, err := s.authorize(ctx, req.AccessToken, PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.SetUserPlan(ctx, caller.ID, req.UserId, req.Plan)
+	switch {
+	case errors.Is(err, ErrUnknownPlan):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to update user")
+	}
+	// Cached validations carry the old plan
+	if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+	}
+
+	return &authpb.SetUserPlanResponse{User: s.userToProto(user)}, nil
+}
+
+// ChangePlan moves a user to another plan for billing. Only the platform's
+// services may call it.
+func (s *GRPCServer) ChangePlan(ctx context.Context, req *authpb.ChangePlanRequest) (*authpb.ChangePlanResponse, error) {
+	if s.serviceKey == "" || subtle.ConstantTimeCompare([]byte(req.ServiceKey), []byte(s.serviceKey)) != 1 {
+		return nil, status.Error(codes.PermissionDenied, "Invalid service key")
+	}
+
+	user, change, err := s.service.ChangePlan(ctx, req.UserId, req.Plan, req.FromPlan)
+	switch {
+	case errors.Is(err, ErrUnknownPlan):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to change plan")
+	}
+
+	resp := &authpb.ChangePlanResponse{User: s.userToProto(user), PreviousPlan: user.Plan}
+	if change != nil {
+		resp.PreviousPlan, resp.Changed = change.From, true
+		// Cached validations carry the old plan
+		if err := s.tokens.InvalidateUser(ctx, user.ID); err != nil {
+			log.Printf("Failed to invalidate cached tokens of user %s: %v", user.ID, err)
+		}
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) ForcePasswordReset(ctx context.Context, req *authpb.ForcePasswordResetRequest) (*authpb.ForcePasswordResetResponse, error) {
+	caller, err := s.authorize(ctx, req.AccessToken, PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.service.ForcePasswordReset(ctx, caller.ID, req.UserId)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to force password reset")
+	}
+	if err := s.tokens.InvalidateUser(ctx, req.UserId); err != nil {
+		log.Printf("Failed to invalidate cached tokens of user %s: %v", req.UserId, err)
+	}
+
+	return &authpb.ForcePasswordResetResponse{
+		Success: true,
+		Message: "User signed out and sent a password reset link",
+	}, nil
+}
+
+func (s *GRPCServer) ListUserSessions(ctx context.Context, req *authpb.ListUserSessionsRequest) (*authpb.ListSessionsResponse, error) {
+	if _, err := s.authorize(ctx, req.AccessToken, PermissionUsersRead); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.service.UserSessions(ctx, req.UserId)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, "User not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to list sessions")
+	}
+
+	resp := &authpb.ListSessionsResponse{}
+	for i := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionToProto(&sessions[i]))
+	}
+	return resp, nil
+}
+
+// CreateSSOConnection only authenticates the caller; the gateway checked
+// that they administer the connection's organization.
+func (s *GRPCServer) CreateSSOConnection(ctx context.Context, req *authpb.CreateSSOConnectionRequest) (*authpb.CreateSSOConnectionResponse, error) {
+	if req.Connection.GetOrgId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Connection and its organization are required")
+	}
+	caller, err := s.authorizeSSO(ctx, req.AccessToken, req.Connection.OrgId)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &SSOConnection{
+		OrgID:        req.Connection.OrgId,
+		Organization: req.Connection.Organization,
+		Domain:       req.Connection.Domain,
+		Protocol:     req.Connection.Protocol,
+		Issuer:       req.Connection.Issuer,
+		ClientID:     req.Connection.ClientId,
+		ClientSecret: req.ClientSecret,
+		GroupsClaim:  req.Connection.GroupsClaim,
+		RoleMappings: req.Connection.RoleMappings,
+	}
+	err = s.service.CreateSSOConnection(ctx, caller.ID, conn)
+	switch {
+	case errors.Is(err, ErrInvalidSSOConnection):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrSSOConnectionExists):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to create connection")
+	}
+
+	return &authpb.CreateSSOConnectionResponse{Connection: ssoConnectionToProto(conn)}, nil
+}
+
+func (s *GRPCServer) ListSSOConnections(ctx context.Context, req *authpb.ListSSOConnectionsRequest) (*authpb.ListSSOConnectionsResponse, error) {
+	if _, err := s.authorizeSSO(ctx, req.AccessToken, req.OrgId); err != nil {
+		return nil, err
+	}
+
+	conns, err := s.service.ListSSOConnections(ctx, req.OrgId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to list connections")
+	}
+	resp := &authpb.ListSSOConnectionsResponse{Connections: make([]*authpb.SSOConnection, len(conns))}
+	for i := range conns {
+		resp.Connections[i] = ssoConnectionToProto(&conns[i])
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) DeleteSSOConnection(ctx context.Context, req *authpb.DeleteSSOConnectionRequest) (*authpb.DeleteSSOConnectionResponse, error) {
+	caller, err := s.authorizeSSO(ctx, req.AccessToken, req.OrgId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.service.DeleteSSOConnection(ctx, caller.ID, req.OrgId, req.Id)
+	switch {
+	case errors.Is(err, ErrSSOConnectionNotFound):
+		return nil, status.Error(codes.NotFound, "Connection not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Failed to delete connection")
+	}
+	return &authpb.DeleteSSOConnectionResponse{Success: true}, nil
+}
+
+// authorizeSSO authenticates the caller of an SSO connection RPC. Calls
+// for an organization were checked by the gateway, which keeps them; the
+// others span every organization and need the sso:manage permission.
+func (s *GRPCServer) authorizeSSO(ctx context.Context, token, orgID string) (*User, error) {
+	if orgID == "" {
+		return s.authorize(ctx, token, PermissionSSOManage)
+	}
+	user, err := s.validate(ctx, token)
+	if errors.Is(err, ErrAccountDeactivated) {
+		return nil, errAccountDeactivated
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return user, nil
+}
+
+// authorize authenticates the caller and checks that their roles grant the
+// permission. Errors are gRPC statuses.
+func (s *GRPCServer) authorize(ctx context.Context, token, permission string) (*User, error) {
+	user, err := s.validate(ctx, token)
+	if errors.Is(err, ErrAccountDeactivated) {
+		return nil, errAccountDeactivated
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	_, permissions, err := s.service.Access(ctx, user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to load permissions")
+	}
+	if !slices.Contains(permissions, permission) {
+		return nil, status.Errorf(codes.PermissionDenied, "Missing permission %s", permission)
+	}
+	return user, nil
+}
+
+func (s *GRPCServer) validate(ctx context.Context, token string) (*User, error) {
+	user, _, err := s.authenticate(ctx, token)
+	return user, err
+}
+
+// authenticate checks the token, then rejects it if its ID was blacklisted
+// by Logout.
+func (s *GRPCServer) authenticate(ctx context.Context, token string) (*User, *Claims, error) {
+	user, claims, err := s.service.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	revoked, err := s.tokens.Revoked(ctx, claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrTokenRevoked
+	}
+	return user, claims, nil
+}
+
+// GetVersion reports the build of the running auth service.
+func (s *GRPCServer) GetVersion(ctx context.Context, req *authpb.GetVersionRequest) (*authpb.GetVersionResponse, error) {
+	info := buildinfo.Get()
+	return &authpb.GetVersionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildTime: info.BuildTime,
+		GoVersion: info.GoVersion,
 	}, nil
 }
 
+// GetJWKS returns the public keys that verify tokens, which the gateway
+// caches to check signatures itself.
+func (s *GRPCServer) GetJWKS(ctx context.Context, req *authpb.GetJWKSRequest) (*authpb.GetJWKSResponse, error) {
+	return &authpb.GetJWKSResponse{Jwks: s.service.JWKS()}, nil
+}
+
+// IntrospectToken lets other services check any token and read what it
+// grants without parsing it themselves.
+func (s *GRPCServer) IntrospectToken(ctx context.Context, req *authpb.IntrospectTokenRequest) (*authpb.IntrospectTokenResponse, error) {
+	introspection, err := s.service.Introspect(ctx, req.Token, strings.TrimSuffix(req.TokenTypeHint, "_token"))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to introspect token")
+	}
+	if !introspection.Active {
+		return &authpb.IntrospectTokenResponse{}, nil
+	}
+	if introspection.TokenType == "access" {
+		// Logout blacklists access tokens here, not in the service
+		revoked, err := s.tokens.Revoked(ctx, introspection.TokenID)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Failed to introspect token")
+		}
+		if revoked {
+			return &authpb.IntrospectTokenResponse{}, nil
+		}
+	}
+
+	resp := &authpb.IntrospectTokenResponse{
+		Active:      true,
+		TokenType:   introspection.TokenType + "_token",
+		Subject:     introspection.UserID,
+		Scopes:      introspection.Scopes,
+		ExpiresAt:   timestamppb.New(introspection.ExpiresAt),
+		TokenId:     introspection.TokenID,
+		SessionId:   introspection.SessionID,
+		Permissions: introspection.Permissions,
+	}
+	if !introspection.IssuedAt.IsZero() {
+		resp.IssuedAt = timestamppb.New(introspection.IssuedAt)
+	}
+	return resp, nil
+}
+
+func sessionToProto(session *Session) *authpb.Session {
+	return &authpb.Session{
+		Id:         session.ID,
+		Device:     session.Device,
+		IpAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  timestamppb.New(session.CreatedAt),
+		LastSeenAt: timestamppb.New(session.LastSeenAt),
+		ExpiresAt:  timestamppb.New(session.ExpiresAt),
+	}
+}
+
+func auditEventToProto(event *AuditEvent) *authpb.AuditEvent {
+	return &authpb.AuditEvent{
+		Id:        event.ID,
+		UserId:    event.UserID,
+		ActorId:   event.ActorID,
+		Type:      event.Type,
+		IpAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Details:   event.Details,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+}
+
+func loginEventToProto(event *LoginEvent) *authpb.LoginEvent {
+	return &authpb.LoginEvent{
+		Id:                event.ID,
+		Method:            event.Method,
+		IpAddress:         event.IPAddress,
+		Country:           event.Country,
+		Device:            event.Device,
+		UserAgent:         event.UserAgent,
+		SuspiciousReasons: event.Reasons,
+		CreatedAt:         timestamppb.New(event.CreatedAt),
+	}
+}
+
+func authMethodToProto(method *AuthMethod) *authpb.AuthMethod {
+	return &authpb.AuthMethod{
+		Type:         method.Type,
+		ConnectionId: method.ConnectionID,
+		Organization: method.Organization,
+		Domain:       method.Domain,
+		LinkedAt:     timestamppb.New(method.LinkedAt),
+	}
+}
+
+func ipAllowlistToProto(allowlist *IPAllowlist) *authpb.IPAllowlist {
+	resp := &authpb.IPAllowlist{Mode: allowlist.Mode}
+	for _, network := range allowlist.Networks {
+		resp.Networks = append(resp.Networks, &authpb.AllowedNetwork{
+			Id:        network.ID,
+			Cidr:      network.CIDR,
+			Label:     network.Label,
+			CreatedAt: timestamppb.New(network.CreatedAt),
+		})
+	}
+	return resp
+}
+
+func ssoConnectionToProto(conn *SSOConnection) *authpb.SSOConnection {
+	return &authpb.SSOConnection{
+		Id:           conn.ID,
+		OrgId:        conn.OrgID,
+		Organization: conn.Organization,
+		Domain:       conn.Domain,
+		Protocol:     conn.Protocol,
+		Issuer:       conn.Issuer,
+		ClientId:     conn.ClientID,
+		GroupsClaim:  conn.GroupsClaim,
+		RoleMappings: conn.RoleMappings,
+		CreatedAt:    timestamppb.New(conn.CreatedAt),
+	}
+}
+
 // Helper function to convert internal User to protobuf User
 func (s *GRPCServer) userToProto(user *User) *authpb.User {
 	var createdAt, updatedAt, lastLoginAt *timestamppb.Timestamp
@@ -138,15 +1253,19 @@ func (s *GRPCServer) userToProto(user *User) *authpb.User {
 	}
 
 	return &authpb.User{
-		Id:          user.ID,
-		Email:       user.Email,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Avatar:      user.Avatar,
-		IsActive:    user.IsActive,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-		LastLoginAt: lastLoginAt,
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Avatar:        user.Avatar,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+		LastLoginAt:   lastLoginAt,
+		Timezone:      user.Timezone,
+		DataRegion:    user.DataRegion,
+		Plan:          user.Plan,
 	}
 }
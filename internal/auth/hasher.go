@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// PasswordHasher hashes passwords into self-describing strings: the
+// algorithm and its parameters are stored with the hash, so hashes made
+// with other settings still verify.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify tells whether the password matches the hash, and whether the
+	// hash should be replaced with a fresh one because it uses an older
+	// algorithm or parameters. An empty hash matches nothing.
+	Verify(hash, password string) (ok, rehash bool, err error)
+}
+
+const (
+	argon2idPrefix = "$argon2id$"
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+)
+
+// argon2idHasher hashes with argon2id in the PHC string format,
+// $argon2id$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<key>. It still
+// verifies bcrypt hashes, asking for them to be rehashed. Hashing waits
+// for a slot of the semaphore, so a burst of logins cannot take more
+// than its share of memory.
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	slots       chan struct{}
+}
+
+// NewPasswordHasher checks the parameters, which argon2 would otherwise
+// panic on at the first login.
+func NewPasswordHasher(cfg config.PasswordHashingConfig) (PasswordHasher, error) {
+	if cfg.Iterations == 0 || cfg.Parallelism == 0 {
+		return nil, errors.New("password hashing needs at least one iteration and one lane")
+	}
+	if cfg.MemoryKiB < 8*uint32(cfg.Parallelism) {
+		return nil, errors.New("password hashing needs at least 8 KiB of memory per lane")
+	}
+	if cfg.MaxConcurrent <= 0 {
+		return nil, errors.New("password hashing needs a positive max_concurrent")
+	}
+	return &argon2idHasher{
+		memory:      cfg.MemoryKiB,
+		iterations:  cfg.Iterations,
+		parallelism: cfg.Parallelism,
+		slots:       make(chan struct{}, cfg.MaxConcurrent),
+	}, nil
+}
+
+// idKey derives an argon2id key once a slot is free.
+func (h *argon2idHasher) idKey(password string, salt []byte, iterations, memory uint32, parallelism uint8, size uint32) []byte {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+	return argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, size)
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := h.idKey(password, salt, h.iterations, h.memory, h.parallelism, argon2KeySize)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *argon2idHasher) Verify(hash, password string) (bool, bool, error) {
+	switch {
+	case hash == "":
+		return false, false, nil
+	case strings.HasPrefix(hash, argon2idPrefix):
+		return h.verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2"):
+		// Accounts from before argon2id
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	default:
+		return false, false, ErrMalformedHash
+	}
+}
+
+func (h *argon2idHasher) verifyArgon2id(hash, password string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrMalformedHash
+	}
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	// argon2 panics on these rather than failing
+	if iterations == 0 || parallelism == 0 {
+		return false, false, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrMalformedHash
+	}
+
+	computed := h.idKey(password, salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false, nil
+	}
+	rehash := memory != h.memory || iterations != h.iterations || parallelism != h.parallelism
+	return true, rehash, nil
+}
+
+// rehashPassword replaces the user's hash with one of the current
+// algorithm and parameters after they signed in with the password. Like
+// audit events, failing to never fails the login.
+func (s *Service) rehashPassword(ctx context.Context, user *User, password string) {
+	hashed, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repo.RehashPassword(ctx, user.ID, user.PasswordHash, hashed)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hashed
+}
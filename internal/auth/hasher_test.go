@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testHasher keeps the argon2id parameters small so the tests run fast.
+func testHasher(t *testing.T, memoryKiB, iterations uint32) PasswordHasher {
+	t.Helper()
+	hasher, err := NewPasswordHasher(config.PasswordHashingConfig{
+		MemoryKiB:     memoryKiB,
+		Iterations:    iterations,
+		Parallelism:   1,
+		MaxConcurrent: 2,
+	})
+	require.NoError(t, err)
+	return hasher
+}
+
+func TestNewPasswordHasher_RejectsParameters(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PasswordHashingConfig
+	}{
+		{"no iterations", config.PasswordHashingConfig{MemoryKiB: 64, Parallelism: 1, MaxConcurrent: 1}},
+		{"no lanes", config.PasswordHashingConfig{MemoryKiB: 64, Iterations: 1, MaxConcurrent: 1}},
+		{"too little memory", config.PasswordHashingConfig{MemoryKiB: 15, Iterations: 1, Parallelism: 2, MaxConcurrent: 1}},
+		{"no concurrency", config.PasswordHashingConfig{MemoryKiB: 64, Iterations: 1, Parallelism: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPasswordHasher(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestArgon2idHasher_Verify(t *testing.T) {
+	hasher := testHasher(t, 64, 1)
+	current, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	older, err := testHasher(t, 32, 2).Hash("correct horse")
+	require.NoError(t, err)
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{name: "current parameters", hash: current, password: "correct horse", wantOK: true},
+		{name: "wrong password", hash: current, password: "battery staple"},
+		{name: "older parameters", hash: older, password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "older parameters, wrong password", hash: older, password: "battery staple"},
+		{name: "bcrypt", hash: string(legacy), password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "bcrypt, wrong password", hash: string(legacy), password: "battery staple"},
+		{name: "no password", hash: "", password: ""},
+		{name: "unknown algorithm", hash: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "plain text", hash: "correct horse", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "missing key", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "empty key", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "other version", hash: "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "garbled parameters", hash: "$argon2id$v=19$memory=64$c2FsdHNhbHRzYWx0c2FsdA$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "no iterations", hash: "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "no lanes", hash: "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "salt not base64", hash: "$argon2id$v=19$m=64,t=1,p=1$not*base64$a2V5", password: "correct horse", wantErr: ErrMalformedHash},
+		{name: "key not base64", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$not*base64", password: "correct horse", wantErr: ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := hasher.Verify(tt.hash, tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantRehash, rehash)
+		})
+	}
+}
+
+func TestArgon2idHasher_HashIsSalted(t *testing.T) {
+	hasher := testHasher(t, 64, 1)
+	first, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	second, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]+\$[A-Za-z0-9+/]+$`, first)
+}
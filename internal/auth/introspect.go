@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Introspection describes a token the way RFC 7662 does. Tokens that are
+// malformed, expired or revoked are only reported as inactive.
+type Introspection struct {
+	Active bool
+	// TokenType is "access" or "refresh"
+	TokenType string
+	UserID    string
+	SessionID string
+	TokenID   string
+	// Scopes are those the token was restricted to, or all of them
+	Scopes []string
+	// Permissions are those the user's roles grant now
+	Permissions []string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+}
+
+// Introspect reports whether the token is active and what it grants. The
+// hint, "access" or "refresh", only decides which type is tried first.
+// Blacklisted access tokens are left to the caller, which holds the
+// blacklist.
+func (s *Service) Introspect(ctx context.Context, token, hint string) (*Introspection, error) {
+	types := []string{"access", "refresh"}
+	if hint == "refresh" {
+		types = []string{"refresh", "access"}
+	}
+
+	for _, tokenType := range types {
+		var claims *Claims
+		var err error
+		if tokenType == "access" {
+			_, claims, err = s.ValidateToken(ctx, token)
+		} else {
+			claims, err = s.validateRefreshToken(ctx, token)
+		}
+		if inactive(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scopes := claims.Scopes
+		if tokenType == "refresh" {
+			// Refresh tokens pass their session's scopes on; families
+			// from before sessions were tracked have no row yet
+			session, err := s.sessions.Get(ctx, claims.SessionID)
+			switch {
+			case err == nil:
+				scopes = session.Scopes
+			case !errors.Is(err, ErrSessionNotFound):
+				return nil, err
+			}
+		}
+		if len(scopes) == 0 {
+			scopes = AllScopes
+		}
+		permissions, err := s.roles.Permissions(ctx, claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+		introspection := &Introspection{
+			Active:      true,
+			TokenType:   tokenType,
+			UserID:      claims.UserID,
+			SessionID:   claims.SessionID,
+			TokenID:     claims.ID,
+			Scopes:      scopes,
+			Permissions: permissions,
+			ExpiresAt:   claims.ExpiresAt.Time,
+		}
+		if claims.IssuedAt != nil {
+			introspection.IssuedAt = claims.IssuedAt.Time
+		}
+		return introspection, nil
+	}
+	return &Introspection{}, nil
+}
+
+// validateRefreshToken checks a refresh token without rotating it: it must
+// be the newest of its session and issued after the user's last sign-out
+// everywhere.
+func (s *Service) validateRefreshToken(ctx context.Context, token string) (*Claims, error) {
+	claims, err := s.tokenService.ValidateRefreshToken(token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	if issuedBeforeRevocation(claims, user) {
+		return nil, ErrTokenRevoked
+	}
+	stored, err := s.sessions.GetRefreshToken(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.RotatedAt != nil || stored.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	claims.SessionID = stored.FamilyID
+	return claims, nil
+}
+
+// inactive reports whether err rejects the token rather than failing to
+// check it.
+func inactive(err error) bool {
+	return errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrExpiredToken) ||
+		errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrAccountDeactivated)
+}
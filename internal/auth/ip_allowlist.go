@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Which requests a user's IP allowlist restricts. Trading and withdrawal
+// endpoints are restricted in both modes.
+const (
+	IPAllowlistAll     = "all"
+	IPAllowlistTrading = "trading"
+)
+
+const MaxAllowedNetworks = 50
+
+var (
+	ErrInvalidNetwork         = errors.New("invalid IP address or CIDR range")
+	ErrInvalidAllowlistMode   = errors.New("invalid IP allowlist mode")
+	ErrAllowedNetworkNotFound = errors.New("allowed network not found")
+	ErrTooManyNetworks        = fmt.Errorf("at most %d allowed networks", MaxAllowedNetworks)
+	ErrAllowlistLockout       = errors.New("the change would block your current IP address; allow it first")
+	ErrIPNotAllowed           = errors.New("IP address not allowed for this account")
+)
+
+// AllowedNetwork is a CIDR range the user's requests may come from. Once a
+// user has any, requests from elsewhere are rejected.
+type AllowedNetwork struct {
+	ID     string `gorm:"primaryKey;type:varchar(36)"`
+	UserID string `gorm:"type:varchar(36);not null;index"`
+	// CIDR is canonical; single addresses are stored as /32 or /128
+	CIDR      string    `gorm:"type:varchar(43);not null"`
+	Label     string    `gorm:"type:varchar(100)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (AllowedNetwork) TableName() string {
+	return "allowed_networks"
+}
+
+// IPAllowlist is a user's allowed networks and what they restrict. An
+// empty list allows every address.
+type IPAllowlist struct {
+	Mode     string
+	Networks []AllowedNetwork
+}
+
+// CIDRs returns the ranges of the allowed networks.
+func (l *IPAllowlist) CIDRs() []string {
+	cidrs := make([]string, 0, len(l.Networks))
+	for _, network := range l.Networks {
+		cidrs = append(cidrs, network.CIDR)
+	}
+	return cidrs
+}
+
+// Restricts tells whether the allowlist applies to all of the user's
+// requests rather than trading and withdrawal ones only.
+func (l *IPAllowlist) Restricts() bool {
+	return l.Mode != IPAllowlistTrading
+}
+
+// Check returns ErrIPNotAllowed when the allowlist restricts all of the
+// user's requests and ip is outside it. Trading mode leaves the check to
+// the trading and withdrawal endpoints.
+func (l *IPAllowlist) Check(ip string) error {
+	if l.Restricts() && !NetworksAllow(l.CIDRs(), ip) {
+		return ErrIPNotAllowed
+	}
+	return nil
+}
+
+// NetworksAllow tells whether ip is in one of the CIDR ranges. No ranges
+// allow every address; an unparsable address is allowed by none.
+func NetworksAllow(cidrs []string, ip string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNetwork turns an address or CIDR range into its canonical
+// range. Ranges covering every address are refused; they allow nothing
+// an empty list does not.
+func normalizeNetwork(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if ip := net.ParseIP(value); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return "", ErrInvalidNetwork
+	}
+	if ones, _ := network.Mask.Size(); ones == 0 {
+		return "", fmt.Errorf("%w: %s matches every address", ErrInvalidNetwork, value)
+	}
+	return network.String(), nil
+}
+
+type IPAllowlistRepository interface {
+	Get(ctx context.Context, userID string) (*IPAllowlist, error)
+	// Add adds the network unless the user has MaxAllowedNetworks, or
+	// check refuses the resulting allowlist
+	Add(ctx context.Context, network *AllowedNetwork, check func(*IPAllowlist) error) error
+	// Remove removes the user's network unless check refuses the
+	// resulting allowlist
+	Remove(ctx context.Context, userID, id string, check func(*IPAllowlist) error) error
+	// SetMode sets what the allowlist restricts unless check refuses the
+	// result
+	SetMode(ctx context.Context, userID, mode string, check func(*IPAllowlist) error) error
+}
+
+type ipAllowlistRepository struct {
+	db *gorm.DB
+}
+
+func NewIPAllowlistRepository(db *gorm.DB) IPAllowlistRepository {
+	return &ipAllowlistRepository{db: db}
+}
+
+func (r *ipAllowlistRepository) Get(ctx context.Context, userID string) (*IPAllowlist, error) {
+	return r.load(r.db.WithContext(ctx), userID, false)
+}
+
+// load reads the allowlist, locking the user's row inside transactions so
+// concurrent changes are checked one after another.
+func (r *ipAllowlistRepository) load(db *gorm.DB, userID string, lock bool) (*IPAllowlist, error) {
+	query := db.Select("id", "ip_allowlist_mode").Where("id = ?", userID)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var user User
+	err := query.First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := &IPAllowlist{Mode: user.IPAllowlistMode, Networks: []AllowedNetwork{}}
+	if list.Mode == "" {
+		list.Mode = IPAllowlistAll
+	}
+	err = db.Where("user_id = ?", userID).Order("created_at, id").Find(&list.Networks).Error
+	return list, err
+}
+
+func (r *ipAllowlistRepository) Add(ctx context.Context, network *AllowedNetwork, check func(*IPAllowlist) error) error {
+	if network.ID == "" {
+		network.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		list, err := r.load(tx, network.UserID, true)
+		if err != nil {
+			return err
+		}
+		if len(list.Networks) >= MaxAllowedNetworks {
+			return ErrTooManyNetworks
+		}
+		list.Networks = append(list.Networks, *network)
+		if err := check(list); err != nil {
+			return err
+		}
+		return tx.Create(network).Error
+	})
+}
+
+func (r *ipAllowlistRepository) Remove(ctx context.Context, userID, id string, check func(*IPAllowlist) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		list, err := r.load(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		remaining := list.Networks[:0]
+		for _, network := range list.Networks {
+			if network.ID != id {
+				remaining = append(remaining, network)
+			}
+		}
+		if len(remaining) == len(list.Networks) {
+			return ErrAllowedNetworkNotFound
+		}
+		list.Networks = remaining
+		if err := check(list); err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&AllowedNetwork{}).Error
+	})
+}
+
+func (r *ipAllowlistRepository) SetMode(ctx context.Context, userID, mode string, check func(*IPAllowlist) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		list, err := r.load(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		list.Mode = mode
+		if err := check(list); err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", userID).Update("ip_allowlist_mode", mode).Error
+	})
+}
+
+// IPAllowlist returns the user's allowed networks and what they restrict.
+func (s *Service) IPAllowlist(ctx context.Context, userID string) (*IPAllowlist, error) {
+	return s.allowlist.Get(ctx, userID)
+}
+
+// AddAllowedNetwork adds an address or CIDR range to the user's allowlist.
+func (s *Service) AddAllowedNetwork(ctx context.Context, userID, cidr, label string) (*IPAllowlist, error) {
+	normalized, err := normalizeNetwork(cidr)
+	if err != nil {
+		return nil, err
+	}
+	network := &AllowedNetwork{UserID: userID, CIDR: normalized, Label: label}
+	if err := s.allowlist.Add(ctx, network, s.lockoutCheck(ctx)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditIPAllowlistChanged, userID, userID, map[string]string{"added": normalized})
+	return s.allowlist.Get(ctx, userID)
+}
+
+// RemoveAllowedNetwork removes a network from the user's allowlist.
+// Removing the last one allows every address again.
+func (s *Service) RemoveAllowedNetwork(ctx context.Context, userID, id string) (*IPAllowlist, error) {
+	if err := s.allowlist.Remove(ctx, userID, id, s.lockoutCheck(ctx)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditIPAllowlistChanged, userID, userID, map[string]string{"removed": id})
+	return s.allowlist.Get(ctx, userID)
+}
+
+// SetIPAllowlistMode sets which of the user's requests the allowlist
+// restricts.
+func (s *Service) SetIPAllowlistMode(ctx context.Context, userID, mode string) (*IPAllowlist, error) {
+	if mode != IPAllowlistAll && mode != IPAllowlistTrading {
+		return nil, ErrInvalidAllowlistMode
+	}
+	if err := s.allowlist.SetMode(ctx, userID, mode, s.lockoutCheck(ctx)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditIPAllowlistChanged, userID, userID, map[string]string{"mode": mode})
+	return s.allowlist.Get(ctx, userID)
+}
+
+// lockoutCheck refuses allowlists that would reject the request changing
+// them, so users cannot lock themselves out of their account. Trading
+// mode leaves the account reachable from anywhere.
+func (s *Service) lockoutCheck(ctx context.Context) func(*IPAllowlist) error {
+	ip, _ := rpc.Client(ctx)
+	return func(list *IPAllowlist) error {
+		if ip != "" && list.Check(ip) != nil {
+			return ErrAllowlistLockout
+		}
+		return nil
+	}
+}
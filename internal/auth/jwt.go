@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/config"
 )
 
 var (
@@ -13,95 +17,188 @@ var (
 )
 
 type TokenService interface {
-	GenerateAccessToken(userID string) (string, error)
-	GenerateRefreshToken(userID string) (string, error)
-	ValidateAccessToken(token string) (string, error)
-	ValidateRefreshToken(token string) (string, error)
+	// GenerateAccessToken carries the user's roles as of issuance, for
+	// clients; authorization checks the current roles. The session ID
+	// lets the token be revoked with its session. Scopes restrict the
+	// token; without any it may do everything the user may.
+	GenerateAccessToken(userID, sessionID string, roles, scopes []string) (string, error)
+	// GenerateRefreshToken returns the token with its claims, whose ID is
+	// unique so the token can be tracked and revoked
+	GenerateRefreshToken(userID string) (string, *Claims, error)
+	ValidateAccessToken(token string) (*Claims, error)
+	ValidateRefreshToken(token string) (*Claims, error)
+	// JWKS returns the public keys that verify tokens as a JSON Web Key
+	// Set; it has no keys while tokens are signed with the shared secret
+	JWKS() []byte
 }
 
 type jwtService struct {
-	secret          []byte
+	// secret signs and verifies HS256 tokens when no signing key is set
+	secret []byte
+	// signing signs new tokens; keys verify them by key ID
+	signing *signingKey
+	keys    map[string]*signingKey
+	jwks    []byte
+
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Type   string `json:"type"` // "access" or "refresh"
+	UserID string   `json:"user_id"`
+	Type   string   `json:"type"` // "access" or "refresh"
+	Roles  []string `json:"roles,omitempty"`
+	// SessionID and Scopes are only set on access tokens
+	SessionID string   `json:"sid,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewJWTService(secret string, accessTokenTTL time.Duration) TokenService {
-	return &jwtService{
-		secret:          []byte(secret),
-		accessTokenTTL:  accessTokenTTL,
+// NewJWTService signs tokens with cfg.SigningKey, or with the shared secret
+// when it is not set. Once a signing key is configured, HS256 tokens are no
+// longer accepted, so a leaked secret cannot forge tokens.
+func NewJWTService(cfg config.JWTConfig) (TokenService, error) {
+	j := &jwtService{
+		secret:          []byte(cfg.Secret),
+		keys:            make(map[string]*signingKey),
+		accessTokenTTL:  cfg.ExpirationTime,
 		refreshTokenTTL: 24 * 7 * time.Hour, // 7 days
 	}
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, keyCfg := range cfg.Keys {
+		if _, ok := j.keys[keyCfg.ID]; ok || keyCfg.ID == "" {
+			return nil, fmt.Errorf("jwt keys need unique non-empty IDs, got %q", keyCfg.ID)
+		}
+		key, err := loadSigningKey(keyCfg.ID, keyCfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		j.keys[key.id] = key
+		jwks.Keys = append(jwks.Keys, key.jwk())
+	}
+
+	if cfg.SigningKey != "" {
+		j.signing = j.keys[cfg.SigningKey]
+		if j.signing == nil {
+			return nil, fmt.Errorf("jwt signing key %q is not among the configured keys", cfg.SigningKey)
+		}
+	} else if len(j.secret) == 0 {
+		return nil, errors.New("jwt needs a secret or a signing key")
+	}
+
+	document, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+	j.jwks = document
+	return j, nil
 }
 
-func (j *jwtService) GenerateAccessToken(userID string) (string, error) {
+func (j *jwtService) GenerateAccessToken(userID, sessionID string, roles, scopes []string) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Type:   "access",
+		UserID:    userID,
+		Type:      "access",
+		Roles:     roles,
+		SessionID: sessionID,
+		Scopes:    scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   userID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	return j.sign(claims)
 }
 
-func (j *jwtService) GenerateRefreshToken(userID string) (string, error) {
-	claims := Claims{
+func (j *jwtService) GenerateRefreshToken(userID string) (string, *Claims, error) {
+	claims := &Claims{
 		UserID: userID,
 		Type:   "refresh",
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.refreshTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   userID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	token, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// sign signs the claims with the signing key, naming it in the header, or
+// with the shared secret.
+func (j *jwtService) sign(claims jwt.Claims) (string, error) {
+	if j.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	}
+	token := jwt.NewWithClaims(j.signing.method, claims)
+	token.Header["kid"] = j.signing.id
+	return token.SignedString(j.signing.private)
+}
+
+func (j *jwtService) JWKS() []byte {
+	return j.jwks
 }
 
-func (j *jwtService) ValidateAccessToken(tokenString string) (string, error) {
-	return j.validateToken(tokenString, "access")
+func (j *jwtService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, "access", j.verifyKey)
 }
 
-func (j *jwtService) ValidateRefreshToken(tokenString string) (string, error) {
-	return j.validateToken(tokenString, "refresh")
+func (j *jwtService) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, "refresh", j.verifyKey)
 }
 
-func (j *jwtService) validateToken(tokenString, tokenType string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// verifyKey selects the key that verifies the token: the secret for HS256,
+// otherwise the public key named by the token's key ID. Keys other than the
+// signing one still verify tokens they signed, so keys can be rotated.
+func (j *jwtService) verifyKey(token *jwt.Token) (interface{}, error) {
+	if j.signing == nil {
 		// Validate the signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
 		return j.secret, nil
-	})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method != key.method {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.private.Public(), nil
+}
 
+// parseToken verifies the token with the key keyFunc selects and checks
+// its claims.
+func parseToken(tokenString, tokenType string, keyFunc jwt.Keyfunc) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 	if err != nil {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
-	if claims.Type != tokenType {
-		return "", ErrInvalidToken
+	// Tokens are blacklisted and rotated by ID
+	if claims.Type != tokenType || claims.ID == "" {
+		return nil, ErrInvalidToken
 	}
 
 	if claims.ExpiresAt.Before(time.Now()) {
-		return "", ErrExpiredToken
+		return nil, ErrExpiredToken
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey means the token names a key ID that is not in the key set,
+// for example because the key set has not been refreshed since a rotation.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// signingKey is a private key that signs tokens under its key ID.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+// loadSigningKey reads a PEM private key: RSA keys sign with RS256,
+// Ed25519 keys with EdDSA.
+func loadSigningKey(id, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", id, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data in %s", id, path)
+	}
+
+	var key any
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", id, err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, private: key}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, private: key}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, key)
+	}
+}
+
+// JWK is the public half of a signing key, as published in the JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are set for Ed25519 and EC keys, Y for EC keys only
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517).
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// publicKey decodes the key and the signing method it is used with.
+func (k JWK) publicKey() (crypto.PublicKey, jwt.SigningMethod, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %s: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %s: invalid exponent: %w", k.Kid, err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, jwt.SigningMethodRS256, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("key %s: invalid Ed25519 key", k.Kid)
+		}
+		return ed25519.PublicKey(x), jwt.SigningMethodEdDSA, nil
+	case "EC":
+		// Only identity providers publish EC keys; ours are RSA or Ed25519
+		var curve elliptic.Curve
+		var method jwt.SigningMethod
+		switch k.Crv {
+		case "P-256":
+			curve, method = elliptic.P256(), jwt.SigningMethodES256
+		case "P-384":
+			curve, method = elliptic.P384(), jwt.SigningMethodES384
+		default:
+			return nil, nil, fmt.Errorf("key %s: unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, nil, fmt.Errorf("key %s: invalid EC point", k.Kid)
+		}
+		public := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if _, err := public.ECDH(); err != nil {
+			return nil, nil, fmt.Errorf("key %s: invalid EC point", k.Kid)
+		}
+		return public, method, nil
+	default:
+		return nil, nil, fmt.Errorf("key %s: unsupported key type %q", k.Kid, k.Kty)
+	}
+}
+
+type verifyKey struct {
+	public crypto.PublicKey
+	method jwt.SigningMethod
+}
+
+// KeySet verifies access tokens against the public keys of the auth
+// service's JWKS, so services can check signatures without the signing
+// keys. It only checks the token itself; revocations still need the auth
+// service.
+type KeySet struct {
+	mutex    sync.RWMutex
+	keys     map[string]verifyKey
+	document []byte
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]verifyKey), document: []byte(`{"keys":[]}`)}
+}
+
+// Update replaces the keys with those of a JWKS document.
+func (s *KeySet) Update(document []byte) error {
+	var jwks JWKS
+	if err := json.Unmarshal(document, &jwks); err != nil {
+		return fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys := make(map[string]verifyKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		public, method, err := jwk.publicKey()
+		if err != nil {
+			return err
+		}
+		keys[jwk.Kid] = verifyKey{public: public, method: method}
+	}
+
+	s.mutex.Lock()
+	s.keys = keys
+	s.document = document
+	s.mutex.Unlock()
+	return nil
+}
+
+// Len returns the number of keys; tokens cannot be verified without any.
+func (s *KeySet) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.keys)
+}
+
+// Document returns the JWKS the keys were last updated from.
+func (s *KeySet) Document() []byte {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.document
+}
+
+// VerifyAccessToken checks the token's signature, type and expiry. It
+// returns ErrUnknownKey when the token names a key not in the set.
+func (s *KeySet) VerifyAccessToken(tokenString string) (*Claims, error) {
+	unknown := false
+	claims, err := parseToken(tokenString, "access", func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		s.mutex.RLock()
+		key, ok := s.keys[kid]
+		s.mutex.RUnlock()
+		if !ok {
+			unknown = true
+			return nil, ErrUnknownKey
+		}
+		if token.Method != key.method {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	})
+	if unknown {
+		return nil, ErrUnknownKey
+	}
+	return claims, err
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Lockout scopes
+const (
+	LockoutAccount = "account"
+	LockoutIP      = "ip"
+)
+
+var ErrLockedOut = errors.New("too many failed logins")
+
+// The window starts at the first failure, so a steady trickle of failures
+// cannot keep a counter alive forever.
+var failureScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n`)
+
+// LockedOutError rejects a login while its account or IP is locked out.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("%s; try again in %s", ErrLockedOut, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedOutError) Unwrap() error {
+	return ErrLockedOut
+}
+
+// Lockout counts failed logins per account and per client IP in redis and
+// locks either out for a while once it reaches its limit. Accounts are
+// tracked by the email tried, whether or not an account uses it, so a
+// lockout does not reveal which emails are registered. Failures and
+// lockouts are published as audit events.
+//
+// Redis errors are logged and let the login through: an outage must not
+// lock everyone out.
+type Lockout struct {
+	client redis.UniversalClient
+	nats   *nats.Conn
+	cfg    config.LockoutConfig
+}
+
+func NewLockout(client redis.UniversalClient, conn *nats.Conn, cfg config.LockoutConfig) *Lockout {
+	return &Lockout{client: client, nats: conn, cfg: cfg}
+}
+
+// lockoutKey hashes the email or IP, which keeps them out of redis.
+func lockoutKey(kind, scope, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "auth:login-" + kind + ":" + scope + ":" + hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check returns a *LockedOutError while the account or the IP is locked
+// out.
+func (l *Lockout) Check(ctx context.Context, email, ip string) error {
+	if !l.cfg.Enabled {
+		return nil
+	}
+
+	var account, address *redis.DurationCmd
+	_, err := cache.Pipelined(ctx, l.client, func(pipe redis.Pipeliner) {
+		account = pipe.PTTL(ctx, lockoutKey("lock", LockoutAccount, normalizeEmail(email)))
+		if ip != "" {
+			address = pipe.PTTL(ctx, lockoutKey("lock", LockoutIP, ip))
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to check login lockout: %v", err)
+		return nil
+	}
+
+	// PTTL is negative for keys that do not exist
+	remaining := account.Val()
+	if address != nil && address.Val() > remaining {
+		remaining = address.Val()
+	}
+	if remaining > 0 {
+		return &LockedOutError{RetryAfter: remaining}
+	}
+	return nil
+}
+
+// Failed records a failed login. userID is empty when no account uses the
+// email.
+func (l *Lockout) Failed(ctx context.Context, email, ip, userID string) {
+	if !l.cfg.Enabled {
+		return
+	}
+	email = normalizeEmail(email)
+
+	failures, err := failureScript.Run(ctx, l.client, []string{lockoutKey("failures", LockoutAccount, email)}, l.cfg.Window.Milliseconds()).Int()
+	if err != nil {
+		log.Printf("Failed to count failed login: %v", err)
+		return
+	}
+	l.publish(&eventspb.LoginFailed{
+		UserId:   userID,
+		Email:    email,
+		ClientIp: ip,
+		Failures: uint32(failures),
+	})
+	if failures >= l.cfg.MaxFailures {
+		l.lock(ctx, LockoutAccount, email, &eventspb.LoginLockedOut{UserId: userID, Email: email, ClientIp: ip})
+	}
+
+	if ip == "" {
+		return
+	}
+	failures, err = failureScript.Run(ctx, l.client, []string{lockoutKey("failures", LockoutIP, ip)}, l.cfg.Window.Milliseconds()).Int()
+	if err != nil {
+		log.Printf("Failed to count failed login: %v", err)
+		return
+	}
+	if failures >= l.cfg.IPMaxFailures {
+		l.lock(ctx, LockoutIP, ip, &eventspb.LoginLockedOut{ClientIp: ip})
+	}
+}
+
+// Succeeded forgets the account's failures.
+func (l *Lockout) Succeeded(ctx context.Context, email string) {
+	if !l.cfg.Enabled {
+		return
+	}
+	if err := l.client.Del(ctx, lockoutKey("failures", LockoutAccount, normalizeEmail(email))).Err(); err != nil {
+		log.Printf("Failed to reset failed logins: %v", err)
+	}
+}
+
+func (l *Lockout) lock(ctx context.Context, scope, value string, event *eventspb.LoginLockedOut) {
+	_, err := cache.Pipelined(ctx, l.client, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, lockoutKey("lock", scope, value), 1, l.cfg.Duration)
+		// The count starts over once the lockout ends
+		pipe.Del(ctx, lockoutKey("failures", scope, value))
+	})
+	if err != nil {
+		log.Printf("Failed to lock out %s: %v", scope, err)
+		return
+	}
+
+	event.Scope = scope
+	event.LockedUntil = timestamppb.New(time.Now().Add(l.cfg.Duration))
+	l.publish(event)
+}
+
+func (l *Lockout) publish(event proto.Message) {
+	if l.nats == nil {
+		return
+	}
+	if err := events.Publish(l.nats, "auth-service", event); err != nil {
+		log.Printf("Failed to publish audit event: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+)
+
+// How users signed in
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodSSO       = "sso"
+)
+
+// Why a login was flagged as suspicious
+const (
+	LoginNewCountry = "new_country"
+	LoginNewDevice  = "new_device"
+)
+
+const (
+	defaultLoginPageSize = 50
+	MaxLoginPageSize     = 200
+)
+
+var ErrStepUpRequired = errors.New("sign-in from a new country or device; confirm it with the link sent to your email")
+
+// LoginEvent is an entry of a user's login history. Country is the ISO
+// code the gateway's proxy located the client in, if any; Fingerprint
+// identifies the device across logins.
+type LoginEvent struct {
+	ID          string `gorm:"primaryKey;type:varchar(36)"`
+	UserID      string `gorm:"type:varchar(36);not null;index:idx_login_events_user,priority:1"`
+	Method      string `gorm:"type:varchar(20);not null"`
+	IPAddress   string `gorm:"type:varchar(45)"`
+	Country     string `gorm:"type:varchar(2)"`
+	Device      string `gorm:"type:varchar(100)"`
+	UserAgent   string `gorm:"type:varchar(500)"`
+	Fingerprint string `gorm:"type:varchar(64)"`
+	// Reasons says why the login was suspicious; empty when it was not
+	Reasons   []string  `gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time `gorm:"not null;index:idx_login_events_user,priority:2,sort:desc"`
+}
+
+// TableName sets the table name for GORM
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
+
+// Suspicious tells whether the login came from a country or device the
+// user had not signed in from before.
+func (e *LoginEvent) Suspicious() bool {
+	return len(e.Reasons) > 0
+}
+
+// LoginFamiliarity is what a user's earlier logins say about a new one.
+type LoginFamiliarity struct {
+	// Known is false for the first login
+	Known bool
+	// Located is whether any earlier login came with a country
+	Located bool
+	Country bool
+	Device  bool
+}
+
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *LoginEvent) error
+	// List returns the user's latest logins, newest first
+	List(ctx context.Context, userID string, limit int) ([]LoginEvent, error)
+	// Familiarity tells whether the user signed in before, and from the
+	// country and device
+	Familiarity(ctx context.Context, userID, country, fingerprint string) (*LoginFamiliarity, error)
+}
+
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{db: db}
+}
+
+func (r *loginEventRepository) Create(ctx context.Context, event *LoginEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	event.IPAddress = truncate(event.IPAddress, 45)
+	event.Device = truncate(event.Device, 100)
+	event.UserAgent = truncate(event.UserAgent, 500)
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *loginEventRepository) List(ctx context.Context, userID string, limit int) ([]LoginEvent, error) {
+	events := []LoginEvent{}
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *loginEventRepository) Familiarity(ctx context.Context, userID, country, fingerprint string) (*LoginFamiliarity, error) {
+	var f LoginFamiliarity
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) > 0 AS known,
+	COALESCE(BOOL_OR(country <> ''), false) AS located,
+	COALESCE(BOOL_OR(country = ?), false) AS country,
+	COALESCE(BOOL_OR(fingerprint = ?), false) AS device
+FROM login_events WHERE user_id = ?`, country, fingerprint, userID).Scan(&f).Error
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// LoginMonitor keeps the login history and flags logins from countries
+// and devices new to the user.
+type LoginMonitor struct {
+	repo   LoginEventRepository
+	sender email.Sender
+	cfg    config.LoginAlertsConfig
+}
+
+func NewLoginMonitor(repo LoginEventRepository, sender email.Sender, cfg config.LoginAlertsConfig) *LoginMonitor {
+	return &LoginMonitor{repo: repo, sender: sender, cfg: cfg}
+}
+
+// Assess returns the history entry of a login about to succeed, with the
+// reasons it is suspicious. A user's first login is never suspicious.
+// After it, a client that hides its device counts as a new device, and
+// one without a country counts as a new country once the user has logged
+// in from a known one, so dropping headers does not skip step-up.
+func (m *LoginMonitor) Assess(ctx context.Context, userID, method string, client ClientInfo) (*LoginEvent, error) {
+	event := newLoginEvent(userID, method, client)
+	f, err := m.repo.Familiarity(ctx, userID, event.Country, event.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check login history: %w", err)
+	}
+	if !f.Known {
+		return event, nil
+	}
+	if event.Country == "" && f.Located || event.Country != "" && !f.Country {
+		event.Reasons = append(event.Reasons, LoginNewCountry)
+	}
+	if event.Fingerprint == "" || !f.Device {
+		event.Reasons = append(event.Reasons, LoginNewDevice)
+	}
+	return event, nil
+}
+
+// Record adds the login to the history, or logs why it could not. Like
+// audit events, failing to record never fails the login.
+func (m *LoginMonitor) Record(ctx context.Context, event *LoginEvent) {
+	if err := m.repo.Create(context.WithoutCancel(ctx), event); err != nil {
+		log.Printf("Failed to record login of user %s: %v", event.UserID, err)
+	}
+}
+
+// History returns the user's latest logins.
+func (m *LoginMonitor) History(ctx context.Context, userID string, limit int) ([]LoginEvent, error) {
+	if limit <= 0 {
+		limit = defaultLoginPageSize
+	}
+	if limit > MaxLoginPageSize {
+		limit = MaxLoginPageSize
+	}
+	return m.repo.List(ctx, userID, limit)
+}
+
+// notify mails the user about a suspicious login.
+func (m *LoginMonitor) notify(ctx context.Context, user *User, event *LoginEvent) error {
+	return m.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was just signed in to from a %s.\n\n%s\nIf this was you, there is nothing to do. If not, change your password and sign out your other sessions right away.\n",
+			user.FirstName, describeReasons(event.Reasons), describeLogin(event)),
+	})
+}
+
+// recordLogin notes a successful login: the time on the user, the history
+// entry and, for suspicious ones, a notification when enabled. Links
+// arrive by email, so mailing about logins through one tells the owner
+// nothing new.
+func (s *Service) recordLogin(ctx context.Context, user *User, event *LoginEvent) {
+	user.LastLoginAt = time.Now()
+	s.logins.Record(Login{UserID: user.ID, At: user.LastLoginAt})
+
+	event.CreatedAt = user.LastLoginAt
+	s.monitor.Record(ctx, event)
+	if !event.Suspicious() || !s.monitor.cfg.Notify || event.Method == LoginMethodMagicLink {
+		return
+	}
+	go func() {
+		if err := s.monitor.notify(context.WithoutCancel(ctx), user, event); err != nil {
+			log.Printf("Failed to send login alert to user %s: %v", user.ID, err)
+		}
+	}()
+}
+
+// stepUp holds back a suspicious password login when step-up is enabled,
+// mailing the user a sign-in link to confirm it with instead. It returns
+// ErrStepUpRequired if it did.
+func (s *Service) stepUp(ctx context.Context, user *User, event *LoginEvent) error {
+	if !event.Suspicious() || !s.monitor.cfg.StepUp {
+		return nil
+	}
+	s.audit(ctx, AuditLoginStepUp, user.ID, "", map[string]string{"reasons": strings.Join(event.Reasons, ",")})
+	go func() {
+		if err := s.magicLinks.Confirm(context.WithoutCancel(ctx), user, describeReasons(event.Reasons), describeLogin(event)); err != nil {
+			log.Printf("Failed to send login confirmation to user %s: %v", user.ID, err)
+		}
+	}()
+	return ErrStepUpRequired
+}
+
+// LoginHistory returns the user's latest logins, newest first.
+func (s *Service) LoginHistory(ctx context.Context, userID string, limit int) ([]LoginEvent, error) {
+	return s.monitor.History(ctx, userID, limit)
+}
+
+func newLoginEvent(userID, method string, client ClientInfo) *LoginEvent {
+	return &LoginEvent{
+		UserID:      userID,
+		Method:      method,
+		IPAddress:   client.IP,
+		Country:     client.Country,
+		Device:      client.Device,
+		UserAgent:   client.UserAgent,
+		Fingerprint: deviceFingerprint(client),
+	}
+}
+
+// versionPattern matches version numbers in user agents
+var versionPattern = regexp.MustCompile(`[0-9]+([._][0-9]+)*`)
+
+// deviceFingerprint hashes the device name and the user agent without its
+// version numbers, so browser and OS updates keep the same fingerprint.
+// It is empty when the client sent neither.
+func deviceFingerprint(client ClientInfo) string {
+	agent := strings.ToLower(versionPattern.ReplaceAllString(client.UserAgent, ""))
+	if client.Device == "" && strings.TrimSpace(agent) == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(client.Device + "\x00" + agent))
+	return hex.EncodeToString(sum[:])
+}
+
+func describeReasons(reasons []string) string {
+	var parts []string
+	for _, reason := range reasons {
+		switch reason {
+		case LoginNewCountry:
+			parts = append(parts, "country")
+		case LoginNewDevice:
+			parts = append(parts, "device")
+		}
+	}
+	return "new " + strings.Join(parts, " and ")
+}
+
+func describeLogin(event *LoginEvent) string {
+	var b strings.Builder
+	if event.IPAddress != "" {
+		fmt.Fprintf(&b, "IP address: %s\n", event.IPAddress)
+	}
+	if event.Country != "" {
+		fmt.Fprintf(&b, "Country: %s\n", event.Country)
+	}
+	if event.Device != "" {
+		fmt.Fprintf(&b, "Device: %s\n", event.Device)
+	}
+	if event.UserAgent != "" {
+		fmt.Fprintf(&b, "Browser: %s\n", event.UserAgent)
+	}
+	return b.String()
+}
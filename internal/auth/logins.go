@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/batch"
+	"github.com/tradingbothub/platform/internal/config"
+	"gorm.io/gorm"
+)
+
+var droppedLogins = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "auth_last_login_dropped_total",
+	Help: "Last-login updates dropped because the login writer was full or closed.",
+})
+
+// Login is a successful sign-in whose time is recorded on the user.
+type Login struct {
+	UserID string
+	At     time.Time
+}
+
+// LoginRecorder tracks last-login times off the login request path.
+type LoginRecorder interface {
+	Record(login Login)
+}
+
+// LoginWriter batches last-login updates into one UPDATE per flush. Write
+// failures are retried, logged and counted by the batch writer.
+type LoginWriter struct {
+	db     *gorm.DB
+	writer *batch.Writer[Login]
+}
+
+func NewLoginWriter(db *gorm.DB, cfg config.WriteBatchConfig) *LoginWriter {
+	w := &LoginWriter{db: db}
+	w.writer = batch.NewWriter("last_logins", cfg, w.flush)
+	return w
+}
+
+// Record queues the login without waiting; when the writer is backed up
+// the update is dropped and counted rather than slowing the login down.
+func (w *LoginWriter) Record(login Login) {
+	if !w.writer.Offer(login) {
+		droppedLogins.Inc()
+		log.Printf("Dropped last-login update of user %s: writer is full", login.UserID)
+	}
+}
+
+// Close writes the queued updates before ctx is done.
+func (w *LoginWriter) Close(ctx context.Context) error {
+	return w.writer.Close(ctx)
+}
+
+func (w *LoginWriter) flush(ctx context.Context, logins []Login) error {
+	// Keep the latest login per user so each row is updated once
+	latest := make(map[string]time.Time, len(logins))
+	for _, login := range logins {
+		if at, ok := latest[login.UserID]; !ok || login.At.After(at) {
+			latest[login.UserID] = login.At
+		}
+	}
+
+	values := make([]string, 0, len(latest))
+	args := make([]interface{}, 0, 2*len(latest))
+	for userID, at := range latest {
+		values = append(values, "(?, ?::timestamptz)")
+		args = append(args, userID, at)
+	}
+
+	// An older time never overwrites a newer one, so retries and batches
+	// from other replicas can land in any order
+	err := w.db.WithContext(ctx).Exec(`UPDATE users SET last_login_at = v.at
+FROM (VALUES `+strings.Join(values, ", ")+`) AS v(id, at)
+WHERE users.id = v.id AND (users.last_login_at IS NULL OR users.last_login_at < v.at)`, args...).Error
+	if err != nil {
+		return fmt.Errorf("failed to update last logins: %w", err)
+	}
+	return nil
+}
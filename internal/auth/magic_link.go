@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMagicLink = errors.New("invalid login link")
+	ErrMagicLinkExpired = errors.New("login link expired")
+)
+
+// MagicLink is an outstanding passwordless login. Only a hash of the
+// emailed token is stored, and the token works once.
+type MagicLink struct {
+	TokenHash string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `gorm:"type:varchar(36);not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (MagicLink) TableName() string {
+	return "magic_links"
+}
+
+type MagicLinkRepository interface {
+	// Create replaces any outstanding link of the user
+	Create(ctx context.Context, link *MagicLink) error
+	// Consume deletes the link and returns its user ID
+	Consume(ctx context.Context, tokenHash string, now time.Time) (string, error)
+}
+
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, link *MagicLink) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", link.UserID).Delete(&MagicLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(link).Error
+	})
+}
+
+func (r *magicLinkRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	var link MagicLink
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", tokenHash).First(&link).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidMagicLink
+			}
+			return err
+		}
+		// Of concurrent uses of the same link, only one deletes it
+		result := tx.Where("token_hash = ?", tokenHash).Delete(&MagicLink{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMagicLink
+		}
+		if now.After(link.ExpiresAt) {
+			return ErrMagicLinkExpired
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return link.UserID, nil
+}
+
+// MagicLinker issues login links and mails them to users.
+type MagicLinker struct {
+	repo   MagicLinkRepository
+	sender email.Sender
+	ttl    time.Duration
+	// link is the page the emailed link opens
+	link string
+}
+
+func NewMagicLinker(repo MagicLinkRepository, sender email.Sender, ttl time.Duration, link string) *MagicLinker {
+	return &MagicLinker{repo: repo, sender: sender, ttl: ttl, link: link}
+}
+
+// Send issues a new link to the user, invalidating earlier ones.
+func (m *MagicLinker) Send(ctx context.Context, user *User) error {
+	link, err := m.issue(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nYou can sign in by opening the link below. It works once and expires in %s.\n\n%s\n\nIf you did not ask to sign in, you can ignore this email.\n",
+			user.FirstName, m.ttl, link),
+	})
+}
+
+// Confirm issues a new link to the user to confirm a password login held
+// back for coming from a new country or device, described by reason and
+// details.
+func (m *MagicLinker) Confirm(ctx context.Context, user *User, reason, details string) error {
+	link, err := m.issue(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Confirm your sign-in",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone signed in to your account with your password from a %s.\n\n%s\nIf this was you, open the link below to finish signing in. It works once and expires in %s.\n\n%s\n\nIf it was not you, change your password right away.\n",
+			user.FirstName, reason, details, m.ttl, link),
+	})
+}
+
+// issue stores a new link of the user and returns its URL.
+func (m *MagicLinker) issue(ctx context.Context, user *User) (string, error) {
+	token, hash, err := newVerificationToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = m.repo.Create(ctx, &MagicLink{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(m.ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store login link: %w", err)
+	}
+
+	link, err := tokenLink(m.link, token)
+	if err != nil {
+		return "", fmt.Errorf("invalid login link url: %w", err)
+	}
+	return link, nil
+}
+
+// Consume redeems the token and returns the ID of the user it signs in.
+func (m *MagicLinker) Consume(ctx context.Context, token string) (string, error) {
+	return m.repo.Consume(ctx, hashVerificationToken(token), time.Now())
+}
+
+// RequestMagicLink mails a login link if an account uses the address. Like
+// ForgotPassword, it succeeds either way and sends in the background, so
+// the answer does not tell whether the account exists.
+func (s *Service) RequestMagicLink(ctx context.Context, address string) {
+	user, err := s.repo.GetByEmail(ctx, address)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			log.Printf("Failed to look up user for login link: %v", err)
+		}
+		return
+	}
+	if !user.IsActive {
+		return
+	}
+
+	go func() {
+		if err := s.magicLinks.Send(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("Failed to send login link to user %s: %v", user.ID, err)
+		}
+	}()
+}
+
+// MagicLinkLogin signs the user in with an emailed login link, starting a
+// session for the client like a password login.
+func (s *Service) MagicLinkLogin(ctx context.Context, token string, client ClientInfo) (*AuthResponse, error) {
+	userID, err := s.magicLinks.Consume(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMagicLink) || errors.Is(err, ErrMagicLinkExpired) {
+			s.audit(ctx, AuditLoginFailed, "", "", map[string]string{"method": "magic_link", "reason": err.Error()})
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		s.audit(ctx, AuditLoginFailed, user.ID, "", map[string]string{"method": "magic_link", "reason": "deactivated"})
+		return nil, ErrAccountDeactivated
+	}
+	// A link is no way around a reset an admin or an email change undo
+	// forced on the user
+	if user.PasswordResetRequired {
+		s.audit(ctx, AuditLoginFailed, user.ID, "", map[string]string{"method": "magic_link", "reason": "password_reset_required"})
+		return nil, ErrPasswordResetRequired
+	}
+
+	event, err := s.monitor.Assess(ctx, user.ID, LoginMethodMagicLink, client)
+	if err != nil {
+		return nil, err
+	}
+	s.recordLogin(ctx, user, event)
+
+	accessToken, refreshToken, err := s.issueTokens(ctx, user.ID, client, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditLoginSucceeded, user.ID, user.ID, map[string]string{"method": "magic_link"})
+
+	return &AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user,
+		ExpiresIn:    3600,
+	}, nil
+}
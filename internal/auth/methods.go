@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ways to sign in that can be linked to an account: a password, and
+// identities at the providers of SSO connections. Consumer OAuth providers
+// without a connection, and passkeys, are not sign-in methods yet; each
+// needs its own identities, sign-in flow and place in removeMethod's count,
+// and is left to a follow-up request.
+const (
+	AuthMethodPassword = "password"
+	AuthMethodSSO      = "sso"
+)
+
+var (
+	ErrAuthMethodNotFound = errors.New("sign-in method not found")
+	ErrLastAuthMethod     = errors.New("cannot remove the last sign-in method")
+	ErrPasswordSet        = errors.New("a password is already set; change it instead")
+	ErrIdentityLinked     = errors.New("the provider account is linked to another user")
+	// ErrReauthRequired is returned when a session that signed in too long
+	// ago adds or removes a sign-in method
+	ErrReauthRequired = errors.New("sign in again to change your sign-in methods")
+)
+
+// AuthMethod is a way a user signs in. Users without a password have an
+// empty password hash; SSO methods are the identities linked through a
+// connection. Emailed sign-in links work for every account and are not
+// listed.
+type AuthMethod struct {
+	Type string
+	// ConnectionID, Organization and Domain describe an SSO method's
+	// identity provider
+	ConnectionID string
+	Organization string
+	Domain       string
+	// LinkedAt is when the identity was linked, or the password last set
+	LinkedAt time.Time
+}
+
+type AuthMethodRepository interface {
+	// List returns the user's password, if set, and linked identities
+	List(ctx context.Context, userID string) ([]AuthMethod, error)
+	// SetPassword sets the password of a user without one, or returns
+	// ErrPasswordSet
+	SetPassword(ctx context.Context, userID, passwordHash string, now time.Time) error
+	// RemovePassword clears the password unless it is the user's last
+	// method
+	RemovePassword(ctx context.Context, userID string) error
+	// Unlink removes the identity linked through the connection unless it
+	// is the user's last method
+	Unlink(ctx context.Context, userID, connectionID string) error
+}
+
+type authMethodRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthMethodRepository(db *gorm.DB) AuthMethodRepository {
+	return &authMethodRepository{db: db}
+}
+
+func (r *authMethodRepository) List(ctx context.Context, userID string) ([]AuthMethod, error) {
+	var user User
+	err := r.db.WithContext(ctx).Select("id", "password_hash", "password_changed_at", "created_at").
+		Where("id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []AuthMethod{}
+	if user.PasswordHash != "" {
+		setAt := user.CreatedAt
+		if user.PasswordChangedAt != nil {
+			setAt = *user.PasswordChangedAt
+		}
+		methods = append(methods, AuthMethod{Type: AuthMethodPassword, LinkedAt: setAt})
+	}
+
+	var identities []AuthMethod
+	err = r.db.WithContext(ctx).Table("sso_identities AS i").
+		Select("c.id AS connection_id, c.organization, c.domain, i.created_at AS linked_at").
+		Joins("JOIN sso_connections AS c ON c.id = i.connection_id").
+		Where("i.user_id = ?", userID).
+		Order("i.created_at").
+		Scan(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		identity.Type = AuthMethodSSO
+		methods = append(methods, identity)
+	}
+	return methods, nil
+}
+
+func (r *authMethodRepository) SetPassword(ctx context.Context, userID, passwordHash string, now time.Time) error {
+	result := r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND password_hash = ''", userID).
+		Updates(map[string]interface{}{"password_hash": passwordHash, "password_changed_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasswordSet
+	}
+	return nil
+}
+
+func (r *authMethodRepository) RemovePassword(ctx context.Context, userID string) error {
+	return r.removeMethod(ctx, userID, func(tx *gorm.DB, user *User) error {
+		if user.PasswordHash == "" {
+			return ErrAuthMethodNotFound
+		}
+		return tx.Model(user).Update("password_hash", "").Error
+	})
+}
+
+func (r *authMethodRepository) Unlink(ctx context.Context, userID, connectionID string) error {
+	return r.removeMethod(ctx, userID, func(tx *gorm.DB, user *User) error {
+		result := tx.Where("user_id = ? AND connection_id = ?", userID, connectionID).Delete(&SSOIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAuthMethodNotFound
+		}
+		return nil
+	})
+}
+
+// removeMethod runs remove with the user's row locked, so concurrent
+// removals cannot take away the last two methods at once, and rolls it
+// back if no method is left.
+func (r *authMethodRepository) removeMethod(ctx context.Context, userID string, remove func(tx *gorm.DB, user *User) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "password_hash").Where("id = ?", userID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := remove(tx, &user); err != nil {
+			return err
+		}
+
+		var left User
+		if err := tx.Select("password_hash").Where("id = ?", userID).First(&left).Error; err != nil {
+			return err
+		}
+		var identities int64
+		if err := tx.Model(&SSOIdentity{}).Where("user_id = ?", userID).Count(&identities).Error; err != nil {
+			return err
+		}
+		if left.PasswordHash == "" && identities == 0 {
+			return ErrLastAuthMethod
+		}
+		return nil
+	})
+}
+
+// AuthMethods lists the ways the user signs in.
+func (s *Service) AuthMethods(ctx context.Context, userID string) ([]AuthMethod, error) {
+	return s.methods.List(ctx, userID)
+}
+
+// requireRecentSignIn returns ErrReauthRequired unless the session signed
+// in within the re-authentication window. Refreshing tokens does not
+// count, so a stolen token alone cannot change how the account signs in.
+func (s *Service) requireRecentSignIn(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return ErrReauthRequired
+	}
+	session, err := s.sessions.Get(ctx, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return ErrReauthRequired
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(session.CreatedAt) > s.reauthWindow {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// SetPassword adds a password to an account that signs in without one,
+// such as one created through single sign-on, from a session that signed
+// in recently. Changing an existing password goes through
+// ChangePassword, which checks the current one.
+func (s *Service) SetPassword(ctx context.Context, userID, sessionID, password string) error {
+	if err := s.requireRecentSignIn(ctx, sessionID); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.methods.SetPassword(ctx, userID, hashedPassword, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditAuthMethodLinked, userID, userID, map[string]string{"method": AuthMethodPassword})
+	return nil
+}
+
+// LinkSSO begins a single sign-on at the identity provider of the email's
+// domain that links the provider account to the user, and returns the
+// provider's URL to send them to. The provider's email may differ from the
+// account's. binding is as in StartSSO. The session must have signed in
+// recently.
+func (s *Service) LinkSSO(ctx context.Context, userID, sessionID, address, binding string) (string, error) {
+	if err := s.requireRecentSignIn(ctx, sessionID); err != nil {
+		return "", err
+	}
+	return s.startSSO(ctx, address, binding, userID)
+}
+
+// UnlinkAuthMethod removes one of the user's sign-in methods: the password,
+// or the identity linked through the SSO connection. The last method is
+// never removed, and the session must have signed in recently. Only
+// LinkSSO links an identity again.
+func (s *Service) UnlinkAuthMethod(ctx context.Context, userID, sessionID, method, connectionID string) error {
+	if err := s.requireRecentSignIn(ctx, sessionID); err != nil {
+		return err
+	}
+
+	var err error
+	switch method {
+	case AuthMethodPassword:
+		err = s.methods.RemovePassword(ctx, userID)
+	case AuthMethodSSO:
+		err = s.methods.Unlink(ctx, userID, connectionID)
+	default:
+		err = ErrAuthMethodNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	details := map[string]string{"method": method}
+	if method == AuthMethodSSO {
+		details["connection_id"] = connectionID
+	}
+	s.audit(ctx, AuditAuthMethodRemoved, userID, userID, details)
+	return nil
+}
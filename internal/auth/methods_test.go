@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// methodsState is the one user the fake database holds: their password
+// hash and the connections of their linked identities.
+type methodsState struct {
+	passwordHash string
+	identities   map[string]bool
+}
+
+func (s methodsState) clone() methodsState {
+	identities := make(map[string]bool, len(s.identities))
+	for id := range s.identities {
+		identities[id] = true
+	}
+	return methodsState{passwordHash: s.passwordHash, identities: identities}
+}
+
+// methodsDriver answers the statements the auth method repository sends
+// for one user, so removeMethod's transaction runs without a Postgres.
+// Rolled back transactions restore the state they started with.
+type methodsDriver struct {
+	mu       sync.Mutex
+	state    methodsState
+	snapshot *methodsState
+}
+
+func (d *methodsDriver) Open(string) (driver.Conn, error) { return d, nil }
+
+func (d *methodsDriver) Connect(context.Context) (driver.Conn, error) { return d, nil }
+
+func (d *methodsDriver) Driver() driver.Driver { return d }
+
+func (d *methodsDriver) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("unexpected prepare: %s", query)
+}
+
+func (d *methodsDriver) Close() error { return nil }
+
+func (d *methodsDriver) Begin() (driver.Tx, error) {
+	return d.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (d *methodsDriver) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	snapshot := d.state.clone()
+	d.snapshot = &snapshot
+	return d, nil
+}
+
+func (d *methodsDriver) Commit() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.snapshot = nil
+	return nil
+}
+
+func (d *methodsDriver) Rollback() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state, d.snapshot = *d.snapshot, nil
+	return nil
+}
+
+func (d *methodsDriver) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.Contains(query, `SELECT "id","password_hash" FROM "users"`):
+		return &methodsRows{columns: []string{"id", "password_hash"}, values: [][]driver.Value{{"user-1", d.state.passwordHash}}}, nil
+	case strings.Contains(query, `SELECT "password_hash" FROM "users"`):
+		return &methodsRows{columns: []string{"password_hash"}, values: [][]driver.Value{{d.state.passwordHash}}}, nil
+	case strings.Contains(query, `SELECT count(*) FROM "sso_identities"`):
+		return &methodsRows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(d.state.identities))}}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (d *methodsDriver) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, `UPDATE "users" SET "password_hash"=`):
+		d.state.passwordHash = args[0].Value.(string)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, `DELETE FROM "sso_identities"`):
+		connectionID := args[1].Value.(string)
+		if !d.state.identities[connectionID] {
+			return driver.RowsAffected(0), nil
+		}
+		delete(d.state.identities, connectionID)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected statement: %s", query)
+}
+
+type methodsRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *methodsRows) Columns() []string { return r.columns }
+
+func (r *methodsRows) Close() error { return nil }
+
+func (r *methodsRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newMethodsRepository(t *testing.T, state methodsState) (*authMethodRepository, *methodsDriver) {
+	t.Helper()
+	fake := &methodsDriver{state: state}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	return &authMethodRepository{db: db}, fake
+}
+
+func TestAuthMethodRepository_RemoveMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  methodsState
+		remove func(r *authMethodRepository) error
+		want   error
+		// left is the state after the removal, or the unchanged state
+		// when it fails
+		left methodsState
+	}{
+		{
+			name:   "password, the last method",
+			state:  methodsState{passwordHash: "hash"},
+			remove: func(r *authMethodRepository) error { return r.RemovePassword(context.Background(), "user-1") },
+			want:   ErrLastAuthMethod,
+			left:   methodsState{passwordHash: "hash"},
+		},
+		{
+			name:   "password next to an identity",
+			state:  methodsState{passwordHash: "hash", identities: map[string]bool{"conn-1": true}},
+			remove: func(r *authMethodRepository) error { return r.RemovePassword(context.Background(), "user-1") },
+			left:   methodsState{identities: map[string]bool{"conn-1": true}},
+		},
+		{
+			name:   "no password",
+			state:  methodsState{identities: map[string]bool{"conn-1": true}},
+			remove: func(r *authMethodRepository) error { return r.RemovePassword(context.Background(), "user-1") },
+			want:   ErrAuthMethodNotFound,
+			left:   methodsState{identities: map[string]bool{"conn-1": true}},
+		},
+		{
+			name:   "identity, the last method",
+			state:  methodsState{identities: map[string]bool{"conn-1": true}},
+			remove: func(r *authMethodRepository) error { return r.Unlink(context.Background(), "user-1", "conn-1") },
+			want:   ErrLastAuthMethod,
+			left:   methodsState{identities: map[string]bool{"conn-1": true}},
+		},
+		{
+			name:   "identity next to another",
+			state:  methodsState{identities: map[string]bool{"conn-1": true, "conn-2": true}},
+			remove: func(r *authMethodRepository) error { return r.Unlink(context.Background(), "user-1", "conn-1") },
+			left:   methodsState{identities: map[string]bool{"conn-2": true}},
+		},
+		{
+			name:   "identity next to a password",
+			state:  methodsState{passwordHash: "hash", identities: map[string]bool{"conn-1": true}},
+			remove: func(r *authMethodRepository) error { return r.Unlink(context.Background(), "user-1", "conn-1") },
+			left:   methodsState{passwordHash: "hash", identities: map[string]bool{}},
+		},
+		{
+			name:   "identity not linked",
+			state:  methodsState{passwordHash: "hash"},
+			remove: func(r *authMethodRepository) error { return r.Unlink(context.Background(), "user-1", "conn-1") },
+			want:   ErrAuthMethodNotFound,
+			left:   methodsState{passwordHash: "hash"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, fake := newMethodsRepository(t, tt.state.clone())
+
+			err := tt.remove(repo)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.left.clone(), fake.state)
+		})
+	}
+}
@@ -5,17 +5,34 @@ import (
 )
 
 type User struct {
-	ID           string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	Email        string    `json:"email" gorm:"uniqueIndex;not null"`
-	Username     string    `json:"username" gorm:"uniqueIndex;not null"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	PasswordHash string    `json:"-" gorm:"not null"`
-	Avatar       string    `json:"avatar"`
-	IsActive     bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	LastLoginAt  time.Time `json:"last_login_at"`
+	ID           string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Email        string `json:"email" gorm:"uniqueIndex;not null"`
+	Username     string `json:"username" gorm:"uniqueIndex;not null"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	PasswordHash string `json:"-" gorm:"not null"`
+	Avatar       string `json:"avatar"`
+	Timezone     string `json:"timezone" gorm:"default:'UTC'"`
+	DataRegion   string `json:"data_region"`
+	IsActive     bool   `json:"is_active" gorm:"default:true"`
+	// EmailVerified is set once the user opened the emailed verification link
+	EmailVerified bool      `json:"email_verified" gorm:"not null;default:false"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	LastLoginAt   time.Time `json:"last_login_at"`
+	// PasswordChangedAt is when the password was last changed or reset
+	PasswordChangedAt *time.Time `json:"-"`
+	// PasswordResetRequired blocks password logins until the user resets
+	// their password, after staff forced a reset
+	PasswordResetRequired bool `json:"-" gorm:"not null;default:false"`
+	// SessionsRevokedAt is when the user was last signed out everywhere;
+	// tokens issued up to then are rejected
+	SessionsRevokedAt *time.Time `json:"-"`
+	// IPAllowlistMode says which requests the user's allowed networks
+	// restrict; see IPAllowlist
+	IPAllowlistMode string `json:"-" gorm:"type:varchar(10);not null;default:'all'"`
+	// Plan is the user's subscription plan; see PlanFree
+	Plan string `json:"plan" gorm:"type:varchar(32);not null;default:'free'"`
 }
 
 // TableName sets the table name for GORM
@@ -29,11 +46,23 @@ type RegisterRequest struct {
 	Password  string `json:"password" validate:"required,min=8"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
+	// Client starts the new account's first session
+	Client ClientInfo `json:"-"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// Scopes restrict the session's access tokens; none leaves them
+	// unrestricted
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientIP is where the login came from, for lockout and auditing
+	ClientIP string `json:"-"`
+	// UserAgent and Device describe the session the login starts
+	UserAgent string `json:"-"`
+	Device    string `json:"-"`
+	// Country is the client's ISO country code, for the login history
+	Country string `json:"-"`
 }
 
 type AuthResponse struct {
@@ -41,4 +70,8 @@ type AuthResponse struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
 	User         *User  `json:"user"`
 	ExpiresIn    int64  `json:"expires_in"`
+	// OrgID and OrgRoles are set by CompleteSSO: the organization of the
+	// connection and the roles there the user's groups map to
+	OrgID    string   `json:"org_id,omitempty"`
+	OrgRoles []string `json:"org_roles,omitempty"`
 }
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcProvider is what an issuer's discovery document and key set say
+// about it.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	keys      map[string]verifyKey
+	fetchedAt time.Time
+}
+
+// OIDCClient speaks the authorization code flow with OpenID Connect
+// providers. Discovery documents and keys are cached per issuer and
+// fetched again when a token names a key the cache does not have.
+type OIDCClient struct {
+	http     *http.Client
+	cacheTTL time.Duration
+
+	mutex     sync.Mutex
+	providers map[string]*oidcProvider
+}
+
+func NewOIDCClient(timeout, cacheTTL time.Duration) *OIDCClient {
+	return &OIDCClient{
+		http:      &http.Client{Timeout: timeout},
+		cacheTTL:  cacheTTL,
+		providers: make(map[string]*oidcProvider),
+	}
+}
+
+// provider returns the issuer's configuration, from the cache unless it is
+// stale or refresh is set.
+func (c *OIDCClient) provider(ctx context.Context, issuer string, refresh bool) (*oidcProvider, error) {
+	c.mutex.Lock()
+	cached, ok := c.providers[issuer]
+	c.mutex.Unlock()
+	if ok && !refresh && time.Since(cached.fetchedAt) < c.cacheTTL {
+		return cached, nil
+	}
+
+	var provider oidcProvider
+	if err := c.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("%w: failed to discover %s: %v", ErrSSOProviderFailed, issuer, err)
+	}
+	// A provider must name itself as the issuer it was discovered under
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("%w: discovery document of %s names issuer %s", ErrSSOProviderFailed, issuer, provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document of %s is missing endpoints", ErrSSOProviderFailed, issuer)
+	}
+
+	var jwks JWKS
+	if err := c.getJSON(ctx, provider.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch keys of %s: %v", ErrSSOProviderFailed, issuer, err)
+	}
+	provider.keys = make(map[string]verifyKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Providers publish keys of types we do not verify with; skip them
+		// rather than refuse the whole set
+		public, method, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		// RSA keys may sign with stronger hashes than our own RS256
+		if jwk.Kty == "RSA" && (strings.HasPrefix(jwk.Alg, "RS") || strings.HasPrefix(jwk.Alg, "PS")) {
+			if alg := jwt.GetSigningMethod(jwk.Alg); alg != nil {
+				method = alg
+			}
+		}
+		provider.keys[jwk.Kid] = verifyKey{public: public, method: method}
+	}
+	provider.fetchedAt = time.Now()
+
+	c.mutex.Lock()
+	c.providers[issuer] = &provider
+	c.mutex.Unlock()
+	return &provider, nil
+}
+
+func (c *OIDCClient) getJSON(ctx context.Context, target string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// AuthorizationURL is where the user is sent to sign in with the
+// connection's provider. The verifier's challenge binds the code to this
+// login (PKCE); the login hint saves the user typing their email again.
+func (c *OIDCClient) AuthorizationURL(ctx context.Context, conn *SSOConnection, redirectURL, state, nonce, verifier, loginHint string) (string, error) {
+	provider, err := c.provider(ctx, conn.Issuer, false)
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint of %s: %v", ErrSSOProviderFailed, conn.Issuer, err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", conn.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+	}
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified claims
+// of the ID token that came with it.
+func (c *OIDCClient) Exchange(ctx context.Context, conn *SSOConnection, redirectURL, code, verifier, nonce string) (jwt.MapClaims, error) {
+	provider, err := c.provider(ctx, conn.Issuer, false)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {conn.ClientID},
+		"code_verifier": {verifier},
+	}
+	if conn.ClientSecret != "" {
+		form.Set("client_secret", conn.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to redeem code with %s: %v", ErrSSOProviderFailed, conn.Issuer, err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%w: invalid token response from %s: %v", ErrSSOProviderFailed, conn.Issuer, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		// invalid_grant and the like mean the code is bad, not the provider
+		return nil, fmt.Errorf("%w: %s answered %d %s", ErrSSOLoginRejected, conn.Issuer, resp.StatusCode, tokens.Error)
+	}
+	return c.verify(ctx, conn, tokens.IDToken, nonce)
+}
+
+// verify checks the ID token's signature, issuer, audience, expiry and
+// nonce.
+func (c *OIDCClient) verify(ctx context.Context, conn *SSOConnection, idToken, nonce string) (jwt.MapClaims, error) {
+	provider, err := c.provider(ctx, conn.Issuer, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := parseIDToken(idToken, conn, provider)
+	if errors.Is(err, ErrUnknownKey) {
+		// The provider may have rotated its keys since they were cached
+		if provider, err = c.provider(ctx, conn.Issuer, true); err != nil {
+			return nil, err
+		}
+		claims, err = parseIDToken(idToken, conn, provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOLoginRejected, err)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrSSOLoginRejected)
+	}
+	return claims, nil
+}
+
+func parseIDToken(idToken string, conn *SSOConnection, provider *oidcProvider) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := provider.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method != key.method {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	}, jwt.WithIssuer(provider.Issuer), jwt.WithAudience(conn.ClientID), jwt.WithExpirationRequired(), jwt.WithLeeway(time.Minute))
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, ErrUnknownKey
+	}
+	return claims, err
+}
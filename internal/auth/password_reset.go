@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPasswordReset = errors.New("invalid password reset token")
+	ErrPasswordResetExpired = errors.New("password reset token expired")
+	ErrPasswordTooShort     = errors.New("password must be at least 8 characters")
+)
+
+// minPasswordLength matches the validation of RegisterRequest.
+const minPasswordLength = 8
+
+// hashPassword checks the length of a new password and hashes it.
+func (s *Service) hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	return s.hasher.Hash(password)
+}
+
+// PasswordReset is an outstanding request to reset a user's password. Only
+// a hash of the emailed token is stored.
+type PasswordReset struct {
+	TokenHash string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `gorm:"type:varchar(36);not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
+
+type PasswordResetRepository interface {
+	// Create replaces any outstanding reset of the user
+	Create(ctx context.Context, reset *PasswordReset) error
+	// Consume sets the token's user's password hash, records the change,
+	// deletes their outstanding resets and revokes their sessions
+	Consume(ctx context.Context, tokenHash, passwordHash string, now time.Time) (*User, error)
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, reset *PasswordReset) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", reset.UserID).Delete(&PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash, passwordHash string, now time.Time) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reset PasswordReset
+		if err := tx.Where("token_hash = ?", tokenHash).First(&reset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidPasswordReset
+			}
+			return err
+		}
+		if now.After(reset.ExpiresAt) {
+			return ErrPasswordResetExpired
+		}
+
+		err := tx.Model(&User{}).Where("id = ?", reset.UserID).Updates(map[string]interface{}{
+			"password_hash":           passwordHash,
+			"password_changed_at":     now,
+			"password_reset_required": false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", reset.UserID).Delete(&PasswordReset{}).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, reset.UserID, now); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", reset.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PasswordResetter issues password reset tokens and mails them to users.
+type PasswordResetter struct {
+	repo   PasswordResetRepository
+	sender email.Sender
+	ttl    time.Duration
+	// link is the page the emailed link opens
+	link string
+}
+
+func NewPasswordResetter(repo PasswordResetRepository, sender email.Sender, ttl time.Duration, link string) *PasswordResetter {
+	return &PasswordResetter{repo: repo, sender: sender, ttl: ttl, link: link}
+}
+
+// Send issues a new token to the user, invalidating earlier ones.
+func (r *PasswordResetter) Send(ctx context.Context, user *User) error {
+	token, hash, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+
+	err = r.repo.Create(ctx, &PasswordReset{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(r.ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store password reset: %w", err)
+	}
+
+	link, err := tokenLink(r.link, token)
+	if err != nil {
+		return fmt.Errorf("invalid password reset url: %w", err)
+	}
+
+	return r.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nYou can choose a new password by opening the link below. It expires in %s.\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.\n",
+			user.FirstName, r.ttl, link),
+	})
+}
+
+// Reset consumes the token and sets the user's new password hash.
+func (r *PasswordResetter) Reset(ctx context.Context, token, passwordHash string) (*User, error) {
+	return r.repo.Consume(ctx, hashVerificationToken(token), passwordHash, time.Now())
+}
+
+// ForgotPassword mails a reset link if an account uses the address. It
+// succeeds either way, and sends in the background so neither the result
+// nor the response time tells whether the account exists.
+func (s *Service) ForgotPassword(ctx context.Context, address string) {
+	user, err := s.repo.GetByEmail(ctx, address)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			log.Printf("Failed to look up user for password reset: %v", err)
+		}
+		return
+	}
+
+	go func() {
+		if err := s.resetter.Send(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("Failed to send password reset email to user %s: %v", user.ID, err)
+		}
+	}()
+}
+
+// ResetPassword sets a new password with an emailed reset token and signs
+// the user out everywhere.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) (*User, error) {
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.resetter.Reset(ctx, token, hashedPassword)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditPasswordReset, user.ID, user.ID, nil)
+	return user, nil
+}
@@ -3,17 +3,50 @@ package auth
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
+	// Create stores the user, failing with ErrEmailTaken when their address
+	// is reserved for undoing another user's email change
 	Create(ctx context.Context, user *User) error
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	Update(ctx context.Context, user *User) error
+	// UpdatePassword sets the user's password hash, records the change and
+	// revokes their sessions
+	UpdatePassword(ctx context.Context, userID, passwordHash string, now time.Time) error
+	// RehashPassword replaces the hash of an unchanged password with a
+	// stronger one of the same password, unless the hash changed since
+	RehashPassword(ctx context.Context, userID, oldHash, newHash string) error
 	Delete(ctx context.Context, id string) error
+	// List returns a page of the users matching query by creation time, and
+	// how many match. The query matches the email, username or name
+	// ignoring case; empty matches everyone.
+	List(ctx context.Context, query string, limit, offset int) ([]User, int64, error)
+	// SetActive activates or deactivates the user. Deactivating revokes
+	// their sessions.
+	SetActive(ctx context.Context, userID string, active bool, now time.Time) error
+	// SetPlan moves the user to plan and records the change, if they are
+	// on from or from is empty. It returns nil when nothing changed.
+	SetPlan(ctx context.Context, userID, plan, from string, now time.Time) (*PlanChange, error)
+	// SetAvatar sets only the user's avatar key and returns the user with
+	// the key it replaced
+	SetAvatar(ctx context.Context, userID, key string) (*User, string, error)
+	// SetDataRegion sets only the user's data region and returns the user
+	SetDataRegion(ctx context.Context, userID, region string) (*User, error)
+	// RequirePasswordReset blocks password logins of the user until they
+	// reset it, and revokes their sessions
+	RequirePasswordReset(ctx context.Context, userID string, now time.Time) error
+	// Identities calls fn with every user in batches. Only the ID, email
+	// and username are loaded.
+	Identities(ctx context.Context, batchSize int, fn func([]User) error) error
 }
 
 type repository struct {
@@ -25,7 +58,14 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 func (r *repository) Create(ctx context.Context, user *User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		// Checked after the insert, which waits on the unique email index
+		// for a confirmation moving the address away to commit
+		return emailReserved(tx, user.Email, user.ID, time.Now())
+	})
 }
 
 func (r *repository) GetByID(ctx context.Context, id string) (*User, error) {
@@ -68,6 +108,155 @@ func (r *repository) Update(ctx context.Context, user *User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
+func (r *repository) UpdatePassword(ctx context.Context, userID, passwordHash string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password_hash":           passwordHash,
+			"password_changed_at":     now,
+			"password_reset_required": false,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return revokeSessions(tx, userID, now)
+	})
+}
+
+func (r *repository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	return r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND password_hash = ?", userID, oldHash).
+		Update("password_hash", newHash).Error
+}
+
 func (r *repository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&User{}, "id = ?", id).Error
 }
+
+func (r *repository) List(ctx context.Context, query string, limit, offset int) ([]User, int64, error) {
+	matching := func(tx *gorm.DB) *gorm.DB {
+		if query == "" {
+			return tx
+		}
+		pattern := "%" + likeEscaper.Replace(query) + "%"
+		return tx.Where("email ILIKE ? OR username ILIKE ? OR first_name || ' ' || last_name ILIKE ?",
+			pattern, pattern, pattern)
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&User{}).Scopes(matching).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []User
+	err := r.db.WithContext(ctx).Scopes(matching).Order("created_at, id").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+// likeEscaper makes user input match literally in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *repository) SetPlan(ctx context.Context, userID, plan, from string, now time.Time) (*PlanChange, error) {
+	var change *PlanChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "plan").Where("id = ?", userID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if user.Plan == plan || from != "" && user.Plan != from {
+			return nil
+		}
+
+		if err := tx.Model(&user).Update("plan", plan).Error; err != nil {
+			return err
+		}
+		change = &PlanChange{ID: uuid.New().String(), UserID: userID, From: user.Plan, To: plan, ChangedAt: now}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (r *repository) SetAvatar(ctx context.Context, userID, key string) (*User, string, error) {
+	var user User
+	var previous string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock keeps two uploads from both replacing the same key, so
+		// neither object is left behind
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar").Where("id = ?", userID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		previous = user.Avatar
+		return tx.Model(&user).Clauses(clause.Returning{}).Where("id = ?", userID).Update("avatar", key).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, previous, nil
+}
+
+func (r *repository) SetDataRegion(ctx context.Context, userID, region string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", userID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&user).Clauses(clause.Returning{}).Where("id = ?", userID).Update("data_region", region).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *repository) SetActive(ctx context.Context, userID string, active bool, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", userID).Update("is_active", active)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		if active {
+			return nil
+		}
+		return revokeSessions(tx, userID, now)
+	})
+}
+
+func (r *repository) RequirePasswordReset(ctx context.Context, userID string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", userID).Update("password_reset_required", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return revokeSessions(tx, userID, now)
+	})
+}
+
+func (r *repository) Identities(ctx context.Context, batchSize int, fn func([]User) error) error {
+	var users []User
+	return r.db.WithContext(ctx).Select("id", "email", "username").
+		FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(users)
+		}).Error
+}
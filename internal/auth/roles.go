@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Built-in roles. Users without a role can only manage their own resources.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+// Permissions are granted through roles and checked by admin routes and
+// RPCs.
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	PermissionBotsHalt    = "bots:halt"
+	PermissionAuditRead   = "audit:read"
+	PermissionSSOManage   = "sso:manage"
+	// PermissionCopyFlagsReview decides the copy trading abuse flags
+	// raised on leaders
+	PermissionCopyFlagsReview = "copy_flags:review"
+)
+
+// builtinRoles are the roles created at startup with their permissions.
+var builtinRoles = map[string][]string{
+	RoleAdmin:   {PermissionUsersRead, PermissionUsersManage, PermissionBotsHalt, PermissionAuditRead, PermissionSSOManage, PermissionCopyFlagsReview},
+	RoleSupport: {PermissionUsersRead, PermissionAuditRead},
+}
+
+var ErrUnknownRole = errors.New("unknown role")
+
+type Role struct {
+	Name      string    `gorm:"primaryKey;type:varchar(50)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission grants a permission to everyone with the role.
+type RolePermission struct {
+	RoleName   string `gorm:"primaryKey;type:varchar(50)"`
+	Permission string `gorm:"primaryKey;type:varchar(100)"`
+}
+
+// TableName sets the table name for GORM
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserRole assigns a role to a user.
+type UserRole struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(36)"`
+	RoleName  string    `gorm:"primaryKey;type:varchar(50);index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+type RoleRepository interface {
+	// SeedBuiltins creates the built-in roles and grants them their
+	// permissions. Permissions granted by hand are kept.
+	SeedBuiltins(ctx context.Context) error
+	// Roles returns the names of the user's roles, sorted
+	Roles(ctx context.Context, userID string) ([]string, error)
+	// RolesOf returns the roles of each of the users that has any
+	RolesOf(ctx context.Context, userIDs []string) (map[string][]string, error)
+	// Permissions returns the permissions the user's roles grant, sorted
+	Permissions(ctx context.Context, userID string) ([]string, error)
+	// SetUserRoles replaces the user's roles. It returns ErrUnknownRole if
+	// one of them does not exist.
+	SetUserRoles(ctx context.Context, userID string, roles []string) error
+	// Exist returns ErrUnknownRole unless all of the roles exist
+	Exist(ctx context.Context, roles []string) error
+	// Grant adds the role to the user of the email, if there is one and
+	// they verified the address
+	Grant(ctx context.Context, email, role string) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) SeedBuiltins(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for name, permissions := range builtinRoles {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Role{Name: name}).Error; err != nil {
+				return err
+			}
+			grants := make([]RolePermission, len(permissions))
+			for i, permission := range permissions {
+				grants[i] = RolePermission{RoleName: name, Permission: permission}
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) Roles(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	err := r.db.WithContext(ctx).Model(&UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role_name").
+		Pluck("role_name", &roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) RolesOf(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	roles := make(map[string][]string)
+	if len(userIDs) == 0 {
+		return roles, nil
+	}
+	var assignments []UserRole
+	err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Order("role_name").Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assignments {
+		roles[assignment.UserID] = append(roles[assignment.UserID], assignment.RoleName)
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) Permissions(ctx context.Context, userID string) ([]string, error) {
+	var permissions []string
+	err := r.db.WithContext(ctx).Model(&RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_name = role_permissions.role_name").
+		Where("user_roles.user_id = ?", userID).
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	roles = dedupe(roles)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := rolesExist(tx, roles); err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&UserRole{}).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&UserRole{UserID: userID, RoleName: role}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) Exist(ctx context.Context, roles []string) error {
+	return rolesExist(r.db.WithContext(ctx), dedupe(roles))
+}
+
+// rolesExist checks distinct role names against the roles table.
+func rolesExist(db *gorm.DB, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	var known int64
+	if err := db.Model(&Role{}).Where("name IN ?", roles).Count(&known).Error; err != nil {
+		return err
+	}
+	if int(known) != len(roles) {
+		return ErrUnknownRole
+	}
+	return nil
+}
+
+func (r *roleRepository) Grant(ctx context.Context, email, role string) error {
+	var user User
+	// Anyone can register an address they do not own; only its owner can
+	// verify it
+	err := r.db.WithContext(ctx).Select("id").Where("email = ? AND email_verified", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRole{UserID: user.ID, RoleName: role}).Error
+}
+
+// dedupe returns the distinct values, sorted.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var distinct []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
+
+// BootstrapRoles creates the built-in roles and makes the users of the
+// emails admins once they verified their address, now or when they do.
+func (s *Service) BootstrapRoles(ctx context.Context, admins []string) error {
+	if err := s.roles.SeedBuiltins(ctx); err != nil {
+		return err
+	}
+	s.admins = admins
+	for _, email := range admins {
+		if err := s.roles.Grant(ctx, email, RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantBootstrapAdmin makes the user an admin if they just verified one
+// of the bootstrap admin emails.
+func (s *Service) grantBootstrapAdmin(ctx context.Context, user *User) {
+	if !slices.ContainsFunc(s.admins, func(email string) bool { return strings.EqualFold(email, user.Email) }) {
+		return
+	}
+	if err := s.roles.Grant(ctx, user.Email, RoleAdmin); err != nil {
+		log.Printf("Failed to grant the admin role to user %s: %v", user.ID, err)
+	}
+}
+
+// Access returns the user's current roles and the permissions they grant.
+func (s *Service) Access(ctx context.Context, userID string) ([]string, []string, error) {
+	roles, err := s.roles.Roles(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	permissions, err := s.roles.Permissions(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return roles, permissions, nil
+}
+
+// ListUsers returns a page of the users matching query with the roles of
+// each, keyed by user ID, and the total number of matches.
+func (s *Service) ListUsers(ctx context.Context, query string, limit, offset int) ([]User, map[string][]string, int64, error) {
+	users, total, err := s.repo.List(ctx, query, limit, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ids := make([]string, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	roles, err := s.roles.RolesOf(ctx, ids)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return users, roles, total, nil
+}
+
+// SetUserRoles replaces the user's roles on behalf of actorID. Tokens
+// already issued keep the old roles in their claims until they expire.
+func (s *Service) SetUserRoles(ctx context.Context, actorID, userID string, roles []string) (*User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roles.SetUserRoles(ctx, userID, roles); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditRolesChanged, userID, actorID, map[string]string{"roles": strings.Join(dedupe(roles), ",")})
+	return user, nil
+}
+
+// accessToken issues an access token for the session carrying the user's
+// roles and the session's scopes.
+func (s *Service) accessToken(ctx context.Context, userID, sessionID string, scopes []string) (string, error) {
+	roles, err := s.roles.Roles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return s.tokenService.GenerateAccessToken(userID, sessionID, roles, scopes)
+}
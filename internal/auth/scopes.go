@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Resources that access tokens can be scoped to. Each has a read scope,
+// "<resource>:read", and most a write scope, "<resource>:write".
+const (
+	ResourceAccount   = "account"
+	ResourceBots      = "bots"
+	ResourceOrders    = "orders"
+	ResourcePortfolio = "portfolio"
+	ResourceMarket    = "market"
+)
+
+// Scopes an access token can be restricted to. A token without scopes may
+// do everything its user may.
+const (
+	ScopeAccountRead   = "account:read"
+	ScopeAccountWrite  = "account:write"
+	ScopeBotsRead      = "bots:read"
+	ScopeBotsWrite     = "bots:write"
+	ScopeOrdersRead    = "orders:read"
+	ScopeOrdersWrite   = "orders:write"
+	ScopePortfolioRead = "portfolio:read"
+	ScopeMarketRead    = "market:read"
+)
+
+// AllScopes lists every scope, which is what an unscoped token grants.
+var AllScopes = []string{
+	ScopeAccountRead, ScopeAccountWrite,
+	ScopeBotsRead, ScopeBotsWrite,
+	ScopeOrdersRead, ScopeOrdersWrite,
+	ScopePortfolioRead,
+	ScopeMarketRead,
+}
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+// NormalizeScopes checks that every requested scope exists and returns
+// them sorted, without duplicates.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		out = append(out, scope)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// HasScope reports whether a token with the given scopes may use scope.
+// Write scopes include reading the same resource.
+func HasScope(scopes []string, scope string) bool {
+	if len(scopes) == 0 || slices.Contains(scopes, scope) {
+		return true
+	}
+	resource, ok := strings.CutSuffix(scope, ":read")
+	return ok && slices.Contains(scopes, resource+":write")
+}
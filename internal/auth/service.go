@@ -3,38 +3,89 @@ package auth
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/tradingbothub/platform/internal/avatar"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidRegion      = errors.New("invalid data region")
+	ErrInvalidAvatar      = errors.New("invalid avatar")
+	ErrTokenRevoked       = errors.New("token revoked")
 )
 
 type Service struct {
 	repo         Repository
 	tokenService TokenService
+	logins       LoginRecorder
+	sessions     SessionRepository
+	roles        RoleRepository
+	existence    *Existence
+	lockout      *Lockout
+	verifier     *Verifier
+	resetter     *PasswordResetter
+	emailChanges *EmailChanger
+	magicLinks   *MagicLinker
+	sso          *SSO
+	auditor      *Auditor
+	monitor      *LoginMonitor
+	methods      AuthMethodRepository
+	allowlist    IPAllowlistRepository
+	hasher       PasswordHasher
+	// admins are the emails made admins once verified
+	admins []string
+	// reauthWindow is how recently a session must have signed in to
+	// change its user's sign-in methods
+	reauthWindow time.Duration
 }
 
-func NewService(repo Repository, tokenService TokenService) *Service {
+func NewService(repo Repository, tokenService TokenService, sessions SessionRepository, roles RoleRepository, existence *Existence, lockout *Lockout, logins LoginRecorder, verifier *Verifier, resetter *PasswordResetter, emailChanges *EmailChanger, magicLinks *MagicLinker, sso *SSO, auditor *Auditor, monitor *LoginMonitor, methods AuthMethodRepository, allowlist IPAllowlistRepository, hasher PasswordHasher, reauthWindow time.Duration) *Service {
 	return &Service{
 		repo:         repo,
 		tokenService: tokenService,
+		sessions:     sessions,
+		roles:        roles,
+		existence:    existence,
+		lockout:      lockout,
+		logins:       logins,
+		verifier:     verifier,
+		resetter:     resetter,
+		emailChanges: emailChanges,
+		magicLinks:   magicLinks,
+		sso:          sso,
+		auditor:      auditor,
+		monitor:      monitor,
+		methods:      methods,
+		allowlist:    allowlist,
+		hasher:       hasher,
+		reauthWindow: reauthWindow,
 	}
 }
 
 func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
 	// Check if user exists
-	if _, err := s.repo.GetByEmail(ctx, req.Email); err == nil {
+	taken, err := s.existence.EmailTaken(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
 		return nil, ErrUserExists
 	}
+	taken, err = s.existence.UsernameTaken(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrUsernameTaken
+	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -46,23 +97,27 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 		Username:     req.Username,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		IsActive:     true,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	if err := s.repo.Create(ctx, user); err != nil {
+	if err := s.repo.Create(ctx, user); errors.Is(err, ErrEmailTaken) {
+		return nil, ErrUserExists
+	} else if err != nil {
 		return nil, err
 	}
+	s.existence.Added(ctx, user)
+	s.sendVerification(ctx, user)
+	s.audit(ctx, AuditRegistered, user.ID, user.ID, nil)
+	// The first session starts the login history later logins are compared to
+	event := newLoginEvent(user.ID, LoginMethodPassword, req.Client)
+	event.CreatedAt = user.CreatedAt
+	s.monitor.Record(ctx, event)
 
 	// Generate tokens
-	accessToken, err := s.tokenService.GenerateAccessToken(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	refreshToken, err := s.tokenService.GenerateRefreshToken(user.ID)
+	accessToken, refreshToken, err := s.issueTokens(ctx, user.ID, req.Client, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -76,31 +131,74 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 }
 
 func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
+	scopes, err := NormalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked out logins are rejected before the password is even checked
+	if err := s.lockout.Check(ctx, req.Email, req.ClientIP); err != nil {
+		s.audit(ctx, AuditLoginFailed, "", "", map[string]string{"email": req.Email, "reason": "locked_out"})
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.repo.GetByEmail(ctx, req.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		s.lockout.Failed(ctx, req.Email, req.ClientIP, "")
+		s.audit(ctx, AuditLoginFailed, "", "", map[string]string{"email": req.Email, "reason": "unknown_email"})
+	}
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	ok, rehash, err := s.hasher.Verify(user.PasswordHash, req.Password)
+	if err != nil {
+		log.Printf("Failed to verify password of user %s: %v", user.ID, err)
+	}
+	if !ok {
+		s.lockout.Failed(ctx, req.Email, req.ClientIP, user.ID)
+		s.audit(ctx, AuditLoginFailed, user.ID, "", map[string]string{"reason": "wrong_password"})
 		return nil, ErrInvalidCredentials
 	}
+	s.lockout.Succeeded(ctx, req.Email)
+	if rehash {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+	if !user.IsActive {
+		s.audit(ctx, AuditLoginFailed, user.ID, "", map[string]string{"reason": "deactivated"})
+		return nil, ErrAccountDeactivated
+	}
+	if user.PasswordResetRequired {
+		s.audit(ctx, AuditLoginFailed, user.ID, "", map[string]string{"reason": "password_reset_required"})
+		return nil, ErrPasswordResetRequired
+	}
 
-	// Update last login
-	user.LastLoginAt = time.Now()
-	s.repo.Update(ctx, user)
-
-	// Generate tokens
-	accessToken, err := s.tokenService.GenerateAccessToken(user.ID)
+	// Logins from a new country or device may need confirming by email
+	client := ClientInfo{
+		Device:    req.Device,
+		IP:        req.ClientIP,
+		UserAgent: req.UserAgent,
+		Country:   req.Country,
+	}
+	event, err := s.monitor.Assess(ctx, user.ID, LoginMethodPassword, client)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.stepUp(ctx, user, event); err != nil {
+		return nil, err
+	}
+
+	// Last login is written asynchronously, off the critical path
+	s.recordLogin(ctx, user, event)
 
-	refreshToken, err := s.tokenService.GenerateRefreshToken(user.ID)
+	// Generate tokens
+	accessToken, refreshToken, err := s.issueTokens(ctx, user.ID, client, scopes)
 	if err != nil {
 		return nil, err
 	}
+	s.audit(ctx, AuditLoginSucceeded, user.ID, user.ID, nil)
 
 	return &AuthResponse{
 		AccessToken:  accessToken,
@@ -112,37 +210,128 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse,
 
 func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
 	// Validate refresh token
-	userID, err := s.tokenService.ValidateRefreshToken(refreshToken)
+	claims, err := s.tokenService.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get user
-	user, err := s.repo.GetByID(ctx, userID)
+	user, err := s.repo.GetByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	if issuedBeforeRevocation(claims, user) {
+		return nil, ErrTokenRevoked
+	}
+
+	// Refresh tokens are single use: this one is replaced by a successor
+	nextToken, nextClaims, err := s.tokenService.GenerateRefreshToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	next := &RefreshToken{
+		ID:        nextClaims.ID,
+		UserID:    user.ID,
+		ExpiresAt: nextClaims.ExpiresAt.Time,
+	}
+	err = s.sessions.Rotate(ctx, claims.ID, next, time.Now())
+	if errors.Is(err, ErrRefreshTokenReused) {
+		s.audit(ctx, AuditRefreshReused, user.ID, "", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate new access token
-	accessToken, err := s.tokenService.GenerateAccessToken(user.ID)
+	// Generate new access token for the same session and scopes
+	session, err := s.sessions.Get(ctx, next.FamilyID)
 	if err != nil {
 		return nil, err
 	}
+	accessToken, err := s.accessToken(ctx, user.ID, next.FamilyID, session.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, AuditTokenRefreshed, user.ID, user.ID, map[string]string{"session_id": next.FamilyID})
 
 	return &AuthResponse{
-		AccessToken: accessToken,
-		User:        user,
-		ExpiresIn:   3600,
+		AccessToken:  accessToken,
+		RefreshToken: nextToken,
+		User:         user,
+		ExpiresIn:    3600,
 	}, nil
 }
 
-func (s *Service) ValidateToken(ctx context.Context, token string) (*User, error) {
+func (s *Service) ValidateToken(ctx context.Context, token string) (*User, *Claims, error) {
 	// Validate token
-	userID, err := s.tokenService.ValidateAccessToken(token)
+	claims, err := s.tokenService.ValidateAccessToken(token)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Get user
-	return s.repo.GetByID(ctx, userID)
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrAccountDeactivated
+	}
+	if issuedBeforeRevocation(claims, user) {
+		return nil, nil, ErrTokenRevoked
+	}
+	revoked, err := s.sessionRevoked(ctx, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrTokenRevoked
+	}
+	return user, claims, nil
+}
+
+// JWKS returns the public keys that verify tokens as a JSON Web Key Set.
+func (s *Service) JWKS() []byte {
+	return s.tokenService.JWKS()
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one, and signs them out everywhere so a leaked password stops working.
+func (s *Service) ChangePassword(ctx context.Context, user *User, oldPassword, newPassword string) error {
+	if ok, _, err := s.hasher.Verify(user.PasswordHash, oldPassword); err != nil || !ok {
+		return ErrInvalidCredentials
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditPasswordChanged, user.ID, user.ID, nil)
+	return nil
+}
+
+// SetDataRegion records where the user's trade data is stored. Callers are
+// responsible for checking that the region exists.
+func (s *Service) SetDataRegion(ctx context.Context, userID, region string) (*User, error) {
+	if region == "" {
+		return nil, ErrInvalidRegion
+	}
+
+	return s.repo.SetDataRegion(ctx, userID, region)
+}
+
+// SetAvatar points the user's avatar at an object key they uploaded, or
+// clears it when key is empty. It returns the user and the key it
+// replaced, whose object the caller deletes.
+func (s *Service) SetAvatar(ctx context.Context, userID, key string) (*User, string, error) {
+	if key != "" && !avatar.Owns(userID, key) {
+		return nil, "", ErrInvalidAvatar
+	}
+
+	return s.repo.SetAvatar(ctx, userID, key)
 }
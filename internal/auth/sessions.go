@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRefreshTokenReused is returned when a refresh token that was
+	// already rotated is presented again, which means it leaked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	ErrSessionNotFound    = errors.New("session not found")
+)
+
+// Session is one signed-in device: a login or registration and the
+// refresh tokens descending from it. Access tokens name their session, so
+// revoking it signs the device out right away.
+type Session struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	UserID    string `gorm:"type:varchar(36);not null;index"`
+	Device    string `gorm:"type:varchar(100)"`
+	IPAddress string `gorm:"type:varchar(45)"`
+	UserAgent string `gorm:"type:varchar(500)"`
+	// LastSeenAt is when the session last signed in or refreshed its tokens
+	LastSeenAt time.Time `gorm:"not null"`
+	// ExpiresAt follows the session's newest refresh token
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// Scopes restrict every access token of the session; refreshing
+	// cannot widen them
+	Scopes    pq.StringArray `gorm:"type:text[]"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// ClientInfo describes where a session was started from.
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+	// Country is the client's ISO country code, if the gateway knows it
+	Country string
+}
+
+// RefreshToken tracks an issued refresh token by its JWT ID. Each refresh
+// replaces the token with a new one of the same family, the chain of
+// tokens descending from one login. The family ID is the session ID.
+type RefreshToken struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `gorm:"type:varchar(36);not null;index"`
+	FamilyID  string    `gorm:"type:varchar(36);not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// RotatedAt is set once the token was exchanged for its successor
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+type SessionRepository interface {
+	// Create starts the session with its first refresh token
+	Create(ctx context.Context, session *Session, token *RefreshToken) error
+	Get(ctx context.Context, id string) (*Session, error)
+	// List returns the user's sessions that are neither revoked nor
+	// expired, most recently seen first
+	List(ctx context.Context, userID string, now time.Time) ([]Session, error)
+	// Rotate marks the token rotated and stores its successor in the same
+	// session. Presenting a rotated token revokes its whole session and
+	// returns ErrRefreshTokenReused.
+	Rotate(ctx context.Context, id string, next *RefreshToken, now time.Time) error
+	// GetRefreshToken returns the refresh token with the JWT ID, or
+	// ErrTokenRevoked if it is not tracked
+	GetRefreshToken(ctx context.Context, id string) (*RefreshToken, error)
+	// Revoke revokes one session of the user and its refresh tokens
+	Revoke(ctx context.Context, userID, sessionID string, now time.Time) error
+	// RevokeUser revokes every session of the user and records the time,
+	// so access tokens issued before it are rejected too
+	RevokeUser(ctx context.Context, userID string, now time.Time) error
+	// DeleteExpired removes tokens and sessions that expired before the
+	// given time
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *Session, token *RefreshToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		token.FamilyID = session.ID
+		return tx.Create(token).Error
+	})
+}
+
+func (r *sessionRepository) Get(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) List(ctx context.Context, userID string, now time.Time) ([]Session, error) {
+	var sessions []Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, id string, next *RefreshToken, now time.Time) error {
+	reused := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock makes concurrent refreshes with one token see each
+		// other's rotation
+		var current RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTokenRevoked
+		}
+		if err != nil {
+			return err
+		}
+		if current.RevokedAt != nil {
+			return ErrTokenRevoked
+		}
+
+		if current.RotatedAt != nil {
+			// The revocation has to be committed, so the error is only
+			// returned after the transaction
+			reused = true
+			return revokeSession(tx, current.FamilyID, now)
+		}
+
+		if err := tx.Model(&current).Update("rotated_at", now).Error; err != nil {
+			return err
+		}
+		next.FamilyID = current.FamilyID
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&Session{}).Where("id = ?", current.FamilyID).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   next.ExpiresAt,
+		})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		// Families started before sessions were tracked get their row on
+		// the first refresh
+		return tx.Create(&Session{
+			ID:         current.FamilyID,
+			UserID:     current.UserID,
+			LastSeenAt: now,
+			ExpiresAt:  next.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetRefreshToken(ctx context.Context, id string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, userID, sessionID string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session Session
+		err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return revokeSession(tx, session.ID, now)
+	})
+}
+
+// revokeSession revokes the session and every refresh token in it.
+func revokeSession(tx *gorm.DB, sessionID string, now time.Time) error {
+	err := tx.Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&Session{}).Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userID string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, userID, now)
+	})
+}
+
+// revokeSessions revokes the user's refresh tokens and rejects their
+// access tokens from now on.
+func revokeSessions(tx *gorm.DB, userID string, now time.Time) error {
+	err := tx.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&User{}).Where("id = ?", userID).Update("sessions_revoked_at", now).Error
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	tokens := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&RefreshToken{})
+	if tokens.Error != nil {
+		return 0, tokens.Error
+	}
+	sessions := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&Session{})
+	return tokens.RowsAffected + sessions.RowsAffected, sessions.Error
+}
+
+// SessionPurger deletes expired refresh tokens and sessions. Their JWTs
+// are rejected once expired, so the rows are no longer needed to detect
+// reuse. Access tokens expire long before their session does.
+type SessionPurger struct {
+	sessions SessionRepository
+}
+
+func NewSessionPurger(sessions SessionRepository) *SessionPurger {
+	return &SessionPurger{sessions: sessions}
+}
+
+// Run matches scheduler.JobFunc.
+func (p *SessionPurger) Run(ctx context.Context) error {
+	_, err := p.sessions.DeleteExpired(ctx, time.Now())
+	return err
+}
+
+// issueTokens starts a new session for a login or registration, whose
+// access tokens are restricted to scopes if any are given.
+func (s *Service) issueTokens(ctx context.Context, userID string, client ClientInfo, scopes []string) (string, string, error) {
+	refreshToken, claims, err := s.tokenService.GenerateRefreshToken(userID)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	session := &Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Device:     truncate(client.Device, 100),
+		IPAddress:  client.IP,
+		UserAgent:  truncate(client.UserAgent, 500),
+		LastSeenAt: now,
+		ExpiresAt:  claims.ExpiresAt.Time,
+		Scopes:     scopes,
+	}
+	err = s.sessions.Create(ctx, session, &RefreshToken{
+		ID:        claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := s.accessToken(ctx, userID, session.ID, scopes)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// Sessions lists the user's signed-in devices.
+func (s *Service) Sessions(ctx context.Context, userID string) ([]Session, error) {
+	return s.sessions.List(ctx, userID, time.Now())
+}
+
+// RevokeSession signs one device out. Its refresh tokens stop working and
+// access tokens naming the session are rejected.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := s.sessions.Revoke(ctx, userID, sessionID, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditSessionRevoked, userID, userID, map[string]string{"session_id": sessionID})
+	return nil
+}
+
+// RevokeSessions signs the user out everywhere: refresh tokens can no
+// longer be used and access tokens issued so far are rejected.
+func (s *Service) RevokeSessions(ctx context.Context, userID string) error {
+	if err := s.sessions.RevokeUser(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditSessionsRevoked, userID, userID, nil)
+	return nil
+}
+
+// issuedBeforeRevocation reports whether the token predates the last time
+// the user's sessions were revoked. Token times have second precision, so
+// tokens from the second of the revocation are rejected too.
+func issuedBeforeRevocation(claims *Claims, user *User) bool {
+	if user.SessionsRevokedAt == nil {
+		return false
+	}
+	return claims.IssuedAt == nil || !claims.IssuedAt.Time.After(user.SessionsRevokedAt.Truncate(time.Second))
+}
+
+// sessionRevoked reports whether the access token's session was revoked.
+// Tokens from before sessions were tracked name none.
+func (s *Service) sessionRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.SessionID == "" {
+		return false, nil
+	}
+	session, err := s.sessions.Get(ctx, claims.SessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return session.RevokedAt != nil, nil
+}
+
+// truncate cuts client supplied strings to fit their columns.
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return strings.ToValidUTF8(value[:limit], "")
+}
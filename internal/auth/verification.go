@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidVerification = errors.New("invalid verification token")
+	ErrVerificationExpired = errors.New("verification token expired")
+	ErrAlreadyVerified     = errors.New("email already verified")
+)
+
+// EmailVerification is a pending confirmation of a user's email address.
+// Only a hash of the emailed token is stored.
+type EmailVerification struct {
+	TokenHash string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `gorm:"type:varchar(36);not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (EmailVerification) TableName() string {
+	return "email_verifications"
+}
+
+type VerificationRepository interface {
+	// Create replaces any pending verification of the user
+	Create(ctx context.Context, verification *EmailVerification) error
+	// Consume marks the token's user verified and deletes their pending
+	// verifications
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*User, error)
+}
+
+type verificationRepository struct {
+	db *gorm.DB
+}
+
+func NewVerificationRepository(db *gorm.DB) VerificationRepository {
+	return &verificationRepository{db: db}
+}
+
+func (r *verificationRepository) Create(ctx context.Context, verification *EmailVerification) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", verification.UserID).Delete(&EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(verification).Error
+	})
+}
+
+func (r *verificationRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var verification EmailVerification
+		if err := tx.Where("token_hash = ?", tokenHash).First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidVerification
+			}
+			return err
+		}
+		if now.After(verification.ExpiresAt) {
+			return ErrVerificationExpired
+		}
+
+		if err := tx.Model(&User{}).Where("id = ?", verification.UserID).Update("email_verified", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", verification.UserID).Delete(&EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", verification.UserID).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Verifier issues email verification tokens and mails them to users.
+type Verifier struct {
+	repo   VerificationRepository
+	sender email.Sender
+	ttl    time.Duration
+	// link is the page the emailed link opens
+	link string
+}
+
+func NewVerifier(repo VerificationRepository, sender email.Sender, ttl time.Duration, link string) *Verifier {
+	return &Verifier{repo: repo, sender: sender, ttl: ttl, link: link}
+}
+
+// Send issues a new token to the user, invalidating earlier ones.
+func (v *Verifier) Send(ctx context.Context, user *User) error {
+	token, hash, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+
+	err = v.repo.Create(ctx, &EmailVerification{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(v.ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store verification: %w", err)
+	}
+
+	link, err := tokenLink(v.link, token)
+	if err != nil {
+		return fmt.Errorf("invalid verification url: %w", err)
+	}
+
+	return v.sender.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.FirstName, v.ttl, link),
+	})
+}
+
+// Verify consumes the token and returns the now verified user.
+func (v *Verifier) Verify(ctx context.Context, token string) (*User, error) {
+	return v.repo.Consume(ctx, hashVerificationToken(token), time.Now())
+}
+
+func newVerificationToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(raw)
+	return token, hashVerificationToken(token), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenLink appends the token to the page as its "token" query parameter.
+func tokenLink(page, token string) (string, error) {
+	link, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// sendVerification mails a verification link, logging rather than failing
+// since the user can ask for another one.
+func (s *Service) sendVerification(ctx context.Context, user *User) {
+	if err := s.verifier.Send(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+}
+
+// VerifyEmail confirms the address the token was sent to.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	user, err := s.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	s.grantBootstrapAdmin(ctx, user)
+	return user, nil
+}
+
+// ResendVerification issues a fresh verification email.
+func (s *Service) ResendVerification(ctx context.Context, user *User) error {
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	return s.verifier.Send(ctx, user)
+}
@@ -0,0 +1,88 @@
+// Package avatar stores profile pictures in the object store and hands out
+// signed URLs to them. Users only reference their avatar by object key, so
+// the bucket can stay private.
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/pkg/objectstore"
+)
+
+var (
+	ErrFileTooLarge = errors.New("avatar file too large")
+	ErrNoAvatar     = errors.New("no avatar")
+)
+
+// Service processes uploads and signs URLs.
+type Service struct {
+	store objectstore.Store
+	cfg   config.AvatarConfig
+}
+
+func NewService(store objectstore.Store, cfg config.AvatarConfig) *Service {
+	return &Service{store: store, cfg: cfg}
+}
+
+// Owns reports whether key is an avatar uploaded by userID. Only such keys
+// are set on a user or signed, so an avatar cannot point at other objects.
+func Owns(userID, key string) bool {
+	return userID != "" && strings.HasPrefix(key, objectstore.PrefixAvatars+userID+"/")
+}
+
+// Upload validates and resizes the image and stores it under a new key,
+// which it returns. Every upload gets its own key, so URLs signed for the
+// previous avatar never show the new one.
+func (s *Service) Upload(ctx context.Context, userID string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > s.cfg.MaxBytes {
+		return "", ErrFileTooLarge
+	}
+
+	img, err := process(data, s.cfg.MaxPixels, s.cfg.Size)
+	if err != nil {
+		return "", err
+	}
+
+	key := objectstore.PrefixAvatars + userID + "/" + uuid.New().String() + img.ext
+	err = s.store.Put(ctx, key, bytes.NewReader(img.data), objectstore.PutOptions{ContentType: img.contentType})
+	if err != nil {
+		return "", fmt.Errorf("failed to store avatar: %w", err)
+	}
+	return key, nil
+}
+
+// URL signs a download URL for the user's avatar and returns when it
+// expires.
+func (s *Service) URL(ctx context.Context, userID, key string) (string, time.Time, error) {
+	if !Owns(userID, key) {
+		return "", time.Time{}, ErrNoAvatar
+	}
+	expiresAt := time.Now().Add(s.cfg.URLTTL)
+	url, err := s.store.SignedURL(ctx, key, http.MethodGet, s.cfg.URLTTL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign avatar URL: %w", err)
+	}
+	return url, expiresAt, nil
+}
+
+// Delete removes a replaced avatar. Keys the user does not own are left
+// alone.
+func (s *Service) Delete(ctx context.Context, userID, key string) error {
+	if !Owns(userID, key) {
+		return nil
+	}
+	return s.store.Delete(ctx, key)
+}
@@ -0,0 +1,105 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrImageTooLarge    = errors.New("avatar image dimensions too large")
+)
+
+// jpegQuality suits photos at avatar size
+const jpegQuality = 85
+
+// processed is an avatar ready to store.
+type processed struct {
+	data        []byte
+	contentType string
+	ext         string
+}
+
+// process decodes an uploaded image, crops it to a centered square and
+// scales it down to size. JPEGs stay JPEGs; everything else becomes a PNG
+// so transparency survives. Re-encoding drops metadata such as EXIF
+// locations, and only the first frame of a GIF is kept.
+func process(data []byte, maxPixels, size int) (*processed, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	// Checked before decoding, which allocates the full image
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	dst := squareThumbnail(src, size)
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+		return &processed{data: out.Bytes(), contentType: "image/jpeg", ext: ".jpg"}, nil
+	}
+	if err := png.Encode(&out, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", err)
+	}
+	return &processed{data: out.Bytes(), contentType: "image/png", ext: ".png"}, nil
+}
+
+// squareThumbnail crops the largest centered square out of src and scales
+// it down to size by averaging the source pixels each target pixel covers.
+// Smaller images are cropped but not enlarged.
+func squareThumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side)
+	offset := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+
+	// Premultiplied RGBA averages correctly across transparent pixels
+	square := image.NewRGBA(crop)
+	draw.Draw(square, crop, src, offset, draw.Src)
+	if size <= 0 || side <= size {
+		return square
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := square.Pix[sy*square.Stride+x0*4 : sy*square.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					bl += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,149 @@
+// Package backtest replays recorded candles through strategy configs,
+// sweeps parameter grids across a worker pool and streams single runs over
+// gRPC as they are simulated.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+// ErrInvalidFillModel is returned for fill models no market could produce.
+var ErrInvalidFillModel = errors.New("invalid fill model")
+
+// FillModel describes how simulated market orders fill.
+type FillModel struct {
+	// SlippageBps is the largest adverse slippage of a fill in basis
+	// points; each fill draws its slippage from the run's seed
+	SlippageBps float64 `json:"slippage_bps"`
+}
+
+// Validate rejects negative slippage, which would fill every order better
+// than the market.
+func (f FillModel) Validate() error {
+	if f.SlippageBps < 0 || math.IsNaN(f.SlippageBps) || math.IsInf(f.SlippageBps, 0) {
+		return fmt.Errorf("%w: slippage_bps must not be negative", ErrInvalidFillModel)
+	}
+	return nil
+}
+
+// Fill is a simulated order and the price it filled at.
+type Fill struct {
+	strategy.SimulatedOrder
+	FillPrice decimal.Decimal `json:"fill_price"`
+}
+
+// Result is the outcome of one backtest run. Seed reproduces it exactly.
+type Result struct {
+	Config     strategy.Config `json:"config"`
+	Seed       int64           `json:"seed"`
+	Fills      []Fill          `json:"fills"`
+	RoundTrips int             `json:"round_trips"`
+	Wins       int             `json:"wins"`
+	// PnL is the realized result in quote currency plus any open position
+	// marked at the last close
+	PnL decimal.Decimal `json:"pnl"`
+}
+
+// EquityPoint is the value of a run at a candle's close: realized PnL
+// plus the open position marked at the close.
+type EquityPoint struct {
+	Time   time.Time       `json:"time"`
+	Equity decimal.Decimal `json:"equity"`
+}
+
+// Observer follows a run while it is simulated. An error returned by any
+// method stops the run with that error.
+type Observer interface {
+	// Fill is called as each simulated order fills
+	Fill(fill Fill) error
+	// Equity is called at every candle from the start of the window on
+	Equity(point EquityPoint) error
+	// Progress is called after each candle with the number of candles
+	// simulated so far, warm-up candles included
+	Progress(done, total int) error
+}
+
+// Run backtests cfg over candles (sorted by time, including warm-up
+// candles before from). Everything random is drawn from seed, so equal
+// inputs give identical results.
+func Run(cfg strategy.Config, candles []marketdata.Candle, from time.Time, fills FillModel, seed int64) (*Result, error) {
+	return Simulate(cfg, candles, from, fills, seed, nil)
+}
+
+// Simulate is Run reporting to observer, which may be nil, one candle at a
+// time.
+func Simulate(cfg strategy.Config, candles []marketdata.Candle, from time.Time, fills FillModel, seed int64, observer Observer) (*Result, error) {
+	stream, err := strategy.NewStream(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	bps := decimal.NewFromFloat(fills.SlippageBps).Div(decimal.NewFromInt(10000))
+	result := &Result{Config: cfg, Seed: seed, Fills: []Fill{}}
+
+	// The simulated bot is long-only and starts flat at from
+	long := false
+	var entry decimal.Decimal
+	for i, candle := range candles {
+		signal, ok := stream.Update(candle)
+		if ok && !signal.Time.Before(from) && (signal.Side == exchange.SideBuy) != long {
+			long = !long
+			order := strategy.Order(cfg, signal)
+			slippage := order.Price.Mul(bps).Mul(decimal.NewFromFloat(rng.Float64())).Round(8)
+			fill := Fill{SimulatedOrder: order, FillPrice: order.Price.Add(slippage)}
+			if order.Side == exchange.SideSell {
+				fill.FillPrice = order.Price.Sub(slippage)
+			}
+			result.Fills = append(result.Fills, fill)
+
+			if order.Side == exchange.SideBuy {
+				entry = fill.FillPrice
+			} else {
+				gain := fill.FillPrice.Sub(entry).Mul(order.Quantity)
+				result.PnL = result.PnL.Add(gain)
+				result.RoundTrips++
+				if gain.IsPositive() {
+					result.Wins++
+				}
+			}
+			if observer != nil {
+				if err := observer.Fill(fill); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if observer == nil {
+			continue
+		}
+		if !candle.Time.Before(from) {
+			equity := result.PnL
+			if long {
+				equity = equity.Add(decimal.NewFromFloat(candle.Close).Sub(entry).Mul(cfg.Quantity))
+			}
+			if err := observer.Equity(EquityPoint{Time: candle.Time, Equity: equity}); err != nil {
+				return nil, err
+			}
+		}
+		if err := observer.Progress(i+1, len(candles)); err != nil {
+			return nil, err
+		}
+	}
+
+	// An open position is marked at the last close
+	if long && len(candles) > 0 {
+		last := decimal.NewFromFloat(candles[len(candles)-1].Close)
+		result.PnL = result.PnL.Add(last.Sub(entry).Mul(cfg.Quantity))
+	}
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	backtestpb "github.com/tradingbothub/platform/api/proto/backtest"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// equityChunkSize is how many equity points are sent per message.
+const equityChunkSize = 500
+
+type GRPCServer struct {
+	backtestpb.UnimplementedBacktestServiceServer
+	candles marketdata.CandleStore
+}
+
+func NewGRPCServer(candles marketdata.CandleStore) *GRPCServer {
+	return &GRPCServer{candles: candles}
+}
+
+func (s *GRPCServer) RunBacktest(req *backtestpb.RunBacktestRequest, stream grpc.ServerStreamingServer[backtestpb.BacktestEvent]) error {
+	fills := FillModel{SlippageBps: req.SlippageBps}
+	cfg, err := ConfigFromProto(req.Config)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err == nil {
+		err = fills.Validate()
+	}
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.From == nil || req.To == nil || !req.From.AsTime().Before(req.To.AsTime()) {
+		return status.Error(codes.InvalidArgument, "from must be before to")
+	}
+	from, to := req.From.AsTime(), req.To.AsTime()
+
+	ctx := stream.Context()
+	interval, _ := marketdata.ParseInterval(cfg.Interval)
+	start := marketdata.BucketsBefore(from, interval, time.UTC, cfg.Warmup())
+	candles, err := marketdata.LoadCandles(ctx, s.candles, req.Exchange, req.Symbol, interval, time.UTC, start, to)
+	if err != nil {
+		return status.Error(codes.Unavailable, "Failed to load candles")
+	}
+
+	sender := &eventSender{stream: stream, percent: -1}
+	result, err := Simulate(cfg, candles, from, fills, req.Seed, sender)
+	if err != nil {
+		// Sends fail with the stream's status once the client is gone
+		return err
+	}
+	if err := sender.flushEquity(); err != nil {
+		return err
+	}
+
+	return stream.Send(&backtestpb.BacktestEvent{Event: &backtestpb.BacktestEvent_Result{Result: &backtestpb.BacktestResult{
+		Seed:       result.Seed,
+		RoundTrips: int32(result.RoundTrips),
+		Wins:       int32(result.Wins),
+		Pnl:        result.PnL.String(),
+	}}})
+}
+
+// eventSender streams a run as it is simulated. Equity points are batched
+// into chunks and progress is only sent when it reaches another percent.
+type eventSender struct {
+	stream  grpc.ServerStreamingServer[backtestpb.BacktestEvent]
+	equity  []*backtestpb.EquityPoint
+	percent int
+}
+
+func (s *eventSender) Fill(fill Fill) error {
+	return s.stream.Send(&backtestpb.BacktestEvent{Event: &backtestpb.BacktestEvent_Trade{Trade: &backtestpb.Trade{
+		Time:      timestamppb.New(fill.Time),
+		Side:      string(fill.Side),
+		Type:      string(fill.Type),
+		Price:     fill.Price.String(),
+		Quantity:  fill.Quantity.String(),
+		FillPrice: fill.FillPrice.String(),
+	}}})
+}
+
+func (s *eventSender) Equity(point EquityPoint) error {
+	s.equity = append(s.equity, &backtestpb.EquityPoint{
+		Time:   timestamppb.New(point.Time),
+		Equity: point.Equity.String(),
+	})
+	if len(s.equity) < equityChunkSize {
+		return nil
+	}
+	return s.flushEquity()
+}
+
+func (s *eventSender) flushEquity() error {
+	if len(s.equity) == 0 {
+		return nil
+	}
+	err := s.stream.Send(&backtestpb.BacktestEvent{Event: &backtestpb.BacktestEvent_Equity{Equity: &backtestpb.EquityChunk{Points: s.equity}}})
+	s.equity = nil
+	return err
+}
+
+func (s *eventSender) Progress(done, total int) error {
+	percent := done * 100 / total
+	if percent <= s.percent {
+		return nil
+	}
+	s.percent = percent
+	// The curve is flushed first so clients can draw up to the progress
+	if err := s.flushEquity(); err != nil {
+		return err
+	}
+	return s.stream.Send(&backtestpb.BacktestEvent{Event: &backtestpb.BacktestEvent_Progress{Progress: &backtestpb.Progress{Percent: int32(percent)}}})
+}
+
+// ConfigToProto converts a strategy config for RunBacktestRequest.
+func ConfigToProto(cfg strategy.Config) *backtestpb.StrategyConfig {
+	return &backtestpb.StrategyConfig{
+		Type:       cfg.Type,
+		Interval:   cfg.Interval,
+		Quantity:   cfg.Quantity.String(),
+		FastPeriod: int32(cfg.FastPeriod),
+		SlowPeriod: int32(cfg.SlowPeriod),
+		RsiPeriod:  int32(cfg.RSIPeriod),
+		Oversold:   cfg.Oversold,
+		Overbought: cfg.Overbought,
+	}
+}
+
+// ConfigFromProto is the inverse of ConfigToProto. The config is not
+// validated.
+func ConfigFromProto(cfg *backtestpb.StrategyConfig) (strategy.Config, error) {
+	if cfg == nil {
+		return strategy.Config{}, fmt.Errorf("%w: missing config", strategy.ErrInvalidConfig)
+	}
+	quantity, err := decimal.NewFromString(cfg.Quantity)
+	if err != nil {
+		return strategy.Config{}, fmt.Errorf("%w: invalid quantity", strategy.ErrInvalidConfig)
+	}
+	return strategy.Config{
+		Type:       cfg.Type,
+		Interval:   cfg.Interval,
+		Quantity:   quantity,
+		FastPeriod: int(cfg.FastPeriod),
+		SlowPeriod: int(cfg.SlowPeriod),
+		RSIPeriod:  int(cfg.RsiPeriod),
+		Oversold:   cfg.Oversold,
+		Overbought: cfg.Overbought,
+	}, nil
+}
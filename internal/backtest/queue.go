@@ -0,0 +1,704 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// DefaultTier schedules the jobs of plans the queue has no tier for.
+const DefaultTier = "free"
+
+var (
+	ErrJobNotFound = errors.New("backtest job not found")
+	ErrJobFinished = errors.New("backtest job already finished")
+	ErrQueueFull   = errors.New("too many queued backtest jobs")
+)
+
+// Tier is how the sweep jobs of a plan are scheduled.
+type Tier struct {
+	// Weight is the plan's share of the slots against the other plans
+	// with waiting jobs
+	Weight int
+	// MaxConcurrent caps the running jobs of each user; zero leaves them
+	// to the slots
+	MaxConcurrent int
+	// MaxQueued caps the waiting jobs of each user; zero does not
+	MaxQueued int
+	// PreemptAfter sends a job that ran this long back to the queue when
+	// jobs of other users wait for its slot; zero never preempts
+	PreemptAfter time.Duration
+}
+
+// SweepJob is a sweep to schedule: the planned runs over the market's
+// candles from CandlesFrom, early enough to warm up every run's
+// indicators by From.
+type SweepJob struct {
+	UserID      string
+	BotID       string
+	Plan        string
+	Seed        int64
+	Exchange    string
+	Symbol      string
+	Interval    string
+	CandlesFrom time.Time
+	From        time.Time
+	To          time.Time
+	Runs        []PlannedRun
+	Fills       FillModel
+}
+
+// Job is a scheduled sweep. Jobs are kept in Postgres, so every gateway
+// replica answers for them and they outlive the replica running them.
+type Job struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"-" gorm:"type:varchar(36);not null;index"`
+	BotID  string `json:"bot_id" gorm:"type:varchar(36);not null"`
+	Plan   string `json:"-" gorm:"not null;default:''"`
+	Status string `json:"status" gorm:"type:varchar(16);not null;index"`
+	// QueuePosition is the job's place among the waiting jobs of all users
+	// in dispatch order, from 1; zero once it left the queue. Per-user caps
+	// may let later jobs start first.
+	QueuePosition int `json:"queue_position,omitempty" gorm:"-"`
+	Runs          int `json:"runs" gorm:"not null"`
+	CompletedRuns int `json:"completed_runs" gorm:"not null;default:0"`
+	// Preemptions counts how often the job was sent back to the queue;
+	// runs it completed are kept
+	Preemptions int        `json:"preemptions,omitempty" gorm:"not null;default:0"`
+	Seed        int64      `json:"seed"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Error       string     `json:"error,omitempty"`
+	Results     []Result   `json:"results,omitempty" gorm:"type:jsonb;serializer:json"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" gorm:"index"`
+
+	Exchange    string       `json:"-" gorm:"not null"`
+	Symbol      string       `json:"-" gorm:"not null"`
+	Interval    string       `json:"-" gorm:"not null"`
+	CandlesFrom time.Time    `json:"-"`
+	Planned     []PlannedRun `json:"-" gorm:"type:jsonb;serializer:json"`
+	Fills       FillModel    `json:"-" gorm:"type:jsonb;serializer:json"`
+	// Finish is the job's virtual finish time; waiting jobs are dispatched
+	// in its order
+	Finish float64 `json:"-" gorm:"not null;default:0"`
+	// Canceled asks the replica running the job to stop it
+	Canceled bool `json:"-" gorm:"not null;default:false"`
+	// Worker is the replica running the job; it holds the job until
+	// LeaseUntil and renews the lease while it runs
+	Worker       string     `json:"-" gorm:"type:varchar(128);not null;default:''"`
+	LeaseUntil   *time.Time `json:"-"`
+	DispatchedAt *time.Time `json:"-"`
+}
+
+// TableName sets the table name for GORM
+func (Job) TableName() string {
+	return "backtest_jobs"
+}
+
+// QueueClock is the queue's virtual time. Its single row is locked by
+// every change to the queue, which serializes scheduling across replicas.
+type QueueClock struct {
+	ID          int `gorm:"primaryKey;autoIncrement:false"`
+	VirtualTime float64
+}
+
+// TableName sets the table name for GORM
+func (QueueClock) TableName() string {
+	return "backtest_queue_clock"
+}
+
+// QueueUser is the virtual finish time of a user's latest job; their next
+// job starts no earlier.
+type QueueUser struct {
+	UserID     string `gorm:"primaryKey;type:varchar(36)"`
+	LastFinish float64
+}
+
+// TableName sets the table name for GORM
+func (QueueUser) TableName() string {
+	return "backtest_queue_users"
+}
+
+// Queue schedules sweep jobs with weighted fair queuing: each job gets a
+// virtual finish time of its start plus its runs divided by its plan's
+// weight, where it starts when its user's previous job finishes or at the
+// queue's virtual time, whichever is later. Free slots go to the waiting
+// job with the earliest finish whose user is under their cap, so heavy
+// users and lighter plans wait longer without starving.
+//
+// The gateway submits, lists and cancels jobs; the backtest service Runs
+// them. Tags, caps and fairness span every replica.
+type Queue struct {
+	db        *gorm.DB
+	tiers     map[string]Tier
+	slots     int
+	workers   int
+	retention time.Duration
+	lease     time.Duration
+	poll      time.Duration
+}
+
+// NewQueue runs up to cfg.Slots jobs at once in each replica that Runs it,
+// each on cfg.Workers goroutines.
+func NewQueue(db *gorm.DB, cfg config.BacktestConfig) *Queue {
+	tiers := make(map[string]Tier, len(cfg.Tiers))
+	for plan, tier := range cfg.Tiers {
+		tiers[plan] = Tier{
+			Weight:        tier.Weight,
+			MaxConcurrent: tier.MaxConcurrent,
+			MaxQueued:     tier.MaxQueued,
+			PreemptAfter:  tier.PreemptAfter,
+		}
+	}
+	slots, workers := cfg.Slots, cfg.Workers
+	if slots <= 0 {
+		slots = 1
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	lease, poll := cfg.JobLease, cfg.PollInterval
+	if lease <= 0 {
+		lease = 30 * time.Second
+	}
+	if poll <= 0 {
+		poll = time.Second
+	}
+	return &Queue{
+		db:        db,
+		tiers:     tiers,
+		slots:     slots,
+		workers:   workers,
+		retention: cfg.JobRetention,
+		lease:     lease,
+		poll:      poll,
+	}
+}
+
+func (q *Queue) tier(plan string) Tier {
+	tier, ok := q.tiers[plan]
+	if !ok {
+		tier = q.tiers[DefaultTier]
+	}
+	if tier.Weight <= 0 {
+		tier.Weight = 1
+	}
+	return tier
+}
+
+// Submit queues a sweep; a replica running the queue picks it up once it
+// is its turn.
+func (q *Queue) Submit(ctx context.Context, sweep SweepJob) (*Job, error) {
+	j := &Job{
+		ID:          uuid.New().String(),
+		UserID:      sweep.UserID,
+		BotID:       sweep.BotID,
+		Plan:        sweep.Plan,
+		Runs:        len(sweep.Runs),
+		Seed:        sweep.Seed,
+		From:        sweep.From,
+		To:          sweep.To,
+		Exchange:    sweep.Exchange,
+		Symbol:      sweep.Symbol,
+		Interval:    sweep.Interval,
+		CandlesFrom: sweep.CandlesFrom,
+		Planned:     sweep.Runs,
+		Fills:       sweep.Fills,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	var view *Job
+	err := q.schedule(ctx, func(tx *gorm.DB, clock *QueueClock) error {
+		if tier := q.tier(sweep.Plan); tier.MaxQueued > 0 {
+			var waiting int64
+			err := tx.Model(&Job{}).Where("user_id = ? AND status = ?", sweep.UserID, JobQueued).Count(&waiting).Error
+			if err != nil {
+				return err
+			}
+			if waiting >= int64(tier.MaxQueued) {
+				return ErrQueueFull
+			}
+		}
+		if err := q.enqueue(tx, clock, j); err != nil {
+			return err
+		}
+		if err := tx.Create(j).Error; err != nil {
+			return err
+		}
+		var err error
+		view, err = q.view(tx, j)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Get returns one of the user's jobs.
+func (q *Queue) Get(ctx context.Context, userID, id string) (*Job, error) {
+	db := q.db.WithContext(ctx)
+	var j Job
+	err := db.Omit("planned").Where("id = ? AND user_id = ?", id, userID).First(&j).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return q.view(db, &j)
+}
+
+// List returns the user's jobs, newest first, without their results.
+func (q *Queue) List(ctx context.Context, userID string) ([]Job, error) {
+	db := q.db.WithContext(ctx)
+	jobs := []Job{}
+	err := db.Omit("planned", "results").Where("user_id = ?", userID).Order("created_at DESC").Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		view, err := q.view(db, &jobs[i])
+		if err != nil {
+			return nil, err
+		}
+		jobs[i] = *view
+	}
+	return jobs, nil
+}
+
+// Cancel removes a waiting job from the queue or has the replica running
+// it stop it.
+func (q *Queue) Cancel(ctx context.Context, userID, id string) (*Job, error) {
+	var view *Job
+	err := q.schedule(ctx, func(tx *gorm.DB, clock *QueueClock) error {
+		var j Job
+		err := tx.Omit("planned").Where("id = ? AND user_id = ?", id, userID).First(&j).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+		switch j.Status {
+		case JobQueued:
+			if err := q.finish(tx, &j, JobCanceled, ""); err != nil {
+				return err
+			}
+		case JobRunning:
+			j.Canceled = true
+			if err := tx.Model(&j).Update("canceled", true).Error; err != nil {
+				return err
+			}
+		default:
+			return ErrJobFinished
+		}
+		view, err = q.view(tx, &j)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Run claims waiting jobs into this replica's slots and executes them
+// until ctx ends. Jobs it still runs then go back to the queue with the
+// runs they completed, as do those of replicas whose lease ran out.
+func (q *Queue) Run(ctx context.Context, worker string, candles marketdata.CandleStore) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	slots := make(chan struct{}, q.slots)
+	freed := make(chan struct{}, q.slots)
+	poll := time.NewTicker(q.poll)
+	defer poll.Stop()
+
+	for {
+		if err := q.maintain(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to maintain backtest queue: %v", err)
+		}
+		for len(slots) < cap(slots) && ctx.Err() == nil {
+			j, err := q.claim(ctx, worker)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to claim backtest job: %v", err)
+				}
+				break
+			}
+			if j == nil {
+				break
+			}
+			slots <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				q.execute(ctx, worker, j, candles)
+				<-slots
+				freed <- struct{}{}
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-freed:
+		}
+	}
+}
+
+// schedule runs fn in a transaction holding the queue clock.
+func (q *Queue) schedule(ctx context.Context, fn func(tx *gorm.DB, clock *QueueClock) error) error {
+	return q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&QueueClock{ID: 1}).Error; err != nil {
+			return err
+		}
+		var clock QueueClock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", 1).First(&clock).Error; err != nil {
+			return err
+		}
+		vtime := clock.VirtualTime
+		if err := fn(tx, &clock); err != nil {
+			return err
+		}
+		if clock.VirtualTime == vtime {
+			return nil
+		}
+		return tx.Model(&clock).Update("virtual_time", clock.VirtualTime).Error
+	})
+}
+
+// enqueue tags the job with its virtual finish time for the runs it has
+// left and marks it waiting.
+func (q *Queue) enqueue(tx *gorm.DB, clock *QueueClock, j *Job) error {
+	var user QueueUser
+	if err := tx.Where("user_id = ?", j.UserID).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	start := clock.VirtualTime
+	if user.LastFinish > start {
+		start = user.LastFinish
+	}
+	j.Finish = start + float64(j.Runs-j.CompletedRuns)/float64(q.tier(j.Plan).Weight)
+	j.Status = JobQueued
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&QueueUser{UserID: j.UserID, LastFinish: j.Finish}).Error
+}
+
+// claim dispatches the earliest waiting job whose user is under their cap
+// to worker, or returns nil when none may start.
+func (q *Queue) claim(ctx context.Context, worker string) (*Job, error) {
+	var claimed *Job
+	err := q.schedule(ctx, func(tx *gorm.DB, clock *QueueClock) error {
+		j, err := q.next(tx, "")
+		if err != nil || j == nil {
+			return err
+		}
+		// The virtual time advances to the start of the job in service
+		start := j.Finish - float64(j.Runs-j.CompletedRuns)/float64(q.tier(j.Plan).Weight)
+		if start > clock.VirtualTime {
+			clock.VirtualTime = start
+		}
+
+		now := time.Now().UTC()
+		lease := now.Add(q.lease)
+		updates := map[string]interface{}{
+			"status":        JobRunning,
+			"worker":        worker,
+			"lease_until":   lease,
+			"dispatched_at": now,
+		}
+		if j.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		if err := tx.Model(j).Updates(updates).Error; err != nil {
+			return err
+		}
+		claimed = j
+		return nil
+	})
+	return claimed, err
+}
+
+// next returns the earliest waiting job that may start, skipping the jobs
+// of except.
+func (q *Queue) next(tx *gorm.DB, except string) (*Job, error) {
+	var waiting []Job
+	err := tx.Select("id", "user_id", "plan", "runs", "completed_runs", "finish", "started_at").
+		Where("status = ? AND user_id <> ?", JobQueued, except).
+		Order("finish").Order("created_at").
+		Find(&waiting).Error
+	if err != nil || len(waiting) == 0 {
+		return nil, err
+	}
+
+	var counts []struct {
+		UserID string
+		Count  int
+	}
+	err = tx.Model(&Job{}).Select("user_id, COUNT(*) AS count").
+		Where("status = ?", JobRunning).Group("user_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[string]int, len(counts))
+	for _, c := range counts {
+		running[c.UserID] = c.Count
+	}
+
+	for i := range waiting {
+		j := &waiting[i]
+		if tier := q.tier(j.Plan); tier.MaxConcurrent > 0 && running[j.UserID] >= tier.MaxConcurrent {
+			continue
+		}
+		return j, nil
+	}
+	return nil, nil
+}
+
+// execute runs the job's remaining runs in batches of one per worker,
+// saving the results of each. It checks for cancellation and preemption
+// between batches, so a preempted job loses no completed runs.
+func (q *Queue) execute(ctx context.Context, worker string, claimed *Job, store marketdata.CandleStore) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go q.renew(runCtx, cancel, worker, claimed.ID)
+
+	j := Job{ID: claimed.ID}
+	if err := q.db.WithContext(runCtx).First(&j).Error; err != nil {
+		q.stop(ctx, worker, &j, err)
+		return
+	}
+	if len(j.Results) != j.Runs {
+		j.Results = make([]Result, j.Runs)
+	}
+
+	interval, err := marketdata.ParseInterval(j.Interval)
+	var candles []marketdata.Candle
+	if err == nil {
+		candles, err = marketdata.LoadCandles(runCtx, store, j.Exchange, j.Symbol, interval, time.UTC, j.CandlesFrom, j.To)
+	}
+	if err != nil {
+		q.stop(ctx, worker, &j, err)
+		return
+	}
+
+	for j.CompletedRuns < j.Runs {
+		end := j.CompletedRuns + q.workers
+		if end > j.Runs {
+			end = j.Runs
+		}
+		results, err := Execute(runCtx, j.Planned[j.CompletedRuns:end], candles, j.From, j.Fills, q.workers)
+		if err != nil {
+			q.stop(ctx, worker, &j, err)
+			return
+		}
+		copy(j.Results[j.CompletedRuns:end], results)
+		j.CompletedRuns = end
+
+		// Completed runs are saved even while shutting down
+		saveCtx, cancelSave := context.WithTimeout(context.Background(), 5*time.Second)
+		done := false
+		err = q.schedule(saveCtx, func(tx *gorm.DB, clock *QueueClock) error {
+			held, err := q.holds(tx, worker, &j)
+			if err != nil || !held {
+				done = true
+				return err
+			}
+			if j.Canceled {
+				done = true
+				return q.finish(tx, &j, JobCanceled, "")
+			}
+			if j.CompletedRuns == j.Runs {
+				done = true
+				return q.finish(tx, &j, JobSucceeded, "")
+			}
+			preempt, err := q.preempt(tx, &j)
+			if err != nil {
+				return err
+			}
+			if !preempt {
+				return tx.Model(&j).Select("results", "completed_runs").Updates(&j).Error
+			}
+			done = true
+			j.Preemptions++
+			j.Worker, j.LeaseUntil = "", nil
+			if err := q.enqueue(tx, clock, &j); err != nil {
+				return err
+			}
+			return tx.Model(&j).Select("results", "completed_runs", "status", "finish", "preemptions", "worker", "lease_until").
+				Updates(&j).Error
+		})
+		cancelSave()
+		if err != nil {
+			log.Printf("Failed to save backtest job %s: %v", j.ID, err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// stop ends a job that could not go on. A replica shutting down hands it
+// back to the queue; a job canceled meanwhile ends canceled, and any other
+// failure fails it.
+func (q *Queue) stop(ctx context.Context, worker string, j *Job, cause error) {
+	// Ours may be cancelled already; the queue must still learn of it
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := q.schedule(saveCtx, func(tx *gorm.DB, clock *QueueClock) error {
+		held, err := q.holds(tx, worker, j)
+		if err != nil || !held {
+			return err
+		}
+		switch {
+		case j.Canceled:
+			return q.finish(tx, j, JobCanceled, "")
+		case ctx.Err() != nil:
+			return tx.Model(j).Updates(map[string]interface{}{"status": JobQueued, "worker": "", "lease_until": nil}).Error
+		}
+		return q.finish(tx, j, JobFailed, cause.Error())
+	})
+	if err != nil {
+		log.Printf("Failed to stop backtest job %s: %v", j.ID, err)
+	}
+}
+
+// holds reloads the job's status and tells whether worker still runs it;
+// the job went back to the queue when its lease ran out.
+func (q *Queue) holds(tx *gorm.DB, worker string, j *Job) (bool, error) {
+	var current Job
+	err := tx.Select("status", "worker", "canceled", "preemptions", "dispatched_at").Where("id = ?", j.ID).First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	j.Canceled, j.Preemptions, j.DispatchedAt = current.Canceled, current.Preemptions, current.DispatchedAt
+	return current.Status == JobRunning && current.Worker == worker, nil
+}
+
+// renew extends worker's lease on the job while it runs, and cancels the
+// run once the job was canceled or the lease lost.
+func (q *Queue) renew(ctx context.Context, cancel context.CancelFunc, worker, id string) {
+	ticker := time.NewTicker(q.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result := q.db.WithContext(ctx).Model(&Job{}).
+				Where("id = ? AND worker = ? AND status = ? AND NOT canceled", id, worker, JobRunning).
+				Update("lease_until", time.Now().UTC().Add(q.lease))
+			if result.Error == nil && result.RowsAffected == 0 {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// preempt tells whether the job ran past its tier's limit while a job of
+// another user waits for a slot.
+func (q *Queue) preempt(tx *gorm.DB, j *Job) (bool, error) {
+	tier := q.tier(j.Plan)
+	if tier.PreemptAfter <= 0 || j.DispatchedAt == nil || time.Since(*j.DispatchedAt) < tier.PreemptAfter {
+		return false, nil
+	}
+	waiting, err := q.next(tx, j.UserID)
+	return waiting != nil, err
+}
+
+func (q *Queue) finish(tx *gorm.DB, j *Job, status, errMsg string) error {
+	now := time.Now().UTC()
+	j.Status, j.Error, j.FinishedAt = status, errMsg, &now
+	j.Worker, j.LeaseUntil = "", nil
+	// Only the results are needed from now on
+	j.Planned = nil
+	if status != JobSucceeded {
+		j.Results = nil
+	}
+	return tx.Model(j).Select("status", "error", "finished_at", "planned", "results", "completed_runs", "worker", "lease_until").
+		Updates(j).Error
+}
+
+// maintain hands the jobs of replicas whose lease ran out back to the
+// queue, forgets jobs finished longer than the retention ago and restarts
+// the virtual clock of an idle queue.
+func (q *Queue) maintain(ctx context.Context) error {
+	return q.schedule(ctx, func(tx *gorm.DB, clock *QueueClock) error {
+		now := time.Now().UTC()
+		err := tx.Model(&Job{}).
+			Where("status = ? AND lease_until < ? AND canceled", JobRunning, now).
+			Updates(map[string]interface{}{"status": JobCanceled, "finished_at": now, "planned": nil, "results": nil, "worker": "", "lease_until": nil}).Error
+		if err != nil {
+			return err
+		}
+		// They keep their tags, and with them their place in the queue
+		err = tx.Model(&Job{}).
+			Where("status = ? AND lease_until < ?", JobRunning, now).
+			Updates(map[string]interface{}{"status": JobQueued, "worker": "", "lease_until": nil}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("finished_at < ?", now.Add(-q.retention)).Delete(&Job{}).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		if err := tx.Model(&Job{}).Where("status IN ?", []string{JobQueued, JobRunning}).Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return nil
+		}
+		// Idle; restart the virtual clock so tags stay small
+		clock.VirtualTime = 0
+		return tx.Where("1 = 1").Delete(&QueueUser{}).Error
+	})
+}
+
+// view copies the job with its current queue position and without the
+// results of a job that has not succeeded yet.
+func (q *Queue) view(tx *gorm.DB, j *Job) (*Job, error) {
+	view := *j
+	view.Planned = nil
+	if view.Status != JobSucceeded {
+		view.Results = nil
+	}
+	if view.Status == JobQueued {
+		var ahead int64
+		err := tx.Model(&Job{}).
+			Where("status = ? AND (finish < ? OR finish = ? AND created_at < ?)", JobQueued, j.Finish, j.Finish, j.CreatedAt).
+			Count(&ahead).Error
+		if err != nil {
+			return nil, err
+		}
+		view.QueuePosition = int(ahead) + 1
+	}
+	return &view, nil
+}
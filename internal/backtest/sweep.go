@@ -0,0 +1,169 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+var (
+	ErrEmptyGrid = errors.New("parameter grid has no valid combination")
+	// ErrGridTooLarge is returned before anything is expanded, so an
+	// oversized grid costs nothing
+	ErrGridTooLarge = errors.New("parameter grid has too many combinations")
+)
+
+// Grid lists the values to try for each strategy parameter. Empty lists
+// keep the base config's value.
+type Grid struct {
+	FastPeriod []int     `json:"fast_period,omitempty"`
+	SlowPeriod []int     `json:"slow_period,omitempty"`
+	RSIPeriod  []int     `json:"rsi_period,omitempty"`
+	Oversold   []float64 `json:"oversold,omitempty"`
+	Overbought []float64 `json:"overbought,omitempty"`
+}
+
+// PlannedRun is one combination of a sweep with the seed it runs with.
+// Index orders the results, so runs can execute anywhere and in any order
+// and still merge into the serial sweep's output.
+type PlannedRun struct {
+	Index  int             `json:"index"`
+	Config strategy.Config `json:"config"`
+	Seed   int64           `json:"seed"`
+}
+
+// Size is the number of combinations the grid expands to, counting
+// invalid ones. It saturates rather than overflows.
+func (g Grid) Size() int {
+	size := 1
+	for _, n := range []int{len(g.FastPeriod), len(g.SlowPeriod), len(g.RSIPeriod), len(g.Oversold), len(g.Overbought)} {
+		if n == 0 {
+			continue
+		}
+		if size > math.MaxInt/n {
+			return math.MaxInt
+		}
+		size *= n
+	}
+	return size
+}
+
+// Plan expands the grid over base in a fixed order, dropping invalid
+// combinations, and derives every run's seed from the sweep seed and its
+// index only. Grids of more than maxRuns combinations are refused with
+// ErrGridTooLarge before they are expanded.
+func Plan(base strategy.Config, grid Grid, seed int64, maxRuns int) ([]PlannedRun, error) {
+	if grid.Size() > maxRuns {
+		return nil, ErrGridTooLarge
+	}
+
+	ints := func(values []int, fallback int) []int {
+		if len(values) == 0 {
+			return []int{fallback}
+		}
+		return values
+	}
+	floats := func(values []float64, fallback float64) []float64 {
+		if len(values) == 0 {
+			return []float64{fallback}
+		}
+		return values
+	}
+
+	var runs []PlannedRun
+	for _, fast := range ints(grid.FastPeriod, base.FastPeriod) {
+		for _, slow := range ints(grid.SlowPeriod, base.SlowPeriod) {
+			for _, rsi := range ints(grid.RSIPeriod, base.RSIPeriod) {
+				for _, oversold := range floats(grid.Oversold, base.Oversold) {
+					for _, overbought := range floats(grid.Overbought, base.Overbought) {
+						cfg := base
+						cfg.FastPeriod, cfg.SlowPeriod = fast, slow
+						cfg.RSIPeriod, cfg.Oversold, cfg.Overbought = rsi, oversold, overbought
+						if cfg.Validate() != nil {
+							continue
+						}
+						runs = append(runs, PlannedRun{Index: len(runs), Config: cfg})
+					}
+				}
+			}
+		}
+	}
+	if len(runs) == 0 {
+		return nil, ErrEmptyGrid
+	}
+
+	for i := range runs {
+		runs[i].Seed = runSeed(seed, runs[i].Index)
+	}
+	return runs, nil
+}
+
+// runSeed mixes the sweep seed with the run index (splitmix64), so seeds
+// do not depend on which worker or machine picks the run up.
+func runSeed(seed int64, index int) int64 {
+	z := uint64(seed) + uint64(index+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// Execute runs the planned runs over candles on up to workers goroutines
+// (all CPUs when workers is zero). Results come back in the order of runs,
+// identical to running them one by one. Candles are shared read-only.
+func Execute(ctx context.Context, runs []PlannedRun, candles []marketdata.Candle, from time.Time, fills FillModel, workers int) ([]Result, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(runs) {
+		workers = len(runs)
+	}
+
+	results := make([]Result, len(runs))
+	errs := make([]error, len(runs))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result, err := Run(runs[i].Config, candles, from, fills, runs[i].Seed)
+				if err != nil {
+					errs[i] = fmt.Errorf("run %d: %w", runs[i].Index, err)
+					continue
+				}
+				results[i] = *result
+			}
+		}()
+	}
+
+feed:
+	for i := range runs {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Report the first failure in run order, as a serial sweep would
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
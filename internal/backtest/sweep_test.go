@@ -0,0 +1,58 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+func sequence(n int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i + 1
+	}
+	return values
+}
+
+func TestGrid_Size(t *testing.T) {
+	assert.Equal(t, 1, Grid{}.Size())
+	assert.Equal(t, 6, Grid{FastPeriod: sequence(2), SlowPeriod: sequence(3)}.Size())
+
+	huge := sequence(1 << 16)
+	assert.Equal(t, math.MaxInt, Grid{FastPeriod: huge, SlowPeriod: huge, RSIPeriod: huge, Oversold: make([]float64, 1<<16)}.Size())
+}
+
+func TestPlan(t *testing.T) {
+	base := strategy.Config{Type: strategy.TypeSMACross, Interval: "1h", Quantity: decimal.NewFromInt(1), FastPeriod: 5, SlowPeriod: 20}
+
+	runs, err := Plan(base, Grid{FastPeriod: []int{5, 10, 30}, SlowPeriod: []int{20, 40}}, 42, 6)
+	require.NoError(t, err)
+	// fast 30 over slow 20 is invalid
+	assert.Len(t, runs, 5)
+	for i, run := range runs {
+		assert.Equal(t, i, run.Index)
+		assert.Equal(t, runSeed(42, i), run.Seed)
+	}
+
+	_, err = Plan(base, Grid{FastPeriod: []int{30}, SlowPeriod: []int{20}}, 42, 6)
+	assert.ErrorIs(t, err, ErrEmptyGrid)
+
+	// Refused before expansion, which would never finish
+	huge := sequence(1000)
+	_, err = Plan(base, Grid{FastPeriod: huge, SlowPeriod: huge, RSIPeriod: huge, Oversold: make([]float64, 1000)}, 42, 500)
+	assert.ErrorIs(t, err, ErrGridTooLarge)
+
+	_, err = Plan(base, Grid{FastPeriod: []int{5, 10, 30}, SlowPeriod: []int{20, 40}}, 42, 5)
+	assert.ErrorIs(t, err, ErrGridTooLarge)
+}
+
+func TestFillModel_Validate(t *testing.T) {
+	assert.NoError(t, FillModel{}.Validate())
+	assert.NoError(t, FillModel{SlippageBps: 5}.Validate())
+	assert.ErrorIs(t, FillModel{SlippageBps: -1}.Validate(), ErrInvalidFillModel)
+	assert.ErrorIs(t, FillModel{SlippageBps: math.NaN()}.Validate(), ErrInvalidFillModel)
+}
@@ -0,0 +1,249 @@
+// Package batch buffers high-volume writes such as fills and candles and
+// flushes them to their store in batches.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+var ErrClosed = errors.New("batch writer is closed")
+
+// maxRetryBackoff caps the wait between attempts to write a failed batch.
+const maxRetryBackoff = 30 * time.Second
+
+var (
+	flushes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "batch_writer_flushes_total",
+		Help: "Batch write attempts by writer and status.",
+	}, []string{"writer", "status"})
+	flushSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "batch_writer_flush_size",
+		Help:    "Items per written batch.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"writer"})
+	buffered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "batch_writer_buffered",
+		Help: "Items accepted but not yet written.",
+	}, []string{"writer"})
+	blocked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "batch_writer_blocked_total",
+		Help: "Adds that waited for buffer space because writes fell behind.",
+	}, []string{"writer"})
+	dropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "batch_writer_dropped_total",
+		Help: "Items still unwritten when the writer's shutdown deadline passed.",
+	}, []string{"writer"})
+)
+
+// FlushFunc writes one batch. It must not keep the slice, and it should be
+// idempotent, since a batch that failed part way is written again.
+type FlushFunc[T any] func(ctx context.Context, items []T) error
+
+// Writer collects items and flushes them once MaxSize items are waiting or
+// FlushInterval has passed. Failed batches are retried until they are
+// written; meanwhile the buffer fills up and Add blocks, which pushes back
+// on producers instead of losing data.
+type Writer[T any] struct {
+	name  string
+	cfg   config.WriteBatchConfig
+	flush FlushFunc[T]
+
+	items chan T
+	// ctx is cancelled when Close gives up on the remaining items
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	lost   atomic.Int64
+
+	mutex    sync.Mutex
+	closed   bool
+	closing  chan struct{}
+	inflight sync.WaitGroup
+}
+
+func NewWriter[T any](name string, cfg config.WriteBatchConfig, flush FlushFunc[T]) *Writer[T] {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1
+	}
+	if cfg.BufferSize < cfg.MaxSize {
+		cfg.BufferSize = cfg.MaxSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer[T]{
+		name:    name,
+		cfg:     cfg,
+		flush:   flush,
+		items:   make(chan T, cfg.BufferSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Add queues items for writing. It blocks while the buffer is full and
+// returns the context's error if that lasts until ctx is done; items queued
+// before then are still written.
+func (w *Writer[T]) Add(ctx context.Context, items ...T) error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return ErrClosed
+	}
+	w.inflight.Add(1)
+	w.mutex.Unlock()
+	defer w.inflight.Done()
+
+	for _, item := range items {
+		select {
+		case w.items <- item:
+			continue
+		default:
+		}
+
+		blocked.WithLabelValues(w.name).Inc()
+		select {
+		case w.items <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.closing:
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// Offer queues item only if there is room right away and reports whether
+// it did, for callers on a latency-sensitive path that must not wait.
+func (w *Writer[T]) Offer(item T) bool {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return false
+	}
+	w.inflight.Add(1)
+	w.mutex.Unlock()
+	defer w.inflight.Done()
+
+	select {
+	case w.items <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting items and writes everything already queued. If
+// that does not finish before ctx is done, the remaining items are dropped
+// and reported in the returned error.
+func (w *Writer[T]) Close(ctx context.Context) error {
+	w.mutex.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+		w.mutex.Unlock()
+
+		// Pending adds return as soon as they see closing, after which
+		// nothing sends on items any more
+		w.inflight.Wait()
+		close(w.items)
+	} else {
+		w.mutex.Unlock()
+	}
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+	}
+	w.cancel()
+
+	if lost := w.lost.Load(); lost > 0 {
+		return fmt.Errorf("%s writer dropped %d items on shutdown", w.name, lost)
+	}
+	return nil
+}
+
+func (w *Writer[T]) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, w.cfg.MaxSize)
+	for {
+		select {
+		case item, ok := <-w.items:
+			if !ok {
+				w.write(batch)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= w.cfg.MaxSize {
+				w.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// write flushes the batch, retrying with backoff until it succeeds or the
+// writer is cancelled on shutdown.
+func (w *Writer[T]) write(batch []T) {
+	buffered.WithLabelValues(w.name).Set(float64(len(batch) + len(w.items)))
+	if len(batch) == 0 {
+		return
+	}
+
+	backoff := w.cfg.RetryBackoff
+	for {
+		err := w.flush(w.ctx, batch)
+		if err == nil {
+			flushes.WithLabelValues(w.name, "ok").Inc()
+			flushSize.WithLabelValues(w.name).Observe(float64(len(batch)))
+			return
+		}
+		flushes.WithLabelValues(w.name, "error").Inc()
+		log.Printf("Failed to write batch of %d %s, retrying in %s: %v", len(batch), w.name, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			// Shutdown gave up; count this batch and whatever is still queued
+			lost := len(batch)
+			for range w.items {
+				lost++
+			}
+			w.lost.Add(int64(lost))
+			dropped.WithLabelValues(w.name).Add(float64(lost))
+			log.Printf("Dropped %d %s that were not written before shutdown", lost, w.name)
+			return
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/email"
+	"github.com/tradingbothub/platform/pkg/money"
+	"gorm.io/gorm"
+)
+
+// Dunner chases past due invoices. Open invoices go past due on their due
+// date, or on a failed payment before it. It emails reminders at the
+// configured offsets from then, then moves the user to the free plan. Nothing is suspended or deleted along the way: the user only loses
+// what the free plan lacks, and paying gives the plan back.
+type Dunner struct {
+	service *Service
+	mailer  email.Sender
+	cfg     config.DunningConfig
+}
+
+func NewDunner(service *Service, mailer email.Sender, cfg config.DunningConfig) *Dunner {
+	return &Dunner{service: service, mailer: mailer, cfg: cfg}
+}
+
+// dunningStep is what the dunner did to an invoice.
+type dunningStep int
+
+const (
+	stepNone dunningStep = iota
+	stepReminder
+	stepDowngrade
+)
+
+// Run matches scheduler.JobFunc.
+func (d *Dunner) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	overdue, err := d.service.invoices.MarkOverdue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to mark overdue invoices: %w", err)
+	}
+	ids, err := d.service.invoices.Dunning(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list past due invoices: %w", err)
+	}
+
+	reminded, downgraded := 0, 0
+	for _, id := range ids {
+		var user *auth.User
+		step := stepNone
+		invoice, err := d.service.invoices.Update(ctx, id, func(tx *gorm.DB, invoice *Invoice) error {
+			var err error
+			step, err = d.step(ctx, invoice, now)
+			if err != nil || step == stepNone {
+				return err
+			}
+			user, err = auth.NewRepository(tx).GetByID(ctx, invoice.UserID)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to dun invoice %s: %v", id, err)
+			continue
+		}
+
+		switch step {
+		case stepReminder:
+			reminded++
+			d.send(ctx, user, d.reminder(invoice))
+		case stepDowngrade:
+			downgraded++
+			d.send(ctx, user, d.downgradeNotice(invoice))
+		}
+	}
+
+	log.Printf("Dunned %d past due invoices (%d newly overdue): %d reminders, %d downgrades", len(ids), overdue, reminded, downgraded)
+	return nil
+}
+
+// step takes the invoice's next dunning step if it is due, and schedules
+// the one after.
+func (d *Dunner) step(ctx context.Context, invoice *Invoice, now time.Time) (dunningStep, error) {
+	// The invoice may have been paid since it was listed
+	if invoice.Status != StatusPastDue || invoice.PastDueSince == nil || invoice.NextDunningAt == nil {
+		return stepNone, nil
+	}
+
+	if invoice.RemindersSent < len(d.cfg.Reminders) {
+		due := invoice.PastDueSince.Add(d.cfg.Reminders[invoice.RemindersSent])
+		if now.Before(due) {
+			invoice.NextDunningAt = &due
+			return stepNone, nil
+		}
+		invoice.RemindersSent++
+		next := d.downgradeAt(invoice)
+		if invoice.RemindersSent < len(d.cfg.Reminders) {
+			next = invoice.PastDueSince.Add(d.cfg.Reminders[invoice.RemindersSent])
+		}
+		invoice.NextDunningAt = &next
+		return stepReminder, nil
+	}
+
+	if due := d.downgradeAt(invoice); now.Before(due) {
+		invoice.NextDunningAt = &due
+		return stepNone, nil
+	}
+	invoice.NextDunningAt = nil
+
+	// Made before the invoice commits; should that fail, the next run
+	// finds the user on the free plan already and records nothing
+	previous, changed, err := d.service.plans.ChangePlan(ctx, invoice.UserID, auth.PlanFree, "")
+	if err != nil || !changed {
+		return stepNone, err
+	}
+	invoice.DowngradedFrom = previous
+	return stepDowngrade, nil
+}
+
+func (d *Dunner) downgradeAt(invoice *Invoice) time.Time {
+	return invoice.PastDueSince.Add(d.cfg.DowngradeAfter)
+}
+
+// send emails the user, logging failures; the step is taken either way.
+func (d *Dunner) send(ctx context.Context, user *auth.User, msg email.Message) {
+	msg.To = user.Email
+	if err := d.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send dunning email to user %s: %v", user.ID, err)
+	}
+}
+
+func (d *Dunner) reminder(invoice *Invoice) email.Message {
+	return email.Message{
+		Subject: fmt.Sprintf("Payment failed for invoice %s", invoice.Number),
+		Body: fmt.Sprintf("We could not collect %s for invoice %s.\n\n"+
+			"Please update your payment method at %s\n\n"+
+			"If the invoice is still unpaid on %s, your account moves to the free plan. "+
+			"Your bots, strategies and history are kept, and paying restores your %s plan.\n",
+			money.Format(invoice.Total, invoice.Currency), invoice.Number, d.cfg.URL,
+			d.downgradeAt(invoice).Format("January 2, 2006"), invoice.Plan),
+	}
+}
+
+func (d *Dunner) downgradeNotice(invoice *Invoice) email.Message {
+	return email.Message{
+		Subject: "Your account moved to the free plan",
+		Body: fmt.Sprintf("Invoice %s of %s is still unpaid, so your account moved from the %s plan to the free plan. "+
+			"Your bots, strategies and history are kept; features beyond the free plan are limited until you pay.\n\n"+
+			"Paying the invoice at %s restores your %s plan.\n",
+			invoice.Number, money.Format(invoice.Total, invoice.Currency), invoice.DowngradedFrom, d.cfg.URL, invoice.DowngradedFrom),
+	}
+}
@@ -0,0 +1,303 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/metering"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+const (
+	// rollupDays are copied from metering on every run, so a few missed
+	// runs lose nothing
+	rollupDays = 3
+	// overageUnit is the number of requests OveragePrice is charged per
+	overageUnit = 1000
+)
+
+// plan is a BillingPlanConfig with its prices parsed.
+type plan struct {
+	price            decimal.Decimal
+	includedRequests int64
+	overagePrice     decimal.Decimal
+}
+
+// segment is a stretch of an invoiced period spent on one plan.
+type segment struct {
+	plan     string
+	from, to time.Time
+}
+
+// Invoicer copies metered API usage into daily totals and invoices every
+// user who was on a paid plan once a month, prorating plan changes.
+type Invoicer struct {
+	invoices     Repository
+	usage        *metering.Recorder
+	currency     string
+	paymentTerms time.Duration
+	plans        map[string]plan
+}
+
+// NewInvoicer fails on a plan price that is not a decimal.
+func NewInvoicer(invoices Repository, usage *metering.Recorder, cfg config.BillingConfig) (*Invoicer, error) {
+	plans := make(map[string]plan, len(cfg.Plans))
+	for name, p := range cfg.Plans {
+		price, err := decimal.NewFromString(p.Price)
+		if err != nil || price.IsNegative() {
+			return nil, fmt.Errorf("invalid price %q of plan %s", p.Price, name)
+		}
+		overage := decimal.Zero
+		if p.OveragePrice != "" {
+			if overage, err = decimal.NewFromString(p.OveragePrice); err != nil || overage.IsNegative() {
+				return nil, fmt.Errorf("invalid overage price %q of plan %s", p.OveragePrice, name)
+			}
+		}
+		plans[name] = plan{price: price, includedRequests: p.IncludedRequests, overagePrice: overage}
+	}
+	return &Invoicer{
+		invoices:     invoices,
+		usage:        usage,
+		currency:     cfg.Currency,
+		paymentTerms: cfg.PaymentTerms,
+		plans:        plans,
+	}, nil
+}
+
+// RollUp matches scheduler.JobFunc. It stores the requests of every
+// subscriber on each of the last complete days.
+func (i *Invoicer) RollUp(ctx context.Context) error {
+	today := startOfDay(time.Now().UTC())
+	subscribers, err := i.invoices.Subscribers(ctx, today.AddDate(0, 0, -rollupDays))
+	if err != nil {
+		return fmt.Errorf("failed to list subscribers: %w", err)
+	}
+
+	var usage []DailyUsage
+	for _, user := range subscribers {
+		for d := rollupDays; d >= 1; d-- {
+			day := today.AddDate(0, 0, -d)
+			// Usage includes the bucket starting at to
+			report, err := i.usage.Usage(ctx, user.ID, day, day.Add(24*time.Hour-metering.Bucket), 0)
+			if err != nil {
+				return fmt.Errorf("failed to read usage of user %s: %w", user.ID, err)
+			}
+			usage = append(usage, DailyUsage{UserID: user.ID, Day: day, Requests: report.Totals.Requests})
+		}
+	}
+	if err := i.invoices.RecordUsage(ctx, usage); err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	log.Printf("Rolled up %d days of API usage of %d subscribers", rollupDays, len(subscribers))
+	return nil
+}
+
+// Run matches scheduler.JobFunc. It invoices every subscriber for the
+// previous calendar month, charging each paid plan they were on for its
+// share of the month; months already invoiced are skipped, so a late or
+// repeated run is harmless.
+func (i *Invoicer) Run(ctx context.Context) error {
+	// The month's last day has to be rolled up first
+	if err := i.RollUp(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, -1, 0)
+	subscribers, err := i.invoices.Subscribers(ctx, start)
+	if err != nil {
+		return fmt.Errorf("failed to list subscribers: %w", err)
+	}
+
+	issued := 0
+	for _, user := range subscribers {
+		changes, err := i.invoices.PlanChanges(ctx, user.ID, start)
+		if err != nil {
+			log.Printf("Failed to list plan changes of user %s: %v", user.ID, err)
+			continue
+		}
+		var paid []segment
+		for _, s := range segments(user.Plan, changes, start, end) {
+			if _, ok := i.plans[s.plan]; ok {
+				paid = append(paid, s)
+			} else if s.plan != auth.PlanFree {
+				log.Printf("Not invoicing user %s for plan %s: it has no price", user.ID, s.plan)
+			}
+		}
+		if len(paid) == 0 {
+			continue
+		}
+		requests, err := i.requests(ctx, user.ID, paid)
+		if err != nil {
+			log.Printf("Failed to total requests of user %s: %v", user.ID, err)
+			continue
+		}
+
+		invoice := i.invoice(user.ID, paid, requests, start, end, now)
+		err = i.invoices.Create(ctx, invoice)
+		if errors.Is(err, ErrInvoiceExists) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to invoice user %s: %v", user.ID, err)
+			continue
+		}
+		issued++
+	}
+
+	log.Printf("Issued %d invoices for %s", issued, start.Format("January 2006"))
+	return nil
+}
+
+// segments splits [start, end) at the plan changes made since start. The
+// plan at start is the one the first change moved away from, or the
+// current plan when there was none.
+func segments(current string, changes []auth.PlanChange, start, end time.Time) []segment {
+	plan := current
+	if len(changes) > 0 {
+		plan = changes[0].From
+	}
+	var segs []segment
+	from := start
+	for _, change := range changes {
+		if !change.ChangedAt.Before(end) {
+			break
+		}
+		if change.ChangedAt.After(from) {
+			segs = append(segs, segment{plan: plan, from: from, to: change.ChangedAt})
+			from = change.ChangedAt
+		}
+		plan = change.To
+	}
+	return append(segs, segment{plan: plan, from: from, to: end})
+}
+
+// requests sums the user's requests on the days the paid segments touch,
+// counting each day once.
+func (i *Invoicer) requests(ctx context.Context, userID string, paid []segment) (int64, error) {
+	var total int64
+	var counted time.Time
+	for _, s := range paid {
+		from, to := startOfDay(s.from), startOfDay(s.to)
+		if to.Before(s.to) {
+			to = to.AddDate(0, 0, 1)
+		}
+		if from.Before(counted) {
+			from = counted
+		}
+		if !from.Before(to) {
+			continue
+		}
+		n, err := i.invoices.Requests(ctx, userID, from, to)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+		counted = to
+	}
+	return total, nil
+}
+
+// invoice charges each paid segment its share of the plan's price, and
+// every started overageUnit of requests above the included ones, prorated
+// the same way, at the last paid plan's overage price. Invoices of
+// nothing are issued paid.
+func (i *Invoicer) invoice(userID string, paid []segment, requests int64, start, end, now time.Time) *Invoice {
+	last := paid[len(paid)-1].plan
+	id := uuid.New().String()
+	invoice := &Invoice{
+		ID:          id,
+		UserID:      userID,
+		Number:      fmt.Sprintf("INV-%s-%s", start.Format("200601"), strings.ToUpper(id[:8])),
+		Plan:        last,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Currency:    i.currency,
+		Status:      StatusOpen,
+		IssuedAt:    now,
+		DueAt:       now.Add(i.paymentTerms),
+	}
+
+	period := decimal.NewFromInt(int64(end.Sub(start) / time.Second))
+	included := decimal.Zero
+	for _, s := range paid {
+		p := i.plans[s.plan]
+		if s.from.Equal(start) && s.to.Equal(end) {
+			invoice.LineItems = append(invoice.LineItems, LineItem{
+				Description: fmt.Sprintf("%s plan, %s", titleCase(s.plan), start.Format("January 2006")),
+				Quantity:    1,
+				UnitPrice:   p.price,
+				Amount:      money.Round(p.price, i.currency),
+			})
+			included = decimal.NewFromInt(p.includedRequests)
+			continue
+		}
+
+		share := decimal.NewFromInt(int64(s.to.Sub(s.from) / time.Second)).Div(period)
+		amount := money.Round(p.price.Mul(share), i.currency)
+		invoice.LineItems = append(invoice.LineItems, LineItem{
+			Description: fmt.Sprintf("%s plan, %s to %s, prorated", titleCase(s.plan),
+				s.from.Format("January 2"), s.to.Add(-time.Nanosecond).Format("January 2, 2006")),
+			Quantity:  1,
+			UnitPrice: amount,
+			Amount:    amount,
+		})
+		included = included.Add(decimal.NewFromInt(p.includedRequests).Mul(share))
+	}
+
+	p := i.plans[last]
+	if over := requests - included.IntPart(); over > 0 && p.overagePrice.IsPositive() {
+		units := (over + overageUnit - 1) / overageUnit
+		invoice.LineItems = append(invoice.LineItems, LineItem{
+			Description: fmt.Sprintf("API requests over %s, per %s", formatCount(included.IntPart()), formatCount(overageUnit)),
+			Quantity:    units,
+			UnitPrice:   p.overagePrice,
+			Amount:      money.Round(p.overagePrice.Mul(decimal.NewFromInt(units)), i.currency),
+		})
+	}
+
+	for _, item := range invoice.LineItems {
+		invoice.Total = invoice.Total.Add(item.Amount)
+	}
+	if !invoice.Total.IsPositive() {
+		invoice.Status = StatusPaid
+		invoice.PaidAt = &now
+	}
+	return invoice
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// formatCount renders a non-negative n with thousands separators, e.g.
+// "1,000,000".
+func formatCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,102 @@
+// Package billing invoices the paid plans and their metered API usage, and
+// chases failed payments before moving users to the free plan.
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Invoice statuses.
+const (
+	// StatusOpen invoices wait for their payment to be reported
+	StatusOpen = "open"
+	StatusPaid = "paid"
+	// StatusPastDue invoices had a payment fail and are dunned until paid
+	StatusPastDue = "past_due"
+)
+
+var (
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrInvoiceExists   = errors.New("period already invoiced")
+	ErrInvoicePaid     = errors.New("invoice already paid")
+)
+
+// Invoice bills a user's plan and API usage for one calendar month.
+type Invoice struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_billing_invoices_period,priority:1"`
+	Number string `json:"number" gorm:"type:varchar(32);not null;uniqueIndex"`
+	// Plan is the last paid plan of the invoiced period
+	Plan        string          `json:"plan" gorm:"type:varchar(32);not null"`
+	PeriodStart time.Time       `json:"period_start" gorm:"not null;uniqueIndex:idx_billing_invoices_period,priority:2"`
+	PeriodEnd   time.Time       `json:"period_end" gorm:"not null"`
+	Currency    string          `json:"currency" gorm:"type:varchar(8);not null"`
+	Total       decimal.Decimal `json:"total" gorm:"type:numeric;not null"`
+	Status      string          `json:"status" gorm:"type:varchar(16);not null;index"`
+	IssuedAt    time.Time       `json:"issued_at" gorm:"not null"`
+	DueAt       time.Time       `json:"due_at" gorm:"not null"`
+	PaidAt      *time.Time      `json:"paid_at,omitempty"`
+
+	FailedPayments int    `json:"failed_payments" gorm:"not null;default:0"`
+	LastFailure    string `json:"last_failure,omitempty"`
+	// PastDueSince is when the first payment failed or the invoice fell
+	// due unpaid, whichever came first; dunning is paced from it
+	PastDueSince  *time.Time `json:"past_due_since,omitempty"`
+	RemindersSent int        `json:"reminders_sent" gorm:"not null;default:0"`
+	// NextDunningAt is when the dunner next looks at the invoice; unset
+	// once there is nothing left to do
+	NextDunningAt *time.Time `json:"-" gorm:"index"`
+	// DowngradedFrom is the plan the user lost for leaving the invoice
+	// unpaid, given back when it is paid
+	DowngradedFrom string `json:"downgraded_from,omitempty" gorm:"type:varchar(32)"`
+
+	LineItems []LineItem `json:"line_items,omitempty" gorm:"foreignKey:InvoiceID"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Invoice) TableName() string {
+	return "billing_invoices"
+}
+
+// LineItem is one charge of an invoice.
+type LineItem struct {
+	ID          string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	InvoiceID   string          `json:"-" gorm:"type:varchar(36);not null;index"`
+	Position    int             `json:"-" gorm:"not null"`
+	Description string          `json:"description" gorm:"not null"`
+	Quantity    int64           `json:"quantity" gorm:"not null"`
+	UnitPrice   decimal.Decimal `json:"unit_price" gorm:"type:numeric;not null"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:numeric;not null"`
+}
+
+// TableName sets the table name for GORM
+func (LineItem) TableName() string {
+	return "billing_line_items"
+}
+
+// DailyUsage is a user's metered API requests on one UTC day. Metering
+// keeps hourly usage only briefly, so it is copied here for invoicing.
+type DailyUsage struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(36)"`
+	Day       time.Time `gorm:"primaryKey;type:date"`
+	Requests  int64     `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (DailyUsage) TableName() string {
+	return "billing_usage"
+}
+
+// Payment is the outcome of charging an invoice, as reported by the
+// payment provider.
+type Payment struct {
+	Succeeded bool
+	// Reason says why a failed payment failed, e.g. "card_declined"
+	Reason string
+}
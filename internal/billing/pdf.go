@@ -0,0 +1,183 @@
+package billing
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+const (
+	issuerName = "TradingBot Hub"
+
+	// A4 in points
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 56
+
+	// Numbers are set in Courier, whose glyphs are all 0.6em wide, so
+	// they can be right-aligned without font metrics
+	courierAdvance = 0.6
+)
+
+// RenderPDF renders the invoice as a one-page PDF receipt billed to
+// billedTo. It writes the PDF by hand with the standard Type 1 fonts, which
+// every viewer has, so no fonts are embedded.
+func RenderPDF(invoice *Invoice, billedTo string) []byte {
+	var page pdfPage
+	y := float64(pageHeight - margin)
+
+	page.text("F2", 22, margin, y-22, "Invoice")
+	page.rightText("F2", 12, pageWidth-margin, y-18, issuerName)
+	y -= 56
+
+	details := [][2]string{
+		{"Invoice number", invoice.Number},
+		{"Billed to", billedTo},
+		{"Issued", invoice.IssuedAt.Format("January 2, 2006")},
+		{"Due", invoice.DueAt.Format("January 2, 2006")},
+		{"Period", fmt.Sprintf("%s - %s", invoice.PeriodStart.Format("January 2, 2006"),
+			invoice.PeriodEnd.AddDate(0, 0, -1).Format("January 2, 2006"))},
+		{"Status", statusLabel(invoice)},
+	}
+	for _, d := range details {
+		page.text("F2", 10, margin, y, d[0])
+		page.text("F1", 10, margin+110, y, d[1])
+		y -= 16
+	}
+	y -= 20
+
+	// Columns: description, then right edges of quantity, unit price and
+	// amount
+	qtyRight := float64(pageWidth - margin - 200)
+	unitRight := float64(pageWidth - margin - 100)
+	amountRight := float64(pageWidth - margin)
+
+	page.text("F2", 10, margin, y, "Description")
+	page.rightText("F2", 10, qtyRight, y, "Qty")
+	page.rightText("F2", 10, unitRight, y, "Unit price")
+	page.rightText("F2", 10, amountRight, y, "Amount")
+	y -= 6
+	page.line(margin, y, pageWidth-margin, y)
+	y -= 16
+
+	for _, item := range invoice.LineItems {
+		page.text("F1", 10, margin, y, item.Description)
+		page.rightText("F3", 10, qtyRight, y, fmt.Sprintf("%d", item.Quantity))
+		page.rightText("F3", 10, unitRight, y, formatUnitPrice(item.UnitPrice, invoice.Currency))
+		page.rightText("F3", 10, amountRight, y, money.Format(item.Amount, invoice.Currency))
+		y -= 16
+	}
+	y += 6
+	page.line(margin, y, pageWidth-margin, y)
+	y -= 18
+
+	page.rightText("F2", 11, unitRight, y, "Total")
+	page.rightText("F3", 11, amountRight, y, money.Format(invoice.Total, invoice.Currency))
+
+	page.text("F1", 8, margin, margin, fmt.Sprintf("%s - invoice %s", issuerName, invoice.Number))
+	return page.document()
+}
+
+// statusLabel is the invoice's status as printed.
+func statusLabel(invoice *Invoice) string {
+	switch invoice.Status {
+	case StatusPaid:
+		if invoice.PaidAt != nil {
+			return "Paid on " + invoice.PaidAt.Format("January 2, 2006")
+		}
+		return "Paid"
+	case StatusPastDue:
+		return "Past due"
+	default:
+		return "Open"
+	}
+}
+
+// formatUnitPrice keeps the digits of sub-cent prices such as per-request
+// rates, which money.Format would round away.
+func formatUnitPrice(price decimal.Decimal, currency string) string {
+	if price.Equal(money.Round(price, currency)) {
+		return money.Format(price, currency)
+	}
+	return price.String() + " " + currency
+}
+
+// pdfPage collects the content stream of a page.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// text draws s with its baseline starting at (x, y). F1 is Helvetica, F2
+// Helvetica-Bold and F3 Courier.
+func (p *pdfPage) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// rightText draws s ending at x. Only Courier is measured; other fonts are
+// estimated at Helvetica's average width, which suits short headings.
+func (p *pdfPage) rightText(font string, size, x, y float64, s string) {
+	advance := 0.55
+	if font == "F3" {
+		advance = courierAdvance
+	}
+	p.text(font, size, x-float64(len([]rune(s)))*advance*size, y, s)
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// document wraps the page into a complete PDF file with its cross-reference
+// table.
+func (p *pdfPage) document() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R "+
+			"/Resources << /Font << /F1 5 0 R /F2 6 0 R /F3 7 0 R >> >> >>", pageWidth, pageHeight),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfString escapes s for a literal string in WinAnsi encoding. Characters
+// the encoding lacks print as "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteString(`\200`)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package billing
+
+import (
+	"context"
+
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+// Plans changes users' plans through the auth service, which records and
+// audits the change and drops the tokens carrying the old plan.
+type Plans interface {
+	// ChangePlan moves the user to plan if they are on from, or on any
+	// plan when from is empty. It returns the plan they were on and
+	// whether it changed.
+	ChangePlan(ctx context.Context, userID, plan, from string) (string, bool, error)
+}
+
+type authPlans struct {
+	client     authpb.AuthServiceClient
+	serviceKey string
+}
+
+// NewAuthPlans calls the auth service with the platform's service key.
+func NewAuthPlans(client authpb.AuthServiceClient, serviceKey string) Plans {
+	return &authPlans{client: client, serviceKey: serviceKey}
+}
+
+func (p *authPlans) ChangePlan(ctx context.Context, userID, plan, from string) (string, bool, error) {
+	resp, err := p.client.ChangePlan(ctx, &authpb.ChangePlanRequest{
+		ServiceKey: p.serviceKey,
+		UserId:     userID,
+		Plan:       plan,
+		FromPlan:   from,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return resp.PreviousPlan, resp.Changed, nil
+}
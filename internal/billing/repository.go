@@ -0,0 +1,200 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/auth"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultInvoiceLimit = 24
+	MaxInvoiceLimit     = 120
+)
+
+type Repository interface {
+	// Subscribers lists the active users on a paid plan or whose plan
+	// changed since the given time; only their ID and plan are loaded
+	Subscribers(ctx context.Context, since time.Time) ([]auth.User, error)
+	// PlanChanges returns the user's plan changes since the given time,
+	// oldest first
+	PlanChanges(ctx context.Context, userID string, since time.Time) ([]auth.PlanChange, error)
+	// RecordUsage stores daily totals, replacing those already recorded
+	RecordUsage(ctx context.Context, usage []DailyUsage) error
+	// Requests sums the user's daily totals of the days in [from, to)
+	Requests(ctx context.Context, userID string, from, to time.Time) (int64, error)
+	// Create stores the invoice with its line items, or returns
+	// ErrInvoiceExists when the user's period is already invoiced
+	Create(ctx context.Context, invoice *Invoice) error
+	// List returns the user's invoices, newest first, without line items
+	List(ctx context.Context, userID string, limit int) ([]Invoice, error)
+	// Get returns one of the user's invoices with its line items
+	Get(ctx context.Context, userID, id string) (*Invoice, error)
+	// MarkOverdue moves open invoices due before now to past due, pacing
+	// their dunning from their due date. It returns how many moved.
+	MarkOverdue(ctx context.Context, now time.Time) (int64, error)
+	// Dunning lists the IDs of past due invoices whose dunning is due
+	Dunning(ctx context.Context, now time.Time) ([]string, error)
+	// Update locks the invoice, lets fn change it and stores its status,
+	// payment and dunning fields unless fn fails. Anything else fn does
+	// in tx commits with them.
+	Update(ctx context.Context, id string, fn func(tx *gorm.DB, invoice *Invoice) error) (*Invoice, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Subscribers(ctx context.Context, since time.Time) ([]auth.User, error) {
+	var users []auth.User
+	err := r.db.WithContext(ctx).
+		Select("id", "plan").
+		Where("is_active AND (plan <> ? OR id IN (?))", auth.PlanFree,
+			r.db.Model(&auth.PlanChange{}).Select("user_id").Where("changed_at >= ?", since)).
+		Order("id").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *repository) PlanChanges(ctx context.Context, userID string, since time.Time) ([]auth.PlanChange, error) {
+	var changes []auth.PlanChange
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND changed_at >= ?", userID, since).
+		Order("changed_at").
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *repository) RecordUsage(ctx context.Context, usage []DailyUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"requests", "updated_at"}),
+	}).Create(&usage).Error
+}
+
+func (r *repository) Requests(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&DailyUsage{}).
+		Select("COALESCE(SUM(requests), 0)").
+		Where("user_id = ? AND day >= ? AND day < ?", userID, from, to).
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *repository) Create(ctx context.Context, invoice *Invoice) error {
+	if invoice.ID == "" {
+		invoice.ID = uuid.New().String()
+	}
+	for i := range invoice.LineItems {
+		item := &invoice.LineItems[i]
+		if item.ID == "" {
+			item.ID = uuid.New().String()
+		}
+		item.InvoiceID = invoice.ID
+		item.Position = i
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("LineItems").Create(invoice)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvoiceExists
+		}
+		if len(invoice.LineItems) == 0 {
+			return nil
+		}
+		return tx.Create(&invoice.LineItems).Error
+	})
+}
+
+func (r *repository) List(ctx context.Context, userID string, limit int) ([]Invoice, error) {
+	if limit <= 0 {
+		limit = defaultInvoiceLimit
+	}
+	if limit > MaxInvoiceLimit {
+		limit = MaxInvoiceLimit
+	}
+
+	var invoices []Invoice
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("period_start DESC").
+		Limit(limit).
+		Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *repository) Get(ctx context.Context, userID, id string) (*Invoice, error) {
+	var invoice Invoice
+	err := r.db.WithContext(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *repository) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&Invoice{}).
+		Where("status = ? AND due_at < ?", StatusOpen, now).
+		Updates(map[string]interface{}{
+			"status":          StatusPastDue,
+			"past_due_since":  gorm.Expr("due_at"),
+			"next_dunning_at": now,
+			"updated_at":      now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) Dunning(ctx context.Context, now time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&Invoice{}).
+		Where("status = ? AND next_dunning_at <= ?", StatusPastDue, now).
+		Order("next_dunning_at").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *repository) Update(ctx context.Context, id string, fn func(tx *gorm.DB, invoice *Invoice) error) (*Invoice, error) {
+	var invoice Invoice
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock keeps a reported payment and a dunning step from
+		// acting on the same invoice at once
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&invoice).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvoiceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(tx, &invoice); err != nil {
+			return err
+		}
+		return tx.Model(&invoice).
+			Select("status", "paid_at", "failed_payments", "last_failure", "past_due_since",
+				"reminders_sent", "next_dunning_at", "downgraded_from", "updated_at").
+			Updates(&invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,67 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/auth"
+	"gorm.io/gorm"
+)
+
+// Service serves invoices to their users and records the payments
+// reported for them.
+type Service struct {
+	invoices Repository
+	plans    Plans
+}
+
+func NewService(invoices Repository, plans Plans) *Service {
+	return &Service{invoices: invoices, plans: plans}
+}
+
+// List returns the user's invoices, newest first.
+func (s *Service) List(ctx context.Context, userID string, limit int) ([]Invoice, error) {
+	return s.invoices.List(ctx, userID, limit)
+}
+
+// Get returns one of the user's invoices with its line items.
+func (s *Service) Get(ctx context.Context, userID, id string) (*Invoice, error) {
+	return s.invoices.Get(ctx, userID, id)
+}
+
+// RecordPayment settles the invoice or starts dunning it. Paying an invoice
+// the user was downgraded for gives them their plan back, unless their
+// plan was changed since.
+func (s *Service) RecordPayment(ctx context.Context, id string, payment Payment) (*Invoice, error) {
+	now := time.Now().UTC()
+	return s.invoices.Update(ctx, id, func(tx *gorm.DB, invoice *Invoice) error {
+		if invoice.Status == StatusPaid {
+			return ErrInvoicePaid
+		}
+
+		if !payment.Succeeded {
+			invoice.FailedPayments++
+			invoice.LastFailure = payment.Reason
+			if invoice.Status == StatusOpen {
+				// The dunner sends the first reminder on its next run
+				invoice.Status = StatusPastDue
+				invoice.PastDueSince = &now
+				invoice.NextDunningAt = &now
+			}
+			return nil
+		}
+
+		invoice.Status = StatusPaid
+		invoice.PaidAt = &now
+		invoice.NextDunningAt = nil
+		if invoice.DowngradedFrom == "" {
+			return nil
+		}
+		plan := invoice.DowngradedFrom
+		invoice.DowngradedFrom = ""
+		// Made before the payment commits, so a failure leaves the
+		// invoice unpaid to be reported again
+		_, _, err := s.plans.ChangePlan(ctx, invoice.UserID, plan, auth.PlanFree)
+		return err
+	})
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// State is the runtime state of a bot that must survive restarts and
+// rebalancing.
+type State struct {
+	// Window holds the latest closed candles the strategy evaluates
+	Window []marketdata.Candle `json:"window"`
+	// Long is true while the bot holds a position
+	Long bool `json:"long"`
+	// LastCandle is the open time of the last candle acted upon; candles
+	// up to it are never traded on again
+	LastCandle time.Time `json:"last_candle"`
+}
+
+// Checkpoint is the persisted State of a bot. ConfigHash ties the
+// indicator window to the config that produced it.
+type Checkpoint struct {
+	BotID      string    `json:"bot_id" gorm:"primaryKey;type:varchar(36)"`
+	ConfigHash string    `json:"config_hash" gorm:"not null"`
+	State      State     `json:"state" gorm:"type:jsonb;serializer:json"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Checkpoint) TableName() string {
+	return "bot_checkpoints"
+}
+
+// ConfigHash fingerprints a strategy config.
+func ConfigHash(cfg strategy.Config) string {
+	data, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+type CheckpointStore interface {
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	Load(ctx context.Context, botID string) (*Checkpoint, error)
+	Delete(ctx context.Context, botID string) error
+}
+
+type checkpointStore struct {
+	db *gorm.DB
+}
+
+func NewCheckpointStore(db *gorm.DB) CheckpointStore {
+	return &checkpointStore{db: db}
+}
+
+func (s *checkpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(checkpoint).Error
+}
+
+func (s *checkpointStore) Load(ctx context.Context, botID string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := s.db.WithContext(ctx).Where("bot_id = ?", botID).First(&checkpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCheckpointNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (s *checkpointStore) Delete(ctx context.Context, botID string) error {
+	return s.db.WithContext(ctx).Where("bot_id = ?", botID).Delete(&Checkpoint{}).Error
+}
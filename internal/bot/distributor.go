@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tradingbothub/platform/internal/cache"
+)
+
+const (
+	replicasKey   = "bot:runtime:replicas"
+	lockKeyPrefix = "bot:runtime:lock:"
+)
+
+// Handler is notified when this replica gains or loses responsibility for a
+// bot. Start is only called while the replica holds the bot's lock, which
+// guarantees at most one runner per bot across the cluster.
+type Handler interface {
+	StartBot(ctx context.Context, botID string) error
+	StopBot(ctx context.Context, botID string) error
+}
+
+type DistributorOptions struct {
+	// HeartbeatInterval is how often membership and locks are refreshed
+	HeartbeatInterval time.Duration
+	// MemberTTL is how long a replica stays in the ring without heartbeating
+	MemberTTL time.Duration
+	// LockTTL bounds how long a crashed replica can keep a bot locked
+	LockTTL time.Duration
+}
+
+// Distributor spreads active bots across bot runtime replicas using a
+// consistent hash ring built from Redis heartbeats, and rebalances when
+// replicas join or leave.
+type Distributor struct {
+	client    redis.UniversalClient
+	replicaID string
+	opts      DistributorOptions
+	activeFn  func(ctx context.Context) ([]string, error)
+	handler   Handler
+
+	mutex sync.Mutex
+	ring  *hashRing
+	owned map[string]*cache.Lock
+}
+
+func NewDistributor(client redis.UniversalClient, replicaID string, activeFn func(ctx context.Context) ([]string, error), handler Handler, opts DistributorOptions) *Distributor {
+	if opts.HeartbeatInterval == 0 {
+		opts.HeartbeatInterval = 5 * time.Second
+	}
+	if opts.MemberTTL == 0 {
+		opts.MemberTTL = 3 * opts.HeartbeatInterval
+	}
+	if opts.LockTTL == 0 {
+		opts.LockTTL = 3 * opts.HeartbeatInterval
+	}
+
+	return &Distributor{
+		client:    client,
+		replicaID: replicaID,
+		opts:      opts,
+		activeFn:  activeFn,
+		handler:   handler,
+		ring:      newHashRing(nil),
+		owned:     make(map[string]*cache.Lock),
+	}
+}
+
+// Run heartbeats and rebalances until ctx is cancelled, then stops every bot
+// this replica runs and leaves the ring.
+func (d *Distributor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.tick(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Bot distributor tick failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			d.shutdown()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Owned returns the IDs of the bots currently running on this replica.
+func (d *Distributor) Owned() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ids := make([]string, 0, len(d.owned))
+	for id := range d.owned {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (d *Distributor) tick(ctx context.Context) error {
+	members, err := d.heartbeat(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	if !d.ring.Equal(members) {
+		log.Printf("Bot runtime membership changed: %v", members)
+		d.ring = newHashRing(members)
+	}
+	ring := d.ring
+	d.mutex.Unlock()
+
+	active, err := d.activeFn(ctx)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, botID := range active {
+		if ring.Owner(botID) == d.replicaID {
+			wanted[botID] = true
+		}
+	}
+
+	d.release(ctx, wanted)
+	d.acquire(ctx, wanted)
+	return nil
+}
+
+// heartbeat registers this replica and returns the live membership.
+func (d *Distributor) heartbeat(ctx context.Context) ([]string, error) {
+	now := time.Now()
+	cutoff := now.Add(-d.opts.MemberTTL)
+
+	pipe := d.client.TxPipeline()
+	pipe.ZAdd(ctx, replicasKey, redis.Z{Score: float64(now.UnixMilli()), Member: d.replicaID})
+	pipe.ZRemRangeByScore(ctx, replicasKey, "-inf", strconv.FormatInt(cutoff.UnixMilli(), 10))
+	members := pipe.ZRange(ctx, replicasKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+// release stops bots that moved away and refreshes the locks of the rest.
+func (d *Distributor) release(ctx context.Context, wanted map[string]bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for botID, lock := range d.owned {
+		if wanted[botID] {
+			if err := lock.Refresh(ctx); err == nil {
+				continue
+			}
+			// Lost the lease; another replica may already run the bot
+			log.Printf("Lost lock for bot %s", botID)
+		}
+
+		if err := d.handler.StopBot(ctx, botID); err != nil {
+			log.Printf("Failed to stop bot %s: %v", botID, err)
+		}
+		lock.Release(ctx)
+		delete(d.owned, botID)
+	}
+}
+
+// acquire locks and starts bots newly assigned to this replica. A bot whose
+// previous owner has not released it yet is retried on the next tick.
+func (d *Distributor) acquire(ctx context.Context, wanted map[string]bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for botID := range wanted {
+		if _, ok := d.owned[botID]; ok {
+			continue
+		}
+
+		lock, err := cache.TryLock(ctx, d.client, lockKeyPrefix+botID, d.replicaID, d.opts.LockTTL)
+		if err != nil {
+			continue
+		}
+
+		if err := d.handler.StartBot(ctx, botID); err != nil {
+			log.Printf("Failed to start bot %s: %v", botID, err)
+			lock.Release(ctx)
+			continue
+		}
+		d.owned[botID] = lock
+	}
+}
+
+func (d *Distributor) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.LockTTL)
+	defer cancel()
+
+	d.release(ctx, nil)
+	d.client.ZRem(ctx, replicasKey, d.replicaID)
+}
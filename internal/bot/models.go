@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusStopped = "stopped"
+	StatusRunning = "running"
+	StatusError   = "error"
+)
+
+type Bot struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	// OrgID is the organization the bot belongs to, empty for a personal
+	// bot; UserID is then the member who created or moved it there
+	OrgID    string `json:"org_id,omitempty" gorm:"type:varchar(36);not null;default:'';index"`
+	Name     string `json:"name" gorm:"not null"`
+	Exchange string `json:"exchange" gorm:"not null"`
+	Symbol   string `json:"symbol" gorm:"not null"`
+	Strategy string `json:"strategy" gorm:"not null"`
+	// APIKeyID is the exchange key the bot trades with; Testnet mirrors
+	// the key's flag so the runtime and analytics need not look it up
+	APIKeyID string `json:"api_key_id,omitempty" gorm:"type:varchar(36)"`
+	Testnet  bool   `json:"testnet" gorm:"not null;default:false;index"`
+	// Config parameterizes the strategy's signal generator
+	Config strategy.Config `json:"config" gorm:"type:jsonb"`
+	Status string          `json:"status" gorm:"not null;default:'stopped';index"`
+	// StartedBy is the member who last started the bot; it trades with an
+	// organization's key under that member's grants
+	StartedBy string `json:"started_by,omitempty" gorm:"type:varchar(36);not null;default:''"`
+	// Capital is the quote amount allocated to the bot; its equity is
+	// measured against this starting balance
+	Capital decimal.Decimal `json:"capital" gorm:"type:numeric"`
+	// MaxOrdersPerMinute and MaxCancelsPerMinute override the platform's
+	// default order rate policy when set
+	MaxOrdersPerMinute  int            `json:"max_orders_per_minute,omitempty"`
+	MaxCancelsPerMinute int            `json:"max_cancels_per_minute,omitempty"`
+	Tags                pq.StringArray `json:"tags" gorm:"type:text[];index:idx_bots_tags,type:gin"`
+	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	// DeletedAt moves the row to the trash; the retention job purges it
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName sets the table name for GORM
+func (Bot) TableName() string {
+	return "bots"
+}
+
+// Owner is whose workspace the bot is in.
+func (b *Bot) Owner() org.Owner {
+	return org.Owner{UserID: b.UserID, OrgID: b.OrgID}
+}
+
+// Starter is who the bot trades on behalf of: the member who started it,
+// or its user for bots started before that was recorded.
+func (b *Bot) Starter() string {
+	if b.StartedBy != "" {
+		return b.StartedBy
+	}
+	return b.UserID
+}
+
+// Account is the ID the bot trades under and its orders, trades and
+// holdings are recorded under: its organization's, or its user's.
+func (b *Bot) Account() string {
+	if b.OrgID != "" {
+		return b.OrgID
+	}
+	return b.UserID
+}
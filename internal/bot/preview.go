@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+// PreviewRun is what one config would have done over the preview window.
+type PreviewRun struct {
+	Config  strategy.Config           `json:"config"`
+	Signals []strategy.Signal         `json:"signals"`
+	Orders  []strategy.SimulatedOrder `json:"orders"`
+}
+
+// PreviewDiff compares the orders of the proposed config with the current
+// one. Orders match when they share a candle and side.
+type PreviewDiff struct {
+	Added     []strategy.SimulatedOrder `json:"added"`
+	Removed   []strategy.SimulatedOrder `json:"removed"`
+	Unchanged int                       `json:"unchanged"`
+}
+
+type PreviewResult struct {
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Current  PreviewRun  `json:"current"`
+	Proposed PreviewRun  `json:"proposed"`
+	Diff     PreviewDiff `json:"diff"`
+}
+
+// CandleLoader returns the recorded candles of the bot's market up to the
+// end of the preview window, starting warmup candles before its start.
+type CandleLoader func(interval marketdata.Interval, warmup int) ([]marketdata.Candle, error)
+
+// Preview replays recorded candles through the current and proposed
+// configs. Warm-up candles only prime indicators; signals are reported
+// from the start of the window on.
+func Preview(current, proposed strategy.Config, load CandleLoader, from, to time.Time) (*PreviewResult, error) {
+	if err := proposed.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &PreviewResult{From: from, To: to}
+
+	// A bot without a config yet has nothing to compare against
+	result.Current = PreviewRun{Config: current, Signals: []strategy.Signal{}, Orders: []strategy.SimulatedOrder{}}
+
+	var err error
+	if current.Type != "" {
+		if result.Current, err = previewRun(current, load, from); err != nil {
+			return nil, err
+		}
+	}
+	if result.Proposed, err = previewRun(proposed, load, from); err != nil {
+		return nil, err
+	}
+
+	result.Diff = diffOrders(result.Current.Orders, result.Proposed.Orders)
+	return result, nil
+}
+
+func previewRun(cfg strategy.Config, load CandleLoader, from time.Time) (PreviewRun, error) {
+	interval, err := marketdata.ParseInterval(cfg.Interval)
+	if err != nil {
+		return PreviewRun{}, err
+	}
+
+	candles, err := load(interval, cfg.Warmup())
+	if err != nil {
+		return PreviewRun{}, err
+	}
+
+	signals, err := strategy.Evaluate(cfg, candles)
+	if err != nil {
+		return PreviewRun{}, err
+	}
+
+	visible := []strategy.Signal{}
+	for _, signal := range signals {
+		if !signal.Time.Before(from) {
+			visible = append(visible, signal)
+		}
+	}
+
+	orders := strategy.Orders(cfg, visible)
+	if orders == nil {
+		orders = []strategy.SimulatedOrder{}
+	}
+	return PreviewRun{Config: cfg, Signals: visible, Orders: orders}, nil
+}
+
+func diffOrders(current, proposed []strategy.SimulatedOrder) PreviewDiff {
+	key := func(o strategy.SimulatedOrder) string {
+		return o.Time.UTC().Format(time.RFC3339) + string(o.Side)
+	}
+
+	existing := make(map[string]bool, len(current))
+	for _, o := range current {
+		existing[key(o)] = true
+	}
+
+	diff := PreviewDiff{Added: []strategy.SimulatedOrder{}, Removed: []strategy.SimulatedOrder{}}
+	matched := make(map[string]bool, len(proposed))
+	for _, o := range proposed {
+		if existing[key(o)] {
+			matched[key(o)] = true
+			diff.Unchanged++
+			continue
+		}
+		diff.Added = append(diff.Added, o)
+	}
+	for _, o := range current {
+		if !matched[key(o)] {
+			diff.Removed = append(diff.Removed, o)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/tags"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrBotNotFound = errors.New("bot not found")
+
+type Repository interface {
+	Create(ctx context.Context, bot *Bot) error
+	Get(ctx context.Context, id string) (*Bot, error)
+	List(ctx context.Context, owner org.Owner, filter tags.Filter) ([]Bot, error)
+	SetTags(ctx context.Context, owner org.Owner, id string, values pq.StringArray) error
+	SetStatus(ctx context.Context, owner org.Owner, id, status string) error
+	// Start sets the bot running on behalf of the user starting it
+	Start(ctx context.Context, owner org.Owner, id, startedBy string) error
+	// SetRateLimits overrides the bot's order rate policy; zero restores
+	// the platform default
+	SetRateLimits(ctx context.Context, owner org.Owner, id string, ordersPerMinute, cancelsPerMinute int) error
+	// SetAPIKey sets the exchange key the bot trades with and whether it
+	// is a testnet key; an empty keyID leaves the bot without one
+	SetAPIKey(ctx context.Context, owner org.Owner, id, keyID string, testnet bool) error
+	// Delete moves the bot to the trash
+	Delete(ctx context.Context, owner org.Owner, id string) error
+	ListDeleted(ctx context.Context, owner org.Owner) ([]Bot, error)
+	Restore(ctx context.Context, owner org.Owner, id string) error
+	// Move hands the bot over to another owner, into an organization or
+	// back to personal use; to.UserID becomes its user either way
+	Move(ctx context.Context, owner org.Owner, id string, to org.Owner) error
+	// PurgeDeleted permanently removes bots trashed before cutoff
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error)
+	// ListRunning returns every bot that should currently be trading
+	ListRunning(ctx context.Context) ([]Bot, error)
+	// ListRunningWithKey returns the running bots trading with the key
+	ListRunningWithKey(ctx context.Context, keyID string) ([]Bot, error)
+	// StopAll stops every running bot of every user and returns them
+	StopAll(ctx context.Context) ([]Bot, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, bot *Bot) error {
+	return r.db.WithContext(ctx).Create(bot).Error
+}
+
+func (r *repository) Get(ctx context.Context, id string) (*Bot, error) {
+	var bot Bot
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&bot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+func (r *repository) List(ctx context.Context, owner org.Owner, filter tags.Filter) ([]Bot, error) {
+	tx := filter.Apply(r.db.WithContext(ctx).Scopes(owner.Scope), "tags")
+
+	var bots []Bot
+	err := tx.Order("created_at DESC").Find(&bots).Error
+	return bots, err
+}
+
+func (r *repository) SetTags(ctx context.Context, owner org.Owner, id string, values pq.StringArray) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Update("tags", values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) SetRateLimits(ctx context.Context, owner org.Owner, id string, ordersPerMinute, cancelsPerMinute int) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"max_orders_per_minute":  ordersPerMinute,
+			"max_cancels_per_minute": cancelsPerMinute,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) SetAPIKey(ctx context.Context, owner org.Owner, id, keyID string, testnet bool) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Updates(map[string]interface{}{"api_key_id": keyID, "testnet": testnet})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) ListRunning(ctx context.Context) ([]Bot, error) {
+	var bots []Bot
+	err := r.db.WithContext(ctx).Where("status = ?", StatusRunning).Order("id").Find(&bots).Error
+	return bots, err
+}
+
+func (r *repository) ListRunningWithKey(ctx context.Context, keyID string) ([]Bot, error) {
+	var bots []Bot
+	err := r.db.WithContext(ctx).Where("status = ? AND api_key_id = ?", StatusRunning, keyID).Order("id").Find(&bots).Error
+	return bots, err
+}
+
+func (r *repository) StopAll(ctx context.Context) ([]Bot, error) {
+	var bots []Bot
+	err := r.db.WithContext(ctx).Model(&bots).
+		Clauses(clause.Returning{}).
+		Where("status = ?", StatusRunning).
+		Update("status", StatusStopped).Error
+	return bots, err
+}
+
+func (r *repository) SetStatus(ctx context.Context, owner org.Owner, id, status string) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) Start(ctx context.Context, owner org.Owner, id, startedBy string) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": StatusRunning, "started_by": startedBy})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, owner org.Owner, id string) error {
+	result := r.db.WithContext(ctx).Scopes(owner.Scope).Where("id = ?", id).Delete(&Bot{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) ListDeleted(ctx context.Context, owner org.Owner) ([]Bot, error) {
+	var bots []Bot
+	err := r.db.WithContext(ctx).Unscoped().
+		Scopes(owner.Scope).Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&bots).Error
+	return bots, err
+}
+
+func (r *repository) Restore(ctx context.Context, owner org.Owner, id string) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *repository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", cutoff).Delete(&Bot{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) Move(ctx context.Context, owner org.Owner, id string, to org.Owner) error {
+	result := r.db.WithContext(ctx).Model(&Bot{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Updates(map[string]interface{}{"user_id": to.UserID, "org_id": to.OrgID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes per replica smooths the distribution of bots across replicas.
+const virtualNodes = 64
+
+// hashRing is a consistent hash ring of bot runtime replicas. When a replica
+// joins or leaves only the bots adjacent to its points move.
+type hashRing struct {
+	points  []uint32
+	owners  map[uint32]string
+	members []string
+}
+
+func newHashRing(members []string) *hashRing {
+	ring := &hashRing{
+		owners:  make(map[uint32]string),
+		members: append([]string{}, members...),
+	}
+	sort.Strings(ring.members)
+
+	for _, member := range ring.members {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(member + "#" + strconv.Itoa(i))
+			ring.points = append(ring.points, point)
+			ring.owners[point] = member
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+
+	return ring
+}
+
+// Owner returns the replica responsible for the bot.
+func (r *hashRing) Owner(botID string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	h := hashKey(botID)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+func (r *hashRing) Equal(members []string) bool {
+	if len(members) != len(r.members) {
+		return false
+	}
+	sorted := append([]string{}, members...)
+	sort.Strings(sorted)
+	for i := range sorted {
+		if sorted[i] != r.members[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
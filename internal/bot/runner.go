@@ -0,0 +1,383 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+var strategyUpdateSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "strategy_update_seconds",
+	Help:    "Time a running strategy takes to process one closed candle.",
+	Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+}, []string{"strategy"})
+
+type RunnerOptions struct {
+	// PollInterval is how often a bot checks for newly closed candles
+	PollInterval time.Duration
+	// CheckpointInterval is how often state is saved between trades;
+	// state is always saved right after an order and when a bot stops
+	CheckpointInterval time.Duration
+	// OrderRate is the default order rate policy; a bot's own limits
+	// replace its per-minute caps
+	OrderRate orders.RatePolicy
+	// Mirror, when set, is told of every order a bot places
+	Mirror Mirror
+	// Keys, when set, checks the bot may still trade with its exchange
+	// key before it starts and before every order
+	Keys KeyAuthorizer
+}
+
+// KeyAuthorizer tells whether a bot may trade with an exchange key of its
+// workspace; exchange.KeyAuthorizer implements it.
+type KeyAuthorizer interface {
+	Authorize(ctx context.Context, owner org.Owner, keyID, userID, botID string) error
+}
+
+// Mirror copies the orders of bots elsewhere, such as into the accounts of
+// their owner's followers. It must not fail the bot's own trading.
+type Mirror interface {
+	Mirror(ctx context.Context, b *Bot, req exchange.OrderRequest)
+}
+
+// Runner executes the strategies of the bots the Distributor assigns to
+// this replica. It implements Handler. Bots resume from their checkpoint,
+// so a restart or rebalance neither replays candles that were already
+// traded on nor forgets an open position.
+type Runner struct {
+	bots        Repository
+	checkpoints CheckpointStore
+	signals     SignalStore
+	candles     marketdata.CandleStore
+	oms         *orders.OMS
+	opts        RunnerOptions
+
+	mutex   sync.Mutex
+	running map[string]*runningBot
+}
+
+type runningBot struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRunner(bots Repository, checkpoints CheckpointStore, signals SignalStore, candles marketdata.CandleStore, oms *orders.OMS, opts RunnerOptions) *Runner {
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	if opts.CheckpointInterval == 0 {
+		opts.CheckpointInterval = time.Minute
+	}
+	return &Runner{
+		bots:        bots,
+		checkpoints: checkpoints,
+		signals:     signals,
+		candles:     candles,
+		oms:         oms,
+		opts:        opts,
+		running:     make(map[string]*runningBot),
+	}
+}
+
+// StartBot restores the bot's state and starts trading it in the
+// background.
+func (r *Runner) StartBot(ctx context.Context, botID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.running[botID]; ok {
+		return nil
+	}
+
+	b, err := r.bots.Get(ctx, botID)
+	if err != nil {
+		return err
+	}
+	if err := b.Config.Validate(); err != nil {
+		return err
+	}
+	interval, err := marketdata.ParseInterval(b.Config.Interval)
+	if err != nil {
+		return err
+	}
+	if err := r.authorize(ctx, b); err != nil {
+		r.halt(ctx, b, err)
+		return err
+	}
+	client, err := r.oms.BotClient(b.Exchange, b.Testnet, b.ID, r.ratePolicy(b))
+	if err != nil {
+		return err
+	}
+
+	state, err := r.restore(ctx, b, interval)
+	if err != nil {
+		return err
+	}
+
+	// The bot outlives the request that started it
+	runCtx, cancel := context.WithCancel(context.Background())
+	running := &runningBot{cancel: cancel, done: make(chan struct{})}
+	r.running[botID] = running
+
+	go func() {
+		defer close(running.done)
+		r.run(runCtx, b, interval, client, state)
+	}()
+	return nil
+}
+
+// StopBot stops the bot and waits for its final checkpoint.
+func (r *Runner) StopBot(ctx context.Context, botID string) error {
+	r.mutex.Lock()
+	running, ok := r.running[botID]
+	delete(r.running, botID)
+	r.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	running.cancel()
+	select {
+	case <-running.done:
+		r.oms.Forget(botID)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// restore resumes from the checkpoint or cold-starts from history. A
+// checkpoint of another config keeps the position but rebuilds the
+// indicator window, since it was computed for different parameters.
+func (r *Runner) restore(ctx context.Context, b *Bot, interval marketdata.Interval) (*State, error) {
+	checkpoint, err := r.checkpoints.Load(ctx, b.ID)
+	switch {
+	case err == nil && checkpoint.ConfigHash == ConfigHash(b.Config):
+		log.Printf("Bot %s resumed from checkpoint at %s", b.ID, checkpoint.State.LastCandle.Format(time.RFC3339))
+		return &checkpoint.State, nil
+	case err != nil && !errors.Is(err, ErrCheckpointNotFound):
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	state := &State{}
+	if checkpoint != nil {
+		state.Long = checkpoint.State.Long
+		log.Printf("Bot %s config changed; rebuilding indicator window", b.ID)
+	} else {
+		log.Printf("Bot %s cold start", b.ID)
+	}
+
+	// History only warms the indicators up; nothing in it is traded on
+	now := time.Now()
+	from := now.Add(-time.Duration(windowSize(b.Config)+1) * interval.Duration)
+	candles, err := r.candles.Candles(ctx, b.Exchange, b.Symbol, interval, from, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load warmup candles: %w", err)
+	}
+	candles = closedCandles(candles, interval, now)
+	state.Window = trimWindow(candles, b.Config)
+	if n := len(candles); n > 0 {
+		state.LastCandle = candles[n-1].Time
+	}
+	return state, nil
+}
+
+func (r *Runner) run(ctx context.Context, b *Bot, interval marketdata.Interval, client exchange.Client, state *State) {
+	// Only the window is checkpointed; replaying it primes the indicators,
+	// after which every candle is an incremental update
+	stream, err := strategy.NewStream(b.Config)
+	if err != nil {
+		log.Printf("Bot %s cannot run: %v", b.ID, err)
+		return
+	}
+	for _, candle := range state.Window {
+		stream.Update(candle)
+	}
+
+	poll := time.NewTicker(r.opts.PollInterval)
+	defer poll.Stop()
+	checkpoint := time.NewTicker(r.opts.CheckpointInterval)
+	defer checkpoint.Stop()
+
+	defer func() {
+		// Save with a fresh context; ours is already cancelled
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		r.save(saveCtx, b, state)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-checkpoint.C:
+			r.save(ctx, b, state)
+		case <-poll.C:
+			traded, err := r.step(ctx, b, interval, client, stream, state)
+			if errors.Is(err, exchange.ErrKeyNotFound) || errors.Is(err, exchange.ErrKeyNotGranted) {
+				r.halt(ctx, b, err)
+				return
+			}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("Bot %s step failed: %v", b.ID, err)
+			}
+			if traded {
+				r.save(ctx, b, state)
+			}
+		}
+	}
+}
+
+// step feeds newly closed candles to the strategy and places the orders it
+// signals. It reports whether an order was placed.
+func (r *Runner) step(ctx context.Context, b *Bot, interval marketdata.Interval, client exchange.Client, stream *strategy.Stream, state *State) (bool, error) {
+	now := time.Now()
+	candles, err := r.candles.Candles(ctx, b.Exchange, b.Symbol, interval, state.LastCandle.Add(time.Nanosecond), now)
+	if err != nil {
+		return false, err
+	}
+
+	traded := false
+	for _, candle := range closedCandles(candles, interval, now) {
+		if !candle.Time.After(state.LastCandle) {
+			continue
+		}
+		state.Window = trimWindow(append(state.Window, candle), b.Config)
+
+		started := time.Now()
+		signal, ok := stream.Update(candle)
+		strategyUpdateSeconds.WithLabelValues(b.Config.Type).Observe(time.Since(started).Seconds())
+		if !ok {
+			state.LastCandle = candle.Time
+			continue
+		}
+
+		record := &SignalRecord{
+			BotID:   b.ID,
+			Time:    signal.Time,
+			Side:    signal.Side,
+			Price:   signal.Price,
+			Reason:  signal.Reason,
+			Trace:   signal.Trace,
+			Outcome: OutcomeIgnored,
+		}
+		if (signal.Side == exchange.SideBuy) != state.Long {
+			// Deterministic so a replay after a crash cannot double-trade
+			clientOrderID := "bot-" + b.ID + "-" + strconv.FormatInt(candle.Time.Unix(), 10)
+			req := exchange.OrderRequest{
+				ClientOrderID: clientOrderID,
+				Symbol:        b.Symbol,
+				Side:          signal.Side,
+				Type:          exchange.OrderTypeMarket,
+				Quantity:      b.Config.Quantity,
+			}
+			if err := r.authorize(ctx, b); err != nil {
+				return traded, err
+			}
+			_, err := client.PlaceOrder(ctx, b.Account(), req)
+			if errors.Is(err, money.ErrBelowMinimum) {
+				// Retrying cannot help; the bot's quantity needs changing
+				log.Printf("Bot %s skipped %s signal at %s: %v", b.ID, signal.Side, candle.Time.Format(time.RFC3339), err)
+				record.Outcome = OutcomeSkipped
+				r.recordSignal(ctx, record)
+				state.LastCandle = candle.Time
+				continue
+			}
+			if err != nil {
+				return traded, fmt.Errorf("failed to place order: %w", err)
+			}
+			if r.opts.Mirror != nil {
+				r.opts.Mirror.Mirror(ctx, b, req)
+			}
+			state.Long = !state.Long
+			traded = true
+			record.Outcome = OutcomeOrdered
+			record.OrderID = clientOrderID
+		}
+		r.recordSignal(ctx, record)
+		state.LastCandle = candle.Time
+	}
+	return traded, nil
+}
+
+// authorize checks the bot may still trade with its exchange key.
+func (r *Runner) authorize(ctx context.Context, b *Bot) error {
+	if r.opts.Keys == nil || b.APIKeyID == "" {
+		return nil
+	}
+	return r.opts.Keys.Authorize(ctx, b.Owner(), b.APIKeyID, b.Starter(), b.ID)
+}
+
+// halt puts a bot that may no longer trade with its key in error, which
+// releases it from every replica.
+func (r *Runner) halt(ctx context.Context, b *Bot, err error) {
+	log.Printf("Bot %s halted: %v", b.ID, err)
+	if err := r.bots.SetStatus(ctx, b.Owner(), b.ID, StatusError); err != nil {
+		log.Printf("Failed to halt bot %s: %v", b.ID, err)
+	}
+}
+
+// recordSignal stores the signal's trace. Losing it only costs
+// explainability, so failures do not stop the bot.
+func (r *Runner) recordSignal(ctx context.Context, record *SignalRecord) {
+	if err := r.signals.Save(ctx, record); err != nil {
+		log.Printf("Failed to record signal of bot %s: %v", record.BotID, err)
+	}
+}
+
+func (r *Runner) ratePolicy(b *Bot) orders.RatePolicy {
+	policy := r.opts.OrderRate
+	if b.MaxOrdersPerMinute > 0 {
+		policy.OrdersPerMinute = b.MaxOrdersPerMinute
+	}
+	if b.MaxCancelsPerMinute > 0 {
+		policy.CancelsPerMinute = b.MaxCancelsPerMinute
+	}
+	return policy
+}
+
+func (r *Runner) save(ctx context.Context, b *Bot, state *State) {
+	err := r.checkpoints.Save(ctx, &Checkpoint{
+		BotID:      b.ID,
+		ConfigHash: ConfigHash(b.Config),
+		State:      *state,
+	})
+	if err != nil {
+		log.Printf("Failed to checkpoint bot %s: %v", b.ID, err)
+	}
+}
+
+// windowSize is one candle more than the warmup so crossings on the
+// newest candle can be detected.
+func windowSize(cfg strategy.Config) int {
+	return cfg.Warmup() + 1
+}
+
+func trimWindow(candles []marketdata.Candle, cfg strategy.Config) []marketdata.Candle {
+	if n := windowSize(cfg); len(candles) > n {
+		return append([]marketdata.Candle(nil), candles[len(candles)-n:]...)
+	}
+	return candles
+}
+
+// closedCandles drops the candle still forming at now.
+func closedCandles(candles []marketdata.Candle, interval marketdata.Interval, now time.Time) []marketdata.Candle {
+	for len(candles) > 0 && candles[len(candles)-1].Time.Add(interval.Duration).After(now) {
+		candles = candles[:len(candles)-1]
+	}
+	return candles
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSignalNotFound = errors.New("signal not found")
+
+// What the runtime did with a signal.
+const (
+	OutcomeOrdered = "ordered"
+	// OutcomeIgnored signals agree with the position the bot already holds
+	OutcomeIgnored = "ignored"
+	// OutcomeSkipped signals would have produced an order the market does
+	// not accept, such as one below the minimum notional
+	OutcomeSkipped = "skipped"
+)
+
+// SignalRecord is a signal a running bot generated, with the trace that
+// explains it. There is at most one per bot and candle, so a replay after
+// a restart does not duplicate it.
+type SignalRecord struct {
+	ID      string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	BotID   string         `json:"bot_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_bot_signals_bot_time,priority:1"`
+	Time    time.Time      `json:"time" gorm:"not null;uniqueIndex:idx_bot_signals_bot_time,priority:2"`
+	Side    exchange.Side  `json:"side" gorm:"not null"`
+	Price   float64        `json:"price"`
+	Reason  string         `json:"reason"`
+	Trace   strategy.Trace `json:"trace" gorm:"type:jsonb;serializer:json"`
+	Outcome string         `json:"outcome" gorm:"not null"`
+	// OrderID is the client order ID of the resulting order, if any
+	OrderID   string    `json:"order_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (SignalRecord) TableName() string {
+	return "bot_signals"
+}
+
+type SignalStore interface {
+	Save(ctx context.Context, record *SignalRecord) error
+	Get(ctx context.Context, botID, id string) (*SignalRecord, error)
+	// List returns the bot's latest signals, newest first
+	List(ctx context.Context, botID string, limit int) ([]SignalRecord, error)
+}
+
+type signalStore struct {
+	db *gorm.DB
+}
+
+func NewSignalStore(db *gorm.DB) SignalStore {
+	return &signalStore{db: db}
+}
+
+func (s *signalStore) Save(ctx context.Context, record *SignalRecord) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "bot_id"}, {Name: "time"}},
+			DoUpdates: clause.AssignmentColumns([]string{"outcome", "order_id"}),
+		}).
+		Create(record).Error
+}
+
+func (s *signalStore) Get(ctx context.Context, botID, id string) (*SignalRecord, error) {
+	var record SignalRecord
+	err := s.db.WithContext(ctx).Where("id = ? AND bot_id = ?", id, botID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSignalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *signalStore) List(ctx context.Context, botID string, limit int) ([]SignalRecord, error) {
+	var records []SignalRecord
+	err := s.db.WithContext(ctx).Where("bot_id = ?", botID).Order("time DESC").Limit(limit).Find(&records).Error
+	return records, err
+}
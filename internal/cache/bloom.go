@@ -0,0 +1,166 @@
+// internal/cache/bloom.go
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxBloomBits is the largest redis bitmap, 512 MB.
+const maxBloomBits = 1 << 32
+
+var ErrBloomNotBuilt = errors.New("bloom filter not built")
+
+// Bits are only set in bitmaps that exist: a filter nobody built yet must
+// stay missing rather than rule out every value it was never filled with.
+// While a rebuild is running, values go to its staging bitmap as well.
+var bloomAddScript = redis.NewScript(`
+local live = redis.call("EXISTS", KEYS[1]) == 1
+local staging = redis.call("EXISTS", KEYS[2]) == 1
+for i = 1, #ARGV do
+	if live then
+		redis.call("SETBIT", KEYS[1], ARGV[i], 1)
+	end
+	if staging then
+		redis.call("SETBIT", KEYS[2], ARGV[i], 1)
+	end
+end
+return 0`)
+
+// BloomFilter is a bloom filter kept in a redis bitmap and shared by every
+// instance of a service. A negative answer is certain; a positive one may
+// be a false positive and has to be confirmed elsewhere.
+//
+// The key includes the filter's size, so resizing it starts a new bitmap
+// that answers ErrBloomNotBuilt until it is rebuilt, instead of reading
+// the old one with the wrong offsets.
+type BloomFilter struct {
+	client  redis.UniversalClient
+	key     string
+	staging string
+	bits    uint64
+	hashes  int
+}
+
+// NewBloomFilter sizes a filter for the expected number of values at the
+// given false positive rate.
+func NewBloomFilter(client redis.UniversalClient, name string, expected uint64, falsePositiveRate float64) *BloomFilter {
+	n := math.Max(float64(expected), 1)
+	bits := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	bits = math.Min(math.Max(bits, 64), maxBloomBits)
+	hashes := int(math.Max(math.Round(bits/n*math.Ln2), 1))
+
+	// The hash tag keeps the bitmap and its staging copy in one cluster slot
+	key := fmt.Sprintf("bloom:{%s}:%d:%d", name, uint64(bits), hashes)
+	return &BloomFilter{
+		client:  client,
+		key:     key,
+		staging: key + ":rebuild",
+		bits:    uint64(bits),
+		hashes:  hashes,
+	}
+}
+
+// offsets derives the value's bit positions from two halves of one
+// 128-bit hash.
+func (f *BloomFilter) offsets(value string) []uint64 {
+	h := fnv.New128a()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+	h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])
+
+	offsets := make([]uint64, f.hashes)
+	for i := range offsets {
+		offsets[i] = (h1 + uint64(i)*h2) % f.bits
+	}
+	return offsets
+}
+
+// MightContain reports whether the value may have been added. It returns
+// ErrBloomNotBuilt while the filter has not been built.
+func (f *BloomFilter) MightContain(ctx context.Context, value string) (bool, error) {
+	offsets := f.offsets(value)
+	var exists *redis.IntCmd
+	bits := make([]*redis.IntCmd, len(offsets))
+	_, err := Pipelined(ctx, f.client, func(pipe redis.Pipeliner) {
+		exists = pipe.Exists(ctx, f.key)
+		for i, offset := range offsets {
+			bits[i] = pipe.GetBit(ctx, f.key, int64(offset))
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	if exists.Val() == 0 {
+		return false, ErrBloomNotBuilt
+	}
+	for _, bit := range bits {
+		if bit.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Add records the values. It does nothing while the filter has not been
+// built.
+func (f *BloomFilter) Add(ctx context.Context, values ...string) error {
+	var args []interface{}
+	for _, value := range values {
+		for _, offset := range f.offsets(value) {
+			args = append(args, offset)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return bloomAddScript.Run(ctx, f.client, []string{f.key, f.staging}, args...).Err()
+}
+
+// BloomRebuild fills a fresh copy of a filter, which replaces the filter
+// on Commit. Values removed since the last build are dropped this way.
+type BloomRebuild struct {
+	filter *BloomFilter
+}
+
+// Rebuild starts a rebuild, discarding any earlier one that did not
+// finish. Values added to the filter meanwhile are kept in the new copy.
+func (f *BloomFilter) Rebuild(ctx context.Context) (*BloomRebuild, error) {
+	_, err := Pipelined(ctx, f.client, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, f.staging)
+		// Setting the last bit allocates the whole bitmap up front
+		pipe.SetBit(ctx, f.staging, int64(f.bits-1), 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BloomRebuild{filter: f}, nil
+}
+
+// Add records the values in the new copy.
+func (r *BloomRebuild) Add(ctx context.Context, values ...string) error {
+	_, err := Pipelined(ctx, r.filter.client, func(pipe redis.Pipeliner) {
+		for _, value := range values {
+			for _, offset := range r.filter.offsets(value) {
+				pipe.SetBit(ctx, r.filter.staging, int64(offset), 1)
+			}
+		}
+	})
+	return err
+}
+
+// Commit replaces the filter with the new copy.
+func (r *BloomRebuild) Commit(ctx context.Context) error {
+	return r.filter.client.Rename(ctx, r.filter.staging, r.filter.key).Err()
+}
+
+// Abort discards the new copy.
+func (r *BloomRebuild) Abort(ctx context.Context) error {
+	return r.filter.client.Del(ctx, r.filter.staging).Err()
+}
@@ -0,0 +1,87 @@
+// internal/cache/lock.go
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrLockNotHeld = errors.New("lock not held")
+
+// Only touch the key if it still carries our token, so a lock that expired
+// and was taken over by another owner is never extended or released.
+var (
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Lock is a lease-based distributed lock. The holder must Refresh it before
+// the TTL runs out to keep ownership.
+type Lock struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+	ttl    time.Duration
+}
+
+// TryLock acquires key for owner token without blocking. It returns
+// ErrLockNotHeld if someone else holds the lock.
+func TryLock(ctx context.Context, client redis.UniversalClient, key, token string, ttl time.Duration) (*Lock, error) {
+	ok, err := client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Re-acquiring a lock we already own is not an error
+		current, err := client.Get(ctx, key).Result()
+		if err != nil || current != token {
+			return nil, ErrLockNotHeld
+		}
+	}
+
+	lock := &Lock{client: client, key: key, token: token, ttl: ttl}
+	if !ok {
+		if err := lock.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return lock, nil
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := refreshScript.Run(ctx, l.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) Release(ctx context.Context) error {
+	res, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// internal/cache/redis.go
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+var commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "redis_command_duration_seconds",
+	Help:    "Latency of redis commands and pipelines as seen by the client.",
+	Buckets: prometheus.ExponentialBuckets(0.0001, 2, 14),
+}, []string{"command", "status"})
+
+// Connect returns the shared redis client of this process: a cluster
+// client when cluster addresses are configured, otherwise a client for the
+// single configured server. Either way the pool is sized from config and
+// commands and pool usage are exported as metrics.
+func Connect(cfg config.RedisConfig) (redis.UniversalClient, error) {
+	var client redis.UniversalClient
+	if len(cfg.ClusterAddrs) > 0 {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           cfg.ClusterAddrs,
+			Password:        cfg.Password,
+			PoolSize:        cfg.PoolSize,
+			MinIdleConns:    cfg.MinIdleConns,
+			PoolTimeout:     cfg.PoolTimeout,
+			DialTimeout:     cfg.DialTimeout,
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr:            net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+			Password:        cfg.Password,
+			DB:              cfg.DB,
+			PoolSize:        cfg.PoolSize,
+			MinIdleConns:    cfg.MinIdleConns,
+			PoolTimeout:     cfg.PoolTimeout,
+			DialTimeout:     cfg.DialTimeout,
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		})
+	}
+	client.AddHook(metricsHook{})
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	// A process connects once; a second client keeps the first one's pool
+	// metrics rather than failing
+	err := prometheus.Register(poolCollector{client: client})
+	var already prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &already) {
+		log.Printf("Failed to register redis pool metrics: %v", err)
+	}
+
+	log.Println("Successfully connected to redis")
+
+	return client, nil
+}
+
+// Pipelined sends the commands queued by fn in one round trip. Unlike the
+// client's own Pipelined, a missing key (redis.Nil) in one command is not
+// an error; callers inspect each command's result instead.
+func Pipelined(ctx context.Context, client redis.UniversalClient, fn func(redis.Pipeliner)) ([]redis.Cmder, error) {
+	pipe := client.Pipeline()
+	fn(pipe)
+	cmds, err := pipe.Exec(ctx)
+	if errors.Is(err, redis.Nil) {
+		err = nil
+		for _, cmd := range cmds {
+			if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+				err = cmdErr
+				break
+			}
+		}
+	}
+	return cmds, err
+}
+
+// metricsHook times every command and pipeline.
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		started := time.Now()
+		err := next(ctx, cmd)
+		commandDuration.WithLabelValues(cmd.Name(), commandStatus(err)).Observe(time.Since(started).Seconds())
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		started := time.Now()
+		err := next(ctx, cmds)
+		commandDuration.WithLabelValues("pipeline", commandStatus(err)).Observe(time.Since(started).Seconds())
+		return err
+	}
+}
+
+func commandStatus(err error) string {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "error"
+	}
+	return "ok"
+}
+
+var (
+	poolConnsDesc = prometheus.NewDesc("redis_pool_connections",
+		"Connections in the redis client pool by state.", []string{"state"}, nil)
+	poolEventsDesc = prometheus.NewDesc("redis_pool_events_total",
+		"Redis client pool lookups by outcome.", []string{"event"}, nil)
+)
+
+// poolCollector reads the pool statistics at scrape time.
+type poolCollector struct {
+	client redis.UniversalClient
+}
+
+func (p poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnsDesc
+	ch <- poolEventsDesc
+}
+
+func (p poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := p.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stats.TotalConns), "total")
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(poolEventsDesc, prometheus.CounterValue, float64(stats.Hits), "hit")
+	ch <- prometheus.MustNewConstMetric(poolEventsDesc, prometheus.CounterValue, float64(stats.Misses), "miss")
+	ch <- prometheus.MustNewConstMetric(poolEventsDesc, prometheus.CounterValue, float64(stats.Timeouts), "timeout")
+	ch <- prometheus.MustNewConstMetric(poolEventsDesc, prometheus.CounterValue, float64(stats.StaleConns), "stale")
+}
@@ -0,0 +1,187 @@
+// internal/cache/tokens.go
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"google.golang.org/protobuf/proto"
+)
+
+var tokenCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_token_cache_requests_total",
+	Help: "Token validations answered from the cache, by result.",
+}, []string{"result"})
+
+// TokenCache holds positive token validations for a short TTL so the
+// gateway does not call the auth service on every request. Entries are
+// keyed by a hash of the token, never the token itself. Revoking a token
+// both drops its entry and blacklists its ID (the JWT jti) until it would
+// have expired, so a validation racing with the revocation cannot cache it
+// again.
+type TokenCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewTokenCache(client redis.UniversalClient, ttl time.Duration) *TokenCache {
+	return &TokenCache{client: client, ttl: ttl}
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenKey(hash string) string { return "auth:token:" + hash }
+func revokedKey(id string) string { return "auth:revoked:" + id }
+func sessionKey(id string) string { return "auth:session:" + id }
+
+// userTokensKey holds the keys of every cached validation of the user
+func userTokensKey(userID string) string {
+	return "auth:user-tokens:" + userID
+}
+
+// Get returns the cached user of a valid token with the given ID and
+// expiry. Expired tokens and errors count as misses.
+func (c *TokenCache) Get(ctx context.Context, token, id string, expiresAt time.Time) (*authpb.User, bool) {
+	if !time.Now().Before(expiresAt) {
+		tokenCacheRequests.WithLabelValues("expired").Inc()
+		return nil, false
+	}
+	// The keys live on different cluster slots, so a pipeline of GETs
+	// rather than an MGET
+	var cached, revoked *redis.StringCmd
+	_, err := Pipelined(ctx, c.client, func(pipe redis.Pipeliner) {
+		cached = pipe.Get(ctx, tokenKey(tokenHash(token)))
+		revoked = pipe.Get(ctx, revokedKey(id))
+	})
+	if err != nil {
+		tokenCacheRequests.WithLabelValues("error").Inc()
+		return nil, false
+	}
+	return c.decode(cached, revoked)
+}
+
+// GetSession returns the cached user of a session, for a token whose
+// signature was verified by the caller and whose ID and expiry are given.
+// Access tokens of a session share its entry, so refreshing one does not
+// cost a validation.
+func (c *TokenCache) GetSession(ctx context.Context, sessionID, id string, expiresAt time.Time) (*authpb.User, bool) {
+	if !time.Now().Before(expiresAt) {
+		tokenCacheRequests.WithLabelValues("expired").Inc()
+		return nil, false
+	}
+	var cached, revoked *redis.StringCmd
+	_, err := Pipelined(ctx, c.client, func(pipe redis.Pipeliner) {
+		cached = pipe.Get(ctx, sessionKey(sessionID))
+		revoked = pipe.Get(ctx, revokedKey(id))
+	})
+	if err != nil {
+		tokenCacheRequests.WithLabelValues("error").Inc()
+		return nil, false
+	}
+	return c.decode(cached, revoked)
+}
+
+func (c *TokenCache) decode(cached, revoked *redis.StringCmd) (*authpb.User, bool) {
+	if revoked.Err() == nil {
+		tokenCacheRequests.WithLabelValues("revoked").Inc()
+		return nil, false
+	}
+	data, err := cached.Bytes()
+	if err != nil {
+		tokenCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	var user authpb.User
+	if err := proto.Unmarshal(data, &user); err != nil {
+		tokenCacheRequests.WithLabelValues("error").Inc()
+		return nil, false
+	}
+	tokenCacheRequests.WithLabelValues("hit").Inc()
+	return &user, true
+}
+
+// Set caches a positive validation of a token expiring at expiresAt. The
+// entry never outlives the token.
+func (c *TokenCache) Set(ctx context.Context, token string, user *authpb.User, expiresAt time.Time) {
+	ttl := min(c.ttl, time.Until(expiresAt))
+	if ttl <= 0 {
+		return
+	}
+	data, err := proto.Marshal(user)
+	if err != nil {
+		return
+	}
+
+	c.set(ctx, tokenKey(tokenHash(token)), user.Id, data, ttl)
+}
+
+// SetSession caches a positive validation of a token of the session.
+func (c *TokenCache) SetSession(ctx context.Context, sessionID string, user *authpb.User) {
+	data, err := proto.Marshal(user)
+	if err != nil {
+		return
+	}
+	c.set(ctx, sessionKey(sessionID), user.Id, data, c.ttl)
+}
+
+func (c *TokenCache) set(ctx context.Context, key, userID string, data []byte, ttl time.Duration) {
+	_, err := Pipelined(ctx, c.client, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, key, data, ttl)
+		pipe.SAdd(ctx, userTokensKey(userID), key)
+		pipe.Expire(ctx, userTokensKey(userID), c.ttl)
+	})
+	if err != nil {
+		log.Printf("Failed to cache token validation: %v", err)
+	}
+}
+
+// Revoked reports whether the token ID was blacklisted.
+func (c *TokenCache) Revoked(ctx context.Context, id string) (bool, error) {
+	n, err := c.client.Exists(ctx, revokedKey(id)).Result()
+	return n > 0, err
+}
+
+// Revoke blacklists the token's ID for the rest of its lifetime and drops
+// its cached validation.
+func (c *TokenCache) Revoke(ctx context.Context, token, id string, remaining time.Duration) error {
+	if remaining <= 0 {
+		// Expired tokens are rejected anyway
+		return c.client.Del(ctx, tokenKey(tokenHash(token))).Err()
+	}
+	// The keys may live on different cluster slots, so this is an ordered
+	// pipeline rather than a transaction; the marker is written first
+	_, err := Pipelined(ctx, c.client, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, revokedKey(id), 1, remaining)
+		pipe.Del(ctx, tokenKey(tokenHash(token)))
+	})
+	return err
+}
+
+// InvalidateUser drops every cached validation of the user, so changes to
+// the user are seen on the next request.
+func (c *TokenCache) InvalidateUser(ctx context.Context, userID string) error {
+	keys, err := c.client.SMembers(ctx, userTokensKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	// One DEL per key, since a multi-key DEL must stay within one slot
+	_, err = Pipelined(ctx, c.client, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, userTokensKey(userID))
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+	})
+	return err
+}
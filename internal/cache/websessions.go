@@ -0,0 +1,80 @@
+// internal/cache/websessions.go
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrWebSessionNotFound = errors.New("web session not found")
+
+// WebSession is a browser signed in with a session cookie. The gateway
+// keeps the session's tokens here so scripts on the page never see them.
+type WebSession struct {
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// AccessExpiresAt is when the access token has to be refreshed by
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	// CSRFToken must accompany the session's state changing requests
+	CSRFToken string `json:"csrf_token"`
+}
+
+// WebSessionStore keeps web sessions in redis, keyed by a hash of the ID
+// the cookie carries. Sessions expire after ttl without use.
+type WebSessionStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewWebSessionStore(client redis.UniversalClient, ttl time.Duration) *WebSessionStore {
+	return &WebSessionStore{client: client, ttl: ttl}
+}
+
+func webSessionKey(id string) string { return "auth:web-session:" + tokenHash(id) }
+
+// Save stores the session under id, restarting its expiry.
+func (s *WebSessionStore) Save(ctx context.Context, id string, session *WebSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, webSessionKey(id), data, s.ttl).Err()
+}
+
+// Get returns the session, or ErrWebSessionNotFound once it expired or
+// was deleted.
+func (s *WebSessionStore) Get(ctx context.Context, id string) (*WebSession, error) {
+	data, err := s.client.Get(ctx, webSessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrWebSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session WebSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch restarts the session's expiry.
+func (s *WebSessionStore) Touch(ctx context.Context, id string) error {
+	return s.client.Expire(ctx, webSessionKey(id), s.ttl).Err()
+}
+
+func (s *WebSessionStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, webSessionKey(id)).Err()
+}
+
+// RefreshLock keeps the session's other requests from refreshing its
+// tokens at the same time; refresh tokens are single use.
+func (s *WebSessionStore) RefreshLock(ctx context.Context, id, owner string, ttl time.Duration) (*Lock, error) {
+	return TryLock(ctx, s.client, webSessionKey(id)+":refresh", owner, ttl)
+}
@@ -5,23 +5,112 @@ import (
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/tradingbothub/platform/pkg/objectstore"
+	"github.com/tradingbothub/platform/pkg/retry"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	NATS     NATSConfig     `mapstructure:"nats"`
-	InfluxDB InfluxConfig   `mapstructure:"influxdb"`
+	Region    string          `mapstructure:"region"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	NATS      NATSConfig      `mapstructure:"nats"`
+	InfluxDB  InfluxConfig    `mapstructure:"influxdb"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+
+	RequestLimits RequestLimitsConfig     `mapstructure:"request_limits"`
+	LoadShedding  LoadSheddingConfig      `mapstructure:"load_shedding"`
+	RequestLanes  RequestLanesConfig      `mapstructure:"request_lanes"`
+	Canary        map[string]CanaryConfig `mapstructure:"canary"`
+
+	Exchanges map[string]ExchangeConfig `mapstructure:"exchanges"`
+	Scheduler SchedulerConfig           `mapstructure:"scheduler"`
+	Trading   TradingConfig             `mapstructure:"trading"`
+	Equity    EquityConfig              `mapstructure:"equity"`
+	Portfolio PortfolioConfig           `mapstructure:"portfolio"`
+	Retention RetentionConfig           `mapstructure:"retention"`
+	Approvals ApprovalsConfig           `mapstructure:"approvals"`
+	Share     ShareConfig               `mapstructure:"share"`
+	Orgs      OrgsConfig                `mapstructure:"organizations"`
+
+	DataResidency DataResidencyConfig `mapstructure:"data_residency"`
+	Demo          DemoConfig          `mapstructure:"demo"`
+	Email         EmailConfig         `mapstructure:"email"`
+	Registry      RegistryConfig      `mapstructure:"registry"`
+	Streaming     StreamingConfig     `mapstructure:"streaming"`
+	BotRuntime    BotRuntimeConfig    `mapstructure:"bot_runtime"`
+	PaperExchange PaperExchangeConfig `mapstructure:"paper_exchange"`
+	Faults        FaultsConfig        `mapstructure:"faults"`
+	ObjectStore   objectstore.Config  `mapstructure:"object_store"`
+	Avatars       AvatarConfig        `mapstructure:"avatars"`
+	Metering      MeteringConfig      `mapstructure:"metering"`
+	CopyTrading   CopyTradingConfig   `mapstructure:"copy_trading"`
+	CandleCache   CandleCacheConfig   `mapstructure:"candle_cache"`
+	Backtest      BacktestConfig      `mapstructure:"backtest"`
+	Billing       BillingConfig       `mapstructure:"billing"`
+	WriteBatching WriteBatchConfig    `mapstructure:"write_batching"`
+	OrderBooks    OrderBookConfig     `mapstructure:"order_books"`
+	GRPC          GRPCConfig          `mapstructure:"grpc"`
+	Warmup        WarmupConfig        `mapstructure:"warmup"`
 }
 
 type ServerConfig struct {
-	Port         string        `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port            string        `mapstructure:"port"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	DrainDelay      time.Duration `mapstructure:"drain_delay"`
+	// ReadHeaderTimeout bounds reading request headers, so slow clients
+	// cannot hold connections open before sending a request
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+	// TCPKeepAlive is the keep-alive probe period of accepted connections
+	TCPKeepAlive time.Duration     `mapstructure:"tcp_keep_alive"`
+	HTTP2        HTTP2ServerConfig `mapstructure:"http2"`
+	Proxies      ProxyConfig       `mapstructure:"proxies"`
+	Timeouts     TimeoutConfig     `mapstructure:"timeouts"`
+}
+
+// TimeoutConfig bounds how long the gateway works on a request before
+// answering 504. Streaming requests are never cut off.
+type TimeoutConfig struct {
+	Default time.Duration `mapstructure:"default"`
+	// Routes overrides the default by "METHOD /route" as registered, e.g.
+	// "POST /api/v1/bots/:id/sweep"; zero disables the timeout
+	Routes map[string]time.Duration `mapstructure:"routes"`
+}
+
+// ProxyConfig says which forwarding headers to believe for the client IP.
+type ProxyConfig struct {
+	// TrustedProxies are IPs or CIDRs of the load balancers in front of
+	// the gateway; headers from any other peer are ignored
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// Headers are checked in order for the client IP
+	Headers []string `mapstructure:"headers"`
+	// Platform trusts a CDN's client IP header from any peer: "cloudflare"
+	// or "google-app-engine". Only for gateways reachable solely through it.
+	Platform string `mapstructure:"platform"`
+	// CountryHeader is the header the proxy or CDN puts the client's ISO
+	// country code in, e.g. CF-IPCountry; logins record it. Empty leaves
+	// the country unknown.
+	CountryHeader string `mapstructure:"country_header"`
+}
+
+// HTTP2ServerConfig tunes HTTP/2 on the gateway's listener.
+type HTTP2ServerConfig struct {
+	// H2C serves HTTP/2 without TLS. Only enable it when the listener is
+	// reachable solely through a trusted proxy or load balancer that
+	// ends TLS; never on a publicly exposed port
+	H2C                  bool `mapstructure:"h2c"`
+	MaxConcurrentStreams int  `mapstructure:"max_concurrent_streams"`
+	// ReadIdleTimeout is how long a connection may be silent before the
+	// server pings it; unanswered pings close it after PingTimeout
+	ReadIdleTimeout time.Duration `mapstructure:"read_idle_timeout"`
+	PingTimeout     time.Duration `mapstructure:"ping_timeout"`
 }
 
 type DatabaseConfig struct {
@@ -39,19 +128,631 @@ type RedisConfig struct {
 	Port     int    `mapstructure:"port"`
 	Password string `mapstructure:"password"`
 	DB       int    `mapstructure:"db"`
+	// ClusterAddrs switches to a redis cluster client; Host, Port and DB
+	// are ignored then
+	ClusterAddrs []string `mapstructure:"cluster_addrs"`
+
+	PoolSize        int           `mapstructure:"pool_size"`
+	MinIdleConns    int           `mapstructure:"min_idle_conns"`
+	PoolTimeout     time.Duration `mapstructure:"pool_timeout"`
+	DialTimeout     time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
 }
 
 type JWTConfig struct {
+	// Secret signs HS256 tokens while no SigningKey is set
 	Secret         string        `mapstructure:"secret"`
 	ExpirationTime time.Duration `mapstructure:"expiration_time"`
+	// SigningKey is the ID of the key in Keys that signs new tokens
+	SigningKey string `mapstructure:"signing_key"`
+	// Keys verify tokens by key ID and are published in the JWKS; keep a
+	// rotated-out key until the tokens it signed have expired
+	Keys []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig is an RSA (RS256) or Ed25519 (EdDSA) private key in PEM.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
 }
 
 type AuthConfig struct {
 	Port string `mapstructure:"port"`
+	// TokenCacheTTL is how long the gateway trusts a positive token
+	// validation without asking the auth service again
+	TokenCacheTTL time.Duration `mapstructure:"token_cache_ttl"`
+	// RefreshTokenPurgeSchedule is how often the scheduler deletes expired
+	// refresh tokens, as a scheduler spec
+	RefreshTokenPurgeSchedule string `mapstructure:"refresh_token_purge_schedule"`
+	// JWKSPort serves the public keys at /.well-known/jwks.json
+	JWKSPort string `mapstructure:"jwks_port"`
+	// JWKSRefreshInterval is how often the gateway fetches the public
+	// keys it verifies token signatures with
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval"`
+	// ReauthWindow is how recently a session must have signed in to add
+	// or remove sign-in methods
+	ReauthWindow time.Duration `mapstructure:"reauth_window"`
+	// Admins are the emails of users granted the admin role when the auth
+	// service starts, so a fresh deployment has someone to assign roles
+	Admins []string `mapstructure:"admins"`
+	// ServiceKey authenticates the platform's services to the auth RPCs
+	// that act without a user, such as billing's plan changes; empty
+	// refuses those RPCs
+	ServiceKey string `mapstructure:"service_key"`
+
+	EmailVerification EmailVerificationConfig `mapstructure:"email_verification"`
+	PasswordReset     PasswordResetConfig     `mapstructure:"password_reset"`
+	EmailChange       EmailChangeConfig       `mapstructure:"email_change"`
+	PasswordHashing   PasswordHashingConfig   `mapstructure:"password_hashing"`
+	MagicLink         MagicLinkConfig         `mapstructure:"magic_link"`
+	SSO               SSOConfig               `mapstructure:"sso"`
+	ExistenceFilter   ExistenceFilterConfig   `mapstructure:"existence_filter"`
+	Lockout           LockoutConfig           `mapstructure:"lockout"`
+	LoginAlerts       LoginAlertsConfig       `mapstructure:"login_alerts"`
+	CookieSessions    CookieSessionsConfig    `mapstructure:"cookie_sessions"`
+}
+
+// EmailVerificationConfig controls confirmation of new accounts' email
+// addresses.
+type EmailVerificationConfig struct {
+	// Required blocks bot creation until the address is verified
+	Required bool          `mapstructure:"required"`
+	TTL      time.Duration `mapstructure:"ttl"`
+	// URL is the page the emailed link opens; the token is appended as
+	// the "token" query parameter
+	URL string `mapstructure:"url"`
+}
+
+// PasswordResetConfig controls the emailed links that reset forgotten
+// passwords.
+type PasswordResetConfig struct {
+	TTL time.Duration `mapstructure:"ttl"`
+	// URL is the page the emailed link opens; the token is appended as
+	// the "token" query parameter
+	URL string `mapstructure:"url"`
+}
+
+// EmailChangeConfig controls moving accounts to another email address. The
+// new address confirms the change; the old one is told and can undo it.
+type EmailChangeConfig struct {
+	// TTL is how long the link sent to the new address confirms the change
+	TTL time.Duration `mapstructure:"ttl"`
+	// URL is the page the confirmation link opens; the token is appended
+	// as the "token" query parameter
+	URL string `mapstructure:"url"`
+	// UndoTTL is how long the link sent to the old address cancels the
+	// change, or reverts it once confirmed
+	UndoTTL time.Duration `mapstructure:"undo_ttl"`
+	UndoURL string        `mapstructure:"undo_url"`
+}
+
+// PasswordHashingConfig sets the argon2id parameters new password hashes
+// use. Hashes made with other parameters, or with bcrypt, still verify and
+// are replaced at the user's next login.
+type PasswordHashingConfig struct {
+	// MemoryKiB is the memory each hash takes; logins in flight at once
+	// need it each
+	MemoryKiB   uint32 `mapstructure:"memory_kib"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	// MaxConcurrent bounds how many passwords are hashed or verified at
+	// once, and so the memory hashing takes
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+}
+
+// MagicLinkConfig controls the emailed one-time links that sign users in
+// without a password.
+type MagicLinkConfig struct {
+	TTL time.Duration `mapstructure:"ttl"`
+	// URL is the page the emailed link opens; the token is appended as
+	// the "token" query parameter
+	URL string `mapstructure:"url"`
+}
+
+// SSOConfig controls sign-in through organizations' identity providers.
+// The providers themselves are configured per email domain by each
+// organization's admins.
+type SSOConfig struct {
+	// RedirectURL is the page providers send users back to; it posts the
+	// code and state to /api/v1/auth/sso/callback. Every provider must
+	// allow it as a redirect URI.
+	RedirectURL string `mapstructure:"redirect_url"`
+	// StateTTL is how long a user has to sign in at the provider, and the
+	// lifetime of the cookie that ties the login to their browser
+	StateTTL time.Duration `mapstructure:"state_ttl"`
+	// HTTPTimeout bounds each request to a provider
+	HTTPTimeout time.Duration `mapstructure:"http_timeout"`
+	// CacheTTL is how long providers' discovery documents and keys are
+	// reused; unknown keys are fetched right away regardless
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// ExistenceFilterConfig sizes the redis bloom filters that tell whether an
+// email or username is taken without querying the users table.
+type ExistenceFilterConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ExpectedUsers is the number of accounts the filters are sized for;
+	// beyond it false positives, each costing a query, become more common
+	ExpectedUsers     uint64  `mapstructure:"expected_users"`
+	FalsePositiveRate float64 `mapstructure:"false_positive_rate"`
+	// RebuildSchedule is how often the scheduler rebuilds the filters from
+	// the users table, dropping deleted and changed values
+	RebuildSchedule string `mapstructure:"rebuild_schedule"`
+}
+
+// LockoutConfig rejects logins for a while after repeated failures, per
+// account and per client IP.
+type LockoutConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxFailures failed logins to one account within Window lock it
+	MaxFailures int `mapstructure:"max_failures"`
+	// IPMaxFailures failed logins from one IP within Window lock the IP
+	// out; it is higher because many users may share an address
+	IPMaxFailures int           `mapstructure:"ip_max_failures"`
+	Window        time.Duration `mapstructure:"window"`
+	// Duration is how long a lockout lasts
+	Duration time.Duration `mapstructure:"duration"`
+}
+
+// LoginAlertsConfig controls what happens when a user signs in from a
+// country or device their login history has not seen before.
+type LoginAlertsConfig struct {
+	// Notify emails the user about such logins
+	Notify bool `mapstructure:"notify"`
+	// StepUp holds back such password logins until the user confirms them
+	// through an emailed sign-in link
+	StepUp bool `mapstructure:"step_up"`
+}
+
+// CookieSessionsConfig lets browser clients sign in with an httpOnly
+// session cookie instead of bearer tokens. The gateway keeps the tokens in
+// redis; the cookie only carries the encrypted session ID.
+type CookieSessionsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Clients are the client types, sent in the X-Client-Type header when
+	// signing in, that get a cookie; others keep getting tokens
+	Clients []string `mapstructure:"clients"`
+	// Key encrypts the cookie: 16, 24 or 32 bytes, base64 encoded
+	Key        string `mapstructure:"key"`
+	CookieName string `mapstructure:"cookie_name"`
+	Domain     string `mapstructure:"domain"`
+	// Secure only sends the cookie, and the single sign-on binding cookie,
+	// over HTTPS; disable for local development
+	Secure bool `mapstructure:"secure"`
+	// TTL is how long an unused session lasts; keep it within the refresh
+	// token lifetime
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// EmailConfig selects how transactional email is sent. The "log" backend
+// prints messages instead of sending them.
+type EmailConfig struct {
+	Backend      string `mapstructure:"backend"`
+	From         string `mapstructure:"from"`
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     int    `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+	// Retry resends messages the relay failed to accept for a transient
+	// reason
+	Retry retry.Config `mapstructure:"retry"`
+}
+
+type TradingConfig struct {
+	// Mode is "paper" for the simulated exchanges of the paper exchange
+	// service or "live"
+	Mode            string `mapstructure:"mode"`
+	BulkConcurrency int    `mapstructure:"bulk_concurrency"`
+	// BotOrderRate is the order rate policy of bots without their own
+	BotOrderRate BotOrderRateConfig `mapstructure:"bot_order_rate"`
+	// GroupSyncInterval is how often OCO, bracket and if-then groups are
+	// checked for fills and trigger prices
+	GroupSyncInterval time.Duration `mapstructure:"group_sync_interval"`
+	// FillSyncInterval is how often the fills of resting orders are
+	// copied into order history
+	FillSyncInterval time.Duration `mapstructure:"fill_sync_interval"`
+	// ExchangeRetry repeats failed exchange reads, cancels and
+	// placements with a client order ID
+	ExchangeRetry retry.Config `mapstructure:"exchange_retry"`
+	// Chaos lets users make their paper accounts misbehave; it has no
+	// effect in live mode
+	Chaos PaperChaosConfig `mapstructure:"chaos"`
+	// ConnectorTimeout bounds each call to an exchange connector instance
+	ConnectorTimeout time.Duration `mapstructure:"connector_timeout"`
+	// ConnectorProbeInterval is how often connector instances are checked
+	// for health and latency
+	ConnectorProbeInterval time.Duration `mapstructure:"connector_probe_interval"`
+}
+
+// PaperChaosConfig lets users make their paper accounts fail the way real
+// exchanges do, so they can check their strategies before risking
+// capital. Each user picks the rates of their own accounts.
+type PaperChaosConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// AckDelay is how late a delayed placement is acknowledged
+	AckDelay time.Duration `mapstructure:"ack_delay"`
+	// Exchanges limits chaos to these exchanges; empty allows all
+	Exchanges []string `mapstructure:"exchanges"`
+}
+
+type BotOrderRateConfig struct {
+	OrdersPerMinute  int           `mapstructure:"orders_per_minute"`
+	CancelsPerMinute int           `mapstructure:"cancels_per_minute"`
+	Burst            int           `mapstructure:"burst"`
+	MaxWait          time.Duration `mapstructure:"max_wait"`
+}
+
+type EquityConfig struct {
+	// SnapshotSchedule is the cadence of equity snapshots as a scheduler
+	// spec, e.g. "@every 1m"
+	SnapshotSchedule string `mapstructure:"snapshot_schedule"`
+}
+
+// PortfolioConfig schedules the jobs that maintain portfolio holdings, as
+// scheduler specs.
+type PortfolioConfig struct {
+	// ValuationSchedule is how often open holdings are marked to market
+	ValuationSchedule string `mapstructure:"valuation_schedule"`
+	// CheckSchedule is how often holdings are checked against trade
+	// history
+	CheckSchedule string `mapstructure:"check_schedule"`
+}
+
+// OrgsConfig controls invitations to organizations.
+type OrgsConfig struct {
+	// InvitationTTL is how long an invitation can be accepted
+	InvitationTTL time.Duration `mapstructure:"invitation_ttl"`
+	// InvitationURL is the page emailed invitations open; the invitation
+	// ID is appended as the "invitation" query parameter
+	InvitationURL string `mapstructure:"invitation_url"`
+}
+
+type ApprovalsConfig struct {
+	// Organizations maps organization IDs to the user IDs of their admins
+	Organizations map[string][]string `mapstructure:"organizations"`
+	// TTL is how long a request waits for a second admin
+	TTL time.Duration `mapstructure:"ttl"`
+	// LiveBotCapital is the bot capital from which starting a live bot
+	// needs approval
+	LiveBotCapital float64 `mapstructure:"live_bot_capital"`
+}
+
+// AvatarConfig limits profile picture uploads. Avatars are stored in the
+// object store, cropped square and scaled down to Size.
+type AvatarConfig struct {
+	// MaxBytes caps the uploaded file
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// MaxPixels caps width times height, so small files cannot decode
+	// into huge images
+	MaxPixels int `mapstructure:"max_pixels"`
+	// Size is the width and height of the stored avatar
+	Size int `mapstructure:"size"`
+	// URLTTL is how long signed avatar URLs stay valid
+	URLTTL time.Duration `mapstructure:"url_ttl"`
+}
+
+type ShareConfig struct {
+	// Secret signs public share link tokens
+	Secret string `mapstructure:"secret"`
+	// MaxTTL caps how long a share link may stay valid
+	MaxTTL time.Duration `mapstructure:"max_ttl"`
+}
+
+// DemoConfig controls the shared demo account behind the API docs
+// "Try it out" buttons.
+type DemoConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Email    string `mapstructure:"email"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Balance funds the demo user's paper wallet on every exchange
+	Balance float64 `mapstructure:"balance"`
+	// Requests per Window across everyone sharing the demo account
+	Requests int           `mapstructure:"requests"`
+	Window   time.Duration `mapstructure:"window"`
+	// ResetSchedule wipes the demo account's data, e.g. "@daily"
+	ResetSchedule string `mapstructure:"reset_schedule"`
+}
+
+type DataResidencyConfig struct {
+	// DefaultRegion stores the data of users who have not picked a region
+	DefaultRegion string                      `mapstructure:"default_region"`
+	Regions       map[string]DataRegionConfig `mapstructure:"regions"`
+}
+
+type DataRegionConfig struct {
+	// DatabaseURL of the regional trade database; empty uses the primary
+	// database
+	DatabaseURL string `mapstructure:"database_url"`
+	// ReplicaURL of a read replica of that database, which serves trade
+	// history, portfolio and export reads; empty reads from the database
+	// itself
+	ReplicaURL string `mapstructure:"replica_url"`
+}
+
+type RetentionConfig struct {
+	// Schedule is the scheduler spec of the purge job
+	Schedule string `mapstructure:"schedule"`
+	// TrashTTL is how long deleted bots and strategies stay restorable
+	TrashTTL time.Duration `mapstructure:"trash_ttl"`
+	// AuditTTL is how long security events are kept. Zero keeps them
+	// forever.
+	AuditTTL time.Duration `mapstructure:"audit_ttl"`
+}
+
+type SchedulerConfig struct {
+	Port         string        `mapstructure:"port"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+type BotRuntimeConfig struct {
+	Port string `mapstructure:"port"`
+	// PollInterval is how often running bots look for newly closed candles
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// CheckpointInterval bounds how much bot state a crash can lose
+	CheckpointInterval time.Duration `mapstructure:"checkpoint_interval"`
+	// HeartbeatInterval is how often replicas rebalance bots between them
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+}
+
+// PaperExchangeConfig runs the simulated exchanges every service trades on
+// in paper mode, as one connector instance.
+type PaperExchangeConfig struct {
+	Port string `mapstructure:"port"`
+	// PriceInterval is how often marks are taken from the latest candles
+	PriceInterval time.Duration `mapstructure:"price_interval"`
+}
+
+// GRPCConfig applies to the internal gRPC servers and the clients calling
+// them.
+type GRPCConfig struct {
+	MaxRecvMsgSize int `mapstructure:"max_recv_msg_size"`
+	MaxSendMsgSize int `mapstructure:"max_send_msg_size"`
+	// Compression maps a backend service to the compressor its calls use,
+	// "gzip" or "zstd"; services that are not listed are not compressed
+	Compression map[string]string `mapstructure:"compression"`
+	// DeadlineMargin is taken off the caller's deadline for unary calls,
+	// so the caller still has time to report which backend timed out
+	DeadlineMargin time.Duration `mapstructure:"deadline_margin"`
+	// Retry repeats unary calls that failed because the backend was
+	// unavailable
+	Retry retry.Config `mapstructure:"retry"`
+}
+
+// FaultsConfig injects failures into gRPC calls, connector requests and
+// NATS consumers for resilience testing. It is refused in live trading mode.
+type FaultsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Latency is added to every affected call or delivery, plus a random
+	// amount up to Jitter
+	Latency time.Duration `mapstructure:"latency"`
+	Jitter  time.Duration `mapstructure:"jitter"`
+	// ErrorRate is the fraction of gRPC calls and connector requests failed
+	// with Unavailable
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// DropRate is the fraction of NATS messages discarded before delivery
+	DropRate float64 `mapstructure:"drop_rate"`
+	// Targets limits injection to these gRPC methods, connector paths or
+	// NATS subjects; entries ending in "*" match by prefix. Empty affects
+	// everything.
+	Targets []string `mapstructure:"targets"`
+}
+
+// MeteringConfig controls per-user API usage accounting in the gateway.
+type MeteringConfig struct {
+	// Retention is how long hourly usage is kept and the longest window
+	// the usage endpoint reports
+	Retention time.Duration `mapstructure:"retention"`
+	// BufferSize is how many events may queue before new ones are dropped
+	BufferSize int `mapstructure:"buffer_size"`
+}
+
+// CandleCacheConfig sizes the in-memory candle cache shared by backtests
+// and bot previews. A zero MaxBytes disables it.
+type CandleCacheConfig struct {
+	MaxBytes int64 `mapstructure:"max_bytes"`
+}
+
+// BacktestConfig bounds parameter sweeps and locates the backtest
+// service, which streams single runs.
+type BacktestConfig struct {
+	Port string `mapstructure:"port"`
+	// Workers is the size of the sweep worker pool; zero uses every CPU
+	Workers int `mapstructure:"workers"`
+	MaxRuns int `mapstructure:"max_runs"`
+	// Slots is how many sweeps each backtest service replica runs at
+	// once; more wait in the queue
+	Slots int `mapstructure:"slots"`
+	// Tiers schedules the sweeps of each plan; plans without one are
+	// scheduled like "free"
+	Tiers map[string]BacktestTierConfig `mapstructure:"tiers"`
+	// JobRetention is how long finished sweeps and their results are kept
+	JobRetention time.Duration `mapstructure:"job_retention"`
+	// JobLease is how long a replica holds a sweep without renewing it;
+	// sweeps of replicas that died go back to the queue after it
+	JobLease time.Duration `mapstructure:"job_lease"`
+	// PollInterval is how often replicas look for waiting sweeps
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// BacktestTierConfig is how the sweeps of a plan are queued.
+type BacktestTierConfig struct {
+	// Weight is the plan's share of the slots against other plans
+	Weight int `mapstructure:"weight"`
+	// MaxConcurrent and MaxQueued cap each user's running and waiting
+	// sweeps; zero leaves them uncapped
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	MaxQueued     int `mapstructure:"max_queued"`
+	// PreemptAfter sends sweeps running this long back to the queue when
+	// others wait; zero never preempts
+	PreemptAfter time.Duration `mapstructure:"preempt_after"`
+}
+
+// BillingConfig prices the subscription plans and schedules invoicing and
+// the dunning of failed payments.
+type BillingConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Currency string `mapstructure:"currency"`
+	// Plans prices each paid plan; users on plans without one, such as
+	// "free", are not invoiced
+	Plans map[string]BillingPlanConfig `mapstructure:"plans"`
+	// UsageSchedule copies metered API usage into daily totals, which
+	// outlive the metering retention
+	UsageSchedule string `mapstructure:"usage_schedule"`
+	// InvoiceSchedule invoices the previous calendar month
+	InvoiceSchedule string `mapstructure:"invoice_schedule"`
+	// PaymentTerms is how long after issue an invoice is due
+	PaymentTerms time.Duration `mapstructure:"payment_terms"`
+	Dunning      DunningConfig `mapstructure:"dunning"`
+}
+
+// BillingPlanConfig is what a plan costs per month. Prices are decimal
+// strings, so no float ever touches them.
+type BillingPlanConfig struct {
+	Price string `mapstructure:"price"`
+	// IncludedRequests are the API requests a month covered by the price
+	IncludedRequests int64 `mapstructure:"included_requests"`
+	// OveragePrice is charged per started 1,000 requests above
+	// IncludedRequests; empty charges nothing
+	OveragePrice string `mapstructure:"overage_price"`
+}
+
+// DunningConfig paces the reminders about an unpaid invoice after its
+// payment failed, and when the user is moved to the free plan.
+type DunningConfig struct {
+	Schedule string `mapstructure:"schedule"`
+	// Reminders are emailed this long after the first failed payment
+	Reminders []time.Duration `mapstructure:"reminders"`
+	// DowngradeAfter moves users still owing this long after the first
+	// failed payment to the free plan until they pay
+	DowngradeAfter time.Duration `mapstructure:"downgrade_after"`
+	// URL is the billing page linked from the emails
+	URL string `mapstructure:"url"`
+}
+
+// WriteBatchConfig tunes the buffered writers used for bursty inserts
+// such as fills and candles.
+type WriteBatchConfig struct {
+	// MaxSize flushes a batch as soon as it holds this many items
+	MaxSize       int           `mapstructure:"max_size"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	// BufferSize bounds the items waiting to be written; adds block once
+	// it is full
+	BufferSize int `mapstructure:"buffer_size"`
+	// RetryBackoff is the first wait after a failed write; it doubles on
+	// each further failure
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+}
+
+// OrderBookConfig sizes the in-memory order book store.
+type OrderBookConfig struct {
+	// Shards spreads books over independently locked writer shards
+	Shards int `mapstructure:"shards"`
+	// MaxDepth caps the price levels kept per side; zero keeps all
+	MaxDepth int `mapstructure:"max_depth"`
+}
+
+// WarmupConfig paces the warm-up tasks a service runs at startup before it
+// reports itself ready.
+type WarmupConfig struct {
+	// Timeout bounds one attempt of a task
+	Timeout time.Duration `mapstructure:"timeout"`
+	// RetryInterval is the pause before a failed required task is retried
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+	// MaxWait is how long readiness waits for required tasks before the
+	// service reports ready anyway; zero waits for good
+	MaxWait time.Duration `mapstructure:"max_wait"`
+}
+
+// CopyTradingConfig bounds follower allocations and drives dynamic
+// multipliers.
+type CopyTradingConfig struct {
+	// MaxMultiplier caps any follow's capital multiplier
+	MaxMultiplier float64 `mapstructure:"max_multiplier"`
+	// AllocationSchedule is the cadence of dynamic multiplier adjustments
+	AllocationSchedule string `mapstructure:"allocation_schedule"`
+	// AllocationWindow is the span of leader performance evaluated
+	AllocationWindow time.Duration `mapstructure:"allocation_window"`
+	// AllocationStep is the fraction a multiplier moves per adjustment
+	AllocationStep float64 `mapstructure:"allocation_step"`
+	// DrawdownLimit reduces allocation whenever the leader's drawdown in
+	// the window exceeds it
+	DrawdownLimit float64 `mapstructure:"drawdown_limit"`
+	// Abuse tunes wash-trade and churn detection on leader accounts
+	Abuse CopyAbuseConfig `mapstructure:"abuse"`
+	// LeaderboardWindow is the span of performance the leaderboard ranks
+	LeaderboardWindow time.Duration `mapstructure:"leaderboard_window"`
+	// PerformanceFee is the share of follower profit above the high-water
+	// mark charged for new follows
+	PerformanceFee float64 `mapstructure:"performance_fee"`
+	// PlatformCommission is the platform's share of each performance fee;
+	// the rest is paid out to the leader
+	PlatformCommission float64 `mapstructure:"platform_commission"`
+	SettlementSchedule string  `mapstructure:"settlement_schedule"`
+	// MirrorConcurrency bounds the follower orders placed at once for one
+	// leader trade
+	MirrorConcurrency int `mapstructure:"mirror_concurrency"`
+}
+
+// CopyAbuseConfig sets when leaders are flagged for review.
+type CopyAbuseConfig struct {
+	Schedule string        `mapstructure:"schedule"`
+	Window   time.Duration `mapstructure:"window"`
+	// MatchWindow is how close opposite fills must be to count as a
+	// self-match
+	MatchWindow     time.Duration `mapstructure:"match_window"`
+	WashRatio       float64       `mapstructure:"wash_ratio"`
+	MinMatchedPairs int           `mapstructure:"min_matched_pairs"`
+	ChurnMinTrades  int           `mapstructure:"churn_min_trades"`
+	ChurnFeeRatio   float64       `mapstructure:"churn_fee_ratio"`
 }
 
 type NATSConfig struct {
+	URL         string            `mapstructure:"url"`
+	ConsumerLag ConsumerLagConfig `mapstructure:"consumer_lag"`
+}
+
+// ConsumerLagConfig controls the monitoring of JetStream consumers, which
+// the scheduler service runs as a job.
+type ConsumerLagConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Schedule is the scheduler spec of the poll
+	Schedule string `mapstructure:"schedule"`
+	// StuckAfter is how long a consumer with outstanding messages may go
+	// without acknowledging any before it is reported stuck
+	StuckAfter time.Duration `mapstructure:"stuck_after"`
+	// MaxPending reports a consumer stuck once this many messages wait to
+	// be delivered to it; zero disables the check
+	MaxPending uint64 `mapstructure:"max_pending"`
+	// Webhook also posts the alerts to an endpoint such as an on-call
+	// tool; no URL disables it
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+// WebhookConfig is an outgoing webhook endpoint.
+type WebhookConfig struct {
 	URL string `mapstructure:"url"`
+	// Secret signs the deliveries
+	Secret string `mapstructure:"secret"`
+	// Timeout bounds each attempt
+	Timeout time.Duration `mapstructure:"timeout"`
+	Retry   retry.Config  `mapstructure:"retry"`
+}
+
+type StreamingConfig struct {
+	// BufferSize is how many undroppable messages a streaming client may
+	// lag behind before it is disconnected
+	BufferSize   int           `mapstructure:"buffer_size"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+}
+
+type RegistryConfig struct {
+	// Interval between service heartbeats
+	Interval time.Duration `mapstructure:"interval"`
+	// TTL after which an instance without heartbeats is reported stale
+	TTL time.Duration `mapstructure:"ttl"`
 }
 
 type InfluxConfig struct {
@@ -61,6 +762,95 @@ type InfluxConfig struct {
 	Bucket string `mapstructure:"bucket"`
 }
 
+type RateLimitConfig struct {
+	Requests     int           `mapstructure:"requests"`
+	Window       time.Duration `mapstructure:"window"`
+	Allowlist    []string      `mapstructure:"allowlist"`
+	Denylist     []string      `mapstructure:"denylist"`
+	SyncInterval time.Duration `mapstructure:"sync_interval"`
+	// QueueMaxWait is how long trading requests over the limit may wait
+	// for a slot before being rejected; zero disables queuing
+	QueueMaxWait time.Duration `mapstructure:"queue_max_wait"`
+}
+
+type AdminConfig struct {
+	APIKey string `mapstructure:"api_key"`
+}
+
+type RequestLimitsConfig struct {
+	MaxBodyBytes   int64 `mapstructure:"max_body_bytes"`
+	MaxDepth       int   `mapstructure:"max_depth"`
+	MaxArrayLength int   `mapstructure:"max_array_length"`
+	MaxStringBytes int   `mapstructure:"max_string_bytes"`
+}
+
+// LoadSheddingConfig sets when the gateway starts rejecting low priority
+// traffic to protect auth and order placement.
+type LoadSheddingConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	MaxInFlight   int64         `mapstructure:"max_in_flight"`
+	LatencyTarget time.Duration `mapstructure:"latency_target"`
+	// NormalFactor is how far over the limits the gateway must be before
+	// regular traffic is shed too
+	NormalFactor float64       `mapstructure:"normal_factor"`
+	RetryAfter   time.Duration `mapstructure:"retry_after"`
+}
+
+// RequestLanesConfig sizes the worker pools that keep order placement and
+// cancellation ahead of list and analytics requests under load.
+type RequestLanesConfig struct {
+	Enabled   bool       `mapstructure:"enabled"`
+	Orders    LaneConfig `mapstructure:"orders"`
+	Default   LaneConfig `mapstructure:"default"`
+	Analytics LaneConfig `mapstructure:"analytics"`
+	// Shared workers serve any lane whose own workers are busy
+	Shared     int           `mapstructure:"shared"`
+	MaxWait    time.Duration `mapstructure:"max_wait"`
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+type LaneConfig struct {
+	Workers    int `mapstructure:"workers"`
+	QueueDepth int `mapstructure:"queue_depth"`
+	// Weight is the lane's share of the shared workers
+	Weight int `mapstructure:"weight"`
+}
+
+// CanaryConfig routes a stable percentage of users for a backend service to
+// an alternative address.
+type CanaryConfig struct {
+	Address string `mapstructure:"address"`
+	Percent int    `mapstructure:"percent"`
+}
+
+// ExchangeConfig describes where an exchange's matching engine runs and the
+// connector instances deployed in each region.
+type ExchangeConfig struct {
+	MatchingEngineRegion string                    `mapstructure:"matching_engine_region"`
+	Connectors           []ExchangeConnectorConfig `mapstructure:"connectors"`
+	// TestnetConnectors reach the exchange's sandbox environment; they
+	// serve requests made with API keys flagged as testnet
+	TestnetConnectors []ExchangeConnectorConfig `mapstructure:"testnet_connectors"`
+	// Timezone is the IANA zone used for exchange-local sessions
+	Timezone string `mapstructure:"timezone"`
+	// Symbols lists the markets offered for search and symbol lookup
+	Symbols []string `mapstructure:"symbols"`
+	// Precision is the price and quantity granularity per symbol
+	Precision map[string]PrecisionConfig `mapstructure:"precision"`
+}
+
+// PrecisionConfig holds decimal strings so they are never parsed as floats.
+type PrecisionConfig struct {
+	TickSize    string `mapstructure:"tick_size"`
+	StepSize    string `mapstructure:"step_size"`
+	MinNotional string `mapstructure:"min_notional"`
+}
+
+type ExchangeConnectorConfig struct {
+	Region  string `mapstructure:"region"`
+	Address string `mapstructure:"address"`
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -87,6 +877,17 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	// Fault injection must never reach real money
+	if config.Faults.Enabled && config.Trading.Mode == "live" {
+		return nil, fmt.Errorf("fault injection cannot be enabled in live trading mode")
+	}
+
+	for service, compressor := range config.GRPC.Compression {
+		if compressor != "gzip" && compressor != "zstd" {
+			return nil, fmt.Errorf("unknown grpc compressor %q for %s", compressor, service)
+		}
+	}
+
 	// Build database URL if not provided
 	if config.Database.URL == "" {
 		config.Database.URL = fmt.Sprintf(
@@ -104,11 +905,31 @@ func Load() (*Config, error) {
 }
 
 func setDefaults() {
+	// Deployment region of this service instance
+	viper.SetDefault("region", "local")
+
 	// Server defaults
 	viper.SetDefault("server.port", ":8080")
 	viper.SetDefault("server.read_timeout", "10s")
 	viper.SetDefault("server.write_timeout", "10s")
 	viper.SetDefault("server.idle_timeout", "60s")
+	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.drain_delay", "5s")
+	viper.SetDefault("server.read_header_timeout", "5s")
+	viper.SetDefault("server.max_header_bytes", 1<<20)
+	viper.SetDefault("server.tcp_keep_alive", "15s")
+	viper.SetDefault("server.http2.h2c", false)
+	viper.SetDefault("server.http2.max_concurrent_streams", 250)
+	viper.SetDefault("server.http2.read_idle_timeout", "30s")
+	viper.SetDefault("server.http2.ping_timeout", "15s")
+	viper.SetDefault("server.proxies.trusted_proxies", []string{})
+	viper.SetDefault("server.proxies.headers", []string{"X-Forwarded-For"})
+	viper.SetDefault("server.timeouts.default", "8s")
+	viper.SetDefault("server.timeouts.routes", map[string]string{
+		"GET /api/v1/stream":                  "0s",
+		"GET /api/v1/portfolio/trades/export": "0s",
+		"POST /api/v1/bots/:id/backtest":      "0s",
+	})
 
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
@@ -123,20 +944,305 @@ func setDefaults() {
 	viper.SetDefault("redis.port", 6379)
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
+	viper.SetDefault("redis.pool_size", 64)
+	viper.SetDefault("redis.min_idle_conns", 8)
+	viper.SetDefault("redis.pool_timeout", "2s")
+	viper.SetDefault("redis.dial_timeout", "2s")
+	viper.SetDefault("redis.read_timeout", "1s")
+	viper.SetDefault("redis.write_timeout", "1s")
+	viper.SetDefault("redis.conn_max_idle_time", "5m")
 
 	// JWT defaults
 	viper.SetDefault("jwt.secret", "your-super-secret-jwt-key")
 	viper.SetDefault("jwt.expiration_time", "1h")
+	viper.SetDefault("jwt.signing_key", "")
+	viper.SetDefault("jwt.keys", []JWTKeyConfig{})
 
 	// Auth service defaults
 	viper.SetDefault("auth.port", ":9001")
+	viper.SetDefault("auth.token_cache_ttl", "45s")
+	viper.SetDefault("auth.refresh_token_purge_schedule", "@daily")
+	viper.SetDefault("auth.jwks_port", ":9011")
+	viper.SetDefault("auth.jwks_refresh_interval", "5m")
+	viper.SetDefault("auth.reauth_window", "10m")
+	viper.SetDefault("auth.admins", []string{})
+	viper.SetDefault("auth.email_verification.required", true)
+	viper.SetDefault("auth.email_verification.ttl", "48h")
+	viper.SetDefault("auth.email_verification.url", "http://localhost:3000/verify-email")
+	viper.SetDefault("auth.password_reset.ttl", "1h")
+	viper.SetDefault("auth.password_reset.url", "http://localhost:3000/reset-password")
+	viper.SetDefault("auth.email_change.ttl", "24h")
+	viper.SetDefault("auth.email_change.url", "http://localhost:3000/confirm-email-change")
+	viper.SetDefault("auth.email_change.undo_ttl", "168h")
+	viper.SetDefault("auth.email_change.undo_url", "http://localhost:3000/undo-email-change")
+	viper.SetDefault("auth.password_hashing.memory_kib", 65536)
+	viper.SetDefault("auth.password_hashing.iterations", 3)
+	viper.SetDefault("auth.password_hashing.parallelism", 2)
+	viper.SetDefault("auth.password_hashing.max_concurrent", 8)
+	viper.SetDefault("auth.magic_link.ttl", "15m")
+	viper.SetDefault("auth.magic_link.url", "http://localhost:3000/magic-link")
+	viper.SetDefault("auth.sso.redirect_url", "http://localhost:3000/sso/callback")
+	viper.SetDefault("auth.sso.state_ttl", "10m")
+	viper.SetDefault("auth.sso.http_timeout", "10s")
+	viper.SetDefault("auth.sso.cache_ttl", "1h")
+	viper.SetDefault("auth.existence_filter.enabled", true)
+	viper.SetDefault("auth.existence_filter.expected_users", 1000000)
+	viper.SetDefault("auth.existence_filter.false_positive_rate", 0.01)
+	viper.SetDefault("auth.existence_filter.rebuild_schedule", "@daily")
+	viper.SetDefault("auth.lockout.enabled", true)
+	viper.SetDefault("auth.lockout.max_failures", 5)
+	viper.SetDefault("auth.lockout.ip_max_failures", 50)
+	viper.SetDefault("auth.lockout.window", "15m")
+	viper.SetDefault("auth.lockout.duration", "15m")
+	viper.SetDefault("auth.login_alerts.notify", true)
+	viper.SetDefault("auth.login_alerts.step_up", false)
+	viper.SetDefault("auth.cookie_sessions.enabled", false)
+	viper.SetDefault("auth.cookie_sessions.clients", []string{"web"})
+	viper.SetDefault("auth.cookie_sessions.cookie_name", "tbh_session")
+	viper.SetDefault("auth.cookie_sessions.secure", true)
+	viper.SetDefault("auth.cookie_sessions.ttl", "168h")
+
+	// Email defaults
+	viper.SetDefault("email.backend", "log")
+	viper.SetDefault("email.from", "TradingBot Hub <no-reply@tradingbothub.com>")
+	viper.SetDefault("email.smtp_port", 587)
+	viper.SetDefault("email.retry.max_attempts", 4)
+	viper.SetDefault("email.retry.initial_backoff", "1s")
+	viper.SetDefault("email.retry.max_backoff", "30s")
+	viper.SetDefault("email.retry.multiplier", 2)
+	viper.SetDefault("email.retry.jitter", 0.2)
+
+	// Scheduler service defaults
+	viper.SetDefault("scheduler.port", ":9002")
+	viper.SetDefault("scheduler.poll_interval", "5s")
+
+	// Bot runtime defaults
+	viper.SetDefault("bot_runtime.port", ":9003")
+	viper.SetDefault("bot_runtime.poll_interval", "10s")
+	viper.SetDefault("bot_runtime.checkpoint_interval", "1m")
+	viper.SetDefault("bot_runtime.heartbeat_interval", "5s")
+
+	// Paper exchange defaults
+	viper.SetDefault("paper_exchange.port", ":9101")
+	viper.SetDefault("paper_exchange.price_interval", "5s")
+
+	// gRPC defaults
+	viper.SetDefault("grpc.max_recv_msg_size", 16<<20)
+	viper.SetDefault("grpc.max_send_msg_size", 16<<20)
+	viper.SetDefault("grpc.compression", map[string]string{"backtest": "zstd"})
+	viper.SetDefault("grpc.deadline_margin", "50ms")
+	viper.SetDefault("grpc.retry.max_attempts", 3)
+	viper.SetDefault("grpc.retry.initial_backoff", "50ms")
+	viper.SetDefault("grpc.retry.max_backoff", "1s")
+	viper.SetDefault("grpc.retry.multiplier", 2)
+	viper.SetDefault("grpc.retry.jitter", 0.2)
+	viper.SetDefault("grpc.retry.budget_ratio", 0.1)
+	viper.SetDefault("grpc.retry.budget_burst", 20)
+
+	// Fault injection defaults
+	viper.SetDefault("faults.enabled", false)
+	viper.SetDefault("faults.error_rate", 0.0)
+	viper.SetDefault("faults.drop_rate", 0.0)
+
+	// Object store defaults
+	viper.SetDefault("object_store.backend", "local")
+	viper.SetDefault("object_store.local.root", "./data/objects")
+	viper.SetDefault("object_store.local.base_url", "http://localhost:8080/files")
+	viper.SetDefault("object_store.cleanup_schedule", "@every 1h")
+
+	// Avatar defaults
+	viper.SetDefault("avatars.max_bytes", 5<<20)
+	viper.SetDefault("avatars.max_pixels", 16000000)
+	viper.SetDefault("avatars.size", 256)
+	viper.SetDefault("avatars.url_ttl", "1h")
+
+	// Metering defaults
+	viper.SetDefault("metering.retention", "168h")
+	viper.SetDefault("metering.buffer_size", 4096)
+
+	// Candle cache defaults
+	viper.SetDefault("candle_cache.max_bytes", 256<<20)
+
+	// Backtest defaults
+	viper.SetDefault("backtest.port", ":9004")
+	viper.SetDefault("backtest.workers", 0)
+	viper.SetDefault("backtest.max_runs", 500)
+	viper.SetDefault("backtest.slots", 4)
+	viper.SetDefault("backtest.job_retention", "1h")
+	viper.SetDefault("backtest.job_lease", "30s")
+	viper.SetDefault("backtest.poll_interval", "1s")
+	viper.SetDefault("backtest.tiers", map[string]interface{}{
+		"free":       map[string]interface{}{"weight": 1, "max_concurrent": 1, "max_queued": 3, "preempt_after": "2m"},
+		"pro":        map[string]interface{}{"weight": 4, "max_concurrent": 2, "max_queued": 10},
+		"enterprise": map[string]interface{}{"weight": 8, "max_concurrent": 4, "max_queued": 25},
+	})
+
+	// Billing defaults
+	viper.SetDefault("billing.enabled", true)
+	viper.SetDefault("billing.currency", "USD")
+	viper.SetDefault("billing.plans", map[string]interface{}{
+		"pro":        map[string]interface{}{"price": "29.00", "included_requests": 1000000, "overage_price": "0.05"},
+		"enterprise": map[string]interface{}{"price": "199.00", "included_requests": 10000000, "overage_price": "0.02"},
+	})
+	viper.SetDefault("billing.usage_schedule", "@daily")
+	viper.SetDefault("billing.invoice_schedule", "@monthly")
+	viper.SetDefault("billing.payment_terms", "168h")
+	viper.SetDefault("billing.dunning.schedule", "@hourly")
+	viper.SetDefault("billing.dunning.reminders", []string{"0s", "72h", "168h"})
+	viper.SetDefault("billing.dunning.downgrade_after", "336h")
+	viper.SetDefault("billing.dunning.url", "http://localhost:3000/settings/billing")
+
+	// Write batching defaults
+	viper.SetDefault("write_batching.max_size", 1000)
+	viper.SetDefault("write_batching.flush_interval", "1s")
+	viper.SetDefault("write_batching.buffer_size", 10000)
+	viper.SetDefault("write_batching.retry_backoff", "500ms")
+
+	// Order book defaults
+	viper.SetDefault("order_books.shards", 16)
+	viper.SetDefault("order_books.max_depth", 1000)
+
+	// Warm-up defaults
+	viper.SetDefault("warmup.timeout", "10s")
+	viper.SetDefault("warmup.retry_interval", "2s")
+	viper.SetDefault("warmup.max_wait", "1m")
+
+	// Copy trading defaults
+	viper.SetDefault("copy_trading.max_multiplier", 5.0)
+	viper.SetDefault("copy_trading.allocation_schedule", "@daily")
+	viper.SetDefault("copy_trading.allocation_window", "720h")
+	viper.SetDefault("copy_trading.allocation_step", 0.1)
+	viper.SetDefault("copy_trading.drawdown_limit", 0.2)
+	viper.SetDefault("copy_trading.leaderboard_window", "720h")
+	viper.SetDefault("copy_trading.performance_fee", 0.2)
+	viper.SetDefault("copy_trading.platform_commission", 0.3)
+	viper.SetDefault("copy_trading.mirror_concurrency", 8)
+	viper.SetDefault("copy_trading.settlement_schedule", "@monthly")
+	viper.SetDefault("copy_trading.abuse.schedule", "@hourly")
+	viper.SetDefault("copy_trading.abuse.window", "168h")
+	viper.SetDefault("copy_trading.abuse.match_window", "1m")
+	viper.SetDefault("copy_trading.abuse.wash_ratio", 0.3)
+	viper.SetDefault("copy_trading.abuse.min_matched_pairs", 5)
+	viper.SetDefault("copy_trading.abuse.churn_min_trades", 50)
+	viper.SetDefault("copy_trading.abuse.churn_fee_ratio", 0.5)
+
+	// Trading defaults
+	viper.SetDefault("trading.mode", "paper")
+	viper.SetDefault("trading.bulk_concurrency", 8)
+	viper.SetDefault("trading.bot_order_rate.orders_per_minute", 30)
+	viper.SetDefault("trading.bot_order_rate.cancels_per_minute", 60)
+	viper.SetDefault("trading.bot_order_rate.burst", 5)
+	viper.SetDefault("trading.bot_order_rate.max_wait", "30s")
+	viper.SetDefault("trading.group_sync_interval", "1s")
+	viper.SetDefault("trading.fill_sync_interval", "5s")
+	viper.SetDefault("trading.exchange_retry.max_attempts", 3)
+	viper.SetDefault("trading.exchange_retry.initial_backoff", "200ms")
+	viper.SetDefault("trading.exchange_retry.max_backoff", "2s")
+	viper.SetDefault("trading.exchange_retry.multiplier", 2)
+	viper.SetDefault("trading.exchange_retry.jitter", 0.2)
+	viper.SetDefault("trading.exchange_retry.budget_ratio", 0.1)
+	viper.SetDefault("trading.exchange_retry.budget_burst", 10)
+	viper.SetDefault("trading.chaos.enabled", false)
+	viper.SetDefault("trading.chaos.ack_delay", "5s")
+	viper.SetDefault("trading.connector_timeout", "10s")
+	viper.SetDefault("trading.connector_probe_interval", "10s")
+
+	// Equity defaults
+	viper.SetDefault("equity.snapshot_schedule", "@every 1m")
+
+	// Portfolio defaults
+	viper.SetDefault("portfolio.valuation_schedule", "@every 1m")
+	viper.SetDefault("portfolio.check_schedule", "@daily")
+
+	// Approval defaults
+	viper.SetDefault("organizations.invitation_ttl", "168h")
+	viper.SetDefault("organizations.invitation_url", "http://localhost:3000/invitations")
+	viper.SetDefault("approvals.ttl", "24h")
+	viper.SetDefault("approvals.live_bot_capital", 10000)
+
+	// Share link defaults
+	viper.SetDefault("share.secret", "your-super-secret-share-key")
+	viper.SetDefault("share.max_ttl", "2160h") // 90 days
+
+	// Data residency defaults
+	viper.SetDefault("data_residency.default_region", "local")
+
+	// Demo sandbox defaults
+	viper.SetDefault("demo.enabled", false)
+	viper.SetDefault("demo.email", "demo@tradingbothub.com")
+	viper.SetDefault("demo.username", "demo")
+	viper.SetDefault("demo.password", "demo-sandbox-password")
+	viper.SetDefault("demo.balance", 10000)
+	viper.SetDefault("demo.requests", 30)
+	viper.SetDefault("demo.window", "1m")
+	viper.SetDefault("demo.reset_schedule", "@daily")
+
+	// Retention defaults
+	viper.SetDefault("retention.schedule", "@daily")
+	viper.SetDefault("retention.trash_ttl", "720h")  // 30 days
+	viper.SetDefault("retention.audit_ttl", "8760h") // 1 year
 
 	// NATS defaults
 	viper.SetDefault("nats.url", "nats://localhost:4222")
+	viper.SetDefault("nats.consumer_lag.enabled", true)
+	viper.SetDefault("nats.consumer_lag.schedule", "@every 15s")
+	viper.SetDefault("nats.consumer_lag.stuck_after", "2m")
+	viper.SetDefault("nats.consumer_lag.max_pending", 10000)
+	viper.SetDefault("nats.consumer_lag.webhook.timeout", "10s")
+	viper.SetDefault("nats.consumer_lag.webhook.retry.max_attempts", 5)
+	viper.SetDefault("nats.consumer_lag.webhook.retry.initial_backoff", "1s")
+	viper.SetDefault("nats.consumer_lag.webhook.retry.max_backoff", "30s")
+	viper.SetDefault("nats.consumer_lag.webhook.retry.multiplier", 2)
+	viper.SetDefault("nats.consumer_lag.webhook.retry.jitter", 0.2)
+
+	// Streaming defaults
+	viper.SetDefault("streaming.buffer_size", 256)
+	viper.SetDefault("streaming.write_timeout", "5s")
+
+	// Service registry defaults
+	viper.SetDefault("registry.interval", "10s")
+	viper.SetDefault("registry.ttl", "30s")
 
 	// InfluxDB defaults
 	viper.SetDefault("influxdb.url", "http://localhost:8086")
 	viper.SetDefault("influxdb.token", "")
 	viper.SetDefault("influxdb.org", "tradingbothub")
 	viper.SetDefault("influxdb.bucket", "market_data")
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.requests", 100)
+	viper.SetDefault("rate_limit.window", "1m")
+	viper.SetDefault("rate_limit.allowlist", []string{})
+	viper.SetDefault("rate_limit.denylist", []string{})
+	viper.SetDefault("rate_limit.sync_interval", "5s")
+	viper.SetDefault("rate_limit.queue_max_wait", "2s")
+
+	// Admin API defaults (empty key disables the admin API)
+	viper.SetDefault("admin.api_key", "")
+
+	// Request body limits
+	viper.SetDefault("request_limits.max_body_bytes", 1<<20) // 1 MiB
+	viper.SetDefault("request_limits.max_depth", 32)
+	viper.SetDefault("request_limits.max_array_length", 10000)
+	viper.SetDefault("request_limits.max_string_bytes", 64<<10) // 64 KiB
+
+	// Load shedding defaults
+	viper.SetDefault("load_shedding.enabled", true)
+	viper.SetDefault("load_shedding.max_in_flight", 512)
+	viper.SetDefault("load_shedding.latency_target", "500ms")
+	viper.SetDefault("load_shedding.normal_factor", 2.0)
+	viper.SetDefault("load_shedding.retry_after", "1s")
+	viper.SetDefault("request_lanes.enabled", true)
+	viper.SetDefault("request_lanes.orders.workers", 64)
+	viper.SetDefault("request_lanes.orders.queue_depth", 256)
+	viper.SetDefault("request_lanes.orders.weight", 8)
+	viper.SetDefault("request_lanes.default.workers", 128)
+	viper.SetDefault("request_lanes.default.queue_depth", 256)
+	viper.SetDefault("request_lanes.default.weight", 4)
+	viper.SetDefault("request_lanes.analytics.workers", 32)
+	viper.SetDefault("request_lanes.analytics.queue_depth", 128)
+	viper.SetDefault("request_lanes.analytics.weight", 1)
+	viper.SetDefault("request_lanes.shared", 128)
+	viper.SetDefault("request_lanes.max_wait", "2s")
+	viper.SetDefault("request_lanes.retry_after", "1s")
 }
@@ -0,0 +1,318 @@
+package copytrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/orders"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFlagNotFound = errors.New("flag not found")
+	// ErrFlagReviewed is returned when deciding a flag twice
+	ErrFlagReviewed = errors.New("flag already reviewed")
+	// ErrSelfReview is returned when a leader reviews a flag on themselves
+	ErrSelfReview = errors.New("flags on your own account cannot be reviewed by you")
+	// ErrNoReviewer is returned when deciding a flag without naming who
+	// decided it
+	ErrNoReviewer = errors.New("flag reviewer required")
+)
+
+const (
+	// FlagWashTrade marks leaders trading against themselves: opposite
+	// fills of the same size and price in quick succession
+	FlagWashTrade = "wash_trade"
+	// FlagChurn marks leaders whose trading mostly generates fees
+	FlagChurn = "churn"
+)
+
+const (
+	FlagOpen      = "open"
+	FlagConfirmed = "confirmed"
+	FlagDismissed = "dismissed"
+)
+
+// Flag queues a suspicious leader for review. Leaders with an open or
+// confirmed flag are left off the leaderboard.
+type Flag struct {
+	ID       string  `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	LeaderID string  `json:"leader_id" gorm:"type:varchar(36);not null;index"`
+	Kind     string  `json:"kind" gorm:"not null"`
+	Status   string  `json:"status" gorm:"not null;index"`
+	Score    float64 `json:"score"`
+	Details  string  `json:"details"`
+	// ReviewedBy names the admin who confirmed or dismissed the flag
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Flag) TableName() string {
+	return "copy_leader_flags"
+}
+
+// AbusePolicy sets the detection thresholds.
+type AbusePolicy struct {
+	// Window is the span of trades scanned per run
+	Window time.Duration
+	// MatchWindow is how close opposite fills must be to count as matched
+	MatchWindow time.Duration
+	// WashRatio is the share of volume in matched pairs that flags a leader
+	WashRatio float64
+	// MinMatchedPairs avoids flagging a couple of coincidences
+	MinMatchedPairs int
+	// ChurnMinTrades is the trade count below which churn is not assessed
+	ChurnMinTrades int
+	// ChurnFeeRatio flags leaders whose fees exceed this multiple of their
+	// gross trading result
+	ChurnFeeRatio float64
+}
+
+// Detector scans the trades of every leader for wash trading and churn.
+type Detector struct {
+	db     *gorm.DB
+	policy AbusePolicy
+}
+
+func NewDetector(db *gorm.DB, policy AbusePolicy) *Detector {
+	return &Detector{db: db, policy: policy}
+}
+
+// Run matches scheduler.JobFunc.
+func (d *Detector) Run(ctx context.Context) error {
+	var leaderIDs []string
+	err := d.db.WithContext(ctx).Model(&Follow{}).Distinct("leader_id").Pluck("leader_id", &leaderIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to list leaders: %w", err)
+	}
+
+	since := time.Now().Add(-d.policy.Window)
+	flagged := 0
+	for _, leaderID := range leaderIDs {
+		var trades []orders.Trade
+		err := d.db.WithContext(ctx).
+			Where("user_id = ? AND executed_at >= ? AND testnet = ?", leaderID, since, false).
+			Order("executed_at").Find(&trades).Error
+		if err != nil {
+			log.Printf("Skipping abuse scan of leader %s: %v", leaderID, err)
+			continue
+		}
+
+		for _, flag := range d.inspect(leaderID, trades) {
+			created, err := d.raise(ctx, flag, since)
+			if err != nil {
+				log.Printf("Failed to flag leader %s: %v", leaderID, err)
+				continue
+			}
+			if created {
+				flagged++
+			}
+		}
+	}
+
+	log.Printf("Abuse scan of %d leaders raised %d flags", len(leaderIDs), flagged)
+	return nil
+}
+
+// inspect returns the flags the leader's trades (oldest first) deserve.
+func (d *Detector) inspect(leaderID string, trades []orders.Trade) []Flag {
+	var flags []Flag
+
+	pairs, matched, total := matchedVolume(trades, d.policy.MatchWindow)
+	if pairs >= d.policy.MinMatchedPairs && total.IsPositive() {
+		ratio := matched.Div(total).InexactFloat64()
+		if ratio >= d.policy.WashRatio {
+			flags = append(flags, Flag{
+				LeaderID: leaderID,
+				Kind:     FlagWashTrade,
+				Score:    ratio,
+				Details:  fmt.Sprintf("%d matched pairs, %.0f%% of volume", pairs, ratio*100),
+			})
+		}
+	}
+
+	if len(trades) >= d.policy.ChurnMinTrades {
+		var fees, gross decimal.Decimal
+		for _, trade := range trades {
+			notional := trade.Price.Mul(trade.Quantity)
+			if trade.Side == "sell" {
+				gross = gross.Add(notional)
+			} else {
+				gross = gross.Sub(notional)
+			}
+			fees = fees.Add(trade.Fee)
+		}
+		// A flat book that pays fees on every round trip has no gross
+		// result to show for them
+		limit := gross.Abs().Mul(decimal.NewFromFloat(d.policy.ChurnFeeRatio))
+		if fees.IsPositive() && fees.GreaterThan(limit) {
+			score := 0.0
+			if gross.IsZero() {
+				score = 1
+			} else {
+				score = fees.Div(gross.Abs()).InexactFloat64()
+			}
+			flags = append(flags, Flag{
+				LeaderID: leaderID,
+				Kind:     FlagChurn,
+				Score:    score,
+				Details:  fmt.Sprintf("%d trades paid %s in fees against a gross result of %s", len(trades), fees.StringFixed(2), gross.StringFixed(2)),
+			})
+		}
+	}
+
+	return flags
+}
+
+// matchedVolume pairs opposite trades of the same symbol, size and price
+// (within 0.1%) executed within window of each other. It returns the
+// number of pairs, their volume and the total volume, both as notional.
+func matchedVolume(trades []orders.Trade, window time.Duration) (int, decimal.Decimal, decimal.Decimal) {
+	tolerance := decimal.NewFromFloat(0.001)
+	used := make([]bool, len(trades))
+	pairs := 0
+	var matched, total decimal.Decimal
+
+	for i, trade := range trades {
+		notional := trade.Price.Mul(trade.Quantity)
+		total = total.Add(notional)
+		if used[i] {
+			continue
+		}
+		for j := i + 1; j < len(trades) && trades[j].ExecutedAt.Sub(trade.ExecutedAt) <= window; j++ {
+			other := trades[j]
+			if used[j] || other.Symbol != trade.Symbol || other.Side == trade.Side || !other.Quantity.Equal(trade.Quantity) {
+				continue
+			}
+			if trade.Price.IsZero() || other.Price.Sub(trade.Price).Abs().Div(trade.Price).GreaterThan(tolerance) {
+				continue
+			}
+			used[i], used[j] = true, true
+			pairs++
+			matched = matched.Add(notional).Add(other.Price.Mul(other.Quantity))
+			break
+		}
+	}
+	return pairs, matched, total
+}
+
+// raise stores the flag unless the leader already has one of that kind
+// from this scan window, so a dismissed flag is not raised again for the
+// same trades.
+func (d *Detector) raise(ctx context.Context, flag Flag, since time.Time) (bool, error) {
+	var existing int64
+	err := d.db.WithContext(ctx).Model(&Flag{}).
+		Where("leader_id = ? AND kind = ? AND (status <> ? OR created_at >= ?)", flag.LeaderID, flag.Kind, FlagDismissed, since).
+		Count(&existing).Error
+	if err != nil || existing > 0 {
+		return false, err
+	}
+
+	flag.ID = uuid.New().String()
+	flag.Status = FlagOpen
+	return true, d.db.WithContext(ctx).Create(&flag).Error
+}
+
+type FlagRepository interface {
+	List(ctx context.Context, status string) ([]Flag, error)
+	// Review confirms or dismisses an open flag on behalf of reviewer, who
+	// must be named and must not be the flagged leader
+	Review(ctx context.Context, id, status, reviewer string) (*Flag, error)
+	// Excluded returns the leaders kept off the leaderboard
+	Excluded(ctx context.Context) (map[string]bool, error)
+}
+
+type flagRepository struct {
+	db *gorm.DB
+}
+
+func NewFlagRepository(db *gorm.DB) FlagRepository {
+	return &flagRepository{db: db}
+}
+
+func (r *flagRepository) List(ctx context.Context, status string) ([]Flag, error) {
+	tx := r.db.WithContext(ctx)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	var flags []Flag
+	err := tx.Order("created_at").Find(&flags).Error
+	return flags, err
+}
+
+func (r *flagRepository) Review(ctx context.Context, id, status, reviewer string) (*Flag, error) {
+	if reviewer == "" {
+		return nil, ErrNoReviewer
+	}
+
+	var flag Flag
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&flag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFlagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if flag.LeaderID == reviewer {
+		return nil, ErrSelfReview
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&Flag{}).
+		Where("id = ? AND status = ?", id, FlagOpen).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewer, "reviewed_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrFlagReviewed
+	}
+
+	flag.Status, flag.ReviewedBy, flag.ReviewedAt = status, reviewer, &now
+	return &flag, nil
+}
+
+func (r *flagRepository) Excluded(ctx context.Context) (map[string]bool, error) {
+	var leaderIDs []string
+	err := r.db.WithContext(ctx).Model(&Flag{}).
+		Where("status IN ?", []string{FlagOpen, FlagConfirmed}).
+		Distinct("leader_id").Pluck("leader_id", &leaderIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool, len(leaderIDs))
+	for _, id := range leaderIDs {
+		excluded[id] = true
+	}
+	return excluded, nil
+}
+
+// LeaderboardEntry is a leader's performance over the leaderboard window.
+type LeaderboardEntry struct {
+	LeaderID  string  `json:"leader_id"`
+	Followers int64   `json:"followers"`
+	Return    float64 `json:"return"`
+	Drawdown  float64 `json:"max_drawdown"`
+}
+
+// RankLeaders sorts entries by return, best first, breaking ties by
+// smaller drawdown.
+func RankLeaders(entries []LeaderboardEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Return != entries[j].Return {
+			return entries[i].Return > entries[j].Return
+		}
+		return entries[i].Drawdown < entries[j].Drawdown
+	})
+}
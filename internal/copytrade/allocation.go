@@ -0,0 +1,129 @@
+package copytrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/equity"
+)
+
+var ErrInvalidBounds = errors.New("invalid multiplier bounds")
+
+// AllocationPolicy controls how dynamic follows react to their leader.
+type AllocationPolicy struct {
+	// Window is how far back the leader's equity is evaluated
+	Window time.Duration
+	// Step is the fraction the multiplier moves per evaluation
+	Step float64
+	// DrawdownLimit is the drawdown above which allocation is reduced
+	// regardless of return
+	DrawdownLimit float64
+}
+
+// Allocator scales dynamic follows up while their leader performs and
+// down on losses or deep drawdowns, one step per run, within each
+// follow's bounds. Every change is written to the allocation log.
+type Allocator struct {
+	follows Repository
+	equity  equity.Store
+	policy  AllocationPolicy
+}
+
+func NewAllocator(follows Repository, equityStore equity.Store, policy AllocationPolicy) *Allocator {
+	return &Allocator{follows: follows, equity: equityStore, policy: policy}
+}
+
+// Run matches scheduler.JobFunc.
+func (a *Allocator) Run(ctx context.Context) error {
+	follows, err := a.follows.ListDynamic(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list dynamic follows: %w", err)
+	}
+
+	now := time.Now().UTC()
+	// Followers of the same leader see the same performance
+	perfs := make(map[string]equity.Performance)
+	changed := 0
+	for i := range follows {
+		follow := &follows[i]
+
+		perf, ok := perfs[follow.LeaderID]
+		if !ok {
+			series, err := a.equity.Series(ctx, follow.LeaderID, "", now.Add(-a.policy.Window), now)
+			if err != nil {
+				log.Printf("Skipping allocation of follow %s: %v", follow.ID, err)
+				continue
+			}
+			perf = equity.Summarize(series)
+			perfs[follow.LeaderID] = perf
+		}
+
+		change := a.adjust(follow, perf)
+		if change == nil {
+			continue
+		}
+		if err := a.follows.SetMultiplier(ctx, follow, change); err != nil {
+			log.Printf("Failed to update allocation of follow %s: %v", follow.ID, err)
+			continue
+		}
+		changed++
+	}
+
+	log.Printf("Adjusted %d of %d dynamic copy allocations", changed, len(follows))
+	return nil
+}
+
+// adjust moves the follow's multiplier one step and returns the change,
+// or nil when it stays put.
+func (a *Allocator) adjust(follow *Follow, perf equity.Performance) *AllocationChange {
+	factor, reason := 1.0, ""
+	switch {
+	case perf.MaxDrawdown.Max > a.policy.DrawdownLimit:
+		factor, reason = 1-a.policy.Step, fmt.Sprintf("drawdown %.1f%% above %.1f%%", perf.MaxDrawdown.Max*100, a.policy.DrawdownLimit*100)
+	case perf.Return < 0:
+		factor, reason = 1-a.policy.Step, fmt.Sprintf("return %.1f%%", perf.Return*100)
+	case perf.Return > 0:
+		factor, reason = 1+a.policy.Step, fmt.Sprintf("return %.1f%%", perf.Return*100)
+	default:
+		return nil
+	}
+
+	next := follow.Multiplier.Mul(decimal.NewFromFloat(factor)).Round(4)
+	next = decimal.Max(follow.MinMultiplier, decimal.Min(follow.MaxMultiplier, next))
+	if next.Equal(follow.Multiplier) {
+		return nil
+	}
+
+	change := &AllocationChange{
+		FollowID: follow.ID,
+		Previous: follow.Multiplier,
+		Current:  next,
+		Return:   perf.Return,
+		Drawdown: perf.MaxDrawdown.Max,
+		Reason:   reason,
+	}
+	follow.Multiplier = next
+	return change
+}
+
+// ValidateBounds checks a follow's multipliers: positive, base within the
+// dynamic bounds and the bounds no wider than maxMultiplier.
+func ValidateBounds(follow *Follow, maxMultiplier decimal.Decimal) error {
+	if !follow.BaseMultiplier.IsPositive() || follow.BaseMultiplier.GreaterThan(maxMultiplier) {
+		return fmt.Errorf("%w: multiplier must be above 0 and at most %s", ErrInvalidBounds, maxMultiplier)
+	}
+	if !follow.Dynamic {
+		return nil
+	}
+	if !follow.MinMultiplier.IsPositive() || follow.MaxMultiplier.GreaterThan(maxMultiplier) {
+		return fmt.Errorf("%w: bounds must be above 0 and at most %s", ErrInvalidBounds, maxMultiplier)
+	}
+	if follow.BaseMultiplier.LessThan(follow.MinMultiplier) || follow.BaseMultiplier.GreaterThan(follow.MaxMultiplier) {
+		return fmt.Errorf("%w: multiplier must be within min_multiplier and max_multiplier", ErrInvalidBounds)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package copytrade
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+var mirroredOrders = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "copytrade_mirrored_orders_total",
+	Help: "Leader orders copied into follower accounts, by outcome.",
+}, []string{"outcome"})
+
+// Engine mirrors the orders of leaders' bots into their followers'
+// accounts, scaled by each follow's multiplier. It implements bot.Mirror.
+// Follower orders go through the OMS like any other and carry a client
+// order ID derived from the leader's, so a bot replaying a trade after a
+// restart cannot copy it twice. Their fills are recorded against the
+// follow, whose realized profit the settlement fees are charged on.
+type Engine struct {
+	follows     Repository
+	oms         *orders.OMS
+	concurrency int
+}
+
+func NewEngine(follows Repository, oms *orders.OMS, concurrency int) *Engine {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Engine{follows: follows, oms: oms, concurrency: concurrency}
+}
+
+// Mirror places req for every follower of the bot's owner. Testnet trades
+// are not copied into real accounts. Failures are logged per follower and
+// never reach the leader's bot.
+func (e *Engine) Mirror(ctx context.Context, b *bot.Bot, req exchange.OrderRequest) {
+	if b.Testnet {
+		return
+	}
+	follows, err := e.follows.ListByLeader(ctx, b.UserID)
+	if err != nil {
+		log.Printf("Failed to list followers of %s: %v", b.UserID, err)
+		return
+	}
+	if len(follows) == 0 {
+		return
+	}
+	client, err := e.oms.Client(b.Exchange, false)
+	if err != nil {
+		log.Printf("Failed to mirror order %s: %v", req.ClientOrderID, err)
+		return
+	}
+
+	sem := make(chan struct{}, e.concurrency)
+	var wg sync.WaitGroup
+	for i := range follows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(follow *Follow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			e.place(ctx, client, follow, req)
+		}(&follows[i])
+	}
+	wg.Wait()
+}
+
+func (e *Engine) place(ctx context.Context, client exchange.Client, follow *Follow, req exchange.OrderRequest) {
+	copied := req
+	copied.ClientOrderID = "copy-" + follow.ID + "-" + req.ClientOrderID
+	copied.Quantity = req.Quantity.Mul(follow.Multiplier)
+
+	order, err := client.PlaceOrder(ctx, follow.FollowerID, copied)
+	switch {
+	case errors.Is(err, money.ErrBelowMinimum):
+		// The follower's share is too small for the market
+		mirroredOrders.WithLabelValues("skipped").Inc()
+		return
+	case err != nil:
+		mirroredOrders.WithLabelValues("failed").Inc()
+		log.Printf("Failed to mirror order %s to follow %s: %v", req.ClientOrderID, follow.ID, err)
+		return
+	}
+	mirroredOrders.WithLabelValues("placed").Inc()
+
+	if !order.Filled.IsPositive() {
+		return
+	}
+	err = e.follows.RecordFill(ctx, &Fill{
+		OrderID:  order.ID,
+		FollowID: follow.ID,
+		Symbol:   order.Symbol,
+		Side:     order.Side,
+		Price:    order.Price,
+		Quantity: order.Filled,
+	})
+	if err != nil {
+		log.Printf("Failed to record copied fill of order %s: %v", order.ID, err)
+	}
+}
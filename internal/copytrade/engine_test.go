@@ -0,0 +1,95 @@
+package copytrade
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+)
+
+type leaderFollows struct {
+	Repository
+	mutex   sync.Mutex
+	follows []Follow
+	fills   map[string]*Fill
+}
+
+func (r *leaderFollows) RecordFill(ctx context.Context, fill *Fill) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.fills == nil {
+		r.fills = make(map[string]*Fill)
+	}
+	r.fills[fill.OrderID] = fill
+	return nil
+}
+
+func (r *leaderFollows) ListByLeader(ctx context.Context, leaderID string) ([]Follow, error) {
+	var out []Follow
+	for _, follow := range r.follows {
+		if follow.LeaderID == leaderID {
+			out = append(out, follow)
+		}
+	}
+	return out, nil
+}
+
+func TestEngine_Mirror(t *testing.T) {
+	ctx := context.Background()
+	paper := exchange.NewPaperClient("binance")
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	for _, user := range []string{"follower-1", "follower-2"} {
+		require.NoError(t, paper.Deposit(ctx, user, decimal.NewFromInt(10000)))
+	}
+	clients := exchange.NewRegistry()
+	clients.Register("binance", paper)
+
+	follows := &leaderFollows{follows: []Follow{
+		{ID: "f-1", FollowerID: "follower-1", LeaderID: "leader", Multiplier: decimal.NewFromFloat(0.5)},
+		{ID: "f-2", FollowerID: "follower-2", LeaderID: "leader", Multiplier: decimal.NewFromInt(2)},
+		{ID: "f-3", FollowerID: "follower-3", LeaderID: "someone-else", Multiplier: decimal.NewFromInt(1)},
+	}}
+	engine := NewEngine(follows, orders.NewOMS(clients, nil, nil), 2)
+
+	req := exchange.OrderRequest{
+		ClientOrderID: "bot-1-1700000000",
+		Symbol:        "BTCUSDT",
+		Side:          exchange.SideBuy,
+		Type:          exchange.OrderTypeMarket,
+		Quantity:      decimal.NewFromInt(4),
+	}
+	leaderBot := &bot.Bot{ID: "bot-1", UserID: "leader", Exchange: "binance", Symbol: "BTCUSDT"}
+	engine.Mirror(ctx, leaderBot, req)
+	// A replayed trade is not copied again
+	engine.Mirror(ctx, leaderBot, req)
+
+	for user, quantity := range map[string]string{"follower-1": "2", "follower-2": "8"} {
+		positions, err := paper.Positions(ctx, user, "BTCUSDT")
+		require.NoError(t, err)
+		require.Len(t, positions, 1, user)
+		assert.Equal(t, quantity, positions[0].Quantity.String(), user)
+	}
+	// Fills are recorded against the follow, once per order
+	require.Len(t, follows.fills, 2)
+	for _, fill := range follows.fills {
+		assert.Equal(t, "100", fill.Price.String())
+	}
+
+	positions, err := paper.Positions(ctx, "follower-3", "BTCUSDT")
+	require.NoError(t, err)
+	assert.Empty(t, positions)
+
+	// Sandbox trades stay out of real accounts
+	engine.Mirror(ctx, &bot.Bot{ID: "bot-2", UserID: "leader", Exchange: "binance", Symbol: "BTCUSDT", Testnet: true},
+		exchange.OrderRequest{ClientOrderID: "bot-2-1700000000", Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.NewFromInt(1)})
+	positions, err = paper.Positions(ctx, "follower-1", "BTCUSDT")
+	require.NoError(t, err)
+	assert.Equal(t, "2", positions[0].Quantity.String())
+}
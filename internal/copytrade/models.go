@@ -0,0 +1,121 @@
+package copytrade
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+// Follow copies a leader's trades into a follower's account, scaled by
+// Multiplier. With Dynamic set the allocator moves the multiplier within
+// [MinMultiplier, MaxMultiplier] according to the leader's recent
+// performance; otherwise it stays at BaseMultiplier.
+type Follow struct {
+	ID             string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FollowerID     string          `json:"follower_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_copy_follows_pair,priority:1"`
+	LeaderID       string          `json:"leader_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_copy_follows_pair,priority:2;index"`
+	BaseMultiplier decimal.Decimal `json:"base_multiplier" gorm:"type:numeric;not null"`
+	Multiplier     decimal.Decimal `json:"multiplier" gorm:"type:numeric;not null"`
+	Dynamic        bool            `json:"dynamic" gorm:"not null;default:false;index"`
+	MinMultiplier  decimal.Decimal `json:"min_multiplier" gorm:"type:numeric"`
+	MaxMultiplier  decimal.Decimal `json:"max_multiplier" gorm:"type:numeric"`
+	// RealizedProfit is the running profit realized by the trades copied
+	// under this follow
+	RealizedProfit decimal.Decimal `json:"realized_profit" gorm:"type:numeric;not null;default:0"`
+	// FeeRate is the share of RealizedProfit above HighWaterMark charged
+	// at each settlement, fixed when the follow is created
+	FeeRate decimal.Decimal `json:"fee_rate" gorm:"type:numeric;not null;default:0"`
+	// HighWaterMark is the RealizedProfit fees were last charged up to, so
+	// losses are recovered before any new fee is due
+	HighWaterMark decimal.Decimal `json:"high_water_mark" gorm:"type:numeric;not null;default:0"`
+	// SettledAt is the end of the last settled period; unset until the
+	// first settlement
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Follow) TableName() string {
+	return "copy_follows"
+}
+
+// AllocationChange records one adjustment of a follow's multiplier and the
+// leader performance that caused it.
+type AllocationChange struct {
+	ID       string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FollowID string          `json:"follow_id" gorm:"type:varchar(36);not null;index:idx_copy_allocation_changes_follow,priority:1"`
+	Previous decimal.Decimal `json:"previous" gorm:"type:numeric"`
+	Current  decimal.Decimal `json:"current" gorm:"type:numeric"`
+	// Return and Drawdown are the leader's over the evaluation window
+	Return    float64   `json:"return"`
+	Drawdown  float64   `json:"drawdown"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_copy_allocation_changes_follow,priority:2,sort:desc"`
+}
+
+// TableName sets the table name for GORM
+func (AllocationChange) TableName() string {
+	return "copy_allocation_changes"
+}
+
+// Position is the net position a follow's copied trades hold in a symbol.
+// It is kept apart from the follower's own trading on the exchange so only
+// profit made by copying is charged for.
+type Position struct {
+	FollowID   string          `json:"follow_id" gorm:"primaryKey;type:varchar(36)"`
+	Symbol     string          `json:"symbol" gorm:"primaryKey"`
+	Side       exchange.Side   `json:"side"`
+	Quantity   decimal.Decimal `json:"quantity" gorm:"type:numeric"`
+	EntryPrice decimal.Decimal `json:"entry_price" gorm:"type:numeric"`
+}
+
+// TableName sets the table name for GORM
+func (Position) TableName() string {
+	return "copy_positions"
+}
+
+// Fill is one execution of a copied order. It is recorded once per order,
+// so a replayed leader trade cannot realize its profit twice.
+type Fill struct {
+	OrderID  string          `json:"order_id" gorm:"primaryKey;type:varchar(64)"`
+	FollowID string          `json:"follow_id" gorm:"type:varchar(36);not null;index"`
+	Symbol   string          `json:"symbol" gorm:"not null"`
+	Side     exchange.Side   `json:"side" gorm:"not null"`
+	Price    decimal.Decimal `json:"price" gorm:"type:numeric"`
+	Quantity decimal.Decimal `json:"quantity" gorm:"type:numeric"`
+	// RealizedProfit is what the fill realized by reducing the position
+	RealizedProfit decimal.Decimal `json:"realized_profit" gorm:"type:numeric"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Fill) TableName() string {
+	return "copy_fills"
+}
+
+// apply adds the fill to the position with average-cost accounting and
+// returns the profit it realized.
+func (p *Position) apply(side exchange.Side, quantity, price decimal.Decimal) decimal.Decimal {
+	if p.Quantity.IsZero() || p.Side == side {
+		total := p.Quantity.Add(quantity)
+		p.EntryPrice = p.EntryPrice.Mul(p.Quantity).Add(price.Mul(quantity)).Div(total)
+		p.Quantity, p.Side = total, side
+		return decimal.Zero
+	}
+
+	closed := decimal.Min(p.Quantity, quantity)
+	realized := price.Sub(p.EntryPrice).Mul(closed)
+	if p.Side == exchange.SideSell {
+		realized = realized.Neg()
+	}
+
+	if remaining := p.Quantity.Sub(quantity); remaining.IsNegative() {
+		// The fill flipped the position
+		p.Side, p.Quantity, p.EntryPrice = side, remaining.Neg(), price
+	} else {
+		p.Quantity = remaining
+	}
+	return realized
+}
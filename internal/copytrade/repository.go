@@ -0,0 +1,166 @@
+package copytrade
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrFollowNotFound = errors.New("follow not found")
+
+type Repository interface {
+	Create(ctx context.Context, follow *Follow) error
+	Get(ctx context.Context, followerID, id string) (*Follow, error)
+	ListByFollower(ctx context.Context, followerID string) ([]Follow, error)
+	Update(ctx context.Context, follow *Follow) error
+	Delete(ctx context.Context, followerID, id string) error
+	// ListByLeader returns the follows whose trades copy the leader's
+	ListByLeader(ctx context.Context, leaderID string) ([]Follow, error)
+	// ListAll returns every follow, grouped by follower
+	ListAll(ctx context.Context) ([]Follow, error)
+	// ListDynamic returns every follow the allocator manages
+	ListDynamic(ctx context.Context) ([]Follow, error)
+	// SetMultiplier stores a new multiplier together with its change log
+	// entry
+	SetMultiplier(ctx context.Context, follow *Follow, change *AllocationChange) error
+	ListChanges(ctx context.Context, followID string, limit int) ([]AllocationChange, error)
+	// RecordFill applies a copied fill to the follow's position and adds
+	// the profit it realizes to the follow. Fills already recorded are
+	// ignored.
+	RecordFill(ctx context.Context, fill *Fill) error
+	// FollowerCounts returns the number of followers per leader
+	FollowerCounts(ctx context.Context) (map[string]int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, follow *Follow) error {
+	return r.db.WithContext(ctx).Create(follow).Error
+}
+
+func (r *repository) Get(ctx context.Context, followerID, id string) (*Follow, error) {
+	var follow Follow
+	err := r.db.WithContext(ctx).Where("id = ? AND follower_id = ?", id, followerID).First(&follow).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFollowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+func (r *repository) ListByFollower(ctx context.Context, followerID string) ([]Follow, error) {
+	var follows []Follow
+	err := r.db.WithContext(ctx).Where("follower_id = ?", followerID).Order("created_at DESC").Find(&follows).Error
+	return follows, err
+}
+
+func (r *repository) Update(ctx context.Context, follow *Follow) error {
+	return r.db.WithContext(ctx).Save(follow).Error
+}
+
+func (r *repository) Delete(ctx context.Context, followerID, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND follower_id = ?", id, followerID).Delete(&Follow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFollowNotFound
+	}
+	return nil
+}
+
+func (r *repository) ListByLeader(ctx context.Context, leaderID string) ([]Follow, error) {
+	var follows []Follow
+	err := r.db.WithContext(ctx).Where("leader_id = ?", leaderID).Order("id").Find(&follows).Error
+	return follows, err
+}
+
+func (r *repository) ListAll(ctx context.Context) ([]Follow, error) {
+	var follows []Follow
+	err := r.db.WithContext(ctx).Order("follower_id, id").Find(&follows).Error
+	return follows, err
+}
+
+func (r *repository) ListDynamic(ctx context.Context) ([]Follow, error) {
+	var follows []Follow
+	err := r.db.WithContext(ctx).Where("dynamic = ?", true).Order("id").Find(&follows).Error
+	return follows, err
+}
+
+func (r *repository) FollowerCounts(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		LeaderID  string
+		Followers int64
+	}
+	err := r.db.WithContext(ctx).Model(&Follow{}).
+		Select("leader_id, COUNT(*) AS followers").Group("leader_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.LeaderID] = row.Followers
+	}
+	return counts, nil
+}
+
+func (r *repository) SetMultiplier(ctx context.Context, follow *Follow, change *AllocationChange) error {
+	if change.ID == "" {
+		change.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Follow{}).Where("id = ?", follow.ID).Update("multiplier", follow.Multiplier).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *repository) ListChanges(ctx context.Context, followID string, limit int) ([]AllocationChange, error) {
+	var changes []AllocationChange
+	err := r.db.WithContext(ctx).Where("follow_id = ?", followID).Order("created_at DESC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+func (r *repository) RecordFill(ctx context.Context, fill *Fill) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(fill)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		position := Position{FollowID: fill.FollowID, Symbol: fill.Symbol}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("follow_id = ? AND symbol = ?", fill.FollowID, fill.Symbol).First(&position).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		fill.RealizedProfit = position.apply(fill.Side, fill.Quantity, fill.Price)
+		if err := tx.Save(&position).Error; err != nil {
+			return err
+		}
+		if fill.RealizedProfit.IsZero() {
+			return nil
+		}
+
+		err = tx.Model(fill).Update("realized_profit", fill.RealizedProfit).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Follow{}).Where("id = ?", fill.FollowID).
+			Update("realized_profit", gorm.Expr("realized_profit + ?", fill.RealizedProfit)).Error
+	})
+}
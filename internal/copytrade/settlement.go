@@ -0,0 +1,212 @@
+package copytrade
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Settlement is one period of a follow's profit sharing. The follower pays
+// FeeRate of the realized profit above the high-water mark; the platform
+// keeps its commission out of that fee and the rest is paid out to the
+// leader.
+type Settlement struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FollowID    string    `json:"follow_id" gorm:"type:varchar(36);not null;index"`
+	FollowerID  string    `json:"follower_id" gorm:"type:varchar(36);not null;index"`
+	LeaderID    string    `json:"leader_id" gorm:"type:varchar(36);not null;index:idx_copy_settlements_leader,priority:1"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" gorm:"not null;index:idx_copy_settlements_leader,priority:2;index"`
+	// RealizedProfit is the follow's running realized profit at PeriodEnd
+	RealizedProfit decimal.Decimal `json:"realized_profit" gorm:"type:numeric"`
+	HighWaterMark  decimal.Decimal `json:"high_water_mark" gorm:"type:numeric"`
+	Profit         decimal.Decimal `json:"profit" gorm:"type:numeric"`
+	FeeRate        decimal.Decimal `json:"fee_rate" gorm:"type:numeric"`
+	Fee            decimal.Decimal `json:"fee" gorm:"type:numeric"`
+	Commission     decimal.Decimal `json:"platform_commission" gorm:"type:numeric"`
+	LeaderPayout   decimal.Decimal `json:"leader_payout" gorm:"type:numeric"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Settlement) TableName() string {
+	return "copy_settlements"
+}
+
+// Totals sums settlements.
+type Totals struct {
+	Settlements  int64           `json:"settlements"`
+	Profit       decimal.Decimal `json:"profit"`
+	Fees         decimal.Decimal `json:"fees"`
+	Commission   decimal.Decimal `json:"platform_commission"`
+	LeaderPayout decimal.Decimal `json:"leader_payout"`
+}
+
+// Statement lists what a leader earned from their followers in a period.
+type Statement struct {
+	LeaderID    string       `json:"leader_id"`
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	Totals      Totals       `json:"totals"`
+	Settlements []Settlement `json:"settlements"`
+}
+
+type SettlementRepository interface {
+	// Settle stores the settlement, if any, and moves the follow's
+	// high-water mark and settled time in one transaction
+	Settle(ctx context.Context, follow *Follow, settlement *Settlement) error
+	// ListByFollow returns the follow's settlements, newest first
+	ListByFollow(ctx context.Context, followID string, limit int) ([]Settlement, error)
+	// Statement covers settlements whose period ended in [from, to)
+	Statement(ctx context.Context, leaderID string, from, to time.Time) (*Statement, error)
+	// Commission totals every settlement whose period ended in [from, to)
+	Commission(ctx context.Context, from, to time.Time) (*Totals, error)
+}
+
+type settlementRepository struct {
+	db *gorm.DB
+}
+
+func NewSettlementRepository(db *gorm.DB) SettlementRepository {
+	return &settlementRepository{db: db}
+}
+
+func (r *settlementRepository) Settle(ctx context.Context, follow *Follow, settlement *Settlement) error {
+	if settlement != nil && settlement.ID == "" {
+		settlement.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Follow{}).Where("id = ?", follow.ID).Updates(map[string]interface{}{
+			"high_water_mark": follow.HighWaterMark,
+			"settled_at":      follow.SettledAt,
+		}).Error
+		if err != nil || settlement == nil {
+			return err
+		}
+		return tx.Create(settlement).Error
+	})
+}
+
+func (r *settlementRepository) ListByFollow(ctx context.Context, followID string, limit int) ([]Settlement, error) {
+	var settlements []Settlement
+	err := r.db.WithContext(ctx).Where("follow_id = ?", followID).Order("period_end DESC").Limit(limit).Find(&settlements).Error
+	return settlements, err
+}
+
+func (r *settlementRepository) Statement(ctx context.Context, leaderID string, from, to time.Time) (*Statement, error) {
+	var settlements []Settlement
+	err := r.db.WithContext(ctx).
+		Where("leader_id = ? AND period_end >= ? AND period_end < ?", leaderID, from, to).
+		Order("period_end").Find(&settlements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		LeaderID:    leaderID,
+		From:        from,
+		To:          to,
+		Totals:      sum(settlements),
+		Settlements: settlements,
+	}, nil
+}
+
+func (r *settlementRepository) Commission(ctx context.Context, from, to time.Time) (*Totals, error) {
+	var settlements []Settlement
+	err := r.db.WithContext(ctx).
+		Where("period_end >= ? AND period_end < ?", from, to).
+		Find(&settlements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := sum(settlements)
+	return &totals, nil
+}
+
+func sum(settlements []Settlement) Totals {
+	totals := Totals{Settlements: int64(len(settlements))}
+	for _, s := range settlements {
+		totals.Profit = totals.Profit.Add(s.Profit)
+		totals.Fees = totals.Fees.Add(s.Fee)
+		totals.Commission = totals.Commission.Add(s.Commission)
+		totals.LeaderPayout = totals.LeaderPayout.Add(s.LeaderPayout)
+	}
+	return totals
+}
+
+// Settler charges profit-sharing fees on every follow once per period.
+// Fees are due on profit the copied trades realized, never on equity, so
+// neither deposits nor open positions nor the follower's own trading are
+// charged for.
+type Settler struct {
+	follows     Repository
+	settlements SettlementRepository
+	// commission is the platform's share of each fee
+	commission decimal.Decimal
+}
+
+func NewSettler(follows Repository, settlements SettlementRepository, commission float64) *Settler {
+	return &Settler{
+		follows:     follows,
+		settlements: settlements,
+		commission:  decimal.NewFromFloat(commission),
+	}
+}
+
+// Run matches scheduler.JobFunc.
+func (s *Settler) Run(ctx context.Context) error {
+	follows, err := s.follows.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list follows: %w", err)
+	}
+
+	now := time.Now().UTC()
+	settled := 0
+	for i := range follows {
+		follow := &follows[i]
+		settlement := s.settle(follow, now)
+		if err := s.settlements.Settle(ctx, follow, settlement); err != nil {
+			log.Printf("Failed to settle follow %s: %v", follow.ID, err)
+			continue
+		}
+		settled++
+	}
+
+	log.Printf("Settled %d of %d copy follows", settled, len(follows))
+	return nil
+}
+
+// settle closes the follow's period. Fees are charged on the realized
+// profit above the high-water mark, which then moves up to it; realized
+// losses are carried until recovered.
+func (s *Settler) settle(follow *Follow, now time.Time) *Settlement {
+	start := follow.CreatedAt
+	if follow.SettledAt != nil {
+		start = *follow.SettledAt
+	}
+	settlement := &Settlement{
+		FollowID:       follow.ID,
+		FollowerID:     follow.FollowerID,
+		LeaderID:       follow.LeaderID,
+		PeriodStart:    start,
+		PeriodEnd:      now,
+		RealizedProfit: follow.RealizedProfit,
+		HighWaterMark:  follow.HighWaterMark,
+		FeeRate:        follow.FeeRate,
+	}
+	if profit := follow.RealizedProfit.Sub(follow.HighWaterMark); profit.IsPositive() {
+		settlement.Profit = profit
+		settlement.Fee = profit.Mul(follow.FeeRate).Round(8)
+		settlement.Commission = settlement.Fee.Mul(s.commission).Round(8)
+		settlement.LeaderPayout = settlement.Fee.Sub(settlement.Commission)
+		follow.HighWaterMark = follow.RealizedProfit
+	}
+	follow.SettledAt = &now
+	return settlement
+}
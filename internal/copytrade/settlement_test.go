@@ -0,0 +1,63 @@
+package copytrade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+func TestPosition_Apply(t *testing.T) {
+	d := decimal.RequireFromString
+	var position Position
+
+	assert.True(t, position.apply(exchange.SideBuy, d("2"), d("100")).IsZero())
+	assert.True(t, position.apply(exchange.SideBuy, d("2"), d("110")).IsZero())
+	assert.Equal(t, "105", position.EntryPrice.String())
+
+	// Closing half at 120 realizes 2 * (120 - 105)
+	assert.Equal(t, "30", position.apply(exchange.SideSell, d("2"), d("120")).String())
+	assert.Equal(t, "2", position.Quantity.String())
+
+	// Selling 3 closes the rest at a loss and flips to a 1 short
+	assert.Equal(t, "-10", position.apply(exchange.SideSell, d("3"), d("100")).String())
+	assert.Equal(t, exchange.SideSell, position.Side)
+	assert.Equal(t, "1", position.Quantity.String())
+	assert.Equal(t, "100", position.EntryPrice.String())
+
+	// Shorts profit when the price falls
+	assert.Equal(t, "10", position.apply(exchange.SideBuy, d("1"), d("90")).String())
+	assert.True(t, position.Quantity.IsZero())
+}
+
+func TestSettler_Settle(t *testing.T) {
+	d := decimal.RequireFromString
+	settler := NewSettler(nil, nil, 0.25)
+	follow := &Follow{ID: "f-1", FeeRate: d("0.2"), CreatedAt: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name     string
+		realized string
+		profit   string
+		fee      string
+		mark     string
+	}{
+		{name: "profit is charged", realized: "100", profit: "100", fee: "20", mark: "100"},
+		{name: "loss carries forward", realized: "40", profit: "0", fee: "0", mark: "100"},
+		{name: "recovery is not charged", realized: "100", profit: "0", fee: "0", mark: "100"},
+		{name: "only profit above the mark", realized: "150", profit: "50", fee: "10", mark: "150"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			follow.RealizedProfit = d(tt.realized)
+			settlement := settler.settle(follow, time.Now())
+
+			assert.Equal(t, tt.profit, settlement.Profit.String())
+			assert.Equal(t, tt.fee, settlement.Fee.String())
+			assert.Equal(t, tt.mark, follow.HighWaterMark.String())
+			assert.Equal(t, settlement.Fee.Mul(d("0.25")).String(), settlement.Commission.String())
+		})
+	}
+}
@@ -5,7 +5,19 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/tradingbothub/platform/internal/approval"
 	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/billing"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/copytrade"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/scheduler"
+	"github.com/tradingbothub/platform/internal/share"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/tags"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -42,8 +54,112 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// Accounts that predate email verification are treated as verified
+	grandfatherEmails := db.Migrator().HasTable(&auth.User{}) && !db.Migrator().HasColumn(&auth.User{}, "email_verified")
+
+	err := db.AutoMigrate(
 		&auth.User{},
+		&auth.EmailVerification{},
+		&auth.PasswordReset{},
+		&auth.EmailChange{},
+		&auth.MagicLink{},
+		&auth.SSOConnection{},
+		&auth.SSOIdentity{},
+		&auth.SSOState{},
+		&auth.Session{},
+		&auth.RefreshToken{},
+		&auth.AuditEvent{},
+		&auth.PlanChange{},
+		&auth.LoginEvent{},
+		&auth.AllowedNetwork{},
+		&auth.Role{},
+		&auth.RolePermission{},
+		&auth.UserRole{},
+		&scheduler.Job{},
+		&orders.Order{},
+		&orders.Trade{},
+		&orders.Holding{},
+		&orders.Group{},
+		&orders.Leg{},
+		&bot.Bot{},
+		&bot.Checkpoint{},
+		&bot.SignalRecord{},
+		&strategy.Strategy{},
+		&org.Organization{},
+		&org.Member{},
+		&org.Invitation{},
+		&tags.SavedFilter{},
+		&approval.Request{},
+		&share.Link{},
+		&exchange.APIKey{},
+		&exchange.KeyGrant{},
+		&exchange.KeyEvent{},
+		&exchange.WithdrawalAddress{},
+		&copytrade.Follow{},
+		&copytrade.AllocationChange{},
+		&copytrade.Flag{},
+		&copytrade.Settlement{},
+		&copytrade.Position{},
+		&copytrade.Fill{},
+		&billing.Invoice{},
+		&billing.LineItem{},
+		&billing.DailyUsage{},
+		&backtest.Job{},
+		&backtest.QueueClock{},
+		&backtest.QueueUser{},
 		// Add more models here as we develop other services
 	)
+	if err != nil {
+		return err
+	}
+
+	if grandfatherEmails {
+		if err := db.Model(&auth.User{}).Where("1 = 1").Update("email_verified", true).Error; err != nil {
+			return fmt.Errorf("failed to mark existing users verified: %w", err)
+		}
+	}
+
+	if err := dropReplacedIndexes(db); err != nil {
+		return err
+	}
+	return createSearchIndexes(db)
+}
+
+// dropReplacedIndexes removes indexes that were superseded under a new
+// name, since AutoMigrate only ever adds indexes. The history filter
+// indexes gained the ID, which pages break ties on.
+func dropReplacedIndexes(db *gorm.DB) error {
+	indexes := []string{
+		"idx_orders_user_symbol",
+		"idx_orders_user_bot",
+		"idx_orders_user_status",
+		"idx_trades_user_symbol",
+		"idx_trades_user_bot",
+	}
+
+	for _, index := range indexes {
+		if err := db.Exec("DROP INDEX IF EXISTS " + index).Error; err != nil {
+			return fmt.Errorf("failed to drop index %s: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// createSearchIndexes adds the trigram indexes behind global search, which
+// GORM tags cannot express.
+func createSearchIndexes(db *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_bots_name_trgm ON bots USING gin (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_strategies_name_trgm ON strategies USING gin (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_orders_symbol_trgm ON orders USING gin (symbol gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_orders_client_order_id_trgm ON orders USING gin (client_order_id gin_trgm_ops)",
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create search indexes: %w", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,95 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/share"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/tags"
+	"gorm.io/gorm"
+)
+
+// GenerationKey counts demo resets. Gateways compare it with the
+// generation they last funded to know when to refill the paper wallet.
+const GenerationKey = "demo:generation"
+
+// Generation returns the current reset generation.
+func Generation(ctx context.Context, client redis.UniversalClient) (int64, error) {
+	value, err := client.Get(ctx, GenerationKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Resetter wipes everything the demo account created. The user itself is
+// kept so the docs keep working with the configured credentials.
+type Resetter struct {
+	db      *gorm.DB
+	tradeDB *gorm.DB
+	redis   redis.UniversalClient
+	email   string
+}
+
+// NewResetter takes the primary database and the database of the demo
+// user's data region, which hold the user's orders and trades.
+func NewResetter(db, tradeDB *gorm.DB, redisClient redis.UniversalClient, email string) *Resetter {
+	return &Resetter{db: db, tradeDB: tradeDB, redis: redisClient, email: email}
+}
+
+// Run matches scheduler.JobFunc.
+func (r *Resetter) Run(ctx context.Context) error {
+	var user auth.User
+	err := r.db.WithContext(ctx).Where("email = ?", r.email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Nobody has used the sandbox yet
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up demo user: %w", err)
+	}
+
+	err = r.tradeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&orders.Trade{}, &orders.Holding{}, &orders.Order{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete demo trades: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		models := []interface{}{&bot.Bot{}, &strategy.Strategy{}, &tags.SavedFilter{}, &share.Link{}}
+		for _, model := range models {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete demo data: %w", err)
+	}
+
+	generation, err := r.redis.Incr(ctx, GenerationKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to bump demo generation: %w", err)
+	}
+
+	log.Printf("Reset demo account %s (generation %d)", user.ID, generation)
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package email sends transactional mail such as verification links.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/pkg/retry"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages. Implementations must be safe for concurrent
+// use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the sender of the configured backend: "smtp", or "log" to
+// print messages instead of sending them during local development. SMTP
+// delivery is retried under cfg.Retry.
+func New(cfg config.EmailConfig) (Sender, error) {
+	switch cfg.Backend {
+	case "", "log":
+		return LogSender{}, nil
+	case "smtp":
+		return &retryingSender{
+			Sender: NewSMTPSender(cfg),
+			policy: retry.New("email_smtp", cfg.Retry, retry.WithClassifier(Retryable)),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown email backend %q", cfg.Backend)
+}
+
+// LogSender writes messages to the log.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPSender delivers plain-text messages through an SMTP relay.
+type SMTPSender struct {
+	addr string
+	// from is the From header, envelope the bare address inside it
+	from     string
+	envelope string
+	auth     smtp.Auth
+}
+
+func NewSMTPSender(cfg config.EmailConfig) *SMTPSender {
+	s := &SMTPSender{
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from:     cfg.From,
+		envelope: cfg.From,
+	}
+	if address, err := mail.ParseAddress(cfg.From); err == nil {
+		s.envelope = address.Address
+	}
+	if cfg.SMTPUsername != "" {
+		s.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	// Header injection would let a crafted address add recipients
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return retry.Terminal(fmt.Errorf("invalid email header"))
+	}
+
+	body := "From: " + s.from + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		msg.Body
+
+	if err := smtp.SendMail(s.addr, s.auth, s.envelope, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// Retryable reports whether sending may succeed on another attempt. SMTP
+// permanent failures, with 5xx codes such as an unknown mailbox, do not.
+func Retryable(err error) bool {
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+	return true
+}
+
+type retryingSender struct {
+	Sender
+	policy *retry.Policy
+}
+
+func (s *retryingSender) Send(ctx context.Context, msg Message) error {
+	return s.policy.Do(ctx, func(ctx context.Context) error {
+		return s.Sender.Send(ctx, msg)
+	})
+}
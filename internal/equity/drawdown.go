@@ -0,0 +1,63 @@
+package equity
+
+import (
+	"time"
+
+	"github.com/tradingbothub/platform/internal/marketdata"
+)
+
+// Drawdown describes the largest peak-to-trough decline of an equity curve.
+type Drawdown struct {
+	// Max is the decline as a fraction of the peak, between 0 and 1
+	Max    float64   `json:"max"`
+	Peak   time.Time `json:"peak"`
+	Trough time.Time `json:"trough"`
+}
+
+// MaxDrawdown scans an equity series (oldest first) for its largest decline.
+func MaxDrawdown(points []marketdata.Point) Drawdown {
+	var dd Drawdown
+	if len(points) == 0 {
+		return dd
+	}
+
+	peak := points[0]
+	for _, p := range points[1:] {
+		if p.Value > peak.Value {
+			peak = p
+			continue
+		}
+		if peak.Value <= 0 {
+			continue
+		}
+		if decline := (peak.Value - p.Value) / peak.Value; decline > dd.Max {
+			dd = Drawdown{Max: decline, Peak: peak.Time, Trough: p.Time}
+		}
+	}
+
+	return dd
+}
+
+// Performance summarizes an equity series.
+type Performance struct {
+	Start       float64  `json:"start"`
+	End         float64  `json:"end"`
+	Return      float64  `json:"return"`
+	MaxDrawdown Drawdown `json:"max_drawdown"`
+}
+
+func Summarize(points []marketdata.Point) Performance {
+	if len(points) == 0 {
+		return Performance{}
+	}
+
+	perf := Performance{
+		Start:       points[0].Value,
+		End:         points[len(points)-1].Value,
+		MaxDrawdown: MaxDrawdown(points),
+	}
+	if perf.Start != 0 {
+		perf.Return = (perf.End - perf.Start) / perf.Start
+	}
+	return perf
+}
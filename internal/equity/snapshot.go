@@ -0,0 +1,192 @@
+package equity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+	"gorm.io/gorm"
+)
+
+const (
+	KindUser = "user"
+	KindBot  = "bot"
+)
+
+// Snapshot is the equity of a user or bot at a point in time.
+type Snapshot struct {
+	Kind    string          `json:"kind"`
+	UserID  string          `json:"user_id"`
+	BotID   string          `json:"bot_id,omitempty"`
+	Balance decimal.Decimal `json:"balance"`
+	// Positions is what open positions add to the balance: unrealized PnL
+	// for margin accounts, marked value for a bot's spot holdings
+	Positions decimal.Decimal `json:"positions"`
+	Equity    decimal.Decimal `json:"equity"`
+	Time      time.Time       `json:"time"`
+}
+
+// Snapshotter values every active user's exchange accounts and every
+// running bot's allocation, and stores the result as one point per account
+// so drawdowns, charts and the leaderboard read precomputed series.
+type Snapshotter struct {
+	db        *gorm.DB
+	bots      bot.Repository
+	exchanges *exchange.Registry
+	store     Store
+}
+
+func NewSnapshotter(db *gorm.DB, bots bot.Repository, exchanges *exchange.Registry, store Store) *Snapshotter {
+	return &Snapshotter{db: db, bots: bots, exchanges: exchanges, store: store}
+}
+
+// Run takes one round of snapshots. It matches scheduler.JobFunc so the
+// cadence is controlled by the job schedule.
+func (s *Snapshotter) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	users, err := s.userSnapshots(ctx, now)
+	if err != nil {
+		return err
+	}
+	bots, err := s.botSnapshots(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Write(ctx, append(users, bots...))
+}
+
+func (s *Snapshotter) userSnapshots(ctx context.Context, now time.Time) ([]Snapshot, error) {
+	var userIDs []string
+	err := s.db.WithContext(ctx).Model(&auth.User{}).Where("is_active = ?", true).Pluck("id", &userIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(userIDs))
+	for _, userID := range userIDs {
+		snapshot, err := s.userSnapshot(ctx, userID, now)
+		if err != nil {
+			// A partial sum would show up as a fake drawdown
+			log.Printf("Skipping equity snapshot for user %s: %v", userID, err)
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// userSnapshot sums the user's accounts across all exchanges.
+func (s *Snapshotter) userSnapshot(ctx context.Context, userID string, now time.Time) (*Snapshot, error) {
+	snapshot := &Snapshot{Kind: KindUser, UserID: userID, Time: now}
+
+	for _, name := range s.exchanges.Names() {
+		client, err := s.exchanges.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		account, err := client.Account(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		snapshot.Balance = snapshot.Balance.Add(account.Balance)
+		snapshot.Positions = snapshot.Positions.Add(account.UnrealizedPnL)
+	}
+
+	snapshot.Equity = snapshot.Balance.Add(snapshot.Positions)
+	return snapshot, nil
+}
+
+// botHolding is the net result of a bot's trades in one market.
+type botHolding struct {
+	BotID    string
+	Exchange string
+	Symbol   string
+	Position decimal.Decimal
+	Cash     decimal.Decimal
+}
+
+// botSnapshots values each bot as its allocated capital plus the cash flow
+// of its trades plus its net position at the last price. Testnet bots are
+// left out so sandbox results never show up in portfolio analytics.
+func (s *Snapshotter) botSnapshots(ctx context.Context, now time.Time) ([]Snapshot, error) {
+	running, err := s.bots.ListRunning(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bots: %w", err)
+	}
+	var bots []bot.Bot
+	for _, b := range running {
+		if !b.Testnet {
+			bots = append(bots, b)
+		}
+	}
+	if len(bots) == 0 {
+		return nil, nil
+	}
+
+	botIDs := make([]string, len(bots))
+	for i, b := range bots {
+		botIDs[i] = b.ID
+	}
+
+	var holdings []botHolding
+	err = s.db.WithContext(ctx).Model(&orders.Trade{}).
+		Select(`bot_id, exchange, symbol,
+			SUM(CASE WHEN side = 'buy' THEN quantity ELSE -quantity END) AS position,
+			SUM(CASE WHEN side = 'buy' THEN -price * quantity ELSE price * quantity END) - SUM(COALESCE(fee, 0)) AS cash`).
+		Where("bot_id IN ? AND testnet = ?", botIDs, false).
+		Group("bot_id, exchange, symbol").
+		Scan(&holdings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate bot trades: %w", err)
+	}
+
+	byBot := make(map[string][]botHolding, len(bots))
+	for _, h := range holdings {
+		byBot[h.BotID] = append(byBot[h.BotID], h)
+	}
+
+	snapshots := make([]Snapshot, 0, len(bots))
+	for _, b := range bots {
+		snapshot, err := s.botSnapshot(ctx, b, byBot[b.ID], now)
+		if err != nil {
+			log.Printf("Skipping equity snapshot for bot %s: %v", b.ID, err)
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	return snapshots, nil
+}
+
+func (s *Snapshotter) botSnapshot(ctx context.Context, b bot.Bot, holdings []botHolding, now time.Time) (*Snapshot, error) {
+	snapshot := &Snapshot{Kind: KindBot, UserID: b.UserID, BotID: b.ID, Balance: b.Capital, Time: now}
+
+	for _, h := range holdings {
+		snapshot.Balance = snapshot.Balance.Add(h.Cash)
+		if h.Position.IsZero() {
+			continue
+		}
+
+		client, err := s.exchanges.Get(h.Exchange)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", h.Exchange, err)
+		}
+		price, err := client.LastPrice(ctx, h.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", h.Exchange, h.Symbol, err)
+		}
+		snapshot.Positions = snapshot.Positions.Add(h.Position.Mul(price))
+	}
+
+	snapshot.Equity = snapshot.Balance.Add(snapshot.Positions)
+	return snapshot, nil
+}
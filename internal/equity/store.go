@@ -0,0 +1,98 @@
+package equity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/marketdata"
+)
+
+// Store persists equity snapshots and reads them back as time series.
+type Store interface {
+	Write(ctx context.Context, snapshots []Snapshot) error
+	// Series returns the equity of a user (botID empty) or bot, oldest first
+	Series(ctx context.Context, userID, botID string, from, to time.Time) ([]marketdata.Point, error)
+}
+
+// InfluxStore writes the "equity" measurement, tagged by kind, user_id and
+// bot_id with balance/positions/equity fields.
+type InfluxStore struct {
+	client   influxdb2.Client
+	writeAPI api.WriteAPIBlocking
+	queryAPI api.QueryAPI
+	bucket   string
+}
+
+func NewInfluxStore(cfg config.InfluxConfig) *InfluxStore {
+	client := influxdb2.NewClient(cfg.URL, cfg.Token)
+	return &InfluxStore{
+		client:   client,
+		writeAPI: client.WriteAPIBlocking(cfg.Org, cfg.Bucket),
+		queryAPI: client.QueryAPI(cfg.Org),
+		bucket:   cfg.Bucket,
+	}
+}
+
+func (s *InfluxStore) Write(ctx context.Context, snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	points := make([]*write.Point, len(snapshots))
+	for i, snapshot := range snapshots {
+		tags := map[string]string{"kind": snapshot.Kind, "user_id": snapshot.UserID}
+		if snapshot.BotID != "" {
+			tags["bot_id"] = snapshot.BotID
+		}
+		points[i] = influxdb2.NewPoint("equity", tags, map[string]interface{}{
+			"balance":   snapshot.Balance.InexactFloat64(),
+			"positions": snapshot.Positions.InexactFloat64(),
+			"equity":    snapshot.Equity.InexactFloat64(),
+		}, snapshot.Time)
+	}
+
+	if err := s.writeAPI.WritePoint(ctx, points...); err != nil {
+		return fmt.Errorf("failed to write equity snapshots: %w", err)
+	}
+	return nil
+}
+
+func (s *InfluxStore) Series(ctx context.Context, userID, botID string, from, to time.Time) ([]marketdata.Point, error) {
+	filter := fmt.Sprintf(`r.kind == %q and r.user_id == %q`, KindUser, userID)
+	if botID != "" {
+		filter = fmt.Sprintf(`r.kind == %q and r.bot_id == %q`, KindBot, botID)
+	}
+
+	query := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == "equity" and r._field == "equity" and %s)
+  |> sort(columns: ["_time"])`,
+		s.bucket, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), filter)
+
+	result, err := s.queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query equity: %w", err)
+	}
+	defer result.Close()
+
+	var points []marketdata.Point
+	for result.Next() {
+		record := result.Record()
+		value, _ := record.Value().(float64)
+		points = append(points, marketdata.Point{Time: record.Time(), Value: value})
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to read equity: %w", result.Err())
+	}
+
+	return points, nil
+}
+
+func (s *InfluxStore) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,243 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//go:generate go run ./gen
+
+var (
+	ErrUnknownEvent = errors.New("unknown event type")
+	// ErrIncompatibleVersion means the event was published with a schema
+	// version this build cannot decode safely
+	ErrIncompatibleVersion = errors.New("incompatible event schema version")
+	ErrUnexpectedEvent     = errors.New("unexpected event type")
+)
+
+var rejectedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "events_rejected_total",
+	Help: "Consumed NATS events dropped because they failed schema validation.",
+}, []string{"type", "reason"})
+
+// wrapConsumer decorates every subscription handler; see WrapConsumers.
+var wrapConsumer = func(subject string, handler nats.MsgHandler) nats.MsgHandler {
+	return handler
+}
+
+// WrapConsumers installs wrap around the handlers of subscriptions made
+// afterwards. It is meant for process-wide concerns such as fault
+// injection and must be called before subscribing.
+func WrapConsumers(wrap func(subject string, handler nats.MsgHandler) nats.MsgHandler) {
+	wrapConsumer = wrap
+}
+
+// Schema describes a registered event payload. The table of schemas is
+// generated from api/proto/events by `go generate ./internal/events`.
+type Schema struct {
+	Type    string
+	Subject string
+	Version uint32
+	// MinVersion is the oldest published version this build still decodes;
+	// it moves up when a field is changed or removed
+	MinVersion uint32
+}
+
+func init() {
+	// A stale table would let producers and consumers disagree silently
+	if err := verify(); err != nil {
+		panic(err)
+	}
+}
+
+// verify checks the generated table against the compiled-in descriptors.
+func verify() error {
+	messages := eventspb.File_api_proto_events_events_proto.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		subject, version, ok := options(md)
+		if !ok {
+			continue
+		}
+		schema, known := schemas[string(md.FullName())]
+		if !known || schema.Subject != subject || schema.Version != version {
+			return fmt.Errorf("events: schema table out of date for %s; run go generate ./internal/events", md.FullName())
+		}
+	}
+	return nil
+}
+
+// options reads the subject and version declared on an event message.
+func options(md protoreflect.MessageDescriptor) (string, uint32, bool) {
+	opts := md.Options()
+	if opts == nil || !proto.HasExtension(opts, eventspb.E_Subject) {
+		return "", 0, false
+	}
+	subject := proto.GetExtension(opts, eventspb.E_Subject).(string)
+	version := proto.GetExtension(opts, eventspb.E_Version).(uint32)
+	return subject, version, true
+}
+
+// Lookup returns the schema of an event message.
+func Lookup(event proto.Message) (Schema, error) {
+	name := string(event.ProtoReflect().Descriptor().FullName())
+	schema, ok := schemas[name]
+	if !ok {
+		return Schema{}, fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+	}
+	return schema, nil
+}
+
+// maxPooledBuffer keeps an occasional huge event from pinning its buffers
+// in the pool.
+const maxPooledBuffer = 64 << 10
+
+// encoder holds the buffers and envelope of one encoding so publishers on
+// the hot path reuse them instead of allocating per event.
+type encoder struct {
+	payload    []byte
+	data       []byte
+	envelope   eventspb.Envelope
+	occurredAt timestamppb.Timestamp
+}
+
+var encoders = sync.Pool{New: func() any { return new(encoder) }}
+
+func getEncoder() *encoder {
+	return encoders.Get().(*encoder)
+}
+
+func putEncoder(e *encoder) {
+	if cap(e.payload) > maxPooledBuffer || cap(e.data) > maxPooledBuffer {
+		return
+	}
+	e.envelope.Payload = nil
+	encoders.Put(e)
+}
+
+// encode marshals the event and its envelope into e.data.
+func (e *encoder) encode(source string, schema Schema, event proto.Message) error {
+	var err error
+	e.payload, err = proto.MarshalOptions{}.MarshalAppend(e.payload[:0], event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e.occurredAt.Seconds, e.occurredAt.Nanos = now.Unix(), int32(now.Nanosecond())
+	e.envelope.Id = uuid.NewString()
+	e.envelope.Type = schema.Type
+	e.envelope.Version = schema.Version
+	e.envelope.Source = source
+	e.envelope.OccurredAt = &e.occurredAt
+	e.envelope.Payload = e.payload
+
+	e.data, err = proto.MarshalOptions{}.MarshalAppend(e.data[:0], &e.envelope)
+	return err
+}
+
+// Encode wraps the event in a versioned envelope and returns the subject
+// to publish it on.
+func Encode(source string, event proto.Message) (string, []byte, error) {
+	schema, err := Lookup(event)
+	if err != nil {
+		return "", nil, err
+	}
+
+	e := getEncoder()
+	defer putEncoder(e)
+	if err := e.encode(source, schema, event); err != nil {
+		return "", nil, err
+	}
+	return schema.Subject, bytes.Clone(e.data), nil
+}
+
+// Decode unwraps an envelope into event, refusing events of another type
+// or of a schema version outside what this build understands.
+func Decode(data []byte, event proto.Message) (*eventspb.Envelope, error) {
+	schema, err := Lookup(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope eventspb.Envelope
+	if err := proto.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+	if envelope.Type != schema.Type {
+		return nil, fmt.Errorf("%w: got %s, want %s", ErrUnexpectedEvent, envelope.Type, schema.Type)
+	}
+	if envelope.Version < schema.MinVersion || envelope.Version > schema.Version {
+		return nil, fmt.Errorf("%w: %s v%d, supported v%d-v%d",
+			ErrIncompatibleVersion, envelope.Type, envelope.Version, schema.MinVersion, schema.Version)
+	}
+
+	if err := proto.Unmarshal(envelope.Payload, event); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", envelope.Type, err)
+	}
+	return &envelope, nil
+}
+
+// Publisher is where events are published: a *nats.Conn in the services,
+// an eventstest.FakeBus in tests. Publish must not keep data after it
+// returns.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Publish encodes and publishes the event on its subject.
+func Publish(conn Publisher, source string, event proto.Message) error {
+	schema, err := Lookup(event)
+	if err != nil {
+		return err
+	}
+
+	e := getEncoder()
+	defer putEncoder(e)
+	if err := e.encode(source, schema, event); err != nil {
+		return err
+	}
+	// Publishers copy data before returning, as the connection does into
+	// its write buffer, so the encoder can go back to the pool right after
+	return conn.Publish(schema.Subject, e.data)
+}
+
+// Subscribe delivers decoded events of type T to handler. Events that fail
+// validation are logged and counted, never handed over half-parsed.
+func Subscribe[T proto.Message](conn *nats.Conn, newEvent func() T, handler func(*eventspb.Envelope, T)) (*nats.Subscription, error) {
+	schema, err := Lookup(newEvent())
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Subscribe(schema.Subject, wrapConsumer(schema.Subject, func(msg *nats.Msg) {
+		event := newEvent()
+		envelope, err := Decode(msg.Data, event)
+		if err != nil {
+			reason := "invalid"
+			switch {
+			case errors.Is(err, ErrIncompatibleVersion):
+				reason = "version"
+			case errors.Is(err, ErrUnexpectedEvent):
+				reason = "type"
+			}
+			rejectedEvents.WithLabelValues(schema.Type, reason).Inc()
+			log.Printf("Dropped event on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(envelope, event)
+	}))
+}
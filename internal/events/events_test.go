@@ -0,0 +1,48 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+)
+
+// discard is a Publisher that drops everything, so benchmarks measure the
+// encoding alone.
+type discard struct{}
+
+func (discard) Publish(subject string, data []byte) error { return nil }
+
+func TestEncode_RoundTrip(t *testing.T) {
+	subject, data, err := Encode("test", &eventspb.BotStatusChanged{BotId: "bot-1", UserId: "user-1", Status: "running"})
+	require.NoError(t, err)
+	assert.Equal(t, "bots.status", subject)
+
+	var event eventspb.BotStatusChanged
+	envelope, err := Decode(data, &event)
+	require.NoError(t, err)
+	assert.Equal(t, "test", envelope.Source)
+	assert.Equal(t, "bot-1", event.BotId)
+	assert.Equal(t, "running", event.Status)
+}
+
+func BenchmarkPublish(b *testing.B) {
+	event := &eventspb.BotStatusChanged{BotId: "bot-1", UserId: "user-1", Status: "running"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Publish(discard{}, "bench", event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	event := &eventspb.BotStatusChanged{BotId: "bot-1", UserId: "user-1", Status: "running"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Encode("bench", event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Command gen regenerates the event schema table from api/proto/events and
+// guards against incompatible schema changes. Field lists of every
+// published version are kept in schemas.lock.json; changing or removing a
+// field without bumping the message's version fails the build.
+//
+// Run it with `go generate ./internal/events`.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	lockFile   = "schemas.lock.json"
+	outputFile = "schemas_gen.go"
+)
+
+type field struct {
+	Number   int32  `json:"number"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Repeated bool   `json:"repeated,omitempty"`
+}
+
+type schema struct {
+	Type       string
+	Subject    string
+	Version    uint32
+	MinVersion uint32
+}
+
+// lock maps event type -> version -> fields
+type lock map[string]map[string][]field
+
+func main() {
+	locked := lock{}
+	if data, err := os.ReadFile(lockFile); err == nil {
+		if err := json.Unmarshal(data, &locked); err != nil {
+			log.Fatalf("Failed to read %s: %v", lockFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Fatalf("Failed to read %s: %v", lockFile, err)
+	}
+
+	var schemas []schema
+	messages := eventspb.File_api_proto_events_events_proto.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		opts := md.Options()
+		if opts == nil || !proto.HasExtension(opts, eventspb.E_Subject) {
+			continue
+		}
+		subject := proto.GetExtension(opts, eventspb.E_Subject).(string)
+		version := proto.GetExtension(opts, eventspb.E_Version).(uint32)
+		if version == 0 {
+			log.Fatalf("%s: version option must be at least 1", md.FullName())
+		}
+
+		name := string(md.FullName())
+		current := fields(md)
+		versions := locked[name]
+		if versions == nil {
+			versions = map[string][]field{}
+			locked[name] = versions
+		}
+
+		key := strconv.FormatUint(uint64(version), 10)
+		if previous, ok := versions[key]; ok {
+			if err := compatible(previous, current); err != nil {
+				log.Fatalf("%s v%d: %v; bump the version option", name, version, err)
+			}
+		}
+		versions[key] = current
+
+		// Older versions stay decodable while all their fields survive
+		// unchanged
+		minVersion := version
+		for v := version - 1; v > 0; v-- {
+			previous, ok := versions[strconv.FormatUint(uint64(v), 10)]
+			if !ok || compatible(previous, current) != nil {
+				break
+			}
+			minVersion = v
+		}
+
+		schemas = append(schemas, schema{Type: name, Subject: subject, Version: version, MinVersion: minVersion})
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Type < schemas[j].Type })
+
+	data, err := json.MarshalIndent(locked, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(lockFile, append(data, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by internal/events/gen. DO NOT EDIT.\n\npackage events\n\n")
+	b.WriteString("var schemas = map[string]Schema{\n")
+	for _, s := range schemas {
+		fmt.Fprintf(&b, "\t%q: {Type: %q, Subject: %q, Version: %d, MinVersion: %d},\n", s.Type, s.Type, s.Subject, s.Version, s.MinVersion)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputFile, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func fields(md protoreflect.MessageDescriptor) []field {
+	var out []field
+	list := md.Fields()
+	for i := 0; i < list.Len(); i++ {
+		fd := list.Get(i)
+		typ := fd.Kind().String()
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			typ = string(fd.Message().FullName())
+		case protoreflect.EnumKind:
+			typ = string(fd.Enum().FullName())
+		}
+		out = append(out, field{
+			Number:   int32(fd.Number()),
+			Name:     string(fd.Name()),
+			Type:     typ,
+			Repeated: fd.Cardinality() == protoreflect.Repeated,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+	return out
+}
+
+// compatible reports whether every previous field is still present with
+// the same name and type. Added fields are fine.
+func compatible(previous, current []field) error {
+	byNumber := make(map[int32]field, len(current))
+	for _, f := range current {
+		byNumber[f.Number] = f
+	}
+	for _, f := range previous {
+		now, ok := byNumber[f.Number]
+		if !ok {
+			return fmt.Errorf("field %d (%s) was removed", f.Number, f.Name)
+		}
+		if now != f {
+			return fmt.Errorf("field %d (%s) changed", f.Number, f.Name)
+		}
+	}
+	return nil
+}
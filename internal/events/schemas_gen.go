@@ -0,0 +1,13 @@
+// Code generated by internal/events/gen. DO NOT EDIT.
+
+package events
+
+var schemas = map[string]Schema{
+	"events.v1.BotStatusChanged": {Type: "events.v1.BotStatusChanged", Subject: "bots.status", Version: 1, MinVersion: 1},
+	"events.v1.ConsumerLagAlert": {Type: "events.v1.ConsumerLagAlert", Subject: "ops.nats.consumer_lag", Version: 1, MinVersion: 1},
+	"events.v1.LoginFailed":      {Type: "events.v1.LoginFailed", Subject: "audit.auth.login_failed", Version: 1, MinVersion: 1},
+	"events.v1.LoginLockedOut":   {Type: "events.v1.LoginLockedOut", Subject: "audit.auth.locked_out", Version: 1, MinVersion: 1},
+	"events.v1.OrderBookUpdated": {Type: "events.v1.OrderBookUpdated", Subject: "marketdata.orderbook", Version: 1, MinVersion: 1},
+	"events.v1.ServiceAnnounced": {Type: "events.v1.ServiceAnnounced", Subject: "registry.announce", Version: 1, MinVersion: 1},
+	"events.v1.ServiceLeft":      {Type: "events.v1.ServiceLeft", Subject: "registry.leave", Version: 1, MinVersion: 1},
+}
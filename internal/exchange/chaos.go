@@ -0,0 +1,187 @@
+// internal/exchange/chaos.go
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+var chaosInjected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "paper_chaos_injected_total",
+	Help: "Failures injected by paper exchanges, by exchange and kind.",
+}, []string{"exchange", "kind"})
+
+// ErrChaosDisabled is returned when chaos is set on an exchange the
+// deployment does not allow it on.
+var ErrChaosDisabled = errors.New("chaos is not enabled on this exchange")
+
+// ChaosSettings are how one user's paper account misbehaves. Rates are
+// fractions of orders or fills; zero settings behave normally.
+type ChaosSettings struct {
+	// RejectRate is the fraction of new orders rejected outright
+	RejectRate float64 `json:"reject_rate"`
+	// PartialFillRate is the fraction of fills executing only part of what
+	// is left of the order. The rest of a limit order stays open; the rest
+	// of a market order is cancelled.
+	PartialFillRate float64 `json:"partial_fill_rate"`
+	// AckDelayRate is the fraction of placements acknowledged only after
+	// the exchange's acknowledgement delay
+	AckDelayRate float64 `json:"ack_delay_rate"`
+	// DuplicateFillRate is the fraction of fills reported twice
+	DuplicateFillRate float64 `json:"duplicate_fill_rate"`
+}
+
+// Validate checks every rate is a fraction.
+func (s ChaosSettings) Validate() error {
+	rates := map[string]float64{
+		"reject_rate":         s.RejectRate,
+		"partial_fill_rate":   s.PartialFillRate,
+		"ack_delay_rate":      s.AckDelayRate,
+		"duplicate_fill_rate": s.DuplicateFillRate,
+	}
+	for name, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	return nil
+}
+
+// Chaos decides which orders and fills of a paper exchange misbehave, as
+// each user chose for their account. A nil Chaos never misbehaves, so
+// paper clients can carry one unconditionally.
+type Chaos struct {
+	name     string
+	ackDelay time.Duration
+	mutex    sync.Mutex
+	users    map[string]ChaosSettings
+}
+
+// NewChaos returns the chaos of the named exchange, or nil when it is
+// disabled or limited to other exchanges. Every user's account behaves
+// normally until they choose otherwise.
+func NewChaos(name string, cfg config.PaperChaosConfig) *Chaos {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Exchanges) > 0 {
+		targeted := false
+		for _, exchange := range cfg.Exchanges {
+			targeted = targeted || exchange == name
+		}
+		if !targeted {
+			return nil
+		}
+	}
+	return &Chaos{name: name, ackDelay: cfg.AckDelay, users: make(map[string]ChaosSettings)}
+}
+
+// Set replaces the user's settings; zero settings turn chaos off for them.
+func (c *Chaos) Set(userID string, settings ChaosSettings) error {
+	if c == nil {
+		return ErrChaosDisabled
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if settings == (ChaosSettings{}) {
+		delete(c.users, userID)
+	} else {
+		c.users[userID] = settings
+	}
+	return nil
+}
+
+// Get returns the user's settings.
+func (c *Chaos) Get(userID string) (ChaosSettings, error) {
+	if c == nil {
+		return ChaosSettings{}, ErrChaosDisabled
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.users[userID], nil
+}
+
+// delay holds a placement back before it is acknowledged.
+func (c *Chaos) delay(ctx context.Context, userID string) error {
+	if c == nil || c.ackDelay <= 0 || !c.roll(userID, "ack_delay") {
+		return nil
+	}
+
+	timer := time.NewTimer(c.ackDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Chaos) reject(userID string) bool {
+	return c.roll(userID, "reject")
+}
+
+// fillQuantity returns how much of remaining a fill executes: all of it,
+// or for a partial fill between a tenth and nine tenths.
+func (c *Chaos) fillQuantity(userID string, remaining decimal.Decimal) decimal.Decimal {
+	if !c.roll(userID, "partial_fill") {
+		return remaining
+	}
+	fraction := decimal.NewFromFloat(0.1 + 0.8*rand.Float64())
+	quantity := remaining.Mul(fraction).Round(8)
+	if !quantity.IsPositive() || quantity.GreaterThanOrEqual(remaining) {
+		return remaining
+	}
+	return quantity
+}
+
+func (c *Chaos) duplicateFill(userID string) bool {
+	return c.roll(userID, "duplicate_fill")
+}
+
+// roll decides whether the user's account misbehaves in the given way.
+func (c *Chaos) roll(userID, kind string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	settings, ok := c.users[userID]
+	c.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	var rate float64
+	switch kind {
+	case "reject":
+		rate = settings.RejectRate
+	case "partial_fill":
+		rate = settings.PartialFillRate
+	case "ack_delay":
+		rate = settings.AckDelayRate
+	case "duplicate_fill":
+		rate = settings.DuplicateFillRate
+	}
+	if rate <= 0 || rand.Float64() >= rate {
+		return false
+	}
+	chaosInjected.WithLabelValues(c.name, kind).Inc()
+	return true
+}
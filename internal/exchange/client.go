@@ -0,0 +1,197 @@
+// internal/exchange/client.go
+package exchange
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnknownExchange = errors.New("unknown exchange")
+	ErrOrderNotFound   = errors.New("order not found")
+	// ErrOrderClosed is returned when cancelling an order that is already
+	// filled or cancelled
+	ErrOrderClosed = errors.New("order already closed")
+)
+
+type Side string
+
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+func (s Side) Opposite() Side {
+	if s == SideBuy {
+		return SideSell
+	}
+	return SideBuy
+}
+
+type OrderType string
+
+const (
+	OrderTypeMarket OrderType = "market"
+	OrderTypeLimit  OrderType = "limit"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusOpen      OrderStatus = "open"
+	OrderStatusFilled    OrderStatus = "filled"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRejected  OrderStatus = "rejected"
+)
+
+type Order struct {
+	ID            string          `json:"id"`
+	ClientOrderID string          `json:"client_order_id"`
+	Exchange      string          `json:"exchange"`
+	Symbol        string          `json:"symbol"`
+	Side          Side            `json:"side"`
+	Type          OrderType       `json:"type"`
+	Status        OrderStatus     `json:"status"`
+	Price         decimal.Decimal `json:"price"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Filled        decimal.Decimal `json:"filled"`
+	ReduceOnly    bool            `json:"reduce_only"`
+	// Testnet orders were placed on the exchange's sandbox environment
+	Testnet   bool      `json:"testnet"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Fill is one execution of an order. Exchanges may report a fill more than
+// once, so consumers deduplicate by ID.
+type Fill struct {
+	ID            string          `json:"id"`
+	OrderID       string          `json:"order_id"`
+	ClientOrderID string          `json:"client_order_id"`
+	Exchange      string          `json:"exchange"`
+	Symbol        string          `json:"symbol"`
+	Side          Side            `json:"side"`
+	Price         decimal.Decimal `json:"price"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Testnet       bool            `json:"testnet"`
+	ExecutedAt    time.Time       `json:"executed_at"`
+}
+
+type OrderRequest struct {
+	// ClientOrderID makes placement idempotent: the exchange returns the
+	// existing order instead of creating a second one
+	ClientOrderID string          `json:"client_order_id"`
+	Symbol        string          `json:"symbol"`
+	Side          Side            `json:"side"`
+	Type          OrderType       `json:"type"`
+	Price         decimal.Decimal `json:"price"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	ReduceOnly    bool            `json:"reduce_only"`
+}
+
+type Position struct {
+	Exchange   string          `json:"exchange"`
+	Symbol     string          `json:"symbol"`
+	Side       Side            `json:"side"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	Testnet    bool            `json:"testnet"`
+}
+
+// Account is the margin account of a user on an exchange, valued in the
+// account's quote currency.
+type Account struct {
+	Exchange      string          `json:"exchange"`
+	Balance       decimal.Decimal `json:"balance"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+	Equity        decimal.Decimal `json:"equity"`
+	Testnet       bool            `json:"testnet"`
+}
+
+// Client is the trading API of an exchange connector, scoped per user.
+type Client interface {
+	OpenOrders(ctx context.Context, userID, symbol string) ([]Order, error)
+	// Order returns one of the user's orders in any status
+	Order(ctx context.Context, userID, orderID string) (*Order, error)
+	PlaceOrder(ctx context.Context, userID string, req OrderRequest) (*Order, error)
+	CancelOrder(ctx context.Context, userID, orderID string) error
+	Positions(ctx context.Context, userID, symbol string) ([]Position, error)
+	Account(ctx context.Context, userID string) (*Account, error)
+	LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error)
+}
+
+// Registry maps exchange names to their connector clients. Testnet
+// clients are kept apart so code iterating the registry, such as equity
+// snapshots and bulk operations, only ever sees mainnet accounts.
+type Registry struct {
+	clients map[string]Client
+	testnet map[string]Client
+	mutex   sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		clients: make(map[string]Client),
+		testnet: make(map[string]Client),
+	}
+}
+
+func (r *Registry) Register(name string, client Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.clients[name] = client
+}
+
+func (r *Registry) Get(name string) (Client, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	client, ok := r.clients[name]
+	if !ok {
+		return nil, ErrUnknownExchange
+	}
+	return client, nil
+}
+
+// RegisterTestnet adds the client for the exchange's testnet environment.
+func (r *Registry) RegisterTestnet(name string, client Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.testnet[name] = client
+}
+
+// For returns the client of the exchange's mainnet or testnet environment,
+// as flagged on the API key in use.
+func (r *Registry) For(name string, testnet bool) (Client, error) {
+	if !testnet {
+		return r.Get(name)
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	client, ok := r.testnet[name]
+	if !ok {
+		return nil, ErrUnknownExchange
+	}
+	return client, nil
+}
+
+// Names returns the registered exchanges in a stable order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,252 @@
+// internal/exchange/connector.go
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrConnectorUnavailable wraps failures to reach a connector instance,
+	// as opposed to errors the exchange behind it returned
+	ErrConnectorUnavailable = errors.New("exchange connector unavailable")
+	ErrNotPaper             = errors.New("exchange is not simulated")
+)
+
+// PaperAccounts funds and clears simulated accounts and sets how they
+// misbehave. Only paper exchanges, and the connectors serving them,
+// implement it.
+type PaperAccounts interface {
+	Deposit(ctx context.Context, userID string, amount decimal.Decimal) error
+	Reset(ctx context.Context, userID string) error
+	SetChaos(ctx context.Context, userID string, settings ChaosSettings) error
+	Chaos(ctx context.Context, userID string) (ChaosSettings, error)
+}
+
+// FillSource lists the executions of an account since a time, oldest
+// first. Paper exchanges, and the connectors serving them, implement it.
+type FillSource interface {
+	Fills(ctx context.Context, userID string, since time.Time) ([]Fill, error)
+}
+
+// connectorErrors maps the error codes of the connector API to the errors
+// clients return, so callers can tell them apart whichever client they use.
+var connectorErrors = map[string]error{
+	"unknown_exchange": ErrUnknownExchange,
+	"order_not_found":  ErrOrderNotFound,
+	"order_closed":     ErrOrderClosed,
+	"no_price":         ErrNoPrice,
+	"not_paper":        ErrNotPaper,
+	"chaos_disabled":   ErrChaosDisabled,
+}
+
+// ConnectorClient is the Client of one connector instance, reached over
+// HTTP. Every path is scoped by exchange and environment, so one instance
+// may serve several exchanges:
+//
+//	GET    /v1/{exchange}/{env}/users/{user}/orders?symbol=
+//	GET    /v1/{exchange}/{env}/users/{user}/orders/{id}
+//	POST   /v1/{exchange}/{env}/users/{user}/orders
+//	DELETE /v1/{exchange}/{env}/users/{user}/orders/{id}
+//	GET    /v1/{exchange}/{env}/users/{user}/positions?symbol=
+//	GET    /v1/{exchange}/{env}/users/{user}/account
+//	GET    /v1/{exchange}/{env}/prices/{symbol}
+//	GET    /v1/{exchange}/{env}/users/{user}/fills?since= (paper only)
+//	POST   /v1/{exchange}/{env}/users/{user}/deposit (paper only)
+//	DELETE /v1/{exchange}/{env}/users/{user} (paper only)
+//	GET    /v1/{exchange}/{env}/users/{user}/chaos (paper only)
+//	PUT    /v1/{exchange}/{env}/users/{user}/chaos (paper only)
+//
+// where env is "mainnet" or "testnet". Errors are JSON objects with an
+// "error" message and a "code".
+type ConnectorClient struct {
+	connector Connector
+	base      string
+	http      *http.Client
+}
+
+func NewConnectorClient(connector Connector, httpClient *http.Client) *ConnectorClient {
+	env := "mainnet"
+	if connector.Testnet {
+		env = "testnet"
+	}
+	return &ConnectorClient{
+		connector: connector,
+		base:      connectorURL(connector.Address) + "/v1/" + url.PathEscape(connector.Exchange) + "/" + env,
+		http:      httpClient,
+	}
+}
+
+// connectorURL accepts bare host:port addresses as configured for gRPC
+// services.
+func connectorURL(address string) string {
+	if strings.Contains(address, "://") {
+		return strings.TrimSuffix(address, "/")
+	}
+	return "http://" + address
+}
+
+func (c *ConnectorClient) OpenOrders(ctx context.Context, userID, symbol string) ([]Order, error) {
+	var orders []Order
+	err := c.call(ctx, http.MethodGet, c.userPath(userID, "orders")+query("symbol", symbol), nil, &orders)
+	return orders, err
+}
+
+func (c *ConnectorClient) Order(ctx context.Context, userID, orderID string) (*Order, error) {
+	var order Order
+	if err := c.call(ctx, http.MethodGet, c.userPath(userID, "orders", orderID), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (c *ConnectorClient) PlaceOrder(ctx context.Context, userID string, req OrderRequest) (*Order, error) {
+	var order Order
+	if err := c.call(ctx, http.MethodPost, c.userPath(userID, "orders"), req, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (c *ConnectorClient) CancelOrder(ctx context.Context, userID, orderID string) error {
+	return c.call(ctx, http.MethodDelete, c.userPath(userID, "orders", orderID), nil, nil)
+}
+
+func (c *ConnectorClient) Positions(ctx context.Context, userID, symbol string) ([]Position, error) {
+	var positions []Position
+	err := c.call(ctx, http.MethodGet, c.userPath(userID, "positions")+query("symbol", symbol), nil, &positions)
+	return positions, err
+}
+
+func (c *ConnectorClient) Account(ctx context.Context, userID string) (*Account, error) {
+	var account Account
+	if err := c.call(ctx, http.MethodGet, c.userPath(userID, "account"), nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (c *ConnectorClient) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	var price struct {
+		Price decimal.Decimal `json:"price"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/prices/"+url.PathEscape(symbol), nil, &price); err != nil {
+		return decimal.Zero, err
+	}
+	return price.Price, nil
+}
+
+func (c *ConnectorClient) Deposit(ctx context.Context, userID string, amount decimal.Decimal) error {
+	body := map[string]decimal.Decimal{"amount": amount}
+	return c.call(ctx, http.MethodPost, c.userPath(userID, "deposit"), body, nil)
+}
+
+func (c *ConnectorClient) Reset(ctx context.Context, userID string) error {
+	return c.call(ctx, http.MethodDelete, c.userPath(userID), nil, nil)
+}
+
+func (c *ConnectorClient) SetChaos(ctx context.Context, userID string, settings ChaosSettings) error {
+	return c.call(ctx, http.MethodPut, c.userPath(userID, "chaos"), settings, nil)
+}
+
+func (c *ConnectorClient) Chaos(ctx context.Context, userID string) (ChaosSettings, error) {
+	var settings ChaosSettings
+	err := c.call(ctx, http.MethodGet, c.userPath(userID, "chaos"), nil, &settings)
+	return settings, err
+}
+
+func (c *ConnectorClient) Fills(ctx context.Context, userID string, since time.Time) ([]Fill, error) {
+	var fills []Fill
+	err := c.call(ctx, http.MethodGet, c.userPath(userID, "fills")+query("since", since.UTC().Format(time.RFC3339Nano)), nil, &fills)
+	return fills, err
+}
+
+// Health checks that the connector answers at all.
+func (c *ConnectorClient) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, connectorURL(c.connector.Address)+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectorUnavailable, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: health check returned %d", ErrConnectorUnavailable, resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *ConnectorClient) userPath(userID string, parts ...string) string {
+	path := "/users/" + url.PathEscape(userID)
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+	return path
+}
+
+func query(key, value string) string {
+	if value == "" {
+		return ""
+	}
+	return "?" + url.Values{key: {value}}.Encode()
+}
+
+func (c *ConnectorClient) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrConnectorUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure); err != nil || failure.Code == "" {
+			return fmt.Errorf("%w: %s returned %d", ErrConnectorUnavailable, c.connector.Address, resp.StatusCode)
+		}
+		if known, ok := connectorErrors[failure.Code]; ok {
+			return known
+		}
+		return fmt.Errorf("%s: %s", c.connector.Exchange, failure.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
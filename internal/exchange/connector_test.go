@@ -0,0 +1,168 @@
+package exchange
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+func paperConnector(t *testing.T) (*PaperClient, *httptest.Server) {
+	paper := NewPaperClient("binance")
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	paper.Deposit(context.Background(), "user-1", decimal.NewFromInt(1000))
+
+	clients := NewRegistry()
+	clients.Register("binance", paper)
+	clients.RegisterTestnet("binance", NewTestnetPaperClient("binance"))
+	server := httptest.NewServer(NewConnectorHandler(clients))
+	t.Cleanup(server.Close)
+	return paper, server
+}
+
+func TestConnectorClient_RoundTrip(t *testing.T) {
+	_, server := paperConnector(t)
+	client := NewConnectorClient(Connector{Exchange: "binance", Address: server.URL}, server.Client())
+	ctx := context.Background()
+
+	order, err := client.PlaceOrder(ctx, "user-1", OrderRequest{
+		ClientOrderID: "c-1",
+		Symbol:        "BTCUSDT",
+		Side:          SideBuy,
+		Type:          OrderTypeMarket,
+		Quantity:      decimal.NewFromInt(2),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, order.Status)
+	assert.True(t, order.Price.Equal(decimal.NewFromInt(100)))
+
+	positions, err := client.Positions(ctx, "user-1", "BTCUSDT")
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.True(t, positions[0].Quantity.Equal(decimal.NewFromInt(2)))
+
+	price, err := client.LastPrice(ctx, "BTCUSDT")
+	require.NoError(t, err)
+	assert.True(t, price.Equal(decimal.NewFromInt(100)))
+
+	// Sentinel errors survive the trip
+	assert.ErrorIs(t, client.CancelOrder(ctx, "user-1", order.ID), ErrOrderClosed)
+	_, err = client.Order(ctx, "user-2", order.ID)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = client.LastPrice(ctx, "ETHUSDT")
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	// Testnet calls reach the testnet client only
+	testnet := NewConnectorClient(Connector{Exchange: "binance", Address: server.URL, Testnet: true}, server.Client())
+	_, err = testnet.Order(ctx, "user-1", order.ID)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	unknown := NewConnectorClient(Connector{Exchange: "kraken", Address: server.URL}, server.Client())
+	_, err = unknown.Account(ctx, "user-1")
+	assert.ErrorIs(t, err, ErrUnknownExchange)
+}
+
+func TestRouter_FailsOverToHealthyConnector(t *testing.T) {
+	_, up := paperConnector(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	router := NewRouter(map[string]config.ExchangeConfig{
+		"binance": {
+			MatchingEngineRegion: "home",
+			Connectors: []config.ExchangeConnectorConfig{
+				{Region: "home", Address: down.URL},
+				{Region: "away", Address: up.URL},
+			},
+		},
+	}, time.Second)
+
+	selected, err := router.Select("binance")
+	require.NoError(t, err)
+	assert.Equal(t, "home", selected.Region)
+
+	client := router.Client("binance", false)
+	_, err = client.Account(context.Background(), "user-1")
+	assert.ErrorIs(t, err, ErrConnectorUnavailable)
+
+	// The failed instance is out of rotation until a probe sees it answer
+	account, err := client.Account(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.NewFromInt(1000)))
+
+	selected, err = router.Select("binance")
+	require.NoError(t, err)
+	assert.Equal(t, "away", selected.Region)
+
+	_, err = router.SelectFor("binance", true)
+	assert.ErrorIs(t, err, ErrNoConnector)
+}
+
+func TestConnectorClient_PaperAccounts(t *testing.T) {
+	_, server := paperConnector(t)
+	client := NewConnectorClient(Connector{Exchange: "binance", Address: server.URL}, server.Client())
+	ctx := context.Background()
+
+	require.NoError(t, client.Deposit(ctx, "user-1", decimal.NewFromInt(500)))
+	account, err := client.Account(ctx, "user-1")
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.NewFromInt(1500)))
+
+	require.NoError(t, client.Reset(ctx, "user-1"))
+	account, err = client.Account(ctx, "user-1")
+	require.NoError(t, err)
+	assert.True(t, account.Balance.IsZero())
+
+	// Live exchanges behind a connector refuse to mint balances
+	live := NewRegistry()
+	live.Register("binance", liveClient{})
+	liveServer := httptest.NewServer(NewConnectorHandler(live))
+	defer liveServer.Close()
+	viaConnector := NewConnectorClient(Connector{Exchange: "binance", Address: liveServer.URL}, liveServer.Client())
+	assert.ErrorIs(t, viaConnector.Deposit(ctx, "user-1", decimal.NewFromInt(1)), ErrNotPaper)
+}
+
+func TestConnectorClient_Chaos(t *testing.T) {
+	paper := NewPaperClient("binance").WithChaos(NewChaos("binance", config.PaperChaosConfig{Enabled: true}))
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	clients := NewRegistry()
+	clients.Register("binance", paper)
+	clients.RegisterTestnet("binance", NewTestnetPaperClient("binance"))
+	server := httptest.NewServer(NewConnectorHandler(clients))
+	defer server.Close()
+	client := NewConnectorClient(Connector{Exchange: "binance", Address: server.URL}, server.Client())
+	ctx := context.Background()
+
+	// Only the user who asked for chaos gets it
+	require.NoError(t, client.SetChaos(ctx, "user-1", ChaosSettings{RejectRate: 1}))
+	settings, err := client.Chaos(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, settings.RejectRate)
+	req := OrderRequest{Symbol: "BTCUSDT", Side: SideBuy, Type: OrderTypeMarket, Quantity: decimal.NewFromInt(1)}
+	order, err := client.PlaceOrder(ctx, "user-1", req)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusRejected, order.Status)
+	order, err = client.PlaceOrder(ctx, "user-2", req)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, order.Status)
+
+	fills, err := client.Fills(ctx, "user-2", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, fills, 1)
+	assert.Equal(t, order.ID, fills[0].OrderID)
+
+	assert.Error(t, client.SetChaos(ctx, "user-1", ChaosSettings{RejectRate: 2}))
+
+	// Exchanges the deployment does not allow chaos on refuse it
+	testnet := NewConnectorClient(Connector{Exchange: "binance", Address: server.URL, Testnet: true}, server.Client())
+	assert.ErrorIs(t, testnet.SetChaos(ctx, "user-1", ChaosSettings{RejectRate: 1}), ErrChaosDisabled)
+}
+
+// liveClient stands in for a real exchange client, which takes no deposits.
+type liveClient struct{ Client }
@@ -0,0 +1,256 @@
+// internal/exchange/connectorserver.go
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// connectorHandler serves the API ConnectorClient calls for the clients of
+// a registry.
+type connectorHandler struct {
+	clients *Registry
+}
+
+// NewConnectorHandler serves the connector API for every client in the
+// registry, so a process holding exchange clients can act as a connector
+// instance for the other services.
+func NewConnectorHandler(clients *Registry) http.Handler {
+	h := &connectorHandler{clients: clients}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/orders", h.openOrders)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/orders/{id}", h.order)
+	mux.HandleFunc("POST /v1/{exchange}/{env}/users/{user}/orders", h.placeOrder)
+	mux.HandleFunc("DELETE /v1/{exchange}/{env}/users/{user}/orders/{id}", h.cancelOrder)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/positions", h.positions)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/account", h.account)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/prices/{symbol}", h.lastPrice)
+	mux.HandleFunc("POST /v1/{exchange}/{env}/users/{user}/deposit", h.deposit)
+	mux.HandleFunc("DELETE /v1/{exchange}/{env}/users/{user}", h.reset)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/fills", h.fills)
+	mux.HandleFunc("GET /v1/{exchange}/{env}/users/{user}/chaos", h.chaos)
+	mux.HandleFunc("PUT /v1/{exchange}/{env}/users/{user}/chaos", h.setChaos)
+	return mux
+}
+
+// client resolves the exchange and environment of the request path.
+func (h *connectorHandler) client(w http.ResponseWriter, r *http.Request) (Client, bool) {
+	env := r.PathValue("env")
+	if env != "mainnet" && env != "testnet" {
+		writeConnectorError(w, ErrUnknownExchange)
+		return nil, false
+	}
+	client, err := h.clients.For(r.PathValue("exchange"), env == "testnet")
+	if err != nil {
+		writeConnectorError(w, err)
+		return nil, false
+	}
+	return client, true
+}
+
+func (h *connectorHandler) openOrders(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	orders, err := client.OpenOrders(r.Context(), r.PathValue("user"), r.URL.Query().Get("symbol"))
+	respond(w, orders, err)
+}
+
+func (h *connectorHandler) order(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	order, err := client.Order(r.Context(), r.PathValue("user"), r.PathValue("id"))
+	respond(w, order, err)
+}
+
+func (h *connectorHandler) placeOrder(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	var req OrderRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_request"})
+		return
+	}
+	order, err := client.PlaceOrder(r.Context(), r.PathValue("user"), req)
+	respond(w, order, err)
+}
+
+func (h *connectorHandler) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	if err := client.CancelOrder(r.Context(), r.PathValue("user"), r.PathValue("id")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *connectorHandler) positions(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	positions, err := client.Positions(r.Context(), r.PathValue("user"), r.URL.Query().Get("symbol"))
+	respond(w, positions, err)
+}
+
+func (h *connectorHandler) account(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	account, err := client.Account(r.Context(), r.PathValue("user"))
+	respond(w, account, err)
+}
+
+func (h *connectorHandler) lastPrice(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	price, err := client.LastPrice(r.Context(), r.PathValue("symbol"))
+	respond(w, map[string]any{"price": price}, err)
+}
+
+// paper resolves the client like client, for the calls only paper
+// exchanges take.
+func (h *connectorHandler) paper(w http.ResponseWriter, r *http.Request) (PaperAccounts, bool) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return nil, false
+	}
+	paper, ok := Unwrap(client).(PaperAccounts)
+	if !ok {
+		writeConnectorError(w, ErrNotPaper)
+		return nil, false
+	}
+	return paper, true
+}
+
+func (h *connectorHandler) deposit(w http.ResponseWriter, r *http.Request) {
+	paper, ok := h.paper(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Amount decimal.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil || !req.Amount.IsPositive() {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "positive amount required", "code": "invalid_request"})
+		return
+	}
+	if err := paper.Deposit(r.Context(), r.PathValue("user"), req.Amount); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *connectorHandler) reset(w http.ResponseWriter, r *http.Request) {
+	paper, ok := h.paper(w, r)
+	if !ok {
+		return
+	}
+	if err := paper.Reset(r.Context(), r.PathValue("user")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *connectorHandler) fills(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.client(w, r)
+	if !ok {
+		return
+	}
+	source, ok := Unwrap(client).(FillSource)
+	if !ok {
+		writeConnectorError(w, ErrNotPaper)
+		return
+	}
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time", "code": "invalid_request"})
+			return
+		}
+		since = parsed
+	}
+	fills, err := source.Fills(r.Context(), r.PathValue("user"), since)
+	respond(w, fills, err)
+}
+
+func (h *connectorHandler) chaos(w http.ResponseWriter, r *http.Request) {
+	paper, ok := h.paper(w, r)
+	if !ok {
+		return
+	}
+	settings, err := paper.Chaos(r.Context(), r.PathValue("user"))
+	respond(w, settings, err)
+}
+
+func (h *connectorHandler) setChaos(w http.ResponseWriter, r *http.Request) {
+	paper, ok := h.paper(w, r)
+	if !ok {
+		return
+	}
+	var settings ChaosSettings
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&settings); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_request"})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_request"})
+		return
+	}
+	if err := paper.SetChaos(r.Context(), r.PathValue("user"), settings); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func respond(w http.ResponseWriter, body any, err error) {
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeConnectorError sends the code ConnectorClient maps back to err.
+func writeConnectorError(w http.ResponseWriter, err error) {
+	for code, known := range connectorErrors {
+		if errors.Is(err, known) {
+			status := http.StatusNotFound
+			if known == ErrOrderClosed || known == ErrNoPrice || known == ErrChaosDisabled {
+				status = http.StatusConflict
+			}
+			writeJSON(w, status, map[string]string{"error": known.Error(), "code": code})
+			return
+		}
+	}
+	writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error(), "code": "exchange_error"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,248 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/org"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Who an organization's key is granted to. Admins use every key of their
+// organization without grants.
+const (
+	// GrantMember lets a member attach the key to the organization's bots
+	// and start bots trading with it
+	GrantMember = "member"
+	// GrantBot lets a bot trade with the key whoever starts it
+	GrantBot = "bot"
+)
+
+// What members did with an organization's keys.
+const (
+	KeyEventCreated    = "key_created"
+	KeyEventDeleted    = "key_deleted"
+	KeyEventGranted    = "key_granted"
+	KeyEventRevoked    = "key_revoked"
+	KeyEventAttached   = "key_attached"
+	KeyEventBotStarted = "bot_started"
+)
+
+const (
+	defaultKeyEventLimit = 100
+	MaxKeyEventLimit     = 500
+)
+
+var (
+	ErrGrantNotFound = errors.New("key grant not found")
+	ErrGrantExists   = errors.New("key already granted")
+	ErrKeyNotGranted = errors.New("exchange key not granted to you or the bot")
+)
+
+// KeyGrant lets a member or a bot use an organization's key without seeing
+// the key itself.
+type KeyGrant struct {
+	ID    string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	KeyID string `json:"key_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_key_grants_subject,priority:1"`
+	OrgID string `json:"org_id" gorm:"type:varchar(36);not null;index"`
+	// Subject is GrantMember or GrantBot; SubjectID the user or bot ID
+	Subject   string    `json:"subject" gorm:"type:varchar(16);not null;uniqueIndex:idx_key_grants_subject,priority:2"`
+	SubjectID string    `json:"subject_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_key_grants_subject,priority:3"`
+	GrantedBy string    `json:"granted_by" gorm:"type:varchar(36);not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (KeyGrant) TableName() string {
+	return "exchange_key_grants"
+}
+
+// KeyEvent records what a member did with one of the organization's keys.
+type KeyEvent struct {
+	ID      string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OrgID   string `json:"org_id" gorm:"type:varchar(36);not null;index:idx_key_events_org_time,priority:1"`
+	KeyID   string `json:"key_id" gorm:"type:varchar(36);not null;index"`
+	ActorID string `json:"actor_id" gorm:"type:varchar(36);not null;index"`
+	Action  string `json:"action" gorm:"type:varchar(32);not null"`
+	// Details holds action specific values such as the grant's subject
+	Details   map[string]string `json:"details,omitempty" gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time         `json:"created_at" gorm:"autoCreateTime;index:idx_key_events_org_time,priority:2"`
+}
+
+// TableName sets the table name for GORM
+func (KeyEvent) TableName() string {
+	return "exchange_key_events"
+}
+
+// KeyEventQuery selects an organization's key events, optionally of one
+// member or key, most recent first.
+type KeyEventQuery struct {
+	OrgID   string
+	ActorID string
+	KeyID   string
+	Limit   int
+}
+
+type GrantRepository interface {
+	// Grants lists the grants of an organization's key
+	Grants(ctx context.Context, orgID, keyID string) ([]KeyGrant, error)
+	// Granted lists the IDs of the organization's keys granted to the
+	// subject
+	Granted(ctx context.Context, orgID, subject, subjectID string) ([]string, error)
+	// IsGranted tells whether the key is granted to the subject
+	IsGranted(ctx context.Context, keyID, subject, subjectID string) (bool, error)
+	Grant(ctx context.Context, grant *KeyGrant) error
+	Revoke(ctx context.Context, orgID, keyID, id string) (*KeyGrant, error)
+	Record(ctx context.Context, event *KeyEvent) error
+	Events(ctx context.Context, q KeyEventQuery) ([]KeyEvent, error)
+}
+
+type grantRepository struct {
+	db *gorm.DB
+}
+
+func NewGrantRepository(db *gorm.DB) GrantRepository {
+	return &grantRepository{db: db}
+}
+
+func (r *grantRepository) Grants(ctx context.Context, orgID, keyID string) ([]KeyGrant, error) {
+	var grants []KeyGrant
+	err := r.db.WithContext(ctx).
+		Where("org_id = ? AND key_id = ?", orgID, keyID).
+		Order("created_at, id").
+		Find(&grants).Error
+	return grants, err
+}
+
+func (r *grantRepository) Granted(ctx context.Context, orgID, subject, subjectID string) ([]string, error) {
+	var keyIDs []string
+	err := r.db.WithContext(ctx).Model(&KeyGrant{}).
+		Where("org_id = ? AND subject = ? AND subject_id = ?", orgID, subject, subjectID).
+		Pluck("key_id", &keyIDs).Error
+	return keyIDs, err
+}
+
+func (r *grantRepository) IsGranted(ctx context.Context, keyID, subject, subjectID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&KeyGrant{}).
+		Where("key_id = ? AND subject = ? AND subject_id = ?", keyID, subject, subjectID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *grantRepository) Grant(ctx context.Context, grant *KeyGrant) error {
+	if grant.ID == "" {
+		grant.ID = uuid.New().String()
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(grant)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGrantExists
+	}
+	return nil
+}
+
+func (r *grantRepository) Revoke(ctx context.Context, orgID, keyID, id string) (*KeyGrant, error) {
+	var grant KeyGrant
+	result := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND org_id = ? AND key_id = ?", id, orgID, keyID).
+		Delete(&grant)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrGrantNotFound
+	}
+	return &grant, nil
+}
+
+func (r *grantRepository) Record(ctx context.Context, event *KeyEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *grantRepository) Events(ctx context.Context, q KeyEventQuery) ([]KeyEvent, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultKeyEventLimit
+	}
+	if limit > MaxKeyEventLimit {
+		limit = MaxKeyEventLimit
+	}
+
+	tx := r.db.WithContext(ctx).Where("org_id = ?", q.OrgID)
+	if q.ActorID != "" {
+		tx = tx.Where("actor_id = ?", q.ActorID)
+	}
+	if q.KeyID != "" {
+		tx = tx.Where("key_id = ?", q.KeyID)
+	}
+	var events []KeyEvent
+	err := tx.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// Memberships tells a user's role in an organization; org.Repository
+// satisfies it.
+type Memberships interface {
+	MemberOf(ctx context.Context, orgID, userID string) (*org.Membership, error)
+}
+
+// KeyAuthorizer checks an organization's key grants where the key is used,
+// so revoking a grant or deleting the key stops bots already trading with
+// it.
+type KeyAuthorizer struct {
+	keys    KeyRepository
+	grants  GrantRepository
+	members Memberships
+}
+
+func NewKeyAuthorizer(keys KeyRepository, grants GrantRepository, members Memberships) *KeyAuthorizer {
+	return &KeyAuthorizer{keys: keys, grants: grants, members: members}
+}
+
+// Authorize tells whether the bot botID, started by userID, may trade with
+// the key keyID of owner's workspace. Personal keys are their user's own.
+// An organization's key needs a grant to the bot, or a member who is an
+// admin or holds a grant. It returns ErrKeyNotFound for a deleted key and
+// ErrKeyNotGranted when the key may not be used.
+func (a *KeyAuthorizer) Authorize(ctx context.Context, owner org.Owner, keyID, userID, botID string) error {
+	key, err := a.keys.Get(ctx, owner, keyID)
+	if err != nil {
+		return err
+	}
+	if key.OrgID == "" {
+		return nil
+	}
+
+	granted, err := a.grants.IsGranted(ctx, key.ID, GrantBot, botID)
+	if err != nil || granted {
+		return err
+	}
+	membership, err := a.members.MemberOf(ctx, key.OrgID, userID)
+	if errors.Is(err, org.ErrNotMember) {
+		return ErrKeyNotGranted
+	}
+	if err != nil {
+		return err
+	}
+	if membership.Role.AtLeast(org.RoleAdmin) {
+		return nil
+	}
+	granted, err = a.grants.IsGranted(ctx, key.ID, GrantMember, userID)
+	if err != nil {
+		return err
+	}
+	if !granted {
+		return ErrKeyNotGranted
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+type memoryKeys struct {
+	KeyRepository
+	keys map[string]APIKey
+}
+
+func (m *memoryKeys) Get(ctx context.Context, owner org.Owner, id string) (*APIKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &key, nil
+}
+
+type memoryGrants struct {
+	GrantRepository
+	grants map[string]bool
+}
+
+func (m *memoryGrants) IsGranted(ctx context.Context, keyID, subject, subjectID string) (bool, error) {
+	return m.grants[keyID+"/"+subject+"/"+subjectID], nil
+}
+
+type memoryMemberships map[string]org.Role
+
+func (m memoryMemberships) MemberOf(ctx context.Context, orgID, userID string) (*org.Membership, error) {
+	role, ok := m[orgID+"/"+userID]
+	if !ok {
+		return nil, org.ErrNotMember
+	}
+	return &org.Membership{Role: role}, nil
+}
+
+func TestKeyAuthorizer_Authorize(t *testing.T) {
+	keys := &memoryKeys{keys: map[string]APIKey{
+		"personal": {ID: "personal", UserID: "user-1"},
+		"shared":   {ID: "shared", UserID: "admin", OrgID: "org-1"},
+	}}
+	grants := &memoryGrants{grants: map[string]bool{
+		"shared/" + GrantMember + "/member-granted": true,
+		"shared/" + GrantBot + "/bot-granted":       true,
+	}}
+	members := memoryMemberships{
+		"org-1/admin":          org.RoleAdmin,
+		"org-1/member":         org.RoleMember,
+		"org-1/member-granted": org.RoleMember,
+	}
+	authorizer := NewKeyAuthorizer(keys, grants, members)
+	owner := org.Shared("org-1")
+
+	tests := []struct {
+		name   string
+		keyID  string
+		userID string
+		botID  string
+		want   error
+	}{
+		{"personal key", "personal", "user-1", "bot-1", nil},
+		{"deleted key", "gone", "admin", "bot-1", ErrKeyNotFound},
+		{"admin", "shared", "admin", "bot-1", nil},
+		{"member grant", "shared", "member-granted", "bot-1", nil},
+		{"bot grant", "shared", "member", "bot-granted", nil},
+		{"no grant", "shared", "member", "bot-1", ErrKeyNotGranted},
+		{"former member", "shared", "stranger", "bot-1", ErrKeyNotGranted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizer.Authorize(context.Background(), owner, tt.keyID, tt.userID, tt.botID)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
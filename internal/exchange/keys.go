@@ -0,0 +1,91 @@
+// internal/exchange/keys.go
+package exchange
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/org"
+	"gorm.io/gorm"
+)
+
+var ErrKeyNotFound = errors.New("api key not found")
+
+// APIKey is a user's or an organization's exchange API key. Testnet keys
+// are issued by the exchange's sandbox; requests made with them are routed
+// to testnet connectors and everything they produce is labelled as testnet.
+type APIKey struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"-" gorm:"type:varchar(36);not null;index"`
+	// OrgID is the organization the key is registered for, empty for a
+	// personal key; UserID is then the admin who registered it
+	OrgID    string `json:"org_id,omitempty" gorm:"type:varchar(36);not null;default:'';index"`
+	Exchange string `json:"exchange" gorm:"not null"`
+	Label    string `json:"label"`
+	// KeyID is the public half of the key as issued by the exchange
+	KeyID     string    `json:"key_id,omitempty" gorm:"not null"`
+	Testnet   bool      `json:"testnet" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (APIKey) TableName() string {
+	return "exchange_api_keys"
+}
+
+// Owner is whose workspace the key is in.
+func (k *APIKey) Owner() org.Owner {
+	return org.Owner{UserID: k.UserID, OrgID: k.OrgID}
+}
+
+type KeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	Get(ctx context.Context, owner org.Owner, id string) (*APIKey, error)
+	List(ctx context.Context, owner org.Owner) ([]APIKey, error)
+	// Delete removes the key along with its grants
+	Delete(ctx context.Context, owner org.Owner, id string) error
+}
+
+type keyRepository struct {
+	db *gorm.DB
+}
+
+func NewKeyRepository(db *gorm.DB) KeyRepository {
+	return &keyRepository{db: db}
+}
+
+func (r *keyRepository) Create(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *keyRepository) Get(ctx context.Context, owner org.Owner, id string) (*APIKey, error) {
+	var key APIKey
+	err := r.db.WithContext(ctx).Scopes(owner.Scope).Where("id = ?", id).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *keyRepository) List(ctx context.Context, owner org.Owner) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.db.WithContext(ctx).Scopes(owner.Scope).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *keyRepository) Delete(ctx context.Context, owner org.Owner, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(owner.Scope).Where("id = ?", id).Delete(&APIKey{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrKeyNotFound
+		}
+		return tx.Where("key_id = ?", id).Delete(&KeyGrant{}).Error
+	})
+}
@@ -0,0 +1,377 @@
+// internal/exchange/paper.go
+package exchange
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var ErrNoPrice = errors.New("no price available for symbol")
+
+// PaperClient is an in-memory exchange used for paper trading. Market
+// orders fill at the last price set for the symbol; limit orders rest until
+// the price crosses them. With chaos, it also rejects, partially fills,
+// acknowledges late and reports fills twice.
+type PaperClient struct {
+	name      string
+	testnet   bool
+	chaos     *Chaos
+	mutex     sync.Mutex
+	prices    map[string]decimal.Decimal
+	orders    map[string]*Order               // by order ID
+	owners    map[string]string               // order ID -> user ID
+	clientIDs map[string]string               // user ID + client order ID -> order ID
+	positions map[string]map[string]*Position // user ID -> symbol -> position
+	balances  map[string]decimal.Decimal      // user ID -> wallet balance
+	fills     map[string][]Fill               // user ID -> executions, oldest first
+}
+
+func NewPaperClient(name string) *PaperClient {
+	return &PaperClient{
+		name:      name,
+		prices:    make(map[string]decimal.Decimal),
+		orders:    make(map[string]*Order),
+		owners:    make(map[string]string),
+		clientIDs: make(map[string]string),
+		positions: make(map[string]map[string]*Position),
+		balances:  make(map[string]decimal.Decimal),
+		fills:     make(map[string][]Fill),
+	}
+}
+
+// NewTestnetPaperClient simulates the exchange's testnet; everything it
+// returns is labelled as testnet.
+func NewTestnetPaperClient(name string) *PaperClient {
+	p := NewPaperClient(name)
+	p.testnet = true
+	return p
+}
+
+// WithChaos makes the exchange misbehave as chaos decides for each user.
+func (p *PaperClient) WithChaos(chaos *Chaos) *PaperClient {
+	p.chaos = chaos
+	return p
+}
+
+// Deposit credits the user's paper wallet.
+func (p *PaperClient) Deposit(ctx context.Context, userID string, amount decimal.Decimal) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.balances[userID] = p.balances[userID].Add(amount)
+	return nil
+}
+
+// Reset drops the user's orders, positions and wallet balance.
+func (p *PaperClient) Reset(ctx context.Context, userID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for id, owner := range p.owners {
+		if owner != userID {
+			continue
+		}
+		if order := p.orders[id]; order.ClientOrderID != "" {
+			delete(p.clientIDs, userID+":"+order.ClientOrderID)
+		}
+		delete(p.orders, id)
+		delete(p.owners, id)
+	}
+	delete(p.positions, userID)
+	delete(p.balances, userID)
+	delete(p.fills, userID)
+	return nil
+}
+
+// SetChaos sets how the user's account misbehaves, if the exchange
+// allows chaos at all.
+func (p *PaperClient) SetChaos(ctx context.Context, userID string, settings ChaosSettings) error {
+	return p.chaos.Set(userID, settings)
+}
+
+// Chaos returns how the user's account misbehaves.
+func (p *PaperClient) Chaos(ctx context.Context, userID string) (ChaosSettings, error) {
+	return p.chaos.Get(userID)
+}
+
+// SetPrice updates the mark price and fills resting limit orders it crosses.
+func (p *PaperClient) SetPrice(symbol string, price decimal.Decimal) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.prices[symbol] = price
+
+	for id, order := range p.orders {
+		if order.Status != OrderStatusOpen || order.Symbol != symbol {
+			continue
+		}
+		crossed := (order.Side == SideBuy && price.LessThanOrEqual(order.Price)) ||
+			(order.Side == SideSell && price.GreaterThanOrEqual(order.Price))
+		if crossed {
+			p.fill(p.owners[id], order, order.Price)
+		}
+	}
+}
+
+func (p *PaperClient) OpenOrders(ctx context.Context, userID, symbol string) ([]Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var orders []Order
+	for id, order := range p.orders {
+		if p.owners[id] != userID || order.Status != OrderStatusOpen {
+			continue
+		}
+		if symbol != "" && order.Symbol != symbol {
+			continue
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
+
+func (p *PaperClient) Order(ctx context.Context, userID, orderID string) (*Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	order, ok := p.orders[orderID]
+	if !ok || p.owners[orderID] != userID {
+		return nil, ErrOrderNotFound
+	}
+	found := *order
+	return &found, nil
+}
+
+// PlaceOrder places the order. A delayed acknowledgement comes after the
+// order is placed, so a caller that gives up waiting has still traded.
+func (p *PaperClient) PlaceOrder(ctx context.Context, userID string, req OrderRequest) (*Order, error) {
+	order, err := p.place(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.chaos.delay(ctx, userID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (p *PaperClient) place(userID string, req OrderRequest) (*Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if req.ClientOrderID != "" {
+		if id, ok := p.clientIDs[userID+":"+req.ClientOrderID]; ok {
+			existing := *p.orders[id]
+			return &existing, nil
+		}
+	}
+
+	order := &Order{
+		ID:            uuid.New().String(),
+		ClientOrderID: req.ClientOrderID,
+		Exchange:      p.name,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		Status:        OrderStatusOpen,
+		Price:         req.Price,
+		Quantity:      req.Quantity,
+		Filled:        decimal.Zero,
+		ReduceOnly:    req.ReduceOnly,
+		Testnet:       p.testnet,
+		CreatedAt:     time.Now(),
+	}
+
+	if p.chaos.reject(userID) {
+		order.Status = OrderStatusRejected
+	} else if req.ReduceOnly {
+		position := p.positions[userID][req.Symbol]
+		if position == nil || position.Side == req.Side || position.Quantity.IsZero() {
+			order.Status = OrderStatusRejected
+		} else if order.Quantity.GreaterThan(position.Quantity) {
+			order.Quantity = position.Quantity
+		}
+	}
+
+	if order.Status == OrderStatusOpen && req.Type == OrderTypeMarket {
+		price, ok := p.prices[req.Symbol]
+		if !ok {
+			return nil, ErrNoPrice
+		}
+		p.fill(userID, order, price)
+		// What a partial fill leaves of a market order does not rest
+		if order.Status == OrderStatusOpen {
+			order.Status = OrderStatusCancelled
+		}
+	}
+
+	p.orders[order.ID] = order
+	p.owners[order.ID] = userID
+	if req.ClientOrderID != "" {
+		p.clientIDs[userID+":"+req.ClientOrderID] = order.ID
+	}
+
+	placed := *order
+	return &placed, nil
+}
+
+func (p *PaperClient) CancelOrder(ctx context.Context, userID, orderID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	order, ok := p.orders[orderID]
+	if !ok || p.owners[orderID] != userID {
+		return ErrOrderNotFound
+	}
+	if order.Status != OrderStatusOpen {
+		return ErrOrderClosed
+	}
+
+	order.Status = OrderStatusCancelled
+	return nil
+}
+
+func (p *PaperClient) Positions(ctx context.Context, userID, symbol string) ([]Position, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var positions []Position
+	for sym, position := range p.positions[userID] {
+		if position.Quantity.IsZero() {
+			continue
+		}
+		if symbol != "" && sym != symbol {
+			continue
+		}
+		positions = append(positions, *position)
+	}
+	return positions, nil
+}
+
+func (p *PaperClient) Account(ctx context.Context, userID string) (*Account, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	account := &Account{
+		Exchange:      p.name,
+		Balance:       p.balances[userID],
+		UnrealizedPnL: decimal.Zero,
+		Testnet:       p.testnet,
+	}
+	for symbol, position := range p.positions[userID] {
+		price, ok := p.prices[symbol]
+		if !ok || position.Quantity.IsZero() {
+			continue
+		}
+		account.UnrealizedPnL = account.UnrealizedPnL.Add(pnl(position, price, position.Quantity))
+	}
+	account.Equity = account.Balance.Add(account.UnrealizedPnL)
+	return account, nil
+}
+
+func (p *PaperClient) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	price, ok := p.prices[symbol]
+	if !ok {
+		return decimal.Zero, ErrNoPrice
+	}
+	return price, nil
+}
+
+// Fills returns the user's executions since the given time, oldest first.
+func (p *PaperClient) Fills(ctx context.Context, userID string, since time.Time) ([]Fill, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var fills []Fill
+	for _, fill := range p.fills[userID] {
+		if !fill.ExecutedAt.Before(since) {
+			fills = append(fills, fill)
+		}
+	}
+	return fills, nil
+}
+
+// fill executes what is left of the order at price, or with chaos possibly
+// only part of it, records the execution and updates the net position.
+// Callers must hold the mutex.
+func (p *PaperClient) fill(userID string, order *Order, price decimal.Decimal) {
+	quantity := p.chaos.fillQuantity(userID, order.Quantity.Sub(order.Filled))
+	order.Filled = order.Filled.Add(quantity)
+	if order.Filled.Equal(order.Quantity) {
+		order.Status = OrderStatusFilled
+	}
+	order.Price = price
+
+	fill := Fill{
+		ID:            uuid.New().String(),
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientOrderID,
+		Exchange:      p.name,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Price:         price,
+		Quantity:      quantity,
+		Testnet:       p.testnet,
+		ExecutedAt:    time.Now(),
+	}
+	p.fills[userID] = append(p.fills[userID], fill)
+	if p.chaos.duplicateFill(userID) {
+		p.fills[userID] = append(p.fills[userID], fill)
+	}
+
+	if p.positions[userID] == nil {
+		p.positions[userID] = make(map[string]*Position)
+	}
+	position := p.positions[userID][order.Symbol]
+	if position == nil || position.Quantity.IsZero() {
+		p.positions[userID][order.Symbol] = &Position{
+			Exchange:   p.name,
+			Symbol:     order.Symbol,
+			Side:       order.Side,
+			Quantity:   quantity,
+			EntryPrice: price,
+			Testnet:    p.testnet,
+		}
+		return
+	}
+
+	if position.Side == order.Side {
+		total := position.Quantity.Add(quantity)
+		position.EntryPrice = position.EntryPrice.Mul(position.Quantity).Add(price.Mul(quantity)).Div(total)
+		position.Quantity = total
+		return
+	}
+
+	closed := decimal.Min(position.Quantity, quantity)
+	p.balances[userID] = p.balances[userID].Add(pnl(position, price, closed))
+
+	remaining := position.Quantity.Sub(quantity)
+	switch {
+	case remaining.IsPositive():
+		position.Quantity = remaining
+	case remaining.IsZero():
+		position.Quantity = decimal.Zero
+	default:
+		// Order flipped the position
+		position.Side = order.Side
+		position.Quantity = remaining.Neg()
+		position.EntryPrice = price
+	}
+}
+
+// pnl is the profit of closing quantity of position at price.
+func pnl(position *Position, price, quantity decimal.Decimal) decimal.Decimal {
+	diff := price.Sub(position.EntryPrice)
+	if position.Side == SideSell {
+		diff = diff.Neg()
+	}
+	return diff.Mul(quantity)
+}
@@ -0,0 +1,37 @@
+// internal/exchange/precision.go
+package exchange
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+// Precisions holds the configured precision of each market by exchange and
+// symbol.
+type Precisions map[string]map[string]money.Precision
+
+func NewPrecisions(cfg map[string]config.ExchangeConfig) (Precisions, error) {
+	precisions := make(Precisions, len(cfg))
+	for name, exchangeCfg := range cfg {
+		symbols := make(map[string]money.Precision, len(exchangeCfg.Precision))
+		for symbol, p := range exchangeCfg.Precision {
+			precision, err := money.ParsePrecision(p.TickSize, p.StepSize, p.MinNotional)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", name, symbol, err)
+			}
+			// viper lowercases map keys
+			symbols[strings.ToUpper(symbol)] = precision
+		}
+		precisions[name] = symbols
+	}
+	return precisions, nil
+}
+
+// Get returns the market's precision; unconfigured markets are
+// unconstrained.
+func (p Precisions) Get(exchange, symbol string) money.Precision {
+	return p[exchange][strings.ToUpper(symbol)]
+}
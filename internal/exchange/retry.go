@@ -0,0 +1,98 @@
+// internal/exchange/retry.go
+package exchange
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/pkg/retry"
+)
+
+// RetryPolicy returns the retry policy of an exchange's connectors. Share
+// it between the exchange's mainnet and testnet clients so they draw on
+// one budget.
+func RetryPolicy(name string, cfg retry.Config) *retry.Policy {
+	return retry.New("exchange_"+name, cfg, retry.WithClassifier(Retryable))
+}
+
+// Retryable reports whether an exchange call may succeed on another
+// attempt. Answers about the order or symbol itself will not change.
+func Retryable(err error) bool {
+	return !errors.Is(err, ErrUnknownExchange) &&
+		!errors.Is(err, ErrOrderNotFound) &&
+		!errors.Is(err, ErrOrderClosed) &&
+		!errors.Is(err, ErrNoPrice)
+}
+
+// WithRetry repeats the client's failed calls under policy. Orders are
+// only placed again when they carry a client order ID, which keeps the
+// exchange from filling them twice.
+func WithRetry(client Client, policy *retry.Policy) Client {
+	return &retryingClient{Client: client, policy: policy}
+}
+
+// Unwrap returns the connector beneath the client's wrappers.
+func Unwrap(client Client) Client {
+	for {
+		wrapper, ok := client.(interface{ Unwrap() Client })
+		if !ok {
+			return client
+		}
+		client = wrapper.Unwrap()
+	}
+}
+
+type retryingClient struct {
+	Client
+	policy *retry.Policy
+}
+
+func (c *retryingClient) Unwrap() Client {
+	return c.Client
+}
+
+func (c *retryingClient) OpenOrders(ctx context.Context, userID, symbol string) ([]Order, error) {
+	return retry.Value(ctx, c.policy, func(ctx context.Context) ([]Order, error) {
+		return c.Client.OpenOrders(ctx, userID, symbol)
+	})
+}
+
+func (c *retryingClient) Order(ctx context.Context, userID, orderID string) (*Order, error) {
+	return retry.Value(ctx, c.policy, func(ctx context.Context) (*Order, error) {
+		return c.Client.Order(ctx, userID, orderID)
+	})
+}
+
+func (c *retryingClient) PlaceOrder(ctx context.Context, userID string, req OrderRequest) (*Order, error) {
+	if req.ClientOrderID == "" {
+		return c.Client.PlaceOrder(ctx, userID, req)
+	}
+	return retry.Value(ctx, c.policy, func(ctx context.Context) (*Order, error) {
+		return c.Client.PlaceOrder(ctx, userID, req)
+	})
+}
+
+func (c *retryingClient) CancelOrder(ctx context.Context, userID, orderID string) error {
+	return c.policy.Do(ctx, func(ctx context.Context) error {
+		return c.Client.CancelOrder(ctx, userID, orderID)
+	})
+}
+
+func (c *retryingClient) Positions(ctx context.Context, userID, symbol string) ([]Position, error) {
+	return retry.Value(ctx, c.policy, func(ctx context.Context) ([]Position, error) {
+		return c.Client.Positions(ctx, userID, symbol)
+	})
+}
+
+func (c *retryingClient) Account(ctx context.Context, userID string) (*Account, error) {
+	return retry.Value(ctx, c.policy, func(ctx context.Context) (*Account, error) {
+		return c.Client.Account(ctx, userID)
+	})
+}
+
+func (c *retryingClient) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	return retry.Value(ctx, c.policy, func(ctx context.Context) (decimal.Decimal, error) {
+		return c.Client.LastPrice(ctx, symbol)
+	})
+}
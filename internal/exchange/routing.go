@@ -0,0 +1,342 @@
+// internal/exchange/routing.go
+package exchange
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+var ErrNoConnector = errors.New("no connector available for exchange")
+
+var connectorLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "exchange_connector_latency_seconds",
+	Help:    "Round-trip latency of calls through connector instances, by region.",
+	Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"exchange", "region"})
+
+// latencyAlpha weights new samples in the moving latency average.
+const latencyAlpha = 0.2
+
+type Connector struct {
+	Exchange string
+	Region   string
+	Address  string
+	// Testnet connectors talk to the exchange's sandbox environment
+	Testnet bool
+}
+
+type connectorState struct {
+	connector Connector
+	client    *ConnectorClient
+	latency   time.Duration // exponentially weighted moving average
+	healthy   bool
+}
+
+// Router picks the connector instance used for order placement. Instances
+// co-located with the exchange's matching engine are preferred; otherwise
+// the instance with the lowest observed latency wins.
+type Router struct {
+	matchingRegions map[string]string
+	connectors      map[string][]*connectorState
+	mutex           sync.RWMutex
+}
+
+func NewRouter(exchanges map[string]config.ExchangeConfig, timeout time.Duration) *Router {
+	r := &Router{
+		matchingRegions: make(map[string]string),
+		connectors:      make(map[string][]*connectorState),
+	}
+
+	httpClient := &http.Client{Timeout: timeout}
+	add := func(connector Connector) {
+		r.connectors[connector.Exchange] = append(r.connectors[connector.Exchange], &connectorState{
+			connector: connector,
+			client:    NewConnectorClient(connector, httpClient),
+			healthy:   true,
+		})
+	}
+	for name, exchange := range exchanges {
+		r.matchingRegions[name] = exchange.MatchingEngineRegion
+		for _, c := range exchange.Connectors {
+			add(Connector{Exchange: name, Region: c.Region, Address: c.Address})
+		}
+		for _, c := range exchange.TestnetConnectors {
+			add(Connector{Exchange: name, Region: c.Region, Address: c.Address, Testnet: true})
+		}
+	}
+
+	return r
+}
+
+// Select returns the best mainnet connector for placing orders on the
+// exchange.
+func (r *Router) Select(exchange string) (Connector, error) {
+	return r.SelectFor(exchange, false)
+}
+
+// SelectFor returns the best connector of the mainnet or testnet
+// environment. Requests signed with a testnet key must never reach a
+// mainnet endpoint, so there is no fallback between the two.
+func (r *Router) SelectFor(exchange string, testnet bool) (Connector, error) {
+	state, err := r.selectState(exchange, testnet)
+	if err != nil {
+		return Connector{}, err
+	}
+	return state.connector, nil
+}
+
+func (r *Router) selectState(exchange string, testnet bool) (*connectorState, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var best *connectorState
+	for _, state := range r.connectors[exchange] {
+		if !state.healthy || state.connector.Testnet != testnet {
+			continue
+		}
+		if best == nil || r.better(exchange, state, best) {
+			best = state
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoConnector
+	}
+	return best, nil
+}
+
+func (r *Router) better(exchange string, a, b *connectorState) bool {
+	home := r.matchingRegions[exchange]
+	aHome, bHome := a.connector.Region == home, b.connector.Region == home
+	if aHome != bHome {
+		return aHome
+	}
+
+	// Unmeasured instances lose against measured ones
+	switch {
+	case a.latency == 0:
+		return false
+	case b.latency == 0:
+		return true
+	}
+	return a.latency < b.latency
+}
+
+// observe records a round-trip measurement for a connector instance.
+func (r *Router) observe(state *connectorState, latency time.Duration) {
+	connectorLatency.WithLabelValues(state.connector.Exchange, state.connector.Region).Observe(latency.Seconds())
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if state.latency == 0 {
+		state.latency = latency
+	} else {
+		state.latency = time.Duration(latencyAlpha*float64(latency) + (1-latencyAlpha)*float64(state.latency))
+	}
+}
+
+// setHealthy marks a connector instance as usable or not.
+func (r *Router) setHealthy(state *connectorState, healthy bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if state.healthy && !healthy {
+		log.Printf("Exchange connector %s in %s at %s is unavailable", state.connector.Exchange, state.connector.Region, state.connector.Address)
+	}
+	state.healthy = healthy
+}
+
+// Probe checks every connector instance each interval until ctx ends.
+// Instances that fail a call are taken out of rotation until a probe finds
+// them answering again, and probes keep the latency of idle instances
+// current.
+func (r *Router) Probe(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.mutex.RLock()
+		var states []*connectorState
+		for _, exchange := range r.connectors {
+			states = append(states, exchange...)
+		}
+		r.mutex.RUnlock()
+
+		for _, state := range states {
+			probeCtx, cancel := context.WithTimeout(ctx, interval)
+			started := time.Now()
+			err := state.client.Health(probeCtx)
+			cancel()
+			if err == nil {
+				r.observe(state, time.Since(started))
+			}
+			r.setHealthy(state, err == nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RegionLatency is a snapshot of a connector's averaged latency.
+type RegionLatency struct {
+	Exchange  string        `json:"exchange"`
+	Region    string        `json:"region"`
+	Latency   time.Duration `json:"latency_ns"`
+	Healthy   bool          `json:"healthy"`
+	Preferred bool          `json:"preferred"`
+	Testnet   bool          `json:"testnet"`
+}
+
+// Latencies returns the current latency view for every connector instance.
+func (r *Router) Latencies() []RegionLatency {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var out []RegionLatency
+	for exchange, states := range r.connectors {
+		for _, state := range states {
+			out = append(out, RegionLatency{
+				Exchange:  exchange,
+				Region:    state.connector.Region,
+				Latency:   state.latency,
+				Healthy:   state.healthy,
+				Preferred: state.connector.Region == r.matchingRegions[exchange],
+				Testnet:   state.connector.Testnet,
+			})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Exchange != out[j].Exchange {
+			return out[i].Exchange < out[j].Exchange
+		}
+		if out[i].Testnet != out[j].Testnet {
+			return !out[i].Testnet
+		}
+		return out[i].Region < out[j].Region
+	})
+	return out
+}
+
+// Client returns a client that sends each call to the connector instance
+// Select picks at that moment, recording its latency.
+func (r *Router) Client(exchange string, testnet bool) Client {
+	return &routedClient{router: r, exchange: exchange, testnet: testnet}
+}
+
+type routedClient struct {
+	router   *Router
+	exchange string
+	testnet  bool
+}
+
+// routed runs call against the selected connector. Failing to reach the
+// instance takes it out of rotation, so a retry goes to the next best one.
+func routed[T any](ctx context.Context, c *routedClient, call func(Client) (T, error)) (T, error) {
+	var zero T
+	state, err := c.router.selectState(c.exchange, c.testnet)
+	if err != nil {
+		return zero, err
+	}
+
+	started := time.Now()
+	value, err := call(state.client)
+	switch {
+	case errors.Is(err, ErrConnectorUnavailable):
+		c.router.setHealthy(state, false)
+	case ctx.Err() == nil:
+		c.router.observe(state, time.Since(started))
+	}
+	return value, err
+}
+
+func (c *routedClient) OpenOrders(ctx context.Context, userID, symbol string) ([]Order, error) {
+	return routed(ctx, c, func(client Client) ([]Order, error) {
+		return client.OpenOrders(ctx, userID, symbol)
+	})
+}
+
+func (c *routedClient) Order(ctx context.Context, userID, orderID string) (*Order, error) {
+	return routed(ctx, c, func(client Client) (*Order, error) {
+		return client.Order(ctx, userID, orderID)
+	})
+}
+
+func (c *routedClient) PlaceOrder(ctx context.Context, userID string, req OrderRequest) (*Order, error) {
+	return routed(ctx, c, func(client Client) (*Order, error) {
+		return client.PlaceOrder(ctx, userID, req)
+	})
+}
+
+func (c *routedClient) CancelOrder(ctx context.Context, userID, orderID string) error {
+	_, err := routed(ctx, c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.CancelOrder(ctx, userID, orderID)
+	})
+	return err
+}
+
+func (c *routedClient) Positions(ctx context.Context, userID, symbol string) ([]Position, error) {
+	return routed(ctx, c, func(client Client) ([]Position, error) {
+		return client.Positions(ctx, userID, symbol)
+	})
+}
+
+func (c *routedClient) Account(ctx context.Context, userID string) (*Account, error) {
+	return routed(ctx, c, func(client Client) (*Account, error) {
+		return client.Account(ctx, userID)
+	})
+}
+
+func (c *routedClient) Deposit(ctx context.Context, userID string, amount decimal.Decimal) error {
+	_, err := routed(ctx, c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.(PaperAccounts).Deposit(ctx, userID, amount)
+	})
+	return err
+}
+
+func (c *routedClient) Reset(ctx context.Context, userID string) error {
+	_, err := routed(ctx, c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.(PaperAccounts).Reset(ctx, userID)
+	})
+	return err
+}
+
+func (c *routedClient) SetChaos(ctx context.Context, userID string, settings ChaosSettings) error {
+	_, err := routed(ctx, c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.(PaperAccounts).SetChaos(ctx, userID, settings)
+	})
+	return err
+}
+
+func (c *routedClient) Chaos(ctx context.Context, userID string) (ChaosSettings, error) {
+	return routed(ctx, c, func(client Client) (ChaosSettings, error) {
+		return client.(PaperAccounts).Chaos(ctx, userID)
+	})
+}
+
+func (c *routedClient) Fills(ctx context.Context, userID string, since time.Time) ([]Fill, error) {
+	return routed(ctx, c, func(client Client) ([]Fill, error) {
+		return client.(FillSource).Fills(ctx, userID, since)
+	})
+}
+
+func (c *routedClient) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	return routed(ctx, c, func(client Client) (decimal.Decimal, error) {
+		return client.LastPrice(ctx, symbol)
+	})
+}
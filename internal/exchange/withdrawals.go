@@ -0,0 +1,86 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWithdrawalAddressNotFound = errors.New("withdrawal address not found")
+	ErrWithdrawalAddressExists   = errors.New("withdrawal address already added")
+)
+
+// WithdrawalAddress is a destination an organization allows funds to be
+// withdrawn to from its exchange accounts. Adding one takes the approval of
+// a second admin; removing one does not.
+type WithdrawalAddress struct {
+	ID       string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OrgID    string `json:"org_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_withdrawal_addresses_org,priority:1"`
+	Exchange string `json:"exchange" gorm:"not null;uniqueIndex:idx_withdrawal_addresses_org,priority:2"`
+	Asset    string `json:"asset" gorm:"type:varchar(16);not null;uniqueIndex:idx_withdrawal_addresses_org,priority:3"`
+	Address  string `json:"address" gorm:"type:varchar(128);not null;uniqueIndex:idx_withdrawal_addresses_org,priority:4"`
+	Label    string `json:"label,omitempty"`
+	// AddedBy is the admin who asked for the address; ApprovedBy the one
+	// who approved it
+	AddedBy    string    `json:"added_by" gorm:"type:varchar(36);not null"`
+	ApprovedBy string    `json:"approved_by" gorm:"type:varchar(36);not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (WithdrawalAddress) TableName() string {
+	return "exchange_withdrawal_addresses"
+}
+
+type WithdrawalAddressRepository interface {
+	// List returns the organization's addresses, oldest first
+	List(ctx context.Context, orgID string) ([]WithdrawalAddress, error)
+	// Add stores the address, or returns ErrWithdrawalAddressExists when
+	// the organization has it for the exchange and asset already
+	Add(ctx context.Context, address *WithdrawalAddress) error
+	Delete(ctx context.Context, orgID, id string) error
+}
+
+type withdrawalAddressRepository struct {
+	db *gorm.DB
+}
+
+func NewWithdrawalAddressRepository(db *gorm.DB) WithdrawalAddressRepository {
+	return &withdrawalAddressRepository{db: db}
+}
+
+func (r *withdrawalAddressRepository) List(ctx context.Context, orgID string) ([]WithdrawalAddress, error) {
+	addresses := []WithdrawalAddress{}
+	err := r.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at").Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *withdrawalAddressRepository) Add(ctx context.Context, address *WithdrawalAddress) error {
+	if address.ID == "" {
+		address.ID = uuid.New().String()
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(address)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWithdrawalAddressExists
+	}
+	return nil
+}
+
+func (r *withdrawalAddressRepository) Delete(ctx context.Context, orgID, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND org_id = ?", id, orgID).Delete(&WithdrawalAddress{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWithdrawalAddressNotFound
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package faults
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var injectedFaults = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "faults_injected_total",
+	Help: "Faults injected into gRPC calls and NATS deliveries.",
+}, []string{"target", "kind"})
+
+// Injector adds latency, errors and dropped messages to the calls and
+// subjects it targets. A nil Injector injects nothing, so callers can wire
+// it unconditionally.
+type Injector struct {
+	cfg config.FaultsConfig
+}
+
+// New returns nil unless fault injection is enabled.
+func New(cfg config.FaultsConfig) *Injector {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Injector{cfg: cfg}
+}
+
+// UnaryServerInterceptor delays and fails incoming calls.
+func (i *Injector) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := i.call(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor delays and fails outgoing calls before they reach
+// the network, which also covers backends that are not injected themselves.
+func (i *Injector) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := i.call(ctx, method); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// WrapHTTP delays and fails incoming HTTP requests, targeted by path. The
+// paper exchange uses it so connector calls from every service see faults.
+func (i *Injector) WrapHTTP(next http.Handler) http.Handler {
+	if i == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := i.call(r.Context(), r.URL.Path); err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WrapHandler delays and drops messages before handler sees them.
+func (i *Injector) WrapHandler(subject string, handler nats.MsgHandler) nats.MsgHandler {
+	if i == nil || !i.targets(subject) {
+		return handler
+	}
+	return func(msg *nats.Msg) {
+		if i.roll(i.cfg.DropRate) {
+			injectedFaults.WithLabelValues(subject, "drop").Inc()
+			return
+		}
+		i.delay(context.Background(), subject)
+		handler(msg)
+	}
+}
+
+func (i *Injector) call(ctx context.Context, method string) error {
+	if i == nil || !i.targets(method) {
+		return nil
+	}
+	if err := i.delay(ctx, method); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if i.roll(i.cfg.ErrorRate) {
+		injectedFaults.WithLabelValues(method, "error").Inc()
+		return status.Error(codes.Unavailable, "injected fault")
+	}
+	return nil
+}
+
+func (i *Injector) delay(ctx context.Context, target string) error {
+	latency := i.cfg.Latency
+	if i.cfg.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(i.cfg.Jitter)))
+	}
+	if latency <= 0 {
+		return nil
+	}
+	injectedFaults.WithLabelValues(target, "latency").Inc()
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (i *Injector) roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+func (i *Injector) targets(name string) bool {
+	if len(i.cfg.Targets) == 0 {
+		return true
+	}
+	for _, target := range i.cfg.Targets {
+		if prefix, ok := strings.CutSuffix(target, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == target {
+			return true
+		}
+	}
+	return false
+}
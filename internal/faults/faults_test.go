@@ -0,0 +1,36 @@
+package faults
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+func TestInjector_WrapHTTP(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		cfg    config.FaultsConfig
+		path   string
+		status int
+	}{
+		{"disabled", config.FaultsConfig{ErrorRate: 1}, "/v1/orders", http.StatusOK},
+		{"targeted", config.FaultsConfig{Enabled: true, ErrorRate: 1, Targets: []string{"/v1/orders*"}}, "/v1/orders/1", http.StatusServiceUnavailable},
+		{"untargeted", config.FaultsConfig{Enabled: true, ErrorRate: 1, Targets: []string{"/v1/orders*"}}, "/v1/balances", http.StatusOK},
+		{"no errors", config.FaultsConfig{Enabled: true}, "/v1/orders", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			New(tt.cfg).WrapHTTP(ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
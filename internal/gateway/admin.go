@@ -0,0 +1,68 @@
+// internal/gateway/admin.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/middleware"
+)
+
+type accessListEntryRequest struct {
+	Entry string `json:"entry" binding:"required"`
+}
+
+// Rate limit access list handlers
+func (gw *Gateway) GetAccessLists(c *gin.Context) {
+	c.JSON(http.StatusOK, gw.AccessList.Entries())
+}
+
+func (gw *Gateway) AddAccessListEntry(c *gin.Context) {
+	var req accessListEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := gw.AccessList.Add(c.Request.Context(), c.Param("list"), req.Entry); err != nil {
+		gw.accessListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gw.AccessList.Entries())
+}
+
+func (gw *Gateway) RemoveAccessListEntry(c *gin.Context) {
+	entry := c.Query("entry")
+	if entry == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entry query parameter required"})
+		return
+	}
+
+	if err := gw.AccessList.Remove(c.Request.Context(), c.Param("list"), entry); err != nil {
+		gw.accessListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gw.AccessList.Entries())
+}
+
+func (gw *Gateway) accessListError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, middleware.ErrUnknownAccessList):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, middleware.ErrInvalidEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// Exchange routing handlers
+func (gw *Gateway) GetExchangeLatencies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"region":     gw.config.Region,
+		"connectors": gw.Exchanges.Latencies(),
+	})
+}
@@ -0,0 +1,109 @@
+// internal/gateway/allowlist.go
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetIPAllowlist returns the networks the caller's requests must come from.
+func (gw *Gateway) GetIPAllowlist(c *gin.Context) {
+	resp, err := gw.authClientFor(c).GetIPAllowlist(c.Request.Context(), &authpb.GetIPAllowlistRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+	})
+	if err != nil {
+		allowlistRPCError(c, err, "Failed to load IP allowlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, ipAllowlistResponse(resp))
+}
+
+// AddAllowedNetwork adds an address or CIDR range to the caller's
+// allowlist. The first one restricts the account to it.
+func (gw *Gateway) AddAllowedNetwork(c *gin.Context) {
+	var req openapi.AddAllowedNetworkJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).AddAllowedNetwork(c.Request.Context(), &authpb.AddAllowedNetworkRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Cidr:        req.Cidr,
+		Label:       req.Label,
+	})
+	if err != nil {
+		allowlistRPCError(c, err, "Failed to add network")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ipAllowlistResponse(resp))
+}
+
+// RemoveAllowedNetwork removes a network from the caller's allowlist.
+func (gw *Gateway) RemoveAllowedNetwork(c *gin.Context) {
+	resp, err := gw.authClientFor(c).RemoveAllowedNetwork(c.Request.Context(), &authpb.RemoveAllowedNetworkRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Id:          c.Param("id"),
+	})
+	if err != nil {
+		allowlistRPCError(c, err, "Failed to remove network")
+		return
+	}
+
+	c.JSON(http.StatusOK, ipAllowlistResponse(resp))
+}
+
+// SetIPAllowlistMode sets whether the allowlist restricts all of the
+// caller's requests or trading and withdrawal ones only.
+func (gw *Gateway) SetIPAllowlistMode(c *gin.Context) {
+	var req openapi.SetIPAllowlistModeJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).SetIPAllowlistMode(c.Request.Context(), &authpb.SetIPAllowlistModeRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Mode:        string(req.Mode),
+	})
+	if err != nil {
+		allowlistRPCError(c, err, "Failed to set IP allowlist mode")
+		return
+	}
+
+	c.JSON(http.StatusOK, ipAllowlistResponse(resp))
+}
+
+// ipAllowlistResponse lists no networks as an empty array rather than
+// leaving the field out.
+func ipAllowlistResponse(allowlist *authpb.IPAllowlist) gin.H {
+	networks := allowlist.Networks
+	if networks == nil {
+		networks = []*authpb.AllowedNetwork{}
+	}
+	return gin.H{"mode": allowlist.Mode, "networks": networks}
+}
+
+// allowlistRPCError maps the errors of the IP allowlist RPCs.
+func allowlistRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": status.Convert(err).Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,91 @@
+// internal/gateway/apikeys.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+type createAPIKeyRequest struct {
+	Exchange string `json:"exchange" binding:"required"`
+	Label    string `json:"label" binding:"max=100"`
+	KeyID    string `json:"key_id" binding:"required"`
+	Testnet  bool   `json:"testnet"`
+}
+
+func (gw *Gateway) ListAPIKeys(c *gin.Context) {
+	keys, err := gw.apiKeys.List(c.Request.Context(), org.Personal(c.GetString("user_id")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// CreateAPIKey registers an exchange key. Testnet keys are only accepted
+// for exchanges with testnet connectors, since their requests must never
+// fall back to mainnet.
+func (gw *Gateway) CreateAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !gw.checkKeyExchange(c, req.Exchange, req.Testnet) {
+		return
+	}
+
+	key := &exchange.APIKey{
+		ID:       uuid.New().String(),
+		UserID:   c.GetString("user_id"),
+		Exchange: req.Exchange,
+		Label:    req.Label,
+		KeyID:    req.KeyID,
+		Testnet:  req.Testnet,
+	}
+	if err := gw.apiKeys.Create(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save API key"})
+		return
+	}
+	gw.audit(c, auth.AuditAPIKeyCreated, map[string]string{"key_id": key.ID, "exchange": key.Exchange})
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (gw *Gateway) DeleteAPIKey(c *gin.Context) {
+	err := gw.apiKeys.Delete(c.Request.Context(), org.Personal(c.GetString("user_id")), c.Param("id"))
+	if errors.Is(err, exchange.ErrKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
+		return
+	}
+	gw.audit(c, auth.AuditAPIKeyDeleted, map[string]string{"key_id": c.Param("id")})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key deleted"})
+}
+
+// checkKeyExchange answers 400 unless keys of the exchange, testnet ones
+// included, can be registered.
+func (gw *Gateway) checkKeyExchange(c *gin.Context, name string, testnet bool) bool {
+	exchangeCfg, ok := gw.config.Exchanges[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": exchange.ErrUnknownExchange.Error()})
+		return false
+	}
+	if testnet && len(exchangeCfg.TestnetConnectors) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exchange has no testnet environment"})
+		return false
+	}
+	return true
+}
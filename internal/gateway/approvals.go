@@ -0,0 +1,170 @@
+// internal/gateway/approvals.go
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/approval"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/middleware"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+type deployLiveBotPayload struct {
+	BotID  string `json:"bot_id"`
+	UserID string `json:"user_id"`
+}
+
+type botLimitsPayload struct {
+	BotID               string `json:"bot_id"`
+	MaxOrdersPerMinute  int    `json:"max_orders_per_minute"`
+	MaxCancelsPerMinute int    `json:"max_cancels_per_minute"`
+}
+
+type rejectRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// errNoApprover is returned when a live bot needs approval but no
+// organization resolves whose admins could give it.
+var errNoApprover = errors.New("no organization can approve this bot")
+
+// requestLiveBotApproval files an approval request when a live bot above
+// the capital threshold is started: with the organization the bot belongs
+// to, or else the one the user administers. It returns nil only when the
+// bot can start right away; without an organization it fails closed.
+func (gw *Gateway) requestLiveBotApproval(c *gin.Context, b *bot.Bot) (*approval.Request, error) {
+	if gw.config.Trading.Mode != "live" || b.Capital.LessThan(money.FromFloat(gw.config.Approvals.LiveBotCapital)) {
+		return nil, nil
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	organizationID := b.OrgID
+	if organizationID == "" {
+		var err error
+		if organizationID, err = gw.approvals.OrganizationOf(ctx, userID); err != nil {
+			return nil, err
+		}
+		if organizationID == "" {
+			return nil, errNoApprover
+		}
+	}
+
+	capital := money.Format(b.Capital, money.QuoteCurrency(b.Symbol))
+	summary := fmt.Sprintf("Start live bot %q on %s %s with capital %s", b.Name, b.Exchange, b.Symbol, capital)
+	return gw.approvals.Submit(ctx, organizationID, userID, approval.ActionDeployLiveBot, summary, deployLiveBotPayload{
+		BotID:  b.ID,
+		UserID: b.UserID,
+	})
+}
+
+// deployLiveBot executes an approved live bot start.
+func (gw *Gateway) deployLiveBot(ctx context.Context, req *approval.Request) error {
+	var payload deployLiveBotPayload
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return err
+	}
+	b, err := gw.bots.Get(ctx, payload.BotID)
+	if err != nil {
+		return err
+	}
+	// The bot may have moved since; it starts wherever it is now
+	if err := gw.bots.Start(ctx, b.Owner(), b.ID, req.RequestedBy); err != nil {
+		return err
+	}
+	gw.publishBotStatus(payload.UserID, payload.BotID, bot.StatusRunning)
+	gw.recordBotStart(ctx, b, req.RequestedBy)
+	return nil
+}
+
+// ListApprovals lists the requests of the organization named by the
+// X-Org-ID header, which the caller must administer. Configured admins may
+// leave it out.
+// raisesRateLimits reports whether the limits let the bot place or cancel
+// more orders than it may now; zero stands for the platform default.
+func (gw *Gateway) raisesRateLimits(b *bot.Bot, ordersPerMinute, cancelsPerMinute int) bool {
+	defaults := gw.config.Trading.BotOrderRate
+	effective := func(limit, fallback int) int {
+		if limit == 0 {
+			return fallback
+		}
+		return limit
+	}
+	return effective(ordersPerMinute, defaults.OrdersPerMinute) > effective(b.MaxOrdersPerMinute, defaults.OrdersPerMinute) ||
+		effective(cancelsPerMinute, defaults.CancelsPerMinute) > effective(b.MaxCancelsPerMinute, defaults.CancelsPerMinute)
+}
+
+// raiseBotLimits executes approved rate limits of an organization's bot.
+func (gw *Gateway) raiseBotLimits(ctx context.Context, req *approval.Request) error {
+	var payload botLimitsPayload
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return err
+	}
+	b, err := gw.bots.Get(ctx, payload.BotID)
+	if err != nil {
+		return err
+	}
+	// The approval only covers the bot while it is the organization's
+	if b.OrgID != req.OrganizationID {
+		return bot.ErrBotNotFound
+	}
+	return gw.bots.SetRateLimits(ctx, b.Owner(), b.ID, payload.MaxOrdersPerMinute, payload.MaxCancelsPerMinute)
+}
+
+func (gw *Gateway) ListApprovals(c *gin.Context) {
+	reqs, err := gw.approvals.List(c.Request.Context(), c.GetHeader(middleware.OrgHeader), c.GetString("user_id"),
+		c.DefaultQuery("status", approval.StatusPending))
+	if err != nil {
+		gw.approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": reqs})
+}
+
+func (gw *Gateway) ApproveRequest(c *gin.Context) {
+	req, err := gw.approvals.Approve(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		gw.approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+func (gw *Gateway) RejectRequest(c *gin.Context) {
+	// The body with a reason is optional
+	var body rejectRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req, err := gw.approvals.Reject(c.Request.Context(), c.Param("id"), c.GetString("user_id"), body.Reason)
+	if err != nil {
+		gw.approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+func (gw *Gateway) approvalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, approval.ErrRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, approval.ErrNotAdmin), errors.Is(err, approval.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, approval.ErrNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,102 @@
+// internal/gateway/audit.go
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/auth"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListSecurityEvents pages through the audit log of the caller's account,
+// newest first: sign-ins, password changes, revoked sessions and exchange
+// key changes.
+func (gw *Gateway) ListSecurityEvents(c *gin.Context) {
+	limit, ok := auditLimit(c)
+	if !ok {
+		return
+	}
+
+	resp, err := gw.authClientFor(c).ListSecurityEvents(c.Request.Context(), &authpb.ListSecurityEventsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Limit:       limit,
+		Cursor:      c.Query("cursor"),
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to list security events")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": resp.Events, "next_cursor": resp.NextCursor})
+}
+
+// ListAuditEvents searches the audit log of all accounts, or the trail of
+// the user in the path. The auth service checks the caller's permission
+// again.
+func (gw *Gateway) ListAuditEvents(c *gin.Context) {
+	limit, ok := auditLimit(c)
+	if !ok {
+		return
+	}
+
+	userID := c.Param("id")
+	if userID == "" {
+		userID = c.Query("user_id")
+	}
+	req := &authpb.ListAuditEventsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      userID,
+		Type:        c.Query("type"),
+		Limit:       limit,
+		Cursor:      c.Query("cursor"),
+	}
+	for param, bound := range map[string]**timestamppb.Timestamp{"from": &req.From, "to": &req.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+			return
+		}
+		*bound = timestamppb.New(t)
+	}
+
+	resp, err := gw.authClientFor(c).ListAuditEvents(c.Request.Context(), req)
+	if err != nil {
+		adminRPCError(c, err, "Failed to list audit events")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": resp.Events, "next_cursor": resp.NextCursor})
+}
+
+// auditLimit reads the page size, answering 400 if it is out of range.
+func auditLimit(c *gin.Context) (int32, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > auth.MaxAuditPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(auth.MaxAuditPageSize)})
+		return 0, false
+	}
+	return int32(limit), true
+}
+
+// audit records a security event of the caller's account that the gateway
+// handled itself.
+func (gw *Gateway) audit(c *gin.Context, eventType string, details map[string]string) {
+	userID := c.GetString("user_id")
+	gw.auditor.Record(c.Request.Context(), &auth.AuditEvent{
+		UserID:    userID,
+		ActorID:   userID,
+		Type:      eventType,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	})
+}
@@ -0,0 +1,145 @@
+// internal/gateway/avatar.go
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/avatar"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// multipartOverhead allows for the boundaries and part headers around the
+// uploaded file
+const multipartOverhead = 64 << 10
+
+// GetAvatar signs a URL to the caller's avatar.
+func (gw *Gateway) GetAvatar(c *gin.Context) {
+	var key string
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*authpb.User); ok {
+			key = user.Avatar
+		}
+	}
+
+	url, expiresAt, err := gw.avatars.URL(c.Request.Context(), c.GetString("user_id"), key)
+	if err != nil {
+		avatarError(c, err, "Failed to sign avatar URL")
+		return
+	}
+
+	c.JSON(http.StatusOK, openapi.Avatar{Avatar: key, URL: url, ExpiresAt: expiresAt})
+}
+
+// UploadAvatar replaces the caller's avatar with the image in the
+// multipart field "avatar". The previous avatar is deleted once the new one
+// is set.
+func (gw *Gateway) UploadAvatar(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, gw.config.Avatars.MaxBytes+multipartOverhead)
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			avatarError(c, avatar.ErrFileTooLarge, "")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multipart field avatar with the image required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	key, err := gw.avatars.Upload(ctx, userID, file)
+	if err != nil {
+		avatarError(c, err, "Failed to store avatar")
+		return
+	}
+
+	resp, err := gw.authClientFor(c).SetAvatar(ctx, &authpb.SetAvatarRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Avatar:      key,
+	})
+	if err != nil {
+		gw.deleteAvatar(ctx, userID, key)
+		avatarRPCError(c, err, "Failed to update avatar")
+		return
+	}
+	gw.deleteAvatar(ctx, userID, resp.PreviousAvatar)
+
+	url, expiresAt, err := gw.avatars.URL(ctx, userID, key)
+	if err != nil {
+		avatarError(c, err, "Failed to sign avatar URL")
+		return
+	}
+
+	c.JSON(http.StatusOK, openapi.Avatar{Avatar: key, URL: url, ExpiresAt: expiresAt})
+}
+
+// DeleteAvatar clears the caller's avatar and deletes its image.
+func (gw *Gateway) DeleteAvatar(c *gin.Context) {
+	resp, err := gw.authClientFor(c).SetAvatar(c.Request.Context(), &authpb.SetAvatarRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+	})
+	if err != nil {
+		avatarRPCError(c, err, "Failed to remove avatar")
+		return
+	}
+	gw.deleteAvatar(c.Request.Context(), c.GetString("user_id"), resp.PreviousAvatar)
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteAvatar removes an avatar image no user points at any more. A
+// failure only leaves an orphaned object behind, so it is logged.
+func (gw *Gateway) deleteAvatar(ctx context.Context, userID, key string) {
+	if key == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := gw.avatars.Delete(ctx, userID, key); err != nil {
+		log.Printf("Failed to delete avatar %s: %v", key, err)
+	}
+}
+
+// avatarError maps the errors of the avatar service.
+func avatarError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, avatar.ErrNoAvatar):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, avatar.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, avatar.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, avatar.ErrImageTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// avatarRPCError maps the status codes of the SetAvatar RPC.
+func avatarRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,319 @@
+// internal/gateway/backtest.go
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	backtestpb "github.com/tradingbothub/platform/api/proto/backtest"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/hub"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type sweepRequest struct {
+	Config strategy.Config    `json:"config" binding:"required"`
+	Grid   backtest.Grid      `json:"grid"`
+	Fills  backtest.FillModel `json:"fills"`
+	// Seed makes the sweep reproducible; a random one is picked and
+	// returned when it is omitted
+	Seed *int64 `json:"seed"`
+	// Hours of recorded data to replay, 24 by default
+	Hours int `json:"hours"`
+}
+
+type backtestRequest struct {
+	Config strategy.Config    `json:"config" binding:"required"`
+	Fills  backtest.FillModel `json:"fills"`
+	// Seed makes the run reproducible; a random one is picked and
+	// returned when it is omitted
+	Seed *int64 `json:"seed"`
+	// Hours of recorded data to replay, 24 by default
+	Hours int `json:"hours"`
+}
+
+// backtestJSON encodes backtest events with the field names of the proto.
+var backtestJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// SweepBot queues a backtest of every combination of the parameter grid
+// over the bot's market and answers with the job, whose results
+// GetSweepJob returns once it ran. The same request and seed always give
+// the same results in the same order.
+func (gw *Gateway) SweepBot(c *gin.Context) {
+	var req sweepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Fills.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Hours == 0 {
+		req.Hours = 24
+	}
+	if req.Hours < 1 || req.Hours > maxPreviewHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and 168"})
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+
+	runs, err := backtest.Plan(req.Config, req.Grid, seed, gw.config.Backtest.MaxRuns)
+	if errors.Is(err, backtest.ErrEmptyGrid) || errors.Is(err, backtest.ErrGridTooLarge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	interval, err := marketdata.ParseInterval(req.Config.Interval)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	warmup := 0
+	for _, run := range runs {
+		if n := run.Config.Warmup(); n > warmup {
+			warmup = n
+		}
+	}
+	to := time.Now().UTC()
+	from := to.Add(-time.Duration(req.Hours) * time.Hour)
+
+	// The backtest service loads the candles when the sweep's turn comes
+	job, err := gw.sweeps.Submit(ctx, backtest.SweepJob{
+		UserID:      c.GetString("user_id"),
+		BotID:       b.ID,
+		Plan:        userPlan(c),
+		Seed:        seed,
+		Exchange:    b.Exchange,
+		Symbol:      b.Symbol,
+		Interval:    req.Config.Interval,
+		CandlesFrom: marketdata.BucketsBefore(from, interval, time.UTC, warmup),
+		From:        from,
+		To:          to,
+		Runs:        runs,
+		Fills:       req.Fills,
+	})
+	if errors.Is(err, backtest.ErrQueueFull) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue sweep"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListSweepJobs lists the caller's queued, running and recently finished
+// sweeps without their results.
+func (gw *Gateway) ListSweepJobs(c *gin.Context) {
+	jobs, err := gw.sweeps.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sweeps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// GetSweepJob returns a sweep with its position in the queue while it
+// waits, and its results once it succeeded.
+func (gw *Gateway) GetSweepJob(c *gin.Context) {
+	job, err := gw.sweeps.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		sweepJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelSweepJob takes a sweep out of the queue, or stops it after the
+// runs in progress.
+func (gw *Gateway) CancelSweepJob(c *gin.Context) {
+	job, err := gw.sweeps.Cancel(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		sweepJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// userPlan returns the subscription plan of the user, which schedules
+// their sweeps.
+func userPlan(c *gin.Context) string {
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*authpb.User); ok {
+			return user.Plan
+		}
+	}
+	return ""
+}
+
+func sweepJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, backtest.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, backtest.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sweep"})
+	}
+}
+
+// BacktestBot runs one backtest over the bot's market on the backtest
+// service and relays it as server-sent events while it is simulated:
+// progress, equity (chunks of the equity curve) and trade events, then a
+// final result or error event.
+func (gw *Gateway) BacktestBot(c *gin.Context) {
+	var req backtestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Fills.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Hours == 0 {
+		req.Hours = 24
+	}
+	if req.Hours < 1 || req.Hours > maxPreviewHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and 168"})
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+
+	// Runs are short, so a drain waits for them instead of cutting them off
+	_, done, ok := gw.Drainer.TrackStream()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is draining", "reconnect": gw.Drainer.Hint()})
+		return
+	}
+	defer done()
+
+	to := time.Now().UTC()
+	from := to.Add(-time.Duration(req.Hours) * time.Hour)
+	stream, err := gw.backtestClientFor(c).RunBacktest(ctx, &backtestpb.RunBacktestRequest{
+		Exchange:    b.Exchange,
+		Symbol:      b.Symbol,
+		Config:      backtest.ConfigToProto(req.Config),
+		From:        timestamppb.New(from),
+		To:          timestamppb.New(to),
+		SlippageBps: req.Fills.SlippageBps,
+		Seed:        seed,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start backtest"})
+		return
+	}
+
+	// Rejected requests fail on the first receive, while a status code can
+	// still be sent
+	event, err := stream.Recv()
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to run backtest"})
+		return
+	}
+
+	sender := &sseSender{writer: c.Writer, controller: http.NewResponseController(c.Writer), buf: make([]byte, 0, 512)}
+	// Long runs outlive the server's write timeout; sends set their own
+	sender.controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("X-Backtest-Seed", fmt.Sprint(seed))
+	c.Status(http.StatusOK)
+
+	for {
+		msg, err := backtestEventMessage(event)
+		if err != nil {
+			gw.sendControl(sender, "error", gin.H{"error": err.Error()})
+			return
+		}
+		if err := gw.sendBacktestEvent(ctx, sender, msg); err != nil {
+			// The client is gone; returning cancels the run
+			return
+		}
+
+		event, err = stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			gw.sendControl(sender, "error", gin.H{"error": status.Convert(err).Message()})
+			return
+		}
+	}
+}
+
+func (gw *Gateway) sendBacktestEvent(ctx context.Context, sender *sseSender, msg hub.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, gw.config.Streaming.WriteTimeout)
+	defer cancel()
+	return sender.Send(ctx, msg)
+}
+
+// backtestEventMessage turns a backtest event into a server-sent event
+// named after its kind.
+func backtestEventMessage(event *backtestpb.BacktestEvent) (hub.Message, error) {
+	var kind string
+	var payload proto.Message
+	switch e := event.Event.(type) {
+	case *backtestpb.BacktestEvent_Progress:
+		kind, payload = "progress", e.Progress
+	case *backtestpb.BacktestEvent_Equity:
+		kind, payload = "equity", e.Equity
+	case *backtestpb.BacktestEvent_Trade:
+		kind, payload = "trade", e.Trade
+	case *backtestpb.BacktestEvent_Result:
+		kind, payload = "result", e.Result
+	default:
+		return hub.Message{}, fmt.Errorf("unknown backtest event %T", e)
+	}
+
+	data, err := backtestJSON.Marshal(payload)
+	if err != nil {
+		return hub.Message{}, err
+	}
+	return hub.Message{Type: kind, Data: data}, nil
+}
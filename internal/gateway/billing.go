@@ -0,0 +1,95 @@
+// internal/gateway/billing.go
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/billing"
+	"github.com/tradingbothub/platform/internal/openapi"
+)
+
+type invoicePaymentRequest struct {
+	Status string `json:"status" binding:"required,oneof=succeeded failed"`
+	// Reason says why a failed payment failed, e.g. "card_declined"
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// ListInvoices lists the caller's invoices, newest first.
+func (gw *Gateway) ListInvoices(c *gin.Context) {
+	var params openapi.ListInvoicesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = 24
+	}
+
+	invoices, err := gw.invoices.List(c.Request.Context(), c.GetString("user_id"), params.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invoices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invoices": invoices})
+}
+
+// GetInvoice returns one of the caller's invoices with its line items.
+func (gw *Gateway) GetInvoice(c *gin.Context) {
+	invoice, err := gw.invoices.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		invoiceError(c, err, "Failed to load invoice")
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// DownloadInvoice renders one of the caller's invoices as a PDF receipt.
+func (gw *Gateway) DownloadInvoice(c *gin.Context) {
+	invoice, err := gw.invoices.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		invoiceError(c, err, "Failed to load invoice")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", billing.RenderPDF(invoice, userEmail(c)))
+}
+
+// RecordInvoicePayment takes the outcome of charging an invoice from the
+// payment provider. A failure starts dunning the invoice; a success after
+// the user was downgraded for it restores their plan.
+func (gw *Gateway) RecordInvoicePayment(c *gin.Context) {
+	var req invoicePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice, err := gw.invoices.RecordPayment(c.Request.Context(), c.Param("id"), billing.Payment{
+		Succeeded: req.Status == "succeeded",
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		invoiceError(c, err, "Failed to record payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// invoiceError maps the errors of the billing service.
+func invoiceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, billing.ErrInvoiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, billing.ErrInvoicePaid):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
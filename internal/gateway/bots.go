@@ -0,0 +1,248 @@
+// internal/gateway/bots.go
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/approval"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+const (
+	maxPreviewHours = 7 * 24
+
+	defaultSignalsLimit = 50
+	maxSignalsLimit     = 500
+)
+
+type previewRequest struct {
+	Config strategy.Config `json:"config" binding:"required"`
+	// Hours of recorded data to replay, 24 by default
+	Hours int `json:"hours"`
+}
+
+type createBotRequest struct {
+	Name     string          `json:"name" binding:"required,max=100"`
+	Exchange string          `json:"exchange" binding:"required"`
+	Symbol   string          `json:"symbol" binding:"required"`
+	Config   strategy.Config `json:"config" binding:"required"`
+	// Capital is the quote amount allocated to the bot
+	Capital decimal.Decimal `json:"capital"`
+}
+
+type rateLimitsRequest struct {
+	// Zero falls back to the platform default
+	MaxOrdersPerMinute  int `json:"max_orders_per_minute" binding:"min=0,max=1200"`
+	MaxCancelsPerMinute int `json:"max_cancels_per_minute" binding:"min=0,max=1200"`
+}
+
+// CreateBot creates a stopped bot in the current workspace. It trades on
+// the exchange's paper environment until an exchange key is set.
+func (gw *Gateway) CreateBot(c *gin.Context) {
+	var req createBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slices.Contains(gw.oms.Names(), req.Exchange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown exchange"})
+		return
+	}
+	if !req.Capital.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "capital must be positive"})
+		return
+	}
+
+	b := &bot.Bot{
+		ID:       uuid.New().String(),
+		UserID:   c.GetString("user_id"),
+		OrgID:    workspace(c).OrgID,
+		Name:     req.Name,
+		Exchange: req.Exchange,
+		Symbol:   strings.ToUpper(req.Symbol),
+		Strategy: req.Config.Type,
+		Config:   req.Config,
+		Status:   bot.StatusStopped,
+		Capital:  req.Capital,
+	}
+	if err := gw.bots.Create(c.Request.Context(), b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bot"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// PreviewBot replays recent market data through a proposed config and the
+// bot's current one and returns both sets of orders and their difference.
+func (gw *Gateway) PreviewBot(c *gin.Context) {
+	var req previewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Hours == 0 {
+		req.Hours = 24
+	}
+	if req.Hours < 1 || req.Hours > maxPreviewHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and 168"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-time.Duration(req.Hours) * time.Hour)
+	load := func(interval marketdata.Interval, warmup int) ([]marketdata.Candle, error) {
+		start := marketdata.BucketsBefore(from, interval, time.UTC, warmup)
+		return gw.loadCandles(ctx, b.Exchange, b.Symbol, interval, time.UTC, start, to)
+	}
+
+	result, err := bot.Preview(b.Config, req.Config, load, from, to)
+	if errors.Is(err, strategy.ErrInvalidConfig) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to run preview"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// publishBotStatus notifies bot runners and other consumers of a status
+// change. Failing to publish does not undo the change.
+func (gw *Gateway) publishBotStatus(userID, botID, status string) {
+	err := events.Publish(gw.nats, "api-gateway", &eventspb.BotStatusChanged{
+		BotId:  botID,
+		UserId: userID,
+		Status: status,
+	})
+	if err != nil {
+		log.Printf("Failed to publish status of bot %s: %v", botID, err)
+	}
+}
+
+// SetBotRateLimits sets the bot's order rate policy. A running bot picks
+// the new limits up the next time it is started. Raising the limits of an
+// organization's bot takes the approval of a second admin.
+func (gw *Gateway) SetBotRateLimits(c *gin.Context) {
+	var req rateLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+	if b.OrgID != "" && gw.raisesRateLimits(b, req.MaxOrdersPerMinute, req.MaxCancelsPerMinute) {
+		summary := fmt.Sprintf("Raise the rate limits of bot %q to %d orders and %d cancels per minute (0 is the default)",
+			b.Name, req.MaxOrdersPerMinute, req.MaxCancelsPerMinute)
+		approvalReq, err := gw.approvals.Submit(ctx, b.OrgID, c.GetString("user_id"), approval.ActionRaiseBotLimits, summary, botLimitsPayload{
+			BotID:               b.ID,
+			MaxOrdersPerMinute:  req.MaxOrdersPerMinute,
+			MaxCancelsPerMinute: req.MaxCancelsPerMinute,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request approval"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "Raising the rate limits of this bot requires approval by another admin",
+			"approval": approvalReq,
+		})
+		return
+	}
+
+	err = gw.bots.SetRateLimits(ctx, workspace(c), b.ID, req.MaxOrdersPerMinute, req.MaxCancelsPerMinute)
+	if errors.Is(err, bot.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                     c.Param("id"),
+		"max_orders_per_minute":  req.MaxOrdersPerMinute,
+		"max_cancels_per_minute": req.MaxCancelsPerMinute,
+	})
+}
+
+// ListBotSignals returns the bot's latest signals with what the runtime
+// did about each.
+func (gw *Gateway) ListBotSignals(c *gin.Context) {
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSignalsLimit)))
+	if err != nil || limit < 1 || limit > maxSignalsLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	signals, err := gw.signals.List(ctx, b.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list signals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"signals": signals})
+}
+
+// GetSignalTrace explains a signal: the indicator values at its candle and
+// how each rule of the strategy evaluated.
+func (gw *Gateway) GetSignalTrace(c *gin.Context) {
+	ctx := c.Request.Context()
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !workspace(c).Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+
+	signal, err := gw.signals.Get(ctx, b.ID, c.Param("signal_id"))
+	if errors.Is(err, bot.ErrSignalNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, signal)
+}
@@ -0,0 +1,54 @@
+// internal/gateway/cookies.go
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/cache"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/middleware"
+)
+
+// newCookieSessions sets up cookie sessions, or returns nil when they are
+// disabled.
+func newCookieSessions(cfg config.CookieSessionsConfig, redisClient redis.UniversalClient, authClient authpb.AuthServiceClient) (*middleware.CookieSessions, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookie session key: %w", err)
+	}
+	return middleware.NewCookieSessions(cache.NewWebSessionStore(redisClient, cfg.TTL), authClient, middleware.CookieSessionOptions{
+		Clients: cfg.Clients,
+		Key:     key,
+		Name:    cfg.CookieName,
+		Domain:  cfg.Domain,
+		Secure:  cfg.Secure,
+		TTL:     cfg.TTL,
+	})
+}
+
+// signedIn answers a successful sign-in. Client types configured for
+// cookie sessions get the session cookie and its CSRF token instead of the
+// tokens; everyone else gets the tokens.
+func (gw *Gateway) signedIn(c *gin.Context, code int, resp *authpb.AuthResponse) {
+	if gw.Cookies == nil || !gw.Cookies.Wants(c) {
+		c.JSON(code, resp)
+		return
+	}
+
+	csrf, err := gw.Cookies.Start(c, resp)
+	if err != nil {
+		log.Printf("Failed to start web session of user %s: %v", resp.User.GetId(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+	c.JSON(code, gin.H{"user": resp.User, "csrf_token": csrf})
+}
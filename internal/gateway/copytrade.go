@@ -0,0 +1,166 @@
+// internal/gateway/copytrade.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/copytrade"
+)
+
+const maxAllocationChanges = 500
+
+type allocationRequest struct {
+	Multiplier    decimal.Decimal `json:"multiplier" binding:"required"`
+	Dynamic       bool            `json:"dynamic"`
+	MinMultiplier decimal.Decimal `json:"min_multiplier"`
+	MaxMultiplier decimal.Decimal `json:"max_multiplier"`
+}
+
+type createFollowRequest struct {
+	LeaderID string `json:"leader_id" binding:"required"`
+	allocationRequest
+}
+
+func (gw *Gateway) ListFollows(c *gin.Context) {
+	follows, err := gw.follows.ListByFollower(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list follows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"follows": follows})
+}
+
+func (gw *Gateway) CreateFollow(c *gin.Context) {
+	var req createFollowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if req.LeaderID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+	var leaders int64
+	err := gw.db.WithContext(c.Request.Context()).Model(&auth.User{}).
+		Where("id = ? AND is_active = ?", req.LeaderID, true).Count(&leaders).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up leader"})
+		return
+	}
+	if leaders == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Leader not found"})
+		return
+	}
+
+	follow := &copytrade.Follow{
+		ID:         uuid.New().String(),
+		FollowerID: userID,
+		LeaderID:   req.LeaderID,
+		FeeRate:    decimal.NewFromFloat(gw.config.CopyTrading.PerformanceFee),
+	}
+	if !gw.applyAllocation(c, follow, req.allocationRequest) {
+		return
+	}
+	if err := gw.follows.Create(c.Request.Context(), follow); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow leader"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, follow)
+}
+
+// UpdateFollowAllocation changes the multiplier and dynamic allocation
+// bounds. The multiplier restarts from the new base.
+func (gw *Gateway) UpdateFollowAllocation(c *gin.Context) {
+	var req allocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	follow, err := gw.follows.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		gw.followError(c, err)
+		return
+	}
+	if !gw.applyAllocation(c, follow, req) {
+		return
+	}
+	if err := gw.follows.Update(c.Request.Context(), follow); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update allocation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, follow)
+}
+
+// ListAllocationChanges returns the log of dynamic multiplier changes,
+// newest first.
+func (gw *Gateway) ListAllocationChanges(c *gin.Context) {
+	follow, err := gw.follows.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		gw.followError(c, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > maxAllocationChanges {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	changes, err := gw.follows.ListChanges(c.Request.Context(), follow.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list allocation changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+func (gw *Gateway) DeleteFollow(c *gin.Context) {
+	if err := gw.follows.Delete(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		gw.followError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unfollowed leader"})
+}
+
+// applyAllocation validates req into follow, writing the error response
+// when it is invalid.
+func (gw *Gateway) applyAllocation(c *gin.Context, follow *copytrade.Follow, req allocationRequest) bool {
+	follow.BaseMultiplier = req.Multiplier
+	follow.Multiplier = req.Multiplier
+	follow.Dynamic = req.Dynamic
+	follow.MinMultiplier = req.MinMultiplier
+	follow.MaxMultiplier = req.MaxMultiplier
+	if !follow.Dynamic {
+		follow.MinMultiplier = req.Multiplier
+		follow.MaxMultiplier = req.Multiplier
+	}
+
+	err := copytrade.ValidateBounds(follow, decimal.NewFromFloat(gw.config.CopyTrading.MaxMultiplier))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (gw *Gateway) followError(c *gin.Context, err error) {
+	if errors.Is(err, copytrade.ErrFollowNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,102 @@
+// internal/gateway/demo.go
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/demo"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateDemoSession logs the caller into the shared demo account,
+// provisioning it on first use, and returns tokens the API docs can use
+// for "Try it out". The account trades on paper wallets that are refilled
+// after every nightly reset.
+func (gw *Gateway) CreateDemoSession(c *gin.Context) {
+	cfg := gw.config.Demo
+	if !cfg.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Demo mode is not enabled"})
+		return
+	}
+	if gw.config.Trading.Mode != "paper" {
+		// Never hand out a shared account that can trade real funds
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Demo mode requires paper trading"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp, err := gw.AuthClient.Login(ctx, &authpb.LoginRequest{Email: cfg.Email, Password: cfg.Password})
+	if status.Code(err) == codes.Unauthenticated {
+		resp, err = gw.AuthClient.Register(ctx, &authpb.RegisterRequest{
+			Email:     cfg.Email,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			FirstName: "Demo",
+			LastName:  "User",
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Demo account unavailable"})
+		return
+	}
+
+	if err := gw.fundDemoAccount(ctx, resp.User.Id); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Demo account unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": resp.AccessToken,
+		"expires_in":   resp.ExpiresIn,
+		"user":         resp.User,
+		"rate_limit": gin.H{
+			"requests": cfg.Requests,
+			"window":   cfg.Window.String(),
+		},
+		"reset_schedule": cfg.ResetSchedule,
+	})
+}
+
+// fundDemoAccount resets the demo user's paper wallets to the starting
+// balance once per reset generation.
+func (gw *Gateway) fundDemoAccount(ctx context.Context, userID string) error {
+	generation, err := demo.Generation(ctx, gw.redis)
+	if err != nil {
+		return err
+	}
+
+	gw.demoMutex.Lock()
+	defer gw.demoMutex.Unlock()
+
+	if gw.demoFunded && gw.demoGeneration == generation {
+		return nil
+	}
+
+	balance := money.FromFloat(gw.config.Demo.Balance)
+	for _, name := range gw.clients.Names() {
+		client, err := gw.clients.Get(name)
+		if err != nil {
+			return err
+		}
+		paper, ok := exchange.Unwrap(client).(exchange.PaperAccounts)
+		if !ok {
+			continue
+		}
+		if err := paper.Reset(ctx, userID); err != nil {
+			return err
+		}
+		if err := paper.Deposit(ctx, userID, balance); err != nil {
+			return err
+		}
+	}
+
+	gw.demoGeneration = generation
+	gw.demoFunded = true
+	return nil
+}
@@ -0,0 +1,99 @@
+// internal/gateway/email_change.go
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestEmailChange mails a confirmation link to the new address and an
+// undo link to the caller's current one.
+func (gw *Gateway) RequestEmailChange(c *gin.Context) {
+	var req openapi.RequestEmailChangeJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).RequestEmailChange(c.Request.Context(), &authpb.RequestEmailChangeRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		NewEmail:    string(req.NewEmail),
+		Password:    req.Password,
+		ClientIp:    c.ClientIP(),
+	})
+	if err != nil {
+		emailChangeRPCError(c, err, "Failed to request email change")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": resp.Message})
+}
+
+// ConfirmEmailChange moves the account to the new address with the token
+// of the confirmation email.
+func (gw *Gateway) ConfirmEmailChange(c *gin.Context) {
+	var req openapi.ConfirmEmailChangeJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.ConfirmEmailChange(c.Request.Context(), &authpb.ConfirmEmailChangeRequest{Token: req.Token})
+	if err != nil {
+		emailChangeRPCError(c, err, "Failed to change email")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+// UndoEmailChange cancels or reverts a change with the token of the notice
+// sent to the old address.
+func (gw *Gateway) UndoEmailChange(c *gin.Context) {
+	var req openapi.UndoEmailChangeJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.UndoEmailChange(c.Request.Context(), &authpb.UndoEmailChangeRequest{Token: req.Token})
+	if err != nil {
+		emailChangeRPCError(c, err, "Failed to undo email change")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+// emailChangeRPCError maps the status codes of the email change RPCs.
+func emailChangeRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	case codes.ResourceExhausted:
+		// Locked out after too many wrong passwords
+		for _, detail := range status.Convert(err).Details() {
+			if retry, ok := detail.(*errdetails.RetryInfo); ok {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.RetryDelay.AsDuration().Seconds()))))
+			}
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": status.Convert(err).Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,60 @@
+// internal/gateway/introspect.go
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+// introspectRequest is an RFC 7662 request; it is usually form encoded,
+// but JSON works too.
+type introspectRequest struct {
+	Token         string `form:"token" json:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint" binding:"omitempty,oneof=access_token refresh_token"`
+}
+
+// IntrospectToken tells internal services whether an access or refresh
+// token is active, whom it was issued to and which scopes it grants, in
+// the RFC 7662 response format.
+func (gw *Gateway) IntrospectToken(c *gin.Context) {
+	var req introspectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.AuthClient.IntrospectToken(c.Request.Context(), &authpb.IntrospectTokenRequest{
+		Token:         req.Token,
+		TokenTypeHint: req.TokenTypeHint,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to introspect token"})
+		return
+	}
+
+	// Responses are about a credential and must not be cached
+	c.Header("Cache-Control", "no-store")
+	if !resp.Active {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+	body := gin.H{
+		"active":      true,
+		"token_type":  resp.TokenType,
+		"sub":         resp.Subject,
+		"scope":       strings.Join(resp.Scopes, " "),
+		"permissions": resp.Permissions,
+		"exp":         resp.ExpiresAt.AsTime().Unix(),
+		"jti":         resp.TokenId,
+	}
+	if resp.IssuedAt != nil {
+		body["iat"] = resp.IssuedAt.AsTime().Unix()
+	}
+	if resp.SessionId != "" {
+		body["sid"] = resp.SessionId
+	}
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,57 @@
+// internal/gateway/jwks.go
+package gateway
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+// syncKeys fetches the auth service's public keys now and every interval
+// after, and returns a function that stops it. Until the first fetch
+// succeeds, every token is left to the auth service.
+func (gw *Gateway) syncKeys(interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := gw.refreshKeys(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh token signing keys: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (gw *Gateway) refreshKeys(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := gw.AuthClient.GetJWKS(ctx, &authpb.GetJWKSRequest{})
+	if err != nil {
+		return err
+	}
+	return gw.Keys.Update(resp.Jwks)
+}
+
+// GetJWKS publishes the public keys that verify access tokens, so third
+// parties can check tokens without sharing a secret.
+func (gw *Gateway) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/json", gw.Keys.Document())
+}
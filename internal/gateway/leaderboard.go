@@ -0,0 +1,120 @@
+// internal/gateway/leaderboard.go
+package gateway
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/copytrade"
+	"github.com/tradingbothub/platform/internal/equity"
+)
+
+const maxLeaderboardEntries = 100
+
+// GetLeaderboard ranks followed leaders by return over the leaderboard
+// window. Leaders flagged for wash trading or churn are left out until
+// the flag is dismissed.
+func (gw *Gateway) GetLeaderboard(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > maxLeaderboardEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	counts, err := gw.follows.FollowerCounts(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list leaders"})
+		return
+	}
+	excluded, err := gw.flags.Excluded(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list flagged leaders"})
+		return
+	}
+
+	now := time.Now().UTC()
+	from := now.Add(-gw.config.CopyTrading.LeaderboardWindow)
+	entries := make([]copytrade.LeaderboardEntry, 0, len(counts))
+	for leaderID, followers := range counts {
+		if excluded[leaderID] {
+			continue
+		}
+		series, err := gw.equity.Series(ctx, leaderID, "", from, now)
+		if err != nil {
+			log.Printf("Leaving leader %s off the leaderboard: %v", leaderID, err)
+			continue
+		}
+		perf := equity.Summarize(series)
+		entries = append(entries, copytrade.LeaderboardEntry{
+			LeaderID:  leaderID,
+			Followers: followers,
+			Return:    perf.Return,
+			Drawdown:  perf.MaxDrawdown.Max,
+		})
+	}
+
+	copytrade.RankLeaders(entries)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": now, "leaders": entries})
+}
+
+// ListLeaderFlags is the review queue; it defaults to open flags.
+func (gw *Gateway) ListLeaderFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", copytrade.FlagOpen)
+	switch status {
+	case copytrade.FlagOpen, copytrade.FlagConfirmed, copytrade.FlagDismissed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, confirmed or dismissed"})
+		return
+	}
+
+	flags, err := gw.flags.List(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": flags})
+}
+
+// ConfirmLeaderFlag keeps the leader off the leaderboard for good.
+func (gw *Gateway) ConfirmLeaderFlag(c *gin.Context) {
+	gw.reviewLeaderFlag(c, copytrade.FlagConfirmed)
+}
+
+// DismissLeaderFlag clears a false positive, returning the leader to the
+// leaderboard unless other flags remain.
+func (gw *Gateway) DismissLeaderFlag(c *gin.Context) {
+	gw.reviewLeaderFlag(c, copytrade.FlagDismissed)
+}
+
+// reviewLeaderFlag records the signed-in staff member as the reviewer; they
+// cannot decide flags raised on their own account.
+func (gw *Gateway) reviewLeaderFlag(c *gin.Context, status string) {
+	flag, err := gw.flags.Review(c.Request.Context(), c.Param("id"), status, c.GetString("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, copytrade.ErrFlagNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, copytrade.ErrNoReviewer):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, copytrade.ErrSelfReview):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, copytrade.ErrFlagReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
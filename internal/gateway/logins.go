@@ -0,0 +1,54 @@
+// internal/gateway/logins.go
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListLogins lists the caller's latest sign-ins, newest first, flagging
+// those from a country or device new to the account.
+func (gw *Gateway) ListLogins(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > auth.MaxLoginPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(auth.MaxLoginPageSize)})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).ListLogins(c.Request.Context(), &authpb.ListLoginsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list logins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logins": resp.Logins})
+}
+
+// clientCountry returns the ISO country code the proxy located the client
+// in, or "" when no country header is configured or it holds no country.
+// Cloudflare sends XX for unknown locations and T1 for Tor.
+func (gw *Gateway) clientCountry(c *gin.Context) string {
+	header := gw.config.Server.Proxies.CountryHeader
+	if header == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(c.GetHeader(header)))
+	if len(country) != 2 || country == "XX" || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return ""
+	}
+	return country
+}
@@ -0,0 +1,208 @@
+// internal/gateway/market.go
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/orders"
+)
+
+const (
+	defaultChartWidth = 800
+	maxChartWidth     = 4000
+	// maxChartBars bounds how much raw data one chart request may read
+	maxChartBars = 20000
+	// maxCandleScanPoints bounds how many stored candles one candles page
+	// may make InfluxDB aggregate
+	maxCandleScanPoints = 200000
+)
+
+// sessionLocation resolves the time zone for daily/weekly buckets from the
+// "session" query parameter (utc, exchange or user). For user sessions the
+// "tz" parameter overrides the time zone stored on the profile.
+func (gw *Gateway) sessionLocation(c *gin.Context, exchangeName string) (*time.Location, error) {
+	userTZ := c.Query("tz")
+	if userTZ == "" {
+		if user, ok := c.Get("user"); ok {
+			if u, ok := user.(*authpb.User); ok {
+				userTZ = u.Timezone
+			}
+		}
+	}
+
+	return marketdata.ResolveLocation(
+		marketdata.Session(c.DefaultQuery("session", string(marketdata.SessionUTC))),
+		gw.config.Exchanges[exchangeName].Timezone,
+		userTZ,
+	)
+}
+
+// loadCandles reads candles for [from, to) from the candle cache.
+func (gw *Gateway) loadCandles(ctx context.Context, exchangeName, symbol string, interval marketdata.Interval, loc *time.Location, from, to time.Time) ([]marketdata.Candle, error) {
+	return marketdata.LoadCandles(ctx, gw.candles, exchangeName, symbol, interval, loc, from, to)
+}
+
+// wantsBinaryCandles reports whether the client prefers the binary candle
+// encoding over JSON according to its Accept header.
+func wantsBinaryCandles(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, marketdata.CandlesMIME) == marketdata.CandlesMIME
+}
+
+// GetChart returns everything a chart needs in one round trip: OHLCV
+// downsampled to the requested pixel width, indicator overlays and the
+// user's own trades as markers. All series are columnar.
+func (gw *Gateway) GetChart(c *gin.Context) {
+	symbol := c.Param("symbol")
+	exchangeName := c.DefaultQuery("exchange", "binance")
+
+	interval, err := marketdata.ParseInterval(c.DefaultQuery("interval", "1h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	width, err := strconv.Atoi(c.DefaultQuery("width", strconv.Itoa(defaultChartWidth)))
+	if err != nil || width < 10 || width > maxChartWidth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "width must be between 10 and 4000"})
+		return
+	}
+
+	indicators, err := marketdata.ParseIndicators(c.Query("indicators"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := gw.sessionLocation(c, exchangeName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+	}
+	from := marketdata.BucketsBefore(to, interval, loc, width-1)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+	}
+	// Divide rather than multiply: weekly intervals times maxChartBars
+	// overflow a Duration
+	if from.IsZero() || !from.Before(to) || to.Sub(from)/interval.Duration > maxChartBars {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+
+	// Read extra bars before the window so overlays start at its left edge
+	warmup := 0
+	for _, indicator := range indicators {
+		if n := indicator.Warmup(); n > warmup {
+			warmup = n
+		}
+	}
+
+	readFrom := marketdata.BucketsBefore(from, interval, loc, warmup)
+	if readFrom.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	candles, err := gw.loadCandles(ctx, exchangeName, symbol, interval, loc, readFrom, to)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load candles"})
+		return
+	}
+
+	overlays := make(map[string]marketdata.SeriesColumns, len(indicators))
+	for _, indicator := range indicators {
+		points := trimPoints(indicator.Compute(candles), from)
+		overlays[indicator.String()] = marketdata.NewSeriesColumns(marketdata.LTTB(points, width))
+	}
+
+	visible := candles
+	for len(visible) > 0 && visible[0].Time.Before(from) {
+		visible = visible[1:]
+	}
+
+	response := gin.H{
+		"symbol":   symbol,
+		"exchange": exchangeName,
+		"interval": interval.Name,
+		"timezone": loc.String(),
+		"candles":  marketdata.NewCandleColumns(marketdata.Downsample(visible, width)),
+		"overlays": overlays,
+	}
+
+	if c.DefaultQuery("trades", "true") == "true" {
+		repo, err := gw.readOrdersFor(c)
+		if err != nil {
+			gw.residencyError(c, err)
+			return
+		}
+		mainnet := false
+		page, err := repo.SearchTrades(ctx, orders.Query{
+			UserID:   c.GetString("user_id"),
+			Symbol:   symbol,
+			Exchange: exchangeName,
+			Testnet:  &mainnet,
+			From:     from,
+			To:       to,
+			Limit:    orders.MaxPageSize,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load trades"})
+			return
+		}
+		response["trades"] = newTradeMarkers(page.Trades)
+		response["trades_truncated"] = page.NextCursor != ""
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// tradeMarkers is the columnar form of the user's fills, oldest first.
+type tradeMarkers struct {
+	Time     []int64   `json:"t"`
+	Price    []float64 `json:"p"`
+	Quantity []float64 `json:"q"`
+	Side     []string  `json:"s"`
+}
+
+func newTradeMarkers(trades []orders.Trade) tradeMarkers {
+	markers := tradeMarkers{
+		Time:     make([]int64, len(trades)),
+		Price:    make([]float64, len(trades)),
+		Quantity: make([]float64, len(trades)),
+		Side:     make([]string, len(trades)),
+	}
+	// Search results are newest first
+	for i, trade := range trades {
+		j := len(trades) - 1 - i
+		markers.Time[j] = trade.ExecutedAt.UnixMilli()
+		markers.Price[j] = trade.Price.InexactFloat64()
+		markers.Quantity[j] = trade.Quantity.InexactFloat64()
+		markers.Side[j] = trade.Side
+	}
+	return markers
+}
+
+func trimPoints(points []marketdata.Point, from time.Time) []marketdata.Point {
+	for len(points) > 0 && points[0].Time.Before(from) {
+		points = points[1:]
+	}
+	return points
+}
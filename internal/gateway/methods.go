@@ -0,0 +1,126 @@
+// internal/gateway/methods.go
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListAuthMethods lists the caller's password, if set, and the identity
+// providers linked to their account.
+func (gw *Gateway) ListAuthMethods(c *gin.Context) {
+	resp, err := gw.authClientFor(c).ListAuthMethods(c.Request.Context(), &authpb.ListAuthMethodsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+	})
+	if err != nil {
+		authMethodRPCError(c, err, "Failed to list sign-in methods")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"methods": resp.Methods})
+}
+
+// SetPassword adds a password to an account that has none.
+func (gw *Gateway) SetPassword(c *gin.Context) {
+	var req openapi.SetPasswordJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).SetPassword(c.Request.Context(), &authpb.SetPasswordRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Password:    req.Password,
+	})
+	if err != nil {
+		authMethodRPCError(c, err, "Failed to set password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+// RemovePassword removes the caller's password unless it is their last
+// sign-in method.
+func (gw *Gateway) RemovePassword(c *gin.Context) {
+	gw.unlinkAuthMethod(c, auth.AuthMethodPassword, "")
+}
+
+// LinkSSO begins signing in through the identity provider of the email's
+// domain to link it to the caller, and answers with the URL to send them to.
+func (gw *Gateway) LinkSSO(c *gin.Context) {
+	var req openapi.LinkSSOJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	binding, ok := gw.bindSSO(c)
+	if !ok {
+		return
+	}
+	resp, err := gw.authClientFor(c).LinkSSO(c.Request.Context(), &authpb.LinkSSORequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Email:       string(req.Email),
+		Binding:     binding,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		case codes.PermissionDenied:
+			c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+			return
+		}
+		ssoRPCError(c, err, "Failed to start single sign-on")
+		return
+	}
+
+	c.JSON(http.StatusOK, openapi.SSORedirect{AuthorizationURL: resp.AuthorizationUrl})
+}
+
+// UnlinkSSO unlinks the caller's identity at the connection's provider
+// unless it is their last sign-in method.
+func (gw *Gateway) UnlinkSSO(c *gin.Context) {
+	gw.unlinkAuthMethod(c, auth.AuthMethodSSO, c.Param("connection_id"))
+}
+
+func (gw *Gateway) unlinkAuthMethod(c *gin.Context, method, connectionID string) {
+	resp, err := gw.authClientFor(c).UnlinkAuthMethod(c.Request.Context(), &authpb.UnlinkAuthMethodRequest{
+		AccessToken:  strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Type:         method,
+		ConnectionId: connectionID,
+	})
+	if err != nil {
+		authMethodRPCError(c, err, "Failed to remove sign-in method")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+// authMethodRPCError maps the errors of the sign-in method RPCs.
+func authMethodRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": status.Convert(err).Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,67 @@
+// internal/gateway/orderbooks.go
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/orderbook"
+)
+
+const (
+	defaultOrderBookDepth = 20
+	maxOrderBookDepth     = 500
+)
+
+// startOrderBooks keeps the in-memory order books current with the
+// exchange feeds published on NATS.
+func (gw *Gateway) startOrderBooks() error {
+	sub, err := events.Subscribe(gw.nats, func() *eventspb.OrderBookUpdated { return &eventspb.OrderBookUpdated{} },
+		func(envelope *eventspb.Envelope, event *eventspb.OrderBookUpdated) {
+			gw.applyOrderBook(event)
+		})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to order book events: %w", err)
+	}
+	gw.streamSubs = append(gw.streamSubs, sub)
+	return nil
+}
+
+func (gw *Gateway) applyOrderBook(event *eventspb.OrderBookUpdated) {
+	if event.Snapshot {
+		gw.orderBooks.Replace(&orderbook.Snapshot{
+			Exchange: event.Exchange,
+			Symbol:   event.Symbol,
+			Sequence: event.Sequence,
+			Time:     event.Time.AsTime(),
+			Bids:     orderBookLevels(event.Bids),
+			Asks:     orderBookLevels(event.Asks),
+		})
+		return
+	}
+
+	err := gw.orderBooks.Apply(event.Exchange, event.Symbol, orderbook.Delta{
+		Sequence:     event.Sequence,
+		PrevSequence: event.PrevSequence,
+		Time:         event.Time.AsTime(),
+		Bids:         orderBookLevels(event.Bids),
+		Asks:         orderBookLevels(event.Asks),
+	})
+	if errors.Is(err, orderbook.ErrSequenceGap) {
+		// A book that missed deltas is wrong; serve none until the feed
+		// sends a fresh snapshot
+		log.Printf("Dropped %s order book of %s after a sequence gap", event.Exchange, event.Symbol)
+		gw.orderBooks.Remove(event.Exchange, event.Symbol)
+	}
+}
+
+func orderBookLevels(levels []*eventspb.OrderBookLevel) []orderbook.Level {
+	out := make([]orderbook.Level, len(levels))
+	for i, level := range levels {
+		out[i] = orderbook.Level{Price: level.Price, Quantity: level.Quantity}
+	}
+	return out
+}
@@ -0,0 +1,240 @@
+// internal/gateway/orders.go
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+)
+
+// Bulk order handlers
+func (gw *Gateway) CancelAllOrders(c *gin.Context) {
+	filter := orders.Filter{
+		Exchange: c.Query("exchange"),
+		Symbol:   c.Query("symbol"),
+	}
+
+	result, err := gw.bulk.CancelAll(c.Request.Context(), account(c), filter)
+	if err != nil {
+		gw.bulkError(c, err)
+		return
+	}
+
+	c.JSON(bulkStatus(result), result)
+}
+
+func (gw *Gateway) FlattenPositions(c *gin.Context) {
+	filter := orders.Filter{
+		Exchange: c.Query("exchange"),
+		Symbol:   c.Query("symbol"),
+	}
+
+	result, err := gw.bulk.Flatten(c.Request.Context(), account(c), filter, c.GetHeader("Idempotency-Key"))
+	if err != nil {
+		gw.bulkError(c, err)
+		return
+	}
+
+	c.JSON(bulkStatus(result), result)
+}
+
+// CreateOrderGroup places an OCO, bracket or if-then order group. A group
+// placed with a testnet key trades on the exchange's sandbox environment.
+// In an organization's workspace the group trades for the organization,
+// with one of its keys the caller may use.
+func (gw *Gateway) CreateOrderGroup(c *gin.Context) {
+	var req orders.GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.APIKeyID != "" {
+		key, err := gw.apiKeys.Get(ctx, workspace(c), req.APIKeyID)
+		if err != nil {
+			gw.orgKeyError(c, err)
+			return
+		}
+		allowed, err := gw.mayUseKey(c, key, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place order group"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": exchange.ErrKeyNotGranted.Error()})
+			return
+		}
+		if key.Exchange != req.Exchange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The key is for another exchange than the group"})
+			return
+		}
+		req.Testnet = key.Testnet
+	}
+
+	group, err := gw.groups.Create(ctx, account(c), req)
+	if err != nil {
+		gw.groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func (gw *Gateway) ListOrderGroups(c *gin.Context) {
+	groups, err := gw.groups.List(c.Request.Context(), account(c), c.Query("status"))
+	if err != nil {
+		gw.groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (gw *Gateway) GetOrderGroup(c *gin.Context) {
+	group, err := gw.groups.Get(c.Request.Context(), account(c), c.Param("id"))
+	if err != nil {
+		gw.groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// CancelOrderGroup cancels every leg of the group. A group still
+// "cancelling" has an order the exchange has not confirmed cancelled yet;
+// it is retried in the background.
+func (gw *Gateway) CancelOrderGroup(c *gin.Context) {
+	group, err := gw.groups.Cancel(c.Request.Context(), account(c), c.Param("id"))
+	if err != nil {
+		gw.groupError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if group.Status == orders.GroupCancelling {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, group)
+}
+
+func (gw *Gateway) groupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, orders.ErrGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, orders.ErrInvalidGroup), errors.Is(err, exchange.ErrUnknownExchange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// syncGroups drives order groups in the background until the returned
+// function is called.
+func (gw *Gateway) syncGroups(interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		gw.groups.Run(ctx, interval)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// syncFills copies the fills of resting orders into order history in the
+// background until the returned function is called.
+func (gw *Gateway) syncFills(interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		gw.oms.RunReconcile(ctx, interval)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// bulkStatus reports 207 when only some items succeeded.
+func bulkStatus(result *orders.BulkResult) int {
+	if result.Failed > 0 && result.Succeeded > 0 {
+		return http.StatusMultiStatus
+	}
+	if result.Failed > 0 {
+		return http.StatusBadGateway
+	}
+	return http.StatusOK
+}
+
+func (gw *Gateway) bulkError(c *gin.Context, err error) {
+	if errors.Is(err, exchange.ErrUnknownExchange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// historyQuery reads the order/trade search filters. Dates are RFC 3339
+// timestamps; "to" is exclusive.
+func historyQuery(c *gin.Context) (orders.Query, error) {
+	query := orders.Query{
+		UserID:   account(c),
+		Symbol:   c.Query("symbol"),
+		Side:     c.Query("side"),
+		Status:   c.Query("status"),
+		BotID:    c.Query("bot_id"),
+		Exchange: c.Query("exchange"),
+		Cursor:   c.Query("cursor"),
+	}
+
+	if query.Side != "" && query.Side != string(exchange.SideBuy) && query.Side != string(exchange.SideSell) {
+		return query, fmt.Errorf("side must be %q or %q", exchange.SideBuy, exchange.SideSell)
+	}
+
+	// Mainnet and testnet history are never mixed in one listing
+	testnet, err := strconv.ParseBool(c.DefaultQuery("testnet", "false"))
+	if err != nil {
+		return query, fmt.Errorf("invalid testnet")
+	}
+	query.Testnet = &testnet
+
+	if from := c.Query("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("invalid limit")
+		}
+	}
+
+	return query, nil
+}
+
+func (gw *Gateway) historyError(c *gin.Context, err error) {
+	if errors.Is(err, orders.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,442 @@
+// internal/gateway/orgkeys.go
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+type botAPIKeyRequest struct {
+	// Empty leaves the bot without a key
+	APIKeyID string `json:"api_key_id"`
+}
+
+// orgAdmin resolves the caller's membership in the organization of the
+// request's path and answers 403 unless they are an admin.
+func (gw *Gateway) orgAdmin(c *gin.Context) (*org.Membership, bool) {
+	membership, err := gw.Orgs.MemberOf(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err == nil && !membership.Role.AtLeast(org.RoleAdmin) {
+		err = org.ErrNotAllowed
+	}
+	if err != nil {
+		gw.orgError(c, err)
+		return nil, false
+	}
+	return membership, true
+}
+
+// ListOrgAPIKeys lists the organization's exchange keys. Members below
+// admin only see the keys granted to them, without the exchange's key ID.
+func (gw *Gateway) ListOrgAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	membership, err := gw.Orgs.MemberOf(ctx, c.Param("id"), userID)
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	keys, err := gw.apiKeys.List(ctx, org.Shared(membership.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+	if !membership.Role.AtLeast(org.RoleAdmin) {
+		granted, err := gw.keyGrants.Granted(ctx, membership.ID, exchange.GrantMember, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+			return
+		}
+		keys = grantedKeys(keys, granted)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// grantedKeys keeps the keys with the given IDs and hides their key IDs.
+func grantedKeys(keys []exchange.APIKey, granted []string) []exchange.APIKey {
+	ids := make(map[string]bool, len(granted))
+	for _, id := range granted {
+		ids[id] = true
+	}
+	visible := make([]exchange.APIKey, 0, len(granted))
+	for _, key := range keys {
+		if ids[key.ID] {
+			key.KeyID = ""
+			visible = append(visible, key)
+		}
+	}
+	return visible
+}
+
+// CreateOrgAPIKey registers an exchange key for the organization's bots.
+func (gw *Gateway) CreateOrgAPIKey(c *gin.Context) {
+	var req openapi.CreateOrgAPIKeyJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+	if !gw.checkKeyExchange(c, req.Exchange, req.Testnet) {
+		return
+	}
+
+	key := &exchange.APIKey{
+		ID:       uuid.New().String(),
+		UserID:   c.GetString("user_id"),
+		OrgID:    membership.ID,
+		Exchange: req.Exchange,
+		Label:    req.Label,
+		KeyID:    req.KeyID,
+		Testnet:  req.Testnet,
+	}
+	if err := gw.apiKeys.Create(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save API key"})
+		return
+	}
+	gw.recordKeyEvent(c.Request.Context(), key.OrgID, key.ID, key.UserID, exchange.KeyEventCreated, map[string]string{"exchange": key.Exchange})
+	gw.audit(c, auth.AuditAPIKeyCreated, map[string]string{"key_id": key.ID, "exchange": key.Exchange, "org_id": key.OrgID})
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// DeleteOrgAPIKey deletes one of the organization's keys and its grants.
+func (gw *Gateway) DeleteOrgAPIKey(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	keyID := c.Param("key_id")
+	err := gw.apiKeys.Delete(c.Request.Context(), org.Shared(membership.ID), keyID)
+	if errors.Is(err, exchange.ErrKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
+		return
+	}
+	gw.recordKeyEvent(c.Request.Context(), membership.ID, keyID, c.GetString("user_id"), exchange.KeyEventDeleted, nil)
+	gw.haltKeyBots(c.Request.Context(), keyID)
+	gw.audit(c, auth.AuditAPIKeyDeleted, map[string]string{"key_id": keyID, "org_id": membership.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key deleted"})
+}
+
+func (gw *Gateway) ListOrgAPIKeyGrants(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := gw.apiKeys.Get(ctx, org.Shared(membership.ID), c.Param("key_id")); err != nil {
+		gw.orgKeyError(c, err)
+		return
+	}
+	grants, err := gw.keyGrants.Grants(ctx, membership.ID, c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list grants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": grants})
+}
+
+// GrantOrgAPIKey lets a member or a bot of the organization use one of its
+// keys.
+func (gw *Gateway) GrantOrgAPIKey(c *gin.Context) {
+	var req openapi.GrantOrgAPIKeyJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := gw.apiKeys.Get(ctx, org.Shared(membership.ID), c.Param("key_id"))
+	if err != nil {
+		gw.orgKeyError(c, err)
+		return
+	}
+	switch string(req.Subject) {
+	case exchange.GrantMember:
+		if _, err := gw.Orgs.MemberOf(ctx, membership.ID, req.SubjectID); err != nil {
+			if errors.Is(err, org.ErrNotMember) {
+				err = org.ErrMemberNotFound
+			}
+			gw.orgError(c, err)
+			return
+		}
+	case exchange.GrantBot:
+		b, err := gw.bots.Get(ctx, req.SubjectID)
+		if err != nil || b.OrgID != membership.ID {
+			c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subject must be member or bot"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	grant := &exchange.KeyGrant{
+		KeyID:     key.ID,
+		OrgID:     membership.ID,
+		Subject:   string(req.Subject),
+		SubjectID: req.SubjectID,
+		GrantedBy: userID,
+	}
+	if err := gw.keyGrants.Grant(ctx, grant); err != nil {
+		gw.orgKeyError(c, err)
+		return
+	}
+	gw.recordKeyEvent(ctx, membership.ID, key.ID, userID, exchange.KeyEventGranted, map[string]string{
+		"subject":    grant.Subject,
+		"subject_id": grant.SubjectID,
+	})
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// RevokeOrgAPIKeyGrant revokes a grant. Bots already running with the key
+// keep trading until they are stopped.
+func (gw *Gateway) RevokeOrgAPIKeyGrant(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	grant, err := gw.keyGrants.Revoke(ctx, membership.ID, c.Param("key_id"), c.Param("grant_id"))
+	if err != nil {
+		gw.orgKeyError(c, err)
+		return
+	}
+	gw.recordKeyEvent(ctx, membership.ID, grant.KeyID, c.GetString("user_id"), exchange.KeyEventRevoked, map[string]string{
+		"subject":    grant.Subject,
+		"subject_id": grant.SubjectID,
+	})
+	gw.haltKeyBots(ctx, grant.KeyID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Grant revoked"})
+}
+
+// ListOrgAPIKeyEvents lists what members did with the organization's keys,
+// optionally of one member or key.
+func (gw *Gateway) ListOrgAPIKeyEvents(c *gin.Context) {
+	var params openapi.ListOrgAPIKeyEventsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = 100
+	}
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	events, err := gw.keyGrants.Events(c.Request.Context(), exchange.KeyEventQuery{
+		OrgID:   membership.ID,
+		ActorID: params.UserID,
+		KeyID:   params.KeyID,
+		Limit:   params.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list key events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// SetBotAPIKey sets the exchange key a stopped bot trades with: a personal
+// key for personal bots, and for an organization's bots one of its keys
+// the caller may use.
+func (gw *Gateway) SetBotAPIKey(c *gin.Context) {
+	var req botAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	owner := workspace(c)
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !owner.Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+	if b.Status == bot.StatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stop the bot before changing its exchange key"})
+		return
+	}
+
+	var key *exchange.APIKey
+	if req.APIKeyID != "" {
+		key, err = gw.apiKeys.Get(ctx, owner, req.APIKeyID)
+		if err != nil {
+			gw.orgKeyError(c, err)
+			return
+		}
+		if key.Exchange != b.Exchange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The key is for another exchange than the bot"})
+			return
+		}
+		// Attaching needs a grant to the member; one to the bot is not enough
+		allowed, err := gw.mayUseKey(c, key, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set exchange key"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": exchange.ErrKeyNotGranted.Error()})
+			return
+		}
+	}
+
+	testnet := key != nil && key.Testnet
+	err = gw.bots.SetAPIKey(ctx, owner, b.ID, req.APIKeyID, testnet)
+	if errors.Is(err, bot.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set exchange key"})
+		return
+	}
+	if key != nil && key.OrgID != "" {
+		gw.recordKeyEvent(ctx, key.OrgID, key.ID, c.GetString("user_id"), exchange.KeyEventAttached, map[string]string{"bot_id": b.ID})
+	}
+
+	b.APIKeyID, b.Testnet = req.APIKeyID, testnet
+	c.JSON(http.StatusOK, b)
+}
+
+// haltKeyBots puts the running bots that may no longer trade with the key
+// in error. The bot runtime checks again before every order; this stops
+// them right away.
+func (gw *Gateway) haltKeyBots(ctx context.Context, keyID string) {
+	bots, err := gw.bots.ListRunningWithKey(ctx, keyID)
+	if err != nil {
+		log.Printf("Failed to list bots of key %s: %v", keyID, err)
+		return
+	}
+	for _, b := range bots {
+		err := gw.keyAuth.Authorize(ctx, b.Owner(), keyID, b.Starter(), b.ID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, exchange.ErrKeyNotFound) && !errors.Is(err, exchange.ErrKeyNotGranted) {
+			log.Printf("Failed to check key of bot %s: %v", b.ID, err)
+			continue
+		}
+		if err := gw.bots.SetStatus(ctx, b.Owner(), b.ID, bot.StatusError); err != nil {
+			log.Printf("Failed to halt bot %s: %v", b.ID, err)
+			continue
+		}
+		gw.publishBotStatus(b.UserID, b.ID, bot.StatusError)
+	}
+}
+
+// authorizeBotKey answers 403 unless the caller may start the bot with its
+// exchange key. Personal keys are the caller's own; an organization's keys
+// need an admin, or a grant to the caller or to the bot.
+func (gw *Gateway) authorizeBotKey(c *gin.Context, b *bot.Bot) bool {
+	if b.OrgID == "" || b.APIKeyID == "" {
+		return true
+	}
+	key, err := gw.apiKeys.Get(c.Request.Context(), b.Owner(), b.APIKeyID)
+	if errors.Is(err, exchange.ErrKeyNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The bot's exchange key was deleted"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start bot"})
+		return false
+	}
+	allowed, err := gw.mayUseKey(c, key, b)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start bot"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": exchange.ErrKeyNotGranted.Error()})
+		return false
+	}
+	return true
+}
+
+// mayUseKey tells whether the caller may use a key of their workspace.
+// Admins use all of an organization's keys; other members those granted
+// to them, or to b when it is given.
+func (gw *Gateway) mayUseKey(c *gin.Context, key *exchange.APIKey, b *bot.Bot) (bool, error) {
+	if key.OrgID == "" {
+		return true, nil
+	}
+	if value, ok := c.Get("org_role"); ok {
+		if role, ok := value.(org.Role); ok && role.AtLeast(org.RoleAdmin) {
+			return true, nil
+		}
+	}
+
+	ctx := c.Request.Context()
+	granted, err := gw.keyGrants.IsGranted(ctx, key.ID, exchange.GrantMember, c.GetString("user_id"))
+	if err != nil || granted || b == nil {
+		return granted, err
+	}
+	return gw.keyGrants.IsGranted(ctx, key.ID, exchange.GrantBot, b.ID)
+}
+
+// recordBotStart records that the member started an organization's bot
+// trading with one of its keys.
+func (gw *Gateway) recordBotStart(ctx context.Context, b *bot.Bot, actorID string) {
+	if b.OrgID != "" && b.APIKeyID != "" {
+		gw.recordKeyEvent(ctx, b.OrgID, b.APIKeyID, actorID, exchange.KeyEventBotStarted, map[string]string{"bot_id": b.ID})
+	}
+}
+
+// recordKeyEvent records a member's action on an organization's key. The
+// action already happened, so failures are only logged.
+func (gw *Gateway) recordKeyEvent(ctx context.Context, orgID, keyID, actorID, action string, details map[string]string) {
+	err := gw.keyGrants.Record(ctx, &exchange.KeyEvent{
+		OrgID:   orgID,
+		KeyID:   keyID,
+		ActorID: actorID,
+		Action:  action,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Failed to record %s of key %s: %v", action, keyID, err)
+	}
+}
+
+func (gw *Gateway) orgKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, exchange.ErrKeyNotFound), errors.Is(err, exchange.ErrGrantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, exchange.ErrGrantExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange key"})
+	}
+}
@@ -0,0 +1,381 @@
+// internal/gateway/orgs.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/tags"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// workspace returns whose bots and strategies the request works with: the
+// organization middleware.OrgContext resolved, or the user's own.
+func workspace(c *gin.Context) org.Owner {
+	if orgID := c.GetString("org_id"); orgID != "" {
+		return org.Shared(orgID)
+	}
+	return org.Personal(c.GetString("user_id"))
+}
+
+// account returns the ID orders, trades and holdings of the workspace are
+// recorded under; the organization's bots trade under it too.
+func account(c *gin.Context) string {
+	if orgID := c.GetString("org_id"); orgID != "" {
+		return orgID
+	}
+	return c.GetString("user_id")
+}
+
+// workspaceRegion returns the data region of the workspace.
+func workspaceRegion(c *gin.Context) string {
+	if value, ok := c.Get("org"); ok {
+		if membership, ok := value.(*org.Membership); ok {
+			return membership.DataRegion
+		}
+	}
+	return userRegion(c)
+}
+
+func (gw *Gateway) ListOrgs(c *gin.Context) {
+	memberships, err := gw.Orgs.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": memberships})
+}
+
+// CreateOrg starts an organization owned by the user. Its trade data is
+// kept in the user's data region.
+func (gw *Gateway) CreateOrg(c *gin.Context) {
+	var req openapi.CreateOrgJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	o, err := gw.Orgs.Create(c.Request.Context(), userID, req.Name, userRegion(c))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, org.Membership{Organization: *o, Role: org.RoleOwner})
+}
+
+func (gw *Gateway) GetOrg(c *gin.Context) {
+	membership, err := gw.Orgs.MemberOf(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// DeleteOrg deletes an organization that no longer has bots or
+// strategies, trashed ones included, so nothing is left without an owner.
+func (gw *Gateway) DeleteOrg(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	orgID := c.Param("id")
+
+	membership, err := gw.Orgs.MemberOf(ctx, orgID, userID)
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+	if membership.Role != org.RoleOwner {
+		gw.orgError(c, org.ErrNotAllowed)
+		return
+	}
+
+	owner := org.Shared(orgID)
+	bots, err := gw.bots.List(ctx, owner, tags.Filter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	deletedBots, err := gw.bots.ListDeleted(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	strategies, err := gw.strategies.List(ctx, owner, tags.Filter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	deletedStrategies, err := gw.strategies.ListDeleted(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+	if len(bots)+len(deletedBots)+len(strategies)+len(deletedStrategies) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Move or delete the organization's bots and strategies first"})
+		return
+	}
+
+	// Its identity providers would otherwise keep signing users of its
+	// domain in, with nobody left to manage them
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	conns, err := gw.authClientFor(c).ListSSOConnections(ctx, &authpb.ListSSOConnectionsRequest{AccessToken: token, OrgId: orgID})
+	if err != nil {
+		adminRPCError(c, err, "Failed to delete organization")
+		return
+	}
+	for _, conn := range conns.Connections {
+		_, err := gw.authClientFor(c).DeleteSSOConnection(ctx, &authpb.DeleteSSOConnectionRequest{AccessToken: token, Id: conn.Id, OrgId: orgID})
+		if err != nil && status.Code(err) != codes.NotFound {
+			adminRPCError(c, err, "Failed to delete organization")
+			return
+		}
+	}
+
+	if err := gw.Orgs.Delete(ctx, orgID, userID); err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted"})
+}
+
+func (gw *Gateway) ListOrgMembers(c *gin.Context) {
+	members, err := gw.Orgs.Members(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (gw *Gateway) AddOrgMember(c *gin.Context) {
+	var req openapi.AddOrgMemberJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := gw.Orgs.AddMember(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req.UserID.String(), org.Role(req.Role))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, m)
+}
+
+func (gw *Gateway) SetOrgMemberRole(c *gin.Context) {
+	var req openapi.SetOrgMemberRoleJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := gw.Orgs.SetRole(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.Param("user_id"), org.Role(req.Role))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": c.Param("user_id"), "role": req.Role})
+}
+
+// RemoveOrgMember removes a member; members remove themselves to leave.
+func (gw *Gateway) RemoveOrgMember(c *gin.Context) {
+	if err := gw.Orgs.RemoveMember(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func (gw *Gateway) ListOrgInvitations(c *gin.Context) {
+	invitations, err := gw.Orgs.Invitations(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// InviteToOrg emails an invitation to join with a role.
+func (gw *Gateway) InviteToOrg(c *gin.Context) {
+	var req openapi.InviteToOrgJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inv, err := gw.Orgs.Invite(c.Request.Context(), c.Param("id"), c.GetString("user_id"), string(req.Email), org.Role(req.Role))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, inv)
+}
+
+func (gw *Gateway) RevokeOrgInvitation(c *gin.Context) {
+	if err := gw.Orgs.RevokeInvitation(c.Request.Context(), c.Param("id"), c.GetString("user_id"), c.Param("invitation_id")); err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// userEmail returns the address of the user, which RequireVerifiedEmail
+// has checked is theirs.
+func userEmail(c *gin.Context) string {
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*authpb.User); ok {
+			return user.Email
+		}
+	}
+	return ""
+}
+
+// ListInvitations lists the pending invitations to the user's address.
+func (gw *Gateway) ListInvitations(c *gin.Context) {
+	invitations, err := gw.Orgs.PendingInvitations(c.Request.Context(), userEmail(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (gw *Gateway) AcceptInvitation(c *gin.Context) {
+	m, err := gw.Orgs.AcceptInvitation(c.Request.Context(), c.Param("id"), c.GetString("user_id"), userEmail(c))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+func (gw *Gateway) DeclineInvitation(c *gin.Context) {
+	if err := gw.Orgs.DeclineInvitation(c.Request.Context(), c.Param("id"), userEmail(c)); err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// moveTarget resolves the workspace a resource is moved to: an
+// organization the user may write to, or their own for an empty ID.
+func (gw *Gateway) moveTarget(c *gin.Context) (org.Owner, bool) {
+	var req openapi.MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return org.Owner{}, false
+	}
+
+	userID := c.GetString("user_id")
+	if req.OrgID == "" {
+		return org.Personal(userID), true
+	}
+	membership, err := gw.Orgs.MemberOf(c.Request.Context(), req.OrgID, userID)
+	if err != nil {
+		gw.orgError(c, err)
+		return org.Owner{}, false
+	}
+	if !membership.Role.CanWrite() {
+		gw.orgError(c, org.ErrNotAllowed)
+		return org.Owner{}, false
+	}
+	return org.Owner{UserID: userID, OrgID: req.OrgID}, true
+}
+
+// MoveBot moves a stopped bot of the current workspace into an
+// organization or back to the user's own workspace.
+func (gw *Gateway) MoveBot(c *gin.Context) {
+	to, ok := gw.moveTarget(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	from := workspace(c)
+	b, err := gw.bots.Get(ctx, c.Param("id"))
+	if err != nil || !from.Owns(b.Owner()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+		return
+	}
+	if b.Status == bot.StatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stop the bot before moving it"})
+		return
+	}
+
+	err = gw.bots.Move(ctx, from, b.ID, to)
+	if errors.Is(err, bot.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move bot"})
+		return
+	}
+
+	b.UserID, b.OrgID = to.UserID, to.OrgID
+	c.JSON(http.StatusOK, b)
+}
+
+// MoveStrategy moves a strategy of the current workspace into an
+// organization or back to the user's own workspace.
+func (gw *Gateway) MoveStrategy(c *gin.Context) {
+	to, ok := gw.moveTarget(c)
+	if !ok {
+		return
+	}
+
+	err := gw.strategies.Move(c.Request.Context(), workspace(c), c.Param("id"), to)
+	if errors.Is(err, strategy.ErrStrategyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move strategy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "org_id": to.OrgID})
+}
+
+func (gw *Gateway) orgError(c *gin.Context, err error) {
+	switch {
+	// Organizations of others look the same as missing ones
+	case errors.Is(err, org.ErrOrgNotFound), errors.Is(err, org.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": org.ErrOrgNotFound.Error()})
+	case errors.Is(err, org.ErrMemberNotFound), errors.Is(err, org.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, org.ErrInvitationExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, org.ErrNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, org.ErrAlreadyMember), errors.Is(err, org.ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, org.ErrInvalidRole), errors.Is(err, org.ErrInvalidName), errors.Is(err, org.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+	}
+}
@@ -0,0 +1,78 @@
+// internal/gateway/paper.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+// GetPaperChaos returns how the workspace's paper account on the exchange
+// misbehaves; ?testnet=true picks its testnet account.
+func (gw *Gateway) GetPaperChaos(c *gin.Context) {
+	paper, ok := gw.paperAccounts(c)
+	if !ok {
+		return
+	}
+
+	settings, err := paper.Chaos(c.Request.Context(), account(c))
+	if err != nil {
+		gw.chaosError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetPaperChaos sets the rates at which the workspace's paper account on
+// the exchange rejects orders, fills them partially, acknowledges them
+// late and reports fills twice. Zero rates turn chaos off.
+func (gw *Gateway) SetPaperChaos(c *gin.Context) {
+	var settings exchange.ChaosSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paper, ok := gw.paperAccounts(c)
+	if !ok {
+		return
+	}
+	if err := paper.SetChaos(c.Request.Context(), account(c), settings); err != nil {
+		gw.chaosError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// paperAccounts resolves the paper exchange of the request path.
+func (gw *Gateway) paperAccounts(c *gin.Context) (exchange.PaperAccounts, bool) {
+	if gw.config.Trading.Mode != "paper" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper trading is not enabled"})
+		return nil, false
+	}
+	client, err := gw.clients.For(c.Param("exchange"), c.Query("testnet") == "true")
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown exchange"})
+		return nil, false
+	}
+	paper, ok := exchange.Unwrap(client).(exchange.PaperAccounts)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper trading is not enabled"})
+		return nil, false
+	}
+	return paper, true
+}
+
+func (gw *Gateway) chaosError(c *gin.Context, err error) {
+	if errors.Is(err, exchange.ErrChaosDisabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": "Paper exchange unavailable"})
+}
@@ -0,0 +1,55 @@
+// internal/gateway/registry.go
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/registry"
+)
+
+// announce starts the gateway's registry heartbeat and returns a function
+// that stops it after the leave message went out.
+func (gw *Gateway) announce(cfg *config.Config) func() {
+	announcer := registry.NewAnnouncer(gw.nats, registry.Instance{
+		Service:      "api-gateway",
+		Region:       cfg.Region,
+		Address:      cfg.Server.Port,
+		Dependencies: []string{"auth-service", "backtest-service", "postgres", "redis", "influxdb", "nats"},
+	}, cfg.Registry.Interval, func() string {
+		if gw.Drainer.Draining() {
+			return registry.HealthDraining
+		}
+		if gw.Warmer == nil {
+			return registry.HealthHealthy
+		}
+		return gw.Warmer.Health()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		announcer.Run(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// GetTopology returns the services that announced themselves, their
+// instances and dependencies. ?format=dot renders a Graphviz graph.
+func (gw *Gateway) GetTopology(c *gin.Context) {
+	topology := gw.registry.Topology()
+
+	if c.Query("format") == "dot" {
+		c.String(http.StatusOK, topology.DOT())
+		return
+	}
+
+	c.JSON(http.StatusOK, topology)
+}
@@ -0,0 +1,221 @@
+// internal/gateway/residency.go
+package gateway
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/residency"
+)
+
+const (
+	// exportFlushRows is how many CSV rows are buffered between flushes
+	exportFlushRows = 500
+	// exportWriteTimeout bounds writing one batch of rows to the client
+	exportWriteTimeout = 30 * time.Second
+)
+
+type setDataRegionRequest struct {
+	Region string `json:"region" binding:"required"`
+}
+
+// userRegion returns the data region of the authenticated user; empty means
+// the default region.
+func userRegion(c *gin.Context) string {
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*authpb.User); ok {
+			return user.DataRegion
+		}
+	}
+	return ""
+}
+
+// readOrdersFor returns the order repository that serves reads of the
+// workspace's data region, backed by its replica where one is configured.
+func (gw *Gateway) readOrdersFor(c *gin.Context) (orders.Repository, error) {
+	return gw.residency.ReadOrders(workspaceRegion(c))
+}
+
+// ListDataRegions lists the regions a user may store their data in.
+func (gw *Gateway) ListDataRegions(c *gin.Context) {
+	current, err := gw.residency.Resolve(userRegion(c))
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"regions": gw.residency.Regions(),
+		"current": current,
+	})
+}
+
+// SetDataRegion moves the user's trade data storage to another region. It
+// is only allowed while the user has no trade data yet, since existing rows
+// are not migrated between regional databases.
+func (gw *Gateway) SetDataRegion(c *gin.Context) {
+	var req setDataRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := gw.residency.Resolve(userRegion(c))
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+	if _, err := gw.residency.Resolve(req.Region); err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	if req.Region != current {
+		repo, err := gw.residency.Orders(current)
+		if err != nil {
+			gw.residencyError(c, err)
+			return
+		}
+		page, err := repo.SearchOrders(c.Request.Context(), orders.Query{UserID: c.GetString("user_id"), Limit: 1})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing trade data"})
+			return
+		}
+		if len(page.Orders) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Trade data already exists in region " + current})
+			return
+		}
+	}
+
+	token := c.GetHeader("Authorization")
+	if len(token) > 7 && token[:7] == "Bearer " {
+		token = token[7:]
+	}
+
+	resp, err := gw.authClientFor(c).SetDataRegion(c.Request.Context(), &authpb.SetDataRegionRequest{
+		AccessToken: token,
+		Region:      req.Region,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ExportTrades streams the user's trade history as CSV, newest first.
+// Exports are only generated by a gateway running in the user's data
+// region so the data never leaves it.
+//
+// X-Total-Count announces how many rows follow, so clients can report
+// progress and tell a complete download from a cut off one. An
+// interrupted download resumes with resume_after set to the ID of the
+// last row received.
+func (gw *Gateway) ExportTrades(c *gin.Context) {
+	region := workspaceRegion(c)
+	if err := gw.residency.CheckExport(region, gw.config.Region); err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	query, err := historyQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repo, err := gw.residency.ReadOrders(region)
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+
+	if after := c.Query("resume_after"); after != "" {
+		if query.Cursor, err = repo.TradeCursor(c.Request.Context(), query, after); err != nil {
+			gw.historyError(c, err)
+			return
+		}
+	}
+
+	gw.exportTradesCSV(c, repo, query)
+}
+
+// exportTradesCSV streams the trades matching query. Large exports
+// outlive the server's write timeout, so each batch of rows gets its own
+// deadline instead: a client that stops reading fails the export rather
+// than holding the database cursor open.
+func (gw *Gateway) exportTradesCSV(c *gin.Context, repo orders.Repository, query orders.Query) {
+	controller := http.NewResponseController(c.Writer)
+	w := csv.NewWriter(c.Writer)
+	started := false
+	rows := 0
+
+	err := repo.ExportTrades(c.Request.Context(), query, func(total int64) error {
+		started = true
+		controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="trades.csv"`)
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+		c.Status(http.StatusOK)
+		return w.Write([]string{"id", "order_id", "bot_id", "exchange", "symbol", "side", "price", "quantity", "fee", "fee_asset", "executed_at"})
+	}, func(trade *orders.Trade) error {
+		err := w.Write([]string{
+			trade.ID,
+			trade.OrderID,
+			trade.BotID,
+			trade.Exchange,
+			trade.Symbol,
+			trade.Side,
+			trade.Price.String(),
+			trade.Quantity.String(),
+			trade.Fee.String(),
+			trade.FeeAsset,
+			trade.ExecutedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		// Flush in batches so the response streams without a write per row
+		if rows++; rows%exportFlushRows == 0 {
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return err
+			}
+			controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		}
+		return nil
+	})
+	if !started {
+		gw.historyError(c, err)
+		return
+	}
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		// Headers are already sent; truncate the download
+		c.Error(err)
+	}
+}
+
+func (gw *Gateway) residencyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, residency.ErrUnknownRegion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, residency.ErrResidencyViolation):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  err.Error(),
+			"region": userRegion(c),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
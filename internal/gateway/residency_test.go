@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/orders"
+)
+
+// exportRepository exports its trades, failing with err once fail of them
+// were handed over.
+type exportRepository struct {
+	orders.Repository
+	trades []orders.Trade
+	fail   int
+	err    error
+}
+
+func (r *exportRepository) ExportTrades(ctx context.Context, q orders.Query, start func(int64) error, fn func(*orders.Trade) error) error {
+	if r.fail == 0 && r.err != nil {
+		return r.err
+	}
+	if err := start(int64(len(r.trades))); err != nil {
+		return err
+	}
+	for i := range r.trades {
+		if i == r.fail && r.err != nil {
+			return r.err
+		}
+		if err := fn(&r.trades[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportTradesCSV(t *testing.T) {
+	trades := []orders.Trade{
+		{ID: "t-2", OrderID: "o-2", Exchange: "binance", Symbol: "BTCUSDT", Side: "sell", Price: decimal.NewFromInt(101), Quantity: decimal.NewFromInt(1), ExecutedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: "t-1", OrderID: "o-1", Exchange: "binance", Symbol: "BTCUSDT", Side: "buy", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(1), ExecutedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name   string
+		repo   *exportRepository
+		status int
+		total  string
+		rows   int
+		failed bool
+	}{
+		{name: "complete", repo: &exportRepository{trades: trades}, status: http.StatusOK, total: "2", rows: 2},
+		{name: "invalid cursor", repo: &exportRepository{trades: trades, err: orders.ErrInvalidCursor}, status: http.StatusBadRequest},
+		{name: "cut off", repo: &exportRepository{trades: trades, fail: 1, err: errors.New("connection reset")}, status: http.StatusOK, total: "2", rows: 1, failed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/portfolio/trades/export", nil)
+
+			(&Gateway{}).exportTradesCSV(c, tt.repo, orders.Query{UserID: "user-1"})
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, tt.failed, len(c.Errors) > 0)
+			if tt.status != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.total, recorder.Header().Get("X-Total-Count"))
+			records, err := csv.NewReader(strings.NewReader(recorder.Body.String())).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, tt.rows+1)
+			assert.Equal(t, "id", records[0][0])
+			assert.Equal(t, "t-2", records[1][0])
+			assert.Equal(t, "2024-01-02T00:00:00Z", records[1][10])
+		})
+	}
+}
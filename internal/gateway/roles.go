@@ -0,0 +1,106 @@
+// internal/gateway/roles.go
+package gateway
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxUsersLimit = 500
+
+type setUserRolesRequest struct {
+	// Replaces the user's roles; an empty list removes them all
+	Roles []string `json:"roles"`
+}
+
+// ListUsers pages through all users with their roles, or those whose
+// email, username or name contains q. The auth service checks the caller's
+// permission again.
+func (gw *Gateway) ListUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > maxUsersLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must not be negative"})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).ListUsers(c.Request.Context(), &authpb.ListUsersRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+		Query:       strings.TrimSpace(c.Query("q")),
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to list users")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": resp.Users, "total": resp.Total})
+}
+
+// SetUserRoles replaces a user's roles. The user's cached validations are
+// dropped, so the new permissions apply to their next request.
+func (gw *Gateway) SetUserRoles(c *gin.Context) {
+	var req setUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).SetUserRoles(c.Request.Context(), &authpb.SetUserRolesRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      c.Param("id"),
+		Roles:       req.Roles,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to set roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+func adminRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": status.Convert(err).Message()})
+	case codes.FailedPrecondition, codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// HaltBots is the global kill switch: it stops every running bot of every
+// user. Owners see the bots as stopped and can start them again.
+func (gw *Gateway) HaltBots(c *gin.Context) {
+	stopped, err := gw.bots.StopAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to halt bots"})
+		return
+	}
+	for _, b := range stopped {
+		gw.publishBotStatus(b.UserID, b.ID, bot.StatusStopped)
+	}
+	log.Printf("User %s halted %d running bots", c.GetString("user_id"), len(stopped))
+
+	c.JSON(http.StatusOK, gin.H{"stopped": len(stopped)})
+}
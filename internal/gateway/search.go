@@ -0,0 +1,52 @@
+// internal/gateway/search.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/search"
+)
+
+// Search looks up the user's bots, strategies and orders and the available
+// symbols. ?types=bots,symbols narrows the groups and ?limit sets the
+// number of hits per group.
+func (gw *Gateway) Search(c *gin.Context) {
+	query := search.Query{
+		UserID: c.GetString("user_id"),
+		Text:   c.Query("q"),
+	}
+	if v := c.Query("types"); v != "" {
+		query.Groups = strings.Split(v, ",")
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		query.Limit = limit
+	}
+
+	ordersDB, err := gw.residency.ReadDB(userRegion(c))
+	if err != nil {
+		gw.residencyError(c, err)
+		return
+	}
+	query.OrdersDB = ordersDB
+
+	results, err := gw.search.Search(c.Request.Context(), query)
+	if errors.Is(err, search.ErrEmptyQuery) || errors.Is(err, search.ErrQueryTooLong) || errors.Is(err, search.ErrUnknownGroup) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": query.Text, "results": results})
+}
@@ -0,0 +1,52 @@
+// internal/gateway/sessions.go
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSessions lists the devices the caller is signed in on. The session
+// of the token making the request is marked current.
+func (gw *Gateway) ListSessions(c *gin.Context) {
+	resp, err := gw.authClientFor(c).ListSessions(c.Request.Context(), &authpb.ListSessionsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+	})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": resp.Sessions})
+}
+
+// RevokeSession signs one of the caller's devices out. Its refresh token
+// stops working and its access tokens are rejected from the next request.
+func (gw *Gateway) RevokeSession(c *gin.Context) {
+	resp, err := gw.authClientFor(c).RevokeSession(c.Request.Context(), &authpb.RevokeSessionRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		SessionId:   c.Param("id"),
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		case codes.Unauthenticated:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
@@ -0,0 +1,99 @@
+// internal/gateway/settlements.go
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxSettlements = 500
+	// statementPeriod is the default span of payout and commission reports
+	statementPeriod = 30 * 24 * time.Hour
+)
+
+// ListFollowSettlements returns the profit-sharing settlements the
+// follower paid on a follow, newest first.
+func (gw *Gateway) ListFollowSettlements(c *gin.Context) {
+	follow, err := gw.follows.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		gw.followError(c, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > maxSettlements {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	settlements, err := gw.settlements.ListByFollow(c.Request.Context(), follow.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list settlements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settlements": settlements})
+}
+
+// GetPayoutStatement is the caller's statement as a leader: the fees their
+// followers paid and the payout left after platform commission.
+func (gw *Gateway) GetPayoutStatement(c *gin.Context) {
+	from, to, ok := statementRange(c)
+	if !ok {
+		return
+	}
+
+	statement, err := gw.settlements.Statement(c.Request.Context(), c.GetString("user_id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build payout statement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// GetPlatformCommission totals the commission the platform kept from
+// performance fees.
+func (gw *Gateway) GetPlatformCommission(c *gin.Context) {
+	from, to, ok := statementRange(c)
+	if !ok {
+		return
+	}
+
+	totals, err := gw.settlements.Commission(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to total commission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "totals": totals})
+}
+
+// statementRange reads the from and to query parameters, defaulting to the
+// last statement period, and writes the error response when invalid.
+func statementRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var err error
+	to := time.Now().UTC()
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	from := to.Add(-statementPeriod)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time range"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,187 @@
+// internal/gateway/share.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/equity"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/share"
+)
+
+// sharedPerformanceWindow is how much equity history a shared page shows
+const sharedPerformanceWindow = 90 * 24 * time.Hour
+
+type createShareRequest struct {
+	Resource   string `json:"resource" binding:"required"`
+	ResourceID string `json:"resource_id" binding:"required"`
+	// ExpiresIn is the lifetime in seconds; zero uses the maximum
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// sharedBot is the public view of a bot: no owner, account or exchange
+// credentials, only what describes its performance.
+type sharedBot struct {
+	Name      string    `json:"name"`
+	Exchange  string    `json:"exchange"`
+	Symbol    string    `json:"symbol"`
+	Strategy  string    `json:"strategy"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sharedBacktest is the public report of a backtest sweep: the market,
+// period and results, without the user, their bot or their plan.
+type sharedBacktest struct {
+	Exchange   string            `json:"exchange"`
+	Symbol     string            `json:"symbol"`
+	Interval   string            `json:"interval"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Seed       int64             `json:"seed"`
+	Results    []backtest.Result `json:"results"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+func (gw *Gateway) CreateShareLink(c *gin.Context) {
+	var req createShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresIn) * time.Second
+	if ttl <= 0 || ttl > gw.config.Share.MaxTTL {
+		ttl = gw.config.Share.MaxTTL
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+
+	// Only a resource of the caller's workspace may be shared: their own,
+	// or, in an organization's, one of its bots
+	switch req.Resource {
+	case share.ResourceBotPerformance:
+		b, err := gw.bots.Get(ctx, req.ResourceID)
+		if err != nil || !workspace(c).Owns(b.Owner()) {
+			c.JSON(http.StatusNotFound, gin.H{"error": bot.ErrBotNotFound.Error()})
+			return
+		}
+	case share.ResourceBacktestReport:
+		// Sweeps are the caller's own; only finished ones have a report
+		job, err := gw.sweeps.Get(ctx, userID, req.ResourceID)
+		if err != nil {
+			sweepJobError(c, err)
+			return
+		}
+		if job.Status != backtest.JobSucceeded {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only succeeded backtests can be shared"})
+			return
+		}
+	}
+
+	link, err := gw.shares.Create(ctx, userID, req.Resource, req.ResourceID, ttl)
+	if errors.Is(err, share.ErrUnknownResource) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+func (gw *Gateway) ListShareLinks(c *gin.Context) {
+	links, err := gw.shares.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list share links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+func (gw *Gateway) RevokeShareLink(c *gin.Context) {
+	err := gw.shares.Revoke(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, share.ErrLinkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedResource serves a shared resource without authentication.
+// Invalid, expired and revoked links all look the same to the caller.
+func (gw *Gateway) GetSharedResource(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	link, err := gw.shares.Resolve(ctx, c.Param("token"))
+	if errors.Is(err, share.ErrInvalidToken) || errors.Is(err, share.ErrLinkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared page not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shared page"})
+		return
+	}
+
+	switch link.Resource {
+	case share.ResourceBotPerformance:
+		b, err := gw.bots.Get(ctx, link.ResourceID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared page not found"})
+			return
+		}
+
+		to := time.Now()
+		points, err := gw.equity.Series(ctx, b.UserID, b.ID, to.Add(-sharedPerformanceWindow), to)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load performance"})
+			return
+		}
+
+		daily, _ := marketdata.ParseInterval("1d")
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{
+			"resource":    link.Resource,
+			"bot":         sharedBot{Name: b.Name, Exchange: b.Exchange, Symbol: b.Symbol, Strategy: b.Strategy, CreatedAt: b.CreatedAt},
+			"performance": equity.Summarize(points),
+			"equity":      marketdata.NewCandleColumns(marketdata.Rollup(points, daily, time.UTC)),
+		})
+	case share.ResourceBacktestReport:
+		job, err := gw.sweeps.Get(ctx, link.UserID, link.ResourceID)
+		if err != nil || job.Status != backtest.JobSucceeded {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared page not found"})
+			return
+		}
+
+		// Finished reports do not change
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.JSON(http.StatusOK, gin.H{
+			"resource": link.Resource,
+			"backtest": sharedBacktest{
+				Exchange:   job.Exchange,
+				Symbol:     job.Symbol,
+				Interval:   job.Interval,
+				From:       job.From,
+				To:         job.To,
+				Seed:       job.Seed,
+				Results:    job.Results,
+				FinishedAt: job.FinishedAt,
+			},
+		})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared page not found"})
+	}
+}
@@ -0,0 +1,260 @@
+// internal/gateway/sso.go
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/openapi"
+	"github.com/tradingbothub/platform/internal/org"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ssoBindingCookie holds the secret that ties a single sign-on to the
+// browser that started it. Only the callback reads it.
+const (
+	ssoBindingCookie = "tbh_sso"
+	ssoBindingPath   = "/api/v1/auth/sso"
+)
+
+// bindSSO sets a fresh binding cookie and returns its value for the auth
+// service to store with the login's state. Without it, a login an
+// attacker started could be completed in a victim's browser, signing the
+// victim into the attacker's account or linking their identity to it.
+func (gw *Gateway) bindSSO(c *gin.Context) (string, bool) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return "", false
+	}
+	binding := hex.EncodeToString(raw)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoBindingCookie, binding, int(gw.config.Auth.SSO.StateTTL.Seconds()), ssoBindingPath, "", gw.config.Auth.CookieSessions.Secure, true)
+	return binding, true
+}
+
+// StartSSO begins signing in through the identity provider of the email's
+// domain and answers with the URL to send the user to.
+func (gw *Gateway) StartSSO(c *gin.Context) {
+	var req openapi.StartSSOJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authorizationURL, ok := gw.startSSO(c, string(req.Email))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, openapi.SSORedirect{AuthorizationURL: authorizationURL})
+}
+
+// StartSSORedirect is StartSSO for plain links: it redirects the browser
+// to the identity provider.
+func (gw *Gateway) StartSSORedirect(c *gin.Context) {
+	var params openapi.StartSSORedirectParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authorizationURL, ok := gw.startSSO(c, string(params.Email))
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+func (gw *Gateway) startSSO(c *gin.Context, email string) (string, bool) {
+	binding, ok := gw.bindSSO(c)
+	if !ok {
+		return "", false
+	}
+	resp, err := gw.AuthClient.StartSSO(c.Request.Context(), &authpb.StartSSORequest{Email: email, Binding: binding})
+	if err != nil {
+		ssoRPCError(c, err, "Failed to start single sign-on")
+		return "", false
+	}
+	return resp.AuthorizationUrl, true
+}
+
+// CompleteSSO redeems the code and state the identity provider sent back
+// to the redirect page, in the browser that started the login, and
+// answers like Login. Users whose provider groups map to roles in the
+// connection's organization are made members with them.
+func (gw *Gateway) CompleteSSO(c *gin.Context) {
+	var req openapi.CompleteSSOJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Used up either way, like the state
+	binding, _ := c.Cookie(ssoBindingCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoBindingCookie, "", -1, ssoBindingPath, "", gw.config.Auth.CookieSessions.Secure, true)
+
+	resp, err := gw.AuthClient.CompleteSSO(c.Request.Context(), &authpb.CompleteSSORequest{
+		State:     req.State,
+		Code:      req.Code,
+		ClientIp:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+		Country:   gw.clientCountry(c),
+		Binding:   binding,
+	})
+	if err != nil {
+		ssoRPCError(c, err, "Failed to sign in")
+		return
+	}
+
+	if resp.OrgId != "" {
+		// The user is signed in regardless; the roles are granted again at
+		// their next sign-in
+		if err := gw.Orgs.Provision(c.Request.Context(), resp.OrgId, resp.User.GetId(), resp.OrgRoles); err != nil {
+			log.Printf("Failed to grant organization %s roles to user %s: %v", resp.OrgId, resp.User.GetId(), err)
+		}
+	}
+	gw.signedIn(c, http.StatusOK, resp)
+}
+
+// ssoRPCError maps the errors of the public single sign-on RPCs.
+func ssoRPCError(c *gin.Context, err error, message string) {
+	switch status.Code(err) {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": status.Convert(err).Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": status.Convert(err).Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": status.Convert(err).Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Identity provider unavailable"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// ssoConnectionRequest is the body of CreateOrgSSOConnection. Role
+// mappings name organization roles below owner.
+type ssoConnectionRequest struct {
+	Domain       string              `json:"domain" binding:"required"`
+	Protocol     string              `json:"protocol" binding:"omitempty,oneof=oidc"`
+	Issuer       string              `json:"issuer" binding:"required,url"`
+	ClientID     string              `json:"client_id" binding:"required"`
+	ClientSecret string              `json:"client_secret"`
+	GroupsClaim  string              `json:"groups_claim"`
+	RoleMappings map[string]org.Role `json:"role_mappings"`
+}
+
+// CreateOrgSSOConnection configures the organization's identity provider
+// for its email domain; only its admins may. Users of the domain then sign
+// in through the provider and join the organization with the roles their
+// groups map to.
+func (gw *Gateway) CreateOrgSSOConnection(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+	var req ssoConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mappings := make(map[string]string, len(req.RoleMappings))
+	for group, role := range req.RoleMappings {
+		if !role.Valid() || role == org.RoleOwner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role mappings must name the viewer, member or admin role"})
+			return
+		}
+		mappings[group] = string(role)
+	}
+
+	resp, err := gw.authClientFor(c).CreateSSOConnection(c.Request.Context(), &authpb.CreateSSOConnectionRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Connection: &authpb.SSOConnection{
+			OrgId:        membership.ID,
+			Organization: membership.Name,
+			Domain:       req.Domain,
+			Protocol:     req.Protocol,
+			Issuer:       req.Issuer,
+			ClientId:     req.ClientID,
+			GroupsClaim:  req.GroupsClaim,
+			RoleMappings: mappings,
+		},
+		ClientSecret: req.ClientSecret,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to create connection")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Connection)
+}
+
+// ListOrgSSOConnections lists the organization's identity providers to its
+// admins; client secrets are never returned.
+func (gw *Gateway) ListOrgSSOConnections(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+	gw.listSSOConnections(c, membership.ID)
+}
+
+// DeleteOrgSSOConnection removes one of the organization's identity
+// providers. Users it signed in keep their accounts and memberships.
+func (gw *Gateway) DeleteOrgSSOConnection(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+	gw.deleteSSOConnection(c, membership.ID, c.Param("connection_id"))
+}
+
+// ListSSOConnections lists the identity providers of every organization,
+// for staff.
+func (gw *Gateway) ListSSOConnections(c *gin.Context) {
+	gw.listSSOConnections(c, "")
+}
+
+// DeleteSSOConnection removes any organization's identity provider, for
+// staff.
+func (gw *Gateway) DeleteSSOConnection(c *gin.Context) {
+	gw.deleteSSOConnection(c, "", c.Param("id"))
+}
+
+func (gw *Gateway) listSSOConnections(c *gin.Context, orgID string) {
+	resp, err := gw.authClientFor(c).ListSSOConnections(c.Request.Context(), &authpb.ListSSOConnectionsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		OrgId:       orgID,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to list connections")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"connections": resp.Connections})
+}
+
+func (gw *Gateway) deleteSSOConnection(c *gin.Context, orgID, id string) {
+	_, err := gw.authClientFor(c).DeleteSSOConnection(c.Request.Context(), &authpb.DeleteSSOConnectionRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		Id:          id,
+		OrgId:       orgID,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to delete connection")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+// internal/gateway/stream.go
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/hub"
+)
+
+// userTopic carries the events of a single user, e.g. bot status changes.
+func userTopic(userID string) string {
+	return "user:" + userID
+}
+
+// botStatusEvent is the stream payload of a bot status change. A typed
+// struct marshals without the intermediate map and reflection of gin.H.
+type botStatusEvent struct {
+	BotID      string    `json:"bot_id"`
+	Status     string    `json:"status"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// startStreams feeds domain events from NATS into the streaming hub.
+func (gw *Gateway) startStreams() error {
+	sub, err := events.Subscribe(gw.nats, func() *eventspb.BotStatusChanged { return &eventspb.BotStatusChanged{} },
+		func(envelope *eventspb.Envelope, event *eventspb.BotStatusChanged) {
+			data, err := json.Marshal(botStatusEvent{
+				BotID:      event.BotId,
+				Status:     event.Status,
+				OccurredAt: envelope.OccurredAt.AsTime(),
+			})
+			if err != nil {
+				return
+			}
+			gw.stream.Publish(hub.Message{
+				Topic:  userTopic(event.UserId),
+				Type:   "bot_status",
+				Policy: hub.Reliable,
+				Data:   data,
+			})
+		})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to bot events: %w", err)
+	}
+	gw.streamSubs = append(gw.streamSubs, sub)
+	return nil
+}
+
+// sseSender writes hub messages as server-sent events. Each event is
+// assembled in buf, which is reused across sends, and written at once.
+type sseSender struct {
+	writer     gin.ResponseWriter
+	controller *http.ResponseController
+	buf        []byte
+}
+
+func (s *sseSender) Send(ctx context.Context, msg hub.Message) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		s.controller.SetWriteDeadline(deadline)
+		defer s.controller.SetWriteDeadline(time.Time{})
+	}
+
+	s.buf = append(s.buf[:0], "event: "...)
+	s.buf = append(s.buf, msg.Type...)
+	s.buf = append(s.buf, "\ndata: "...)
+	s.buf = append(s.buf, msg.Data...)
+	s.buf = append(s.buf, "\n\n"...)
+	if _, err := s.writer.Write(s.buf); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	s.writer.Flush()
+	return nil
+}
+
+// Stream pushes the user's events as server-sent events. Clients that
+// cannot keep up are disconnected with a slow_client event and should
+// reconnect and reload state.
+func (gw *Gateway) Stream(c *gin.Context) {
+	drain, done, ok := gw.Drainer.TrackStream()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is draining", "reconnect": gw.Drainer.Hint()})
+		return
+	}
+	defer done()
+
+	client := gw.stream.Connect(userTopic(c.GetString("user_id")))
+	defer gw.stream.Disconnect(client)
+
+	sender := &sseSender{writer: c.Writer, controller: http.NewResponseController(c.Writer), buf: make([]byte, 0, 512)}
+	// Streams outlive the server's write timeout; sends set their own
+	sender.controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	gw.runStream(ctx, cancel, client, sender, drain)
+}
+
+// runStream runs a hub client until it stops or the gateway drains, then
+// tells the peer why the stream ends.
+func (gw *Gateway) runStream(ctx context.Context, cancel context.CancelFunc, client *hub.Client, sender hub.Sender, drain <-chan struct{}) {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- client.Run(ctx, sender)
+	}()
+
+	select {
+	case err := <-errc:
+		if errors.Is(err, hub.ErrSlowClient) {
+			gw.sendControl(sender, "slow_client", gin.H{"error": err.Error()})
+		}
+	case <-drain:
+		cancel()
+		<-errc
+		gw.sendControl(sender, "reconnect", gw.Drainer.Hint())
+	}
+}
+
+// sendControl writes a final control event once the hub client stopped.
+func (gw *Gateway) sendControl(sender hub.Sender, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gw.config.Streaming.WriteTimeout)
+	defer cancel()
+	sender.Send(ctx, hub.Message{Type: event, Data: data})
+}
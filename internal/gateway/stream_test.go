@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/hub"
+)
+
+func newSSESender(w http.ResponseWriter) *sseSender {
+	c, _ := gin.CreateTestContext(w)
+	return &sseSender{writer: c.Writer, controller: http.NewResponseController(c.Writer), buf: make([]byte, 0, 512)}
+}
+
+func TestSSESender_Send(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sender := newSSESender(recorder)
+
+	require.NoError(t, sender.Send(context.Background(), hub.Message{Type: "bot_status", Data: []byte(`{"bot_id":"b-1"}`)}))
+	require.NoError(t, sender.Send(context.Background(), hub.Message{Type: "slow_client", Data: []byte(`{}`)}))
+	assert.Equal(t, "event: bot_status\ndata: {\"bot_id\":\"b-1\"}\n\nevent: slow_client\ndata: {}\n\n", recorder.Body.String())
+	assert.True(t, recorder.Flushed)
+}
+
+// discardWriter is a flushable response that keeps nothing.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+func (w *discardWriter) Flush()                      {}
+
+func BenchmarkSSESender_Send(b *testing.B) {
+	sender := newSSESender(&discardWriter{header: make(http.Header)})
+	msg := hub.Message{Type: "bot_status", Data: []byte(`{"bot_id":"bot-1","status":"running"}`)}
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := sender.Send(ctx, msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// internal/gateway/tags.go
+package gateway
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/internal/tags"
+)
+
+var errFilterResource = errors.New("saved filter belongs to a different resource")
+
+type setTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type savedFilterRequest struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	Resource string   `json:"resource" binding:"required,oneof=bot strategy"`
+	Tags     []string `json:"tags" binding:"required"`
+	Match    string   `json:"match"`
+}
+
+// tagFilter reads the tag filter of a list request: either a saved filter
+// (?filter=<id>) or ad-hoc tags (?tags=a,b&match=all|any).
+func (gw *Gateway) tagFilter(c *gin.Context, resource string) (tags.Filter, error) {
+	if id := c.Query("filter"); id != "" {
+		saved, err := gw.tags.GetFilter(c.Request.Context(), c.GetString("user_id"), id)
+		if err != nil {
+			return tags.Filter{}, err
+		}
+		if saved.Resource != resource {
+			return tags.Filter{}, errFilterResource
+		}
+		return saved.Filter(), nil
+	}
+
+	var raw []string
+	if v := c.Query("tags"); v != "" {
+		raw = strings.Split(v, ",")
+	}
+	return tags.NewFilter(raw, c.Query("match"))
+}
+
+func (gw *Gateway) SetBotTags(c *gin.Context) {
+	var req setTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	normalized, err := tags.Normalize(req.Tags)
+	if err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	if err := gw.bots.SetTags(c.Request.Context(), workspace(c), c.Param("id"), normalized); err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": normalized})
+}
+
+func (gw *Gateway) SetStrategyTags(c *gin.Context) {
+	var req setTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	normalized, err := tags.Normalize(req.Tags)
+	if err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	if err := gw.strategies.SetTags(c.Request.Context(), workspace(c), c.Param("id"), normalized); err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": normalized})
+}
+
+func (gw *Gateway) ListSavedFilters(c *gin.Context) {
+	filters, err := gw.tags.ListFilters(c.Request.Context(), c.GetString("user_id"), c.Query("resource"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list filters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filters": filters})
+}
+
+func (gw *Gateway) CreateSavedFilter(c *gin.Context) {
+	var req savedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	filter, err := tags.NewFilter(req.Tags, req.Match)
+	if err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	saved := &tags.SavedFilter{
+		ID:       uuid.New().String(),
+		UserID:   c.GetString("user_id"),
+		Resource: req.Resource,
+		Name:     req.Name,
+		Tags:     filter.Tags,
+		Match:    filter.Match,
+	}
+	if err := gw.tags.CreateFilter(c.Request.Context(), saved); err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+func (gw *Gateway) DeleteSavedFilter(c *gin.Context) {
+	if err := gw.tags.DeleteFilter(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		gw.tagsError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// tagsError answers with the message of the sentinel err matches, never
+// err itself, which may carry storage details or echo the request.
+func (gw *Gateway) tagsError(c *gin.Context, err error) {
+	for _, known := range []error{tags.ErrInvalidTag, tags.ErrTooManyTags, tags.ErrInvalidMatch, errFilterResource} {
+		if errors.Is(err, known) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": known.Error()})
+			return
+		}
+	}
+
+	switch {
+	case errors.Is(err, tags.ErrFilterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+	case errors.Is(err, bot.ErrBotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bot not found"})
+	case errors.Is(err, strategy.ErrStrategyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategy not found"})
+	case errors.Is(err, tags.ErrFilterExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "A saved filter with this name already exists"})
+	default:
+		log.Printf("Failed to handle tags of user %s: %v", c.GetString("user_id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process tags"})
+	}
+}
@@ -0,0 +1,90 @@
+// internal/gateway/trash.go
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+const (
+	trashTypeBot      = "bot"
+	trashTypeStrategy = "strategy"
+)
+
+type trashItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the retention job permanently deletes the item
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// ListTrash returns the workspace's deleted bots and strategies, most
+// recently deleted first.
+func (gw *Gateway) ListTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	owner := workspace(c)
+	ttl := gw.config.Retention.TrashTTL
+
+	bots, err := gw.bots.ListDeleted(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash"})
+		return
+	}
+	strategies, err := gw.strategies.ListDeleted(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash"})
+		return
+	}
+
+	items := make([]trashItem, 0, len(bots)+len(strategies))
+	for _, b := range bots {
+		deletedAt := b.DeletedAt.Time
+		items = append(items, trashItem{ID: b.ID, Type: trashTypeBot, Name: b.Name, DeletedAt: deletedAt, PurgeAt: deletedAt.Add(ttl)})
+	}
+	for _, s := range strategies {
+		deletedAt := s.DeletedAt.Time
+		items = append(items, trashItem{ID: s.ID, Type: trashTypeStrategy, Name: s.Name, DeletedAt: deletedAt, PurgeAt: deletedAt.Add(ttl)})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// RestoreTrash restores a deleted bot or strategy. Bots come back stopped.
+func (gw *Gateway) RestoreTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	owner := workspace(c)
+	id := c.Param("id")
+
+	err := gw.bots.Restore(ctx, owner, id)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{"id": id, "type": trashTypeBot})
+		return
+	}
+	if !errors.Is(err, bot.ErrBotNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+
+	err = gw.strategies.Restore(ctx, owner, id)
+	if errors.Is(err, strategy.ErrStrategyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "type": trashTypeStrategy})
+}
@@ -0,0 +1,38 @@
+// internal/gateway/usage.go
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUsageHours = 24
+	usageTopEndpoints = 10
+)
+
+// GetAPIUsage reports the user's request counts, error rate and rate-limit
+// hits per hour over the last ?hours (default 24), with the busiest
+// endpoints. The window is capped by the metering retention.
+func (gw *Gateway) GetAPIUsage(c *gin.Context) {
+	maxHours := int(gw.config.Metering.Retention / time.Hour)
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", strconv.Itoa(defaultUsageHours)))
+	if err != nil || hours < 1 || hours > maxHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("hours must be between 1 and %d", maxHours)})
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-time.Duration(hours-1) * time.Hour)
+	report, err := gw.Metering.Usage(c.Request.Context(), c.GetString("user_id"), from, to, usageTopEndpoints)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,91 @@
+// internal/gateway/users.go
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+type setUserPlanRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
+
+// DeactivateUser disables an account and signs it out everywhere. The auth
+// service checks the caller's permission again.
+func (gw *Gateway) DeactivateUser(c *gin.Context) {
+	gw.setUserActive(c, false)
+}
+
+// ReactivateUser enables a deactivated account again. Its sessions stay
+// signed out.
+func (gw *Gateway) ReactivateUser(c *gin.Context) {
+	gw.setUserActive(c, true)
+}
+
+func (gw *Gateway) setUserActive(c *gin.Context, active bool) {
+	resp, err := gw.authClientFor(c).SetUserActive(c.Request.Context(), &authpb.SetUserActiveRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      c.Param("id"),
+		Active:      active,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to update user")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+// SetUserPlan moves a user to another subscription plan. It applies to
+// their next request.
+func (gw *Gateway) SetUserPlan(c *gin.Context) {
+	var req setUserPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := gw.authClientFor(c).SetUserPlan(c.Request.Context(), &authpb.SetUserPlanRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      c.Param("id"),
+		Plan:        req.Plan,
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to set plan")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.User)
+}
+
+// ForcePasswordReset signs a user out everywhere and emails them a reset
+// link; their password stops working until they used it.
+func (gw *Gateway) ForcePasswordReset(c *gin.Context) {
+	resp, err := gw.authClientFor(c).ForcePasswordReset(c.Request.Context(), &authpb.ForcePasswordResetRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      c.Param("id"),
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to force password reset")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": resp.Message})
+}
+
+// ListUserSessions lists another user's signed-in devices for staff.
+func (gw *Gateway) ListUserSessions(c *gin.Context) {
+	resp, err := gw.authClientFor(c).ListUserSessions(c.Request.Context(), &authpb.ListUserSessionsRequest{
+		AccessToken: strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		UserId:      c.Param("id"),
+	})
+	if err != nil {
+		adminRPCError(c, err, "Failed to list sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": resp.Sessions})
+}
@@ -0,0 +1,64 @@
+// internal/gateway/warmup.go
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/rpc"
+	"github.com/tradingbothub/platform/internal/warmup"
+)
+
+// warmUp starts the gateway's warm-up tasks. /health reports the gateway
+// warming until the backends every request needs are reachable; the
+// backtest connection and the candle cache only speed up their routes.
+func (gw *Gateway) warmUp(cfg *config.Config) {
+	gw.Warmer = warmup.New(cfg.Warmup)
+
+	// gRPC connections dial lazily, on the first call
+	gw.Warmer.Register(warmup.Task{
+		Name: "auth-service",
+		Run: func(ctx context.Context) error {
+			return rpc.Connect(ctx, gw.authConn)
+		},
+	})
+	gw.Warmer.Register(warmup.Task{
+		Name:     "backtest-service",
+		Optional: true,
+		Run: func(ctx context.Context) error {
+			return rpc.Connect(ctx, gw.backtestConn)
+		},
+	})
+	gw.Warmer.Register(warmup.Postgres(gw.db))
+	gw.Warmer.Register(warmup.Redis(gw.redis))
+	gw.Warmer.Register(warmup.Task{
+		Name:     "candle-cache",
+		Optional: true,
+		Run:      gw.primeCandles,
+	})
+
+	gw.Warmer.Start(context.Background())
+}
+
+// primeCandles reads the default chart of every configured symbol into the
+// candle cache.
+func (gw *Gateway) primeCandles(ctx context.Context) error {
+	interval, err := marketdata.ParseInterval("1h")
+	if err != nil {
+		return err
+	}
+	to := time.Now()
+	from := marketdata.BucketsBefore(to, interval, time.UTC, defaultChartWidth-1)
+
+	for name, exchangeCfg := range gw.config.Exchanges {
+		for _, symbol := range exchangeCfg.Symbols {
+			if _, err := gw.loadCandles(ctx, name, symbol, interval, time.UTC, from, to); err != nil {
+				return fmt.Errorf("failed to load %s %s candles: %w", name, symbol, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// internal/gateway/websocket.go
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/hub"
+	"github.com/tradingbothub/platform/internal/middleware"
+	"golang.org/x/net/websocket"
+)
+
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+// wsSender writes hub messages as WebSocket text frames holding
+// {"type": ..., "data": ...}. Each frame is assembled in buf, which is
+// reused across sends.
+type wsSender struct {
+	conn *websocket.Conn
+	buf  []byte
+}
+
+func (s *wsSender) Send(ctx context.Context, msg hub.Message) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+		defer s.conn.SetWriteDeadline(time.Time{})
+	}
+
+	s.buf = append(s.buf[:0], `{"type":`...)
+	s.buf = strconv.AppendQuote(s.buf, msg.Type)
+	s.buf = append(s.buf, `,"data":`...)
+	s.buf = append(s.buf, msg.Data...)
+	s.buf = append(s.buf, '}')
+	if _, err := s.conn.Write(s.buf); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// checkStreamOrigin refuses WebSocket handshakes from browsers on origins
+// CORS would not admit; the session cookie would otherwise let any site
+// read the user's stream. Clients that send no Origin are not browsers.
+func checkStreamOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if !slices.Contains(middleware.AllowedOrigins, origin) {
+		return errOriginNotAllowed
+	}
+	return nil
+}
+
+// StreamWebSocket is Stream over a WebSocket, for clients that prefer it
+// to server-sent events. Events arrive as text frames with the same types
+// and payloads; slow_client and reconnect are the last frame before the
+// server closes the connection. Messages from the client are ignored.
+func (gw *Gateway) StreamWebSocket(c *gin.Context) {
+	drain, done, ok := gw.Drainer.TrackStream()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is draining", "reconnect": gw.Drainer.Hint()})
+		return
+	}
+	defer done()
+
+	userID := c.GetString("user_id")
+	server := websocket.Server{
+		Handshake: checkStreamOrigin,
+		Handler: func(conn *websocket.Conn) {
+			// Streams outlive the server's timeouts, which still apply
+			// to the hijacked connection; sends set their own
+			conn.SetDeadline(time.Time{})
+			conn.PayloadType = websocket.TextFrame
+
+			client := gw.stream.Connect(userTopic(userID))
+			defer gw.stream.Disconnect(client)
+
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+
+			// Reading handles pings and notices the peer closing
+			go func() {
+				io.Copy(io.Discard, conn)
+				cancel()
+			}()
+
+			gw.runStream(ctx, cancel, client, &wsSender{conn: conn, buf: make([]byte, 0, 512)}, drain)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
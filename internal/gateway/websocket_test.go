@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/hub"
+	"golang.org/x/net/websocket"
+)
+
+func TestWSSender_Frames(t *testing.T) {
+	server := httptest.NewServer(websocket.Server{
+		Handshake: checkStreamOrigin,
+		Handler: func(conn *websocket.Conn) {
+			sender := &wsSender{conn: conn}
+			sender.Send(context.Background(), hub.Message{Type: "bot_status", Data: []byte(`{"bot_id":"b-1"}`)})
+			sender.Send(context.Background(), hub.Message{Type: "slow_client", Data: []byte(`{}`)})
+		},
+	})
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, err := websocket.Dial(url, "", "http://localhost:3000")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var frame string
+	require.NoError(t, websocket.Message.Receive(conn, &frame))
+	assert.JSONEq(t, `{"type":"bot_status","data":{"bot_id":"b-1"}}`, frame)
+	require.NoError(t, websocket.Message.Receive(conn, &frame))
+	assert.JSONEq(t, `{"type":"slow_client","data":{}}`, frame)
+
+	// Browsers on other sites are refused before the upgrade
+	_, err = websocket.Dial(url, "", "https://evil.example")
+	assert.Error(t, err)
+}
+
+func TestCheckStreamOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"https://tradingbothub.com", true},
+		{"https://tradingbothub.com.evil.example", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/stream/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		assert.Equal(t, tt.allowed, checkStreamOrigin(nil, r) == nil, tt.origin)
+	}
+}
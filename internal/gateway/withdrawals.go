@@ -0,0 +1,101 @@
+// internal/gateway/withdrawals.go
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/approval"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/openapi"
+)
+
+// ListWithdrawalAddresses lists the organization's withdrawal addresses to
+// any of its members.
+func (gw *Gateway) ListWithdrawalAddresses(c *gin.Context) {
+	ctx := c.Request.Context()
+	membership, err := gw.Orgs.MemberOf(ctx, c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		gw.orgError(c, err)
+		return
+	}
+
+	addresses, err := gw.withdrawals.List(ctx, membership.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list withdrawal addresses"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// AddWithdrawalAddress asks the organization's other admins to approve a
+// new withdrawal address.
+func (gw *Gateway) AddWithdrawalAddress(c *gin.Context) {
+	var req openapi.AddWithdrawalAddressJSONBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+	if !gw.checkKeyExchange(c, req.Exchange, false) {
+		return
+	}
+
+	address := exchange.WithdrawalAddress{
+		OrgID:    membership.ID,
+		Exchange: req.Exchange,
+		Asset:    strings.ToUpper(req.Asset),
+		Address:  req.Address,
+		Label:    req.Label,
+	}
+	summary := fmt.Sprintf("Add %s withdrawal address %s on %s", address.Asset, address.Address, address.Exchange)
+	approvalReq, err := gw.approvals.Submit(c.Request.Context(), membership.ID, c.GetString("user_id"), approval.ActionAddWithdrawalAddress, summary, address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request approval"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Adding a withdrawal address requires approval by another admin",
+		"approval": approvalReq,
+	})
+}
+
+// addWithdrawalAddress executes an approved withdrawal address.
+func (gw *Gateway) addWithdrawalAddress(ctx context.Context, req *approval.Request) error {
+	var address exchange.WithdrawalAddress
+	if err := json.Unmarshal(req.Payload, &address); err != nil {
+		return err
+	}
+	address.OrgID = req.OrganizationID
+	address.AddedBy, address.ApprovedBy = req.RequestedBy, req.DecidedBy
+	return gw.withdrawals.Add(ctx, &address)
+}
+
+// DeleteWithdrawalAddress removes one of the organization's withdrawal
+// addresses. Removing an address only narrows where funds may go, so it
+// takes no approval.
+func (gw *Gateway) DeleteWithdrawalAddress(c *gin.Context) {
+	membership, ok := gw.orgAdmin(c)
+	if !ok {
+		return
+	}
+
+	err := gw.withdrawals.Delete(c.Request.Context(), membership.ID, c.Param("address_id"))
+	if errors.Is(err, exchange.ErrWithdrawalAddressNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete withdrawal address"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Withdrawal address deleted"})
+}
@@ -0,0 +1,337 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrSlowClient is returned to a client that fell so far behind that a
+// message which must not be dropped no longer fit its buffer. The client
+// is disconnected and expected to reconnect and resync.
+var ErrSlowClient = errors.New("client too slow")
+
+// ErrHubClosed is returned to clients still connected when the hub closes.
+var ErrHubClosed = errors.New("hub closed")
+
+// Policy decides what happens to a message when a client cannot keep up.
+type Policy int
+
+const (
+	// Reliable messages are never dropped, e.g. fills. A client whose
+	// buffer is full is disconnected instead.
+	Reliable Policy = iota
+	// BestEffort messages are dropped when the client's buffer is full.
+	BestEffort
+	// Conflate keeps only the latest message per key, e.g. tickers. They
+	// never take up buffer space.
+	Conflate
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Reliable:
+		return "reliable"
+	case BestEffort:
+		return "best_effort"
+	case Conflate:
+		return "conflate"
+	}
+	return "unknown"
+}
+
+type Message struct {
+	Topic string
+	// Type names the message for the client, e.g. the SSE event name
+	Type string
+	// Key identifies conflatable messages within a topic, e.g. the symbol
+	Key    string
+	Policy Policy
+	Data   []byte
+}
+
+var (
+	connectedClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hub_clients",
+		Help: "Number of clients connected to a streaming hub.",
+	}, []string{"hub"})
+
+	droppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hub_messages_dropped_total",
+		Help: "Best-effort messages dropped because a client's buffer was full.",
+	}, []string{"hub", "topic"})
+
+	conflatedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hub_messages_conflated_total",
+		Help: "Messages replaced by a newer one before the client received them.",
+	}, []string{"hub", "topic"})
+
+	slowClients = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hub_slow_clients_total",
+		Help: "Clients disconnected for not keeping up.",
+	}, []string{"hub", "reason"})
+)
+
+type Config struct {
+	// BufferSize is the number of reliable and best-effort messages a
+	// client may have pending
+	BufferSize int
+	// WriteTimeout bounds a single write to the client; a client that
+	// takes longer is considered slow
+	WriteTimeout time.Duration
+}
+
+// Sender writes a message to the client's transport, e.g. a WebSocket or
+// an SSE response.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Hub fans messages out to subscribed clients without ever blocking the
+// publisher on a slow client.
+type Hub struct {
+	name   string
+	config Config
+	mutex  sync.RWMutex
+	topics map[string]map[*Client]struct{}
+	closed bool
+}
+
+func New(name string, config Config) *Hub {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 256
+	}
+	return &Hub{
+		name:   name,
+		config: config,
+		topics: make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Connect registers a client subscribed to topics. The caller must run
+// the client and call Disconnect when done.
+func (h *Hub) Connect(topics ...string) *Client {
+	client := &Client{
+		hub:       h,
+		topics:    topics,
+		conflated: make(map[conflationKey]Message),
+		notify:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		client.close(ErrHubClosed)
+		return client
+	}
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*Client]struct{})
+		}
+		h.topics[topic][client] = struct{}{}
+	}
+	connectedClients.WithLabelValues(h.name).Inc()
+	return client
+}
+
+func (h *Hub) Disconnect(client *Client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.remove(client)
+}
+
+// remove unsubscribes the client. Callers must hold the mutex.
+func (h *Hub) remove(client *Client) {
+	removed := false
+	for _, topic := range client.topics {
+		subscribers := h.topics[topic]
+		if _, ok := subscribers[client]; !ok {
+			continue
+		}
+		removed = true
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+	if removed {
+		connectedClients.WithLabelValues(h.name).Dec()
+	}
+	client.close(nil)
+}
+
+// Publish hands the message to every subscriber of its topic.
+func (h *Hub) Publish(msg Message) {
+	var slow []*Client
+
+	h.mutex.RLock()
+	for client := range h.topics[msg.Topic] {
+		if !client.enqueue(msg) {
+			slow = append(slow, client)
+		}
+	}
+	h.mutex.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, client := range slow {
+		slowClients.WithLabelValues(h.name, "buffer_full").Inc()
+		client.close(ErrSlowClient)
+		h.remove(client)
+	}
+}
+
+// Close disconnects every client.
+func (h *Hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	for _, subscribers := range h.topics {
+		for client := range subscribers {
+			client.close(ErrHubClosed)
+			h.remove(client)
+		}
+	}
+}
+
+type conflationKey struct {
+	topic string
+	key   string
+}
+
+// Client is one connection's view of the hub: a bounded queue for
+// reliable and best-effort messages plus the latest conflated message per
+// key. Conflated messages are delivered after the queued ones of the same
+// flush, so ordering across policies is not preserved.
+type Client struct {
+	hub    *Hub
+	topics []string
+
+	mutex     sync.Mutex
+	queue     []Message
+	conflated map[conflationKey]Message
+	order     []conflationKey // first-seen order of pending conflated keys
+	notify    chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// enqueue buffers msg and reports false if the client must be dropped.
+func (c *Client) enqueue(msg Message) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch msg.Policy {
+	case Conflate:
+		key := conflationKey{topic: msg.Topic, key: msg.Key}
+		if _, pending := c.conflated[key]; pending {
+			conflatedMessages.WithLabelValues(c.hub.name, msg.Topic).Inc()
+		} else {
+			c.order = append(c.order, key)
+		}
+		c.conflated[key] = msg
+	case BestEffort:
+		if len(c.queue) >= c.hub.config.BufferSize {
+			droppedMessages.WithLabelValues(c.hub.name, msg.Topic).Inc()
+			return true
+		}
+		c.queue = append(c.queue, msg)
+	default:
+		if len(c.queue) >= c.hub.config.BufferSize {
+			return false
+		}
+		c.queue = append(c.queue, msg)
+	}
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// take moves everything pending into batch. The queue keeps its backing
+// array and the caller reuses batch, so a client that keeps up does not
+// allocate per message.
+func (c *Client) take(batch []Message) []Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	batch = append(batch, c.queue...)
+	clear(c.queue)
+	c.queue = c.queue[:0]
+	for _, key := range c.order {
+		batch = append(batch, c.conflated[key])
+		delete(c.conflated, key)
+	}
+	c.order = c.order[:0]
+	return batch
+}
+
+func (c *Client) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// Done is closed when the hub disconnects the client.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Run writes pending messages to sender until ctx ends or the client is
+// disconnected. It returns ErrSlowClient for clients that could not keep
+// up.
+func (c *Client) Run(ctx context.Context, sender Sender) error {
+	var batch []Message
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return c.err
+		case <-c.notify:
+		}
+
+		batch = c.take(batch[:0])
+		for _, msg := range batch {
+			if err := c.send(ctx, sender, msg); err != nil {
+				return err
+			}
+		}
+		// Drop references to sent payloads before the batch is reused
+		clear(batch)
+	}
+}
+
+func (c *Client) send(ctx context.Context, sender Sender, msg Message) error {
+	if c.hub.config.WriteTimeout <= 0 {
+		return sender.Send(ctx, msg)
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, c.hub.config.WriteTimeout)
+	defer cancel()
+
+	err := sender.Send(writeCtx, msg)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		slowClients.WithLabelValues(c.hub.name, "write_timeout").Inc()
+		return ErrSlowClient
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package hub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func (r *recorder) Send(ctx context.Context, msg Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *recorder) len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.messages)
+}
+
+type counter struct{ n atomic.Int64 }
+
+func (c *counter) Send(ctx context.Context, msg Message) error {
+	c.n.Add(1)
+	return nil
+}
+
+func TestHub_Publish(t *testing.T) {
+	h := New("test", Config{BufferSize: 4})
+	defer h.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := h.Connect("user-1")
+	received := &recorder{}
+	go client.Run(ctx, received)
+
+	h.Publish(Message{Topic: "user-1", Type: "bot_status", Data: []byte(`{}`)})
+	h.Publish(Message{Topic: "user-2", Type: "bot_status", Data: []byte(`{}`)})
+	require.Eventually(t, func() bool { return received.len() == 1 }, time.Second, time.Millisecond)
+
+	// A reliable message that does not fit the buffer drops the client
+	stalled := h.Connect("user-3")
+	for i := 0; i < 5; i++ {
+		h.Publish(Message{Topic: "user-3", Type: "bot_status"})
+	}
+	<-stalled.Done()
+	assert.ErrorIs(t, stalled.Run(ctx, received), ErrSlowClient)
+}
+
+// Fan-out of one message to ten clients that keep up.
+func BenchmarkHub_Publish(b *testing.B) {
+	h := New("bench", Config{BufferSize: 1 << 16})
+	defer h.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const clients = 10
+	sent := &counter{}
+	for i := 0; i < clients; i++ {
+		client := h.Connect("prices")
+		go client.Run(ctx, sent)
+	}
+
+	msg := Message{Topic: "prices", Type: "price", Key: "BTCUSDT", Policy: BestEffort, Data: []byte(`{"symbol":"BTCUSDT","price":"100"}`)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Publish(msg)
+	}
+}
@@ -0,0 +1,71 @@
+package keyset
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page of a listing ordered by time, newest
+// first, with the ID breaking ties. Paging by (time, id) instead of
+// offsets keeps pages stable while new rows are being inserted: new rows
+// never shift the ones still to come.
+//
+// A cursor also records the listing and a digest of the filters it was
+// issued for. Replayed against another listing, its position would be
+// meaningless and the page would silently skip or repeat rows, so it is
+// rejected instead.
+type Cursor struct {
+	Listing string    `json:"h"`
+	Filters string    `json:"f"`
+	Time    time.Time `json:"t"`
+	ID      string    `json:"id"`
+}
+
+// Encode returns the cursor continuing after the row (t, id) of the
+// listing selected by filters.
+func Encode(listing string, filters []string, t time.Time, id string) string {
+	data, _ := json.Marshal(Cursor{Listing: listing, Filters: digest(filters), Time: t, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses s, returning ErrInvalidCursor unless it was issued for
+// the same listing and filters.
+func Decode(s, listing string, filters []string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Listing != listing || c.Filters != digest(filters) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Bound formats a time filter; the zero time, meaning unbounded, is empty.
+func Bound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// digest fingerprints what selects the rows of a listing. The page size
+// is left out, so clients may change it between pages.
+func digest(filters []string) string {
+	h := sha256.New()
+	for _, field := range filters {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
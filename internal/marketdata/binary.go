@@ -0,0 +1,103 @@
+package marketdata
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// CandlesMIME is the media type of the binary candle encoding. Clients opt
+// in with "Accept: application/vnd.tradingbothub.candles.v1"; everything
+// else gets JSON.
+const CandlesMIME = "application/vnd.tradingbothub.candles.v1"
+
+var (
+	candlesMagic = [4]byte{'C', 'N', 'D', 'L'}
+
+	ErrInvalidEncoding = errors.New("invalid binary candle encoding")
+)
+
+const candlesVersion = 1
+
+// EncodeCandles writes candles in a compact columnar layout, roughly 40%
+// of the size of the JSON form:
+//
+//	magic "CNDL" | version uint8 | count uvarint
+//	times:  first Unix ms as varint, then deltas as varints
+//	open, high, low, close, volume: count little-endian float64 each
+//
+// Time deltas are almost always the interval, so they take 2-4 bytes.
+func EncodeCandles(candles []Candle) []byte {
+	buf := make([]byte, 0, 16+len(candles)*44)
+	buf = append(buf, candlesMagic[:]...)
+	buf = append(buf, candlesVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(candles)))
+
+	var prev int64
+	for _, candle := range candles {
+		ms := candle.Time.UnixMilli()
+		buf = binary.AppendVarint(buf, ms-prev)
+		prev = ms
+	}
+
+	columns := []func(Candle) float64{
+		func(c Candle) float64 { return c.Open },
+		func(c Candle) float64 { return c.High },
+		func(c Candle) float64 { return c.Low },
+		func(c Candle) float64 { return c.Close },
+		func(c Candle) float64 { return c.Volume },
+	}
+	for _, column := range columns {
+		for _, candle := range candles {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(column(candle)))
+		}
+	}
+
+	return buf
+}
+
+// DecodeCandles reads the output of EncodeCandles. Times are UTC.
+func DecodeCandles(data []byte) ([]Candle, error) {
+	if len(data) < 5 || [4]byte(data[:4]) != candlesMagic || data[4] != candlesVersion {
+		return nil, ErrInvalidEncoding
+	}
+	data = data[5:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, ErrInvalidEncoding
+	}
+	data = data[n:]
+
+	candles := make([]Candle, count)
+	var ms int64
+	for i := range candles {
+		delta, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, ErrInvalidEncoding
+		}
+		data = data[n:]
+		ms += delta
+		candles[i].Time = time.UnixMilli(ms).UTC()
+	}
+
+	if uint64(len(data)) != count*5*8 {
+		return nil, ErrInvalidEncoding
+	}
+	columns := []func(*Candle) *float64{
+		func(c *Candle) *float64 { return &c.Open },
+		func(c *Candle) *float64 { return &c.High },
+		func(c *Candle) *float64 { return &c.Low },
+		func(c *Candle) *float64 { return &c.Close },
+		func(c *Candle) *float64 { return &c.Volume },
+	}
+	for _, column := range columns {
+		for i := range candles {
+			*column(&candles[i]) = math.Float64frombits(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		}
+	}
+
+	return candles, nil
+}
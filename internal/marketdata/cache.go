@@ -0,0 +1,181 @@
+package marketdata
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	candleCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketdata_candle_cache_requests_total",
+		Help: "Candle reads by how much of the closed range the cache served.",
+	}, []string{"result"})
+	candleCacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "marketdata_candle_cache_bytes",
+		Help: "Estimated memory held by cached candles.",
+	})
+	candleCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "marketdata_candle_cache_evictions_total",
+		Help: "Candle series evicted to stay within the memory limit.",
+	})
+)
+
+const candleSize = int64(unsafe.Sizeof(Candle{}))
+
+// CandleCache keeps recently read candle series in memory so repeated
+// backtests and previews over the same symbol and interval do not go back
+// to the store. Only closed candles are cached; the still-forming tail of
+// a range is always read through. Each series is held as one contiguous
+// range that grows as later reads extend it, and whole series are evicted
+// least recently used first once the memory limit is reached.
+type CandleCache struct {
+	store    CandleStore
+	maxBytes int64
+
+	mutex sync.Mutex
+	bytes int64
+	lru   *list.List
+	// series maps a series key to its element in lru
+	series map[string]*list.Element
+}
+
+type cachedSeries struct {
+	key      string
+	from, to time.Time
+	candles  []Candle
+}
+
+func NewCandleCache(store CandleStore, maxBytes int64) *CandleCache {
+	return &CandleCache{
+		store:    store,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		series:   make(map[string]*list.Element),
+	}
+}
+
+func (c *CandleCache) Candles(ctx context.Context, exchange, symbol string, interval Interval, from, to time.Time) ([]Candle, error) {
+	// A candle is closed once its whole interval has passed. Aligning the
+	// cutoff to the interval extends a cached series once per candle
+	// rather than on every read
+	closed := time.Now().Add(-interval.Duration).Truncate(interval.Duration)
+	cacheTo := to
+	if closed.Before(cacheTo) {
+		cacheTo = closed
+	}
+	if c.maxBytes <= 0 || interval.Calendar() || !from.Before(cacheTo) {
+		candleCacheRequests.WithLabelValues("bypass").Inc()
+		return c.store.Candles(ctx, exchange, symbol, interval, from, to)
+	}
+
+	key := exchange + "|" + symbol + "|" + interval.Name
+	candles, err := c.closedCandles(ctx, key, exchange, symbol, interval, from, cacheTo)
+	if err != nil {
+		return nil, err
+	}
+	if cacheTo.Before(to) {
+		tail, err := c.store.Candles(ctx, exchange, symbol, interval, cacheTo, to)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, tail...)
+	}
+	return candles, nil
+}
+
+// closedCandles returns a copy of the candles in [from, to), reading only
+// what the cached range of the series lacks.
+func (c *CandleCache) closedCandles(ctx context.Context, key, exchange, symbol string, interval Interval, from, to time.Time) ([]Candle, error) {
+	c.mutex.Lock()
+	var cached *cachedSeries
+	if elem, ok := c.series[key]; ok {
+		c.lru.MoveToFront(elem)
+		cached = elem.Value.(*cachedSeries)
+	}
+	// Only a range starting at or before from can be extended; anything
+	// else is replaced by a fresh read
+	usable := cached != nil && !cached.from.After(from) && !cached.to.Before(from)
+	var rangeFrom, rangeTo time.Time
+	var known []Candle
+	if usable {
+		rangeFrom, rangeTo, known = cached.from, cached.to, cached.candles
+	}
+	c.mutex.Unlock()
+
+	if usable {
+		if !rangeTo.Before(to) {
+			candleCacheRequests.WithLabelValues("hit").Inc()
+			return slice(known, from, to), nil
+		}
+
+		candleCacheRequests.WithLabelValues("partial").Inc()
+		tail, err := c.store.Candles(ctx, exchange, symbol, interval, rangeTo, to)
+		if err != nil {
+			return nil, err
+		}
+		merged := make([]Candle, 0, len(known)+len(tail))
+		merged = append(append(merged, known...), tail...)
+		c.put(&cachedSeries{key: key, from: rangeFrom, to: to, candles: merged})
+		return slice(merged, from, to), nil
+	}
+
+	candleCacheRequests.WithLabelValues("miss").Inc()
+	candles, err := c.store.Candles(ctx, exchange, symbol, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	c.put(&cachedSeries{key: key, from: from, to: to, candles: candles})
+	return slice(candles, from, to), nil
+}
+
+// put stores the series unless a concurrent read already cached a larger
+// range, then evicts until the cache fits its limit.
+func (c *CandleCache) put(s *cachedSeries) {
+	size := int64(len(s.candles)) * candleSize
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.series[s.key]; ok {
+		old := elem.Value.(*cachedSeries)
+		if !old.from.After(s.from) && !old.to.Before(s.to) {
+			return
+		}
+		c.bytes -= int64(len(old.candles)) * candleSize
+		elem.Value = s
+		c.lru.MoveToFront(elem)
+	} else {
+		c.series[s.key] = c.lru.PushFront(s)
+	}
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		oldest := c.lru.Back()
+		evicted := oldest.Value.(*cachedSeries)
+		c.lru.Remove(oldest)
+		delete(c.series, evicted.key)
+		c.bytes -= int64(len(evicted.candles)) * candleSize
+		candleCacheEvictions.Inc()
+	}
+	candleCacheBytes.Set(float64(c.bytes))
+}
+
+// slice copies the candles of a sorted series that fall in [from, to), so
+// callers cannot modify the cached data.
+func slice(candles []Candle, from, to time.Time) []Candle {
+	start := sort.Search(len(candles), func(i int) bool { return !candles[i].Time.Before(from) })
+	end := sort.Search(len(candles), func(i int) bool { return !candles[i].Time.Before(to) })
+	out := make([]Candle, end-start)
+	copy(out, candles[start:end])
+	return out
+}
@@ -0,0 +1,159 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidInterval = errors.New("invalid interval")
+
+type Candle struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// Interval is a candle width. Day and week intervals are calendar based and
+// follow the session time zone, including DST transitions.
+type Interval struct {
+	Name     string
+	Duration time.Duration
+	Days     int
+}
+
+func (i Interval) Calendar() bool {
+	return i.Days > 0
+}
+
+// ParseInterval accepts intervals such as "1m", "15m", "4h", "1d" and "1w".
+func ParseInterval(s string) (Interval, error) {
+	if len(s) < 2 {
+		return Interval{}, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+
+	// Only the canonical spelling is accepted, since the name keys caches
+	// and stored candles: no sign and no leading zeros
+	digits := s[:len(s)-1]
+	if digits[0] < '1' || digits[0] > '9' {
+		return Interval{}, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return Interval{}, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return Interval{}, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+	// Durations overflow after about 292 years
+	if n > int(math.MaxInt64/unit) {
+		return Interval{}, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+
+	interval := Interval{Name: s, Duration: time.Duration(n) * unit}
+	if unit >= 24*time.Hour {
+		interval.Days = int(unit/(24*time.Hour)) * n
+	}
+	return interval, nil
+}
+
+// BucketStart returns the start of the bucket containing t, with bucket
+// boundaries aligned to wall-clock time in loc. Daily buckets start at local
+// midnight and weekly buckets on Monday at local midnight.
+func BucketStart(t time.Time, interval Interval, loc *time.Location) time.Time {
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	if !interval.Calendar() {
+		elapsed := local.Sub(midnight)
+		return midnight.Add(elapsed - elapsed%interval.Duration)
+	}
+
+	if interval.Days%7 == 0 {
+		// Go weeks start on Sunday; sessions start on Monday
+		offset := (int(midnight.Weekday()) + 6) % 7
+		midnight = time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, loc)
+		interval.Days /= 7
+		if interval.Days == 1 {
+			return midnight
+		}
+		weeks := int(midnight.Sub(epochMonday(loc)).Hours()/24+0.5) / 7
+		return time.Date(midnight.Year(), midnight.Month(), midnight.Day()-7*(weeks%interval.Days), 0, 0, 0, 0, loc)
+	}
+
+	if interval.Days == 1 {
+		return midnight
+	}
+	days := int(midnight.Sub(time.Date(1970, 1, 1, 0, 0, 0, 0, loc)).Hours()/24 + 0.5)
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day()-days%interval.Days, 0, 0, 0, 0, loc)
+}
+
+// epochMonday is the first Monday of the Unix epoch in loc, used to align
+// multi-week buckets.
+func epochMonday(loc *time.Location) time.Time {
+	return time.Date(1970, 1, 5, 0, 0, 0, 0, loc)
+}
+
+// BucketsBefore returns the start of the bucket n buckets before the one
+// containing t.
+func BucketsBefore(t time.Time, interval Interval, loc *time.Location, n int) time.Time {
+	// Spans past the range of a Duration go back as far as time does
+	if n > 0 && interval.Duration > math.MaxInt64/time.Duration(n) {
+		return time.Time{}
+	}
+	start := BucketStart(t, interval, loc)
+	if interval.Calendar() {
+		return start.AddDate(0, 0, -interval.Days*n)
+	}
+	return start.Add(-interval.Duration * time.Duration(n))
+}
+
+// Aggregate rolls candles (sorted by time) up into wider buckets in the
+// given session time zone.
+func Aggregate(candles []Candle, interval Interval, loc *time.Location) []Candle {
+	var out []Candle
+
+	for _, candle := range candles {
+		start := BucketStart(candle.Time, interval, loc)
+
+		if n := len(out); n > 0 && out[n-1].Time.Equal(start) {
+			bucket := &out[n-1]
+			if candle.High > bucket.High {
+				bucket.High = candle.High
+			}
+			if candle.Low < bucket.Low {
+				bucket.Low = candle.Low
+			}
+			bucket.Close = candle.Close
+			bucket.Volume += candle.Volume
+			continue
+		}
+
+		out = append(out, Candle{
+			Time:   start,
+			Open:   candle.Open,
+			High:   candle.High,
+			Low:    candle.Low,
+			Close:  candle.Close,
+			Volume: candle.Volume,
+		})
+	}
+
+	return out
+}
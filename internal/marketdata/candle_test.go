@@ -0,0 +1,66 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketsBefore(t *testing.T) {
+	now := time.Date(2024, 3, 13, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval string
+		n        int
+		want     time.Time
+	}{
+		{"hours", "1h", 3, time.Date(2024, 3, 13, 7, 0, 0, 0, time.UTC)},
+		{"days", "1d", 2, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"weeks", "1w", 1, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"minute overflow", "5000000m", 4000, time.Time{}},
+		{"week overflow", "10000w", 4000, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, err := ParseInterval(tt.interval)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, BucketsBefore(now, interval, time.UTC, tt.n))
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		err      bool
+	}{
+		{interval: "1m", want: time.Minute},
+		{interval: "15m", want: 15 * time.Minute},
+		{interval: "4h", want: 4 * time.Hour},
+		{interval: "10d", want: 240 * time.Hour},
+		{interval: "0m", err: true},
+		{interval: "007m", err: true},
+		{interval: "+5m", err: true},
+		{interval: "-5m", err: true},
+		{interval: " 5m", err: true},
+		{interval: "5s", err: true},
+		{interval: "m", err: true},
+		{interval: "99999999999999999999m", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			interval, err := ParseInterval(tt.interval)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidInterval)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, interval.Duration)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package marketdata
+
+// Chart payloads are columnar: one array per field instead of one object per
+// bar, which roughly halves the JSON size and maps directly onto the typed
+// arrays charting libraries consume. Times are Unix milliseconds.
+
+type CandleColumns struct {
+	Time   []int64   `json:"t"`
+	Open   []float64 `json:"o"`
+	High   []float64 `json:"h"`
+	Low    []float64 `json:"l"`
+	Close  []float64 `json:"c"`
+	Volume []float64 `json:"v"`
+}
+
+func NewCandleColumns(candles []Candle) CandleColumns {
+	cols := CandleColumns{
+		Time:   make([]int64, len(candles)),
+		Open:   make([]float64, len(candles)),
+		High:   make([]float64, len(candles)),
+		Low:    make([]float64, len(candles)),
+		Close:  make([]float64, len(candles)),
+		Volume: make([]float64, len(candles)),
+	}
+	for i, candle := range candles {
+		cols.Time[i] = candle.Time.UnixMilli()
+		cols.Open[i] = candle.Open
+		cols.High[i] = candle.High
+		cols.Low[i] = candle.Low
+		cols.Close[i] = candle.Close
+		cols.Volume[i] = candle.Volume
+	}
+	return cols
+}
+
+type SeriesColumns struct {
+	Time  []int64   `json:"t"`
+	Value []float64 `json:"v"`
+}
+
+func NewSeriesColumns(points []Point) SeriesColumns {
+	cols := SeriesColumns{
+		Time:  make([]int64, len(points)),
+		Value: make([]float64, len(points)),
+	}
+	for i, p := range points {
+		cols.Time[i] = p.Time.UnixMilli()
+		cols.Value[i] = p.Value
+	}
+	return cols
+}
@@ -0,0 +1,119 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidIndicator = errors.New("invalid indicator")
+
+// MaxPeriod bounds the periods of indicators and strategies; a period of
+// closes is kept in memory for each.
+const MaxPeriod = 1000
+
+// Indicator is a closing-price overlay such as "sma:20".
+type Indicator struct {
+	Name   string
+	Period int
+}
+
+func (i Indicator) String() string {
+	return i.Name + ":" + strconv.Itoa(i.Period)
+}
+
+// ParseIndicators parses a comma-separated list like "sma:20,ema:50,rsi:14".
+func ParseIndicators(s string) ([]Indicator, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var indicators []Indicator
+	for _, spec := range strings.Split(s, ",") {
+		name, period, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		n, err := strconv.Atoi(period)
+		if !ok || err != nil || n <= 0 || n > MaxPeriod {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidIndicator, spec)
+		}
+
+		switch name {
+		case "sma", "ema", "rsi":
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidIndicator, spec)
+		}
+		indicators = append(indicators, Indicator{Name: name, Period: n})
+	}
+
+	return indicators, nil
+}
+
+// Compute evaluates the indicator over candles (sorted by time). The result
+// starts once enough candles are available for a full period.
+func (i Indicator) Compute(candles []Candle) []Point {
+	switch i.Name {
+	case "sma":
+		return sma(candles, i.Period)
+	case "ema":
+		return ema(candles, i.Period)
+	case "rsi":
+		return rsi(candles, i.Period)
+	}
+	return nil
+}
+
+func sma(candles []Candle, period int) []Point {
+	var out []Point
+	rolling := NewRollingSMA(period)
+	for _, candle := range candles {
+		if value, ok := rolling.Update(candle.Close); ok {
+			out = append(out, Point{Time: candle.Time, Value: value})
+		}
+	}
+	return out
+}
+
+func ema(candles []Candle, period int) []Point {
+	if len(candles) < period {
+		return nil
+	}
+
+	// Seed with the SMA of the first period
+	seed := sma(candles[:period], period)[0].Value
+	out := []Point{{Time: candles[period-1].Time, Value: seed}}
+
+	k := 2 / float64(period+1)
+	prev := seed
+	for _, candle := range candles[period:] {
+		prev = candle.Close*k + prev*(1-k)
+		out = append(out, Point{Time: candle.Time, Value: prev})
+	}
+	return out
+}
+
+// rsi uses Wilder's smoothing.
+func rsi(candles []Candle, period int) []Point {
+	var out []Point
+	rolling := NewRollingRSI(period)
+	for _, candle := range candles {
+		if value, ok := rolling.Update(candle.Close); ok {
+			out = append(out, Point{Time: candle.Time, Value: value})
+		}
+	}
+	return out
+}
+
+func rsiValue(gain, loss float64) float64 {
+	if loss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// Warmup is the number of candles needed before the first value.
+func (i Indicator) Warmup() int {
+	if i.Name == "rsi" {
+		return i.Period + 1
+	}
+	return i.Period
+}
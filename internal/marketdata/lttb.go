@@ -0,0 +1,87 @@
+package marketdata
+
+import "math"
+
+// LTTB downsamples a series (sorted by time) to at most threshold points
+// with the Largest-Triangle-Three-Buckets algorithm, which keeps the visual
+// shape of a line chart far better than taking every n-th point. The first
+// and last points are always kept.
+func LTTB(points []Point, threshold int) []Point {
+	if threshold >= len(points) || threshold < 3 {
+		return points
+	}
+
+	sampled := make([]Point, 0, threshold)
+	sampled = append(sampled, points[0])
+
+	// Buckets exclude the fixed first and last points
+	every := float64(len(points)-2) / float64(threshold-2)
+	a := 0
+
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket is the third triangle vertex
+		nextStart := int(float64(i+1)*every) + 1
+		nextEnd := int(float64(i+2)*every) + 1
+		if nextEnd > len(points) {
+			nextEnd = len(points)
+		}
+		var avgX, avgY float64
+		for _, p := range points[nextStart:nextEnd] {
+			avgX += float64(p.Time.UnixMilli())
+			avgY += p.Value
+		}
+		n := float64(nextEnd - nextStart)
+		avgX /= n
+		avgY /= n
+
+		start := int(float64(i)*every) + 1
+		end := int(float64(i+1)*every) + 1
+		ax, ay := float64(points[a].Time.UnixMilli()), points[a].Value
+
+		maxArea := -1.0
+		next := start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(points[j].Value-ay) - (ax-float64(points[j].Time.UnixMilli()))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+
+		sampled = append(sampled, points[next])
+		a = next
+	}
+
+	return append(sampled, points[len(points)-1])
+}
+
+// Downsample merges candles (sorted by time) into at most width candles.
+// OHLC bars cannot be picked like line points without losing highs and lows,
+// so consecutive candles are merged instead; each merged candle keeps the
+// time of its first member.
+func Downsample(candles []Candle, width int) []Candle {
+	if width <= 0 || len(candles) <= width {
+		return candles
+	}
+
+	out := make([]Candle, 0, width)
+	per := float64(len(candles)) / float64(width)
+
+	for i := 0; i < width; i++ {
+		group := candles[int(float64(i)*per):int(float64(i+1)*per)]
+		if len(group) == 0 {
+			continue
+		}
+
+		merged := group[0]
+		for _, candle := range group[1:] {
+			merged.High = math.Max(merged.High, candle.High)
+			merged.Low = math.Min(merged.Low, candle.Low)
+			merged.Close = candle.Close
+			merged.Volume += candle.Volume
+		}
+		out = append(out, merged)
+	}
+
+	return out
+}
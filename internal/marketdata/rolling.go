@@ -0,0 +1,77 @@
+package marketdata
+
+// RollingSMA is a simple moving average updated one close at a time in
+// constant time.
+type RollingSMA struct {
+	period int
+	closes []float64
+	next   int
+	count  int
+	sum    float64
+}
+
+func NewRollingSMA(period int) *RollingSMA {
+	return &RollingSMA{period: period, closes: make([]float64, period)}
+}
+
+// Update adds the next close and returns the average once a full period
+// has been seen.
+func (s *RollingSMA) Update(close float64) (float64, bool) {
+	s.sum += close
+	if s.count >= s.period {
+		s.sum -= s.closes[s.next]
+	}
+	s.closes[s.next] = close
+	s.next = (s.next + 1) % s.period
+	s.count++
+
+	if s.count < s.period {
+		return 0, false
+	}
+	return s.sum / float64(s.period), true
+}
+
+// RollingRSI is Wilder's RSI updated one close at a time in constant time.
+type RollingRSI struct {
+	period     int
+	changes    int
+	prev       float64
+	gain, loss float64
+}
+
+func NewRollingRSI(period int) *RollingRSI {
+	return &RollingRSI{period: period, changes: -1}
+}
+
+// Update adds the next close and returns the RSI once period changes have
+// been seen.
+func (r *RollingRSI) Update(close float64) (float64, bool) {
+	r.changes++
+	change := close - r.prev
+	r.prev = close
+	if r.changes == 0 {
+		return 0, false
+	}
+
+	up, down := 0.0, 0.0
+	if change > 0 {
+		up = change
+	} else {
+		down = -change
+	}
+
+	switch {
+	case r.changes < r.period:
+		r.gain += up
+		r.loss += down
+		return 0, false
+	case r.changes == r.period:
+		// The first average is a plain mean of the changes
+		r.gain = (r.gain + up) / float64(r.period)
+		r.loss = (r.loss + down) / float64(r.period)
+	default:
+		r.gain = (r.gain*float64(r.period-1) + up) / float64(r.period)
+		r.loss = (r.loss*float64(r.period-1) + down) / float64(r.period)
+	}
+	return rsiValue(r.gain, r.loss), true
+}
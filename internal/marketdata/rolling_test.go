@@ -0,0 +1,97 @@
+package marketdata
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func closes(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 100 + 10*math.Sin(float64(i)/7)
+	}
+	return values
+}
+
+func candlesOf(values []float64) []Candle {
+	candles := make([]Candle, len(values))
+	for i, value := range values {
+		candles[i] = Candle{Close: value}
+	}
+	return candles
+}
+
+// The rolling indicators agree with the batch ones on every candle.
+func TestRolling_MatchesBatch(t *testing.T) {
+	values := closes(200)
+	candles := candlesOf(values)
+
+	smaBatch := sma(candles, 20)
+	rolling := NewRollingSMA(20)
+	var smaRolling []float64
+	for _, value := range values {
+		if avg, ok := rolling.Update(value); ok {
+			smaRolling = append(smaRolling, avg)
+		}
+	}
+	assert.Len(t, smaRolling, len(smaBatch))
+	for i, point := range smaBatch {
+		assert.InDelta(t, point.Value, smaRolling[i], 1e-9)
+	}
+
+	rsiBatch := rsi(candles, 14)
+	rsiStream := NewRollingRSI(14)
+	var rsiRolling []float64
+	for _, value := range values {
+		if v, ok := rsiStream.Update(value); ok {
+			rsiRolling = append(rsiRolling, v)
+		}
+	}
+	assert.Len(t, rsiRolling, len(rsiBatch))
+	for i, point := range rsiBatch {
+		assert.InDelta(t, point.Value, rsiRolling[i], 1e-9)
+	}
+}
+
+// An update costs the same whatever the period; recomputing the window
+// grows with it.
+func BenchmarkRollingSMA_Update(b *testing.B) {
+	values := closes(4096)
+	for _, period := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("period=%d", period), func(b *testing.B) {
+			s := NewRollingSMA(period)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Update(values[i%len(values)])
+			}
+		})
+	}
+}
+
+func BenchmarkRollingRSI_Update(b *testing.B) {
+	values := closes(4096)
+	for _, period := range []int{14, 100, 1000} {
+		b.Run(fmt.Sprintf("period=%d", period), func(b *testing.B) {
+			r := NewRollingRSI(period)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Update(values[i%len(values)])
+			}
+		})
+	}
+}
+
+func BenchmarkSMA_Window(b *testing.B) {
+	candles := candlesOf(closes(4096))
+	for _, period := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("period=%d", period), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sma(candles[:period], period)
+			}
+		})
+	}
+}
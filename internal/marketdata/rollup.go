@@ -0,0 +1,22 @@
+package marketdata
+
+import "time"
+
+// Point is a single sample of a time series such as account equity or
+// realized PnL.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Rollup buckets points (sorted by time) into open/high/low/close candles
+// using the same session rules as market candles, so daily performance
+// lines up with the daily chart the user is looking at. Volume counts the
+// samples in each bucket.
+func Rollup(points []Point, interval Interval, loc *time.Location) []Candle {
+	candles := make([]Candle, len(points))
+	for i, p := range points {
+		candles[i] = Candle{Time: p.Time, Open: p.Value, High: p.Value, Low: p.Value, Close: p.Value, Volume: 1}
+	}
+	return Aggregate(candles, interval, loc)
+}
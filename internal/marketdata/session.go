@@ -0,0 +1,58 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidSession = errors.New("invalid session")
+
+// Session selects the time zone that daily and weekly buckets follow.
+type Session string
+
+const (
+	SessionUTC      Session = "utc"
+	SessionExchange Session = "exchange"
+	SessionUser     Session = "user"
+)
+
+// ResolveLocation returns the time zone for a session. exchangeTZ and
+// userTZ are IANA names; empty values fall back to UTC.
+func ResolveLocation(session Session, exchangeTZ, userTZ string) (*time.Location, error) {
+	var name string
+	switch session {
+	case "", SessionUTC:
+		return time.UTC, nil
+	case SessionExchange:
+		name = exchangeTZ
+	case SessionUser:
+		name = userTZ
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSession, session)
+	}
+
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidSession, name)
+	}
+	return loc, nil
+}
+
+// BaseInterval picks the stored candle width to aggregate from. Zones with
+// fractional-hour offsets (e.g. Asia/Kolkata) need sub-hour source candles
+// so that local hour and day boundaries can be honoured.
+func BaseInterval(interval Interval, loc *time.Location, at time.Time) Interval {
+	if !interval.Calendar() && interval.Duration < time.Hour {
+		return Interval{Name: "1m", Duration: time.Minute}
+	}
+
+	if _, offset := at.In(loc).Zone(); offset%3600 != 0 {
+		return Interval{Name: "15m", Duration: 15 * time.Minute}
+	}
+	return Interval{Name: "1h", Duration: time.Hour}
+}
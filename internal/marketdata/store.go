@@ -0,0 +1,211 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+// CandleStore reads stored candles. Candles are returned oldest first.
+type CandleStore interface {
+	Candles(ctx context.Context, exchange, symbol string, interval Interval, from, to time.Time) ([]Candle, error)
+}
+
+// AggregatingStore is a CandleStore that can roll base candles up into
+// wider session buckets itself.
+type AggregatingStore interface {
+	CandleStore
+	AggregatedCandles(ctx context.Context, exchange, symbol string, base, interval Interval, loc *time.Location, from, to time.Time, limit int) ([]Candle, error)
+}
+
+// LoadCandles reads candles of interval for [from, to). Stored candles are
+// aggregated from a narrower interval so buckets follow the session time
+// zone instead of the UTC boundaries of the stored data.
+func LoadCandles(ctx context.Context, store CandleStore, exchange, symbol string, interval Interval, loc *time.Location, from, to time.Time) ([]Candle, error) {
+	base := BaseInterval(interval, loc, to)
+	candles, err := store.Candles(ctx, exchange, symbol, base, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if base.Name != interval.Name {
+		candles = Aggregate(candles, interval, loc)
+	}
+	return candles, nil
+}
+
+// SeriesCandle is a candle together with the series it belongs to.
+type SeriesCandle struct {
+	Exchange string
+	Symbol   string
+	Interval string
+	Candle
+}
+
+// InfluxStore reads and writes the "candles" measurement, tagged by
+// exchange, symbol and interval with open/high/low/close/volume fields.
+type InfluxStore struct {
+	client   influxdb2.Client
+	writeAPI api.WriteAPIBlocking
+	queryAPI api.QueryAPI
+	bucket   string
+}
+
+func NewInfluxStore(cfg config.InfluxConfig) *InfluxStore {
+	client := influxdb2.NewClient(cfg.URL, cfg.Token)
+	return &InfluxStore{
+		client:   client,
+		writeAPI: client.WriteAPIBlocking(cfg.Org, cfg.Bucket),
+		queryAPI: client.QueryAPI(cfg.Org),
+		bucket:   cfg.Bucket,
+	}
+}
+
+// Ping checks that InfluxDB is up and ready.
+func (s *InfluxStore) Ping(ctx context.Context) error {
+	ready, err := s.client.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return errors.New("influxdb is not ready")
+	}
+	return nil
+}
+
+// WriteCandles writes the candles in one request. Points are keyed by
+// series and time, so writing a batch again overwrites rather than
+// duplicates it.
+func (s *InfluxStore) WriteCandles(ctx context.Context, candles []SeriesCandle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	points := make([]*write.Point, len(candles))
+	for i, c := range candles {
+		points[i] = influxdb2.NewPoint("candles", map[string]string{
+			"exchange": c.Exchange,
+			"symbol":   c.Symbol,
+			"interval": c.Interval,
+		}, map[string]interface{}{
+			"open":   c.Open,
+			"high":   c.High,
+			"low":    c.Low,
+			"close":  c.Close,
+			"volume": c.Volume,
+		}, c.Time)
+	}
+
+	if err := s.writeAPI.WritePoint(ctx, points...); err != nil {
+		return fmt.Errorf("failed to write candles: %w", err)
+	}
+	return nil
+}
+
+func (s *InfluxStore) Candles(ctx context.Context, exchange, symbol string, interval Interval, from, to time.Time) ([]Candle, error) {
+	query := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == "candles" and r.exchange == %q and r.symbol == %q and r.interval == %q)
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+  |> sort(columns: ["_time"])`,
+		s.bucket, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), exchange, symbol, interval.Name)
+
+	return s.query(ctx, query)
+}
+
+// AggregatedCandles returns the newest limit candles of interval in
+// [from, to), oldest first. Bucketing in the session time zone, OHLCV
+// aggregation and the limit are all evaluated by InfluxDB over the stored
+// base interval, so only the result crosses the wire.
+func (s *InfluxStore) AggregatedCandles(ctx context.Context, exchange, symbol string, base, interval Interval, loc *time.Location, from, to time.Time, limit int) ([]Candle, error) {
+	every := interval.Name
+	offset := "0s"
+	if interval.Calendar() && interval.Days%7 == 0 {
+		// Flux weeks start on Thursday, the weekday of the Unix epoch
+		offset = "4d"
+	}
+
+	window := func(field, fn string) string {
+		return fmt.Sprintf(`data |> filter(fn: (r) => r._field == %q) |> aggregateWindow(every: %s, offset: %s, fn: %s, createEmpty: false, timeSrc: "_start", location: loc)`,
+			field, every, offset, fn)
+	}
+
+	query := fmt.Sprintf(`import "timezone"
+
+loc = timezone.location(name: %q)
+data = from(bucket: %q)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == "candles" and r.exchange == %q and r.symbol == %q and r.interval == %q)
+
+union(tables: [
+  %s,
+  %s,
+  %s,
+  %s,
+  %s,
+])
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+  |> group()
+  |> sort(columns: ["_time"], desc: true)
+  |> limit(n: %d)`,
+		loc.String(), s.bucket, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), exchange, symbol, base.Name,
+		window("open", "first"), window("high", "max"), window("low", "min"), window("close", "last"), window("volume", "sum"),
+		limit)
+
+	candles, err := s.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// query runs a Flux query whose rows are pivoted candles.
+func (s *InfluxStore) query(ctx context.Context, query string) ([]Candle, error) {
+	result, err := s.queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer result.Close()
+
+	var candles []Candle
+	for result.Next() {
+		record := result.Record()
+		candles = append(candles, Candle{
+			Time:   record.Time(),
+			Open:   toFloat(record.ValueByKey("open")),
+			High:   toFloat(record.ValueByKey("high")),
+			Low:    toFloat(record.ValueByKey("low")),
+			Close:  toFloat(record.ValueByKey("close")),
+			Volume: toFloat(record.ValueByKey("volume")),
+		})
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to read candles: %w", result.Err())
+	}
+
+	return candles, nil
+}
+
+func (s *InfluxStore) Close() {
+	s.client.Close()
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,263 @@
+// internal/messaging/lag.go
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+var (
+	consumerPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nats_consumer_pending_messages",
+		Help: "Stream messages not yet delivered to the JetStream consumer.",
+	}, []string{"stream", "consumer"})
+
+	consumerAckPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nats_consumer_ack_pending_messages",
+		Help: "Messages delivered to the JetStream consumer but not acknowledged yet.",
+	}, []string{"stream", "consumer"})
+
+	consumerRedelivered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nats_consumer_redelivered_messages",
+		Help: "Unacknowledged messages the JetStream consumer got more than once.",
+	}, []string{"stream", "consumer"})
+
+	consumerStuck = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nats_consumer_stuck",
+		Help: "1 while the JetStream consumer has a backlog but stopped acknowledging.",
+	}, []string{"stream", "consumer"})
+
+	consumerAckLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nats_consumer_ack_latency_seconds",
+		Help:    "Time from delivery to acknowledgement, from the sampled ack advisories of consumers with a sample frequency.",
+		Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5, 30, 120},
+	}, []string{"stream", "consumer"})
+
+	lagPollErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "nats_consumer_lag_poll_errors_total",
+		Help: "Failed polls of JetStream consumer state.",
+	})
+)
+
+// ackMetricSubject carries the ack samples JetStream publishes for
+// consumers configured with a sample frequency.
+const ackMetricSubject = "$JS.EVENT.METRIC.CONSUMER.ACK.>"
+
+// Reasons a consumer is reported stuck
+const (
+	StuckNoProgress = "no_progress"
+	StuckBacklog    = "backlog"
+)
+
+// ConsumerLag is the state of one JetStream consumer at the last poll.
+type ConsumerLag struct {
+	Stream      string     `json:"stream"`
+	Consumer    string     `json:"consumer"`
+	Pending     uint64     `json:"pending"`
+	AckPending  int        `json:"ack_pending"`
+	Redelivered int        `json:"redelivered"`
+	AckFloor    uint64     `json:"ack_floor"`
+	LastAck     *time.Time `json:"last_ack,omitempty"`
+	// Progressed is when the ack floor last moved, or the consumer was
+	// first seen
+	Progressed time.Time `json:"progressed"`
+	Stuck      bool      `json:"stuck"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// LagMonitor polls every JetStream consumer, exports its backlog to
+// Prometheus and reports consumers that stopped keeping up. A consumer is
+// stuck when it has messages outstanding but its ack floor has not moved
+// for StuckAfter, or when its backlog exceeds MaxPending. Events behind a
+// stuck consumer are not lost, but every state derived from them, such as
+// portfolios, silently falls behind.
+type LagMonitor struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+	cfg  config.ConsumerLagConfig
+	// alert is called whenever a consumer becomes stuck or recovers
+	alert func(ConsumerLag)
+
+	mutex     sync.Mutex
+	consumers map[string]*ConsumerLag
+}
+
+func NewLagMonitor(conn *nats.Conn, cfg config.ConsumerLagConfig, alert func(ConsumerLag)) (*LagMonitor, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jetstream context: %w", err)
+	}
+	return &LagMonitor{
+		conn:      conn,
+		js:        js,
+		cfg:       cfg,
+		alert:     alert,
+		consumers: make(map[string]*ConsumerLag),
+	}, nil
+}
+
+// Run polls the consumers once. It matches scheduler.JobFunc: only the
+// scheduler's leader polls, so a consumer's alerts are sent once however
+// many replicas run.
+func (m *LagMonitor) Run(ctx context.Context) error {
+	if err := m.Poll(ctx, time.Now()); err != nil {
+		lagPollErrors.Inc()
+		return fmt.Errorf("failed to poll jetstream consumers: %w", err)
+	}
+	return nil
+}
+
+// ObserveAcks records the ack latency samples until the subscription is
+// unsubscribed. Replicas join the same queue group, so every sample is
+// observed once.
+func (m *LagMonitor) ObserveAcks(queue string) (*nats.Subscription, error) {
+	return m.conn.QueueSubscribe(ackMetricSubject, queue, m.observeAck)
+}
+
+// Poll reads the state of every consumer of every stream once.
+func (m *LagMonitor) Poll(ctx context.Context, now time.Time) error {
+	seen := make(map[string]bool)
+	var alerts []ConsumerLag
+
+	for stream := range m.js.StreamNames(nats.Context(ctx)) {
+		for info := range m.js.Consumers(stream, nats.Context(ctx)) {
+			key := info.Stream + "/" + info.Name
+			seen[key] = true
+			if lag, changed := m.update(key, info, now); changed {
+				alerts = append(alerts, lag)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Drop consumers that were deleted
+	m.mutex.Lock()
+	for key, lag := range m.consumers {
+		if !seen[key] {
+			m.forget(lag)
+			delete(m.consumers, key)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, lag := range alerts {
+		if lag.Stuck {
+			log.Printf("JetStream consumer %s/%s is stuck (%s): %d pending, %d awaiting ack, no progress since %s",
+				lag.Stream, lag.Consumer, lag.Reason, lag.Pending, lag.AckPending, lag.Progressed.Format(time.RFC3339))
+		} else {
+			log.Printf("JetStream consumer %s/%s recovered", lag.Stream, lag.Consumer)
+		}
+		if m.alert != nil {
+			m.alert(lag)
+		}
+	}
+	return nil
+}
+
+// update records a consumer's state and reports whether it became stuck
+// or recovered.
+func (m *LagMonitor) update(key string, info *nats.ConsumerInfo, now time.Time) (ConsumerLag, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lag, ok := m.consumers[key]
+	if !ok {
+		lag = &ConsumerLag{Stream: info.Stream, Consumer: info.Name, Progressed: now}
+		m.consumers[key] = lag
+	}
+	wasStuck := lag.Stuck
+
+	if info.AckFloor.Consumer != lag.AckFloor {
+		lag.Progressed = now
+	}
+	lag.AckFloor = info.AckFloor.Consumer
+	lag.Pending = info.NumPending
+	lag.AckPending = info.NumAckPending
+	lag.Redelivered = info.NumRedelivered
+	if info.AckFloor.Last != nil {
+		lag.LastAck = info.AckFloor.Last
+	}
+
+	outstanding := lag.Pending > 0 || lag.AckPending > 0
+	lag.Stuck, lag.Reason = false, ""
+	switch {
+	case outstanding && m.cfg.StuckAfter > 0 && now.Sub(lag.Progressed) >= m.cfg.StuckAfter:
+		lag.Stuck, lag.Reason = true, StuckNoProgress
+	case m.cfg.MaxPending > 0 && lag.Pending > m.cfg.MaxPending:
+		lag.Stuck, lag.Reason = true, StuckBacklog
+	}
+	if !outstanding {
+		// An idle consumer is not falling behind
+		lag.Progressed = now
+	}
+
+	consumerPending.WithLabelValues(lag.Stream, lag.Consumer).Set(float64(lag.Pending))
+	consumerAckPending.WithLabelValues(lag.Stream, lag.Consumer).Set(float64(lag.AckPending))
+	consumerRedelivered.WithLabelValues(lag.Stream, lag.Consumer).Set(float64(lag.Redelivered))
+	stuck := 0.0
+	if lag.Stuck {
+		stuck = 1
+	}
+	consumerStuck.WithLabelValues(lag.Stream, lag.Consumer).Set(stuck)
+
+	return *lag, lag.Stuck != wasStuck
+}
+
+// forget removes a deleted consumer's series. Callers hold the mutex.
+func (m *LagMonitor) forget(lag *ConsumerLag) {
+	consumerPending.DeleteLabelValues(lag.Stream, lag.Consumer)
+	consumerAckPending.DeleteLabelValues(lag.Stream, lag.Consumer)
+	consumerRedelivered.DeleteLabelValues(lag.Stream, lag.Consumer)
+	consumerStuck.DeleteLabelValues(lag.Stream, lag.Consumer)
+	consumerAckLatency.DeleteLabelValues(lag.Stream, lag.Consumer)
+}
+
+// ackSample is the part of a JetStream ack advisory that is used.
+type ackSample struct {
+	Stream   string `json:"stream"`
+	Consumer string `json:"consumer"`
+	// AckTime is in nanoseconds
+	AckTime int64 `json:"ack_time"`
+}
+
+func (m *LagMonitor) observeAck(msg *nats.Msg) {
+	var sample ackSample
+	if err := json.Unmarshal(msg.Data, &sample); err != nil || sample.Stream == "" {
+		return
+	}
+	consumerAckLatency.WithLabelValues(sample.Stream, sample.Consumer).Observe(time.Duration(sample.AckTime).Seconds())
+}
+
+// Consumers returns the state of every consumer at the last poll, stuck
+// ones first.
+func (m *LagMonitor) Consumers() []ConsumerLag {
+	m.mutex.Lock()
+	consumers := make([]ConsumerLag, 0, len(m.consumers))
+	for _, lag := range m.consumers {
+		consumers = append(consumers, *lag)
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Stuck != consumers[j].Stuck {
+			return consumers[i].Stuck
+		}
+		if consumers[i].Stream != consumers[j].Stream {
+			return consumers[i].Stream < consumers[j].Stream
+		}
+		return consumers[i].Consumer < consumers[j].Consumer
+	})
+	return consumers
+}
@@ -0,0 +1,36 @@
+// internal/messaging/nats.go
+package messaging
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/tradingbothub/platform/internal/config"
+)
+
+// Connect opens a NATS connection that keeps retrying in the background,
+// so a service can start before NATS is reachable. Publishes made while
+// disconnected are buffered by the client.
+func Connect(cfg config.NATSConfig, name string) (*nats.Conn, error) {
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name(name),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("Disconnected from nats: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Printf("Reconnected to nats at %s", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	return conn, nil
+}
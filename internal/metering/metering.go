@@ -0,0 +1,266 @@
+package metering
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"github.com/tradingbothub/platform/internal/cache"
+)
+
+const (
+	keyPrefix = "usage:api:"
+	// Bucket is the resolution usage is stored at
+	Bucket = time.Hour
+
+	fieldRequests    = "requests"
+	fieldErrors      = "errors"
+	fieldRateLimited = "rate_limited"
+	// Per-endpoint fields are prefixed with the counter they belong to
+	endpointSep = "|"
+)
+
+var droppedEvents = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "metering_events_dropped_total",
+	Help: "API usage events dropped because the metering buffer was full.",
+})
+
+// Event is one API request by a user.
+type Event struct {
+	UserID string
+	// Endpoint is the method and route, e.g. "GET /api/v1/bots/:id"
+	Endpoint string
+	Status   int
+	Time     time.Time
+}
+
+// RateLimited reports whether the request was throttled.
+func (e Event) RateLimited() bool {
+	return e.Status == http.StatusTooManyRequests
+}
+
+// Failed reports whether the request ended in an error other than
+// throttling, which is counted separately.
+func (e Event) Failed() bool {
+	return e.Status >= http.StatusBadRequest && !e.RateLimited()
+}
+
+// Recorder meters API requests per user into hourly Redis hashes. Events
+// are buffered and written in batches off the request path; when Redis
+// falls behind, events are dropped rather than slowing requests down.
+type Recorder struct {
+	client    redis.UniversalClient
+	retention time.Duration
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewRecorder(client redis.UniversalClient, retention time.Duration, bufferSize int) *Recorder {
+	if bufferSize <= 0 {
+		bufferSize = 4096
+	}
+	r := &Recorder{
+		client:    client,
+		retention: retention,
+		events:    make(chan Event, bufferSize),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues the event without blocking.
+func (r *Recorder) Record(event Event) {
+	select {
+	case r.events <- event:
+	default:
+		droppedEvents.Inc()
+	}
+}
+
+// Close flushes queued events and stops the writer.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.events)
+		<-r.done
+	})
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var batch []Event
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= 512 {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipe := r.client.Pipeline()
+	expiring := make(map[string]bool)
+	for _, event := range batch {
+		key := bucketKey(event.UserID, event.Time)
+		counters := []string{fieldRequests}
+		if event.Failed() {
+			counters = append(counters, fieldErrors)
+		}
+		if event.RateLimited() {
+			counters = append(counters, fieldRateLimited)
+		}
+		for _, counter := range counters {
+			pipe.HIncrBy(ctx, key, counter, 1)
+			pipe.HIncrBy(ctx, key, counter+endpointSep+event.Endpoint, 1)
+		}
+		if !expiring[key] {
+			expiring[key] = true
+			pipe.Expire(ctx, key, r.retention+Bucket)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to write %d API usage events: %v", len(batch), err)
+	}
+}
+
+// Counts are the counters of a period.
+type Counts struct {
+	Requests    int64 `json:"requests"`
+	Errors      int64 `json:"errors"`
+	RateLimited int64 `json:"rate_limited"`
+}
+
+// ErrorRate is the fraction of requests that failed, throttling excluded.
+func (c Counts) ErrorRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.Errors) / float64(c.Requests)
+}
+
+func (c *Counts) add(o Counts) {
+	c.Requests += o.Requests
+	c.Errors += o.Errors
+	c.RateLimited += o.RateLimited
+}
+
+type Point struct {
+	Time time.Time `json:"time"`
+	Counts
+}
+
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"`
+	Counts
+}
+
+// Report is a user's API usage over a period, oldest bucket first.
+type Report struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Bucket       string          `json:"bucket"`
+	Totals       Counts          `json:"totals"`
+	ErrorRate    float64         `json:"error_rate"`
+	Series       []Point         `json:"series"`
+	TopEndpoints []EndpointUsage `json:"top_endpoints"`
+}
+
+// Usage returns the user's usage in the hourly buckets overlapping
+// [from, to], with the top endpoints by request count.
+func (r *Recorder) Usage(ctx context.Context, userID string, from, to time.Time, top int) (*Report, error) {
+	start := from.UTC().Truncate(Bucket)
+
+	var buckets []time.Time
+	var cmds []*redis.MapStringStringCmd
+	_, err := cache.Pipelined(ctx, r.client, func(pipe redis.Pipeliner) {
+		for t := start; !t.After(to); t = t.Add(Bucket) {
+			buckets = append(buckets, t)
+			cmds = append(cmds, pipe.HGetAll(ctx, bucketKey(userID, t)))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{From: start, To: to, Bucket: Bucket.String(), Series: make([]Point, 0, len(buckets))}
+	endpoints := make(map[string]*Counts)
+	for i, cmd := range cmds {
+		point := Point{Time: buckets[i]}
+		for field, value := range cmd.Val() {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			counter, endpoint, perEndpoint := strings.Cut(field, endpointSep)
+			target := &point.Counts
+			if perEndpoint {
+				if endpoints[endpoint] == nil {
+					endpoints[endpoint] = &Counts{}
+				}
+				target = endpoints[endpoint]
+			}
+			switch counter {
+			case fieldRequests:
+				target.Requests += n
+			case fieldErrors:
+				target.Errors += n
+			case fieldRateLimited:
+				target.RateLimited += n
+			}
+		}
+		report.Totals.add(point.Counts)
+		report.Series = append(report.Series, point)
+	}
+	report.ErrorRate = report.Totals.ErrorRate()
+
+	report.TopEndpoints = make([]EndpointUsage, 0, len(endpoints))
+	for endpoint, counts := range endpoints {
+		report.TopEndpoints = append(report.TopEndpoints, EndpointUsage{Endpoint: endpoint, Counts: *counts})
+	}
+	sort.Slice(report.TopEndpoints, func(i, j int) bool {
+		a, b := report.TopEndpoints[i], report.TopEndpoints[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	if len(report.TopEndpoints) > top {
+		report.TopEndpoints = report.TopEndpoints[:top]
+	}
+	return report, nil
+}
+
+func bucketKey(userID string, t time.Time) string {
+	return keyPrefix + userID + ":" + strconv.FormatInt(t.UTC().Truncate(Bucket).Unix(), 10)
+}
@@ -0,0 +1,239 @@
+// internal/middleware/accesslist.go
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	AllowList = "allow"
+	DenyList  = "deny"
+)
+
+var (
+	ErrUnknownAccessList = errors.New("unknown access list")
+	ErrInvalidEntry      = errors.New("invalid access list entry")
+)
+
+// AccessListStore persists runtime allow/deny entries so that every gateway
+// replica converges on the same lists.
+type AccessListStore interface {
+	Members(ctx context.Context, list string) ([]string, error)
+	Add(ctx context.Context, list, entry string) error
+	Remove(ctx context.Context, list, entry string) error
+}
+
+type redisAccessListStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisAccessListStore(client redis.UniversalClient) AccessListStore {
+	return &redisAccessListStore{client: client}
+}
+
+func (s *redisAccessListStore) key(list string) string {
+	return "ratelimit:" + list + "list"
+}
+
+func (s *redisAccessListStore) Members(ctx context.Context, list string) ([]string, error) {
+	return s.client.SMembers(ctx, s.key(list)).Result()
+}
+
+func (s *redisAccessListStore) Add(ctx context.Context, list, entry string) error {
+	return s.client.SAdd(ctx, s.key(list), entry).Err()
+}
+
+func (s *redisAccessListStore) Remove(ctx context.Context, list, entry string) error {
+	return s.client.SRem(ctx, s.key(list), entry).Err()
+}
+
+// entrySet holds exact matches (IPs and user IDs) and CIDR ranges.
+type entrySet struct {
+	exact    map[string]struct{}
+	networks []*net.IPNet
+}
+
+func newEntrySet(entries ...[]string) *entrySet {
+	set := &entrySet{exact: make(map[string]struct{})}
+	for _, list := range entries {
+		for _, entry := range list {
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				set.networks = append(set.networks, network)
+				continue
+			}
+			set.exact[entry] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (s *entrySet) contains(ip, userID string) bool {
+	if _, ok := s.exact[ip]; ok {
+		return true
+	}
+	if userID != "" {
+		if _, ok := s.exact[userID]; ok {
+			return true
+		}
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range s.networks {
+			if network.Contains(parsed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AccessList combines the statically configured allow/deny entries with the
+// runtime entries kept in the store. Entries are IP addresses, CIDR ranges or
+// user IDs.
+type AccessList struct {
+	store        AccessListStore
+	staticAllow  []string
+	staticDeny   []string
+	runtimeAllow []string
+	runtimeDeny  []string
+	allow        *entrySet
+	deny         *entrySet
+	mutex        sync.RWMutex
+}
+
+func NewAccessList(store AccessListStore, allow, deny []string, syncInterval time.Duration) *AccessList {
+	al := &AccessList{
+		store:       store,
+		staticAllow: allow,
+		staticDeny:  deny,
+	}
+	al.rebuild()
+
+	if store == nil {
+		return al
+	}
+
+	al.Sync(context.Background())
+
+	// Pull runtime entries periodically so changes made on other replicas
+	// are picked up
+	go func() {
+		ticker := time.NewTicker(syncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			al.Sync(context.Background())
+		}
+	}()
+
+	return al
+}
+
+// Sync reloads the runtime entries from the store.
+func (al *AccessList) Sync(ctx context.Context) {
+	allow, err := al.store.Members(ctx, AllowList)
+	if err != nil {
+		log.Printf("Failed to sync rate limit allowlist: %v", err)
+		return
+	}
+
+	deny, err := al.store.Members(ctx, DenyList)
+	if err != nil {
+		log.Printf("Failed to sync rate limit denylist: %v", err)
+		return
+	}
+
+	al.mutex.Lock()
+	al.runtimeAllow = allow
+	al.runtimeDeny = deny
+	al.mutex.Unlock()
+
+	al.rebuild()
+}
+
+func (al *AccessList) rebuild() {
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+
+	al.allow = newEntrySet(al.staticAllow, al.runtimeAllow)
+	al.deny = newEntrySet(al.staticDeny, al.runtimeDeny)
+}
+
+// Check reports whether the client is allowlisted or denylisted. Deny wins
+// when an entry appears on both lists.
+func (al *AccessList) Check(ip, userID string) (allowed, denied bool) {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+
+	if al.deny.contains(ip, userID) {
+		return false, true
+	}
+	return al.allow.contains(ip, userID), false
+}
+
+// Entries returns the static and runtime entries of both lists.
+func (al *AccessList) Entries() map[string][]string {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+
+	return map[string][]string{
+		AllowList: append(append([]string{}, al.staticAllow...), al.runtimeAllow...),
+		DenyList:  append(append([]string{}, al.staticDeny...), al.runtimeDeny...),
+	}
+}
+
+func (al *AccessList) Add(ctx context.Context, list, entry string) error {
+	entry, err := al.validate(list, entry)
+	if err != nil {
+		return err
+	}
+
+	if err := al.store.Add(ctx, list, entry); err != nil {
+		return err
+	}
+
+	al.Sync(ctx)
+	return nil
+}
+
+func (al *AccessList) Remove(ctx context.Context, list, entry string) error {
+	entry, err := al.validate(list, entry)
+	if err != nil {
+		return err
+	}
+
+	if err := al.store.Remove(ctx, list, entry); err != nil {
+		return err
+	}
+
+	al.Sync(ctx)
+	return nil
+}
+
+func (al *AccessList) validate(list, entry string) (string, error) {
+	if list != AllowList && list != DenyList {
+		return "", ErrUnknownAccessList
+	}
+
+	entry = strings.TrimSpace(entry)
+	if entry == "" || strings.ContainsAny(entry, " \t\n") {
+		return "", ErrInvalidEntry
+	}
+	if strings.Contains(entry, "/") {
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return "", ErrInvalidEntry
+		}
+	}
+
+	if al.store == nil {
+		return "", errors.New("access list store not configured")
+	}
+
+	return entry, nil
+}
@@ -2,15 +2,31 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/golang-jwt/jwt/v5"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func JWTAuth(authClient authpb.AuthServiceClient) gin.HandlerFunc {
+// JWTAuth validates the bearer token with the auth service. Positive
+// validations are cached in tokens for a short while; tokens blacklisted
+// by Logout are rejected even while cached. Tokens of deactivated users
+// are refused with 403 rather than 401, so clients do not try to refresh.
+// Tokens signed with a key in keys are verified locally first, so forged
+// ones are rejected without a round trip to the auth service. Requests
+// from addresses outside the user's IP allowlist are refused with 403
+// unless it only restricts trading; cached validations are checked too.
+// Validations of locally verified tokens are cached per session, so a
+// refreshed token is not validated again.
+func JWTAuth(authClient authpb.AuthServiceClient, tokens *cache.TokenCache, keys *auth.KeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -30,12 +46,60 @@ func JWTAuth(authClient authpb.AuthServiceClient) gin.HandlerFunc {
 
 		token := parts[1]
 
+		// The signature is checked by the auth service before anything is
+		// cached, so the unverified ID only selects the blacklist entry and
+		// the expiry is that of the token the cache entry was made for
+		var claims jwt.RegisteredClaims
+		if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+		// A key the set does not know yet, e.g. right after a rotation,
+		// is left to the auth service
+		sessionID := ""
+		if keys != nil && keys.Len() > 0 {
+			verified, err := keys.VerifyAccessToken(token)
+			switch {
+			case err == nil:
+				sessionID = verified.SessionID
+			case !errors.Is(err, auth.ErrUnknownKey):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+		}
+
+		var user *authpb.User
+		var ok bool
+		if sessionID != "" {
+			user, ok = tokens.GetSession(c.Request.Context(), sessionID, claims.ID, claims.ExpiresAt.Time)
+		} else {
+			user, ok = tokens.Get(c.Request.Context(), token, claims.ID, claims.ExpiresAt.Time)
+		}
+		if ok {
+			if user.IpAllowlistMode != auth.IPAllowlistTrading && !auth.NetworksAllow(user.AllowedNetworks, c.ClientIP()) {
+				c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrIPNotAllowed.Error()})
+				c.Abort()
+				return
+			}
+			c.Set("user_id", user.Id)
+			c.Set("user", user)
+			c.Next()
+			return
+		}
+
 		// Validate token with auth service
 		req := &authpb.ValidateTokenRequest{
 			AccessToken: token,
 		}
 
-		resp, err := authClient.ValidateToken(context.Background(), req)
+		resp, err := authClient.ValidateToken(c.Request.Context(), req)
+		if status.Code(err) == codes.PermissionDenied {
+			c.JSON(http.StatusForbidden, gin.H{"error": status.Convert(err).Message()})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -48,6 +112,12 @@ func JWTAuth(authClient authpb.AuthServiceClient) gin.HandlerFunc {
 			return
 		}
 
+		if sessionID != "" {
+			tokens.SetSession(c.Request.Context(), sessionID, resp.User)
+		} else {
+			tokens.Set(c.Request.Context(), token, resp.User, claims.ExpiresAt.Time)
+		}
+
 		// Set user info in context
 		c.Set("user_id", resp.User.Id)
 		c.Set("user", resp.User)
@@ -55,3 +125,62 @@ func JWTAuth(authClient authpb.AuthServiceClient) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAllowedIP rejects requests from addresses outside the user's IP
+// allowlist whatever its mode. It guards trading and withdrawal endpoints
+// and must run after JWTAuth.
+func RequireAllowedIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		if user, ok := value.(*authpb.User); !ok || !auth.NetworksAllow(user.AllowedNetworks, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrIPNotAllowed.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects users who have not confirmed their email
+// address yet. It must run after JWTAuth.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		if user, ok := value.(*authpb.User); !ok || !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Email address not verified",
+				"message": "Open the link in the verification email or request a new one",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects users without the role. It must run after JWTAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		if user, ok := value.(*authpb.User); !ok || !slices.Contains(user.Roles, role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Requires the " + role + " role"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission rejects users whose roles do not grant the permission.
+// It must run after JWTAuth.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		if user, ok := value.(*authpb.User); !ok || !slices.Contains(user.Permissions, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
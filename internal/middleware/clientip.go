@@ -0,0 +1,85 @@
+// internal/middleware/clientip.go
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/rpc"
+)
+
+// ClientIPConfig controls where c.ClientIP() comes from. Rate limits,
+// access lists, login lockout and audit logs are keyed by it, so
+// forwarding headers are only believed when the connection comes from a
+// trusted proxy.
+type ClientIPConfig struct {
+	// TrustedProxies are the IPs or CIDRs of the load balancers in front
+	// of the gateway. Empty trusts none: the client IP is the peer address.
+	TrustedProxies []string
+	// Headers are checked in order for the client IP
+	Headers []string
+	// Platform names a CDN whose client IP header is trusted regardless of
+	// the peer, for gateways only reachable through it
+	Platform string
+}
+
+var trustedPlatforms = map[string]string{
+	"cloudflare":        gin.PlatformCloudflare,
+	"google-app-engine": gin.PlatformGoogleAppEngine,
+}
+
+// ConfigureClientIP applies the config to the engine.
+func ConfigureClientIP(engine *gin.Engine, cfg ClientIPConfig) error {
+	// Trusting everyone lets any client pick its IP
+	for _, proxy := range cfg.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if ones, _ := network.Mask.Size(); ones == 0 {
+				return fmt.Errorf("trusted proxy %s matches every address", proxy)
+			}
+		}
+	}
+	// Gin trusts every proxy unless told otherwise
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	engine.ForwardedByClientIP = len(cfg.TrustedProxies) > 0
+	engine.RemoteIPHeaders = cfg.Headers
+
+	engine.TrustedPlatform = ""
+	if cfg.Platform != "" {
+		header, ok := trustedPlatforms[cfg.Platform]
+		if !ok {
+			return fmt.Errorf("unknown trusted platform %q", cfg.Platform)
+		}
+		engine.TrustedPlatform = header
+	}
+	return nil
+}
+
+// JoinForwardedFor merges repeated X-Forwarded-For header lines into one.
+// Gin only reads the first line, which the client controls when a proxy
+// adds its own line instead of appending to the existing one. Joined, the
+// addresses are walked from the proxy's end as usual.
+func JoinForwardedFor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := http.CanonicalHeaderKey("X-Forwarded-For")
+		if values := c.Request.Header[key]; len(values) > 1 {
+			c.Request.Header[key] = []string{strings.Join(values, ", ")}
+		}
+		c.Next()
+	}
+}
+
+// ForwardClient passes the client IP and user agent on to the backend
+// calls made with the request's context, so services behind the gateway
+// can audit who acted.
+func ForwardClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := rpc.WithClient(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	proxied := ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8"}, Headers: []string{"X-Forwarded-For"}}
+	tests := []struct {
+		name    string
+		cfg     ClientIPConfig
+		peer    string
+		headers map[string][]string
+		want    string
+	}{
+		{
+			name:    "no proxies ignores forwarded for",
+			peer:    "203.0.113.7:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "untrusted peer cannot forward",
+			cfg:     proxied,
+			peer:    "203.0.113.7:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "trusted proxy forwards the client",
+			cfg:     proxied,
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"203.0.113.7"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "forged entry before the proxy's is skipped",
+			cfg:     proxied,
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, 203.0.113.7"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "forged line before the proxy's is skipped",
+			cfg:     proxied,
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1", "203.0.113.7"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "unlisted header is not read",
+			cfg:     proxied,
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "platform header without a platform is not read",
+			cfg:     proxied,
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"Cf-Connecting-Ip": {"198.51.100.1"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "platform header",
+			cfg:     ClientIPConfig{Platform: "cloudflare"},
+			peer:    "10.0.0.1:4000",
+			headers: map[string][]string{"Cf-Connecting-Ip": {"203.0.113.7"}},
+			want:    "203.0.113.7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			require.NoError(t, ConfigureClientIP(router, tt.cfg))
+			router.Use(JoinForwardedFor())
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.peer
+			for key, values := range tt.headers {
+				req.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func TestConfigureClientIP_Rejects(t *testing.T) {
+	for _, cfg := range []ClientIPConfig{
+		{TrustedProxies: []string{"0.0.0.0/0"}},
+		{TrustedProxies: []string{"10.0.0.0/8", "::/0"}},
+		{TrustedProxies: []string{"not-an-ip"}},
+		{Platform: "akamai"},
+	} {
+		assert.Error(t, ConfigureClientIP(gin.New(), cfg), "%+v", cfg)
+	}
+}
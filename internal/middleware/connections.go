@@ -0,0 +1,151 @@
+// internal/middleware/connections.go
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	openConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_server_open_connections",
+		Help: "Open client connections by state: new, active, idle or h2c.",
+	}, []string{"state"})
+
+	acceptedConnections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_server_connections_total",
+		Help: "Client connections accepted.",
+	})
+
+	handshakeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_handshake_errors_total",
+		Help: "Connections that failed the TLS or HTTP/2 handshake, by protocol.",
+	}, []string{"protocol"})
+
+	http2Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_http2_errors_total",
+		Help: "HTTP/2 protocol errors, by type.",
+	}, []string{"type"})
+
+	requestQueueTime = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "http_request_queue_seconds",
+		Help:    "Time requests waited between the load balancer and the gateway, from X-Request-Start.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	})
+)
+
+// ConnTracker counts open connections by state. Its ConnState method is
+// meant for http.Server.ConnState.
+type ConnTracker struct {
+	mutex  sync.Mutex
+	states map[net.Conn]http.ConnState
+}
+
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{states: make(map[net.Conn]http.ConnState)}
+}
+
+func (t *ConnTracker) ConnState(conn net.Conn, state http.ConnState) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if previous, ok := t.states[conn]; ok {
+		openConnections.WithLabelValues(previous.String()).Dec()
+	}
+	switch state {
+	case http.StateNew, http.StateActive, http.StateIdle:
+		if state == http.StateNew {
+			acceptedConnections.Inc()
+		}
+		t.states[conn] = state
+		openConnections.WithLabelValues(state.String()).Inc()
+	default:
+		// Hijacked streams are tracked by the Drainer from here on
+		delete(t.states, conn)
+	}
+}
+
+// TrackH2C counts h2c connections, which net/http hijacks and hands to
+// the HTTP/2 server so ConnTracker no longer sees them. The h2c handler
+// serves the whole connection within the request that started it, so h
+// must be the h2c handler.
+func TrackH2C(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priorKnowledge := r.Method == "PRI" && r.URL.Path == "*"
+		upgrade := strings.EqualFold(r.Header.Get("Upgrade"), "h2c") && r.Header.Get("HTTP2-Settings") != ""
+		if priorKnowledge || upgrade {
+			openConnections.WithLabelValues("h2c").Inc()
+			defer openConnections.WithLabelValues("h2c").Dec()
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// CountHTTP2Error is meant for http.HTTP2Config.CountError. A client whose
+// first frame is not a valid SETTINGS frame failed the handshake.
+func CountHTTP2Error(errType string) {
+	http2Errors.WithLabelValues(errType).Inc()
+	if errType == "first_settings" {
+		handshakeErrors.WithLabelValues("http2").Inc()
+	}
+}
+
+// ServerErrorLog returns a logger for http.Server.ErrorLog that also counts
+// TLS handshake failures, which the server only reports there.
+func ServerErrorLog() *log.Logger {
+	return log.New(serverErrorWriter{}, "", log.LstdFlags)
+}
+
+type serverErrorWriter struct{}
+
+func (serverErrorWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("TLS handshake error")) {
+		handshakeErrors.WithLabelValues("tls").Inc()
+	}
+	return log.Writer().Write(p)
+}
+
+// RequestQueueing records how long requests queued in front of the
+// gateway, using the X-Request-Start header load balancers set to when
+// they received the request: "t=" followed by a Unix time in seconds,
+// milliseconds or microseconds.
+func RequestQueueing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if start, ok := parseRequestStart(c.GetHeader("X-Request-Start")); ok {
+			// Clock skew between hosts can make the wait look negative
+			if wait := time.Since(start); wait >= 0 && wait < time.Minute {
+				requestQueueTime.Observe(wait.Seconds())
+			}
+		}
+		c.Next()
+	}
+}
+
+func parseRequestStart(header string) (time.Time, bool) {
+	if header == "" {
+		return time.Time{}, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimPrefix(header, "t="), 64)
+	if err != nil || value <= 0 {
+		return time.Time{}, false
+	}
+
+	// Infer the unit from the magnitude of a current timestamp
+	switch {
+	case value > 1e15:
+		return time.UnixMicro(int64(value)), true
+	case value > 1e12:
+		return time.UnixMilli(int64(value)), true
+	}
+	return time.Unix(0, int64(value*float64(time.Second))), true
+}
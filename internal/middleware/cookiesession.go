@@ -0,0 +1,269 @@
+// internal/middleware/cookiesession.go
+package middleware
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/internal/cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ClientTypeHeader names the kind of client signing in, e.g. "web"
+	ClientTypeHeader = "X-Client-Type"
+	// CSRFHeader carries the CSRF token of a cookie session
+	CSRFHeader = "X-CSRF-Token"
+)
+
+const (
+	// refreshMargin is how long before the access token expires the
+	// session refreshes it
+	refreshMargin  = 2 * time.Minute
+	refreshLockTTL = 10 * time.Second
+)
+
+// CookieSessionOptions configure cookie sessions.
+type CookieSessionOptions struct {
+	// Clients are the client types that get a cookie instead of tokens
+	Clients []string
+	// Key encrypts the cookie; 16, 24 or 32 bytes
+	Key    []byte
+	Name   string
+	Domain string
+	Secure bool
+	// TTL is how long the cookie and an unused session last
+	TTL time.Duration
+}
+
+// CookieSessions let browsers sign in with an httpOnly cookie instead of
+// holding tokens. The cookie carries the encrypted ID of a session in
+// redis, which keeps the tokens and is refreshed as it is used. Requests
+// other than GET, HEAD and OPTIONS must send the session's CSRF token in
+// the X-CSRF-Token header.
+type CookieSessions struct {
+	store      *cache.WebSessionStore
+	authClient authpb.AuthServiceClient
+	aead       cipher.AEAD
+	opts       CookieSessionOptions
+}
+
+func NewCookieSessions(store *cache.WebSessionStore, authClient authpb.AuthServiceClient, opts CookieSessionOptions) (*CookieSessions, error) {
+	block, err := aes.NewCipher(opts.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookie key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &CookieSessions{store: store, authClient: authClient, aead: aead, opts: opts}, nil
+}
+
+// Wants tells whether the request comes from a client type that signs in
+// with a cookie.
+func (s *CookieSessions) Wants(c *gin.Context) bool {
+	clientType := c.GetHeader(ClientTypeHeader)
+	for _, client := range s.opts.Clients {
+		if clientType != "" && clientType == client {
+			return true
+		}
+	}
+	return false
+}
+
+// Start keeps the tokens of a login in a new session, sets its cookie and
+// returns its CSRF token.
+func (s *CookieSessions) Start(c *gin.Context, resp *authpb.AuthResponse) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.store.Save(c.Request.Context(), id, &cache.WebSession{
+		UserID:          resp.User.GetId(),
+		AccessToken:     resp.AccessToken,
+		RefreshToken:    resp.RefreshToken,
+		AccessExpiresAt: time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+		CSRFToken:       csrf,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store web session: %w", err)
+	}
+	s.setCookie(c, s.seal(id), int(s.opts.TTL.Seconds()))
+	return csrf, nil
+}
+
+// End deletes the request's session, if it has one, and clears its cookie.
+func (s *CookieSessions) End(c *gin.Context) {
+	id := c.GetString("web_session_id")
+	if id == "" {
+		return
+	}
+	if err := s.store.Delete(c.Request.Context(), id); err != nil {
+		log.Printf("Failed to delete web session: %v", err)
+	}
+	s.setCookie(c, "", -1)
+}
+
+// CookieAuth signs requests with a session cookie in with the session's
+// access token, as if the browser had sent it as a bearer token, and sets
+// "web_session_id". Requests with an Authorization header, and all
+// requests while sessions is nil, pass untouched. It must run before
+// JWTAuth.
+func CookieAuth(sessions *CookieSessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessions == nil || c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+		value, err := c.Cookie(sessions.opts.Name)
+		if err != nil || value == "" {
+			c.Next()
+			return
+		}
+
+		id, ok := sessions.open(value)
+		if !ok {
+			sessions.setCookie(c, "", -1)
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		session, err := sessions.store.Get(ctx, id)
+		if errors.Is(err, cache.ErrWebSessionNotFound) {
+			sessions.setCookie(c, "", -1)
+			c.Next()
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to load web session: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+			c.Abort()
+			return
+		}
+
+		// A cross-site form or script can make the browser send the
+		// cookie, but cannot read the token to send alongside it
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader(CSRFHeader)), []byte(session.CSRFToken)) != 1 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+				c.Abort()
+				return
+			}
+		}
+
+		if time.Until(session.AccessExpiresAt) < refreshMargin {
+			session, err = sessions.refresh(ctx, id, session)
+			if err != nil {
+				sessions.setCookie(c, "", -1)
+				c.Next()
+				return
+			}
+		} else if err := sessions.store.Touch(ctx, id); err != nil {
+			log.Printf("Failed to extend web session: %v", err)
+		}
+
+		c.Request.Header.Set("Authorization", "Bearer "+session.AccessToken)
+		c.Set("web_session_id", id)
+		// Pages that lost the token, e.g. on reload, read it from any response
+		c.Header(CSRFHeader, session.CSRFToken)
+		c.Next()
+	}
+}
+
+// refresh replaces the session's tokens before the access token expires.
+// Of concurrent requests only one refreshes; the others carry on with the
+// current access token. It returns an error when the session has ended.
+func (s *CookieSessions) refresh(ctx context.Context, id string, session *cache.WebSession) (*cache.WebSession, error) {
+	lock, err := s.store.RefreshLock(ctx, id, uuid.New().String(), refreshLockTTL)
+	if err != nil {
+		return session, nil
+	}
+	defer lock.Release(context.WithoutCancel(ctx))
+
+	// Another request may have refreshed while this one waited for the lock
+	current, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if time.Until(current.AccessExpiresAt) >= refreshMargin {
+		return current, nil
+	}
+
+	resp, err := s.authClient.RefreshToken(ctx, &authpb.RefreshTokenRequest{RefreshToken: current.RefreshToken})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated, codes.PermissionDenied:
+		// Signed out elsewhere, expired or deactivated
+		if err := s.store.Delete(ctx, id); err != nil {
+			log.Printf("Failed to delete web session: %v", err)
+		}
+		return nil, err
+	default:
+		log.Printf("Failed to refresh web session: %v", err)
+		return current, nil
+	}
+
+	current.AccessToken = resp.AccessToken
+	current.RefreshToken = resp.RefreshToken
+	current.AccessExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	if err := s.store.Save(ctx, id, current); err != nil {
+		log.Printf("Failed to store refreshed web session: %v", err)
+	}
+	return current, nil
+}
+
+func (s *CookieSessions) setCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(s.opts.Name, value, maxAge, "/", s.opts.Domain, s.opts.Secure, true)
+}
+
+// seal encrypts the session ID into a cookie value.
+func (s *CookieSessions) seal(id string) string {
+	nonce := make([]byte, s.aead.NonceSize())
+	rand.Read(nonce)
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(id), []byte(s.opts.Name)))
+}
+
+// open decrypts a cookie value, rejecting any the gateway did not seal.
+func (s *CookieSessions) open(value string) (string, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", false
+	}
+	nonce, sealed := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	id, err := s.aead.Open(nil, nonce, sealed, []byte(s.opts.Name))
+	if err != nil {
+		return "", false
+	}
+	return string(id), true
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -8,12 +8,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// AllowedOrigins are the web origins that may call the API from a browser,
+// with credentials. WebSocket handshakes, which CORS does not cover, are
+// checked against the same list.
+var AllowedOrigins = []string{"http://localhost:3000", "http://localhost:8080", "https://tradingbothub.com"}
+
 func CORS() gin.HandlerFunc {
 	return cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:8080", "https://tradingbothub.com"},
+		AllowOrigins:     AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length", "X-Total-Count"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Requested-With", OrgHeader, ClientTypeHeader, CSRFHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count", CSRFHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ChangeAdded      = "added"
+	ChangeChanged    = "changed"
+	ChangeDeprecated = "deprecated"
+	ChangeRemoved    = "removed"
+)
+
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_deprecated_requests_total",
+	Help: "Requests served by deprecated routes.",
+}, []string{"method", "path"})
+
+// Deprecation describes a route scheduled for removal.
+type Deprecation struct {
+	// Since is when the route was deprecated
+	Since time.Time
+	// Sunset is when the route stops working; zero if not decided yet
+	Sunset time.Time
+	// Link points to migration documentation
+	Link string
+	// Replacement is the route clients should move to, e.g. "GET /api/v2/x"
+	Replacement string
+}
+
+// ChangelogEntry is one API change, as exposed by the changelog endpoint.
+type ChangelogEntry struct {
+	Date        time.Time  `json:"date"`
+	Type        string     `json:"type"`
+	Method      string     `json:"method,omitempty"`
+	Path        string     `json:"path,omitempty"`
+	Description string     `json:"description"`
+	Sunset      *time.Time `json:"sunset,omitempty"`
+	Link        string     `json:"link,omitempty"`
+	Replacement string     `json:"replacement,omitempty"`
+}
+
+// Changelog collects API changes from route metadata. Deprecations are
+// registered where the route is declared, so headers, metrics and the
+// changelog cannot drift apart.
+type Changelog struct {
+	mutex   sync.RWMutex
+	entries []ChangelogEntry
+}
+
+func NewChangelog() *Changelog {
+	return &Changelog{}
+}
+
+// Add records a change that is not a deprecation.
+func (cl *Changelog) Add(entry ChangelogEntry) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	cl.entries = append(cl.entries, entry)
+}
+
+// Deprecate records the deprecation of method path and returns middleware
+// for that route which sets the Deprecation (RFC 9745), Sunset (RFC 8594)
+// and Link headers and counts usage.
+func (cl *Changelog) Deprecate(method, path, description string, d Deprecation) gin.HandlerFunc {
+	entry := ChangelogEntry{
+		Date:        d.Since,
+		Type:        ChangeDeprecated,
+		Method:      method,
+		Path:        path,
+		Description: description,
+		Link:        d.Link,
+		Replacement: d.Replacement,
+	}
+	if !d.Sunset.IsZero() {
+		sunset := d.Sunset
+		entry.Sunset = &sunset
+	}
+	cl.Add(entry)
+
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Link != "" {
+			c.Header("Link", "<"+d.Link+">; rel=\"deprecation\"")
+		}
+
+		deprecatedRequests.WithLabelValues(method, path).Inc()
+		c.Next()
+	}
+}
+
+// Entries returns the changelog, newest first.
+func (cl *Changelog) Entries() []ChangelogEntry {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+
+	entries := make([]ChangelogEntry, len(cl.entries))
+	copy(entries, cl.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	return entries
+}
+
+// Handler serves the changelog as JSON.
+func (cl *Changelog) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"changes": cl.Entries()})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelog_Deprecate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	since := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	changelog := NewChangelog()
+	changelog.Add(ChangelogEntry{Date: since.Add(-time.Hour), Type: ChangeAdded, Description: "older"})
+	router := gin.New()
+	router.GET("/old", changelog.Deprecate(http.MethodGet, "/old", "Use /new", Deprecation{
+		Since:       since,
+		Sunset:      sunset,
+		Link:        "https://example.com/migrate",
+		Replacement: "GET /new",
+	}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1767312000", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/migrate>; rel="deprecation"`, w.Header().Get("Link"))
+
+	entries := changelog.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, ChangeDeprecated, entries[0].Type)
+	assert.Equal(t, "GET /new", entries[0].Replacement)
+	assert.Equal(t, sunset, *entries[0].Sunset)
+	assert.Equal(t, "older", entries[1].Description)
+}
@@ -0,0 +1,137 @@
+// internal/middleware/drain.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconnectHint is sent to streaming clients when the server starts draining
+// so they can reconnect to another replica.
+type ReconnectHint struct {
+	Type         string `json:"type"`
+	Reason       string `json:"reason"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+// Drainer coordinates lame-duck mode during shutdown: new streaming
+// connections are refused, open streams are told to reconnect elsewhere, and
+// shutdown waits for them to go away.
+type Drainer struct {
+	retryAfter time.Duration
+	draining   atomic.Bool
+	notify     chan struct{}
+	once       sync.Once
+	streams    sync.WaitGroup
+	active     atomic.Int64
+}
+
+func NewDrainer(retryAfter time.Duration) *Drainer {
+	return &Drainer{
+		retryAfter: retryAfter,
+		notify:     make(chan struct{}),
+	}
+}
+
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// StartDraining switches the server into lame-duck mode. It is safe to call
+// more than once.
+func (d *Drainer) StartDraining() {
+	d.once.Do(func() {
+		d.draining.Store(true)
+		close(d.notify)
+	})
+}
+
+// Hint returns the reconnect hint streaming handlers should send when the
+// drain channel fires.
+func (d *Drainer) Hint() ReconnectHint {
+	return ReconnectHint{
+		Type:         "reconnect",
+		Reason:       "server_draining",
+		RetryAfterMs: d.retryAfter.Milliseconds(),
+	}
+}
+
+// TrackStream registers a long-lived connection. The returned channel is
+// closed when draining starts; done must be called when the stream ends.
+// ok is false if the server is already draining.
+func (d *Drainer) TrackStream() (drain <-chan struct{}, done func(), ok bool) {
+	if d.Draining() {
+		return nil, nil, false
+	}
+
+	d.streams.Add(1)
+	d.active.Add(1)
+
+	var doneOnce sync.Once
+	return d.notify, func() {
+		doneOnce.Do(func() {
+			d.active.Add(-1)
+			d.streams.Done()
+		})
+	}, true
+}
+
+// ActiveStreams returns the number of streaming connections still open.
+func (d *Drainer) ActiveStreams() int64 {
+	return d.active.Load()
+}
+
+// Wait blocks until every tracked stream has finished or ctx expires.
+func (d *Drainer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LameDuck refuses new connections to the stream routes while draining and
+// asks HTTP clients to close their keep-alive connections.
+func LameDuck(d *Drainer, streams []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !d.Draining() {
+			c.Next()
+			return
+		}
+
+		c.Header("Connection", "close")
+
+		if isStreamingRequest(c, streams) {
+			c.Header("Retry-After", strconv.Itoa(int(d.retryAfter.Seconds())))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":     "Server is draining",
+				"message":   "Please reconnect",
+				"reconnect": d.Hint(),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isStreamingRequest tells whether the request is for one of the stream
+// routes. It goes by the matched route rather than Accept or Upgrade
+// headers, which any client can send to skip lanes, shedding and timeouts.
+func isStreamingRequest(c *gin.Context, streams []string) bool {
+	return matchRoute(streams, c.FullPath())
+}
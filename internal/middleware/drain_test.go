@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLameDuck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	drainer := NewDrainer(time.Second)
+	drainer.StartDraining()
+	router := gin.New()
+	router.Use(LameDuck(drainer, []string{"/stream", "/stream/ws"}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/stream", ok)
+	router.GET("/stream/ws", ok)
+	router.GET("/bots", ok)
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{"stream route", "/stream", nil, http.StatusServiceUnavailable},
+		{"websocket route", "/stream/ws", nil, http.StatusServiceUnavailable},
+		{"event stream accept elsewhere", "/bots", map[string]string{"Accept": "text/event-stream"}, http.StatusOK},
+		{"websocket upgrade elsewhere", "/bots", map[string]string{"Upgrade": "websocket"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "close", w.Header().Get("Connection"))
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// internal/middleware/jsonlimits.go
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JSONLimits struct {
+	MaxBodyBytes   int64
+	MaxDepth       int
+	MaxArrayLength int
+	MaxStringBytes int
+	// UploadRoutes take non-JSON bodies larger than MaxBodyBytes and enforce
+	// their own limits, e.g. "/api/v1/user/avatar"
+	UploadRoutes []string
+}
+
+// jsonLimitError describes which limit a payload violated.
+type jsonLimitError struct {
+	Code  string
+	Limit int64
+	Path  string
+}
+
+func (e *jsonLimitError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s (limit %d)", e.Code, e.Limit)
+	}
+	return fmt.Sprintf("%s at %s (limit %d)", e.Code, e.Path, e.Limit)
+}
+
+// JSONBodyLimits rejects oversized or pathologically nested JSON payloads
+// before any handler binds them. Zero limits are not enforced. The
+// Content-Type is not trusted, since ShouldBindJSON ignores it: every body
+// is capped at MaxBodyBytes and every body that looks like JSON is checked.
+func JSONBodyLimits(limits JSONLimits) gin.HandlerFunc {
+	uploads := make(map[string]bool, len(limits.UploadRoutes))
+	for _, route := range limits.UploadRoutes {
+		uploads[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody || uploads[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		reader := io.Reader(c.Request.Body)
+		if limits.MaxBodyBytes > 0 {
+			// Read one extra byte to detect bodies over the limit
+			reader = io.LimitReader(c.Request.Body, limits.MaxBodyBytes+1)
+		}
+
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		if limits.MaxBodyBytes > 0 && int64(len(body)) > limits.MaxBodyBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Request body too large",
+				"code":  "max_body_bytes",
+				"limit": limits.MaxBodyBytes,
+			})
+			c.Abort()
+			return
+		}
+
+		if !looksLikeJSON(body) {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Next()
+			return
+		}
+		if err := checkJSONLimits(body, limits); err != nil {
+			var limitErr *jsonLimitError
+			if errors.As(err, &limitErr) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Request body exceeds JSON limits",
+					"code":  limitErr.Code,
+					"limit": limitErr.Limit,
+					"path":  limitErr.Path,
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Malformed JSON body",
+					"message": err.Error(),
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// looksLikeJSON reports whether body is a JSON object or array. Form
+// encoded bodies never start with either, and scalars cannot nest.
+func looksLikeJSON(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// jsonFrame tracks an open object or array while walking the token stream.
+type jsonFrame struct {
+	array bool
+	count int
+	key   string
+}
+
+// checkJSONLimits walks the token stream without building the document, so
+// the check itself cannot be used to exhaust memory.
+func checkJSONLimits(body []byte, limits JSONLimits) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var stack []*jsonFrame
+	expectKey := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Object keys arrive as plain strings; only check their size
+		if expectKey {
+			if delim, ok := token.(json.Delim); ok && delim == '}' {
+				stack = stack[:len(stack)-1]
+				expectKey = len(stack) > 0 && !stack[len(stack)-1].array
+				continue
+			}
+			key := token.(string)
+			if limits.MaxStringBytes > 0 && len(key) > limits.MaxStringBytes {
+				return &jsonLimitError{Code: "max_string_bytes", Limit: int64(limits.MaxStringBytes), Path: jsonPath(stack)}
+			}
+			stack[len(stack)-1].key = key
+			expectKey = false
+			continue
+		}
+
+		if len(stack) > 0 && stack[len(stack)-1].array {
+			if delim, ok := token.(json.Delim); !ok || delim != ']' {
+				top := stack[len(stack)-1]
+				top.count++
+				if limits.MaxArrayLength > 0 && top.count > limits.MaxArrayLength {
+					return &jsonLimitError{Code: "max_array_length", Limit: int64(limits.MaxArrayLength), Path: jsonPath(stack)}
+				}
+			}
+		}
+
+		switch value := token.(type) {
+		case json.Delim:
+			switch value {
+			case '{', '[':
+				stack = append(stack, &jsonFrame{array: value == '['})
+				if limits.MaxDepth > 0 && len(stack) > limits.MaxDepth {
+					return &jsonLimitError{Code: "max_depth", Limit: int64(limits.MaxDepth), Path: jsonPath(stack[:len(stack)-1])}
+				}
+				expectKey = value == '{'
+				continue
+			case ']':
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if limits.MaxStringBytes > 0 && len(value) > limits.MaxStringBytes {
+				return &jsonLimitError{Code: "max_string_bytes", Limit: int64(limits.MaxStringBytes), Path: jsonPath(stack)}
+			}
+		}
+
+		// After a value inside an object the next token is a key
+		expectKey = len(stack) > 0 && !stack[len(stack)-1].array
+	}
+
+	return nil
+}
+
+// jsonPath renders the location of the current token, e.g. "$.params[3]".
+func jsonPath(stack []*jsonFrame) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, frame := range stack {
+		if frame.array {
+			fmt.Fprintf(&b, "[%d]", frame.count-1)
+		} else if frame.key != "" {
+			b.WriteString(".")
+			b.WriteString(frame.key)
+		}
+	}
+	return b.String()
+}
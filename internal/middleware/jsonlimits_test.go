@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func jsonLimitsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(JSONBodyLimits(JSONLimits{
+		MaxBodyBytes:   64,
+		MaxDepth:       2,
+		MaxArrayLength: 3,
+		MaxStringBytes: 8,
+		UploadRoutes:   []string{"/upload"},
+	}))
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	router.POST("/bind", echo)
+	router.POST("/upload", echo)
+	return router
+}
+
+func TestJSONBodyLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"json within limits", "/bind", "application/json", `{"a":[1,2]}`, http.StatusOK},
+		{"too deep", "/bind", "application/json", `{"a":{"b":{}}}`, http.StatusBadRequest},
+		{"too deep without content type", "/bind", "", `{"a":{"b":{}}}`, http.StatusBadRequest},
+		{"too deep as text", "/bind", "text/plain", `{"a":{"b":{}}}`, http.StatusBadRequest},
+		{"long array as form", "/bind", "application/x-www-form-urlencoded", `[1,2,3,4]`, http.StatusBadRequest},
+		{"long string as multipart", "/bind", "multipart/form-data; boundary=x", `["123456789"]`, http.StatusBadRequest},
+		{"form body", "/bind", "application/x-www-form-urlencoded", `token=abc&token_type_hint=access_token`, http.StatusOK},
+		{"oversized form body", "/bind", "application/x-www-form-urlencoded", "token=" + strings.Repeat("a", 64), http.StatusRequestEntityTooLarge},
+		{"oversized upload", "/upload", "multipart/form-data; boundary=x", strings.Repeat("a", 128), http.StatusOK},
+	}
+
+	router := jsonLimitsRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, w.Body.String())
+			}
+		})
+	}
+}
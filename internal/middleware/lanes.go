@@ -0,0 +1,284 @@
+// internal/middleware/lanes.go
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	laneQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_lane_queue_depth",
+		Help: "Requests waiting for a worker, by lane.",
+	}, []string{"lane"})
+
+	laneInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_lane_in_flight",
+		Help: "Requests being served, by lane.",
+	}, []string{"lane"})
+
+	laneWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_lane_wait_seconds",
+		Help:    "Time requests waited for a worker, by lane.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"lane"})
+
+	laneRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_lane_rejected_total",
+		Help: "Requests rejected without being served, by lane and reason: queue_full or timeout.",
+	}, []string{"lane", "reason"})
+)
+
+// Request lanes, from most to least urgent
+const (
+	LaneOrders    = "orders"
+	LaneDefault   = "default"
+	LaneAnalytics = "analytics"
+)
+
+// Lane sizes one class of traffic.
+type Lane struct {
+	// Workers are reserved for the lane; other lanes never use them
+	Workers int
+	// QueueDepth bounds the requests waiting for a worker; more are
+	// rejected right away
+	QueueDepth int
+	// Weight is the lane's share of the shared workers while several
+	// lanes are waiting for them
+	Weight int
+}
+
+type LaneLimits struct {
+	Orders    Lane
+	Default   Lane
+	Analytics Lane
+	// Shared workers serve any lane once its own are busy
+	Shared int
+	// MaxWait bounds the time a request waits for a worker
+	MaxWait    time.Duration
+	RetryAfter time.Duration
+}
+
+// LaneRoutes assigns routes to lanes by their gin full path, matched like
+// ShedPriorities. Order routes only apply to requests that change state,
+// analytics routes only to GET requests; everything else uses the default
+// lane.
+type LaneRoutes struct {
+	Orders    []string
+	Analytics []string
+	// Streams are the long-lived routes, which bypass the lanes
+	Streams []string
+}
+
+func (r LaneRoutes) classify(c *gin.Context) string {
+	path := c.FullPath()
+	if c.Request.Method == http.MethodGet {
+		if matchRoute(r.Analytics, path) {
+			return LaneAnalytics
+		}
+		return LaneDefault
+	}
+	if matchRoute(r.Orders, path) {
+		return LaneOrders
+	}
+	return LaneDefault
+}
+
+// LaneScheduler runs requests on per-lane worker pools so that a flood of
+// list and analytics requests cannot delay order placement. A freed shared
+// worker goes to the waiting lanes in proportion to their weights, using
+// smooth weighted round robin.
+type LaneScheduler struct {
+	limits LaneLimits
+
+	mutex  sync.Mutex
+	lanes  map[string]*lane
+	order  []*lane
+	shared int
+}
+
+type lane struct {
+	Lane
+	name    string
+	busy    int
+	waiters list.List // of chan bool; true grants a shared worker
+	credit  int
+}
+
+func NewLaneScheduler(limits LaneLimits) *LaneScheduler {
+	s := &LaneScheduler{limits: limits, lanes: make(map[string]*lane), shared: limits.Shared}
+	for _, l := range []*lane{
+		{Lane: limits.Orders, name: LaneOrders},
+		{Lane: limits.Default, name: LaneDefault},
+		{Lane: limits.Analytics, name: LaneAnalytics},
+	} {
+		if l.Weight < 1 {
+			l.Weight = 1
+		}
+		s.lanes[l.name] = l
+		s.order = append(s.order, l)
+	}
+	return s
+}
+
+// acquire waits for a worker of the lane. It reports whether the worker is
+// a shared one, which must be passed back to release.
+func (s *LaneScheduler) acquire(ctx context.Context, name string) (shared bool, reason string) {
+	s.mutex.Lock()
+	l := s.lanes[name]
+	// Waiting requests are served first; newcomers only skip the queue
+	// when it is empty
+	if l.waiters.Len() == 0 {
+		if l.busy < l.Workers {
+			l.busy++
+			s.mutex.Unlock()
+			return false, ""
+		}
+		if s.shared > 0 && !s.anyWaiting() {
+			s.shared--
+			s.mutex.Unlock()
+			return true, ""
+		}
+	}
+	if l.waiters.Len() >= l.QueueDepth {
+		s.mutex.Unlock()
+		return false, "queue_full"
+	}
+	grant := make(chan bool, 1)
+	element := l.waiters.PushBack(grant)
+	laneQueueDepth.WithLabelValues(name).Set(float64(l.waiters.Len()))
+	s.mutex.Unlock()
+
+	select {
+	case shared := <-grant:
+		return shared, ""
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case shared := <-grant:
+		// Granted while giving up; hand the worker on
+		s.handOff(l, shared)
+	default:
+		l.waiters.Remove(element)
+		laneQueueDepth.WithLabelValues(name).Set(float64(l.waiters.Len()))
+	}
+	return false, "timeout"
+}
+
+// release returns a worker taken by acquire.
+func (s *LaneScheduler) release(name string, shared bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handOff(s.lanes[name], shared)
+}
+
+// handOff passes a freed worker to the next waiting request. A lane's own
+// worker stays with the lane; a shared one goes to the lane picked by
+// weight. Callers hold the mutex.
+func (s *LaneScheduler) handOff(l *lane, shared bool) {
+	if !shared {
+		if l.waiters.Len() > 0 {
+			s.grant(l, false)
+			return
+		}
+		l.busy--
+		return
+	}
+
+	if next := s.nextWeighted(); next != nil {
+		s.grant(next, true)
+		return
+	}
+	s.shared++
+}
+
+func (s *LaneScheduler) grant(l *lane, shared bool) {
+	grant := l.waiters.Remove(l.waiters.Front()).(chan bool)
+	grant <- shared
+	laneQueueDepth.WithLabelValues(l.name).Set(float64(l.waiters.Len()))
+}
+
+// nextWeighted picks the waiting lane with the most credit after adding
+// each its weight, then charges it the total, so over time every lane gets
+// shared workers in proportion to its weight.
+func (s *LaneScheduler) nextWeighted() *lane {
+	var best *lane
+	total := 0
+	for _, l := range s.order {
+		if l.waiters.Len() == 0 {
+			continue
+		}
+		l.credit += l.Weight
+		total += l.Weight
+		if best == nil || l.credit > best.credit {
+			best = l
+		}
+	}
+	if best != nil {
+		best.credit -= total
+	}
+	return best
+}
+
+func (s *LaneScheduler) anyWaiting() bool {
+	for _, l := range s.order {
+		if l.waiters.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestLanes makes requests wait for a worker of their lane before being
+// served, rejecting them with 503 when the lane's queue is full or the
+// wait runs out. Streams are long-lived by design and bypass the lanes.
+func RequestLanes(s *LaneScheduler, routes LaneRoutes) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isStreamingRequest(c, routes.Streams) {
+			c.Next()
+			return
+		}
+
+		name := routes.classify(c)
+		ctx := c.Request.Context()
+		if s.limits.MaxWait > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.limits.MaxWait)
+			defer cancel()
+		}
+
+		start := time.Now()
+		shared, reason := s.acquire(ctx, name)
+		if reason != "" {
+			laneRejected.WithLabelValues(name, reason).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(s.limits.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Server is busy",
+				"message": "Please retry later",
+				"lane":    name,
+			})
+			return
+		}
+		laneWait.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		laneInFlight.WithLabelValues(name).Inc()
+		defer func() {
+			laneInFlight.WithLabelValues(name).Dec()
+			s.release(name, shared)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,194 @@
+// internal/middleware/loadshed.go
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	shedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loadshed_requests_shed_total",
+		Help: "Requests rejected by load shedding, by priority.",
+	}, []string{"priority"})
+
+	shedLoad = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "loadshed_load",
+		Help: "Gateway load relative to the shedding thresholds; low priority traffic is shed above 1.",
+	})
+
+	shedInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "loadshed_in_flight",
+		Help: "Requests currently being served, excluding streams.",
+	})
+)
+
+// latencyDecay is the time constant of the latency average. It also lets
+// the average fall back while everything is being shed and no fresh
+// samples arrive.
+const latencyDecay = 5 * time.Second
+
+// Priority orders traffic for load shedding; lower priorities go first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	// PriorityCritical traffic, e.g. auth and order placement, is never shed
+	PriorityCritical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+type LoadShedLimits struct {
+	// MaxInFlight is the number of concurrent requests above which low
+	// priority traffic is shed
+	MaxInFlight int64
+	// LatencyTarget is the average request latency above which low
+	// priority traffic is shed
+	LatencyTarget time.Duration
+	// NormalFactor is how far over either limit the gateway must be before
+	// normal priority traffic is shed as well
+	NormalFactor float64
+	RetryAfter   time.Duration
+}
+
+// LoadShedder tracks in-flight requests and a decaying average of their
+// latency, and decides which priorities the gateway still admits.
+type LoadShedder struct {
+	limits   LoadShedLimits
+	inFlight atomic.Int64
+
+	mutex   sync.Mutex
+	latency float64 // seconds
+	sampled time.Time
+}
+
+func NewLoadShedder(limits LoadShedLimits) *LoadShedder {
+	if limits.NormalFactor < 1 {
+		limits.NormalFactor = 1
+	}
+	return &LoadShedder{limits: limits}
+}
+
+// Load is the larger of in-flight requests and average latency relative
+// to their limits. Zero limits are ignored.
+func (s *LoadShedder) Load() float64 {
+	var load float64
+	if s.limits.MaxInFlight > 0 {
+		load = float64(s.inFlight.Load()) / float64(s.limits.MaxInFlight)
+	}
+	if s.limits.LatencyTarget > 0 {
+		s.mutex.Lock()
+		latency := s.latency * math.Exp(-time.Since(s.sampled).Seconds()/latencyDecay.Seconds())
+		s.mutex.Unlock()
+		load = math.Max(load, latency/s.limits.LatencyTarget.Seconds())
+	}
+	return load
+}
+
+func (s *LoadShedder) admit(priority Priority) bool {
+	switch priority {
+	case PriorityCritical:
+		return true
+	case PriorityNormal:
+		return s.Load() < s.limits.NormalFactor
+	}
+	return s.Load() < 1
+}
+
+// observe folds a request's latency into the average, weighting it by the
+// time since the previous sample.
+func (s *LoadShedder) observe(d time.Duration) {
+	s.mutex.Lock()
+	now := time.Now()
+	weight := 1 - math.Exp(-now.Sub(s.sampled).Seconds()/latencyDecay.Seconds())
+	// Bursts of samples still move the average a little each
+	weight = math.Max(weight, 0.01)
+	s.latency += weight * (d.Seconds() - s.latency)
+	s.sampled = now
+	s.mutex.Unlock()
+
+	shedLoad.Set(s.Load())
+}
+
+// ShedPriorities classifies routes by their gin full path. Entries ending
+// in "/" match as prefixes, others exactly. Low priority only applies to
+// GET requests; everything unlisted is normal priority.
+type ShedPriorities struct {
+	Critical []string
+	Low      []string
+	// Streams are the long-lived routes, which are never shed
+	Streams []string
+}
+
+func (p ShedPriorities) classify(c *gin.Context) Priority {
+	path := c.FullPath()
+	if matchRoute(p.Critical, path) {
+		return PriorityCritical
+	}
+	if c.Request.Method == http.MethodGet && matchRoute(p.Low, path) {
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+func matchRoute(routes []string, path string) bool {
+	for _, route := range routes {
+		if path == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(path, route)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadShed rejects requests with 503 once the gateway is overloaded, low
+// priority traffic first. Streams are long-lived by design, so they are
+// neither shed nor counted.
+func LoadShed(s *LoadShedder, priorities ShedPriorities) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isStreamingRequest(c, priorities.Streams) {
+			c.Next()
+			return
+		}
+
+		priority := priorities.classify(c)
+		if !s.admit(priority) {
+			shedRequests.WithLabelValues(priority.String()).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(s.limits.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Server is overloaded",
+				"message": "Please retry later",
+			})
+			return
+		}
+
+		shedInFlight.Set(float64(s.inFlight.Add(1)))
+		start := time.Now()
+		defer func() {
+			shedInFlight.Set(float64(s.inFlight.Add(-1)))
+			s.observe(time.Since(start))
+		}()
+
+		c.Next()
+	}
+}
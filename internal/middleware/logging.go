@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
 )
 
 type bodyLogWriter struct {
@@ -32,6 +33,7 @@ func StructuredLogging() gin.HandlerFunc {
 			"latency":     param.Latency,
 			"user_agent":  param.Request.UserAgent(),
 			"error":       param.ErrorMessage,
+			"commit":      buildinfo.ShortCommit(),
 		})
 
 		if param.StatusCode >= 400 {
@@ -88,6 +90,7 @@ func RequestLogging() gin.HandlerFunc {
 			"latency":      latency,
 			"user_agent":   c.Request.UserAgent(),
 			"request_size": c.Request.ContentLength,
+			"commit":       buildinfo.ShortCommit(),
 		}
 
 		// Add user ID if authenticated
@@ -122,6 +125,7 @@ func isSensitiveEndpoint(path string) bool {
 		"/api/v1/auth/login",
 		"/api/v1/auth/register",
 		"/api/v1/user/change-password",
+		"/api/v1/user/change-email",
 	}
 
 	for _, endpoint := range sensitiveEndpoints {
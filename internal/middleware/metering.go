@@ -0,0 +1,56 @@
+// internal/middleware/metering.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/metering"
+)
+
+// Metering records each authenticated request for the user's API usage
+// report. It must run before the per-IP limit: requests that limit rejects
+// never reach authentication, so they are attributed to the bearer token's
+// user when keys verifies its signature. Unverifiable tokens are not
+// counted, so nobody can fill another user's report.
+func Metering(recorder *metering.Recorder, keys *auth.KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID := c.GetString("user_id")
+		if userID == "" && c.Writer.Status() == http.StatusTooManyRequests {
+			userID = tokenUser(c, keys)
+		}
+		if userID == "" {
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		recorder.Record(metering.Event{
+			UserID:   userID,
+			Endpoint: c.Request.Method + " " + route,
+			Status:   c.Writer.Status(),
+			Time:     time.Now(),
+		})
+	}
+}
+
+// tokenUser returns the user of the request's bearer token if its
+// signature verifies against keys.
+func tokenUser(c *gin.Context, keys *auth.KeySet) string {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || keys == nil {
+		return ""
+	}
+	claims, err := keys.VerifyAccessToken(token)
+	if err != nil {
+		return ""
+	}
+	return claims.UserID
+}
@@ -24,6 +24,27 @@ var (
 		Name: "http_active_connections",
 		Help: "Number of active HTTP connections.",
 	})
+
+	backendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_backend_duration_seconds",
+		Help: "Duration of HTTP requests by backend service and deployment variant.",
+	}, []string{"service", "variant", "status"})
+
+	rateLimitQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ratelimit_queue_depth",
+		Help: "Number of requests waiting for a rate limit slot.",
+	})
+
+	rateLimitQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ratelimit_queue_wait_seconds",
+		Help:    "Time requests spent queued for a rate limit slot.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2, 5},
+	})
+
+	rateLimitRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimit_queue_rejected_total",
+		Help: "Requests rejected by queuing rate limiters, by reason.",
+	}, []string{"reason"})
 )
 
 func Metrics() gin.HandlerFunc {
@@ -42,5 +63,10 @@ func Metrics() gin.HandlerFunc {
 		httpDuration.WithLabelValues(path, method, status).Observe(duration.Seconds())
 		httpRequests.WithLabelValues(path, method, status).Inc()
 		activeConnections.Dec()
+
+		// Split by canary/stable variant when the handler routed to a backend
+		if service := c.GetString("backend_service"); service != "" {
+			backendDuration.WithLabelValues(service, c.GetString("backend_variant"), status).Observe(duration.Seconds())
+		}
 	}
 }
@@ -0,0 +1,60 @@
+// internal/middleware/org.go
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tradingbothub/platform/internal/org"
+)
+
+// OrgHeader selects the organization whose workspace a request works in.
+const OrgHeader = "X-Org-ID"
+
+// Organizations resolves a user's membership in an organization.
+type Organizations interface {
+	MemberOf(ctx context.Context, orgID, userID string) (*org.Membership, error)
+}
+
+// OrgContext puts the request in the workspace of the organization named
+// by the X-Org-ID header, setting "org_id", "org_role" and "org" for the
+// handlers. Users must be members; viewers may only use GET and HEAD.
+// Requests without the header stay in the user's personal workspace. It
+// must run after JWTAuth.
+func OrgContext(orgs Organizations) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.GetHeader(OrgHeader)
+		if orgID == "" {
+			c.Next()
+			return
+		}
+
+		membership, err := orgs.MemberOf(c.Request.Context(), orgID, c.GetString("user_id"))
+		if errors.Is(err, org.ErrNotMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of the organization"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to resolve organization %s: %v", orgID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve organization"})
+			c.Abort()
+			return
+		}
+
+		readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if !readOnly && !membership.Role.CanWrite() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role in the organization is read-only"})
+			c.Abort()
+			return
+		}
+
+		c.Set("org_id", membership.ID)
+		c.Set("org_role", membership.Role)
+		c.Set("org", membership)
+		c.Next()
+	}
+}
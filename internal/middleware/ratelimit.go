@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -65,6 +66,57 @@ func (rl *rateLimiter) Allow(key string) bool {
 	return true
 }
 
+// Reserve takes a slot for key. When the window is full it books the next
+// slot that frees up, as long as that is at most maxWait away, and returns
+// the time the caller may use it. Booked slots are stored as future
+// timestamps so later callers queue behind them; a caller that gives up
+// before its slot must Cancel it. When no slot is booked the returned time
+// is when the next one opens.
+func (rl *rateLimiter) Reserve(key string, maxWait time.Duration) (time.Time, bool) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := time.Now()
+	windowStart := now.Add(-rl.window)
+
+	validRequests := make([]time.Time, 0)
+	for _, reqTime := range rl.requests[key] {
+		if reqTime.After(windowStart) {
+			validRequests = append(validRequests, reqTime)
+		}
+	}
+
+	if len(validRequests) < rl.limit {
+		rl.requests[key] = append(validRequests, now)
+		return now, true
+	}
+
+	// The next slot opens when the request limit places back leaves the window
+	at := validRequests[len(validRequests)-rl.limit].Add(rl.window)
+	if at.Sub(now) > maxWait {
+		rl.requests[key] = validRequests
+		return at, false
+	}
+
+	rl.requests[key] = append(validRequests, at)
+	return at, true
+}
+
+// Cancel gives back a slot booked by Reserve that was never used, so it
+// does not count against key for a whole window.
+func (rl *rateLimiter) Cancel(key string, slot time.Time) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	requests := rl.requests[key]
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Equal(slot) {
+			rl.requests[key] = append(requests[:i:i], requests[i+1:]...)
+			return
+		}
+	}
+}
+
 func (rl *rateLimiter) cleanup() {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
@@ -95,24 +147,118 @@ func RateLimit() gin.HandlerFunc {
 }
 
 func RateLimitWithLimiter(rl *rateLimiter) gin.HandlerFunc {
+	return RateLimitWithAccessList(rl, nil)
+}
+
+// RateLimitWithAccessList rejects denylisted clients outright and lets
+// allowlisted clients bypass the limiter.
+func RateLimitWithAccessList(rl *rateLimiter, al *AccessList) gin.HandlerFunc {
+	return RateLimitWithQueue(rl, al, 0)
+}
+
+// RateLimitWithQueue is RateLimitWithAccessList with soft limiting: a client
+// slightly over its limit is held for up to maxWait until a slot frees
+// instead of getting a 429. Meant for trading endpoints, where rejecting a
+// closing order is worse than delaying it. A maxWait of zero disables
+// queuing.
+func RateLimitWithQueue(rl *rateLimiter, al *AccessList, maxWait time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Use IP address as the key
-		key := c.ClientIP()
+		ip := c.ClientIP()
+		key := ip
 
 		// For authenticated requests, use user ID
-		if userID, exists := c.Get("user_id"); exists {
-			key = userID.(string)
+		userID := c.GetString("user_id")
+		if userID != "" {
+			key = userID
+		}
+
+		if al != nil {
+			allowed, denied := al.Check(ip, userID)
+			if denied {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "Access denied",
+					"message": "Your access to this API has been blocked",
+				})
+				c.Abort()
+				return
+			}
+			if allowed {
+				c.Next()
+				return
+			}
 		}
 
-		if !rl.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests, please try again later",
-			})
-			c.Abort()
+		if maxWait <= 0 {
+			if !rl.Allow(key) {
+				rejectRateLimited(c, 0)
+				return
+			}
+			c.Next()
 			return
 		}
 
+		// Never hold a request past its own deadline
+		budget := maxWait
+		if deadline, ok := c.Request.Context().Deadline(); ok {
+			if remaining := time.Until(deadline); remaining < budget {
+				budget = remaining
+			}
+		}
+
+		slot, ok := rl.Reserve(key, budget)
+		wait := time.Until(slot)
+		if !ok {
+			rateLimitRejected.WithLabelValues("limit").Inc()
+			rejectRateLimited(c, wait)
+			return
+		}
+
+		if wait > 0 {
+			rateLimitQueueDepth.Inc()
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+				rateLimitQueueDepth.Dec()
+				rateLimitQueueWait.Observe(wait.Seconds())
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				rl.Cancel(key, slot)
+				rateLimitQueueDepth.Dec()
+				rateLimitRejected.WithLabelValues("cancelled").Inc()
+				c.AbortWithStatus(http.StatusRequestTimeout)
+				return
+			}
+		}
+
 		c.Next()
 	}
 }
+
+// RateLimitWithQueuedRoutes is RateLimitWithQueue for a limiter in front of
+// every route: requests to the given routes, matched by gin full path like
+// ShedPriorities, queue for up to maxWait and all others are rejected
+// outright. A limiter that runs before a route's own queuing limiter must
+// queue as well, or its 429 comes first and the queue is never reached.
+func RateLimitWithQueuedRoutes(rl *rateLimiter, al *AccessList, maxWait time.Duration, routes []string) gin.HandlerFunc {
+	queued := RateLimitWithQueue(rl, al, maxWait)
+	direct := RateLimitWithQueue(rl, al, 0)
+	return func(c *gin.Context) {
+		if matchRoute(routes, c.FullPath()) {
+			queued(c)
+			return
+		}
+		direct(c)
+	}
+}
+
+func rejectRateLimited(c *gin.Context, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":   "Rate limit exceeded",
+		"message": "Too many requests, please try again later",
+	})
+	c.Abort()
+}
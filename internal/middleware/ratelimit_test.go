@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_CancelGivesBackSlot(t *testing.T) {
+	rl := NewRateLimiter(1, time.Minute)
+
+	_, ok := rl.Reserve("user-1", 0)
+	assert.True(t, ok)
+
+	slot, ok := rl.Reserve("user-1", 2*time.Minute)
+	assert.True(t, ok)
+	rl.Cancel("user-1", slot)
+
+	// Without the cancel the next slot would be two windows out
+	again, ok := rl.Reserve("user-1", 90*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, slot, again)
+}
+
+func TestRateLimitWithQueuedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimitWithQueuedRoutes(NewRateLimiter(1, 100*time.Millisecond), nil, time.Second, []string{"/orders/"}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/orders/cancel-all", ok)
+	router.GET("/bots", ok)
+
+	call := func(method, path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/orders/cancel-all"))
+	// Over the limit: queued routes wait for the slot, others are rejected
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/orders/cancel-all"))
+	assert.Equal(t, http.StatusTooManyRequests, call(http.MethodGet, "/bots"))
+}
+
+func TestRateLimitWithQueue_CancelledRequestFreesSlot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimiter(1, time.Minute)
+	router := gin.New()
+	router.Use(RateLimitWithQueue(rl, nil, 2*time.Minute))
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A client that hangs up while queued, rather than one whose deadline
+	// is too close to queue at all
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+
+	_, ok := rl.Reserve("192.0.2.1", 90*time.Second)
+	assert.True(t, ok)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
 )
 
 func Recovery() gin.HandlerFunc {
@@ -19,6 +20,8 @@ func Recovery() gin.HandlerFunc {
 			"method":     c.Request.Method,
 			"client_ip":  c.ClientIP(),
 			"user_agent": c.Request.UserAgent(),
+			"commit":     buildinfo.Get().Commit,
+			"version":    buildinfo.Version,
 		}).Error("Panic recovered")
 
 		// Return error response
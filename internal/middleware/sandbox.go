@@ -0,0 +1,59 @@
+// internal/middleware/sandbox.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+// SandboxRoutes lists what the demo account may use, by gin full path
+// matched like ShedPriorities. Read routes apply to GET requests, write
+// routes to every other method; anything not listed is refused.
+type SandboxRoutes struct {
+	Read  []string
+	Write []string
+}
+
+func (r SandboxRoutes) allows(c *gin.Context) bool {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return matchRoute(r.Read, c.FullPath())
+	}
+	return matchRoute(r.Write, c.FullPath())
+}
+
+// Sandbox fences in the shared demo account: its requests share one tight
+// rate limit and may only reach the listed routes, so one visitor cannot
+// lock the others out or reach anything beyond the demo. Other users pass
+// through.
+func Sandbox(rl *rateLimiter, demoEmail string, routes SandboxRoutes) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("user")
+		if !ok {
+			c.Next()
+			return
+		}
+		user, ok := value.(*authpb.User)
+		if !ok || demoEmail == "" || !strings.EqualFold(user.Email, demoEmail) {
+			c.Next()
+			return
+		}
+
+		if !routes.allows(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available for the demo account"})
+			c.Abort()
+			return
+		}
+
+		// One bucket for the account, not per visitor
+		if !rl.Allow("demo:" + user.Id) {
+			rejectRateLimited(c, 0)
+			return
+		}
+
+		c.Header("X-Demo-Account", "true")
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+)
+
+func TestSandbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(email, method, path string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &authpb.User{Id: "user-1", Email: email})
+		})
+		router.Use(Sandbox(NewRateLimiter(100, time.Minute), "demo@example.com", SandboxRoutes{
+			Read:  []string{"/bots", "/bots/"},
+			Write: []string{"/bots/:id/start"},
+		}))
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		router.GET("/bots", ok)
+		router.GET("/bots/:id", ok)
+		router.POST("/bots/:id/start", ok)
+		router.PUT("/bots/:id/exchange-key", ok)
+		router.GET("/user/exchange-keys", ok)
+		router.POST("/orgs", ok)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	tests := []struct {
+		name   string
+		email  string
+		method string
+		path   string
+		status int
+	}{
+		{"listed read", "demo@example.com", http.MethodGet, "/bots/1", http.StatusOK},
+		{"listed write", "DEMO@example.com", http.MethodPost, "/bots/1/start", http.StatusOK},
+		{"write under a listed read prefix", "demo@example.com", http.MethodPut, "/bots/1/exchange-key", http.StatusForbidden},
+		{"unlisted read", "demo@example.com", http.MethodGet, "/user/exchange-keys", http.StatusForbidden},
+		{"unlisted write", "demo@example.com", http.MethodPost, "/orgs", http.StatusForbidden},
+		{"other users pass", "someone@example.com", http.MethodPost, "/orgs", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, serve(tt.email, tt.method, tt.path))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// internal/middleware/timeout.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/rpc"
+)
+
+var requestTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_request_timeouts_total",
+	Help: "Requests answered 504, by the stage that ran out of time.",
+}, []string{"stage"})
+
+const (
+	// TimeoutStageGateway means the request's own budget ran out
+	TimeoutStageGateway = "gateway"
+	// TimeoutStageDownstream means a backend call ran out of its share
+	TimeoutStageDownstream = "downstream"
+)
+
+// Timeouts are the request budgets by route.
+type Timeouts struct {
+	Default time.Duration
+	// Routes maps "METHOD /route" to its budget; keys are matched case
+	// insensitively since config keys arrive lowercased
+	Routes map[string]time.Duration
+	// Streams are the long-lived routes, which get no deadline
+	Streams []string
+}
+
+// For returns the budget of the route, zero for none.
+func (t Timeouts) For(method, route string) time.Duration {
+	if d, ok := t.Routes[strings.ToLower(method+" "+route)]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// RouteTimeouts gives each request a deadline from its route's budget.
+// Backend calls inherit it, less the rpc deadline margin. When the budget
+// runs out, or a backend call exceeded its share, the handler's response
+// is replaced by a 504 naming the stage that timed out. Handlers must
+// honor the request context; they are not interrupted.
+func RouteTimeouts(timeouts Timeouts) gin.HandlerFunc {
+	routes := make(map[string]time.Duration, len(timeouts.Routes))
+	for route, d := range timeouts.Routes {
+		routes[strings.ToLower(route)] = d
+	}
+	timeouts.Routes = routes
+
+	return func(c *gin.Context) {
+		timeout := timeouts.For(c.Request.Method, c.FullPath())
+		if timeout <= 0 || isStreamingRequest(c, timeouts.Streams) {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		w := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx, timeout: timeout}
+		c.Request = c.Request.WithContext(rpc.WithDeadlineReporter(ctx, w.downstreamTimedOut))
+		c.Writer = w
+
+		c.Next()
+
+		// Handlers that gave up without answering still get the 504
+		if ctx.Err() == context.DeadlineExceeded || w.downstreamService() != "" {
+			w.WriteHeaderNow()
+		}
+	}
+}
+
+// timeoutWriter decides when the response starts whether it is the
+// handler's or a 504. Once a 504 was sent, the handler's output is
+// discarded.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	timeout time.Duration
+
+	mu         sync.Mutex
+	downstream string
+
+	decided  bool
+	timedOut bool
+}
+
+func (w *timeoutWriter) downstreamTimedOut(service string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.downstream == "" {
+		w.downstream = service
+	}
+}
+
+func (w *timeoutWriter) downstreamService() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.downstream
+}
+
+// expired sends the 504 if the budget is gone by the time the response
+// starts, and reports whether it did.
+func (w *timeoutWriter) expired() bool {
+	if w.decided {
+		return w.timedOut
+	}
+	w.decided = true
+
+	service := w.downstreamService()
+	if service == "" && w.ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	w.timedOut = true
+
+	body := gin.H{
+		"error":      "Request timed out",
+		"stage":      TimeoutStageGateway,
+		"timeout_ms": w.timeout.Milliseconds(),
+	}
+	if service != "" {
+		body["stage"] = TimeoutStageDownstream
+		body["service"] = service
+	}
+	requestTimeouts.WithLabelValues(body["stage"].(string)).Inc()
+
+	encoded, _ := json.Marshal(body)
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Del("Content-Disposition")
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.Write(encoded)
+	return true
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.decided && w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.expired() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
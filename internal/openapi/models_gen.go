@@ -0,0 +1,814 @@
+// Package openapi provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
+package openapi
+
+import (
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	BearerAuthScopes = "BearerAuth.Scopes"
+	CookieAuthScopes = "CookieAuth.Scopes"
+)
+
+// Defines values for AuditEventType.
+const (
+	AuditEventTypeAPIKeyCreated        AuditEventType = "api_key_created"
+	AuditEventTypeAPIKeyDeleted        AuditEventType = "api_key_deleted"
+	AuditEventTypeAccountDeactivated   AuditEventType = "account_deactivated"
+	AuditEventTypeAccountReactivated   AuditEventType = "account_reactivated"
+	AuditEventTypeAuthMethodLinked     AuditEventType = "auth_method_linked"
+	AuditEventTypeAuthMethodRemoved    AuditEventType = "auth_method_removed"
+	AuditEventTypeEmailChangeRequested AuditEventType = "email_change_requested"
+	AuditEventTypeEmailChangeUndone    AuditEventType = "email_change_undone"
+	AuditEventTypeEmailChanged         AuditEventType = "email_changed"
+	AuditEventTypeIPAllowlistChanged   AuditEventType = "ip_allowlist_changed"
+	AuditEventTypeLoginFailed          AuditEventType = "login_failed"
+	AuditEventTypeLoginStepUp          AuditEventType = "login_step_up"
+	AuditEventTypeLoginSucceeded       AuditEventType = "login_succeeded"
+	AuditEventTypePasswordChanged      AuditEventType = "password_changed"
+	AuditEventTypePasswordReset        AuditEventType = "password_reset"
+	AuditEventTypePasswordResetForced  AuditEventType = "password_reset_forced"
+	AuditEventTypePlanChanged          AuditEventType = "plan_changed"
+	AuditEventTypeRefreshTokenReused   AuditEventType = "refresh_token_reused"
+	AuditEventTypeRegistered           AuditEventType = "registered"
+	AuditEventTypeRolesChanged         AuditEventType = "roles_changed"
+	AuditEventTypeSessionRevoked       AuditEventType = "session_revoked"
+	AuditEventTypeSessionsRevoked      AuditEventType = "sessions_revoked"
+	AuditEventTypeSsoConnectionCreated AuditEventType = "sso_connection_created"
+	AuditEventTypeSsoConnectionDeleted AuditEventType = "sso_connection_deleted"
+	AuditEventTypeTokenRefreshed       AuditEventType = "token_refreshed"
+)
+
+// Defines values for AuthMethodType.
+const (
+	AuthMethodTypePassword AuthMethodType = "password"
+	AuthMethodTypeSso      AuthMethodType = "sso"
+)
+
+// Defines values for BotStatus.
+const (
+	BotStatusActive  BotStatus = "active"
+	BotStatusError   BotStatus = "error"
+	BotStatusPaused  BotStatus = "paused"
+	BotStatusStopped BotStatus = "stopped"
+)
+
+// Defines values for IPAllowlistMode.
+const (
+	IPAllowlistModeAll     IPAllowlistMode = "all"
+	IPAllowlistModeTrading IPAllowlistMode = "trading"
+)
+
+// Defines values for InvoiceStatus.
+const (
+	InvoiceStatusOpen    InvoiceStatus = "open"
+	InvoiceStatusPaid    InvoiceStatus = "paid"
+	InvoiceStatusPastDue InvoiceStatus = "past_due"
+)
+
+// Defines values for JWKAlg.
+const (
+	JWKAlgEdDSA JWKAlg = "EdDSA"
+	JWKAlgRS256 JWKAlg = "RS256"
+)
+
+// Defines values for JWKKty.
+const (
+	JWKKtyOKP JWKKty = "OKP"
+	JWKKtyRSA JWKKty = "RSA"
+)
+
+// Defines values for KeyEventAction.
+const (
+	KeyEventActionBotStarted  KeyEventAction = "bot_started"
+	KeyEventActionKeyAttached KeyEventAction = "key_attached"
+	KeyEventActionKeyCreated  KeyEventAction = "key_created"
+	KeyEventActionKeyDeleted  KeyEventAction = "key_deleted"
+	KeyEventActionKeyGranted  KeyEventAction = "key_granted"
+	KeyEventActionKeyRevoked  KeyEventAction = "key_revoked"
+)
+
+// Defines values for KeyGrantSubject.
+const (
+	KeyGrantSubjectBot    KeyGrantSubject = "bot"
+	KeyGrantSubjectMember KeyGrantSubject = "member"
+)
+
+// Defines values for LoginEventMethod.
+const (
+	LoginEventMethodMagicLink LoginEventMethod = "magic_link"
+	LoginEventMethodPassword  LoginEventMethod = "password"
+	LoginEventMethodSso       LoginEventMethod = "sso"
+)
+
+// Defines values for LoginEventSuspiciousReasons.
+const (
+	LoginEventSuspiciousReasonsNewCountry LoginEventSuspiciousReasons = "new_country"
+	LoginEventSuspiciousReasonsNewDevice  LoginEventSuspiciousReasons = "new_device"
+)
+
+// Defines values for LoginRequestScopes.
+const (
+	LoginRequestScopesAccountRead   LoginRequestScopes = "account:read"
+	LoginRequestScopesAccountWrite  LoginRequestScopes = "account:write"
+	LoginRequestScopesBotsRead      LoginRequestScopes = "bots:read"
+	LoginRequestScopesBotsWrite     LoginRequestScopes = "bots:write"
+	LoginRequestScopesMarketRead    LoginRequestScopes = "market:read"
+	LoginRequestScopesOrdersRead    LoginRequestScopes = "orders:read"
+	LoginRequestScopesOrdersWrite   LoginRequestScopes = "orders:write"
+	LoginRequestScopesPortfolioRead LoginRequestScopes = "portfolio:read"
+)
+
+// Defines values for OrgRole.
+const (
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleMember OrgRole = "member"
+	OrgRoleOwner  OrgRole = "owner"
+	OrgRoleViewer OrgRole = "viewer"
+)
+
+// Defines values for SetIPAllowlistModeJSONBodyMode.
+const (
+	SetIPAllowlistModeJSONBodyModeAll     SetIPAllowlistModeJSONBodyMode = "all"
+	SetIPAllowlistModeJSONBodyModeTrading SetIPAllowlistModeJSONBodyMode = "trading"
+)
+
+// AllowedNetwork defines model for AllowedNetwork.
+type AllowedNetwork struct {
+	// Cidr Canonical CIDR range; single addresses as /32 or /128
+	Cidr      string             `json:"cidr,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	Label     string             `json:"label,omitempty"`
+}
+
+// AuditEvent defines model for AuditEvent.
+type AuditEvent struct {
+	// ActorID Who acted; differs from user_id when staff changed the account
+	ActorID   string             `json:"actor_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	Details   map[string]string  `json:"details,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	IPAddress string             `json:"ip_address,omitempty"`
+	Type      AuditEventType     `json:"type,omitempty"`
+	UserAgent string             `json:"user_agent,omitempty"`
+
+	// UserID The account the event concerns
+	UserID string `json:"user_id,omitempty"`
+}
+
+// AuditEventType defines model for AuditEvent.type.
+type AuditEventType string
+
+// AuthMethod defines model for AuthMethod.
+type AuthMethod struct {
+	// ConnectionID The identity provider's connection; sso methods only
+	ConnectionID openapi_types.UUID `json:"connection_id,omitempty"`
+	Domain       string             `json:"domain,omitempty"`
+
+	// LinkedAt When the identity was linked, or the password last set
+	LinkedAt     time.Time      `json:"linked_at,omitempty"`
+	Organization string         `json:"organization,omitempty"`
+	Type         AuthMethodType `json:"type,omitempty"`
+}
+
+// AuthMethodType defines model for AuthMethod.type.
+type AuthMethodType string
+
+// AuthResponse Client types configured for cookie sessions, named in the
+// X-Client-Type header when signing in, get an httpOnly session cookie
+// instead of the tokens: the response only holds user and csrf_token.
+// Their requests are authenticated by the cookie; all but GET, HEAD
+// and OPTIONS must send csrf_token in the X-CSRF-Token header, which
+// every cookie authenticated response also carries. The gateway
+// refreshes the session's tokens itself.
+type AuthResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+
+	// CsrfToken Cookie sessions only
+	CsrfToken    string `json:"csrf_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	User         User   `json:"user,omitempty"`
+}
+
+// Avatar defines model for Avatar.
+type Avatar struct {
+	// Avatar Object key of the avatar
+	Avatar    string    `json:"avatar,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
+	// URL Signed URL that downloads the avatar until expires_at
+	URL string `json:"url,omitempty"`
+}
+
+// Bot defines model for Bot.
+type Bot struct {
+	Config      map[string]interface{} `json:"config,omitempty"`
+	CreatedAt   time.Time              `json:"created_at,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Exchange    string                 `json:"exchange,omitempty"`
+	ID          openapi_types.UUID     `json:"id,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+
+	// OrgID The organization the bot belongs to; absent for the owner's own bots
+	OrgID      string             `json:"org_id,omitempty"`
+	Status     BotStatus          `json:"status,omitempty"`
+	StrategyID openapi_types.UUID `json:"strategy_id,omitempty"`
+	UpdatedAt  time.Time          `json:"updated_at,omitempty"`
+}
+
+// BotStatus defines model for Bot.status.
+type BotStatus string
+
+// IPAllowlist defines model for IPAllowlist.
+type IPAllowlist struct {
+	Mode     IPAllowlistMode  `json:"mode,omitempty"`
+	Networks []AllowedNetwork `json:"networks,omitempty"`
+}
+
+// IPAllowlistMode defines model for IPAllowlist.mode.
+type IPAllowlistMode string
+
+// Invitation defines model for Invitation.
+type Invitation struct {
+	CreatedAt time.Time           `json:"created_at,omitempty"`
+	Email     openapi_types.Email `json:"email,omitempty"`
+	ExpiresAt time.Time           `json:"expires_at,omitempty"`
+	ID        openapi_types.UUID  `json:"id,omitempty"`
+	InvitedBy openapi_types.UUID  `json:"invited_by,omitempty"`
+	OrgID     openapi_types.UUID  `json:"org_id,omitempty"`
+
+	// OrgName Only in the invitee's list
+	OrgName string  `json:"org_name,omitempty"`
+	Role    OrgRole `json:"role,omitempty"`
+}
+
+// Invoice A month of the caller's plan and the API requests above those it
+// includes. When a payment fails the invoice becomes past_due and
+// reminders are emailed; if it stays unpaid the account moves to the
+// free plan, which paying the invoice reverts.
+type Invoice struct {
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+
+	// DowngradedFrom The plan the account lost while the invoice is unpaid
+	DowngradedFrom string             `json:"downgraded_from,omitempty"`
+	DueAt          time.Time          `json:"due_at,omitempty"`
+	FailedPayments int                `json:"failed_payments,omitempty"`
+	ID             openapi_types.UUID `json:"id,omitempty"`
+	IssuedAt       time.Time          `json:"issued_at,omitempty"`
+
+	// LastFailure Why the last payment failed
+	LastFailure  string            `json:"last_failure,omitempty"`
+	LineItems    []InvoiceLineItem `json:"line_items,omitempty"`
+	Number       string            `json:"number,omitempty"`
+	PaidAt       time.Time         `json:"paid_at,omitempty"`
+	PastDueSince time.Time         `json:"past_due_since,omitempty"`
+
+	// PeriodEnd Exclusive
+	PeriodEnd   time.Time `json:"period_end,omitempty"`
+	PeriodStart time.Time `json:"period_start,omitempty"`
+
+	// Plan The plan invoiced
+	Plan          string        `json:"plan,omitempty"`
+	RemindersSent int           `json:"reminders_sent,omitempty"`
+	Status        InvoiceStatus `json:"status,omitempty"`
+
+	// Total Decimal amount
+	Total     string    `json:"total,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// InvoiceStatus defines model for Invoice.status.
+type InvoiceStatus string
+
+// InvoiceLineItem defines model for InvoiceLineItem.
+type InvoiceLineItem struct {
+	// Amount Decimal amount
+	Amount      string             `json:"amount,omitempty"`
+	Description string             `json:"description,omitempty"`
+	ID          openapi_types.UUID `json:"id,omitempty"`
+	Quantity    int64              `json:"quantity,omitempty"`
+
+	// UnitPrice Decimal amount
+	UnitPrice string `json:"unit_price,omitempty"`
+}
+
+// JWK Public key; RSA keys set n and e, Ed25519 keys crv and x
+type JWK struct {
+	Alg JWKAlg `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Kty JWKKty `json:"kty,omitempty"`
+	N   string `json:"n,omitempty"`
+	Use string `json:"use,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKAlg defines model for JWK.alg.
+type JWKAlg string
+
+// JWKKty defines model for JWK.kty.
+type JWKKty string
+
+// JWKS defines model for JWKS.
+type JWKS struct {
+	Keys []JWK `json:"keys,omitempty"`
+}
+
+// KeyEvent defines model for KeyEvent.
+type KeyEvent struct {
+	Action KeyEventAction `json:"action,omitempty"`
+
+	// ActorID The member who acted
+	ActorID   openapi_types.UUID `json:"actor_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	Details   map[string]string  `json:"details,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	KeyID     openapi_types.UUID `json:"key_id,omitempty"`
+	OrgID     openapi_types.UUID `json:"org_id,omitempty"`
+}
+
+// KeyEventAction defines model for KeyEvent.action.
+type KeyEventAction string
+
+// KeyGrant defines model for KeyGrant.
+type KeyGrant struct {
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	GrantedBy openapi_types.UUID `json:"granted_by,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	KeyID     openapi_types.UUID `json:"key_id,omitempty"`
+	OrgID     openapi_types.UUID `json:"org_id,omitempty"`
+	Subject   KeyGrantSubject    `json:"subject,omitempty"`
+	SubjectID string             `json:"subject_id,omitempty"`
+}
+
+// KeyGrantSubject defines model for KeyGrantSubject.
+type KeyGrantSubject string
+
+// LoginEvent defines model for LoginEvent.
+type LoginEvent struct {
+	// Country ISO 3166 country code; absent when unknown
+	Country   string             `json:"country,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	Device    string             `json:"device,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	IPAddress string             `json:"ip_address,omitempty"`
+	Method    LoginEventMethod   `json:"method,omitempty"`
+
+	// SuspiciousReasons Why the login was flagged; absent when it was not
+	SuspiciousReasons []LoginEventSuspiciousReasons `json:"suspicious_reasons,omitempty"`
+	UserAgent         string                        `json:"user_agent,omitempty"`
+}
+
+// LoginEventMethod defines model for LoginEvent.method.
+type LoginEventMethod string
+
+// LoginEventSuspiciousReasons defines model for LoginEvent.suspicious_reasons.
+type LoginEventSuspiciousReasons string
+
+// LoginRequest defines model for LoginRequest.
+type LoginRequest struct {
+	// Device Name of the device, shown in the session list
+	Device   string              `binding:"omitempty,max=100" json:"device,omitempty"`
+	Email    openapi_types.Email `binding:"required,email" json:"email"`
+	Password string              `binding:"required" json:"password"`
+
+	// Scopes Restricts the session's access tokens, e.g. to bots:read for a
+	// read-only integration. Write scopes include reading. Without
+	// scopes the tokens may do everything the user may.
+	Scopes []LoginRequestScopes `json:"scopes,omitempty"`
+}
+
+// LoginRequestScopes defines model for LoginRequest.scopes.
+type LoginRequestScopes string
+
+// MoveRequest defines model for MoveRequest.
+type MoveRequest struct {
+	// OrgID The target organization; empty for the caller's own workspace
+	OrgID string `json:"org_id,omitempty"`
+}
+
+// OrgAPIKey defines model for OrgAPIKey.
+type OrgAPIKey struct {
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	Exchange  string             `json:"exchange,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+
+	// KeyID Only shown to admins
+	KeyID   string             `json:"key_id,omitempty"`
+	Label   string             `json:"label,omitempty"`
+	OrgID   openapi_types.UUID `json:"org_id,omitempty"`
+	Testnet bool               `json:"testnet,omitempty"`
+}
+
+// OrgMember defines model for OrgMember.
+type OrgMember struct {
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	OrgID     openapi_types.UUID `json:"org_id,omitempty"`
+	Role      OrgRole            `json:"role,omitempty"`
+	UserID    openapi_types.UUID `json:"user_id,omitempty"`
+}
+
+// OrgRole Owners also manage owners and delete the organization, admins
+// manage members, members change bots and strategies, viewers read
+type OrgRole string
+
+// Organization defines model for Organization.
+type Organization struct {
+	CreatedAt  time.Time          `json:"created_at,omitempty"`
+	CreatedBy  openapi_types.UUID `json:"created_by,omitempty"`
+	DataRegion string             `json:"data_region,omitempty"`
+	ID         openapi_types.UUID `json:"id,omitempty"`
+	Name       string             `json:"name,omitempty"`
+	Role       OrgRole            `json:"role,omitempty"`
+	UpdatedAt  time.Time          `json:"updated_at,omitempty"`
+}
+
+// RegisterRequest defines model for RegisterRequest.
+type RegisterRequest struct {
+	// Device Name of the device, shown in the session list
+	Device    string              `binding:"omitempty,max=100" json:"device,omitempty"`
+	Email     openapi_types.Email `binding:"required,email" json:"email"`
+	FirstName string              `binding:"required" json:"first_name"`
+	LastName  string              `binding:"required" json:"last_name"`
+	Password  string              `binding:"required,min=8" json:"password"`
+	Username  string              `binding:"required,min=3,max=50" json:"username"`
+}
+
+// SSORedirect defines model for SSORedirect.
+type SSORedirect struct {
+	// AuthorizationURL Where to send the user to sign in at the identity provider
+	AuthorizationURL string `json:"authorization_url,omitempty"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	CreatedAt time.Time `json:"created_at,omitempty"`
+
+	// Current Whether this is the session making the request
+	Current   bool               `json:"current,omitempty"`
+	Device    string             `json:"device,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at,omitempty"`
+	ID        openapi_types.UUID `json:"id,omitempty"`
+	IPAddress string             `json:"ip_address,omitempty"`
+
+	// LastSeenAt Last sign-in or token refresh of the session
+	LastSeenAt time.Time `json:"last_seen_at,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// User defines model for User.
+type User struct {
+	// Avatar Object key of the uploaded avatar; GET /user/avatar signs a URL
+	// to it
+	Avatar        string              `json:"avatar,omitempty"`
+	CreatedAt     time.Time           `json:"created_at,omitempty"`
+	Email         openapi_types.Email `json:"email,omitempty"`
+	EmailVerified bool                `json:"email_verified,omitempty"`
+	FirstName     string              `json:"first_name,omitempty"`
+	ID            openapi_types.UUID  `json:"id,omitempty"`
+	IsActive      bool                `json:"is_active,omitempty"`
+	LastName      string              `json:"last_name,omitempty"`
+
+	// Roles Roles such as admin or support; most users have none
+	Roles     []string  `json:"roles,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	Username  string    `json:"username,omitempty"`
+}
+
+// WithdrawalAddress defines model for WithdrawalAddress.
+type WithdrawalAddress struct {
+	AddedBy    openapi_types.UUID `json:"added_by,omitempty"`
+	Address    string             `json:"address,omitempty"`
+	ApprovedBy openapi_types.UUID `json:"approved_by,omitempty"`
+	Asset      string             `json:"asset,omitempty"`
+	CreatedAt  time.Time          `json:"created_at,omitempty"`
+	Exchange   string             `json:"exchange,omitempty"`
+	ID         openapi_types.UUID `json:"id,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	OrgID      openapi_types.UUID `json:"org_id,omitempty"`
+}
+
+// CheckAvailabilityParams defines parameters for CheckAvailability.
+type CheckAvailabilityParams struct {
+	Email openapi_types.Email `binding:"omitempty,email" form:"email,omitempty" json:"email,omitempty"`
+
+	Username string `form:"username,omitempty" json:"username,omitempty"`
+}
+
+// ConfirmEmailChangeJSONBody defines parameters for ConfirmEmailChange.
+type ConfirmEmailChangeJSONBody struct {
+	Token string `binding:"required" json:"token"`
+}
+
+// UndoEmailChangeJSONBody defines parameters for UndoEmailChange.
+type UndoEmailChangeJSONBody struct {
+	Token string `binding:"required" json:"token"`
+}
+
+// ForgotPasswordJSONBody defines parameters for ForgotPassword.
+type ForgotPasswordJSONBody struct {
+	Email openapi_types.Email `binding:"required,email" json:"email"`
+}
+
+// LoginParams defines parameters for Login.
+type LoginParams struct {
+	// XClientType Client types configured for cookie sessions, e.g. "web", get a session cookie instead of tokens
+	XClientType string `json:"X-Client-Type,omitempty"`
+}
+
+// RequestMagicLinkJSONBody defines parameters for RequestMagicLink.
+type RequestMagicLinkJSONBody struct {
+	Email openapi_types.Email `binding:"required,email" json:"email"`
+}
+
+// ConsumeMagicLinkJSONBody defines parameters for ConsumeMagicLink.
+type ConsumeMagicLinkJSONBody struct {
+	// Device Name of the device the session starts on
+	Device string `binding:"omitempty,max=100" json:"device,omitempty"`
+	Token  string `binding:"required" json:"token"`
+}
+
+// ConsumeMagicLinkParams defines parameters for ConsumeMagicLink.
+type ConsumeMagicLinkParams struct {
+	// XClientType Client types configured for cookie sessions, e.g. "web", get a session cookie instead of tokens
+	XClientType string `json:"X-Client-Type,omitempty"`
+}
+
+// RefreshTokenJSONBody defines parameters for RefreshToken.
+type RefreshTokenJSONBody struct {
+	RefreshToken string `binding:"required" json:"refresh_token"`
+}
+
+// RegisterParams defines parameters for Register.
+type RegisterParams struct {
+	// XClientType Client types configured for cookie sessions, e.g. "web", get a session cookie instead of tokens
+	XClientType string `json:"X-Client-Type,omitempty"`
+}
+
+// ResetPasswordJSONBody defines parameters for ResetPassword.
+type ResetPasswordJSONBody struct {
+	NewPassword string `binding:"required,min=8" json:"new_password"`
+	Token       string `binding:"required" json:"token"`
+}
+
+// CompleteSSOJSONBody defines parameters for CompleteSSO.
+type CompleteSSOJSONBody struct {
+	Code string `binding:"required" json:"code"`
+
+	// Device Name of the device the session starts on
+	Device string `binding:"omitempty,max=100" json:"device,omitempty"`
+	State  string `binding:"required" json:"state"`
+}
+
+// CompleteSSOParams defines parameters for CompleteSSO.
+type CompleteSSOParams struct {
+	// XClientType Client types configured for cookie sessions, e.g. "web", get a session cookie instead of tokens
+	XClientType string `json:"X-Client-Type,omitempty"`
+}
+
+// StartSSORedirectParams defines parameters for StartSSORedirect.
+type StartSSORedirectParams struct {
+	Email openapi_types.Email `binding:"required,email" form:"email" json:"email"`
+}
+
+// StartSSOJSONBody defines parameters for StartSSO.
+type StartSSOJSONBody struct {
+	Email openapi_types.Email `binding:"required,email" json:"email"`
+}
+
+// VerifyEmailJSONBody defines parameters for VerifyEmail.
+type VerifyEmailJSONBody struct {
+	Token string `binding:"required" json:"token"`
+}
+
+// ListInvoicesParams defines parameters for ListInvoices.
+type ListInvoicesParams struct {
+	Limit int `binding:"omitempty,min=1,max=120" form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListBotsParams defines parameters for ListBots.
+type ListBotsParams struct {
+	Limit int `binding:"omitempty,max=100" form:"limit,omitempty" json:"limit,omitempty"`
+
+	Offset int `form:"offset,omitempty" json:"offset,omitempty"`
+
+	// XOrgID Work in the organization's workspace instead of the caller's own
+	XOrgID openapi_types.UUID `json:"X-Org-ID,omitempty"`
+}
+
+// MoveBotParams defines parameters for MoveBot.
+type MoveBotParams struct {
+	// XOrgID Work in the organization's workspace instead of the caller's own
+	XOrgID openapi_types.UUID `json:"X-Org-ID,omitempty"`
+}
+
+// CreateOrgJSONBody defines parameters for CreateOrg.
+type CreateOrgJSONBody struct {
+	Name string `binding:"required,max=100" json:"name"`
+}
+
+// ListOrgAPIKeyEventsParams defines parameters for ListOrgAPIKeyEvents.
+type ListOrgAPIKeyEventsParams struct {
+	// UserID Only the actions of this member
+	UserID string `binding:"omitempty,uuid" form:"user_id,omitempty" json:"user_id,omitempty"`
+
+	// KeyID Only the actions on this key
+	KeyID string `binding:"omitempty,uuid" form:"key_id,omitempty" json:"key_id,omitempty"`
+
+	Limit int `binding:"omitempty,min=1,max=500" form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// CreateOrgAPIKeyJSONBody defines parameters for CreateOrgAPIKey.
+type CreateOrgAPIKeyJSONBody struct {
+	Exchange string `binding:"required" json:"exchange"`
+
+	// KeyID The public half of the key as issued by the exchange
+	KeyID   string `binding:"required" json:"key_id"`
+	Label   string `binding:"omitempty,max=100" json:"label,omitempty"`
+	Testnet bool   `json:"testnet,omitempty"`
+}
+
+// GrantOrgAPIKeyJSONBody defines parameters for GrantOrgAPIKey.
+type GrantOrgAPIKeyJSONBody struct {
+	Subject KeyGrantSubject `json:"subject"`
+
+	// SubjectID The member's user ID or the bot's ID
+	SubjectID string `binding:"required" json:"subject_id"`
+}
+
+// InviteToOrgJSONBody defines parameters for InviteToOrg.
+type InviteToOrgJSONBody struct {
+	Email openapi_types.Email `binding:"required,email" json:"email"`
+	Role  OrgRole             `json:"role"`
+}
+
+// AddOrgMemberJSONBody defines parameters for AddOrgMember.
+type AddOrgMemberJSONBody struct {
+	Role   OrgRole            `json:"role"`
+	UserID openapi_types.UUID `binding:"required" json:"user_id"`
+}
+
+// SetOrgMemberRoleJSONBody defines parameters for SetOrgMemberRole.
+type SetOrgMemberRoleJSONBody struct {
+	Role OrgRole `json:"role"`
+}
+
+// AddWithdrawalAddressJSONBody defines parameters for AddWithdrawalAddress.
+type AddWithdrawalAddressJSONBody struct {
+	Address  string `binding:"required,max=128" json:"address"`
+	Asset    string `binding:"required,max=16" json:"asset"`
+	Exchange string `binding:"required" json:"exchange"`
+	Label    string `binding:"omitempty,max=100" json:"label,omitempty"`
+}
+
+// MoveStrategyParams defines parameters for MoveStrategy.
+type MoveStrategyParams struct {
+	// XOrgID Work in the organization's workspace instead of the caller's own
+	XOrgID openapi_types.UUID `json:"X-Org-ID,omitempty"`
+}
+
+// UploadAvatarMultipartBody defines parameters for UploadAvatar.
+type UploadAvatarMultipartBody struct {
+	Avatar openapi_types.File `json:"avatar"`
+}
+
+// RequestEmailChangeJSONBody defines parameters for RequestEmailChange.
+type RequestEmailChangeJSONBody struct {
+	NewEmail openapi_types.Email `binding:"required,email" json:"new_email"`
+	Password string              `binding:"required" json:"password"`
+}
+
+// ChangePasswordJSONBody defines parameters for ChangePassword.
+type ChangePasswordJSONBody struct {
+	NewPassword string `binding:"required,min=8" json:"new_password"`
+	OldPassword string `binding:"required" json:"old_password"`
+}
+
+// ListLoginsParams defines parameters for ListLogins.
+type ListLoginsParams struct {
+	Limit int `binding:"omitempty,min=1,max=200" form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListSecurityEventsParams defines parameters for ListSecurityEvents.
+type ListSecurityEventsParams struct {
+	Limit int `binding:"omitempty,min=1,max=200" form:"limit,omitempty" json:"limit,omitempty"`
+
+	Cursor string `form:"cursor,omitempty" json:"cursor,omitempty"`
+}
+
+// SetIPAllowlistModeJSONBody defines parameters for SetIPAllowlistMode.
+type SetIPAllowlistModeJSONBody struct {
+	Mode SetIPAllowlistModeJSONBodyMode `binding:"required,oneof=all trading" json:"mode"`
+}
+
+// SetIPAllowlistModeJSONBodyMode defines parameters for SetIPAllowlistMode.
+type SetIPAllowlistModeJSONBodyMode string
+
+// AddAllowedNetworkJSONBody defines parameters for AddAllowedNetwork.
+type AddAllowedNetworkJSONBody struct {
+	// Cidr An IP address or CIDR range, e.g. 203.0.113.0/24
+	Cidr  string `binding:"required" json:"cidr"`
+	Label string `binding:"omitempty,max=100" json:"label,omitempty"`
+}
+
+// SetPasswordJSONBody defines parameters for SetPassword.
+type SetPasswordJSONBody struct {
+	Password string `binding:"required,min=8" json:"password"`
+}
+
+// LinkSSOJSONBody defines parameters for LinkSSO.
+type LinkSSOJSONBody struct {
+	Email openapi_types.Email `binding:"required,email" json:"email"`
+}
+
+// ConfirmEmailChangeJSONRequestBody defines body for ConfirmEmailChange for application/json ContentType.
+type ConfirmEmailChangeJSONRequestBody ConfirmEmailChangeJSONBody
+
+// UndoEmailChangeJSONRequestBody defines body for UndoEmailChange for application/json ContentType.
+type UndoEmailChangeJSONRequestBody UndoEmailChangeJSONBody
+
+// ForgotPasswordJSONRequestBody defines body for ForgotPassword for application/json ContentType.
+type ForgotPasswordJSONRequestBody ForgotPasswordJSONBody
+
+// LoginJSONRequestBody defines body for Login for application/json ContentType.
+type LoginJSONRequestBody = LoginRequest
+
+// RequestMagicLinkJSONRequestBody defines body for RequestMagicLink for application/json ContentType.
+type RequestMagicLinkJSONRequestBody RequestMagicLinkJSONBody
+
+// ConsumeMagicLinkJSONRequestBody defines body for ConsumeMagicLink for application/json ContentType.
+type ConsumeMagicLinkJSONRequestBody ConsumeMagicLinkJSONBody
+
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody RefreshTokenJSONBody
+
+// RegisterJSONRequestBody defines body for Register for application/json ContentType.
+type RegisterJSONRequestBody = RegisterRequest
+
+// ResetPasswordJSONRequestBody defines body for ResetPassword for application/json ContentType.
+type ResetPasswordJSONRequestBody ResetPasswordJSONBody
+
+// CompleteSSOJSONRequestBody defines body for CompleteSSO for application/json ContentType.
+type CompleteSSOJSONRequestBody CompleteSSOJSONBody
+
+// StartSSOJSONRequestBody defines body for StartSSO for application/json ContentType.
+type StartSSOJSONRequestBody StartSSOJSONBody
+
+// VerifyEmailJSONRequestBody defines body for VerifyEmail for application/json ContentType.
+type VerifyEmailJSONRequestBody VerifyEmailJSONBody
+
+// MoveBotJSONRequestBody defines body for MoveBot for application/json ContentType.
+type MoveBotJSONRequestBody = MoveRequest
+
+// CreateOrgJSONRequestBody defines body for CreateOrg for application/json ContentType.
+type CreateOrgJSONRequestBody CreateOrgJSONBody
+
+// CreateOrgAPIKeyJSONRequestBody defines body for CreateOrgAPIKey for application/json ContentType.
+type CreateOrgAPIKeyJSONRequestBody CreateOrgAPIKeyJSONBody
+
+// GrantOrgAPIKeyJSONRequestBody defines body for GrantOrgAPIKey for application/json ContentType.
+type GrantOrgAPIKeyJSONRequestBody GrantOrgAPIKeyJSONBody
+
+// InviteToOrgJSONRequestBody defines body for InviteToOrg for application/json ContentType.
+type InviteToOrgJSONRequestBody InviteToOrgJSONBody
+
+// AddOrgMemberJSONRequestBody defines body for AddOrgMember for application/json ContentType.
+type AddOrgMemberJSONRequestBody AddOrgMemberJSONBody
+
+// SetOrgMemberRoleJSONRequestBody defines body for SetOrgMemberRole for application/json ContentType.
+type SetOrgMemberRoleJSONRequestBody SetOrgMemberRoleJSONBody
+
+// AddWithdrawalAddressJSONRequestBody defines body for AddWithdrawalAddress for application/json ContentType.
+type AddWithdrawalAddressJSONRequestBody AddWithdrawalAddressJSONBody
+
+// MoveStrategyJSONRequestBody defines body for MoveStrategy for application/json ContentType.
+type MoveStrategyJSONRequestBody = MoveRequest
+
+// UploadAvatarMultipartRequestBody defines body for UploadAvatar for multipart/form-data ContentType.
+type UploadAvatarMultipartRequestBody UploadAvatarMultipartBody
+
+// RequestEmailChangeJSONRequestBody defines body for RequestEmailChange for application/json ContentType.
+type RequestEmailChangeJSONRequestBody RequestEmailChangeJSONBody
+
+// ChangePasswordJSONRequestBody defines body for ChangePassword for application/json ContentType.
+type ChangePasswordJSONRequestBody ChangePasswordJSONBody
+
+// SetIPAllowlistModeJSONRequestBody defines body for SetIPAllowlistMode for application/json ContentType.
+type SetIPAllowlistModeJSONRequestBody SetIPAllowlistModeJSONBody
+
+// AddAllowedNetworkJSONRequestBody defines body for AddAllowedNetwork for application/json ContentType.
+type AddAllowedNetworkJSONRequestBody AddAllowedNetworkJSONBody
+
+// SetPasswordJSONRequestBody defines body for SetPassword for application/json ContentType.
+type SetPasswordJSONRequestBody SetPasswordJSONBody
+
+// LinkSSOJSONRequestBody defines body for LinkSSO for application/json ContentType.
+type LinkSSOJSONRequestBody LinkSSOJSONBody
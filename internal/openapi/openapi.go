@@ -0,0 +1,10 @@
+// Package openapi holds the gateway's request and response models,
+// generated from docs/api/openapi.yaml by oapi-codegen so field names and
+// validation rules are defined once, in the spec. Bind requests into these
+// types instead of declaring ad hoc structs in handlers.
+//
+// Gin binding rules come from each property's x-oapi-codegen-extra-tags;
+// keep them in line with the constraints documented next to them.
+package openapi
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.5.0 -config oapi-codegen.yaml ../../docs/api/openapi.yaml
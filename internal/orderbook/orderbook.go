@@ -0,0 +1,316 @@
+// Package orderbook keeps live per-symbol order books in memory. Writers
+// apply exchange deltas by publishing a new immutable snapshot, so readers
+// such as strategies and the streaming hub never take a lock.
+package orderbook
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ErrNoSnapshot is returned for deltas on a book that was never
+	// initialised with a full snapshot
+	ErrNoSnapshot = errors.New("order book has no snapshot")
+	// ErrSequenceGap means deltas were missed; the caller must resync the
+	// book from a fresh exchange snapshot
+	ErrSequenceGap = errors.New("order book sequence gap")
+)
+
+var (
+	updates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orderbook_updates_total",
+		Help: "Order book writes by result.",
+	}, []string{"result"})
+	books = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "orderbook_books",
+		Help: "Order books held in memory.",
+	})
+	lockContended = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orderbook_shard_lock_contended_total",
+		Help: "Writes that found their shard locked by another writer.",
+	}, []string{"shard"})
+	lockWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "orderbook_shard_lock_wait_seconds",
+		Help:    "Time contended writers waited for their shard lock.",
+		Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+	})
+)
+
+// Level is the total quantity resting at one price.
+type Level struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Snapshot is one immutable version of a book, bids best (highest) first
+// and asks best (lowest) first. Readers share it and must not modify it.
+type Snapshot struct {
+	Exchange string    `json:"exchange"`
+	Symbol   string    `json:"symbol"`
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Bids     []Level   `json:"bids"`
+	Asks     []Level   `json:"asks"`
+}
+
+func (s *Snapshot) BestBid() (Level, bool) {
+	if len(s.Bids) == 0 {
+		return Level{}, false
+	}
+	return s.Bids[0], true
+}
+
+func (s *Snapshot) BestAsk() (Level, bool) {
+	if len(s.Asks) == 0 {
+		return Level{}, false
+	}
+	return s.Asks[0], true
+}
+
+// Top returns the snapshot cut to depth levels per side. The levels are
+// shared with s.
+func (s *Snapshot) Top(depth int) *Snapshot {
+	top := *s
+	top.Bids = trim(s.Bids, depth)
+	top.Asks = trim(s.Asks, depth)
+	return &top
+}
+
+// Delta changes price levels of a book. A zero quantity removes the level.
+type Delta struct {
+	Sequence uint64
+	// PrevSequence, when the exchange provides it, must match the book's
+	// sequence; anything else means deltas were lost
+	PrevSequence uint64
+	Time         time.Time
+	Bids         []Level
+	Asks         []Level
+}
+
+// book is the slot a symbol's snapshots are published to.
+type book struct {
+	current atomic.Pointer[Snapshot]
+}
+
+// shard owns a subset of books. Its map is copied on write as well, so
+// lookups never lock; mutex only serialises writers of the shard.
+type shard struct {
+	mutex     sync.Mutex
+	books     atomic.Pointer[map[string]*book]
+	contended prometheus.Counter
+}
+
+// lock takes the writer lock, recording how long a contended writer waited.
+func (s *shard) lock() {
+	if s.mutex.TryLock() {
+		return
+	}
+	s.contended.Inc()
+	start := time.Now()
+	s.mutex.Lock()
+	lockWait.Observe(time.Since(start).Seconds())
+}
+
+// Store holds the books of every exchange and symbol, spread over shards
+// by key so writers of unrelated symbols rarely share a lock.
+type Store struct {
+	shards []*shard
+	// maxDepth caps the levels kept per side; zero keeps all
+	maxDepth int
+}
+
+func NewStore(shards, maxDepth int) *Store {
+	if shards <= 0 {
+		shards = 16
+	}
+	s := &Store{shards: make([]*shard, shards), maxDepth: maxDepth}
+	for i := range s.shards {
+		s.shards[i] = &shard{contended: lockContended.WithLabelValues(strconv.Itoa(i))}
+		empty := map[string]*book{}
+		s.shards[i].books.Store(&empty)
+	}
+	return s
+}
+
+func key(exchange, symbol string) string {
+	return exchange + "|" + symbol
+}
+
+func (s *Store) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Snapshot returns the latest version of a book without locking.
+func (s *Store) Snapshot(exchange, symbol string) (*Snapshot, bool) {
+	k := key(exchange, symbol)
+	b, ok := (*s.shard(k).books.Load())[k]
+	if !ok {
+		return nil, false
+	}
+	snapshot := b.current.Load()
+	return snapshot, snapshot != nil
+}
+
+// Replace installs a full exchange snapshot, e.g. on subscribe or after a
+// sequence gap. The store takes ownership of the level slices.
+func (s *Store) Replace(snapshot *Snapshot) {
+	k := key(snapshot.Exchange, snapshot.Symbol)
+	sh := s.shard(k)
+	sh.lock()
+	defer sh.mutex.Unlock()
+
+	sortSide(snapshot.Bids, true)
+	sortSide(snapshot.Asks, false)
+	snapshot.Bids = trim(snapshot.Bids, s.maxDepth)
+	snapshot.Asks = trim(snapshot.Asks, s.maxDepth)
+
+	sh.get(k).current.Store(snapshot)
+	updates.WithLabelValues("replaced").Inc()
+}
+
+// Apply merges a delta into the book and publishes the result as a new
+// snapshot. Deltas older than the book are ignored.
+func (s *Store) Apply(exchange, symbol string, delta Delta) error {
+	k := key(exchange, symbol)
+	sh := s.shard(k)
+	sh.lock()
+	defer sh.mutex.Unlock()
+
+	b, ok := (*sh.books.Load())[k]
+	var current *Snapshot
+	if ok {
+		current = b.current.Load()
+	}
+	switch {
+	case current == nil:
+		updates.WithLabelValues("no_snapshot").Inc()
+		return ErrNoSnapshot
+	case delta.Sequence != 0 && delta.Sequence <= current.Sequence:
+		updates.WithLabelValues("stale").Inc()
+		return nil
+	case delta.PrevSequence != 0 && delta.PrevSequence != current.Sequence:
+		updates.WithLabelValues("gap").Inc()
+		return ErrSequenceGap
+	}
+
+	next := &Snapshot{
+		Exchange: exchange,
+		Symbol:   symbol,
+		Sequence: current.Sequence,
+		Time:     delta.Time,
+		Bids:     merge(current.Bids, delta.Bids, true, s.maxDepth),
+		Asks:     merge(current.Asks, delta.Asks, false, s.maxDepth),
+	}
+	if delta.Sequence != 0 {
+		next.Sequence = delta.Sequence
+	}
+	b.current.Store(next)
+	updates.WithLabelValues("applied").Inc()
+	return nil
+}
+
+// Remove drops a book, e.g. when its feed is unsubscribed.
+func (s *Store) Remove(exchange, symbol string) {
+	k := key(exchange, symbol)
+	sh := s.shard(k)
+	sh.lock()
+	defer sh.mutex.Unlock()
+
+	old := *sh.books.Load()
+	if _, ok := old[k]; !ok {
+		return
+	}
+	next := make(map[string]*book, len(old)-1)
+	for name, b := range old {
+		if name != k {
+			next[name] = b
+		}
+	}
+	sh.books.Store(&next)
+	books.Dec()
+}
+
+// get returns the book's slot, publishing a copy of the shard map with a
+// new slot if needed. Callers must hold the shard's mutex.
+func (sh *shard) get(k string) *book {
+	old := *sh.books.Load()
+	if b, ok := old[k]; ok {
+		return b
+	}
+
+	next := make(map[string]*book, len(old)+1)
+	for name, b := range old {
+		next[name] = b
+	}
+	b := &book{}
+	next[k] = b
+	sh.books.Store(&next)
+	books.Inc()
+	return b
+}
+
+// better reports whether price a ranks ahead of b on the side.
+func better(a, b float64, bids bool) bool {
+	if bids {
+		return a > b
+	}
+	return a < b
+}
+
+func sortSide(levels []Level, bids bool) {
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price, bids) })
+}
+
+func trim(levels []Level, maxDepth int) []Level {
+	if maxDepth > 0 && len(levels) > maxDepth {
+		return levels[:maxDepth]
+	}
+	return levels
+}
+
+// merge returns a new side with the changes applied; levels is left
+// untouched because readers may still hold it.
+func merge(levels, changes []Level, bids bool, maxDepth int) []Level {
+	if len(changes) == 0 {
+		return levels
+	}
+	sorted := make([]Level, len(changes))
+	copy(sorted, changes)
+	sort.SliceStable(sorted, func(i, j int) bool { return better(sorted[i].Price, sorted[j].Price, bids) })
+
+	out := make([]Level, 0, len(levels)+len(sorted))
+	i, j := 0, 0
+	for i < len(levels) || j < len(sorted) {
+		switch {
+		case j == len(sorted) || (i < len(levels) && better(levels[i].Price, sorted[j].Price, bids)):
+			out = append(out, levels[i])
+			i++
+		default:
+			// The last change of a price wins; a level it replaces is skipped
+			change := sorted[j]
+			for j+1 < len(sorted) && sorted[j+1].Price == change.Price {
+				j++
+				change = sorted[j]
+			}
+			j++
+			if i < len(levels) && levels[i].Price == change.Price {
+				i++
+			}
+			if change.Quantity > 0 {
+				out = append(out, change)
+			}
+		}
+	}
+	return trim(out, maxDepth)
+}
@@ -0,0 +1,52 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Apply(t *testing.T) {
+	store := NewStore(4, 0)
+	assert.ErrorIs(t, store.Apply("binance", "BTCUSDT", Delta{Sequence: 1}), ErrNoSnapshot)
+
+	store.Replace(&Snapshot{
+		Exchange: "binance",
+		Symbol:   "BTCUSDT",
+		Sequence: 10,
+		Bids:     []Level{{Price: 99, Quantity: 1}, {Price: 100, Quantity: 2}},
+		Asks:     []Level{{Price: 102, Quantity: 1}, {Price: 101, Quantity: 3}},
+	})
+	before, ok := store.Snapshot("binance", "BTCUSDT")
+	require.True(t, ok)
+
+	require.NoError(t, store.Apply("binance", "BTCUSDT", Delta{
+		Sequence:     11,
+		PrevSequence: 10,
+		Bids:         []Level{{Price: 100, Quantity: 0}, {Price: 98, Quantity: 5}},
+		Asks:         []Level{{Price: 101, Quantity: 4}},
+	}))
+	after, ok := store.Snapshot("binance", "BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, uint64(11), after.Sequence)
+	assert.Equal(t, []Level{{Price: 99, Quantity: 1}, {Price: 98, Quantity: 5}}, after.Bids)
+	assert.Equal(t, []Level{{Price: 101, Quantity: 4}, {Price: 102, Quantity: 1}}, after.Asks)
+	// Readers holding the old snapshot still see it unchanged
+	assert.Equal(t, []Level{{Price: 100, Quantity: 2}, {Price: 99, Quantity: 1}}, before.Bids)
+
+	// Stale deltas are ignored and missed ones reported
+	assert.NoError(t, store.Apply("binance", "BTCUSDT", Delta{Sequence: 11}))
+	assert.ErrorIs(t, store.Apply("binance", "BTCUSDT", Delta{Sequence: 13, PrevSequence: 12}), ErrSequenceGap)
+}
+
+func TestSnapshot_Top(t *testing.T) {
+	snapshot := &Snapshot{
+		Bids: []Level{{Price: 100, Quantity: 1}, {Price: 99, Quantity: 1}},
+		Asks: []Level{{Price: 101, Quantity: 1}},
+	}
+	top := snapshot.Top(1)
+	assert.Len(t, top.Bids, 1)
+	assert.Len(t, top.Asks, 1)
+	assert.Len(t, snapshot.Bids, 2)
+}
@@ -0,0 +1,203 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+const (
+	ResultCancelled = "cancelled"
+	// ResultAlreadyClosed means the order was gone before we got to it,
+	// which is what a retried request sees for orders cancelled earlier
+	ResultAlreadyClosed = "already_closed"
+	ResultClosed        = "closed"
+	ResultFailed        = "failed"
+)
+
+// ItemResult is the outcome for a single order or position.
+type ItemResult struct {
+	Exchange string `json:"exchange"`
+	Symbol   string `json:"symbol"`
+	OrderID  string `json:"order_id,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkResult aggregates the outcome of a bulk operation.
+type BulkResult struct {
+	Results   []ItemResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+func (r *BulkResult) add(item ItemResult) {
+	r.Results = append(r.Results, item)
+	if item.Status == ResultFailed {
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+}
+
+// Filter narrows a bulk operation to an exchange and/or symbol. Empty
+// fields match everything.
+type Filter struct {
+	Exchange string
+	Symbol   string
+}
+
+// BulkService cancels orders and flattens positions across exchanges with
+// bounded concurrency. Both operations are safe to retry: they act on the
+// current state of the exchange and treat already-closed orders as success.
+type BulkService struct {
+	oms         *OMS
+	concurrency int
+}
+
+func NewBulkService(oms *OMS, concurrency int) *BulkService {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &BulkService{oms: oms, concurrency: concurrency}
+}
+
+// CancelAll cancels every open order of the user matching the filter.
+func (s *BulkService) CancelAll(ctx context.Context, userID string, filter Filter) (*BulkResult, error) {
+	clients, err := s.clients(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Results: []ItemResult{}}
+	var tasks []func() ItemResult
+
+	for name, client := range clients {
+		openOrders, err := client.OpenOrders(ctx, userID, filter.Symbol)
+		if err != nil {
+			result.add(ItemResult{Exchange: name, Symbol: filter.Symbol, Status: ResultFailed, Error: err.Error()})
+			continue
+		}
+
+		for _, order := range openOrders {
+			name, client, order := name, client, order
+			tasks = append(tasks, func() ItemResult {
+				item := ItemResult{Exchange: name, Symbol: order.Symbol, OrderID: order.ID, Status: ResultCancelled}
+				err := client.CancelOrder(ctx, userID, order.ID)
+				switch {
+				case err == nil:
+				case errors.Is(err, exchange.ErrOrderClosed), errors.Is(err, exchange.ErrOrderNotFound):
+					item.Status = ResultAlreadyClosed
+				default:
+					item.Status = ResultFailed
+					item.Error = err.Error()
+				}
+				return item
+			})
+		}
+	}
+
+	for _, item := range s.run(tasks) {
+		result.add(item)
+	}
+	return result, nil
+}
+
+// Flatten cancels open orders and closes every position matching the
+// filter with reduce-only market orders. idempotencyKey is folded into the
+// client order IDs so a retried request cannot double-close a position.
+func (s *BulkService) Flatten(ctx context.Context, userID string, filter Filter, idempotencyKey string) (*BulkResult, error) {
+	// Resting orders could reopen a position right after it is closed
+	result, err := s.CancelAll(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := s.clients(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []func() ItemResult
+	for name, client := range clients {
+		positions, err := client.Positions(ctx, userID, filter.Symbol)
+		if err != nil {
+			result.add(ItemResult{Exchange: name, Symbol: filter.Symbol, Status: ResultFailed, Error: err.Error()})
+			continue
+		}
+
+		for _, position := range positions {
+			name, client, position := name, client, position
+			tasks = append(tasks, func() ItemResult {
+				req := exchange.OrderRequest{
+					Symbol:     position.Symbol,
+					Side:       position.Side.Opposite(),
+					Type:       exchange.OrderTypeMarket,
+					Quantity:   position.Quantity,
+					ReduceOnly: true,
+				}
+				if idempotencyKey != "" {
+					req.ClientOrderID = "flatten-" + idempotencyKey + "-" + position.Symbol
+				}
+
+				item := ItemResult{Exchange: name, Symbol: position.Symbol, Status: ResultClosed}
+				order, err := client.PlaceOrder(ctx, userID, req)
+				if err != nil {
+					item.Status = ResultFailed
+					item.Error = err.Error()
+					return item
+				}
+				item.OrderID = order.ID
+				if order.Status == exchange.OrderStatusRejected {
+					item.Status = ResultFailed
+					item.Error = "close order rejected"
+				}
+				return item
+			})
+		}
+	}
+
+	for _, item := range s.run(tasks) {
+		result.add(item)
+	}
+	return result, nil
+}
+
+func (s *BulkService) clients(filter Filter) (map[string]exchange.Client, error) {
+	names := s.oms.Names()
+	if filter.Exchange != "" {
+		names = []string{filter.Exchange}
+	}
+
+	clients := make(map[string]exchange.Client, len(names))
+	for _, name := range names {
+		client, err := s.oms.Client(name, false)
+		if err != nil {
+			return nil, err
+		}
+		clients[name] = client
+	}
+	return clients, nil
+}
+
+// run executes tasks with at most s.concurrency in flight, preserving order.
+func (s *BulkService) run(tasks []func() ItemResult) []ItemResult {
+	results := make([]ItemResult, len(tasks))
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, task func() ItemResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = task()
+		}(i, task)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,44 @@
+package orders
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/keyset"
+)
+
+var ErrInvalidCursor = keyset.ErrInvalidCursor
+
+// Histories a cursor pages through
+const (
+	historyOrders = "orders"
+	historyTrades = "trades"
+)
+
+// timeColumns is the column each history is ordered by, newest first, with
+// the ID breaking ties.
+var timeColumns = map[string]string{
+	historyOrders: "created_at",
+	historyTrades: "executed_at",
+}
+
+func encodeCursor(history string, q Query, t time.Time, id string) string {
+	return keyset.Encode(history, cursorFilters(history, q), t, id)
+}
+
+func decodeCursor(s, history string, q Query) (*keyset.Cursor, error) {
+	return keyset.Decode(s, history, cursorFilters(history, q))
+}
+
+// cursorFilters lists what selects the rows of a history listing.
+func cursorFilters(history string, q Query) []string {
+	testnet := ""
+	if q.Testnet != nil {
+		testnet = strconv.FormatBool(*q.Testnet)
+	}
+	status := q.Status
+	if history == historyTrades {
+		status = ""
+	}
+	return []string{q.UserID, q.Symbol, q.Side, status, q.BotID, q.Exchange, testnet, keyset.Bound(q.From), keyset.Bound(q.To)}
+}
@@ -0,0 +1,661 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGroupNotFound = errors.New("order group not found")
+	ErrInvalidGroup  = errors.New("invalid order group")
+)
+
+// Group types.
+const (
+	// GroupOCO places two orders; the first to fill cancels the other
+	GroupOCO = "oco"
+	// GroupBracket places an entry and, once it fills, a stop loss and a
+	// take profit that cancel each other
+	GroupBracket = "bracket"
+	// GroupIfThen places the second order only once the first fills
+	GroupIfThen = "if_then"
+)
+
+const (
+	GroupActive = "active"
+	// GroupCancelling groups have no leg left to activate but still wait
+	// for an open order to be cancelled on the exchange
+	GroupCancelling = "cancelling"
+	GroupCompleted  = "completed"
+	GroupCancelled  = "cancelled"
+	GroupFailed     = "failed"
+)
+
+const (
+	RoleOCO        = "oco"
+	RoleEntry      = "entry"
+	RoleStopLoss   = "stop_loss"
+	RoleTakeProfit = "take_profit"
+	RoleIf         = "if"
+	RoleThen       = "then"
+)
+
+const (
+	// LegPending legs wait for the leg they depend on to fill
+	LegPending = "pending"
+	// LegWaiting legs are active but wait for their trigger price
+	LegWaiting   = "waiting"
+	LegOpen      = "open"
+	LegFilled    = "filled"
+	LegCancelled = "cancelled"
+	LegRejected  = "rejected"
+)
+
+// Group is a set of orders on one market managed as a unit: legs are
+// activated and cancelled by the group's rules, and cancelling the group
+// cancels every leg.
+type Group struct {
+	ID       string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID   string `json:"-" gorm:"type:varchar(36);not null;index"`
+	Type     string `json:"type" gorm:"not null"`
+	Exchange string `json:"exchange" gorm:"not null"`
+	Symbol   string `json:"symbol" gorm:"not null"`
+	// Testnet groups trade on the exchange's sandbox environment
+	Testnet   bool      `json:"testnet" gorm:"not null;default:false"`
+	Status    string    `json:"status" gorm:"not null;index"`
+	Legs      []Leg     `json:"legs" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Group) TableName() string {
+	return "order_groups"
+}
+
+// Leg is one order of a group. A leg with a trigger price is held back
+// until the market reaches it, like a stop order: sell legs trigger at or
+// below the price, buy legs at or above it.
+type Leg struct {
+	ID           string             `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	GroupID      string             `json:"-" gorm:"type:varchar(36);not null;index"`
+	Role         string             `json:"role" gorm:"not null"`
+	Side         exchange.Side      `json:"side" gorm:"not null"`
+	Type         exchange.OrderType `json:"type" gorm:"not null"`
+	Price        decimal.Decimal    `json:"price" gorm:"type:numeric"`
+	Quantity     decimal.Decimal    `json:"quantity" gorm:"type:numeric"`
+	TriggerPrice decimal.Decimal    `json:"trigger_price" gorm:"type:numeric"`
+	ReduceOnly   bool               `json:"reduce_only"`
+	Status       string             `json:"status" gorm:"not null"`
+	// OrderID is the exchange order once the leg is placed
+	OrderID string `json:"order_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TableName sets the table name for GORM
+func (Leg) TableName() string {
+	return "order_group_legs"
+}
+
+func (l *Leg) terminal() bool {
+	return l.Status == LegFilled || l.Status == LegCancelled || l.Status == LegRejected
+}
+
+func (l *Leg) triggered(price decimal.Decimal) bool {
+	if l.Side == exchange.SideSell {
+		return price.LessThanOrEqual(l.TriggerPrice)
+	}
+	return price.GreaterThanOrEqual(l.TriggerPrice)
+}
+
+type LegRequest struct {
+	Role         string             `json:"role"`
+	Side         exchange.Side      `json:"side"`
+	Type         exchange.OrderType `json:"type"`
+	Price        decimal.Decimal    `json:"price"`
+	Quantity     decimal.Decimal    `json:"quantity"`
+	TriggerPrice decimal.Decimal    `json:"trigger_price"`
+}
+
+type GroupRequest struct {
+	Type     string       `json:"type"`
+	Exchange string       `json:"exchange"`
+	Symbol   string       `json:"symbol"`
+	Legs     []LegRequest `json:"legs"`
+	// APIKeyID selects the exchange key to trade with; Testnet mirrors
+	// its flag and is set by the caller, never from the request body
+	APIKeyID string `json:"api_key_id"`
+	Testnet  bool   `json:"-"`
+}
+
+// GroupService places order groups and drives them: Sync polls the legs'
+// orders and market prices, activates dependent legs and cancels the
+// losing side of one-cancels-other pairs. Each group has its own lock,
+// so a group is never acted on twice at once while other groups, and other
+// users, are not held up by its exchange calls.
+type GroupService struct {
+	db  *gorm.DB
+	oms *OMS
+
+	// mutex only guards locks, never I/O
+	mutex sync.Mutex
+	locks map[string]*groupLock
+}
+
+type groupLock struct {
+	sync.Mutex
+	// refs counts the holders and waiters, so idle locks are dropped
+	refs int
+}
+
+func NewGroupService(db *gorm.DB, oms *OMS) *GroupService {
+	return &GroupService{db: db, oms: oms, locks: make(map[string]*groupLock)}
+}
+
+// lock acquires the group's lock and returns its release.
+func (s *GroupService) lock(id string) func() {
+	s.mutex.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &groupLock{}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mutex.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
+
+// Create validates the request, stores the group and activates its first
+// legs.
+func (s *GroupService) Create(ctx context.Context, userID string, req GroupRequest) (*Group, error) {
+	group, err := newGroup(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.oms.Client(group.Exchange, group.Testnet)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.lock(group.ID)()
+
+	// Stored first so a crash while placing legs leaves a group to sync
+	if err := s.db.WithContext(ctx).Create(group).Error; err != nil {
+		return nil, err
+	}
+	// Legs that depend on no other start right away
+	parent := group.parent()
+	for i := range group.Legs {
+		if leg := &group.Legs[i]; parent == nil || leg.ID == parent.ID {
+			s.activate(ctx, client, group, leg)
+		}
+	}
+	s.advance(ctx, client, group)
+	return group, s.save(ctx, group)
+}
+
+func (s *GroupService) Get(ctx context.Context, userID, id string) (*Group, error) {
+	var group Group
+	err := s.db.WithContext(ctx).Preload("Legs").Where("id = ? AND user_id = ?", id, userID).First(&group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List returns the user's groups, newest first. An empty status matches
+// every group.
+func (s *GroupService) List(ctx context.Context, userID, status string) ([]Group, error) {
+	tx := s.db.WithContext(ctx).Preload("Legs").Where("user_id = ?", userID)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	var groups []Group
+	err := tx.Order("created_at DESC").Limit(MaxPageSize).Find(&groups).Error
+	return groups, err
+}
+
+// Cancel cancels every leg of the group. Legs that are not placed yet are
+// cancelled first so nothing new can be activated; open orders are then
+// cancelled on the exchange. Orders the exchange fails to cancel leave the
+// group cancelling until Sync has cancelled them.
+func (s *GroupService) Cancel(ctx context.Context, userID, id string) (*Group, error) {
+	defer s.lock(id)()
+
+	group, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.oms.Client(group.Exchange, group.Testnet)
+	if err != nil {
+		return nil, err
+	}
+	if group.Status != GroupActive && group.Status != GroupCancelling {
+		return group, nil
+	}
+
+	group.Status = GroupCancelling
+	s.cancelLegs(ctx, client, group, group.Legs)
+	s.finish(group)
+	return group, s.save(ctx, group)
+}
+
+// Sync advances every active group. It is meant to be called periodically.
+func (s *GroupService) Sync(ctx context.Context) error {
+	var ids []string
+	err := s.db.WithContext(ctx).Model(&Group{}).
+		Where("status IN ?", []string{GroupActive, GroupCancelling}).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return fmt.Errorf("failed to list order groups: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := s.syncGroup(ctx, id); err != nil {
+			log.Printf("Failed to sync order group %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// syncGroup advances one group. The group is read again under its lock,
+// since it may have been cancelled since it was listed.
+func (s *GroupService) syncGroup(ctx context.Context, id string) error {
+	defer s.lock(id)()
+
+	var group Group
+	err := s.db.WithContext(ctx).Preload("Legs").
+		Where("id = ? AND status IN ?", id, []string{GroupActive, GroupCancelling}).
+		First(&group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	client, err := s.oms.Client(group.Exchange, group.Testnet)
+	if err != nil {
+		return err
+	}
+
+	if group.Status == GroupCancelling {
+		s.cancelLegs(ctx, client, &group, group.Legs)
+		s.finish(&group)
+	} else {
+		s.advance(ctx, client, &group)
+	}
+	return s.save(ctx, &group)
+}
+
+// Run syncs groups every interval until ctx is cancelled.
+func (s *GroupService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil {
+				log.Printf("Order group sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// advance refreshes the legs and applies the group's rules until no leg
+// changes, so a market leg that fills right away activates its dependents
+// in the same call.
+func (s *GroupService) advance(ctx context.Context, client exchange.Client, group *Group) {
+	for pass := 0; pass <= len(group.Legs); pass++ {
+		before := group.statuses()
+		s.refresh(ctx, client, group)
+
+		if parent := group.parent(); parent != nil {
+			switch parent.Status {
+			case LegFilled:
+				for i := range group.Legs {
+					leg := &group.Legs[i]
+					if leg.Status == LegPending {
+						s.activate(ctx, client, group, leg)
+					}
+				}
+			case LegCancelled, LegRejected:
+				for i := range group.Legs {
+					if leg := &group.Legs[i]; leg.Status == LegPending {
+						leg.Status = LegCancelled
+					}
+				}
+			}
+		}
+
+		// One filled exit cancels the others
+		exits := group.exits()
+		for _, leg := range exits {
+			if leg.Status != LegFilled {
+				continue
+			}
+			var others []Leg
+			for _, other := range exits {
+				if other.ID != leg.ID && !other.terminal() {
+					others = append(others, *other)
+				}
+			}
+			s.cancelLegs(ctx, client, group, others)
+			break
+		}
+
+		if group.statuses() == before {
+			break
+		}
+	}
+	s.finish(group)
+}
+
+// refresh updates open legs from their exchange orders and places waiting
+// legs whose trigger price has been reached.
+func (s *GroupService) refresh(ctx context.Context, client exchange.Client, group *Group) {
+	var price decimal.Decimal
+	var priced bool
+
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		switch leg.Status {
+		case LegOpen:
+			order, err := client.Order(ctx, group.UserID, leg.OrderID)
+			if err != nil {
+				leg.Error = err.Error()
+				continue
+			}
+			leg.Status = legStatus(order.Status)
+		case LegWaiting:
+			if !priced {
+				var err error
+				if price, err = client.LastPrice(ctx, group.Symbol); err != nil {
+					leg.Error = err.Error()
+					continue
+				}
+				priced = true
+			}
+			if leg.triggered(price) {
+				s.place(ctx, client, group, leg)
+			}
+		}
+	}
+}
+
+// activate starts a leg: triggered legs begin watching the price, others
+// are placed right away.
+func (s *GroupService) activate(ctx context.Context, client exchange.Client, group *Group, leg *Leg) {
+	if leg.TriggerPrice.IsPositive() {
+		leg.Status = LegWaiting
+		return
+	}
+	s.place(ctx, client, group, leg)
+}
+
+func (s *GroupService) place(ctx context.Context, client exchange.Client, group *Group, leg *Leg) {
+	order, err := client.PlaceOrder(ctx, group.UserID, exchange.OrderRequest{
+		// Retried placements return the existing order
+		ClientOrderID: "group-" + leg.ID,
+		Symbol:        group.Symbol,
+		Side:          leg.Side,
+		Type:          leg.Type,
+		Price:         leg.Price,
+		Quantity:      leg.Quantity,
+		ReduceOnly:    leg.ReduceOnly,
+	})
+	if err != nil {
+		// Left as is so the next sync retries
+		leg.Error = err.Error()
+		return
+	}
+	leg.OrderID = order.ID
+	leg.Status = legStatus(order.Status)
+	leg.Error = ""
+}
+
+// cancelLegs cancels the given legs of group, matched by ID. Unplaced legs
+// go first so none of them can be activated by a fill seen meanwhile.
+func (s *GroupService) cancelLegs(ctx context.Context, client exchange.Client, group *Group, legs []Leg) {
+	ids := make(map[string]bool, len(legs))
+	for _, leg := range legs {
+		ids[leg.ID] = true
+	}
+
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		if ids[leg.ID] && (leg.Status == LegPending || leg.Status == LegWaiting) {
+			leg.Status = LegCancelled
+		}
+	}
+
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		if ids[leg.ID] && leg.Status == LegOpen {
+			err := client.CancelOrder(ctx, group.UserID, leg.OrderID)
+			switch {
+			case err == nil:
+				leg.Status = LegCancelled
+			case errors.Is(err, exchange.ErrOrderClosed):
+				// Filled before we got to it
+				if order, err := client.Order(ctx, group.UserID, leg.OrderID); err == nil {
+					leg.Status = legStatus(order.Status)
+				}
+			default:
+				leg.Error = err.Error()
+			}
+		}
+	}
+}
+
+// finish settles the group status once no leg can change anymore.
+func (s *GroupService) finish(group *Group) {
+	for i := range group.Legs {
+		if !group.Legs[i].terminal() {
+			return
+		}
+	}
+
+	first := &group.Legs[0]
+	if parent := group.parent(); parent != nil {
+		first = parent
+	}
+	switch {
+	case group.Status == GroupCancelling:
+		group.Status = GroupCancelled
+	case first.Status == LegRejected:
+		group.Status = GroupFailed
+	case group.filled():
+		group.Status = GroupCompleted
+	default:
+		group.Status = GroupCancelled
+	}
+}
+
+func (s *GroupService) save(ctx context.Context, group *Group) error {
+	return s.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(group).Error
+}
+
+// parent is the leg the others depend on, if the group type has one.
+func (g *Group) parent() *Leg {
+	switch g.Type {
+	case GroupBracket:
+		return g.leg(RoleEntry)
+	case GroupIfThen:
+		return g.leg(RoleIf)
+	}
+	return nil
+}
+
+// exits are the legs that cancel each other.
+func (g *Group) exits() []*Leg {
+	var exits []*Leg
+	for i := range g.Legs {
+		switch g.Legs[i].Role {
+		case RoleOCO, RoleStopLoss, RoleTakeProfit:
+			exits = append(exits, &g.Legs[i])
+		}
+	}
+	return exits
+}
+
+func (g *Group) leg(role string) *Leg {
+	for i := range g.Legs {
+		if g.Legs[i].Role == role {
+			return &g.Legs[i]
+		}
+	}
+	return nil
+}
+
+func (g *Group) statuses() string {
+	var statuses string
+	for _, leg := range g.Legs {
+		statuses += leg.Status + ","
+	}
+	return statuses
+}
+
+func (g *Group) filled() bool {
+	for _, leg := range g.Legs {
+		if leg.Status == LegFilled {
+			return true
+		}
+	}
+	return false
+}
+
+func legStatus(status exchange.OrderStatus) string {
+	switch status {
+	case exchange.OrderStatusFilled:
+		return LegFilled
+	case exchange.OrderStatusCancelled:
+		return LegCancelled
+	case exchange.OrderStatusRejected:
+		return LegRejected
+	}
+	return LegOpen
+}
+
+// newGroup validates req and builds the group with every leg pending.
+func newGroup(userID string, req GroupRequest) (*Group, error) {
+	if req.Exchange == "" || req.Symbol == "" {
+		return nil, fmt.Errorf("%w: exchange and symbol are required", ErrInvalidGroup)
+	}
+
+	var roles []string
+	switch req.Type {
+	case GroupOCO:
+		roles = []string{RoleOCO, RoleOCO}
+	case GroupBracket:
+		roles = []string{RoleEntry, RoleStopLoss, RoleTakeProfit}
+	case GroupIfThen:
+		roles = []string{RoleIf, RoleThen}
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidGroup, req.Type)
+	}
+	if len(req.Legs) != len(roles) {
+		return nil, fmt.Errorf("%w: %s needs %d legs", ErrInvalidGroup, req.Type, len(roles))
+	}
+
+	group := &Group{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		Type:     req.Type,
+		Exchange: req.Exchange,
+		Symbol:   req.Symbol,
+		Testnet:  req.Testnet,
+		Status:   GroupActive,
+	}
+
+	seen := make(map[string]int)
+	for _, legReq := range req.Legs {
+		role := legReq.Role
+		if req.Type == GroupOCO {
+			role = RoleOCO
+		}
+		seen[role]++
+		if err := validateLeg(role, legReq); err != nil {
+			return nil, err
+		}
+
+		leg := Leg{
+			ID:           uuid.New().String(),
+			GroupID:      group.ID,
+			Role:         role,
+			Side:         legReq.Side,
+			Type:         legReq.Type,
+			Price:        legReq.Price,
+			Quantity:     legReq.Quantity,
+			TriggerPrice: legReq.TriggerPrice,
+			Status:       LegPending,
+		}
+		group.Legs = append(group.Legs, leg)
+	}
+	for _, role := range roles {
+		if seen[role] == 0 {
+			return nil, fmt.Errorf("%w: missing %s leg", ErrInvalidGroup, role)
+		}
+	}
+
+	if req.Type == GroupBracket {
+		entry := group.leg(RoleEntry)
+		for _, exit := range group.exits() {
+			if exit.Side != entry.Side.Opposite() {
+				return nil, fmt.Errorf("%w: %s must close the entry", ErrInvalidGroup, exit.Role)
+			}
+			exit.ReduceOnly = true
+		}
+		if !group.leg(RoleStopLoss).TriggerPrice.IsPositive() {
+			return nil, fmt.Errorf("%w: stop_loss needs a trigger_price", ErrInvalidGroup)
+		}
+	}
+	return group, nil
+}
+
+func validateLeg(role string, req LegRequest) error {
+	if req.Side != exchange.SideBuy && req.Side != exchange.SideSell {
+		return fmt.Errorf("%w: %s leg side must be buy or sell", ErrInvalidGroup, role)
+	}
+	if !req.Quantity.IsPositive() {
+		return fmt.Errorf("%w: %s leg quantity must be positive", ErrInvalidGroup, role)
+	}
+	if req.TriggerPrice.IsNegative() {
+		return fmt.Errorf("%w: %s leg trigger_price must not be negative", ErrInvalidGroup, role)
+	}
+	switch req.Type {
+	case exchange.OrderTypeMarket:
+	case exchange.OrderTypeLimit:
+		if !req.Price.IsPositive() {
+			return fmt.Errorf("%w: %s limit leg needs a price", ErrInvalidGroup, role)
+		}
+	default:
+		return fmt.Errorf("%w: %s leg type must be market or limit", ErrInvalidGroup, role)
+	}
+	return nil
+}
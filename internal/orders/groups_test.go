@@ -0,0 +1,39 @@
+package orders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupService_LockPerGroup(t *testing.T) {
+	s := NewGroupService(nil, nil)
+
+	unlockA := s.lock("group-a")
+
+	// Another group is not held up
+	done := make(chan struct{})
+	go func() {
+		s.lock("group-b")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock of another group blocked")
+	}
+
+	// The same group waits for the release
+	acquired := make(chan func())
+	go func() { acquired <- s.lock("group-a") }()
+	select {
+	case <-acquired:
+		t.Fatal("lock of a held group did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	(<-acquired)()
+
+	assert.Empty(t, s.locks)
+}
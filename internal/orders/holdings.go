@@ -0,0 +1,319 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var repairedHoldings = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "portfolio_holdings_repaired_total",
+	Help: "Portfolio holdings the consistency check found out of line with their trades and rewrote.",
+})
+
+// Holding is a user's net result in one market. It is updated in the same
+// statement that writes the market's trades, so reading the portfolio never
+// aggregates trade history. Testnet trades are left out.
+type Holding struct {
+	UserID   string          `json:"-" gorm:"primaryKey;type:varchar(36)"`
+	Exchange string          `json:"exchange" gorm:"primaryKey;index:idx_portfolio_holdings_market,priority:1"`
+	Symbol   string          `json:"symbol" gorm:"primaryKey;index:idx_portfolio_holdings_market,priority:2"`
+	Quantity decimal.Decimal `json:"quantity" gorm:"type:numeric;not null;default:0"`
+	// Cash is the net cash flow of the trades: sales less purchases and fees
+	Cash   decimal.Decimal `json:"cash" gorm:"type:numeric;not null;default:0"`
+	Trades int64           `json:"trades" gorm:"not null;default:0"`
+	// LastPrice is set by the valuation job; ValuedAt is nil until then
+	LastPrice decimal.Decimal `json:"last_price" gorm:"type:numeric;not null;default:0"`
+	ValuedAt  *time.Time      `json:"valued_at,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// TableName sets the table name for GORM
+func (Holding) TableName() string {
+	return "portfolio_holdings"
+}
+
+// Value is the holding's position marked at the last price.
+func (h Holding) Value() decimal.Decimal {
+	return h.Quantity.Mul(h.LastPrice)
+}
+
+// tradeTotals aggregates the mainnet trades of a relation into holding
+// deltas.
+const tradeTotals = `SELECT user_id, exchange, symbol,
+	SUM(CASE WHEN side = 'buy' THEN quantity ELSE -quantity END) AS quantity,
+	SUM(CASE WHEN side = 'buy' THEN -price * quantity ELSE price * quantity END) - SUM(COALESCE(fee, 0)) AS cash,
+	COUNT(*) AS trades
+FROM %s WHERE NOT testnet
+GROUP BY user_id, exchange, symbol`
+
+// insertTrades writes a batch of trades and adds the ones that were not
+// already stored to the holdings. It is one statement so a retried batch
+// is never counted twice.
+func insertTrades(tx *gorm.DB, trades []Trade) error {
+	values := make([]string, len(trades))
+	args := make([]interface{}, 0, 13*len(trades))
+	for i, t := range trades {
+		values[i] = "(?, ?, ?, ?, ?, ?, ?, ?::numeric, ?::numeric, ?::numeric, ?, ?::boolean, ?::timestamptz)"
+		args = append(args, t.ID, t.OrderID, t.UserID, t.BotID, t.Exchange, t.Symbol, t.Side,
+			t.Price, t.Quantity, t.Fee, t.FeeAsset, t.Testnet, t.ExecutedAt)
+	}
+
+	return tx.Exec(`WITH inserted AS (
+	INSERT INTO trades (id, order_id, user_id, bot_id, exchange, symbol, side, price, quantity, fee, fee_asset, testnet, executed_at)
+	VALUES `+strings.Join(values, ", ")+`
+	ON CONFLICT DO NOTHING
+	RETURNING *
+)
+INSERT INTO portfolio_holdings (user_id, exchange, symbol, quantity, cash, trades, updated_at)
+SELECT user_id, exchange, symbol, quantity, cash, trades, NOW() FROM (`+fmt.Sprintf(tradeTotals, "inserted")+`) AS totals
+ON CONFLICT (user_id, exchange, symbol) DO UPDATE SET
+	quantity = portfolio_holdings.quantity + excluded.quantity,
+	cash = portfolio_holdings.cash + excluded.cash,
+	trades = portfolio_holdings.trades + excluded.trades,
+	updated_at = excluded.updated_at`, args...).Error
+}
+
+func (r *repository) ListHoldings(ctx context.Context, userID string) ([]Holding, error) {
+	var holdings []Holding
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("exchange, symbol").Find(&holdings).Error
+	return holdings, err
+}
+
+// Portfolio totals a user's holdings.
+type Portfolio struct {
+	Holdings []Holding `json:"holdings"`
+	// Cash is the net cash flow of all trades
+	Cash decimal.Decimal `json:"cash"`
+	// Value is the open positions marked at their last prices
+	Value decimal.Decimal `json:"value"`
+	// PnL is the result of trading so far, realized and unrealized
+	PnL decimal.Decimal `json:"pnl"`
+	// ValuedAt is the oldest valuation of an open position
+	ValuedAt *time.Time `json:"valued_at,omitempty"`
+}
+
+func NewPortfolio(holdings []Holding) *Portfolio {
+	p := &Portfolio{Holdings: holdings}
+	if p.Holdings == nil {
+		p.Holdings = []Holding{}
+	}
+	for _, h := range holdings {
+		p.Cash = p.Cash.Add(h.Cash)
+		p.Value = p.Value.Add(h.Value())
+		if !h.Quantity.IsZero() && h.ValuedAt != nil && (p.ValuedAt == nil || h.ValuedAt.Before(*p.ValuedAt)) {
+			p.ValuedAt = h.ValuedAt
+		}
+	}
+	p.PnL = p.Cash.Add(p.Value)
+	return p
+}
+
+// HoldingsValuer marks open holdings at the exchanges' last prices.
+type HoldingsValuer struct {
+	dbs       []*gorm.DB
+	exchanges *exchange.Registry
+}
+
+// NewHoldingsValuer values the holdings stored in each of dbs, one per
+// data region.
+func NewHoldingsValuer(dbs []*gorm.DB, exchanges *exchange.Registry) *HoldingsValuer {
+	return &HoldingsValuer{dbs: dbs, exchanges: exchanges}
+}
+
+// Run values every open holding once. It matches scheduler.JobFunc so the
+// cadence is controlled by the job schedule.
+func (v *HoldingsValuer) Run(ctx context.Context) error {
+	for _, db := range v.dbs {
+		if err := v.value(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type market struct {
+	Exchange string
+	Symbol   string
+}
+
+func (v *HoldingsValuer) value(ctx context.Context, db *gorm.DB) error {
+	var markets []market
+	err := db.WithContext(ctx).Model(&Holding{}).Distinct("exchange", "symbol").
+		Where("quantity <> 0").Scan(&markets).Error
+	if err != nil {
+		return fmt.Errorf("failed to list held markets: %w", err)
+	}
+	if len(markets) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	values := make([]string, 0, len(markets))
+	args := make([]interface{}, 0, 3*len(markets))
+	for _, m := range markets {
+		client, err := v.exchanges.Get(m.Exchange)
+		if err != nil {
+			log.Printf("Skipping valuation of %s %s: %v", m.Exchange, m.Symbol, err)
+			continue
+		}
+		price, err := client.LastPrice(ctx, m.Symbol)
+		if err != nil {
+			// The holding keeps its previous price and valuation time
+			log.Printf("Skipping valuation of %s %s: %v", m.Exchange, m.Symbol, err)
+			continue
+		}
+		values = append(values, "(?, ?, ?::numeric)")
+		args = append(args, m.Exchange, m.Symbol, price)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	err = db.WithContext(ctx).Exec(`UPDATE portfolio_holdings SET last_price = v.price, valued_at = ?
+FROM (VALUES `+strings.Join(values, ", ")+`) AS v(exchange, symbol, price)
+WHERE portfolio_holdings.exchange = v.exchange AND portfolio_holdings.symbol = v.symbol`,
+		append([]interface{}{now}, args...)...).Error
+	if err != nil {
+		return fmt.Errorf("failed to store valuations: %w", err)
+	}
+	return nil
+}
+
+// HoldingsChecker recomputes holdings from trade history and rewrites the
+// ones that drifted, e.g. after trades were written or deleted around
+// insertTrades.
+type HoldingsChecker struct {
+	dbs []*gorm.DB
+}
+
+// NewHoldingsChecker checks the holdings stored in each of dbs, one per
+// data region.
+func NewHoldingsChecker(dbs []*gorm.DB) *HoldingsChecker {
+	return &HoldingsChecker{dbs: dbs}
+}
+
+// Run checks every holding once. It matches scheduler.JobFunc so the
+// cadence is controlled by the job schedule.
+func (c *HoldingsChecker) Run(ctx context.Context) error {
+	for _, db := range c.dbs {
+		if err := c.check(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type holdingKey struct {
+	UserID   string
+	Exchange string
+	Symbol   string
+}
+
+type holdingTotals struct {
+	holdingKey
+	Quantity decimal.Decimal
+	Cash     decimal.Decimal
+	Trades   int64
+}
+
+func (t holdingTotals) equal(o holdingTotals) bool {
+	return t.Quantity.Equal(o.Quantity) && t.Cash.Equal(o.Cash) && t.Trades == o.Trades
+}
+
+// check compares without locks first, since holdings change under it, and
+// only repairs the holdings that still differ once locked.
+func (c *HoldingsChecker) check(ctx context.Context, db *gorm.DB) error {
+	var expected []holdingTotals
+	if err := db.WithContext(ctx).Raw(fmt.Sprintf(tradeTotals, "trades")).Scan(&expected).Error; err != nil {
+		return fmt.Errorf("failed to aggregate trades: %w", err)
+	}
+	var stored []holdingTotals
+	err := db.WithContext(ctx).Model(&Holding{}).
+		Select("user_id, exchange, symbol, quantity, cash, trades").Scan(&stored).Error
+	if err != nil {
+		return fmt.Errorf("failed to list holdings: %w", err)
+	}
+
+	suspects := make(map[holdingKey]bool)
+	byKey := make(map[holdingKey]holdingTotals, len(stored))
+	for _, s := range stored {
+		byKey[s.holdingKey] = s
+	}
+	for _, e := range expected {
+		if s, ok := byKey[e.holdingKey]; !ok || !s.equal(e) {
+			suspects[e.holdingKey] = true
+		}
+		delete(byKey, e.holdingKey)
+	}
+	// Holdings left over have no trades at all
+	for key := range byKey {
+		suspects[key] = true
+	}
+
+	for key := range suspects {
+		repaired, err := c.repair(ctx, db, key)
+		if err != nil {
+			return err
+		}
+		if repaired {
+			repairedHoldings.Inc()
+			log.Printf("Repaired portfolio holding of user %s in %s %s", key.UserID, key.Exchange, key.Symbol)
+		}
+	}
+	return nil
+}
+
+// repair locks the holding before recomputing it, so trades written
+// concurrently are either seen by the recomputation or added after it.
+func (c *HoldingsChecker) repair(ctx context.Context, db *gorm.DB, key holdingKey) (bool, error) {
+	repaired := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Create a missing row so there is something to lock
+		holding := Holding{UserID: key.UserID, Exchange: key.Exchange, Symbol: key.Symbol, UpdatedAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&holding).Error; err != nil {
+			return err
+		}
+		var stored holdingTotals
+		err := tx.Model(&Holding{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("user_id, exchange, symbol, quantity, cash, trades").
+			Where("user_id = ? AND exchange = ? AND symbol = ?", key.UserID, key.Exchange, key.Symbol).
+			Scan(&stored).Error
+		if err != nil {
+			return err
+		}
+
+		var expected []holdingTotals
+		err = tx.Raw(fmt.Sprintf(tradeTotals, "(SELECT * FROM trades WHERE user_id = ? AND exchange = ? AND symbol = ?) AS t"),
+			key.UserID, key.Exchange, key.Symbol).Scan(&expected).Error
+		if err != nil {
+			return err
+		}
+
+		where := tx.Model(&Holding{}).Where("user_id = ? AND exchange = ? AND symbol = ?", key.UserID, key.Exchange, key.Symbol)
+		if len(expected) == 0 {
+			repaired = stored.Trades != 0 || !stored.Quantity.IsZero() || !stored.Cash.IsZero()
+			return where.Delete(&Holding{}).Error
+		}
+		if stored.equal(expected[0]) {
+			return nil
+		}
+		repaired = true
+		return where.Updates(map[string]interface{}{
+			"quantity":   expected[0].Quantity,
+			"cash":       expected[0].Cash,
+			"trades":     expected[0].Trades,
+			"updated_at": time.Now(),
+		}).Error
+	})
+	return repaired, err
+}
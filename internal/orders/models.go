@@ -0,0 +1,55 @@
+package orders
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Order is the persisted history of an order. The composite indexes match
+// the search filters: every query is scoped to a user and pages by
+// creation time, then ID.
+type Order struct {
+	ID            string          `json:"id" gorm:"primaryKey;type:varchar(36);index:idx_orders_user_created,priority:3,sort:desc;index:idx_orders_user_symbol_keyset,priority:4,sort:desc;index:idx_orders_user_bot_keyset,priority:4,sort:desc;index:idx_orders_user_status_keyset,priority:4,sort:desc"`
+	UserID        string          `json:"user_id" gorm:"type:varchar(36);not null;index:idx_orders_user_created,priority:1;index:idx_orders_user_symbol_keyset,priority:1;index:idx_orders_user_bot_keyset,priority:1;index:idx_orders_user_status_keyset,priority:1"`
+	BotID         string          `json:"bot_id,omitempty" gorm:"type:varchar(36);index:idx_orders_user_bot_keyset,priority:2"`
+	Exchange      string          `json:"exchange" gorm:"not null"`
+	Symbol        string          `json:"symbol" gorm:"not null;index:idx_orders_user_symbol_keyset,priority:2"`
+	Side          string          `json:"side" gorm:"not null"`
+	Type          string          `json:"type" gorm:"not null"`
+	Status        string          `json:"status" gorm:"not null;index:idx_orders_user_status_keyset,priority:2"`
+	Price         decimal.Decimal `json:"price" gorm:"type:numeric"`
+	Quantity      decimal.Decimal `json:"quantity" gorm:"type:numeric"`
+	Filled        decimal.Decimal `json:"filled" gorm:"type:numeric"`
+	ClientOrderID string          `json:"client_order_id,omitempty"`
+	// Testnet orders are kept out of portfolio analytics
+	Testnet   bool      `json:"testnet" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_orders_user_created,priority:2,sort:desc;index:idx_orders_user_symbol_keyset,priority:3,sort:desc;index:idx_orders_user_bot_keyset,priority:3,sort:desc;index:idx_orders_user_status_keyset,priority:3,sort:desc"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Order) TableName() string {
+	return "orders"
+}
+
+type Trade struct {
+	ID         string          `json:"id" gorm:"primaryKey;type:varchar(36);index:idx_trades_user_executed,priority:3,sort:desc;index:idx_trades_user_symbol_keyset,priority:4,sort:desc;index:idx_trades_user_bot_keyset,priority:4,sort:desc"`
+	OrderID    string          `json:"order_id" gorm:"type:varchar(36);index"`
+	UserID     string          `json:"user_id" gorm:"type:varchar(36);not null;index:idx_trades_user_executed,priority:1;index:idx_trades_user_symbol_keyset,priority:1;index:idx_trades_user_bot_keyset,priority:1"`
+	BotID      string          `json:"bot_id,omitempty" gorm:"type:varchar(36);index:idx_trades_user_bot_keyset,priority:2"`
+	Exchange   string          `json:"exchange" gorm:"not null"`
+	Symbol     string          `json:"symbol" gorm:"not null;index:idx_trades_user_symbol_keyset,priority:2"`
+	Side       string          `json:"side" gorm:"not null"`
+	Price      decimal.Decimal `json:"price" gorm:"type:numeric"`
+	Quantity   decimal.Decimal `json:"quantity" gorm:"type:numeric"`
+	Fee        decimal.Decimal `json:"fee" gorm:"type:numeric"`
+	FeeAsset   string          `json:"fee_asset"`
+	Testnet    bool            `json:"testnet" gorm:"not null;default:false"`
+	ExecutedAt time.Time       `json:"executed_at" gorm:"not null;index:idx_trades_user_executed,priority:2,sort:desc;index:idx_trades_user_symbol_keyset,priority:3,sort:desc;index:idx_trades_user_bot_keyset,priority:3,sort:desc"`
+}
+
+// TableName sets the table name for GORM
+func (Trade) TableName() string {
+	return "trades"
+}
@@ -0,0 +1,192 @@
+package orders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+// OMS is the one path orders take to an exchange, whoever places them:
+// bots, order groups and bulk operations. Prices and quantities are
+// snapped to the market's precision and orders below its minimums are
+// refused with money.ErrBelowMinimum before they reach the exchange.
+// Orders of bots are also throttled by their rate policy. Placed orders
+// and the trades of their fills are recorded in history, which keeps the
+// users' holdings current; a nil history records nothing.
+type OMS struct {
+	exchanges  *exchange.Registry
+	precisions exchange.Precisions
+	history    Repository
+	throttle   *Throttle
+}
+
+func NewOMS(exchanges *exchange.Registry, precisions exchange.Precisions, history Repository) *OMS {
+	return &OMS{exchanges: exchanges, precisions: precisions, history: history, throttle: NewThrottle()}
+}
+
+// Client returns the client of the exchange's mainnet or testnet
+// environment with orders going through the OMS.
+func (o *OMS) Client(name string, testnet bool) (exchange.Client, error) {
+	return o.client(name, testnet, "")
+}
+
+func (o *OMS) client(name string, testnet bool, botID string) (exchange.Client, error) {
+	client, err := o.exchanges.For(name, testnet)
+	if err != nil {
+		return nil, err
+	}
+	if o.history != nil {
+		client = &historyClient{Client: client, exchange: name, testnet: testnet, botID: botID, history: o.history}
+	}
+	return &precisionClient{Client: client, exchange: name, precisions: o.precisions}, nil
+}
+
+// BotClient is Client with the bot's placements and cancels subject to
+// policy.
+func (o *OMS) BotClient(name string, testnet bool, botID string, policy RatePolicy) (exchange.Client, error) {
+	client, err := o.client(name, testnet, botID)
+	if err != nil {
+		return nil, err
+	}
+	return o.throttle.Wrap(client, botID, policy), nil
+}
+
+// Forget drops the schedule of a bot that stopped.
+func (o *OMS) Forget(botID string) {
+	o.throttle.Forget(botID)
+}
+
+// Names returns the mainnet exchanges in a stable order.
+func (o *OMS) Names() []string {
+	return o.exchanges.Names()
+}
+
+type precisionClient struct {
+	exchange.Client
+	exchange   string
+	precisions exchange.Precisions
+}
+
+func (c *precisionClient) PlaceOrder(ctx context.Context, userID string, req exchange.OrderRequest) (*exchange.Order, error) {
+	precision := c.precisions.Get(c.exchange, req.Symbol)
+	req.Quantity = precision.RoundQuantity(req.Quantity)
+	if req.Type == exchange.OrderTypeLimit {
+		req.Price = precision.RoundPrice(req.Price, req.Side == exchange.SideBuy)
+	}
+
+	// Closing a position must work however small it is
+	if req.ReduceOnly {
+		if err := (money.Precision{}).Check(req.Price, req.Quantity); err != nil {
+			return nil, err
+		}
+		return c.Client.PlaceOrder(ctx, userID, req)
+	}
+
+	// Market orders are checked at the price they are expected to fill at
+	price := req.Price
+	if req.Type == exchange.OrderTypeMarket && precision.MinNotional.IsPositive() {
+		last, err := c.Client.LastPrice(ctx, req.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		price = last
+	}
+	if err := precision.Check(price, req.Quantity); err != nil {
+		return nil, err
+	}
+	return c.Client.PlaceOrder(ctx, userID, req)
+}
+
+// historyClient records the orders it places. IDs are derived from the
+// exchange's order ID, so an order the exchange returns again for a
+// repeated client order ID is not recorded twice. Orders that rest on the
+// book are filled later; their trade is recorded once a lookup finds them
+// closed, which is how order groups learn of their legs' fills.
+type historyClient struct {
+	exchange.Client
+	exchange string
+	testnet  bool
+	botID    string
+	history  Repository
+}
+
+func (c *historyClient) PlaceOrder(ctx context.Context, userID string, req exchange.OrderRequest) (*exchange.Order, error) {
+	order, err := c.Client.PlaceOrder(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order stands whether or not it could be recorded
+	ctx = context.WithoutCancel(ctx)
+	err = c.history.CreateOrder(ctx, &Order{
+		ID:            c.orderID(order),
+		UserID:        userID,
+		BotID:         c.botID,
+		Exchange:      c.exchange,
+		Symbol:        order.Symbol,
+		Side:          string(order.Side),
+		Type:          string(order.Type),
+		Status:        string(order.Status),
+		Price:         order.Price,
+		Quantity:      order.Quantity,
+		Filled:        order.Filled,
+		ClientOrderID: order.ClientOrderID,
+		Testnet:       c.testnet,
+	})
+	if err != nil {
+		log.Printf("Failed to record order %s of user %s: %v", order.ID, userID, err)
+	}
+	if order.Status != exchange.OrderStatusOpen {
+		c.recordFill(ctx, userID, order)
+	}
+	return order, nil
+}
+
+func (c *historyClient) Order(ctx context.Context, userID, orderID string) (*exchange.Order, error) {
+	order, err := c.Client.Order(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != exchange.OrderStatusOpen {
+		c.recordFill(context.WithoutCancel(ctx), userID, order)
+	}
+	return order, nil
+}
+
+func (c *historyClient) orderID(order *exchange.Order) string {
+	return historyOrderID(c.exchange, order.ID)
+}
+
+// historyOrderID is the ID an exchange's order is recorded under.
+func historyOrderID(exchange, orderID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(exchange+"|order|"+orderID)).String()
+}
+
+// recordFill records what has been filled of a closed order as one trade.
+// Recording it again is a no-op, since the trade's ID is derived from the
+// order's.
+func (c *historyClient) recordFill(ctx context.Context, userID string, order *exchange.Order) {
+	if !order.Filled.IsPositive() {
+		return
+	}
+	err := c.history.CreateTrade(ctx, &Trade{
+		ID:         uuid.NewSHA1(uuid.NameSpaceOID, []byte(c.exchange+"|trade|"+order.ID)).String(),
+		OrderID:    c.orderID(order),
+		UserID:     userID,
+		BotID:      c.botID,
+		Exchange:   c.exchange,
+		Symbol:     order.Symbol,
+		Side:       string(order.Side),
+		Price:      order.Price,
+		Quantity:   order.Filled,
+		Testnet:    c.testnet,
+		ExecutedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to record the fill of order %s of user %s: %v", order.ID, userID, err)
+	}
+}
@@ -0,0 +1,245 @@
+package orders
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+// recordedHistory keeps what the OMS records in memory.
+type recordedHistory struct {
+	Repository
+	orders map[string]Order
+	trades map[string]Trade
+}
+
+func (h *recordedHistory) CreateOrder(ctx context.Context, order *Order) error {
+	if _, ok := h.orders[order.ID]; !ok {
+		h.orders[order.ID] = *order
+	}
+	return nil
+}
+
+func (h *recordedHistory) OpenOrders(ctx context.Context) ([]Order, error) {
+	var open []Order
+	for _, order := range h.orders {
+		if order.Status == string(exchange.OrderStatusOpen) {
+			open = append(open, order)
+		}
+	}
+	return open, nil
+}
+
+func (h *recordedHistory) SettleOrder(ctx context.Context, id, status string, filled decimal.Decimal) error {
+	order := h.orders[id]
+	order.Status, order.Filled = status, filled
+	h.orders[id] = order
+	return nil
+}
+
+func (h *recordedHistory) CreateTrade(ctx context.Context, trade *Trade) error {
+	if _, ok := h.trades[trade.ID]; !ok {
+		h.trades[trade.ID] = *trade
+	}
+	return nil
+}
+
+func paperOMS(t *testing.T) *OMS {
+	paper := exchange.NewPaperClient("binance")
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	require.NoError(t, paper.Deposit(context.Background(), "user-1", decimal.NewFromInt(1000)))
+
+	clients := exchange.NewRegistry()
+	clients.Register("binance", paper)
+	precision, err := money.ParsePrecision("0.5", "0.01", "5")
+	require.NoError(t, err)
+	return NewOMS(clients, exchange.Precisions{"binance": {"BTCUSDT": precision}}, nil)
+}
+
+func TestOMS_PlaceOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      exchange.OrderRequest
+		err      error
+		price    string
+		quantity string
+	}{
+		{
+			name:     "market quantity truncated to step",
+			req:      exchange.OrderRequest{Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.RequireFromString("0.1299")},
+			quantity: "0.12",
+		},
+		{
+			name:     "limit buy price rounded down",
+			req:      exchange.OrderRequest{Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Price: decimal.RequireFromString("99.9"), Quantity: decimal.NewFromInt(1)},
+			price:    "99.5",
+			quantity: "1",
+		},
+		{
+			name:     "limit sell price rounded up",
+			req:      exchange.OrderRequest{Side: exchange.SideSell, Type: exchange.OrderTypeLimit, Price: decimal.RequireFromString("100.1"), Quantity: decimal.NewFromInt(1)},
+			price:    "100.5",
+			quantity: "1",
+		},
+		{
+			name: "market notional checked at last price",
+			req:  exchange.OrderRequest{Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.RequireFromString("0.04")},
+			err:  money.ErrBelowMinimum,
+		},
+		{
+			name: "quantity rounding to zero",
+			req:  exchange.OrderRequest{Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.RequireFromString("0.009")},
+			err:  money.ErrBelowMinimum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := paperOMS(t).Client("binance", false)
+			require.NoError(t, err)
+
+			tt.req.Symbol = "BTCUSDT"
+			order, err := client.PlaceOrder(context.Background(), "user-1", tt.req)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.quantity, order.Quantity.String())
+			if tt.price != "" {
+				assert.Equal(t, tt.price, order.Price.String())
+			}
+		})
+	}
+}
+
+func TestOMS_ReduceOnlyIgnoresMinNotional(t *testing.T) {
+	client, err := paperOMS(t).Client("binance", false)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.PlaceOrder(ctx, "user-1", exchange.OrderRequest{Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+
+	_, err = client.PlaceOrder(ctx, "user-1", exchange.OrderRequest{Symbol: "BTCUSDT", Side: exchange.SideSell, Type: exchange.OrderTypeMarket, Quantity: decimal.RequireFromString("0.01"), ReduceOnly: true})
+	assert.NoError(t, err)
+}
+
+func TestOMS_BotClientThrottles(t *testing.T) {
+	oms := paperOMS(t)
+	client, err := oms.BotClient("binance", false, "bot-1", RatePolicy{OrdersPerMinute: 1, Burst: 1})
+	require.NoError(t, err)
+	ctx := context.Background()
+	req := exchange.OrderRequest{Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.NewFromInt(1)}
+
+	_, err = client.PlaceOrder(ctx, "user-1", req)
+	require.NoError(t, err)
+	_, err = client.PlaceOrder(ctx, "user-1", req)
+	assert.ErrorIs(t, err, ErrThrottled)
+
+	// A restarted bot starts with a fresh schedule
+	oms.Forget("bot-1")
+	client, err = oms.BotClient("binance", false, "bot-1", RatePolicy{OrdersPerMinute: 1, Burst: 1})
+	require.NoError(t, err)
+	_, err = client.PlaceOrder(ctx, "user-1", req)
+	assert.NoError(t, err)
+}
+
+func TestOMS_RecordsHistory(t *testing.T) {
+	paper := exchange.NewPaperClient("binance")
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	ctx := context.Background()
+	require.NoError(t, paper.Deposit(ctx, "user-1", decimal.NewFromInt(1000)))
+	clients := exchange.NewRegistry()
+	clients.Register("binance", paper)
+	history := &recordedHistory{orders: map[string]Order{}, trades: map[string]Trade{}}
+	oms := NewOMS(clients, nil, history)
+
+	client, err := oms.BotClient("binance", false, "bot-1", RatePolicy{})
+	require.NoError(t, err)
+	req := exchange.OrderRequest{ClientOrderID: "bot-1-1", Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeMarket, Quantity: decimal.NewFromInt(2)}
+	order, err := client.PlaceOrder(ctx, "user-1", req)
+	require.NoError(t, err)
+	// The exchange answers a repeated client order ID with the same order
+	_, err = client.PlaceOrder(ctx, "user-1", req)
+	require.NoError(t, err)
+
+	require.Len(t, history.orders, 1)
+	require.Len(t, history.trades, 1)
+	for _, trade := range history.trades {
+		assert.Equal(t, "bot-1", trade.BotID)
+		assert.Equal(t, "user-1", trade.UserID)
+		assert.Equal(t, order.Price.String(), trade.Price.String())
+		assert.Equal(t, "2", trade.Quantity.String())
+		assert.Contains(t, history.orders, trade.OrderID)
+	}
+}
+
+func TestOMS_RecordsRestingFill(t *testing.T) {
+	paper := exchange.NewPaperClient("binance")
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	ctx := context.Background()
+	clients := exchange.NewRegistry()
+	clients.Register("binance", paper)
+	history := &recordedHistory{orders: map[string]Order{}, trades: map[string]Trade{}}
+
+	client, err := NewOMS(clients, nil, history).Client("binance", false)
+	require.NoError(t, err)
+	order, err := client.PlaceOrder(ctx, "user-1", exchange.OrderRequest{Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Price: decimal.NewFromInt(90), Quantity: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	_, err = client.Order(ctx, "user-1", order.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history.trades)
+
+	// The fill is recorded once the order is looked up closed, and only once
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(90))
+	for range 2 {
+		_, err = client.Order(ctx, "user-1", order.ID)
+		require.NoError(t, err)
+	}
+	require.Len(t, history.trades, 1)
+	for _, trade := range history.trades {
+		assert.Equal(t, "90", trade.Price.String())
+		assert.Equal(t, "1", trade.Quantity.String())
+		assert.Contains(t, history.orders, trade.OrderID)
+	}
+}
+
+func TestOMS_Reconcile(t *testing.T) {
+	// Every fill is reported twice
+	chaos := exchange.NewChaos("binance", config.PaperChaosConfig{Enabled: true})
+	require.NoError(t, chaos.Set("user-1", exchange.ChaosSettings{DuplicateFillRate: 1}))
+	paper := exchange.NewPaperClient("binance").WithChaos(chaos)
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(100))
+	ctx := context.Background()
+	clients := exchange.NewRegistry()
+	clients.Register("binance", paper)
+	history := &recordedHistory{orders: map[string]Order{}, trades: map[string]Trade{}}
+	oms := NewOMS(clients, nil, history)
+
+	client, err := oms.BotClient("binance", false, "bot-1", RatePolicy{})
+	require.NoError(t, err)
+	_, err = client.PlaceOrder(ctx, "user-1", exchange.OrderRequest{Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Price: decimal.NewFromInt(90), Quantity: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	require.NoError(t, oms.Reconcile(ctx))
+	assert.Empty(t, history.trades)
+
+	paper.SetPrice("BTCUSDT", decimal.NewFromInt(90))
+	for range 2 {
+		require.NoError(t, oms.Reconcile(ctx))
+	}
+	require.Len(t, history.trades, 1)
+	for _, trade := range history.trades {
+		assert.Equal(t, "bot-1", trade.BotID)
+		assert.Equal(t, "1", trade.Quantity.String())
+		order := history.orders[trade.OrderID]
+		assert.Equal(t, string(exchange.OrderStatusFilled), order.Status)
+		assert.Equal(t, "1", order.Filled.String())
+	}
+}
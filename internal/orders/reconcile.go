@@ -0,0 +1,115 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+// account is one user's account on one exchange environment.
+type account struct {
+	userID   string
+	exchange string
+	testnet  bool
+}
+
+// Reconcile settles the orders history still has open from the fills the
+// exchanges report. An order that closed has its status and filled
+// quantity updated and its fills recorded as one trade, under the same ID
+// its lookup would record it under, so reconciling again, or a fill that
+// is reported twice, records nothing more. Orders still resting keep
+// their status. Exchanges that cannot list fills are skipped.
+func (o *OMS) Reconcile(ctx context.Context) error {
+	if o.history == nil {
+		return nil
+	}
+	open, err := o.history.OpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list open orders: %w", err)
+	}
+
+	orders := make(map[account]map[string]Order)
+	since := make(map[account]time.Time)
+	for _, order := range open {
+		key := account{userID: order.UserID, exchange: order.Exchange, testnet: order.Testnet}
+		if orders[key] == nil {
+			orders[key] = make(map[string]Order)
+		}
+		orders[key][order.ID] = order
+		if oldest, ok := since[key]; !ok || order.CreatedAt.Before(oldest) {
+			since[key] = order.CreatedAt
+		}
+	}
+
+	for key, byID := range orders {
+		if err := o.reconcile(ctx, key, byID, since[key]); err != nil {
+			log.Printf("Failed to reconcile %s orders of user %s: %v", key.exchange, key.userID, err)
+		}
+	}
+	return nil
+}
+
+// reconcile settles the account's open orders that have fills since the
+// given time.
+func (o *OMS) reconcile(ctx context.Context, key account, open map[string]Order, since time.Time) error {
+	client, err := o.exchanges.For(key.exchange, key.testnet)
+	if err != nil {
+		return err
+	}
+	source, ok := exchange.Unwrap(client).(exchange.FillSource)
+	if !ok {
+		return nil
+	}
+	fills, err := source.Fills(ctx, key.userID, since)
+	if errors.Is(err, exchange.ErrNotPaper) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	settled := make(map[string]bool)
+	for _, fill := range fills {
+		id := historyOrderID(key.exchange, fill.OrderID)
+		recorded, ok := open[id]
+		if !ok || settled[id] {
+			continue
+		}
+		settled[id] = true
+
+		history := &historyClient{Client: client, exchange: key.exchange, testnet: key.testnet, botID: recorded.BotID, history: o.history}
+		order, err := history.Order(ctx, key.userID, fill.OrderID)
+		if err != nil {
+			log.Printf("Failed to look up order %s of user %s: %v", fill.OrderID, key.userID, err)
+			continue
+		}
+		if string(order.Status) == recorded.Status && order.Filled.Equal(recorded.Filled) {
+			continue
+		}
+		if err := o.history.SettleOrder(ctx, id, string(order.Status), order.Filled); err != nil {
+			log.Printf("Failed to settle order %s of user %s: %v", fill.OrderID, key.userID, err)
+		}
+	}
+	return nil
+}
+
+// RunReconcile reconciles every interval until ctx is cancelled.
+func (o *OMS) RunReconcile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := o.Reconcile(ctx); err != nil {
+				log.Printf("Fill reconciliation failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+
+	// tradeInsertBatch keeps multi-row inserts well below the Postgres
+	// limit of 65535 bind parameters
+	tradeInsertBatch = 1000
+)
+
+// Query filters order and trade history. Empty fields match everything;
+// Status is ignored for trades.
+type Query struct {
+	UserID   string
+	Symbol   string
+	Side     string
+	Status   string
+	BotID    string
+	Exchange string
+	// Testnet selects the testnet or mainnet environment; nil matches both
+	Testnet *bool
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Cursor  string
+}
+
+type OrderPage struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type TradePage struct {
+	Trades     []Trade `json:"trades"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type Repository interface {
+	// CreateOrder records an order; an order that was recorded before is
+	// left alone
+	CreateOrder(ctx context.Context, order *Order) error
+	UpdateOrder(ctx context.Context, order *Order) error
+	// OpenOrders returns the orders of every user still recorded as open
+	OpenOrders(ctx context.Context) ([]Order, error)
+	// SettleOrder records the status and filled quantity the exchange
+	// reports for an order
+	SettleOrder(ctx context.Context, id, status string, filled decimal.Decimal) error
+	CreateTrade(ctx context.Context, trade *Trade) error
+	// CreateTrades bulk inserts trades, skipping IDs that already exist so
+	// a retried batch does not fail on the rows it wrote before. The
+	// user's holdings are updated with the trades.
+	CreateTrades(ctx context.Context, trades []Trade) error
+	// ListHoldings returns the user's holdings, one per market traded
+	ListHoldings(ctx context.Context, userID string) ([]Holding, error)
+	SearchOrders(ctx context.Context, q Query) (*OrderPage, error)
+	SearchTrades(ctx context.Context, q Query) (*TradePage, error)
+	// ExportTrades calls start with the number of trades matching q after
+	// q.Cursor, ignoring q.Limit, then fn with each of them, newest first.
+	// Both read one snapshot, so fn gets exactly as many trades as start
+	// was told. Trades are read from one query as fn consumes them, so
+	// memory stays bounded however many there are.
+	ExportTrades(ctx context.Context, q Query, start func(total int64) error, fn func(*Trade) error) error
+	// TradeCursor returns the cursor that continues the trades matching q
+	// after the user's trade, or ErrInvalidCursor if they have no such
+	// trade
+	TradeCursor(ctx context.Context, q Query, tradeID string) (string, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOrder(ctx context.Context, order *Order) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(order).Error
+}
+
+func (r *repository) UpdateOrder(ctx context.Context, order *Order) error {
+	return r.db.WithContext(ctx).Save(order).Error
+}
+
+func (r *repository) OpenOrders(ctx context.Context) ([]Order, error) {
+	var orders []Order
+	err := r.db.WithContext(ctx).Where("status = ?", string(exchange.OrderStatusOpen)).Find(&orders).Error
+	return orders, err
+}
+
+func (r *repository) SettleOrder(ctx context.Context, id, status string, filled decimal.Decimal) error {
+	return r.db.WithContext(ctx).Model(&Order{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "filled": filled}).Error
+}
+
+func (r *repository) CreateTrade(ctx context.Context, trade *Trade) error {
+	return r.CreateTrades(ctx, []Trade{*trade})
+}
+
+func (r *repository) CreateTrades(ctx context.Context, trades []Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(trades); start += tradeInsertBatch {
+			end := min(start+tradeInsertBatch, len(trades))
+			if err := insertTrades(tx, trades[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repository) SearchOrders(ctx context.Context, q Query) (*OrderPage, error) {
+	tx, limit, err := r.search(ctx, q, historyOrders)
+	if err != nil {
+		return nil, err
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+
+	var orders []Order
+	if err := tx.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	page := &OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = encodeCursor(historyOrders, q, last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *repository) SearchTrades(ctx context.Context, q Query) (*TradePage, error) {
+	tx, limit, err := r.search(ctx, q, historyTrades)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []Trade
+	if err := tx.Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	page := &TradePage{Trades: trades}
+	if len(trades) > limit {
+		page.Trades = trades[:limit]
+		last := page.Trades[limit-1]
+		page.NextCursor = encodeCursor(historyTrades, q, last.ExecutedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *repository) ExportTrades(ctx context.Context, q Query, start func(total int64) error, fn func(*Trade) error) error {
+	// A repeatable read transaction keeps trades recorded meanwhile out of
+	// both the count and the rows
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var total int64
+		counted, err := r.filter(tx, q, historyTrades)
+		if err != nil {
+			return err
+		}
+		if err := counted.Model(&Trade{}).Count(&total).Error; err != nil {
+			return err
+		}
+		if err := start(total); err != nil {
+			return err
+		}
+
+		listed, err := r.filter(tx, q, historyTrades)
+		if err != nil {
+			return err
+		}
+		rows, err := listed.Model(&Trade{}).Order("executed_at DESC").Order("id DESC").Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var trade Trade
+			if err := tx.ScanRows(rows, &trade); err != nil {
+				return err
+			}
+			if err := fn(&trade); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+func (r *repository) TradeCursor(ctx context.Context, q Query, tradeID string) (string, error) {
+	var trade Trade
+	err := r.db.WithContext(ctx).Select("id", "executed_at").
+		Where("user_id = ? AND id = ?", q.UserID, tradeID).First(&trade).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrInvalidCursor
+	}
+	if err != nil {
+		return "", err
+	}
+	return encodeCursor(historyTrades, q, trade.ExecutedAt, trade.ID), nil
+}
+
+// search builds the shared part of a history query, newest first. It
+// fetches one extra row to know whether another page exists.
+func (r *repository) search(ctx context.Context, q Query, history string) (*gorm.DB, int, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	tx, err := r.filter(r.db.WithContext(ctx), q, history)
+	if err != nil {
+		return nil, 0, err
+	}
+	tx = tx.Order(timeColumns[history] + " DESC").Order("id DESC").Limit(limit + 1)
+	return tx, limit, nil
+}
+
+// filter applies the query's filters and its cursor to db.
+func (r *repository) filter(db *gorm.DB, q Query, history string) (*gorm.DB, error) {
+	timeColumn := timeColumns[history]
+	tx := db.Where("user_id = ?", q.UserID)
+
+	if q.Symbol != "" {
+		tx = tx.Where("symbol = ?", q.Symbol)
+	}
+	if q.Side != "" {
+		tx = tx.Where("side = ?", q.Side)
+	}
+	if q.BotID != "" {
+		tx = tx.Where("bot_id = ?", q.BotID)
+	}
+	if q.Exchange != "" {
+		tx = tx.Where("exchange = ?", q.Exchange)
+	}
+	if q.Testnet != nil {
+		tx = tx.Where("testnet = ?", *q.Testnet)
+	}
+	if !q.From.IsZero() {
+		tx = tx.Where(timeColumn+" >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		tx = tx.Where(timeColumn+" < ?", q.To)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, history, q)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("("+timeColumn+", id) < (?, ?)", c.Time, c.ID)
+	}
+	return tx, nil
+}
@@ -0,0 +1,150 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+// ErrThrottled is returned when an order or cancel would have to wait
+// longer than the policy allows for its slot.
+var ErrThrottled = errors.New("order rate limit exceeded")
+
+const (
+	throttlePlace  = "place"
+	throttleCancel = "cancel"
+)
+
+var throttledRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "orders_throttled_total",
+	Help: "Bot order requests delayed or rejected by their rate policy.",
+}, []string{"kind", "outcome"})
+
+// RatePolicy caps how fast a bot may place and cancel orders. Requests are
+// spread evenly over the minute after an initial burst, so a runaway
+// strategy is slowed down long before it could trip an exchange ban. Zero
+// limits are unlimited.
+type RatePolicy struct {
+	OrdersPerMinute  int
+	CancelsPerMinute int
+	// Burst is how many requests may go out back to back before smoothing
+	// kicks in
+	Burst int
+	// MaxWait is the longest a request is queued for its slot; beyond that
+	// it fails with ErrThrottled
+	MaxWait time.Duration
+}
+
+// Throttle tracks the request schedule of every bot it has wrapped.
+type Throttle struct {
+	mutex sync.Mutex
+	// next is the theoretical arrival time of the next request per bot and
+	// kind, as in the generic cell rate algorithm
+	next map[string]time.Time
+}
+
+func NewThrottle() *Throttle {
+	return &Throttle{next: make(map[string]time.Time)}
+}
+
+// Wrap returns client with the bot's placements and cancels subject to
+// policy. Reads pass straight through.
+func (t *Throttle) Wrap(client exchange.Client, botID string, policy RatePolicy) exchange.Client {
+	return &throttledClient{Client: client, throttle: t, botID: botID, policy: policy}
+}
+
+// Forget drops the bot's schedule once it stops.
+func (t *Throttle) Forget(botID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.next, botID+":"+throttlePlace)
+	delete(t.next, botID+":"+throttleCancel)
+}
+
+// reserve books the next slot for key and returns how long to wait for it.
+func (t *Throttle) reserve(key string, perMinute, burst int, maxWait time.Duration) (time.Duration, bool) {
+	interval := time.Minute / time.Duration(perMinute)
+	if burst < 1 {
+		burst = 1
+	}
+	tolerance := time.Duration(burst-1) * interval
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	next := t.next[key]
+	if next.Before(now) {
+		next = now
+	}
+	wait := next.Sub(now) - tolerance
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+
+	t.next[key] = next.Add(interval)
+	return wait, true
+}
+
+type throttledClient struct {
+	exchange.Client
+	throttle *Throttle
+	botID    string
+	policy   RatePolicy
+}
+
+func (c *throttledClient) PlaceOrder(ctx context.Context, userID string, req exchange.OrderRequest) (*exchange.Order, error) {
+	if err := c.wait(ctx, throttlePlace, c.policy.OrdersPerMinute); err != nil {
+		return nil, err
+	}
+	return c.Client.PlaceOrder(ctx, userID, req)
+}
+
+func (c *throttledClient) CancelOrder(ctx context.Context, userID, orderID string) error {
+	if err := c.wait(ctx, throttleCancel, c.policy.CancelsPerMinute); err != nil {
+		return err
+	}
+	return c.Client.CancelOrder(ctx, userID, orderID)
+}
+
+func (c *throttledClient) wait(ctx context.Context, kind string, perMinute int) error {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	// Never queue past the caller's own deadline
+	budget := c.policy.MaxWait
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < budget {
+			budget = remaining
+		}
+	}
+
+	wait, ok := c.throttle.reserve(c.botID+":"+kind, perMinute, c.policy.Burst, budget)
+	if !ok {
+		throttledRequests.WithLabelValues(kind, "rejected").Inc()
+		return ErrThrottled
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	throttledRequests.WithLabelValues(kind, "delayed").Inc()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,243 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/email"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("invitation expired")
+	ErrInvalidEmail       = errors.New("invalid email address")
+)
+
+// Invitation offers membership with a role to whoever holds the email
+// address. It is deleted once accepted or declined; inviting the address
+// again replaces it.
+type Invitation struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OrgID     string    `json:"org_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_invitations_org_email"`
+	Email     string    `json:"email" gorm:"not null;uniqueIndex:idx_invitations_org_email;index"`
+	Role      Role      `json:"role" gorm:"type:varchar(16);not null"`
+	InvitedBy string    `json:"invited_by" gorm:"type:varchar(36);not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Invitation) TableName() string {
+	return "organization_invitations"
+}
+
+// PendingInvitation is an invitation as its invitee sees it.
+type PendingInvitation struct {
+	Invitation
+	OrgName string `json:"org_name"`
+}
+
+type InvitationRepository interface {
+	// Create replaces any invitation of the address to the organization
+	Create(ctx context.Context, inv *Invitation) error
+	// List returns the organization's invitations that expire after now
+	List(ctx context.Context, orgID string, now time.Time) ([]Invitation, error)
+	// ListForEmail returns the address's invitations that expire after now
+	ListForEmail(ctx context.Context, address string, now time.Time) ([]PendingInvitation, error)
+	// Accept deletes the address's invitation and adds the user as a
+	// member with its role
+	Accept(ctx context.Context, id, address, userID string, now time.Time) (*Member, error)
+	// Delete removes the invitation if it matches the filter
+	Delete(ctx context.Context, id string, filter Invitation) error
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, inv *Invitation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND email = ?", inv.OrgID, inv.Email).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(inv).Error
+	})
+}
+
+func (r *invitationRepository) List(ctx context.Context, orgID string, now time.Time) ([]Invitation, error) {
+	invitations := []Invitation{}
+	err := r.db.WithContext(ctx).
+		Where("org_id = ? AND expires_at > ?", orgID, now).
+		Order("created_at, id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) ListForEmail(ctx context.Context, address string, now time.Time) ([]PendingInvitation, error) {
+	invitations := []PendingInvitation{}
+	err := r.db.WithContext(ctx).Table("organization_invitations AS i").
+		Select("i.*, o.name AS org_name").
+		Joins("JOIN organizations AS o ON o.id = i.org_id").
+		Where("i.email = ? AND i.expires_at > ?", address, now).
+		Order("i.created_at, i.id").
+		Scan(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) Accept(ctx context.Context, id, address, userID string, now time.Time) (*Member, error) {
+	var m *Member
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var inv Invitation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND email = ?", id, address).
+			First(&inv).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !inv.ExpiresAt.After(now) {
+			return ErrInvitationExpired
+		}
+
+		if err := tx.Delete(&inv).Error; err != nil {
+			return err
+		}
+		m = &Member{OrgID: inv.OrgID, UserID: userID, Role: inv.Role}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyMember
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *invitationRepository) Delete(ctx context.Context, id string, filter Invitation) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Where(&filter).Delete(&Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// Invite invites the address to the organization with the role and mails
+// the invitee a link to answer. Admins invite with their own role or
+// below, as when adding members directly.
+func (s *Service) Invite(ctx context.Context, orgID, actorID, address string, role Role) (*Invitation, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != strings.TrimSpace(address) {
+		return nil, ErrInvalidEmail
+	}
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !mayManage(actor.Role, role) {
+		return nil, ErrNotAllowed
+	}
+
+	inv := &Invitation{
+		ID:        uuid.New().String(),
+		OrgID:     orgID,
+		Email:     strings.ToLower(parsed.Address),
+		Role:      role,
+		InvitedBy: actorID,
+		ExpiresAt: time.Now().Add(s.invitationTTL),
+	}
+	if err := s.invitations.Create(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	// Like other transactional mail, sent in the background; the invitee
+	// also finds the invitation in their pending list
+	go func() {
+		if err := s.sendInvitation(context.WithoutCancel(ctx), &actor.Organization, inv); err != nil {
+			log.Printf("Failed to send invitation %s: %v", inv.ID, err)
+		}
+	}()
+	return inv, nil
+}
+
+func (s *Service) sendInvitation(ctx context.Context, o *Organization, inv *Invitation) error {
+	link, err := url.Parse(s.invitationLink)
+	if err != nil {
+		return fmt.Errorf("invalid invitation link url: %w", err)
+	}
+	query := link.Query()
+	query.Set("invitation", inv.ID)
+	link.RawQuery = query.Encode()
+
+	return s.sender.Send(ctx, email.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You are invited to join %s", o.Name),
+		Body: fmt.Sprintf("Hi,\n\nYou are invited to join the organization %q on TradingBotHub as %s. Sign in with this email address and open the link below to accept or decline. The invitation expires in %s.\n\n%s\n\nIf you did not expect this invitation, you can ignore this email.\n",
+			o.Name, inv.Role, s.invitationTTL, link),
+	})
+}
+
+// Invitations lists the organization's pending invitations to its admins.
+func (s *Service) Invitations(ctx context.Context, orgID, actorID string) ([]Invitation, error) {
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Role.AtLeast(RoleAdmin) {
+		return nil, ErrNotAllowed
+	}
+	return s.invitations.List(ctx, orgID, time.Now())
+}
+
+// RevokeInvitation withdraws a pending invitation.
+func (s *Service) RevokeInvitation(ctx context.Context, orgID, actorID, id string) error {
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.Role.AtLeast(RoleAdmin) {
+		return ErrNotAllowed
+	}
+	return s.invitations.Delete(ctx, id, Invitation{OrgID: orgID})
+}
+
+// PendingInvitations lists the invitations of the address. Callers make
+// sure the address is verified to be the user's.
+func (s *Service) PendingInvitations(ctx context.Context, address string) ([]PendingInvitation, error) {
+	return s.invitations.ListForEmail(ctx, strings.ToLower(address), time.Now())
+}
+
+// AcceptInvitation makes the user, who holds the invited address, a member.
+func (s *Service) AcceptInvitation(ctx context.Context, id, userID, address string) (*Member, error) {
+	return s.invitations.Accept(ctx, id, strings.ToLower(address), userID, time.Now())
+}
+
+// DeclineInvitation deletes an invitation of the address.
+func (s *Service) DeclineInvitation(ctx context.Context, id, address string) error {
+	return s.invitations.Delete(ctx, id, Invitation{Email: strings.ToLower(address)})
+}
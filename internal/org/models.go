@@ -0,0 +1,116 @@
+package org
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Role is what a member may do in an organization. Each role includes
+// what the ones below it may do.
+type Role string
+
+const (
+	// RoleOwner may also delete the organization and manage other owners
+	RoleOwner Role = "owner"
+	// RoleAdmin manages members
+	RoleAdmin Role = "admin"
+	// RoleMember works with the organization's bots and strategies
+	RoleMember Role = "member"
+	// RoleViewer only reads them
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// AtLeast tells whether the role includes other.
+func (r Role) AtLeast(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// CanWrite tells whether the role may change the organization's resources.
+func (r Role) CanWrite() bool {
+	return r.AtLeast(RoleMember)
+}
+
+// Organization is a workspace shared by a team. Bots and strategies
+// created in it belong to the organization rather than to one member.
+type Organization struct {
+	ID   string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Name string `json:"name" gorm:"not null"`
+	// DataRegion is where the organization's trade data is stored; it is
+	// the creator's region at creation
+	DataRegion string    `json:"data_region"`
+	CreatedBy  string    `json:"created_by" gorm:"type:varchar(36);not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Member is a user's membership in an organization.
+type Member struct {
+	OrgID     string    `json:"org_id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:varchar(36);index"`
+	Role      Role      `json:"role" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Member) TableName() string {
+	return "organization_members"
+}
+
+// Membership is an organization as one of its members sees it.
+type Membership struct {
+	Organization
+	Role Role `json:"role"`
+}
+
+// Owner is whose bots and strategies a query covers: an organization's,
+// or the personal ones of a user.
+type Owner struct {
+	UserID string
+	OrgID  string
+}
+
+// Personal is the owner of a user's own resources.
+func Personal(userID string) Owner {
+	return Owner{UserID: userID}
+}
+
+// Shared is the owner of an organization's resources.
+func Shared(orgID string) Owner {
+	return Owner{OrgID: orgID}
+}
+
+// Scope restricts a query of a table with user_id and org_id columns to
+// the owner's rows. Resources moved into an organization keep the user
+// who created or moved them, so they are left out of that user's own.
+func (o Owner) Scope(tx *gorm.DB) *gorm.DB {
+	if o.OrgID != "" {
+		return tx.Where("org_id = ?", o.OrgID)
+	}
+	return tx.Where("user_id = ? AND org_id = ''", o.UserID)
+}
+
+// Owns tells whether a resource of the given owner is in o's workspace,
+// matching what Scope selects.
+func (o Owner) Owns(resource Owner) bool {
+	if o.OrgID != "" {
+		return resource.OrgID == o.OrgID
+	}
+	return resource.OrgID == "" && resource.UserID == o.UserID
+}
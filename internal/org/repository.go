@@ -0,0 +1,160 @@
+package org
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Create stores the organization with its creator as the only owner
+	Create(ctx context.Context, o *Organization) error
+	Get(ctx context.Context, id string) (*Organization, error)
+	// Delete removes the organization, its memberships and invitations
+	Delete(ctx context.Context, id string) error
+	// ListForUser returns the organizations the user is a member of, in
+	// the order they joined them
+	ListForUser(ctx context.Context, userID string) ([]Membership, error)
+	// MemberOf returns the organization and the user's role in it, or
+	// ErrNotMember
+	MemberOf(ctx context.Context, orgID, userID string) (*Membership, error)
+	Members(ctx context.Context, orgID string) ([]Member, error)
+	AddMember(ctx context.Context, m *Member) error
+	// SetRole changes a member's role, refusing to demote the last owner
+	SetRole(ctx context.Context, orgID, userID string, role Role) error
+	// RemoveMember refuses to remove the last owner. Both return
+	// ErrMemberNotFound for users who are not members
+	RemoveMember(ctx context.Context, orgID, userID string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, o *Organization) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(o).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Member{OrgID: o.ID, UserID: o.CreatedBy, Role: RoleOwner}).Error
+	})
+}
+
+func (r *repository) Get(ctx context.Context, id string) (*Organization, error) {
+	var o Organization
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&o).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *repository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ?", id).Delete(&Member{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", id).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&Organization{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrgNotFound
+		}
+		return nil
+	})
+}
+
+// memberships selects organizations with the member's role.
+func (r *repository) memberships(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table("organization_members AS m").
+		Select("o.*, m.role").
+		Joins("JOIN organizations AS o ON o.id = m.org_id")
+}
+
+func (r *repository) ListForUser(ctx context.Context, userID string) ([]Membership, error) {
+	memberships := []Membership{}
+	err := r.memberships(ctx).Where("m.user_id = ?", userID).Order("m.created_at, o.id").Scan(&memberships).Error
+	return memberships, err
+}
+
+func (r *repository) MemberOf(ctx context.Context, orgID, userID string) (*Membership, error) {
+	var m Membership
+	result := r.memberships(ctx).Where("m.org_id = ? AND m.user_id = ?", orgID, userID).Limit(1).Scan(&m)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotMember
+	}
+	return &m, nil
+}
+
+func (r *repository) Members(ctx context.Context, orgID string) ([]Member, error) {
+	var members []Member
+	err := r.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at, user_id").Find(&members).Error
+	return members, err
+}
+
+func (r *repository) AddMember(ctx context.Context, m *Member) error {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyMember
+	}
+	return nil
+}
+
+func (r *repository) SetRole(ctx context.Context, orgID, userID string, role Role) error {
+	return r.changeMember(ctx, orgID, userID, role != RoleOwner, func(tx *gorm.DB) error {
+		return tx.Model(&Member{}).Where("org_id = ? AND user_id = ?", orgID, userID).Update("role", role).Error
+	})
+}
+
+func (r *repository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	return r.changeMember(ctx, orgID, userID, true, func(tx *gorm.DB) error {
+		return tx.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&Member{}).Error
+	})
+}
+
+// changeMember applies change to a membership. When the change takes away
+// an owner role, the organization's owners are locked first so concurrent
+// changes cannot leave it without one.
+func (r *repository) changeMember(ctx context.Context, orgID, userID string, demotes bool, change func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var owners []Member
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("org_id = ? AND role = ?", orgID, RoleOwner).
+			Find(&owners).Error
+		if err != nil {
+			return err
+		}
+
+		var m Member
+		err = tx.Where("org_id = ? AND user_id = ?", orgID, userID).First(&m).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if demotes && m.Role == RoleOwner && len(owners) == 1 {
+			return ErrLastOwner
+		}
+		return change(tx)
+	})
+}
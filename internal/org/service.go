@@ -0,0 +1,207 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/email"
+)
+
+const maxNameLength = 100
+
+var (
+	ErrOrgNotFound    = errors.New("organization not found")
+	ErrNotMember      = errors.New("not a member of the organization")
+	ErrMemberNotFound = errors.New("member not found")
+	ErrAlreadyMember  = errors.New("user is already a member of the organization")
+	ErrNotAllowed     = errors.New("your role in the organization does not allow this")
+	ErrLastOwner      = errors.New("an organization needs at least one owner")
+	ErrInvalidRole    = errors.New("invalid organization role")
+	ErrInvalidName    = errors.New("invalid organization name")
+)
+
+// Service manages organizations and their members. Every change is made
+// on behalf of an acting member and checked against their role: admins
+// manage members, and only owners grant or take away the owner role or
+// delete the organization. Anyone may leave, except the last owner.
+type Service struct {
+	repo        Repository
+	invitations InvitationRepository
+	sender      email.Sender
+	// invitationTTL is how long invitations can be accepted
+	invitationTTL time.Duration
+	// invitationLink is the page the emailed invitation opens
+	invitationLink string
+}
+
+func NewService(repo Repository, invitations InvitationRepository, sender email.Sender, invitationTTL time.Duration, invitationLink string) *Service {
+	return &Service{
+		repo:           repo,
+		invitations:    invitations,
+		sender:         sender,
+		invitationTTL:  invitationTTL,
+		invitationLink: invitationLink,
+	}
+}
+
+// Create starts an organization with the user as its owner. Its trade
+// data is stored in region.
+func (s *Service) Create(ctx context.Context, userID, name, region string) (*Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return nil, fmt.Errorf("%w: must be 1 to %d characters", ErrInvalidName, maxNameLength)
+	}
+
+	o := &Organization{
+		ID:         uuid.New().String(),
+		Name:       name,
+		DataRegion: region,
+		CreatedBy:  userID,
+	}
+	if err := s.repo.Create(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// List returns the user's organizations with their role in each.
+func (s *Service) List(ctx context.Context, userID string) ([]Membership, error) {
+	return s.repo.ListForUser(ctx, userID)
+}
+
+// MemberOf returns the organization as the user sees it, or ErrNotMember;
+// organizations that do not exist look the same to non-members.
+func (s *Service) MemberOf(ctx context.Context, orgID, userID string) (*Membership, error) {
+	return s.repo.MemberOf(ctx, orgID, userID)
+}
+
+// Members lists the organization's members to one of them.
+func (s *Service) Members(ctx context.Context, orgID, actorID string) ([]Member, error) {
+	if _, err := s.repo.MemberOf(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+	return s.repo.Members(ctx, orgID)
+}
+
+// AddMember adds the user with the role.
+func (s *Service) AddMember(ctx context.Context, orgID, actorID, userID string, role Role) (*Member, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !mayManage(actor.Role, role) {
+		return nil, ErrNotAllowed
+	}
+
+	m := &Member{OrgID: orgID, UserID: userID, Role: role}
+	if err := s.repo.AddMember(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetRole changes a member's role.
+func (s *Service) SetRole(ctx context.Context, orgID, actorID, userID string, role Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	current, err := s.member(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	// Touching an owner needs an owner, whichever way the role changes
+	if !mayManage(actor.Role, role) || !mayManage(actor.Role, current.Role) {
+		return ErrNotAllowed
+	}
+	return s.repo.SetRole(ctx, orgID, userID, role)
+}
+
+// Provision makes the user a member with the highest of roles, which the
+// organization's identity provider granted them when they signed in. A
+// member keeps a higher role they already have; roles are only ever
+// raised this way, and never to owner. Unknown roles are ignored.
+func (s *Service) Provision(ctx context.Context, orgID, userID string, roles []string) error {
+	var role Role
+	for _, r := range roles {
+		if r := Role(r); r.Valid() && r != RoleOwner && r.AtLeast(role) {
+			role = r
+		}
+	}
+	if role == "" {
+		return nil
+	}
+
+	current, err := s.repo.MemberOf(ctx, orgID, userID)
+	switch {
+	case errors.Is(err, ErrNotMember):
+		err = s.repo.AddMember(ctx, &Member{OrgID: orgID, UserID: userID, Role: role})
+		if errors.Is(err, ErrAlreadyMember) {
+			// A concurrent sign-in added them
+			return nil
+		}
+		return err
+	case err != nil:
+		return err
+	case current.Role.AtLeast(role):
+		return nil
+	}
+	return s.repo.SetRole(ctx, orgID, userID, role)
+}
+
+// RemoveMember removes the user, or lets the actor leave when it is them.
+func (s *Service) RemoveMember(ctx context.Context, orgID, actorID, userID string) error {
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorID != userID {
+		current, err := s.member(ctx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if !mayManage(actor.Role, current.Role) {
+			return ErrNotAllowed
+		}
+	}
+	return s.repo.RemoveMember(ctx, orgID, userID)
+}
+
+// Delete removes the organization; only owners may. Callers move or
+// delete its resources first.
+func (s *Service) Delete(ctx context.Context, orgID, actorID string) error {
+	actor, err := s.repo.MemberOf(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != RoleOwner {
+		return ErrNotAllowed
+	}
+	return s.repo.Delete(ctx, orgID)
+}
+
+// member looks up the member an actor acts on.
+func (s *Service) member(ctx context.Context, orgID, userID string) (*Membership, error) {
+	m, err := s.repo.MemberOf(ctx, orgID, userID)
+	if errors.Is(err, ErrNotMember) {
+		return nil, ErrMemberNotFound
+	}
+	return m, err
+}
+
+// mayManage tells whether an actor may manage members of the role:
+// admins manage admins and below, owners everyone.
+func mayManage(actor, role Role) bool {
+	return actor.AtLeast(RoleAdmin) && actor.AtLeast(role)
+}
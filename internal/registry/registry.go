@@ -0,0 +1,311 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/pkg/buildinfo"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	HealthHealthy  = "healthy"
+	HealthDraining = "draining"
+	// HealthWarming means the instance is still running its warm-up tasks
+	HealthWarming = "warming"
+	// HealthStale means no heartbeat arrived within the TTL
+	HealthStale = "stale"
+	// HealthUnknown is used for dependencies that do not report
+	// themselves, such as databases
+	HealthUnknown = "unknown"
+)
+
+// Instance is what a running service reports about itself.
+type Instance struct {
+	Service  string         `json:"service"`
+	ID       string         `json:"id"`
+	Build    buildinfo.Info `json:"build"`
+	Region   string         `json:"region"`
+	Address  string         `json:"address,omitempty"`
+	Status   string         `json:"status"`
+	Started  time.Time      `json:"started_at"`
+	LastSeen time.Time      `json:"last_seen"`
+	// Dependencies are service names, e.g. "auth-service" or "postgres"
+	Dependencies []string `json:"dependencies"`
+}
+
+// InstanceID identifies this process among instances of the same service.
+func InstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Announcer publishes the instance's heartbeat until its context ends.
+type Announcer struct {
+	conn     *nats.Conn
+	instance Instance
+	interval time.Duration
+	status   func() string
+}
+
+// NewAnnouncer takes an optional status callback, e.g. to report
+// draining; nil always reports healthy.
+func NewAnnouncer(conn *nats.Conn, instance Instance, interval time.Duration, status func() string) *Announcer {
+	if instance.ID == "" {
+		instance.ID = InstanceID()
+	}
+	if instance.Build == (buildinfo.Info{}) {
+		instance.Build = buildinfo.Get()
+	}
+	if instance.Started.IsZero() {
+		instance.Started = time.Now()
+	}
+	if status == nil {
+		status = func() string { return HealthHealthy }
+	}
+	return &Announcer{conn: conn, instance: instance, interval: interval, status: status}
+}
+
+func (a *Announcer) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	a.announce()
+	for {
+		select {
+		case <-ctx.Done():
+			a.publish(&eventspb.ServiceLeft{Service: a.instance.Service, InstanceId: a.instance.ID})
+			a.conn.Flush()
+			return
+		case <-ticker.C:
+			a.announce()
+		}
+	}
+}
+
+func (a *Announcer) announce() {
+	instance := a.instance
+	a.publish(&eventspb.ServiceAnnounced{
+		Service:    instance.Service,
+		InstanceId: instance.ID,
+		Build: &eventspb.BuildInfo{
+			Version:   instance.Build.Version,
+			Commit:    instance.Build.Commit,
+			BuildTime: instance.Build.BuildTime,
+			GoVersion: instance.Build.GoVersion,
+		},
+		Region:       instance.Region,
+		Address:      instance.Address,
+		Status:       a.status(),
+		StartedAt:    timestamppb.New(instance.Started),
+		Dependencies: instance.Dependencies,
+	})
+}
+
+func (a *Announcer) publish(event proto.Message) {
+	if err := events.Publish(a.conn, a.instance.Service, event); err != nil {
+		log.Printf("Failed to publish registry announcement: %v", err)
+	}
+}
+
+// Registry collects announcements into the current topology. An instance
+// that misses heartbeats for ttl is reported stale and forgotten after
+// several more.
+type Registry struct {
+	mutex     sync.RWMutex
+	instances map[string]Instance // by service + instance ID
+	ttl       time.Duration
+	subs      []*nats.Subscription
+}
+
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{instances: make(map[string]Instance), ttl: ttl}
+}
+
+// Subscribe starts listening for announcements.
+func (r *Registry) Subscribe(conn *nats.Conn) error {
+	announce, err := events.Subscribe(conn, func() *eventspb.ServiceAnnounced { return &eventspb.ServiceAnnounced{} },
+		func(_ *eventspb.Envelope, event *eventspb.ServiceAnnounced) {
+			r.Observe(instanceFromEvent(event))
+		})
+	if err != nil {
+		return err
+	}
+	leave, err := events.Subscribe(conn, func() *eventspb.ServiceLeft { return &eventspb.ServiceLeft{} },
+		func(_ *eventspb.Envelope, event *eventspb.ServiceLeft) {
+			r.Forget(Instance{Service: event.Service, ID: event.InstanceId})
+		})
+	if err != nil {
+		announce.Unsubscribe()
+		return err
+	}
+	r.subs = []*nats.Subscription{announce, leave}
+	return nil
+}
+
+func (r *Registry) Close() {
+	for _, sub := range r.subs {
+		sub.Unsubscribe()
+	}
+}
+
+func instanceFromEvent(event *eventspb.ServiceAnnounced) Instance {
+	instance := Instance{
+		Service:      event.Service,
+		ID:           event.InstanceId,
+		Region:       event.Region,
+		Address:      event.Address,
+		Status:       event.Status,
+		Started:      event.StartedAt.AsTime(),
+		Dependencies: event.Dependencies,
+	}
+	if build := event.Build; build != nil {
+		instance.Build = buildinfo.Info{
+			Version:   build.Version,
+			Commit:    build.Commit,
+			BuildTime: build.BuildTime,
+			GoVersion: build.GoVersion,
+		}
+	}
+	return instance
+}
+
+// Observe records a heartbeat. The receive time is used as last seen so
+// clock skew between hosts does not mark instances stale.
+func (r *Registry) Observe(instance Instance) {
+	instance.LastSeen = time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.instances[instance.Service+"/"+instance.ID] = instance
+}
+
+func (r *Registry) Forget(instance Instance) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.instances, instance.Service+"/"+instance.ID)
+}
+
+// Node is a service and its live instances. Dependencies that never
+// announce themselves appear as external nodes without instances.
+type Node struct {
+	Service   string     `json:"service"`
+	Health    string     `json:"health"`
+	External  bool       `json:"external,omitempty"`
+	Instances []Instance `json:"instances,omitempty"`
+}
+
+// Edge points from a service to something it depends on.
+type Edge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Health string `json:"health"`
+}
+
+type Topology struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Topology returns the services, their instances and the dependency graph.
+// A service is healthy if any of its instances is.
+func (r *Registry) Topology() Topology {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	nodes := make(map[string]*Node)
+	edges := make(map[Edge]bool)
+
+	for key, instance := range r.instances {
+		age := now.Sub(instance.LastSeen)
+		if age > 5*r.ttl {
+			delete(r.instances, key)
+			continue
+		}
+		if age > r.ttl {
+			instance.Status = HealthStale
+		}
+
+		node := nodes[instance.Service]
+		if node == nil {
+			node = &Node{Service: instance.Service, Health: instance.Status}
+			nodes[instance.Service] = node
+		}
+		node.Instances = append(node.Instances, instance)
+		if instance.Status == HealthHealthy {
+			node.Health = HealthHealthy
+		}
+
+		for _, dep := range instance.Dependencies {
+			edges[Edge{From: instance.Service, To: dep}] = true
+		}
+	}
+
+	for edge := range edges {
+		if nodes[edge.To] == nil {
+			nodes[edge.To] = &Node{Service: edge.To, Health: HealthUnknown, External: true}
+		}
+	}
+
+	topology := Topology{Nodes: []Node{}, Edges: []Edge{}}
+	for _, node := range nodes {
+		sort.Slice(node.Instances, func(i, j int) bool { return node.Instances[i].ID < node.Instances[j].ID })
+		topology.Nodes = append(topology.Nodes, *node)
+	}
+	for edge := range edges {
+		edge.Health = nodes[edge.To].Health
+		topology.Edges = append(topology.Edges, edge)
+	}
+
+	sort.Slice(topology.Nodes, func(i, j int) bool { return topology.Nodes[i].Service < topology.Nodes[j].Service })
+	sort.Slice(topology.Edges, func(i, j int) bool {
+		if topology.Edges[i].From != topology.Edges[j].From {
+			return topology.Edges[i].From < topology.Edges[j].From
+		}
+		return topology.Edges[i].To < topology.Edges[j].To
+	})
+	return topology
+}
+
+// DOT renders the topology in Graphviz format.
+func (t Topology) DOT() string {
+	colors := map[string]string{
+		HealthHealthy:  "green",
+		HealthDraining: "orange",
+		HealthWarming:  "yellow",
+		HealthStale:    "red",
+		HealthUnknown:  "gray",
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph topology {\n")
+	for _, node := range t.Nodes {
+		shape := "box"
+		if node.External {
+			shape = "cylinder"
+		}
+		fmt.Fprintf(&b, "  %q [shape=%s, color=%s, label=%q];\n",
+			node.Service, shape, colors[node.Health], fmt.Sprintf("%s (%d)", node.Service, len(node.Instances)))
+	}
+	for _, edge := range t.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [color=%s];\n", edge.From, edge.To, colors[edge.Health])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package residency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/orders"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownRegion = errors.New("unknown data region")
+	// ErrResidencyViolation is returned when data would leave the region
+	// the user chose to keep it in
+	ErrResidencyViolation = errors.New("data must stay in the user's data region")
+)
+
+type region struct {
+	db     *gorm.DB
+	orders orders.Repository
+	// replica serves reads; it is db when the region has no replica
+	replica *gorm.DB
+	reads   orders.Repository
+}
+
+// Router sends trade data reads and writes to the database of the user's
+// data region, reads to its replica where one is configured. Users without
+// a region use the default region.
+type Router struct {
+	primary       *gorm.DB
+	defaultRegion string
+	regions       map[string]*region
+}
+
+// NewRouter connects to every configured region. Regions without a
+// database URL share the primary database.
+func NewRouter(cfg config.DataResidencyConfig, primary *gorm.DB) (*Router, error) {
+	r := &Router{
+		primary:       primary,
+		defaultRegion: cfg.DefaultRegion,
+		regions:       make(map[string]*region),
+	}
+
+	for name, regionCfg := range cfg.Regions {
+		db := primary
+		if regionCfg.DatabaseURL != "" {
+			var err error
+			if db, err = database.Connect(regionCfg.DatabaseURL); err != nil {
+				r.Close()
+				return nil, fmt.Errorf("region %s: %w", name, err)
+			}
+		}
+		reg := newRegion(db, db)
+		r.regions[name] = reg
+
+		if regionCfg.ReplicaURL != "" {
+			replica, err := database.Connect(regionCfg.ReplicaURL)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("region %s replica: %w", name, err)
+			}
+			reg.replica, reg.reads = replica, orders.NewRepository(replica)
+		}
+	}
+
+	if _, ok := r.regions[r.defaultRegion]; !ok {
+		r.regions[r.defaultRegion] = newRegion(primary, primary)
+	}
+	return r, nil
+}
+
+func newRegion(db, replica *gorm.DB) *region {
+	reg := &region{db: db, orders: orders.NewRepository(db), replica: replica}
+	reg.reads = reg.orders
+	if replica != db {
+		reg.reads = orders.NewRepository(replica)
+	}
+	return reg
+}
+
+// Resolve maps a user's stored region to a configured one.
+func (r *Router) Resolve(userRegion string) (string, error) {
+	if userRegion == "" {
+		return r.defaultRegion, nil
+	}
+	if _, ok := r.regions[userRegion]; !ok {
+		return "", ErrUnknownRegion
+	}
+	return userRegion, nil
+}
+
+func (r *Router) Orders(userRegion string) (orders.Repository, error) {
+	name, err := r.Resolve(userRegion)
+	if err != nil {
+		return nil, err
+	}
+	return r.regions[name].orders, nil
+}
+
+func (r *Router) DB(userRegion string) (*gorm.DB, error) {
+	name, err := r.Resolve(userRegion)
+	if err != nil {
+		return nil, err
+	}
+	return r.regions[name].db, nil
+}
+
+// ReadOrders is Orders for reads, served by the region's replica. Replicas
+// lag, so checks that must see the latest writes use Orders.
+func (r *Router) ReadOrders(userRegion string) (orders.Repository, error) {
+	name, err := r.Resolve(userRegion)
+	if err != nil {
+		return nil, err
+	}
+	return r.regions[name].reads, nil
+}
+
+// ReadDB is DB for reads, served by the region's replica.
+func (r *Router) ReadDB(userRegion string) (*gorm.DB, error) {
+	name, err := r.Resolve(userRegion)
+	if err != nil {
+		return nil, err
+	}
+	return r.regions[name].replica, nil
+}
+
+// CheckExport verifies that an export of the user's data is generated and
+// stored in the user's data region.
+func (r *Router) CheckExport(userRegion, exportRegion string) error {
+	name, err := r.Resolve(userRegion)
+	if err != nil {
+		return err
+	}
+	if exportRegion != name {
+		return ErrResidencyViolation
+	}
+	return nil
+}
+
+// Regions lists the configured regions.
+func (r *Router) Regions() []string {
+	names := make([]string, 0, len(r.regions))
+	for name := range r.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DBs lists each regional database once, for jobs that cover every region.
+func (r *Router) DBs() []*gorm.DB {
+	seen := make(map[*gorm.DB]bool)
+	var dbs []*gorm.DB
+	for _, name := range r.Regions() {
+		if db := r.regions[name].db; !seen[db] {
+			seen[db] = true
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs
+}
+
+func (r *Router) DefaultRegion() string {
+	return r.defaultRegion
+}
+
+// Close closes the regional databases and replicas, leaving the primary
+// one open.
+func (r *Router) Close() {
+	closed := map[*gorm.DB]bool{r.primary: true}
+	for _, reg := range r.regions {
+		for _, db := range []*gorm.DB{reg.db, reg.replica} {
+			if db == nil || closed[db] {
+				continue
+			}
+			closed[db] = true
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		}
+	}
+}
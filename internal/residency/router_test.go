@@ -0,0 +1,39 @@
+package residency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/config"
+	"gorm.io/gorm"
+)
+
+func TestRouter_ReadsWithoutReplicaUseTheDatabase(t *testing.T) {
+	primary := &gorm.DB{}
+	router, err := NewRouter(config.DataResidencyConfig{
+		DefaultRegion: "eu",
+		Regions:       map[string]config.DataRegionConfig{"eu": {}},
+	}, primary)
+	require.NoError(t, err)
+
+	db, err := router.ReadDB("")
+	require.NoError(t, err)
+	assert.Same(t, primary, db)
+
+	writes, err := router.Orders("eu")
+	require.NoError(t, err)
+	reads, err := router.ReadOrders("eu")
+	require.NoError(t, err)
+	assert.Same(t, writes, reads)
+
+	_, err = router.ReadOrders("us")
+	assert.ErrorIs(t, err, ErrUnknownRegion)
+}
+
+func TestRegion_ReadsGoToReplica(t *testing.T) {
+	db, replica := &gorm.DB{}, &gorm.DB{}
+	reg := newRegion(db, replica)
+	assert.Same(t, replica, reg.replica)
+	assert.NotSame(t, reg.orders, reg.reads)
+}
@@ -0,0 +1,41 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+// Purger permanently deletes trashed bots and strategies once they have
+// been in the trash longer than the retention period.
+type Purger struct {
+	bots       bot.Repository
+	strategies strategy.Repository
+	trashTTL   time.Duration
+}
+
+func NewPurger(bots bot.Repository, strategies strategy.Repository, trashTTL time.Duration) *Purger {
+	return &Purger{bots: bots, strategies: strategies, trashTTL: trashTTL}
+}
+
+// Run matches scheduler.JobFunc.
+func (p *Purger) Run(ctx context.Context) error {
+	cutoff := time.Now().Add(-p.trashTTL)
+
+	bots, err := p.bots.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to purge bots: %w", err)
+	}
+
+	strategies, err := p.strategies.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to purge strategies: %w", err)
+	}
+
+	log.Printf("Purged %d bots and %d strategies deleted before %s", bots, strategies, cutoff.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys the gateway forwards the end user's address and browser
+// in. The peer of a backend call is always the gateway itself.
+const (
+	clientIPKey        = "x-client-ip"
+	clientUserAgentKey = "x-client-user-agent"
+)
+
+// WithClient returns a context whose calls tell the backend who the end
+// user is, for its audit records.
+func WithClient(ctx context.Context, ip, userAgent string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		clientIPKey, printable(ip),
+		clientUserAgentKey, printable(userAgent),
+	)
+}
+
+// Client returns the end user's address and user agent forwarded with an
+// incoming call, or empty strings if the caller did not send them.
+func Client(ctx context.Context) (ip, userAgent string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ""
+	}
+	return first(md.Get(clientIPKey)), first(md.Get(clientUserAgentKey))
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// printable drops the characters gRPC refuses in metadata values, so an
+// odd user agent cannot fail the call it rides on.
+func printable(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, value)
+}
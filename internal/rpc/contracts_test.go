@@ -0,0 +1,257 @@
+package rpc
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	backtestpb "github.com/tradingbothub/platform/api/proto/backtest"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var update = flag.Bool("update", false, "add new methods, fields and enum values to the contracts lock")
+
+// contractsLock keeps every method of the auth and backtest services, with
+// the fields of all messages it exchanges and the values of the enums
+// those use. A gateway built from an older proto keeps working against a
+// new service build only while those stay as they are.
+const contractsLock = "contracts.lock.json"
+
+var contractFiles = []protoreflect.FileDescriptor{
+	authpb.File_api_proto_auth_auth_proto,
+	backtestpb.File_api_proto_backtest_backtest_proto,
+}
+
+type contractMethod struct {
+	Request         string `json:"request"`
+	Response        string `json:"response"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+}
+
+type contractField struct {
+	Number   int32  `json:"number"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Repeated bool   `json:"repeated,omitempty"`
+}
+
+type contractValue struct {
+	Number int32  `json:"number"`
+	Name   string `json:"name"`
+}
+
+// contracts holds methods by full gRPC method name, e.g.
+// "/auth.v1.AuthService/Login", and messages and enums by full name.
+type contracts struct {
+	Methods  map[string]contractMethod  `json:"methods"`
+	Messages map[string][]contractField `json:"messages"`
+	Enums    map[string][]contractValue `json:"enums"`
+}
+
+// TestContracts fails when a service proto removes a locked method, changes
+// its message types or streaming, removes or changes a locked field, or
+// removes or renumbers a locked enum value. A break that is intended, with
+// every gateway deployed upgraded first, is accepted by editing the lock.
+// Additions fail too until they are locked with -update.
+func TestContracts(t *testing.T) {
+	var locked contracts
+	data, err := os.ReadFile(contractsLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &locked); err != nil {
+		t.Fatalf("failed to read %s: %v", contractsLock, err)
+	}
+
+	current := currentContracts()
+	for _, problem := range contractBreaks(locked, current) {
+		t.Error(problem)
+	}
+	if t.Failed() {
+		return
+	}
+
+	if *update {
+		data, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(contractsLock, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !reflect.DeepEqual(locked, current) {
+		t.Errorf("the services added methods, fields or enum values; lock them with go test ./internal/rpc -run TestContracts -update")
+	}
+}
+
+func currentContracts() contracts {
+	current := contracts{
+		Methods:  map[string]contractMethod{},
+		Messages: map[string][]contractField{},
+		Enums:    map[string][]contractValue{},
+	}
+	for _, file := range contractFiles {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			sd := services.Get(i)
+			methods := sd.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				md := methods.Get(j)
+				current.Methods[fmt.Sprintf("/%s/%s", sd.FullName(), md.Name())] = contractMethod{
+					Request:         string(md.Input().FullName()),
+					Response:        string(md.Output().FullName()),
+					ClientStreaming: md.IsStreamingClient(),
+					ServerStreaming: md.IsStreamingServer(),
+				}
+				current.addMessage(md.Input())
+				current.addMessage(md.Output())
+			}
+		}
+	}
+	return current
+}
+
+// addMessage records the message and every message and enum its fields
+// reach. Well-known types never change and are left out.
+func (c *contracts) addMessage(md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := c.Messages[name]; ok || strings.HasPrefix(name, "google.protobuf.") {
+		return
+	}
+
+	var out []contractField
+	list := md.Fields()
+	for i := 0; i < list.Len(); i++ {
+		fd := list.Get(i)
+		typ := contractType(fd)
+		if fd.IsMap() {
+			typ = fmt.Sprintf("map<%s,%s>", contractType(fd.MapKey()), contractType(fd.MapValue()))
+		}
+		out = append(out, contractField{
+			Number:   int32(fd.Number()),
+			Name:     string(fd.Name()),
+			Type:     typ,
+			Repeated: fd.Cardinality() == protoreflect.Repeated && !fd.IsMap(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+	// Recorded before recursing so self-referencing messages terminate
+	c.Messages[name] = out
+
+	for i := 0; i < list.Len(); i++ {
+		fd := list.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			c.addMessage(fd.Message())
+		}
+		if fd.Enum() != nil {
+			c.addEnum(fd.Enum())
+		}
+	}
+}
+
+func (c *contracts) addEnum(ed protoreflect.EnumDescriptor) {
+	name := string(ed.FullName())
+	if _, ok := c.Enums[name]; ok || strings.HasPrefix(name, "google.protobuf.") {
+		return
+	}
+	values := ed.Values()
+	out := make([]contractValue, values.Len())
+	for i := range out {
+		vd := values.Get(i)
+		out[i] = contractValue{Number: int32(vd.Number()), Name: string(vd.Name())}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+	c.Enums[name] = out
+}
+
+func contractType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
+
+// contractBreaks lists the locked methods, fields and enum values that are
+// missing or changed.
+func contractBreaks(locked, current contracts) []string {
+	var problems []string
+	for _, name := range sortedKeys(locked.Methods) {
+		now, ok := current.Methods[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s was removed", name))
+		case now != locked.Methods[name]:
+			problems = append(problems, fmt.Sprintf("%s changed its message types or streaming", name))
+		}
+	}
+
+	for _, name := range sortedKeys(locked.Messages) {
+		now, ok := current.Messages[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is no longer used by any method", name))
+			continue
+		}
+		byNumber := make(map[int32]contractField, len(now))
+		for _, f := range now {
+			byNumber[f.Number] = f
+		}
+		for _, f := range locked.Messages[name] {
+			got, ok := byNumber[f.Number]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s: field %d (%s) was removed", name, f.Number, f.Name))
+			case got != f:
+				problems = append(problems, fmt.Sprintf("%s: field %d (%s) changed", name, f.Number, f.Name))
+			}
+		}
+	}
+
+	// Old clients send and read enums by number, so a value keeps its
+	// number and name
+	for _, name := range sortedKeys(locked.Enums) {
+		now, ok := current.Enums[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is no longer used by any method", name))
+			continue
+		}
+		byNumber := make(map[int32]contractValue, len(now))
+		for _, v := range now {
+			byNumber[v.Number] = v
+		}
+		for _, v := range locked.Enums[name] {
+			got, ok := byNumber[v.Number]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s: value %d (%s) was removed", name, v.Number, v.Name))
+			case got != v:
+				problems = append(problems, fmt.Sprintf("%s: value %d (%s) was renamed to %s", name, v.Number, v.Name, got.Name))
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
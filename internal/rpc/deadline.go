@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type deadlineReporterKey struct{}
+
+// WithDeadlineReporter returns a context whose unary calls report to
+// report when the backend ran out of time, with the backend's service
+// name, e.g. "auth.v1.AuthService".
+func WithDeadlineReporter(ctx context.Context, report func(service string)) context.Context {
+	return context.WithValue(ctx, deadlineReporterKey{}, report)
+}
+
+// DeadlineInterceptor hands unary calls the caller's deadline minus
+// margin. The backend gives up first, leaving the caller the margin to
+// answer that the backend timed out instead of timing out itself. Calls
+// whose budget is already used up fail without being sent.
+func DeadlineInterceptor(margin time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		var err error
+		if budget := time.Until(deadline) - margin; budget <= 0 {
+			err = status.Error(codes.DeadlineExceeded, "no time left for the call")
+		} else {
+			callCtx, cancel := context.WithTimeout(ctx, budget)
+			err = invoker(callCtx, method, req, reply, cc, opts...)
+			cancel()
+		}
+
+		if status.Code(err) == codes.DeadlineExceeded {
+			if report, ok := ctx.Value(deadlineReporterKey{}).(func(string)); ok {
+				report(serviceName(method))
+			}
+		}
+		return err
+	}
+}
+
+// serviceName extracts the service from a full method name such as
+// "/auth.v1.AuthService/Login".
+func serviceName(method string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	return service
+}
@@ -0,0 +1,27 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/tradingbothub/platform/internal/warmup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ServeHealth registers the standard gRPC health service on s. It reports
+// NOT_SERVING until warmer is ready, so load balancers and clients probing
+// health keep traffic away from a cold instance. Stopping ctx leaves the
+// status as it is; call Shutdown on the returned server when draining.
+func ServeHealth(ctx context.Context, s *grpc.Server, warmer *warmup.Warmer) *health.Server {
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s, server)
+
+	go func() {
+		if warmer.Wait(ctx) == nil {
+			server.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	authpb "github.com/tradingbothub/platform/api/proto/auth"
+	"github.com/tradingbothub/platform/pkg/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// policies holds one retry policy per backend service, so every
+// connection to a service, such as its canary, draws on the same budget.
+var policies sync.Map
+
+func retryPolicy(service string, cfg retry.Config) *retry.Policy {
+	if policy, ok := policies.Load(service); ok {
+		return policy.(*retry.Policy)
+	}
+	policy, _ := policies.LoadOrStore(service, retry.New("grpc_"+service, cfg, retry.WithClassifier(Retryable)))
+	return policy.(*retry.Policy)
+}
+
+// idempotentMethods are the unary calls that may be repeated. Only reads
+// are listed: an Unavailable error does not prove the backend never saw
+// the call, and a repeated write such as a registration or a password
+// change would act twice.
+var idempotentMethods = map[string]bool{
+	authpb.AuthService_ValidateToken_FullMethodName:      true,
+	authpb.AuthService_IntrospectToken_FullMethodName:    true,
+	authpb.AuthService_GetJWKS_FullMethodName:            true,
+	authpb.AuthService_GetVersion_FullMethodName:         true,
+	authpb.AuthService_GetIPAllowlist_FullMethodName:     true,
+	authpb.AuthService_CheckAvailability_FullMethodName:  true,
+	authpb.AuthService_ListSessions_FullMethodName:       true,
+	authpb.AuthService_ListUserSessions_FullMethodName:   true,
+	authpb.AuthService_ListSecurityEvents_FullMethodName: true,
+	authpb.AuthService_ListAuditEvents_FullMethodName:    true,
+	authpb.AuthService_ListLogins_FullMethodName:         true,
+	authpb.AuthService_ListAuthMethods_FullMethodName:    true,
+	authpb.AuthService_ListSSOConnections_FullMethodName: true,
+	authpb.AuthService_ListUsers_FullMethodName:          true,
+}
+
+// Retryable reports whether a call failed because the backend could not
+// be reached. Other failures come from the backend itself, which may have
+// acted on the call, so they are not retried.
+func Retryable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// RetryInterceptor repeats idempotent unary calls under the policy; others
+// are made once. It must run before DeadlineInterceptor so the attempts
+// share the caller's deadline.
+func RetryInterceptor(policy *retry.Policy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !idempotentMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return policy.Do(ctx, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
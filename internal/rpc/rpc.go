@@ -0,0 +1,60 @@
+// Package rpc holds the message size limits, compression and deadline
+// handling shared by the internal gRPC servers and their clients.
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	// Registers the gzip compressor next to zstd
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// ServerOptions limits message sizes. Servers answer with the compression
+// the client used, so they need no compression setting of their own.
+func ServerOptions(cfg config.GRPCConfig) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(cfg.MaxSendMsgSize),
+	}
+}
+
+// DialOptions limits message sizes, compresses calls to the service when a
+// compressor is configured for it, retries idempotent unary calls the
+// service was unavailable for and leaves them a margin of the caller's
+// deadline.
+func DialOptions(cfg config.GRPCConfig, service string) []grpc.DialOption {
+	callOptions := []grpc.CallOption{
+		grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.MaxCallSendMsgSize(cfg.MaxSendMsgSize),
+	}
+	if compressor := cfg.Compression[service]; compressor != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(compressor))
+	}
+	options := []grpc.DialOption{grpc.WithDefaultCallOptions(callOptions...)}
+	if cfg.Retry.MaxAttempts > 1 {
+		options = append(options, grpc.WithChainUnaryInterceptor(RetryInterceptor(retryPolicy(service, cfg.Retry))))
+	}
+	if cfg.DeadlineMargin > 0 {
+		options = append(options, grpc.WithChainUnaryInterceptor(DeadlineInterceptor(cfg.DeadlineMargin)))
+	}
+	return options
+}
+
+// Connect makes a client connection, which dials lazily, connect now and
+// waits until it is ready.
+func Connect(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection to %s is %s: %w", conn.Target(), state, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes the scheduler admin API.
+type Handler struct {
+	scheduler *Scheduler
+}
+
+func NewHandler(scheduler *Scheduler) *Handler {
+	return &Handler{scheduler: scheduler}
+}
+
+func (h *Handler) ListJobs(c *gin.Context) {
+	jobs, err := h.scheduler.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leader": h.scheduler.IsLeader(),
+		"jobs":   jobs,
+	})
+}
+
+func (h *Handler) PauseJob(c *gin.Context) {
+	if err := h.scheduler.Pause(c.Request.Context(), c.Param("name")); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Job paused"})
+}
+
+func (h *Handler) ResumeJob(c *gin.Context) {
+	if err := h.scheduler.Resume(c.Request.Context(), c.Param("name")); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Job resumed"})
+}
+
+func (h *Handler) TriggerJob(c *gin.Context) {
+	if err := h.scheduler.Trigger(c.Request.Context(), c.Param("name")); err != nil {
+		h.error(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Job triggered"})
+}
+
+func (h *Handler) error(c *gin.Context, err error) {
+	if errors.Is(err, ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// schedulerLockID is the Postgres advisory lock key that elects the leader.
+const schedulerLockID = 7423001
+
+// leaderElector holds a session-level advisory lock on a dedicated
+// connection. The lock is released automatically by Postgres if the leader
+// dies, so another replica takes over on its next attempt.
+type leaderElector struct {
+	db    *sql.DB
+	mutex sync.Mutex
+	conn  *sql.Conn
+}
+
+func newLeaderElector(db *sql.DB) *leaderElector {
+	return &leaderElector{db: db}
+}
+
+// TryAcquire attempts to become leader and verifies an existing leadership
+// is still backed by a live connection.
+func (l *leaderElector) TryAcquire(ctx context.Context) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true
+		}
+		// Connection died, and the lock with it
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", schedulerLockID).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return false
+	}
+
+	l.conn = conn
+	return true
+}
+
+func (l *leaderElector) IsLeader() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.conn != nil
+}
+
+func (l *leaderElector) Release(ctx context.Context) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", schedulerLockID)
+	l.conn.Close()
+	l.conn = nil
+}
@@ -0,0 +1,23 @@
+package scheduler
+
+import (
+	"time"
+)
+
+type Job struct {
+	Name         string     `json:"name" gorm:"primaryKey;type:varchar(100)"`
+	Schedule     string     `json:"schedule" gorm:"not null"`
+	Paused       bool       `json:"paused" gorm:"default:false"`
+	NextRunAt    time.Time  `json:"next_run_at" gorm:"index"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	LastDuration int64      `json:"last_duration_ms"`
+	LastError    string     `json:"last_error"`
+	RunCount     int64      `json:"run_count"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName sets the table name for GORM
+func (Job) TableName() string {
+	return "scheduled_jobs"
+}
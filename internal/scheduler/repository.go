@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+type Repository interface {
+	// Upsert registers a job, keeping the paused flag and next run time of
+	// an existing row unless its schedule changed
+	Upsert(ctx context.Context, job *Job) error
+	List(ctx context.Context) ([]Job, error)
+	Get(ctx context.Context, name string) (*Job, error)
+	Due(ctx context.Context, now time.Time) ([]Job, error)
+	SetPaused(ctx context.Context, name string, paused bool) error
+	SetNextRun(ctx context.Context, name string, next time.Time) error
+	RecordRun(ctx context.Context, name string, startedAt time.Time, duration time.Duration, runErr error, next time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Upsert(ctx context.Context, job *Job) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", job.Name).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(job).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if existing.Schedule == job.Schedule {
+			return nil
+		}
+		return tx.Model(&Job{}).Where("name = ?", job.Name).Updates(map[string]interface{}{
+			"schedule":    job.Schedule,
+			"next_run_at": job.NextRunAt,
+		}).Error
+	})
+}
+
+func (r *repository) List(ctx context.Context) ([]Job, error) {
+	var jobs []Job
+	err := r.db.WithContext(ctx).Order("name").Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *repository) Get(ctx context.Context, name string) (*Job, error) {
+	var job Job
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *repository) Due(ctx context.Context, now time.Time) ([]Job, error) {
+	var jobs []Job
+	err := r.db.WithContext(ctx).
+		Where("paused = ? AND next_run_at <= ?", false, now).
+		Order("next_run_at").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *repository) SetPaused(ctx context.Context, name string, paused bool) error {
+	return r.update(ctx, name, map[string]interface{}{"paused": paused})
+}
+
+func (r *repository) SetNextRun(ctx context.Context, name string, next time.Time) error {
+	return r.update(ctx, name, map[string]interface{}{"next_run_at": next})
+}
+
+func (r *repository) RecordRun(ctx context.Context, name string, startedAt time.Time, duration time.Duration, runErr error, next time.Time) error {
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+
+	return r.update(ctx, name, map[string]interface{}{
+		"last_run_at":   startedAt,
+		"last_duration": duration.Milliseconds(),
+		"last_error":    lastError,
+		"run_count":     gorm.Expr("run_count + 1"),
+		"next_run_at":   next,
+	})
+}
+
+func (r *repository) update(ctx context.Context, name string, fields map[string]interface{}) error {
+	res := r.db.WithContext(ctx).Model(&Job{}).Where("name = ?", name).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobFunc is the work performed by a scheduled job.
+type JobFunc func(ctx context.Context) error
+
+type registeredJob struct {
+	schedule Schedule
+	fn       JobFunc
+}
+
+// JobStatus is a job row annotated with the runtime state of this replica.
+type JobStatus struct {
+	Job
+	Registered bool `json:"registered"`
+	Running    bool `json:"running"`
+}
+
+// Scheduler runs registered jobs on their schedules. Job state lives in
+// Postgres so schedules survive restarts, and only the replica holding the
+// advisory lock executes jobs.
+type Scheduler struct {
+	repo         Repository
+	leader       *leaderElector
+	pollInterval time.Duration
+
+	mutex   sync.Mutex
+	jobs    map[string]*registeredJob
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func New(db *gorm.DB, repo Repository, pollInterval time.Duration) (*Scheduler, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	return &Scheduler{
+		repo:         repo,
+		leader:       newLeaderElector(sqlDB),
+		pollInterval: pollInterval,
+		jobs:         make(map[string]*registeredJob),
+		running:      make(map[string]bool),
+	}, nil
+}
+
+// Register adds a job to this replica and records it in the job table.
+func (s *Scheduler) Register(ctx context.Context, name, spec string, fn JobFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	job := &Job{
+		Name:      name,
+		Schedule:  spec,
+		NextRunAt: schedule.Next(time.Now()),
+	}
+	if err := s.repo.Upsert(ctx, job); err != nil {
+		return fmt.Errorf("failed to register job %s: %w", name, err)
+	}
+
+	s.mutex.Lock()
+	s.jobs[name] = &registeredJob{schedule: schedule, fn: fn}
+	s.mutex.Unlock()
+
+	return nil
+}
+
+// Run polls for due jobs until ctx is cancelled, then waits for running
+// jobs to finish and gives up leadership.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.leader.TryAcquire(ctx) {
+			s.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			s.leader.Release(context.Background())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) IsLeader() bool {
+	return s.leader.IsLeader()
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	due, err := s.repo.Due(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to load due jobs: %v", err)
+		return
+	}
+
+	for _, job := range due {
+		s.mutex.Lock()
+		registered, ok := s.jobs[job.Name]
+		if !ok || s.running[job.Name] {
+			s.mutex.Unlock()
+			continue
+		}
+		s.running[job.Name] = true
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go s.execute(ctx, job.Name, registered)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, name string, job *registeredJob) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.running, name)
+		s.mutex.Unlock()
+	}()
+
+	startedAt := time.Now()
+	err := s.call(ctx, job.fn)
+	duration := time.Since(startedAt)
+
+	if err != nil {
+		log.Printf("Job %s failed after %s: %v", name, duration, err)
+	}
+
+	// Record with a fresh context so shutdown does not lose the result
+	next := job.schedule.Next(time.Now())
+	if err := s.repo.RecordRun(context.Background(), name, startedAt, duration, err, next); err != nil {
+		log.Printf("Failed to record run of job %s: %v", name, err)
+	}
+}
+
+// call runs the job, turning panics into errors.
+func (s *Scheduler) call(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Scheduler) List(ctx context.Context) ([]JobStatus, error) {
+	jobs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		_, registered := s.jobs[job.Name]
+		statuses = append(statuses, JobStatus{
+			Job:        job,
+			Registered: registered,
+			Running:    s.running[job.Name],
+		})
+	}
+	return statuses, nil
+}
+
+func (s *Scheduler) Pause(ctx context.Context, name string) error {
+	return s.repo.SetPaused(ctx, name, true)
+}
+
+func (s *Scheduler) Resume(ctx context.Context, name string) error {
+	job, err := s.repo.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Skip the runs missed while paused
+	if schedule, err := ParseSchedule(job.Schedule); err == nil && job.NextRunAt.Before(time.Now()) {
+		if err := s.repo.SetNextRun(ctx, name, schedule.Next(time.Now())); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetPaused(ctx, name, false)
+}
+
+// Trigger makes the job due immediately. The leader picks it up on its next
+// poll, whichever replica received the request.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	if _, err := s.repo.Get(ctx, name); err != nil {
+		return err
+	}
+	return s.repo.SetNextRun(ctx, name, time.Now())
+}
@@ -0,0 +1,274 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/orders"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"gorm.io/gorm"
+)
+
+const (
+	GroupBots       = "bots"
+	GroupStrategies = "strategies"
+	GroupSymbols    = "symbols"
+	GroupOrders     = "orders"
+
+	DefaultLimit = 5
+	MaxLimit     = 20
+	MaxQueryLen  = 100
+
+	// similarityThreshold is the pg_trgm similarity a fuzzy match needs
+	similarityThreshold = 0.3
+)
+
+var (
+	ErrEmptyQuery   = errors.New("query must not be empty")
+	ErrQueryTooLong = errors.New("query is too long")
+	ErrUnknownGroup = errors.New("unknown search group")
+)
+
+var allGroups = []string{GroupBots, GroupStrategies, GroupSymbols, GroupOrders}
+
+type Symbol struct {
+	Exchange string `json:"exchange"`
+	Symbol   string `json:"symbol"`
+}
+
+type BotHit struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Exchange string `json:"exchange"`
+	Symbol   string `json:"symbol"`
+	Status   string `json:"status"`
+}
+
+type StrategyHit struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type OrderHit struct {
+	ID            string    `json:"id"`
+	ClientOrderID string    `json:"client_order_id,omitempty"`
+	Exchange      string    `json:"exchange"`
+	Symbol        string    `json:"symbol"`
+	Side          string    `json:"side"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Results are grouped by kind. Groups that were not requested are omitted.
+type Results struct {
+	Bots       []BotHit      `json:"bots,omitempty"`
+	Strategies []StrategyHit `json:"strategies,omitempty"`
+	Symbols    []Symbol      `json:"symbols,omitempty"`
+	Orders     []OrderHit    `json:"orders,omitempty"`
+}
+
+type Query struct {
+	UserID string
+	Text   string
+	// Groups restricts the search; empty searches every group
+	Groups []string
+	// Limit is per group
+	Limit int
+	// OrdersDB holds the user's orders when they live outside the primary
+	// database, e.g. in the user's data region
+	OrdersDB *gorm.DB
+}
+
+// Service searches a user's entities in Postgres. Names are matched with
+// pg_trgm so type-ahead works on substrings and tolerates typos; prefix
+// matches rank first.
+type Service struct {
+	db      *gorm.DB
+	symbols []Symbol
+}
+
+// NewService takes the tradable symbols per exchange; they are few enough
+// to be searched in memory.
+func NewService(db *gorm.DB, symbols map[string][]string) *Service {
+	s := &Service{db: db}
+	for exchange, list := range symbols {
+		for _, symbol := range list {
+			s.symbols = append(s.symbols, Symbol{Exchange: exchange, Symbol: strings.ToUpper(symbol)})
+		}
+	}
+	sort.Slice(s.symbols, func(i, j int) bool {
+		if s.symbols[i].Symbol != s.symbols[j].Symbol {
+			return s.symbols[i].Symbol < s.symbols[j].Symbol
+		}
+		return s.symbols[i].Exchange < s.symbols[j].Exchange
+	})
+	return s
+}
+
+func (s *Service) Search(ctx context.Context, q Query) (*Results, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return nil, ErrEmptyQuery
+	}
+	if len(q.Text) > MaxQueryLen {
+		return nil, ErrQueryTooLong
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+
+	groups := allGroups
+	if len(q.Groups) > 0 {
+		groups = nil
+		seen := make(map[string]bool)
+		for _, group := range q.Groups {
+			if !contains(allGroups, group) {
+				return nil, ErrUnknownGroup
+			}
+			if !seen[group] {
+				seen[group] = true
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	results := &Results{}
+	var wg sync.WaitGroup
+	errs := make([]error, len(groups))
+
+	// Each group writes only its own field of results
+	for i, group := range groups {
+		var run func() error
+		switch group {
+		case GroupBots:
+			run = func() (err error) {
+				results.Bots, err = s.bots(ctx, q)
+				return err
+			}
+		case GroupStrategies:
+			run = func() (err error) {
+				results.Strategies, err = s.strategies(ctx, q)
+				return err
+			}
+		case GroupSymbols:
+			results.Symbols = s.matchSymbols(q)
+			continue
+		case GroupOrders:
+			run = func() (err error) {
+				results.Orders, err = s.orders(ctx, q)
+				return err
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, run func() error) {
+			defer wg.Done()
+			errs[i] = run()
+		}(i, run)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (s *Service) bots(ctx context.Context, q Query) ([]BotHit, error) {
+	hits := []BotHit{}
+	err := s.db.WithContext(ctx).Model(&bot.Bot{}).
+		Select("id, name, exchange, symbol, status").
+		Scopes(org.Personal(q.UserID).Scope).
+		Where("name ILIKE ? OR similarity(name, ?) > ? OR ? = ANY(tags)", substring(q.Text), q.Text, similarityThreshold, strings.ToLower(q.Text)).
+		Order(gorm.Expr("name ILIKE ? DESC, similarity(name, ?) DESC, name", prefix(q.Text), q.Text)).
+		Limit(q.Limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (s *Service) strategies(ctx context.Context, q Query) ([]StrategyHit, error) {
+	hits := []StrategyHit{}
+	err := s.db.WithContext(ctx).Model(&strategy.Strategy{}).
+		Select("id, name, description").
+		Scopes(org.Personal(q.UserID).Scope).
+		Where("name ILIKE ? OR similarity(name, ?) > ? OR ? = ANY(tags)", substring(q.Text), q.Text, similarityThreshold, strings.ToLower(q.Text)).
+		Order(gorm.Expr("name ILIKE ? DESC, similarity(name, ?) DESC, name", prefix(q.Text), q.Text)).
+		Limit(q.Limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// orders matches order IDs and client order IDs by prefix and symbols by
+// substring, newest first.
+func (s *Service) orders(ctx context.Context, q Query) ([]OrderHit, error) {
+	db := s.db
+	if q.OrdersDB != nil {
+		db = q.OrdersDB
+	}
+
+	hits := []OrderHit{}
+	err := db.WithContext(ctx).Model(&orders.Order{}).
+		Select("id, client_order_id, exchange, symbol, side, status, created_at").
+		Where("user_id = ?", q.UserID).
+		Where("id LIKE ? OR client_order_id ILIKE ? OR symbol ILIKE ?", prefix(strings.ToLower(q.Text)), prefix(q.Text), substring(q.Text)).
+		Order("created_at DESC").
+		Limit(q.Limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (s *Service) matchSymbols(q Query) []Symbol {
+	text := strings.ToUpper(strings.NewReplacer("/", "", "-", "", "_", "").Replace(q.Text))
+
+	var prefixed, rest []Symbol
+	for _, symbol := range s.symbols {
+		switch {
+		case strings.HasPrefix(symbol.Symbol, text):
+			prefixed = append(prefixed, symbol)
+		case strings.Contains(symbol.Symbol, text):
+			rest = append(rest, symbol)
+		}
+	}
+
+	matches := append(prefixed, rest...)
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	if matches == nil {
+		matches = []Symbol{}
+	}
+	return matches
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func substring(text string) string {
+	return "%" + escapeLike(text) + "%"
+}
+
+func prefix(text string) string {
+	return escapeLike(text) + "%"
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
@@ -0,0 +1,154 @@
+package share
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Resources that can be shared.
+const (
+	// ResourceBotPerformance is the public performance page of a bot
+	ResourceBotPerformance = "bot_performance"
+	// ResourceBacktestReport is the report of a finished backtest sweep
+	ResourceBacktestReport = "backtest_report"
+)
+
+var (
+	ErrLinkNotFound    = errors.New("share link not found")
+	ErrInvalidToken    = errors.New("invalid share link")
+	ErrUnknownResource = errors.New("unknown share resource")
+)
+
+// Link grants read-only public access to one resource until it expires or
+// its owner revokes it.
+type Link struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID     string     `json:"-" gorm:"type:varchar(36);not null;index"`
+	Resource   string     `json:"resource" gorm:"not null"`
+	ResourceID string     `json:"resource_id" gorm:"type:varchar(36);not null"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Views      int64      `json:"views"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	// Token is the signed public token, only filled when the link is issued
+	// or listed to its owner
+	Token string `json:"token,omitempty" gorm:"-"`
+}
+
+// TableName sets the table name for GORM
+func (Link) TableName() string {
+	return "share_links"
+}
+
+func (l *Link) active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
+
+// Service issues and resolves share links. Tokens are the link ID with an
+// HMAC, so guessed or tampered tokens are rejected without a database
+// lookup; revocation is checked against the stored link.
+type Service struct {
+	db     *gorm.DB
+	secret []byte
+}
+
+func NewService(db *gorm.DB, secret string) *Service {
+	return &Service{db: db, secret: []byte(secret)}
+}
+
+func (s *Service) Create(ctx context.Context, userID, resource, resourceID string, ttl time.Duration) (*Link, error) {
+	if resource != ResourceBotPerformance && resource != ResourceBacktestReport {
+		return nil, ErrUnknownResource
+	}
+
+	link := &Link{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Resource:   resource,
+		ResourceID: resourceID,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.WithContext(ctx).Create(link).Error; err != nil {
+		return nil, err
+	}
+	link.Token = s.sign(link.ID)
+	return link, nil
+}
+
+func (s *Service) List(ctx context.Context, userID string) ([]Link, error) {
+	var links []Link
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range links {
+		if links[i].active(time.Now()) {
+			links[i].Token = s.sign(links[i].ID)
+		}
+	}
+	return links, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, userID, id string) error {
+	result := s.db.WithContext(ctx).Model(&Link{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// Resolve verifies a public token and returns its active link, counting
+// the view.
+func (s *Service) Resolve(ctx context.Context, token string) (*Link, error) {
+	id, ok := s.verify(token)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	var link Link
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !link.active(time.Now()) {
+		return nil, ErrLinkNotFound
+	}
+
+	s.db.WithContext(ctx).Model(&Link{}).Where("id = ?", id).UpdateColumn("views", gorm.Expr("views + 1"))
+	return &link, nil
+}
+
+func (s *Service) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) verify(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(token), []byte(s.sign(id)))
+}
@@ -0,0 +1,36 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tradingbothub/platform/internal/org"
+	"gorm.io/gorm"
+)
+
+type Strategy struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	// OrgID is the organization the strategy belongs to, empty for a
+	// personal one; UserID is then the member who created or moved it there
+	OrgID       string `json:"org_id,omitempty" gorm:"type:varchar(36);not null;default:'';index"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	// Source is the strategy definition in the strategy DSL
+	Source    string         `json:"source" gorm:"type:text"`
+	Tags      pq.StringArray `json:"tags" gorm:"type:text[];index:idx_strategies_tags,type:gin"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	// DeletedAt moves the row to the trash; the retention job purges it
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName sets the table name for GORM
+func (Strategy) TableName() string {
+	return "strategies"
+}
+
+// Owner is whose workspace the strategy is in.
+func (s *Strategy) Owner() org.Owner {
+	return org.Owner{UserID: s.UserID, OrgID: s.OrgID}
+}
@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tradingbothub/platform/internal/org"
+	"github.com/tradingbothub/platform/internal/tags"
+	"gorm.io/gorm"
+)
+
+var ErrStrategyNotFound = errors.New("strategy not found")
+
+type Repository interface {
+	Get(ctx context.Context, owner org.Owner, id string) (*Strategy, error)
+	List(ctx context.Context, owner org.Owner, filter tags.Filter) ([]Strategy, error)
+	SetTags(ctx context.Context, owner org.Owner, id string, values pq.StringArray) error
+	// Delete moves the strategy to the trash
+	Delete(ctx context.Context, owner org.Owner, id string) error
+	ListDeleted(ctx context.Context, owner org.Owner) ([]Strategy, error)
+	Restore(ctx context.Context, owner org.Owner, id string) error
+	// Move hands the strategy over to another owner, into an organization or
+	// back to personal use; to.UserID becomes its user either way
+	Move(ctx context.Context, owner org.Owner, id string, to org.Owner) error
+	// PurgeDeleted permanently removes strategies trashed before cutoff
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Get(ctx context.Context, owner org.Owner, id string) (*Strategy, error) {
+	var strategy Strategy
+	err := r.db.WithContext(ctx).Scopes(owner.Scope).Where("id = ?", id).First(&strategy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStrategyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &strategy, nil
+}
+
+func (r *repository) List(ctx context.Context, owner org.Owner, filter tags.Filter) ([]Strategy, error) {
+	tx := filter.Apply(r.db.WithContext(ctx).Scopes(owner.Scope), "tags")
+
+	var strategies []Strategy
+	err := tx.Order("created_at DESC").Find(&strategies).Error
+	return strategies, err
+}
+
+func (r *repository) SetTags(ctx context.Context, owner org.Owner, id string, values pq.StringArray) error {
+	result := r.db.WithContext(ctx).Model(&Strategy{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Update("tags", values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStrategyNotFound
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, owner org.Owner, id string) error {
+	result := r.db.WithContext(ctx).Scopes(owner.Scope).Where("id = ?", id).Delete(&Strategy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStrategyNotFound
+	}
+	return nil
+}
+
+func (r *repository) ListDeleted(ctx context.Context, owner org.Owner) ([]Strategy, error) {
+	var strategies []Strategy
+	err := r.db.WithContext(ctx).Unscoped().
+		Scopes(owner.Scope).Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&strategies).Error
+	return strategies, err
+}
+
+func (r *repository) Restore(ctx context.Context, owner org.Owner, id string) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&Strategy{}).
+		Scopes(owner.Scope).Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStrategyNotFound
+	}
+	return nil
+}
+
+func (r *repository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", cutoff).Delete(&Strategy{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) Move(ctx context.Context, owner org.Owner, id string, to org.Owner) error {
+	result := r.db.WithContext(ctx).Model(&Strategy{}).
+		Scopes(owner.Scope).Where("id = ?", id).
+		Updates(map[string]interface{}{"user_id": to.UserID, "org_id": to.OrgID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStrategyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package strategy
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+const (
+	TypeSMACross = "sma_cross"
+	TypeRSI      = "rsi"
+)
+
+var ErrInvalidConfig = errors.New("invalid strategy config")
+
+// Config parameterizes one of the built-in signal generators. It is stored
+// as JSON on the bot.
+type Config struct {
+	Type     string          `json:"type"`
+	Interval string          `json:"interval"`
+	Quantity decimal.Decimal `json:"quantity"`
+
+	// sma_cross: buy when the fast SMA crosses above the slow SMA
+	FastPeriod int `json:"fast_period,omitempty"`
+	SlowPeriod int `json:"slow_period,omitempty"`
+
+	// rsi: buy below Oversold, sell above Overbought
+	RSIPeriod  int     `json:"rsi_period,omitempty"`
+	Oversold   float64 `json:"oversold,omitempty"`
+	Overbought float64 `json:"overbought,omitempty"`
+}
+
+func (c Config) Validate() error {
+	if _, err := marketdata.ParseInterval(c.Interval); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if !c.Quantity.IsPositive() {
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalidConfig)
+	}
+
+	switch c.Type {
+	case TypeSMACross:
+		if c.FastPeriod <= 0 || c.SlowPeriod <= c.FastPeriod || c.SlowPeriod > marketdata.MaxPeriod {
+			return fmt.Errorf("%w: need 0 < fast_period < slow_period <= %d", ErrInvalidConfig, marketdata.MaxPeriod)
+		}
+	case TypeRSI:
+		if c.RSIPeriod <= 1 || c.RSIPeriod > marketdata.MaxPeriod || c.Oversold <= 0 || c.Overbought >= 100 || c.Oversold >= c.Overbought {
+			return fmt.Errorf("%w: need 1 < rsi_period <= %d and 0 < oversold < overbought < 100", ErrInvalidConfig, marketdata.MaxPeriod)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidConfig, c.Type)
+	}
+	return nil
+}
+
+// Warmup is the number of candles needed before the first signal.
+func (c Config) Warmup() int {
+	if c.Type == TypeRSI {
+		return c.RSIPeriod + 1
+	}
+	return c.SlowPeriod + 1
+}
+
+// Value implements driver.Valuer so the config is stored as jsonb.
+func (c Config) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner.
+func (c *Config) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = Config{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("cannot scan %T into strategy.Config", value)
+}
+
+// Signal is a trading decision at the close of a candle.
+type Signal struct {
+	Time   time.Time     `json:"time"`
+	Side   exchange.Side `json:"side"`
+	Price  float64       `json:"price"`
+	Reason string        `json:"reason"`
+	// Trace records the inputs that produced the signal
+	Trace Trace `json:"trace"`
+}
+
+// Trace explains a signal: the indicator values at its candle and how each
+// rule of the strategy evaluated.
+type Trace struct {
+	Indicators []IndicatorValue `json:"indicators"`
+	Rules      []RuleResult     `json:"rules"`
+}
+
+// IndicatorValue is an indicator at the signal's candle and the one
+// before it, since crossing rules compare the two.
+type IndicatorValue struct {
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	Previous float64 `json:"previous,omitempty"`
+}
+
+type RuleResult struct {
+	Rule   string `json:"rule"`
+	Passed bool   `json:"passed"`
+}
+
+// SimulatedOrder is the order a long-only bot would have sent for a signal.
+type SimulatedOrder struct {
+	Time     time.Time          `json:"time"`
+	Side     exchange.Side      `json:"side"`
+	Type     exchange.OrderType `json:"type"`
+	Price    decimal.Decimal    `json:"price"`
+	Quantity decimal.Decimal    `json:"quantity"`
+}
+
+// Evaluate runs the strategy over candles (sorted by time) and returns its
+// raw signals.
+func Evaluate(cfg Config, candles []marketdata.Candle) ([]Signal, error) {
+	stream, err := NewStream(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var signals []Signal
+	for _, candle := range candles {
+		if signal, ok := stream.Update(candle); ok {
+			signals = append(signals, signal)
+		}
+	}
+	return signals, nil
+}
+
+// Orders turns signals into the market orders of a long-only bot that
+// starts flat: it buys when flat and sells when long, ignoring repeats.
+func Orders(cfg Config, signals []Signal) []SimulatedOrder {
+	var orders []SimulatedOrder
+	long := false
+
+	for _, signal := range signals {
+		if (signal.Side == exchange.SideBuy) == long {
+			continue
+		}
+		long = !long
+		orders = append(orders, Order(cfg, signal))
+	}
+	return orders
+}
+
+// Order is the market order a bot sends for the signal.
+func Order(cfg Config, signal Signal) SimulatedOrder {
+	return SimulatedOrder{
+		Time:     signal.Time,
+		Side:     signal.Side,
+		Type:     exchange.OrderTypeMarket,
+		Price:    money.FromFloat(signal.Price),
+		Quantity: cfg.Quantity,
+	}
+}
@@ -0,0 +1,113 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+)
+
+// Stream evaluates a strategy one closed candle at a time. Indicators are
+// updated incrementally, so each candle costs the same however long the
+// strategy has been running.
+type Stream struct {
+	cfg Config
+
+	// sma_cross
+	fast, slow         *marketdata.RollingSMA
+	prevFast, prevSlow float64
+	// crossed is set once both averages existed on the previous candle
+	crossed bool
+
+	// rsi
+	rsi     *marketdata.RollingRSI
+	prevRSI float64
+	hasRSI  bool
+}
+
+func NewStream(cfg Config) (*Stream, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	stream := &Stream{cfg: cfg}
+	switch cfg.Type {
+	case TypeSMACross:
+		stream.fast = marketdata.NewRollingSMA(cfg.FastPeriod)
+		stream.slow = marketdata.NewRollingSMA(cfg.SlowPeriod)
+	case TypeRSI:
+		stream.rsi = marketdata.NewRollingRSI(cfg.RSIPeriod)
+	}
+	return stream, nil
+}
+
+// Update feeds the next closed candle and returns the signal at it, if
+// any.
+func (s *Stream) Update(candle marketdata.Candle) (Signal, bool) {
+	if s.cfg.Type == TypeRSI {
+		return s.updateRSI(candle)
+	}
+	return s.updateSMACross(candle)
+}
+
+func (s *Stream) updateSMACross(candle marketdata.Candle) (Signal, bool) {
+	fast, _ := s.fast.Update(candle.Close)
+	slow, ok := s.slow.Update(candle.Close)
+	prevFast, prevSlow, crossed := s.prevFast, s.prevSlow, s.crossed
+	s.prevFast, s.prevSlow, s.crossed = fast, slow, ok
+	if !ok || !crossed {
+		return Signal{}, false
+	}
+
+	prev := prevFast - prevSlow
+	curr := fast - slow
+	trace := func(before, after string) Trace {
+		return Trace{
+			Indicators: []IndicatorValue{
+				{Name: fmt.Sprintf("sma:%d", s.cfg.FastPeriod), Value: fast, Previous: prevFast},
+				{Name: fmt.Sprintf("sma:%d", s.cfg.SlowPeriod), Value: slow, Previous: prevSlow},
+			},
+			Rules: []RuleResult{
+				{Rule: "previous fast SMA " + before + " previous slow SMA", Passed: true},
+				{Rule: "fast SMA " + after + " slow SMA", Passed: true},
+			},
+		}
+	}
+
+	switch {
+	case prev <= 0 && curr > 0:
+		return Signal{Time: candle.Time, Side: exchange.SideBuy, Price: candle.Close, Reason: "fast SMA crossed above slow SMA", Trace: trace("<=", ">")}, true
+	case prev >= 0 && curr < 0:
+		return Signal{Time: candle.Time, Side: exchange.SideSell, Price: candle.Close, Reason: "fast SMA crossed below slow SMA", Trace: trace(">=", "<")}, true
+	}
+	return Signal{}, false
+}
+
+func (s *Stream) updateRSI(candle marketdata.Candle) (Signal, bool) {
+	value, ok := s.rsi.Update(candle.Close)
+	if !ok {
+		return Signal{}, false
+	}
+
+	indicator := IndicatorValue{Name: fmt.Sprintf("rsi:%d", s.cfg.RSIPeriod), Value: value}
+	if s.hasRSI {
+		indicator.Previous = s.prevRSI
+	}
+	s.prevRSI, s.hasRSI = value, true
+
+	trace := Trace{
+		Indicators: []IndicatorValue{indicator},
+		Rules: []RuleResult{
+			{Rule: fmt.Sprintf("RSI < oversold %.1f", s.cfg.Oversold), Passed: value < s.cfg.Oversold},
+			{Rule: fmt.Sprintf("RSI > overbought %.1f", s.cfg.Overbought), Passed: value > s.cfg.Overbought},
+		},
+	}
+
+	switch {
+	case value < s.cfg.Oversold:
+		return Signal{Time: candle.Time, Side: exchange.SideBuy, Price: candle.Close, Reason: fmt.Sprintf("RSI %.1f below %.1f", value, s.cfg.Oversold), Trace: trace}, true
+	case value > s.cfg.Overbought:
+		return Signal{Time: candle.Time, Side: exchange.SideSell, Price: candle.Close, Reason: fmt.Sprintf("RSI %.1f above %.1f", value, s.cfg.Overbought), Trace: trace}, true
+	}
+	return Signal{}, false
+}
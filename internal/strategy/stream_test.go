@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/marketdata"
+)
+
+func benchCandles(n int) []marketdata.Candle {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]marketdata.Candle, n)
+	for i := range candles {
+		price := 100 + 10*math.Sin(float64(i)/7)
+		candles[i] = marketdata.Candle{Time: start.Add(time.Duration(i) * time.Hour), Open: price, High: price, Low: price, Close: price}
+	}
+	return candles
+}
+
+var benchConfigs = []struct {
+	name string
+	cfg  Config
+}{
+	{"sma_cross", Config{Type: TypeSMACross, Interval: "1h", Quantity: decimal.NewFromInt(1), FastPeriod: 50, SlowPeriod: 200}},
+	{"rsi", Config{Type: TypeRSI, Interval: "1h", Quantity: decimal.NewFromInt(1), RSIPeriod: 200, Oversold: 30, Overbought: 70}},
+}
+
+// One candle through the live pipeline.
+func BenchmarkStream_Update(b *testing.B) {
+	candles := benchCandles(4096)
+	for _, bc := range benchConfigs {
+		b.Run(bc.name, func(b *testing.B) {
+			stream, err := NewStream(bc.cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				stream.Update(candles[i%len(candles)])
+			}
+		})
+	}
+}
+
+// One candle the way the pipeline worked before streams: the strategy
+// re-evaluated over the warm-up window.
+func BenchmarkEvaluate_Window(b *testing.B) {
+	candles := benchCandles(4096)
+	for _, bc := range benchConfigs {
+		b.Run(bc.name, func(b *testing.B) {
+			window := bc.cfg.Warmup() + 1
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				end := window + i%(len(candles)-window)
+				if _, err := Evaluate(bc.cfg, candles[end-window:end]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package tags
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ResourceBot      = "bot"
+	ResourceStrategy = "strategy"
+)
+
+// SavedFilter is a named tag filter a user can reapply to a list endpoint.
+type SavedFilter struct {
+	ID        string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string         `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_saved_filters_user_name,priority:1"`
+	Resource  string         `json:"resource" gorm:"not null;uniqueIndex:idx_saved_filters_user_name,priority:2"`
+	Name      string         `json:"name" gorm:"not null;uniqueIndex:idx_saved_filters_user_name,priority:3"`
+	Tags      pq.StringArray `json:"tags" gorm:"type:text[]"`
+	Match     string         `json:"match" gorm:"not null;default:'all'"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name for GORM
+func (SavedFilter) TableName() string {
+	return "saved_filters"
+}
+
+// Filter returns the tag filter the saved definition describes.
+func (s *SavedFilter) Filter() Filter {
+	return Filter{Tags: s.Tags, Match: s.Match}
+}
@@ -0,0 +1,77 @@
+package tags
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFilterNotFound = errors.New("saved filter not found")
+	ErrFilterExists   = errors.New("a saved filter with this name already exists")
+)
+
+type Repository interface {
+	CreateFilter(ctx context.Context, filter *SavedFilter) error
+	// ListFilters returns the user's filters, optionally for one resource
+	ListFilters(ctx context.Context, userID, resource string) ([]SavedFilter, error)
+	GetFilter(ctx context.Context, userID, id string) (*SavedFilter, error)
+	DeleteFilter(ctx context.Context, userID, id string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateFilter(ctx context.Context, filter *SavedFilter) error {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&SavedFilter{}).
+		Where("user_id = ? AND resource = ? AND name = ?", filter.UserID, filter.Resource, filter.Name).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrFilterExists
+	}
+	return r.db.WithContext(ctx).Create(filter).Error
+}
+
+func (r *repository) ListFilters(ctx context.Context, userID, resource string) ([]SavedFilter, error) {
+	tx := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if resource != "" {
+		tx = tx.Where("resource = ?", resource)
+	}
+
+	var filters []SavedFilter
+	err := tx.Order("name").Find(&filters).Error
+	return filters, err
+}
+
+func (r *repository) GetFilter(ctx context.Context, userID, id string) (*SavedFilter, error) {
+	var filter SavedFilter
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&filter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFilterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+func (r *repository) DeleteFilter(ctx context.Context, userID, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&SavedFilter{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFilterNotFound
+	}
+	return nil
+}
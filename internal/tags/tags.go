@@ -0,0 +1,94 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxTags      = 20
+	MaxTagLength = 32
+
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+var (
+	ErrInvalidTag   = errors.New("invalid tag")
+	ErrTooManyTags  = fmt.Errorf("at most %d tags are allowed", MaxTags)
+	ErrInvalidMatch = errors.New("match must be \"all\" or \"any\"")
+)
+
+// Normalize lower-cases, validates and de-duplicates tags. Tags may contain
+// letters, digits and - _ : . / so users can namespace them ("env:prod").
+func Normalize(raw []string) (pq.StringArray, error) {
+	seen := make(map[string]bool, len(raw))
+	out := pq.StringArray{}
+
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxTagLength || strings.IndexFunc(tag, invalidRune) >= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+
+	if len(out) > MaxTags {
+		return nil, ErrTooManyTags
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func invalidRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		return false
+	case r == '-', r == '_', r == ':', r == '.', r == '/':
+		return false
+	}
+	return true
+}
+
+// Filter selects rows by their tags. An empty filter matches everything.
+type Filter struct {
+	Tags  pq.StringArray `json:"tags"`
+	Match string         `json:"match"`
+}
+
+// NewFilter validates a filter; match defaults to MatchAll.
+func NewFilter(raw []string, match string) (Filter, error) {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if match == "" {
+		match = MatchAll
+	}
+	if match != MatchAll && match != MatchAny {
+		return Filter{}, ErrInvalidMatch
+	}
+	return Filter{Tags: normalized, Match: match}, nil
+}
+
+// Apply restricts tx to rows whose text[] column matches the filter. Both
+// operators are served by a GIN index on the column.
+func (f Filter) Apply(tx *gorm.DB, column string) *gorm.DB {
+	if len(f.Tags) == 0 {
+		return tx
+	}
+	if f.Match == MatchAny {
+		return tx.Where(column+" && ?", f.Tags)
+	}
+	return tx.Where(column+" @> ?", f.Tags)
+}
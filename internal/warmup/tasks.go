@@ -0,0 +1,32 @@
+package warmup
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Postgres is a required task that succeeds once the database answers.
+func Postgres(db *gorm.DB) Task {
+	return Task{
+		Name: "postgres",
+		Run: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// Redis is a required task that succeeds once redis answers.
+func Redis(client redis.UniversalClient) Task {
+	return Task{
+		Name: "redis",
+		Run: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
@@ -0,0 +1,247 @@
+package warmup
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/registry"
+)
+
+// Task states
+const (
+	StatePending = "pending"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+var taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "warmup_task_duration_seconds",
+	Help:    "Duration of warm-up task attempts, by task and result.",
+	Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+}, []string{"task", "result"})
+
+// Task is a piece of startup work that saves the first requests after a
+// deploy from paying for it, such as connecting to a backend or priming a
+// cache.
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Optional tasks are attempted once and do not gate readiness;
+	// required ones are retried until they succeed
+	Optional bool
+}
+
+// TaskStatus is the progress of one task.
+type TaskStatus struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional"`
+	State    string `json:"state"`
+	Attempts int    `json:"attempts"`
+	// DurationMs is how long the last attempt took
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Warmer runs a service's warm-up tasks in the background and tells when
+// the service is ready to take traffic: once every required task
+// succeeded, or once MaxWait passed, so a backend that stays down degrades
+// the requests needing it instead of keeping the whole service out of
+// rotation.
+type Warmer struct {
+	cfg config.WarmupConfig
+
+	mutex  sync.Mutex
+	tasks  []Task
+	status map[string]*TaskStatus
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func New(cfg config.WarmupConfig) *Warmer {
+	return &Warmer{
+		cfg:    cfg,
+		status: make(map[string]*TaskStatus),
+		ready:  make(chan struct{}),
+	}
+}
+
+// Register adds a task. Tasks must be registered before Start.
+func (w *Warmer) Register(task Task) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.tasks = append(w.tasks, task)
+	w.status[task.Name] = &TaskStatus{Name: task.Name, Optional: task.Optional, State: StatePending}
+}
+
+// Start runs the tasks concurrently and returns at once. The tasks stop
+// when ctx is done or the warmer is stopped.
+func (w *Warmer) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	w.mutex.Lock()
+	tasks := w.tasks
+	w.mutex.Unlock()
+
+	var required sync.WaitGroup
+	for _, task := range tasks {
+		if !task.Optional {
+			required.Add(1)
+		}
+		w.wg.Add(1)
+		go func(task Task) {
+			defer w.wg.Done()
+			if !task.Optional {
+				defer required.Done()
+			}
+			ok := w.run(ctx, task)
+			for !ok && !task.Optional {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(w.cfg.RetryInterval):
+				}
+				ok = w.run(ctx, task)
+			}
+		}(task)
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		done := make(chan struct{})
+		go func() {
+			required.Wait()
+			close(done)
+		}()
+
+		var deadline <-chan time.Time
+		if w.cfg.MaxWait > 0 {
+			timer := time.NewTimer(w.cfg.MaxWait)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+
+		select {
+		case <-done:
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Warm-up finished")
+		case <-deadline:
+			log.Printf("Warm-up still waiting for %v after %s, reporting ready", w.pending(), w.cfg.MaxWait)
+		case <-ctx.Done():
+			return
+		}
+		w.readyOnce.Do(func() { close(w.ready) })
+	}()
+}
+
+// run makes one attempt at the task and reports whether it succeeded.
+func (w *Warmer) run(ctx context.Context, task Task) bool {
+	attemptCtx := ctx
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := task.Run(attemptCtx)
+	elapsed := time.Since(start)
+
+	w.mutex.Lock()
+	status := w.status[task.Name]
+	status.Attempts++
+	status.DurationMs = elapsed.Milliseconds()
+	if err != nil {
+		status.State = StateFailed
+		status.Error = err.Error()
+	} else {
+		status.State = StateDone
+		status.Error = ""
+	}
+	w.mutex.Unlock()
+
+	if err != nil {
+		taskDuration.WithLabelValues(task.Name, "error").Observe(elapsed.Seconds())
+		log.Printf("Warm-up task %s failed: %v", task.Name, err)
+		return false
+	}
+	taskDuration.WithLabelValues(task.Name, "ok").Observe(elapsed.Seconds())
+	return true
+}
+
+// pending returns the required tasks that have not succeeded yet.
+func (w *Warmer) pending() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var names []string
+	for _, status := range w.status {
+		if !status.Optional && status.State != StateDone {
+			names = append(names, status.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ready reports whether the service may take traffic.
+func (w *Warmer) Ready() bool {
+	select {
+	case <-w.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Health is the registry status of a service warming up with w, for
+// announcers that report nothing else.
+func (w *Warmer) Health() string {
+	if !w.Ready() {
+		return registry.HealthWarming
+	}
+	return registry.HealthHealthy
+}
+
+// Wait blocks until the service is ready or ctx is done.
+func (w *Warmer) Wait(ctx context.Context) error {
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns the progress of every task, sorted by name.
+func (w *Warmer) Status() []TaskStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	statuses := make([]TaskStatus, 0, len(w.status))
+	for _, status := range w.status {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Stop cancels the tasks still running and waits for them to return.
+func (w *Warmer) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
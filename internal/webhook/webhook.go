@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/pkg/retry"
+)
+
+// Sender posts JSON events to a webhook endpoint. Each delivery carries
+// the event name, a timestamp and an HMAC-SHA256 of "timestamp.body" under
+// the shared secret, so the receiver can authenticate it and reject
+// replays. Deliveries are retried under the configured policy; receivers
+// must tolerate duplicates.
+type Sender struct {
+	url    string
+	secret []byte
+	client *http.Client
+	policy *retry.Policy
+}
+
+// New returns a sender reporting its retry metrics as name.
+func New(name string, cfg config.WebhookConfig) *Sender {
+	return &Sender{
+		url:    cfg.URL,
+		secret: []byte(cfg.Secret),
+		client: &http.Client{Timeout: cfg.Timeout},
+		policy: retry.New("webhook_"+name, cfg.Retry, retry.WithClassifier(Retryable)),
+	}
+}
+
+// statusError is a delivery the receiver answered with an error status.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook answered %d", e.code)
+}
+
+// Send delivers the event with payload as its JSON body.
+func (s *Sender) Send(ctx context.Context, event string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return s.policy.Do(ctx, func(ctx context.Context) error {
+		return s.post(ctx, event, body)
+	})
+}
+
+func (s *Sender) post(ctx context.Context, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return retry.Terminal(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(s.secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" under secret.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Retryable reports whether a delivery may succeed on another attempt.
+// Receivers rejecting the request with a 4xx other than 408 and 429 will
+// reject it again.
+func Retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
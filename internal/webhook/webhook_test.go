@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/pkg/retry"
+)
+
+func TestSender_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		err      bool
+		attempts int32
+	}{
+		{"delivered", []int{http.StatusNoContent}, false, 1},
+		{"server error retried", []int{http.StatusBadGateway, http.StatusOK}, false, 2},
+		{"throttled retried", []int{http.StatusTooManyRequests, http.StatusOK}, false, 2},
+		{"rejection not retried", []int{http.StatusBadRequest}, true, 1},
+		{"attempts exhausted", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				body, _ := io.ReadAll(r.Body)
+				timestamp := r.Header.Get("X-Webhook-Timestamp")
+				assert.Equal(t, "alert", r.Header.Get("X-Webhook-Event"))
+				assert.Equal(t, "sha256="+Sign([]byte("secret"), timestamp, body), r.Header.Get("X-Webhook-Signature"))
+				assert.JSONEq(t, `{"stuck":true}`, string(body))
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			sender := New("test", config.WebhookConfig{
+				URL:     server.URL,
+				Secret:  "secret",
+				Timeout: time.Second,
+				Retry:   retry.Config{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			})
+			err := sender.Send(context.Background(), "alert", map[string]bool{"stuck": true})
+			if tt.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, attempts.Load())
+		})
+	}
+}
@@ -0,0 +1,334 @@
+// Package authtest provides an in-memory auth.Repository for tests of code
+// built on the auth service, without a database.
+package authtest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tradingbothub/platform/internal/auth"
+)
+
+// Epoch is where the clock of new fakes starts.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeRepository keeps users in memory and behaves like the database
+// repository: lookups of missing users return auth.ErrUserNotFound, and
+// lists come back in creation order. Creating a user whose ID, email or
+// username is taken returns auth.ErrUserExists where the database would
+// fail its unique index. Users are copied in and out, so callers never
+// share state with the fake.
+//
+// Timestamps come from a clock that starts at Epoch and only moves when
+// told to, so runs are reproducible.
+type FakeRepository struct {
+	mutex sync.Mutex
+	users map[string]*auth.User
+	now   time.Time
+	fail  map[string][]error
+}
+
+func NewFakeRepository(users ...auth.User) *FakeRepository {
+	f := &FakeRepository{
+		users: make(map[string]*auth.User),
+		now:   Epoch,
+		fail:  make(map[string][]error),
+	}
+	for i := range users {
+		if err := f.Create(context.Background(), &users[i]); err != nil {
+			panic("authtest: seeding " + users[i].ID + ": " + err.Error())
+		}
+	}
+	return f
+}
+
+// Advance moves the fake's clock forward.
+func (f *FakeRepository) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+// FailNext makes the next call of the named method, such as "GetByEmail",
+// return err. Failures queue up, one per call.
+func (f *FakeRepository) FailNext(method string, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.fail[method] = append(f.fail[method], err)
+}
+
+// Users returns a copy of every stored user, in creation order.
+func (f *FakeRepository) Users() []auth.User {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.sorted(func(*auth.User) bool { return true })
+}
+
+func (f *FakeRepository) Create(ctx context.Context, user *auth.User) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Create"); err != nil {
+		return err
+	}
+	if _, ok := f.users[user.ID]; ok {
+		return auth.ErrUserExists
+	}
+	for _, u := range f.users {
+		if u.Email == user.Email || u.Username == user.Username {
+			return auth.ErrUserExists
+		}
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = f.now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = f.now
+	}
+	// Column defaults, which the database also fills in for zero values
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+	if !user.IsActive {
+		user.IsActive = true
+	}
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+func (f *FakeRepository) GetByID(ctx context.Context, id string) (*auth.User, error) {
+	return f.get("GetByID", func(u *auth.User) bool { return u.ID == id })
+}
+
+func (f *FakeRepository) GetByEmail(ctx context.Context, email string) (*auth.User, error) {
+	return f.get("GetByEmail", func(u *auth.User) bool { return u.Email == email })
+}
+
+func (f *FakeRepository) GetByUsername(ctx context.Context, username string) (*auth.User, error) {
+	return f.get("GetByUsername", func(u *auth.User) bool { return u.Username == username })
+}
+
+// Update saves every field of the user, creating them when missing, as
+// the database repository does.
+func (f *FakeRepository) Update(ctx context.Context, user *auth.User) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Update"); err != nil {
+		return err
+	}
+	for id, u := range f.users {
+		if id != user.ID && (u.Email == user.Email || u.Username == user.Username) {
+			return auth.ErrUserExists
+		}
+	}
+	user.UpdatedAt = f.now
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+func (f *FakeRepository) UpdatePassword(ctx context.Context, userID, passwordHash string, now time.Time) error {
+	return f.modify("UpdatePassword", userID, func(u *auth.User) {
+		u.PasswordHash = passwordHash
+		changed := now
+		u.PasswordChangedAt = &changed
+		u.PasswordResetRequired = false
+		u.SessionsRevokedAt = &changed
+	})
+}
+
+func (f *FakeRepository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	return f.modify("RehashPassword", userID, func(u *auth.User) {
+		if u.PasswordHash == oldHash {
+			u.PasswordHash = newHash
+		}
+	})
+}
+
+func (f *FakeRepository) Delete(ctx context.Context, id string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Delete"); err != nil {
+		return err
+	}
+	delete(f.users, id)
+	return nil
+}
+
+func (f *FakeRepository) List(ctx context.Context, query string, limit, offset int) ([]auth.User, int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("List"); err != nil {
+		return nil, 0, err
+	}
+	query = strings.ToLower(query)
+	users := f.sorted(func(u *auth.User) bool {
+		return strings.Contains(strings.ToLower(u.Email), query) ||
+			strings.Contains(strings.ToLower(u.Username), query) ||
+			strings.Contains(strings.ToLower(u.FirstName+" "+u.LastName), query)
+	})
+
+	total := int64(len(users))
+	users = users[min(max(offset, 0), len(users)):]
+	if limit >= 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (f *FakeRepository) SetActive(ctx context.Context, userID string, active bool, now time.Time) error {
+	return f.modify("SetActive", userID, func(u *auth.User) {
+		u.IsActive = active
+		if !active {
+			revoked := now
+			u.SessionsRevokedAt = &revoked
+		}
+	})
+}
+
+func (f *FakeRepository) SetPlan(ctx context.Context, userID, plan, from string, now time.Time) (*auth.PlanChange, error) {
+	var change *auth.PlanChange
+	err := f.modify("SetPlan", userID, func(u *auth.User) {
+		if u.Plan == plan || from != "" && u.Plan != from {
+			return
+		}
+		change = &auth.PlanChange{ID: uuid.New().String(), UserID: userID, From: u.Plan, To: plan, ChangedAt: now}
+		u.Plan = plan
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (f *FakeRepository) SetDataRegion(ctx context.Context, userID, region string) (*auth.User, error) {
+	err := f.modify("SetDataRegion", userID, func(u *auth.User) {
+		u.DataRegion = region
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.GetByID(ctx, userID)
+}
+
+func (f *FakeRepository) SetAvatar(ctx context.Context, userID, key string) (*auth.User, string, error) {
+	var previous string
+	err := f.modify("SetAvatar", userID, func(u *auth.User) {
+		previous = u.Avatar
+		u.Avatar = key
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := f.GetByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, previous, nil
+}
+
+func (f *FakeRepository) RequirePasswordReset(ctx context.Context, userID string, now time.Time) error {
+	return f.modify("RequirePasswordReset", userID, func(u *auth.User) {
+		u.PasswordResetRequired = true
+		revoked := now
+		u.SessionsRevokedAt = &revoked
+	})
+}
+
+// Identities calls fn in batches ordered by ID, like the database's
+// batched scan, with only the ID, email and username filled in.
+func (f *FakeRepository) Identities(ctx context.Context, batchSize int, fn func([]auth.User) error) error {
+	f.mutex.Lock()
+	if err := f.failure("Identities"); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	users := make([]auth.User, 0, len(f.users))
+	for _, u := range f.users {
+		users = append(users, auth.User{ID: u.ID, Email: u.Email, Username: u.Username})
+	}
+	f.mutex.Unlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if batchSize <= 0 {
+		batchSize = len(users)
+	}
+	for start := 0; start < len(users); start += batchSize {
+		if err := fn(users[start:min(start+batchSize, len(users))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FakeRepository) get(method string, match func(*auth.User) bool) (*auth.User, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure(method); err != nil {
+		return nil, err
+	}
+	for _, u := range f.users {
+		if match(u) {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, auth.ErrUserNotFound
+}
+
+func (f *FakeRepository) modify(method, userID string, change func(*auth.User)) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure(method); err != nil {
+		return err
+	}
+	u, ok := f.users[userID]
+	if !ok {
+		return auth.ErrUserNotFound
+	}
+	change(u)
+	u.UpdatedAt = f.now
+	return nil
+}
+
+// sorted returns copies of the matching users by creation time, then ID.
+// Callers must hold the mutex.
+func (f *FakeRepository) sorted(match func(*auth.User) bool) []auth.User {
+	users := []auth.User{}
+	for _, u := range f.users {
+		if match(u) {
+			users = append(users, *u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+// failure pops the next error queued for the method. Callers must hold
+// the mutex.
+func (f *FakeRepository) failure(method string) error {
+	queued := f.fail[method]
+	if len(queued) == 0 {
+		return nil
+	}
+	f.fail[method] = queued[1:]
+	return queued[0]
+}
@@ -0,0 +1,68 @@
+// Package buildinfo describes the running binary. Release builds set the
+// variables with ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/tradingbothub/platform/pkg/buildinfo.Version=v1.2.0 \
+//	    -X github.com/tradingbothub/platform/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	    -X github.com/tradingbothub/platform/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without ldflags the commit and time fall back to the VCS stamp Go embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info of the running binary.
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		}
+
+		build, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	})
+	return info
+}
+
+// ShortCommit is the first 12 characters of the commit, for log lines.
+func ShortCommit() string {
+	commit := Get().Commit
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
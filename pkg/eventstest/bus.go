@@ -0,0 +1,181 @@
+// Package eventstest provides an in-memory event bus for tests of event
+// publishers and consumers, without a NATS server.
+package eventstest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Epoch is where the clock of new fakes starts.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Message is a published event as it went over the bus.
+type Message struct {
+	Subject string
+	Data    []byte
+}
+
+// FakeBus is an events.Publisher that records what is published and hands
+// it to its subscribers before Publish returns, in subscription order. To
+// keep runs reproducible, envelopes get the IDs "event-1", "event-2" and
+// so on in publishing order, and the time of the fake's clock, which only
+// moves on Advance.
+type FakeBus struct {
+	mutex       sync.Mutex
+	now         time.Time
+	messages    []Message
+	subscribers []*subscriber
+	fail        []error
+}
+
+type subscriber struct {
+	subject string
+	handle  func(data []byte)
+}
+
+func NewFakeBus() *FakeBus {
+	return &FakeBus{now: Epoch}
+}
+
+// Advance moves the fake's clock forward.
+func (b *FakeBus) Advance(d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.now = b.now.Add(d)
+}
+
+// FailNext makes the next Publish return err without publishing. Failures
+// queue up, one per call.
+func (b *FakeBus) FailNext(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.fail = append(b.fail, err)
+}
+
+// Publish records the message and delivers it to the subscribers of its
+// subject.
+func (b *FakeBus) Publish(subject string, data []byte) error {
+	b.mutex.Lock()
+	if len(b.fail) > 0 {
+		err := b.fail[0]
+		b.fail = b.fail[1:]
+		b.mutex.Unlock()
+		return err
+	}
+	data, err := b.stamp(data)
+	if err != nil {
+		b.mutex.Unlock()
+		return err
+	}
+	b.messages = append(b.messages, Message{Subject: subject, Data: data})
+	var handlers []func([]byte)
+	for _, s := range b.subscribers {
+		if s.subject == subject {
+			handlers = append(handlers, s.handle)
+		}
+	}
+	b.mutex.Unlock()
+
+	// Outside the lock, so handlers may publish in turn
+	for _, handle := range handlers {
+		handle(bytes.Clone(data))
+	}
+	return nil
+}
+
+// Messages returns everything published so far, in order.
+func (b *FakeBus) Messages() []Message {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	messages := make([]Message, len(b.messages))
+	for i, m := range b.messages {
+		messages[i] = Message{Subject: m.Subject, Data: bytes.Clone(m.Data)}
+	}
+	return messages
+}
+
+// Reset forgets the published messages; subscriptions stay.
+func (b *FakeBus) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.messages = nil
+}
+
+// stamp replaces the envelope's ID and time with deterministic ones. Data
+// that is not an envelope is recorded as is. Callers must hold the mutex.
+func (b *FakeBus) stamp(data []byte) ([]byte, error) {
+	var envelope eventspb.Envelope
+	if err := proto.Unmarshal(data, &envelope); err != nil || envelope.Type == "" {
+		return bytes.Clone(data), nil
+	}
+	envelope.Id = fmt.Sprintf("event-%d", len(b.messages)+1)
+	envelope.OccurredAt = timestamppb.New(b.now)
+	return proto.Marshal(&envelope)
+}
+
+// Subscribe delivers the events of type T published on the bus to handler,
+// decoded as events.Subscribe does; events that fail to decode are
+// dropped. The returned function ends the subscription.
+func Subscribe[T proto.Message](bus *FakeBus, newEvent func() T, handler func(*eventspb.Envelope, T)) (func(), error) {
+	schema, err := events.Lookup(newEvent())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriber{subject: schema.Subject, handle: func(data []byte) {
+		event := newEvent()
+		envelope, err := events.Decode(data, event)
+		if err != nil {
+			return
+		}
+		handler(envelope, event)
+	}}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.subscribers = append(bus.subscribers, s)
+	return func() {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+		for i, other := range bus.subscribers {
+			if other == s {
+				bus.subscribers = append(bus.subscribers[:i:i], bus.subscribers[i+1:]...)
+				return
+			}
+		}
+	}, nil
+}
+
+// Published returns the events of type T published so far, in order, for
+// asserting on what a publisher sent.
+func Published[T proto.Message](bus *FakeBus, newEvent func() T) ([]T, error) {
+	schema, err := events.Lookup(newEvent())
+	if err != nil {
+		return nil, err
+	}
+
+	var published []T
+	for _, m := range bus.Messages() {
+		if m.Subject != schema.Subject {
+			continue
+		}
+		event := newEvent()
+		if _, err := events.Decode(m.Data, event); err != nil {
+			return nil, fmt.Errorf("event on %s: %w", m.Subject, err)
+		}
+		published = append(published, event)
+	}
+	return published, nil
+}
@@ -0,0 +1,352 @@
+// Package exchangetest provides a scripted exchange.Client for tests of
+// bots, strategies and order handling, without a connector or network.
+package exchangetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+// Epoch is where the clock of new fakes starts.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeExchange is an exchange whose behavior depends only on what the test
+// does to it. Market orders fill in full at the price last set for their
+// symbol; limit orders rest until SetPrice crosses them and then fill at
+// their limit, oldest first. Orders are numbered "<name>-1", "<name>-2"
+// and so on, stamped with the fake's clock, which only moves on Advance,
+// and listed in placement order.
+//
+// Orders of symbols without a price, reduce-only orders without a position
+// to reduce and idempotent re-placements behave as on the paper exchange.
+type FakeExchange struct {
+	name      string
+	mutex     sync.Mutex
+	now       time.Time
+	prices    map[string]decimal.Decimal
+	orders    []*exchange.Order // in placement order
+	owners    map[string]string // order ID -> user ID
+	clientIDs map[string]string // user ID + client order ID -> order ID
+	positions map[string]map[string]*exchange.Position
+	balances  map[string]decimal.Decimal
+	fail      map[string][]error
+}
+
+func NewFakeExchange(name string) *FakeExchange {
+	return &FakeExchange{
+		name:      name,
+		now:       Epoch,
+		prices:    make(map[string]decimal.Decimal),
+		owners:    make(map[string]string),
+		clientIDs: make(map[string]string),
+		positions: make(map[string]map[string]*exchange.Position),
+		balances:  make(map[string]decimal.Decimal),
+		fail:      make(map[string][]error),
+	}
+}
+
+// Advance moves the fake's clock forward.
+func (f *FakeExchange) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+// FailNext makes the next call of the named method, such as "PlaceOrder",
+// return err without any effect. Failures queue up, one per call.
+func (f *FakeExchange) FailNext(method string, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.fail[method] = append(f.fail[method], err)
+}
+
+// Deposit credits the user's wallet.
+func (f *FakeExchange) Deposit(userID string, amount decimal.Decimal) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.balances[userID] = f.balances[userID].Add(amount)
+}
+
+// SetPrice sets the symbol's last price and fills the resting limit orders
+// it crosses.
+func (f *FakeExchange) SetPrice(symbol string, price decimal.Decimal) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.prices[symbol] = price
+	for _, order := range f.orders {
+		if order.Status != exchange.OrderStatusOpen || order.Symbol != symbol {
+			continue
+		}
+		crossed := (order.Side == exchange.SideBuy && price.LessThanOrEqual(order.Price)) ||
+			(order.Side == exchange.SideSell && price.GreaterThanOrEqual(order.Price))
+		if crossed {
+			f.fill(f.owners[order.ID], order, order.Price)
+		}
+	}
+}
+
+// Orders returns every order of the user in any status, in placement
+// order, for asserting on what was traded.
+func (f *FakeExchange) Orders(userID string) []exchange.Order {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.list(userID, func(*exchange.Order) bool { return true })
+}
+
+func (f *FakeExchange) OpenOrders(ctx context.Context, userID, symbol string) ([]exchange.Order, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("OpenOrders"); err != nil {
+		return nil, err
+	}
+	return f.list(userID, func(order *exchange.Order) bool {
+		return order.Status == exchange.OrderStatusOpen && (symbol == "" || order.Symbol == symbol)
+	}), nil
+}
+
+func (f *FakeExchange) Order(ctx context.Context, userID, orderID string) (*exchange.Order, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Order"); err != nil {
+		return nil, err
+	}
+	order := f.find(userID, orderID)
+	if order == nil {
+		return nil, exchange.ErrOrderNotFound
+	}
+	found := *order
+	return &found, nil
+}
+
+func (f *FakeExchange) PlaceOrder(ctx context.Context, userID string, req exchange.OrderRequest) (*exchange.Order, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("PlaceOrder"); err != nil {
+		return nil, err
+	}
+	if req.ClientOrderID != "" {
+		if id, ok := f.clientIDs[userID+":"+req.ClientOrderID]; ok {
+			existing := *f.find(userID, id)
+			return &existing, nil
+		}
+	}
+
+	order := &exchange.Order{
+		ID:            fmt.Sprintf("%s-%d", f.name, len(f.orders)+1),
+		ClientOrderID: req.ClientOrderID,
+		Exchange:      f.name,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		Status:        exchange.OrderStatusOpen,
+		Price:         req.Price,
+		Quantity:      req.Quantity,
+		Filled:        decimal.Zero,
+		ReduceOnly:    req.ReduceOnly,
+		CreatedAt:     f.now,
+	}
+
+	if req.ReduceOnly {
+		position := f.positions[userID][req.Symbol]
+		if position == nil || position.Side == req.Side || position.Quantity.IsZero() {
+			order.Status = exchange.OrderStatusRejected
+		} else if order.Quantity.GreaterThan(position.Quantity) {
+			order.Quantity = position.Quantity
+		}
+	}
+
+	if order.Status == exchange.OrderStatusOpen && req.Type == exchange.OrderTypeMarket {
+		price, ok := f.prices[req.Symbol]
+		if !ok {
+			return nil, exchange.ErrNoPrice
+		}
+		f.fill(userID, order, price)
+	}
+
+	f.orders = append(f.orders, order)
+	f.owners[order.ID] = userID
+	if req.ClientOrderID != "" {
+		f.clientIDs[userID+":"+req.ClientOrderID] = order.ID
+	}
+
+	placed := *order
+	return &placed, nil
+}
+
+func (f *FakeExchange) CancelOrder(ctx context.Context, userID, orderID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("CancelOrder"); err != nil {
+		return err
+	}
+	order := f.find(userID, orderID)
+	if order == nil {
+		return exchange.ErrOrderNotFound
+	}
+	if order.Status != exchange.OrderStatusOpen {
+		return exchange.ErrOrderClosed
+	}
+	order.Status = exchange.OrderStatusCancelled
+	return nil
+}
+
+// Positions returns the user's open positions by symbol.
+func (f *FakeExchange) Positions(ctx context.Context, userID, symbol string) ([]exchange.Position, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Positions"); err != nil {
+		return nil, err
+	}
+	var positions []exchange.Position
+	for sym, position := range f.positions[userID] {
+		if position.Quantity.IsZero() || (symbol != "" && sym != symbol) {
+			continue
+		}
+		positions = append(positions, *position)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions, nil
+}
+
+func (f *FakeExchange) Account(ctx context.Context, userID string) (*exchange.Account, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("Account"); err != nil {
+		return nil, err
+	}
+	account := &exchange.Account{
+		Exchange:      f.name,
+		Balance:       f.balances[userID],
+		UnrealizedPnL: decimal.Zero,
+	}
+	for symbol, position := range f.positions[userID] {
+		price, ok := f.prices[symbol]
+		if !ok || position.Quantity.IsZero() {
+			continue
+		}
+		account.UnrealizedPnL = account.UnrealizedPnL.Add(pnl(position, price, position.Quantity))
+	}
+	account.Equity = account.Balance.Add(account.UnrealizedPnL)
+	return account, nil
+}
+
+func (f *FakeExchange) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.failure("LastPrice"); err != nil {
+		return decimal.Zero, err
+	}
+	price, ok := f.prices[symbol]
+	if !ok {
+		return decimal.Zero, exchange.ErrNoPrice
+	}
+	return price, nil
+}
+
+// list returns copies of the user's matching orders. Callers must hold the
+// mutex.
+func (f *FakeExchange) list(userID string, match func(*exchange.Order) bool) []exchange.Order {
+	var orders []exchange.Order
+	for _, order := range f.orders {
+		if f.owners[order.ID] == userID && match(order) {
+			orders = append(orders, *order)
+		}
+	}
+	return orders
+}
+
+// find returns the user's order, or nil. Callers must hold the mutex.
+func (f *FakeExchange) find(userID, orderID string) *exchange.Order {
+	if f.owners[orderID] != userID {
+		return nil
+	}
+	for _, order := range f.orders {
+		if order.ID == orderID {
+			return order
+		}
+	}
+	return nil
+}
+
+// fill executes the order in full at price and nets it into the user's
+// position, crediting the PnL of what it closes. Callers must hold the
+// mutex.
+func (f *FakeExchange) fill(userID string, order *exchange.Order, price decimal.Decimal) {
+	order.Status = exchange.OrderStatusFilled
+	order.Filled = order.Quantity
+	order.Price = price
+
+	if f.positions[userID] == nil {
+		f.positions[userID] = make(map[string]*exchange.Position)
+	}
+	position := f.positions[userID][order.Symbol]
+	if position == nil || position.Quantity.IsZero() {
+		f.positions[userID][order.Symbol] = &exchange.Position{
+			Exchange:   f.name,
+			Symbol:     order.Symbol,
+			Side:       order.Side,
+			Quantity:   order.Quantity,
+			EntryPrice: price,
+		}
+		return
+	}
+
+	if position.Side == order.Side {
+		total := position.Quantity.Add(order.Quantity)
+		position.EntryPrice = position.EntryPrice.Mul(position.Quantity).Add(price.Mul(order.Quantity)).Div(total)
+		position.Quantity = total
+		return
+	}
+
+	closed := decimal.Min(position.Quantity, order.Quantity)
+	f.balances[userID] = f.balances[userID].Add(pnl(position, price, closed))
+
+	remaining := position.Quantity.Sub(order.Quantity)
+	switch {
+	case remaining.IsPositive():
+		position.Quantity = remaining
+	case remaining.IsZero():
+		position.Quantity = decimal.Zero
+	default:
+		position.Side = order.Side
+		position.Quantity = remaining.Neg()
+		position.EntryPrice = price
+	}
+}
+
+func pnl(position *exchange.Position, price, quantity decimal.Decimal) decimal.Decimal {
+	diff := price.Sub(position.EntryPrice)
+	if position.Side == exchange.SideSell {
+		diff = diff.Neg()
+	}
+	return diff.Mul(quantity)
+}
+
+// failure pops the next error queued for the method. Callers must hold
+// the mutex.
+func (f *FakeExchange) failure(method string) error {
+	queued := f.fail[method]
+	if len(queued) == 0 {
+		return nil
+	}
+	f.fail[method] = queued[1:]
+	return queued[0]
+}
@@ -0,0 +1,102 @@
+// Package money is the one place amounts cross between floats and
+// fixed-point decimals, get rounded to what an exchange accepts, and get
+// formatted for people. Prices, quantities and balances are
+// decimal.Decimal everywhere else; floats are for indicators and charts.
+package money
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency describes how amounts of an asset are displayed.
+type Currency struct {
+	Code string
+	// Decimals is the number of fraction digits shown
+	Decimals int32
+	Symbol   string
+}
+
+var currencies = map[string]Currency{
+	"USD":  {Code: "USD", Decimals: 2, Symbol: "$"},
+	"EUR":  {Code: "EUR", Decimals: 2, Symbol: "€"},
+	"GBP":  {Code: "GBP", Decimals: 2, Symbol: "£"},
+	"JPY":  {Code: "JPY", Decimals: 0, Symbol: "¥"},
+	"USDT": {Code: "USDT", Decimals: 2},
+	"USDC": {Code: "USDC", Decimals: 2},
+	"BUSD": {Code: "BUSD", Decimals: 2},
+	"BTC":  {Code: "BTC", Decimals: 8},
+	"ETH":  {Code: "ETH", Decimals: 8},
+}
+
+// quoteCodes are tried longest first when splitting concatenated symbols
+// such as "BTCUSDT".
+var quoteCodes = []string{"USDT", "USDC", "BUSD", "USD", "EUR", "GBP", "JPY", "BTC", "ETH"}
+
+// Lookup returns the display rules for code. Unknown assets show eight
+// decimals, enough for any crypto asset in practice.
+func Lookup(code string) Currency {
+	code = strings.ToUpper(code)
+	if c, ok := currencies[code]; ok {
+		return c
+	}
+	return Currency{Code: code, Decimals: 8}
+}
+
+// QuoteCurrency returns the currency prices of symbol are quoted in, for
+// symbols like "BTC/USDT", "BTC-USD" or "BTCUSDT". It returns "" when the
+// quote cannot be told.
+func QuoteCurrency(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if i := strings.IndexAny(symbol, "/-_"); i >= 0 {
+		return symbol[i+1:]
+	}
+	for _, code := range quoteCodes {
+		if len(symbol) > len(code) && strings.HasSuffix(symbol, code) {
+			return code
+		}
+	}
+	return ""
+}
+
+// FromFloat converts a float, such as a candle price, to a decimal. It
+// uses the shortest representation that round-trips, so 0.1 becomes
+// exactly 0.1 rather than its binary approximation.
+func FromFloat(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f)
+}
+
+// Round rounds amount half away from zero to the currency's decimals.
+func Round(amount decimal.Decimal, code string) decimal.Decimal {
+	return amount.Round(Lookup(code).Decimals)
+}
+
+// Format renders amount for display with thousands separators, e.g.
+// "$1,234.50", "-€12.00" or "0.00120000 BTC".
+func Format(amount decimal.Decimal, code string) string {
+	c := Lookup(code)
+	s := amount.Abs().StringFixed(c.Decimals)
+
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if amount.Round(c.Decimals).IsNegative() {
+		b.WriteByte('-')
+	}
+	b.WriteString(c.Symbol)
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	if c.Symbol == "" {
+		b.WriteByte(' ')
+		b.WriteString(c.Code)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidPrecision = errors.New("invalid precision")
+	// ErrBelowMinimum means the order is too small once rounded to the
+	// market's precision
+	ErrBelowMinimum = errors.New("order below market minimum")
+)
+
+// maxExponent bounds the exponents ParsePrecision accepts. Arithmetic on
+// decimals scales them to a common exponent, which for "1e2147483647"
+// would take forever.
+const maxExponent = 32
+
+// Precision is the granularity a market accepts. Zero values mean no
+// constraint.
+type Precision struct {
+	// TickSize is the price increment
+	TickSize decimal.Decimal
+	// StepSize is the quantity increment
+	StepSize decimal.Decimal
+	// MinNotional is the smallest price * quantity accepted
+	MinNotional decimal.Decimal
+}
+
+// ParsePrecision reads the decimal strings of a market's precision, as
+// kept in config so no float ever touches them.
+func ParsePrecision(tickSize, stepSize, minNotional string) (Precision, error) {
+	var p Precision
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *decimal.Decimal
+	}{
+		{"tick_size", tickSize, &p.TickSize},
+		{"step_size", stepSize, &p.StepSize},
+		{"min_notional", minNotional, &p.MinNotional},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := decimal.NewFromString(field.value)
+		if err != nil || d.IsNegative() || d.Exponent() < -maxExponent || d.Exponent() > maxExponent {
+			return Precision{}, fmt.Errorf("%w: %s %q", ErrInvalidPrecision, field.name, field.value)
+		}
+		*field.dst = d
+	}
+	return p, nil
+}
+
+// RoundPrice snaps a limit price to the tick grid on the side that never
+// trades worse than asked: buys round down and sells round up.
+func (p Precision) RoundPrice(price decimal.Decimal, buy bool) decimal.Decimal {
+	if !p.TickSize.IsPositive() {
+		return price
+	}
+	// QuoRem is exact where Div rounds to DivisionPrecision digits, which
+	// loses the price entirely against a tick far larger than it
+	ticks, rest := price.QuoRem(p.TickSize, 0)
+	if buy && rest.IsNegative() {
+		ticks = ticks.Sub(decimal.NewFromInt(1))
+	} else if !buy && rest.IsPositive() {
+		ticks = ticks.Add(decimal.NewFromInt(1))
+	}
+	return ticks.Mul(p.TickSize)
+}
+
+// RoundQuantity truncates quantity to the step grid, so an order never
+// spends more than was allocated.
+func (p Precision) RoundQuantity(quantity decimal.Decimal) decimal.Decimal {
+	if !p.StepSize.IsPositive() {
+		return quantity
+	}
+	steps, rest := quantity.QuoRem(p.StepSize, 0)
+	if rest.IsNegative() {
+		steps = steps.Sub(decimal.NewFromInt(1))
+	}
+	return steps.Mul(p.StepSize)
+}
+
+// Check reports whether an order of quantity at price meets the market's
+// minimums. Market orders pass their expected fill price.
+func (p Precision) Check(price, quantity decimal.Decimal) error {
+	if !quantity.IsPositive() {
+		return fmt.Errorf("%w: quantity rounds to zero", ErrBelowMinimum)
+	}
+	if p.MinNotional.IsPositive() && price.IsPositive() && price.Mul(quantity).LessThan(p.MinNotional) {
+		return fmt.Errorf("%w: notional %s under %s", ErrBelowMinimum, price.Mul(quantity), p.MinNotional)
+	}
+	return nil
+}
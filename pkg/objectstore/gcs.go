@@ -0,0 +1,113 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCS stores objects in a Google Cloud Storage bucket.
+type GCS struct {
+	cfg    GCSConfig
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+func NewGCS(ctx context.Context, bucket string, cfg GCSConfig) (*GCS, error) {
+	if bucket == "" {
+		return nil, errors.New("gcs object store needs a bucket")
+	}
+
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcs client: %w", err)
+	}
+	return &GCS{cfg: cfg, client: client, bucket: client.Bucket(bucket)}, nil
+}
+
+func (g *GCS) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	w := g.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = opts.ContentType
+	w.KMSKeyName = g.cfg.KMSKeyName
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	// The object is only committed once Close succeeds
+	return w.Close()
+}
+
+func (g *GCS) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	r, err := g.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := g.bucket.Object(key).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (g *GCS) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	it := g.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, Object{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			ContentType:  attrs.ContentType,
+			LastModified: attrs.Updated,
+		})
+	}
+}
+
+// SignedURL signs with the service account of the client's credentials.
+func (g *GCS) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateMethod(method); err != nil {
+		return "", err
+	}
+	return g.bucket.SignedURL(key, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(expiry),
+	})
+}
+
+func (g *GCS) Close() error {
+	return g.client.Close()
+}
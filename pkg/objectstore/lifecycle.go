@@ -0,0 +1,50 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Cleaner applies lifecycle rules by deleting expired objects. Backends
+// with native lifecycle policies can use those instead; this keeps local
+// disk and buckets without policies bounded.
+type Cleaner struct {
+	store Store
+	rules []Rule
+}
+
+func NewCleaner(store Store, rules []Rule) *Cleaner {
+	return &Cleaner{store: store, rules: rules}
+}
+
+// Run matches scheduler.JobFunc.
+func (c *Cleaner) Run(ctx context.Context) error {
+	now := time.Now()
+	for _, rule := range c.rules {
+		if rule.TTL <= 0 {
+			continue
+		}
+
+		objects, err := c.store.List(ctx, rule.Prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", rule.Prefix, err)
+		}
+
+		deleted := 0
+		for _, object := range objects {
+			if now.Sub(object.LastModified) < rule.TTL {
+				continue
+			}
+			if err := c.store.Delete(ctx, object.Key); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", object.Key, err)
+			}
+			deleted++
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d objects under %s older than %s", deleted, rule.Prefix, rule.TTL)
+		}
+	}
+	return nil
+}
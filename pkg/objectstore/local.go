@@ -0,0 +1,198 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores objects as files under a root directory. It serves its own
+// signed URLs through Handler.
+type Local struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocal(cfg LocalConfig) (*Local, error) {
+	if cfg.Root == "" {
+		return nil, errors.New("local object store needs a root directory")
+	}
+	if err := os.MkdirAll(cfg.Root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create object store root: %w", err)
+	}
+	return &Local{
+		root:    cfg.Root,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		secret:  []byte(cfg.Secret),
+	}, nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	name := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+
+	// Write aside and rename so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(l.root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  mime.TypeByExtension(path.Ext(key)),
+			LastModified: info.ModTime(),
+		})
+		return ctx.Err()
+	})
+	return objects, err
+}
+
+// SignedURL points below BaseURL with an HMAC of method, key and expiry.
+func (l *Local) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateMethod(method); err != nil {
+		return "", err
+	}
+	if l.baseURL == "" || len(l.secret) == 0 {
+		return "", fmt.Errorf("%w: local signed URLs need base_url and secret", ErrUnsupported)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", l.sign(method, key, expires))
+	return l.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (l *Local) Close() error {
+	return nil
+}
+
+// Handler serves signed GET and PUT URLs. Mount it at BaseURL with the
+// mount path stripped.
+func (l *Local) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if validateKey(key) != nil || validateMethod(r.Method) != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		expires := r.URL.Query().Get("expires")
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || time.Now().Unix() > unix ||
+			!hmac.Equal([]byte(l.sign(r.Method, key, expires)), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			if err := l.Put(r.Context(), key, r.Body, PutOptions{}); err != nil {
+				http.Error(w, "upload failed", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		f, err := os.Open(l.path(key))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+	})
+}
+
+func (l *Local) sign(method, key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
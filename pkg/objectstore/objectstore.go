@@ -0,0 +1,133 @@
+// Package objectstore stores binary artifacts such as avatars, export
+// files, backtest reports and strategy bundles on local disk, S3 or GCS
+// behind one interface.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+	// ErrUnsupported is returned for operations a backend cannot perform
+	ErrUnsupported = errors.New("operation not supported by backend")
+)
+
+// Key prefixes of the artifact kinds, so lifecycle rules can target them.
+const (
+	PrefixAvatars   = "avatars/"
+	PrefixExports   = "exports/"
+	PrefixBacktests = "backtests/"
+	PrefixBundles   = "strategy-bundles/"
+)
+
+type Config struct {
+	// Backend is "local", "s3" or "gcs"
+	Backend string `mapstructure:"backend"`
+	// Bucket names the S3 or GCS bucket
+	Bucket string      `mapstructure:"bucket"`
+	Local  LocalConfig `mapstructure:"local"`
+	S3     S3Config    `mapstructure:"s3"`
+	GCS    GCSConfig   `mapstructure:"gcs"`
+	// Lifecycle deletes objects under a prefix once they reach its TTL
+	Lifecycle []Rule `mapstructure:"lifecycle"`
+	// CleanupSchedule is the scheduler spec of the lifecycle job
+	CleanupSchedule string `mapstructure:"cleanup_schedule"`
+}
+
+type LocalConfig struct {
+	Root string `mapstructure:"root"`
+	// BaseURL is where Handler is mounted; signed URLs point below it
+	BaseURL string `mapstructure:"base_url"`
+	// Secret signs URLs
+	Secret string `mapstructure:"secret"`
+}
+
+type S3Config struct {
+	Region string `mapstructure:"region"`
+	// Endpoint overrides the AWS endpoint for S3-compatible stores
+	Endpoint     string `mapstructure:"endpoint"`
+	UsePathStyle bool   `mapstructure:"use_path_style"`
+	// Static credentials; when empty the default AWS credential chain is used
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	// Encryption is the server-side encryption: "", "AES256" or "aws:kms"
+	Encryption string `mapstructure:"encryption"`
+	// KMSKeyID selects the key for "aws:kms"; empty uses the AWS managed key
+	KMSKeyID string `mapstructure:"kms_key_id"`
+}
+
+type GCSConfig struct {
+	// CredentialsFile is a service account key; when empty application
+	// default credentials are used. Signing URLs needs a service account.
+	CredentialsFile string `mapstructure:"credentials_file"`
+	// KMSKeyName encrypts new objects with a customer-managed key
+	KMSKeyName string `mapstructure:"kms_key_name"`
+}
+
+// Rule expires objects under Prefix older than TTL.
+type Rule struct {
+	Prefix string        `mapstructure:"prefix"`
+	TTL    time.Duration `mapstructure:"ttl"`
+}
+
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type PutOptions struct {
+	ContentType string
+}
+
+// Store is an object storage backend. Keys are slash separated relative
+// paths such as "exports/<user>/trades.csv".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL grants time-limited access to key without credentials.
+	// method is http.MethodGet to download or http.MethodPut to upload.
+	SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error)
+	Close() error
+}
+
+// New opens the configured backend.
+func New(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocal(cfg.Local)
+	case "s3":
+		return NewS3(ctx, cfg.Bucket, cfg.S3)
+	case "gcs":
+		return NewGCS(ctx, cfg.Bucket, cfg.GCS)
+	}
+	return nil, fmt.Errorf("unknown object store backend %q", cfg.Backend)
+}
+
+// validateKey rejects keys that could escape the store's namespace.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+func validateMethod(method string) error {
+	if method != http.MethodGet && method != http.MethodPut {
+		return fmt.Errorf("%w: signed %s URLs", ErrUnsupported, method)
+	}
+	return nil
+}
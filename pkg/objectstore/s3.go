@@ -0,0 +1,177 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3 stores objects in an S3 bucket or an S3-compatible store.
+type S3 struct {
+	bucket   string
+	cfg      S3Config
+	client   *s3.Client
+	uploader *manager.Uploader
+	presign  *s3.PresignClient
+}
+
+func NewS3(ctx context.Context, bucket string, cfg S3Config) (*S3, error) {
+	if bucket == "" {
+		return nil, errors.New("s3 object store needs a bucket")
+	}
+	switch types.ServerSideEncryption(cfg.Encryption) {
+	case "", types.ServerSideEncryptionAes256, types.ServerSideEncryptionAwsKms:
+	default:
+		return nil, fmt.Errorf("unsupported s3 encryption %q", cfg.Encryption)
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.Region)}
+	if cfg.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.UsePathStyle
+	})
+	return &S3{
+		bucket:   bucket,
+		cfg:      cfg,
+		client:   client,
+		uploader: manager.NewUploader(client),
+		presign:  s3.NewPresignClient(client),
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	s.encrypt(input)
+
+	// The uploader streams readers of unknown length in parts
+	_, err := s.uploader.Upload(ctx, input)
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(item.Key),
+				Size:         aws.ToInt64(item.Size),
+				LastModified: aws.ToTime(item.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// SignedURL presigns with the store's credentials. Uploads through the URL
+// must send the encryption headers the signature covers.
+func (s *S3) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if err := validateMethod(method); err != nil {
+		return "", err
+	}
+
+	var (
+		req *v4.PresignedHTTPRequest
+		err error
+	)
+	if method == http.MethodPut {
+		input := &s3.PutObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)}
+		s.encrypt(input)
+		req, err = s.presign.PresignPutObject(ctx, input, s3.WithPresignExpires(expiry))
+	} else {
+		req, err = s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(expiry))
+	}
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *S3) Close() error {
+	return nil
+}
+
+func (s *S3) encrypt(input *s3.PutObjectInput) {
+	if s.cfg.Encryption == "" {
+		return
+	}
+	input.ServerSideEncryption = types.ServerSideEncryption(s.cfg.Encryption)
+	if s.cfg.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.cfg.KMSKeyID)
+	}
+}
@@ -0,0 +1,235 @@
+// Package retry runs operations again after transient failures, with
+// jittered exponential backoff, a retry budget that stops retry storms when
+// a dependency is down, and a classification of errors into retryable and
+// terminal ones. Every policy reports its attempts under its name.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of a call, as reported in retry_calls_total
+const (
+	OutcomeSuccess = "success"
+	// OutcomeTerminal failed with an error that retrying cannot fix
+	OutcomeTerminal = "terminal"
+	// OutcomeExhausted failed on its last allowed attempt
+	OutcomeExhausted = "exhausted"
+	// OutcomeBudget failed because the budget allowed no more retries
+	OutcomeBudget = "budget_exhausted"
+	// OutcomeCancelled ran out of context while waiting or running
+	OutcomeCancelled = "cancelled"
+)
+
+var (
+	retryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retry_attempts_total",
+		Help: "Retries, not counting first attempts, by policy.",
+	}, []string{"policy"})
+
+	retryCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retry_calls_total",
+		Help: "Calls run under a retry policy, by policy and outcome.",
+	}, []string{"policy", "outcome"})
+
+	retryBudgetTokens = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "retry_budget_tokens",
+		Help: "Retries the policy's budget currently allows.",
+	}, []string{"policy"})
+)
+
+// Config is the configurable part of a policy.
+type Config struct {
+	// MaxAttempts includes the first attempt; 1 disables retries
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// InitialBackoff is the wait before the first retry; it grows by
+	// Multiplier on each further retry up to MaxBackoff
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	Multiplier     float64       `mapstructure:"multiplier"`
+	// Jitter randomizes each wait by up to this fraction of it, in both
+	// directions, so clients that failed together do not retry together
+	Jitter float64 `mapstructure:"jitter"`
+	// BudgetRatio caps retries at this fraction of calls, e.g. 0.1 allows
+	// one retry per ten calls; zero leaves retries unbudgeted
+	BudgetRatio float64 `mapstructure:"budget_ratio"`
+	// BudgetBurst is the most retries the budget banks, and its balance
+	// at start, so a quiet client can still ride out a blip
+	BudgetBurst int `mapstructure:"budget_burst"`
+}
+
+// Policy decides whether and when a failed call is attempted again.
+// Policies are safe for concurrent use; share one per dependency so its
+// budget sees all calls.
+type Policy struct {
+	name      string
+	cfg       Config
+	budget    *budget
+	retryable func(error) bool
+}
+
+// Option customizes a Policy.
+type Option func(*Policy)
+
+// WithClassifier decides which errors are worth retrying. Errors marked
+// with Terminal and context errors are never retried, whatever it says.
+func WithClassifier(retryable func(error) bool) Option {
+	return func(p *Policy) {
+		p.retryable = retryable
+	}
+}
+
+// New returns a policy reporting its metrics as name. By default every
+// error is retryable.
+func New(name string, cfg Config, opts ...Option) *Policy {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	cfg.Jitter = math.Min(math.Max(cfg.Jitter, 0), 1)
+
+	p := &Policy{name: name, cfg: cfg, retryable: func(error) bool { return true }}
+	if cfg.BudgetRatio > 0 {
+		p.budget = newBudget(name, cfg.BudgetRatio, cfg.BudgetBurst)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Do calls fn until it succeeds, fails with a terminal error, runs out of
+// attempts or budget, or ctx is done. It returns fn's last error.
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := Value(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Value is Do for functions that return a result.
+func Value[T any](ctx context.Context, p *Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	if p.budget != nil {
+		p.budget.deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		value, err := fn(ctx)
+		if err == nil {
+			retryCalls.WithLabelValues(p.name, OutcomeSuccess).Inc()
+			return value, nil
+		}
+
+		outcome := ""
+		switch {
+		case ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			outcome = OutcomeCancelled
+		case IsTerminal(err) || !p.retryable(err):
+			outcome = OutcomeTerminal
+		case attempt >= p.cfg.MaxAttempts:
+			outcome = OutcomeExhausted
+		case p.budget != nil && !p.budget.withdraw():
+			outcome = OutcomeBudget
+		}
+		if outcome != "" {
+			retryCalls.WithLabelValues(p.name, outcome).Inc()
+			return value, err
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			retryCalls.WithLabelValues(p.name, OutcomeCancelled).Inc()
+			return value, err
+		}
+		retryAttempts.WithLabelValues(p.name).Inc()
+	}
+}
+
+// Backoff returns the jittered wait after the given failed attempt,
+// counting from 1.
+func (p *Policy) Backoff(attempt int) time.Duration {
+	wait := float64(p.cfg.InitialBackoff) * math.Pow(p.cfg.Multiplier, float64(attempt-1))
+	wait = math.Min(wait, float64(p.cfg.MaxBackoff))
+	if p.cfg.Jitter > 0 {
+		wait *= 1 + p.cfg.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(wait)
+}
+
+type terminalError struct {
+	err error
+}
+
+func (e terminalError) Error() string { return e.err.Error() }
+func (e terminalError) Unwrap() error { return e.err }
+
+// Terminal marks err as one that retrying cannot fix, such as a rejected
+// request. It stays comparable with errors.Is and errors.As.
+func Terminal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return terminalError{err: err}
+}
+
+// IsTerminal reports whether err was marked with Terminal.
+func IsTerminal(err error) bool {
+	var terminal terminalError
+	return errors.As(err, &terminal)
+}
+
+// budget is a token bucket of retries: every call earns ratio tokens and
+// every retry spends one, so retries stay a bounded share of the traffic.
+// The balance is capped, so a long healthy spell does not bank enough for
+// a storm.
+type budget struct {
+	name  string
+	ratio float64
+	max   float64
+
+	mutex  sync.Mutex
+	tokens float64
+}
+
+func newBudget(name string, ratio float64, burst int) *budget {
+	b := &budget{name: name, ratio: ratio, max: math.Max(float64(burst), 1)}
+	b.tokens = b.max
+	retryBudgetTokens.WithLabelValues(name).Set(b.tokens)
+	return b
+}
+
+func (b *budget) deposit() {
+	b.mutex.Lock()
+	b.tokens = math.Min(b.tokens+b.ratio, b.max)
+	tokens := b.tokens
+	b.mutex.Unlock()
+	retryBudgetTokens.WithLabelValues(b.name).Set(tokens)
+}
+
+func (b *budget) withdraw() bool {
+	b.mutex.Lock()
+	ok := b.tokens >= 1
+	if ok {
+		b.tokens--
+	}
+	tokens := b.tokens
+	b.mutex.Unlock()
+	retryBudgetTokens.WithLabelValues(b.name).Set(tokens)
+	return ok
+}
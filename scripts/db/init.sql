@@ -9,6 +9,9 @@ GRANT ALL PRIVILEGES ON DATABASE tradingbot TO tradingbot;
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Enable trigram matching for global search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 -- Create users table
 CREATE TABLE IF NOT EXISTS users (
                                      id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -18,6 +21,8 @@ CREATE TABLE IF NOT EXISTS users (
     first_name VARCHAR(100),
     last_name VARCHAR(100),
     avatar VARCHAR(500),
+    timezone VARCHAR(64) DEFAULT 'UTC',
+    data_region VARCHAR(64),
     is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
@@ -29,7 +29,7 @@ fi
 export PATH="$PATH:$(go env GOPATH)/bin"
 
 # Create output directory if it doesn't exist
-mkdir -p api/proto/auth
+mkdir -p api/proto/auth api/proto/backtest api/proto/events
 
 # Generate Go files from proto
 echo "Generating Go files from auth.proto..."
@@ -37,11 +37,32 @@ protoc --go_out=. --go_opt=paths=source_relative \
        --go-grpc_out=. --go-grpc_opt=paths=source_relative \
        api/proto/auth/auth.proto
 
+echo "Generating Go files from backtest.proto..."
+protoc --go_out=. --go_opt=paths=source_relative \
+       --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+       api/proto/backtest/backtest.proto
+
+echo "Generating Go files from events.proto..."
+protoc --go_out=. --go_opt=paths=source_relative \
+       api/proto/events/events.proto
+
+# Refresh the event schema table; fails on incompatible schema changes
+echo "Checking event schemas..."
+go generate ./internal/events
+
+# Check the gateway's gRPC contracts and lock additions; fails on
+# breaking service changes
+echo "Checking gRPC contracts..."
+go test ./internal/rpc -run TestContracts -update
+
 # Check if files were generated successfully
-if [[ -f "api/proto/auth/auth.pb.go" && -f "api/proto/auth/auth_grpc.pb.go" ]]; then
+if [[ -f "api/proto/auth/auth.pb.go" && -f "api/proto/auth/auth_grpc.pb.go" && -f "api/proto/backtest/backtest.pb.go" && -f "api/proto/backtest/backtest_grpc.pb.go" && -f "api/proto/events/events.pb.go" ]]; then
     echo "✅ Protobuf files generated successfully:"
     echo "   - api/proto/auth/auth.pb.go"
     echo "   - api/proto/auth/auth_grpc.pb.go"
+    echo "   - api/proto/backtest/backtest.pb.go"
+    echo "   - api/proto/backtest/backtest_grpc.pb.go"
+    echo "   - api/proto/events/events.pb.go"
 else
     echo "❌ Failed to generate protobuf files"
     exit 1
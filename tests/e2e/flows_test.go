@@ -0,0 +1,495 @@
+//go:build integration
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/shopspring/decimal"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/auth"
+	"github.com/tradingbothub/platform/internal/bot"
+	"github.com/tradingbothub/platform/internal/config"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/orders"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// flows run in order; later ones use the account the register flow
+// creates, and the portfolio holds what the OCO flow bought.
+var flows = []struct {
+	name string
+	run  func(s *suite, ctx context.Context) error
+}{
+	{"health", (*suite).health},
+	{"register", (*suite).register},
+	{"unauthorized", (*suite).unauthorized},
+	{"login", (*suite).login},
+	{"login/wrong-password", (*suite).wrongPassword},
+	{"profile", (*suite).profile},
+	{"bots/lifecycle", (*suite).botLifecycle},
+	{"orders/paper-oco", (*suite).paperOCO},
+	{"orders/paper-oco-cancel", (*suite).paperOCOCancel},
+	{"portfolio", (*suite).portfolio},
+	{"auth/refresh", (*suite).refresh},
+}
+
+func TestFlows(t *testing.T) {
+	for _, flow := range flows {
+		t.Run(flow.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := flow.run(s, ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// suite talks to the gateway over HTTP and checks side effects directly
+// in postgres and on NATS.
+type suite struct {
+	gateway string
+	client  *http.Client
+	db      *gorm.DB
+	nc      *nats.Conn
+	candles *marketdata.InfluxStore
+
+	email        string
+	password     string
+	userID       string
+	accessToken  string
+	refreshToken string
+}
+
+func newSuite(gateway, databaseURL, natsURL, influxURL, influxToken string) (*suite, error) {
+	db, err := database.Connect(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	// A fresh account per run, so the suite can run against a stack that
+	// is kept between runs
+	id := uuid.New().String()[:8]
+	return &suite{
+		gateway: gateway,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		db:      db,
+		nc:      nc,
+		candles: marketdata.NewInfluxStore(config.InfluxConfig{
+			URL:    influxURL,
+			Token:  influxToken,
+			Org:    "tradingbothub",
+			Bucket: "market_data",
+		}),
+		email:    "e2e-" + id + "@example.com",
+		password: "E2e-password-" + id,
+	}, nil
+}
+
+func (s *suite) close() {
+	s.candles.Close()
+	s.nc.Close()
+	if sqlDB, err := s.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// call sends body as JSON with the bearer token, if any, and decodes the
+// response into out, if given. It returns the status code.
+func (s *suite) call(ctx context.Context, method, path, token string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.gateway+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("%s %s: invalid response %q: %w", method, path, data, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// expect calls the gateway and fails unless it answers with want.
+func (s *suite) expect(ctx context.Context, want int, method, path, token string, body, out any) error {
+	code, err := s.call(ctx, method, path, token, body, out)
+	if err != nil {
+		return err
+	}
+	if code != want {
+		return fmt.Errorf("%s %s: status %d, want %d", method, path, code, want)
+	}
+	return nil
+}
+
+// await subscribes to an event before trigger runs and waits for one that
+// match accepts.
+func await[T proto.Message](ctx context.Context, s *suite, newEvent func() T, match func(T) bool, trigger func() error) error {
+	received := make(chan struct{}, 1)
+	sub, err := events.Subscribe(s.nc, newEvent, func(_ *eventspb.Envelope, event T) {
+		if match(event) {
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	if err := s.nc.Flush(); err != nil {
+		return err
+	}
+
+	if err := trigger(); err != nil {
+		return err
+	}
+	select {
+	case <-received:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no %T event received", newEvent())
+	}
+}
+
+// eventually retries check for a few seconds, for side effects written
+// after the response.
+func eventually(ctx context.Context, what string, check func() error) error {
+	return waitFor(ctx, what, 10*time.Second, check)
+}
+
+type authResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+func (s *suite) health(ctx context.Context) error {
+	return s.expect(ctx, http.StatusOK, http.MethodGet, "/health", "", nil, nil)
+}
+
+func (s *suite) register(ctx context.Context) error {
+	var resp authResponse
+	if err := s.expect(ctx, http.StatusCreated, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email":      s.email,
+		"username":   s.email[:12],
+		"password":   s.password,
+		"first_name": "End",
+		"last_name":  "ToEnd",
+	}, &resp); err != nil {
+		return err
+	}
+	if resp.AccessToken == "" || resp.User.ID == "" {
+		return errors.New("register returned no token or user")
+	}
+	s.userID, s.accessToken, s.refreshToken = resp.User.ID, resp.AccessToken, resp.RefreshToken
+
+	var user auth.User
+	if err := s.db.WithContext(ctx).Where("email = ?", s.email).First(&user).Error; err != nil {
+		return fmt.Errorf("user not stored: %w", err)
+	}
+	var sessions int64
+	if err := s.db.WithContext(ctx).Model(&auth.Session{}).Where("user_id = ?", s.userID).Count(&sessions).Error; err != nil {
+		return err
+	}
+	if sessions != 1 {
+		return fmt.Errorf("%d sessions after register, want 1", sessions)
+	}
+	return nil
+}
+
+func (s *suite) unauthorized(ctx context.Context) error {
+	if err := s.expect(ctx, http.StatusUnauthorized, http.MethodGet, "/api/v1/user/profile", "", nil, nil); err != nil {
+		return err
+	}
+	return s.expect(ctx, http.StatusUnauthorized, http.MethodGet, "/api/v1/user/profile", "not-a-token", nil, nil)
+}
+
+func (s *suite) login(ctx context.Context) error {
+	var resp authResponse
+	if err := s.expect(ctx, http.StatusOK, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    s.email,
+		"password": s.password,
+	}, &resp); err != nil {
+		return err
+	}
+	s.accessToken, s.refreshToken = resp.AccessToken, resp.RefreshToken
+
+	return eventually(ctx, "login audit event", func() error {
+		var count int64
+		err := s.db.WithContext(ctx).Model(&auth.AuditEvent{}).
+			Where("user_id = ? AND type = ?", s.userID, auth.AuditLoginSucceeded).Count(&count).Error
+		if err == nil && count == 0 {
+			err = errors.New("not recorded")
+		}
+		return err
+	})
+}
+
+func (s *suite) wrongPassword(ctx context.Context) error {
+	return await(ctx, s, func() *eventspb.LoginFailed { return new(eventspb.LoginFailed) }, func(event *eventspb.LoginFailed) bool {
+		return event.Email == s.email
+	}, func() error {
+		return s.expect(ctx, http.StatusUnauthorized, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+			"email":    s.email,
+			"password": "wrong-" + s.password,
+		}, nil)
+	})
+}
+
+// profile checks the gateway resolved the token to the right user.
+func (s *suite) profile(ctx context.Context) error {
+	var profile struct {
+		UserID string `json:"user_id"`
+	}
+	if err := s.expect(ctx, http.StatusOK, http.MethodGet, "/api/v1/user/profile", s.accessToken, nil, &profile); err != nil {
+		return err
+	}
+	if profile.UserID != s.userID {
+		return fmt.Errorf("profile is for %q, want %q", profile.UserID, s.userID)
+	}
+	return nil
+}
+
+// botLifecycle creates a bot, then starts and stops it.
+func (s *suite) botLifecycle(ctx context.Context) error {
+	var b bot.Bot
+	if err := s.expect(ctx, http.StatusCreated, http.MethodPost, "/api/v1/bots", s.accessToken, map[string]any{
+		"name":     "e2e",
+		"exchange": "binance",
+		"symbol":   "BTCUSDT",
+		"config": map[string]any{
+			"type":        "sma_cross",
+			"interval":    "1m",
+			"quantity":    "0.001",
+			"fast_period": 5,
+			"slow_period": 20,
+		},
+		"capital": "1000",
+	}, &b); err != nil {
+		return err
+	}
+
+	var created bot.Bot
+	if err := s.db.WithContext(ctx).First(&created, "id = ? AND user_id = ?", b.ID, s.userID).Error; err != nil {
+		return fmt.Errorf("bot not stored: %w", err)
+	}
+	if created.Status != bot.StatusStopped || created.Strategy != "sma_cross" || created.Config.SlowPeriod != 20 {
+		return fmt.Errorf("stored bot is %s %s with slow period %d", created.Status, created.Strategy, created.Config.SlowPeriod)
+	}
+
+	for _, step := range []struct{ action, status string }{
+		{"start", bot.StatusRunning},
+		{"stop", bot.StatusStopped},
+	} {
+		err := await(ctx, s, func() *eventspb.BotStatusChanged { return new(eventspb.BotStatusChanged) }, func(event *eventspb.BotStatusChanged) bool {
+			return event.BotId == b.ID && event.Status == step.status
+		}, func() error {
+			return s.expect(ctx, http.StatusOK, http.MethodPost, "/api/v1/bots/"+b.ID+"/"+step.action, s.accessToken, nil, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.action, err)
+		}
+
+		var stored bot.Bot
+		if err := s.db.WithContext(ctx).First(&stored, "id = ?", b.ID).Error; err != nil {
+			return err
+		}
+		if stored.Status != step.status {
+			return fmt.Errorf("%s: bot is %s, want %s", step.action, stored.Status, step.status)
+		}
+	}
+	return nil
+}
+
+// paperOCO marks the market at 100 and places a one-cancels-other pair
+// whose buy leg rests at that price, so the paper exchange fills it on
+// its next mark. The group then cancels the sell leg and completes, and
+// the fill is recorded as a trade.
+func (s *suite) paperOCO(ctx context.Context) error {
+	if err := s.candles.WriteCandles(ctx, []marketdata.SeriesCandle{{
+		Exchange: "binance",
+		Symbol:   "BTCUSDT",
+		Interval: "1m",
+		Candle:   marketdata.Candle{Time: time.Now().Truncate(time.Minute), Open: 100, High: 100, Low: 100, Close: 100, Volume: 1},
+	}}); err != nil {
+		return err
+	}
+
+	var group orders.Group
+	if err := s.expect(ctx, http.StatusCreated, http.MethodPost, "/api/v1/orders/groups", s.accessToken, map[string]any{
+		"type":     orders.GroupOCO,
+		"exchange": "binance",
+		"symbol":   "BTCUSDT",
+		"legs": []map[string]string{
+			{"side": "sell", "type": "limit", "price": "1000000", "quantity": "0.001"},
+			{"side": "buy", "type": "limit", "price": "100", "quantity": "0.1"},
+		},
+	}, &group); err != nil {
+		return err
+	}
+
+	err := eventually(ctx, "group completion", func() error {
+		if err := s.expect(ctx, http.StatusOK, http.MethodGet, "/api/v1/orders/groups/"+group.ID, s.accessToken, nil, &group); err != nil {
+			return err
+		}
+		if group.Status != orders.GroupCompleted {
+			return fmt.Errorf("group is %s", group.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, leg := range group.Legs {
+		want := orders.LegCancelled
+		if leg.Side == exchange.SideBuy {
+			want = orders.LegFilled
+		}
+		if leg.Status != want {
+			return fmt.Errorf("%s leg is %s, want %s", leg.Side, leg.Status, want)
+		}
+	}
+
+	return eventually(ctx, "recorded fill", func() error {
+		var trades []orders.Trade
+		if err := s.db.WithContext(ctx).Where("user_id = ? AND symbol = ?", s.userID, "BTCUSDT").Find(&trades).Error; err != nil {
+			return err
+		}
+		if len(trades) != 1 {
+			return fmt.Errorf("%d trades recorded, want 1", len(trades))
+		}
+		if trade := trades[0]; trade.Side != "buy" || !trade.Price.Equal(decimal.NewFromInt(100)) || !trade.Quantity.Equal(decimal.RequireFromString("0.1")) {
+			return fmt.Errorf("recorded %s %s at %s", trade.Side, trade.Quantity, trade.Price)
+		}
+		return nil
+	})
+}
+
+// paperOCOCancel places a pair far enough from the market that neither
+// fills, and cancels it.
+func (s *suite) paperOCOCancel(ctx context.Context) error {
+	var group orders.Group
+	if err := s.expect(ctx, http.StatusCreated, http.MethodPost, "/api/v1/orders/groups", s.accessToken, map[string]any{
+		"type":     orders.GroupOCO,
+		"exchange": "binance",
+		"symbol":   "BTCUSDT",
+		"legs": []map[string]string{
+			{"side": "sell", "type": "limit", "price": "1000000", "quantity": "0.001"},
+			{"side": "buy", "type": "limit", "price": "10", "quantity": "1"},
+		},
+	}, &group); err != nil {
+		return err
+	}
+
+	var stored orders.Group
+	if err := s.db.WithContext(ctx).Preload("Legs").First(&stored, "id = ? AND user_id = ?", group.ID, s.userID).Error; err != nil {
+		return fmt.Errorf("group not stored: %w", err)
+	}
+	if len(stored.Legs) != 2 {
+		return fmt.Errorf("group has %d legs, want 2", len(stored.Legs))
+	}
+
+	code, err := s.call(ctx, http.MethodDelete, "/api/v1/orders/groups/"+group.ID, s.accessToken, nil, &group)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK && code != http.StatusAccepted {
+		return fmt.Errorf("cancel: status %d", code)
+	}
+	return eventually(ctx, "group cancellation", func() error {
+		if err := s.db.WithContext(ctx).First(&stored, "id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		if stored.Status != orders.GroupCancelled {
+			return fmt.Errorf("group is %s", stored.Status)
+		}
+		return nil
+	})
+}
+
+// portfolio checks the OCO flow's purchase is held: 0.1 BTCUSDT bought
+// for 10.
+func (s *suite) portfolio(ctx context.Context) error {
+	return eventually(ctx, "portfolio holding", func() error {
+		var portfolio orders.Portfolio
+		if err := s.expect(ctx, http.StatusOK, http.MethodGet, "/api/v1/portfolio", s.accessToken, nil, &portfolio); err != nil {
+			return err
+		}
+		if len(portfolio.Holdings) != 1 {
+			return fmt.Errorf("%d holdings, want 1", len(portfolio.Holdings))
+		}
+		holding := portfolio.Holdings[0]
+		if holding.Exchange != "binance" || holding.Symbol != "BTCUSDT" || !holding.Quantity.Equal(decimal.RequireFromString("0.1")) || holding.Trades != 1 {
+			return fmt.Errorf("holding is %s %s of %s over %d trades", holding.Quantity, holding.Symbol, holding.Exchange, holding.Trades)
+		}
+		if !holding.Cash.Equal(decimal.NewFromInt(-10)) || !portfolio.Cash.Equal(holding.Cash) {
+			return fmt.Errorf("cash is %s, portfolio cash %s, want -10", holding.Cash, portfolio.Cash)
+		}
+		return nil
+	})
+}
+
+// refresh rotates the refresh token; the old one must not work again.
+func (s *suite) refresh(ctx context.Context) error {
+	var resp authResponse
+	if err := s.expect(ctx, http.StatusOK, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": s.refreshToken,
+	}, &resp); err != nil {
+		return err
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == s.refreshToken {
+		return errors.New("refresh token was not rotated")
+	}
+
+	if err := s.expect(ctx, http.StatusUnauthorized, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": s.refreshToken,
+	}, nil); err != nil {
+		return fmt.Errorf("reusing the old refresh token: %w", err)
+	}
+	s.refreshToken = resp.RefreshToken
+	return nil
+}
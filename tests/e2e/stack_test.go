@@ -0,0 +1,200 @@
+//go:build integration
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/tradingbothub/platform/internal/database"
+)
+
+// composeProject keeps the suite's containers and volumes apart from the
+// development stack's.
+const composeProject = "stellarium-e2e"
+
+// services are started in order; each later one depends on the earlier
+var services = []string{"auth-service", "paper-exchange", "api-gateway"}
+
+// stackConfig is merged over the defaults of every service. Bots may be
+// created without a verified email, since the suite cannot read the
+// verification link, and one paper exchange is enough. It marks prices
+// every second so resting orders fill soon after the suite writes a
+// candle.
+const stackConfig = `
+influxdb:
+  token: "tradingbot-influx-token"
+paper_exchange:
+  price_interval: "1s"
+auth:
+  email_verification:
+    required: false
+  lockout:
+    max_failures: 100
+trading:
+  mode: "paper"
+exchanges:
+  binance:
+    symbols: ["BTCUSDT"]
+    precision:
+      BTCUSDT: { tick_size: "0.01", step_size: "0.00001", min_notional: "5" }
+    connectors:
+      - region: "local"
+        address: "localhost:9101"
+    testnet_connectors:
+      - region: "local"
+        address: "localhost:9101"
+`
+
+type stack struct {
+	dir   string
+	procs []*exec.Cmd
+}
+
+// startStack brings up the infrastructure, waits for postgres, then builds
+// and starts the services from a scratch directory holding their config
+// and logs.
+func startStack(ctx context.Context, gateway, databaseURL string) (*stack, error) {
+	// Tests run in their package's directory
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "stellarium-e2e-")
+	if err != nil {
+		return nil, err
+	}
+	s := &stack{dir: dir}
+	log.Printf("Stack logs and binaries are in %s", dir)
+
+	compose := exec.CommandContext(ctx, "docker", "compose", "-p", composeProject, "up", "-d", "--wait",
+		"postgres", "redis", "nats", "influxdb")
+	compose.Dir = root
+	compose.Stdout, compose.Stderr = os.Stdout, os.Stderr
+	if err := compose.Run(); err != nil {
+		return nil, fmt.Errorf("docker compose up: %w", err)
+	}
+
+	if err := waitFor(ctx, "postgres", 60*time.Second, func() error {
+		db, err := database.Connect(databaseURL)
+		if err != nil {
+			return err
+		}
+		sqlDB, _ := db.DB()
+		return sqlDB.Close()
+	}); err != nil {
+		s.stop(false)
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "configs"), 0o755); err != nil {
+		s.stop(false)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "configs", "config.yaml"), []byte(stackConfig), 0o644); err != nil {
+		s.stop(false)
+		return nil, err
+	}
+
+	for _, service := range services {
+		if err := s.start(ctx, root, service); err != nil {
+			s.stop(false)
+			return nil, fmt.Errorf("failed to start %s: %w", service, err)
+		}
+	}
+
+	if err := waitFor(ctx, "gateway", 90*time.Second, func() error {
+		resp, err := http.Get(gateway + "/health")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("health is %d", resp.StatusCode)
+		}
+		return nil
+	}); err != nil {
+		s.stop(false)
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *stack) start(ctx context.Context, root, service string) error {
+	binary := filepath.Join(s.dir, service)
+	build := exec.CommandContext(ctx, "go", "build", "-o", binary, "./cmd/"+service)
+	build.Dir = root
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+
+	logFile, err := os.Create(filepath.Join(s.dir, service+".log"))
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(binary)
+	cmd.Dir = s.dir
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return err
+	}
+	s.procs = append(s.procs, cmd)
+	return nil
+}
+
+// stop shuts the services down in reverse order, then removes the
+// containers and their volumes unless keep is set.
+func (s *stack) stop(keep bool) {
+	for i := len(s.procs) - 1; i >= 0; i-- {
+		cmd := s.procs[i]
+		cmd.Process.Signal(syscall.SIGTERM)
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+	}
+
+	if keep {
+		log.Printf("Leaving compose project %s running", composeProject)
+		return
+	}
+	down := exec.Command("docker", "compose", "-p", composeProject, "down", "-v")
+	down.Stdout, down.Stderr = os.Stdout, os.Stderr
+	if err := down.Run(); err != nil {
+		log.Printf("Failed to stop compose project %s: %v", composeProject, err)
+	}
+}
+
+// waitFor polls check until it succeeds or timeout passes.
+func waitFor(ctx context.Context, what string, timeout time.Duration, check func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	for {
+		if err = check(); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %s: %w", what, timeout, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
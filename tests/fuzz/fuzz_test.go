@@ -0,0 +1,247 @@
+// Package fuzz holds fuzz targets for the parsers that read untrusted
+// input: strategy configs submitted with bots, event envelopes off NATS,
+// binary candles, intervals and indicator lists from query strings, job
+// schedules and market precision. go test replays each target's seeds and
+// its corpus under testdata/fuzz; go test -fuzz mutates them. Commit a
+// failing input go test -fuzz writes to the corpus with its fix, so it is
+// replayed from then on.
+//
+//	go test ./tests/fuzz
+//	go test ./tests/fuzz -run '^$' -fuzz '^FuzzStrategyConfig$' -fuzztime 5m
+package fuzz
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	eventspb "github.com/tradingbothub/platform/api/proto/events"
+	"github.com/tradingbothub/platform/internal/events"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/scheduler"
+	"github.com/tradingbothub/platform/internal/strategy"
+	"github.com/tradingbothub/platform/pkg/money"
+)
+
+func FuzzStrategyConfig(f *testing.F) {
+	fuzz(f, checkStrategyConfig,
+		[]byte(`{"type":"sma_cross","interval":"1m","quantity":"0.5","fast_period":5,"slow_period":20}`),
+		[]byte(`{"type":"rsi","interval":"4h","quantity":"2","rsi_period":14,"oversold":30,"overbought":70}`),
+	)
+}
+
+func FuzzEnvelope(f *testing.F) {
+	fuzz(f, checkEnvelope, mustEncode(f, &eventspb.BotStatusChanged{BotId: "bot-1", UserId: "user-1", Status: "running"}))
+}
+
+func FuzzCandles(f *testing.F) {
+	fuzz(f, checkCandles, candleSeed())
+}
+
+func FuzzInterval(f *testing.F) {
+	fuzz(f, checkInterval, []byte("1m"), []byte("15m"), []byte("4h"), []byte("1d"), []byte("1w"))
+}
+
+func FuzzIndicators(f *testing.F) {
+	fuzz(f, checkIndicators, []byte("sma:20,ema:50,rsi:14"), []byte(" sma:5"))
+}
+
+func FuzzSchedule(f *testing.F) {
+	fuzz(f, checkSchedule,
+		[]byte("*/15 9-17 * * 1-5"), []byte("0 0 29 2 *"), []byte("@every 90s"), []byte("@weekly"), []byte("5,35 */2 1,15 * 0"),
+	)
+}
+
+func FuzzPrecision(f *testing.F) {
+	fuzz(f, checkPrecision, []byte("0.01\n0.0001\n10"), []byte("0.5\n\n"), []byte("\n1\n0"))
+}
+
+// fuzz seeds the target and fails an input that breaks one of the
+// parser's invariants, as check reports them; inputs the parser refuses
+// are fine.
+func fuzz(f *testing.F, check func(data []byte) error, seeds ...[]byte) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := check(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// checkStrategyConfig feeds a bot's strategy config, as users submit it
+// and as it is read back from the database, through a stream of candles.
+// A config that validates must run.
+func checkStrategyConfig(data []byte) error {
+	var cfg strategy.Config
+	if err := cfg.Scan(data); err != nil {
+		return nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil
+	}
+	stream, err := strategy.NewStream(cfg)
+	if err != nil {
+		return fmt.Errorf("config validates but the stream refuses it: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < cfg.Warmup()+8; i++ {
+		price := 100 + 10*math.Sin(float64(i)/3)
+		signal, ok := stream.Update(marketdata.Candle{Time: start.Add(time.Duration(i) * time.Minute), Close: price})
+		if ok && signal.Price != price {
+			return fmt.Errorf("signal at %v is priced %v, the close was %v", signal.Time, signal.Price, price)
+		}
+	}
+	return nil
+}
+
+// checkEnvelope decodes NATS messages as the gateway's stream and the bot
+// consumers do.
+func checkEnvelope(data []byte) error {
+	var event eventspb.BotStatusChanged
+	envelope, err := events.Decode(data, &event)
+	if err != nil {
+		return nil
+	}
+	schema, err := events.Lookup(&event)
+	if err != nil {
+		return err
+	}
+	if envelope.Type != schema.Type {
+		return fmt.Errorf("decoded a %s envelope as %s", envelope.Type, schema.Type)
+	}
+	return nil
+}
+
+// checkCandles decodes the binary candle encoding clients and caches send;
+// whatever decodes must survive encoding again unchanged.
+func checkCandles(data []byte) error {
+	candles, err := marketdata.DecodeCandles(data)
+	if err != nil {
+		return nil
+	}
+	again, err := marketdata.DecodeCandles(marketdata.EncodeCandles(candles))
+	if err != nil {
+		return fmt.Errorf("re-encoded %d candles do not decode: %v", len(candles), err)
+	}
+	if len(again) != len(candles) {
+		return fmt.Errorf("%d candles re-encoded as %d", len(candles), len(again))
+	}
+	for i := range candles {
+		a, b := candles[i], again[i]
+		same := a.Time.Equal(b.Time) &&
+			math.Float64bits(a.Open) == math.Float64bits(b.Open) &&
+			math.Float64bits(a.High) == math.Float64bits(b.High) &&
+			math.Float64bits(a.Low) == math.Float64bits(b.Low) &&
+			math.Float64bits(a.Close) == math.Float64bits(b.Close) &&
+			math.Float64bits(a.Volume) == math.Float64bits(b.Volume)
+		if !same {
+			return fmt.Errorf("candle %d is %+v, re-encoded %+v", i, a, b)
+		}
+	}
+	return nil
+}
+
+// checkInterval parses intervals from query strings and strategy configs;
+// an accepted interval must be a positive width.
+func checkInterval(data []byte) error {
+	interval, err := marketdata.ParseInterval(string(data))
+	if err != nil {
+		return nil
+	}
+	if interval.Duration <= 0 || interval.Days < 0 {
+		return fmt.Errorf("%q parses as %v and %d days", data, interval.Duration, interval.Days)
+	}
+	return nil
+}
+
+// checkIndicators parses chart overlays from query strings; a parsed list
+// must read the same when written back.
+func checkIndicators(data []byte) error {
+	indicators, err := marketdata.ParseIndicators(string(data))
+	if err != nil || len(indicators) == 0 {
+		return nil
+	}
+	specs := make([]string, len(indicators))
+	for i, indicator := range indicators {
+		specs[i] = indicator.String()
+	}
+	again, err := marketdata.ParseIndicators(strings.Join(specs, ","))
+	if err != nil {
+		return fmt.Errorf("%q parses but its canonical form %q does not: %v", data, strings.Join(specs, ","), err)
+	}
+	if !reflect.DeepEqual(indicators, again) {
+		return fmt.Errorf("%q parses as %v, its canonical form as %v", data, indicators, again)
+	}
+	return nil
+}
+
+var scheduleFrom = time.Date(2024, 2, 28, 23, 59, 30, 0, time.UTC)
+
+// checkSchedule parses users' job schedules; the next activation is never
+// at or before the time asked about.
+func checkSchedule(data []byte) error {
+	schedule, err := scheduler.ParseSchedule(string(data))
+	if err != nil {
+		return nil
+	}
+	if next := schedule.Next(scheduleFrom); !next.IsZero() && !next.After(scheduleFrom) {
+		return fmt.Errorf("%q activates at %v, asked after %v", data, next, scheduleFrom)
+	}
+	return nil
+}
+
+// checkPrecision parses a market's tick size, step size and minimum
+// notional, one per line, and rounds an order with them. Rounding never
+// trades worse than asked and lands on the grid.
+func checkPrecision(data []byte) error {
+	fields := append(strings.SplitN(string(data), "\n", 3), "", "")
+	p, err := money.ParsePrecision(fields[0], fields[1], fields[2])
+	if err != nil {
+		return nil
+	}
+
+	price := decimal.RequireFromString("1234.56789")
+	quantity := decimal.RequireFromString("0.987654321")
+	if buy := p.RoundPrice(price, true); buy.GreaterThan(price) {
+		return fmt.Errorf("tick %s rounds a buy at %s up to %s", p.TickSize, price, buy)
+	}
+	if sell := p.RoundPrice(price, false); sell.LessThan(price) {
+		return fmt.Errorf("tick %s rounds a sell at %s down to %s", p.TickSize, price, sell)
+	}
+	rounded := p.RoundQuantity(quantity)
+	if rounded.GreaterThan(quantity) || rounded.IsNegative() {
+		return fmt.Errorf("step %s rounds %s to %s", p.StepSize, quantity, rounded)
+	}
+	if p.StepSize.IsPositive() && !rounded.Mod(p.StepSize).IsZero() {
+		return fmt.Errorf("step %s rounds %s off the grid to %s", p.StepSize, quantity, rounded)
+	}
+	_ = p.Check(price, rounded)
+	return nil
+}
+
+func mustEncode(f *testing.F, event *eventspb.BotStatusChanged) []byte {
+	_, data, err := events.Encode("fuzz", event)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return data
+}
+
+func candleSeed() []byte {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]marketdata.Candle, 3)
+	for i := range candles {
+		candles[i] = marketdata.Candle{
+			Time: start.Add(time.Duration(i) * time.Minute),
+			Open: 100, High: 101.5, Low: 99.25, Close: 100.75, Volume: 12.5,
+		}
+	}
+	return marketdata.EncodeCandles(candles)
+}
@@ -0,0 +1,166 @@
+package golden
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+// datasetFile is a day of one-minute candles that every case runs over.
+// The golden outputs depend on it byte for byte, so it is never edited;
+// new data goes into a new file with its own golden outputs.
+const datasetFile = "candles.csv"
+
+const timeFormat = "2006-01-02 15:04"
+
+// warmup is the number of candles before the backtest window, so signals
+// from the first candles of the window are exercised too.
+const warmup = 120
+
+// goldenCase is one backtest whose output is checked.
+type goldenCase struct {
+	name  string
+	cfg   strategy.Config
+	fills backtest.FillModel
+	seed  int64
+}
+
+// cases cover both strategies, with and without slippage, and seeds that
+// draw different slippage for the same signals. Names are keys in the
+// golden file; renaming one needs -update.
+var cases = []goldenCase{
+	{
+		name: "sma_cross/5-20",
+		cfg:  strategy.Config{Type: strategy.TypeSMACross, Interval: "1m", Quantity: decimal.RequireFromString("0.5"), FastPeriod: 5, SlowPeriod: 20},
+		seed: 1,
+	},
+	{
+		name:  "sma_cross/5-20/slippage",
+		cfg:   strategy.Config{Type: strategy.TypeSMACross, Interval: "1m", Quantity: decimal.RequireFromString("0.5"), FastPeriod: 5, SlowPeriod: 20},
+		fills: backtest.FillModel{SlippageBps: 10},
+		seed:  1,
+	},
+	{
+		name:  "sma_cross/5-20/slippage-seed7",
+		cfg:   strategy.Config{Type: strategy.TypeSMACross, Interval: "1m", Quantity: decimal.RequireFromString("0.5"), FastPeriod: 5, SlowPeriod: 20},
+		fills: backtest.FillModel{SlippageBps: 10},
+		seed:  7,
+	},
+	{
+		name:  "sma_cross/12-26",
+		cfg:   strategy.Config{Type: strategy.TypeSMACross, Interval: "1m", Quantity: decimal.NewFromInt(1), FastPeriod: 12, SlowPeriod: 26},
+		fills: backtest.FillModel{SlippageBps: 5},
+		seed:  1,
+	},
+	{
+		name: "rsi/14-35-65",
+		cfg:  strategy.Config{Type: strategy.TypeRSI, Interval: "1m", Quantity: decimal.NewFromInt(2), RSIPeriod: 14, Oversold: 35, Overbought: 65},
+		seed: 1,
+	},
+	{
+		name:  "rsi/14-35-65/slippage",
+		cfg:   strategy.Config{Type: strategy.TypeRSI, Interval: "1m", Quantity: decimal.NewFromInt(2), RSIPeriod: 14, Oversold: 35, Overbought: 65},
+		fills: backtest.FillModel{SlippageBps: 25},
+		seed:  3,
+	},
+	{
+		name:  "rsi/7-25-75",
+		cfg:   strategy.Config{Type: strategy.TypeRSI, Interval: "1m", Quantity: decimal.RequireFromString("0.1"), RSIPeriod: 7, Oversold: 25, Overbought: 75},
+		fills: backtest.FillModel{SlippageBps: 10},
+		seed:  42,
+	},
+}
+
+// run backtests the case over candles, after the warm-up candles.
+func (c goldenCase) run(candles []marketdata.Candle) (*outcome, error) {
+	if len(candles) <= warmup {
+		return nil, fmt.Errorf("dataset has %d candles, need more than %d", len(candles), warmup)
+	}
+	drawdown := &drawdown{}
+	result, err := backtest.Simulate(c.cfg, candles, candles[warmup].Time, c.fills, c.seed, drawdown)
+	if err != nil {
+		return nil, err
+	}
+	return &outcome{
+		Name:        c.name,
+		RoundTrips:  result.RoundTrips,
+		Wins:        result.Wins,
+		PnL:         result.PnL,
+		MaxDrawdown: drawdown.max,
+		Fills:       result.Fills,
+	}, nil
+}
+
+// drawdown follows the equity curve for its largest fall from a high.
+type drawdown struct {
+	peak decimal.Decimal
+	max  decimal.Decimal
+}
+
+func (d *drawdown) Fill(fill backtest.Fill) error {
+	return nil
+}
+
+func (d *drawdown) Equity(point backtest.EquityPoint) error {
+	if point.Equity.GreaterThan(d.peak) {
+		d.peak = point.Equity
+	}
+	if fall := d.peak.Sub(point.Equity); fall.GreaterThan(d.max) {
+		d.max = fall
+	}
+	return nil
+}
+
+func (d *drawdown) Progress(done, total int) error {
+	return nil
+}
+
+// loadCandles reads a CSV of time, open, high, low, close and volume with
+// a header row.
+func loadCandles(path string) ([]marketdata.Candle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 6
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	candles := make([]marketdata.Candle, 0, len(records)-1)
+	for i, record := range records[1:] {
+		t, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		var values [5]float64
+		for j := range values {
+			if values[j], err = strconv.ParseFloat(record[j+1], 64); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+			}
+		}
+		candles = append(candles, marketdata.Candle{
+			Time:   t,
+			Open:   values[0],
+			High:   values[1],
+			Low:    values[2],
+			Close:  values[3],
+			Volume: values[4],
+		})
+	}
+	return candles, nil
+}
@@ -0,0 +1,190 @@
+// Package golden backtests a fixed set of strategy configs over a recorded
+// day of candles and compares every run with its canonical output in
+// testdata/golden.json: each fill, the round trips and wins, the PnL and
+// the largest drawdown of the equity curve. Fill times and sides must
+// match exactly; prices, PnL and drawdown may drift within the tolerances
+// below. Any other difference is a change in results, so refactors of the
+// backtester must leave this passing. A change of results that is
+// intended is recorded by running with -update and committing the diff.
+//
+//	go test ./tests/golden
+//	go test ./tests/golden -run TestGolden/rsi -update
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/backtest"
+)
+
+var update = flag.Bool("update", false, "write the outputs of the cases run as the new golden outputs")
+
+var (
+	// priceTolerance covers float rounding of candle prices and slippage
+	priceTolerance = decimal.New(1, -8)
+	// pnlTolerance is in quote currency over a whole run
+	pnlTolerance = decimal.New(1, -6)
+)
+
+// maxDiffs caps the differences reported for one case.
+const maxDiffs = 10
+
+// goldenFile is the canonical output of every case, in the order of cases.
+type goldenFile struct {
+	Dataset string    `json:"dataset"`
+	Cases   []outcome `json:"cases"`
+}
+
+func TestGolden(t *testing.T) {
+	candles, err := loadCandles(filepath.Join("testdata", datasetFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldenPath := filepath.Join("testdata", "golden.json")
+	golden, err := loadGolden(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := make(map[string]outcome, len(golden.Cases))
+	for _, o := range golden.Cases {
+		stored[o.Name] = o
+	}
+	defined := make(map[string]bool, len(cases))
+	ran := make(map[string]*outcome, len(cases))
+
+	for _, c := range cases {
+		defined[c.name] = true
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.run(candles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ran[c.name] = got
+			if *update {
+				t.Logf("wrote %d fills, PnL %s", len(got.Fills), got.PnL)
+				return
+			}
+			want, ok := stored[c.name]
+			if !ok {
+				t.Fatal("no golden output; run with -update")
+			}
+			if diffs := compare(&want, got); len(diffs) > 0 {
+				t.Errorf("differs from the golden output:\n\t%s", strings.Join(diffs, "\n\t"))
+			}
+		})
+	}
+
+	if *update {
+		// Cases filtered out by -run keep their stored output
+		updated := goldenFile{Dataset: datasetFile}
+		for _, c := range cases {
+			if got, ok := ran[c.name]; ok {
+				updated.Cases = append(updated.Cases, *got)
+			} else if want, ok := stored[c.name]; ok {
+				updated.Cases = append(updated.Cases, want)
+			}
+		}
+		if err := writeGolden(goldenPath, &updated); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// A golden output without a case means a case was dropped by accident
+	// or renamed; -update removes it
+	for _, o := range golden.Cases {
+		if !defined[o.Name] {
+			t.Errorf("%s: golden output of a case that no longer exists", o.Name)
+		}
+	}
+}
+
+func loadGolden(path string) (*goldenFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &goldenFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var golden goldenFile
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if golden.Dataset != datasetFile {
+		return nil, fmt.Errorf("%s was recorded on %s, not %s", path, golden.Dataset, datasetFile)
+	}
+	return &golden, nil
+}
+
+func writeGolden(path string, golden *goldenFile) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(golden); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// compare lists how got differs from want beyond the tolerances.
+func compare(want, got *outcome) []string {
+	var diffs []string
+	add := func(format string, args ...any) {
+		if len(diffs) < maxDiffs {
+			diffs = append(diffs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	if got.RoundTrips != want.RoundTrips || got.Wins != want.Wins {
+		add("%d round trips with %d wins, golden has %d with %d", got.RoundTrips, got.Wins, want.RoundTrips, want.Wins)
+	}
+	if !within(got.PnL, want.PnL, pnlTolerance) {
+		add("PnL %s, golden has %s", got.PnL, want.PnL)
+	}
+	if !within(got.MaxDrawdown, want.MaxDrawdown, pnlTolerance) {
+		add("max drawdown %s, golden has %s", got.MaxDrawdown, want.MaxDrawdown)
+	}
+	if len(got.Fills) != len(want.Fills) {
+		add("%d fills, golden has %d", len(got.Fills), len(want.Fills))
+	}
+	for i := 0; i < len(got.Fills) && i < len(want.Fills); i++ {
+		g, w := got.Fills[i], want.Fills[i]
+		switch {
+		case !g.Time.Equal(w.Time) || g.Side != w.Side || g.Type != w.Type:
+			add("fill %d is a %s %s at %s, golden has a %s %s at %s",
+				i, g.Type, g.Side, g.Time.Format(timeFormat), w.Type, w.Side, w.Time.Format(timeFormat))
+		case !within(g.Price, w.Price, priceTolerance) || !within(g.FillPrice, w.FillPrice, priceTolerance):
+			add("fill %d priced %s filled at %s, golden has %s filled at %s", i, g.Price, g.FillPrice, w.Price, w.FillPrice)
+		case !g.Quantity.Equal(w.Quantity):
+			add("fill %d is for %s, golden has %s", i, g.Quantity, w.Quantity)
+		}
+	}
+	return diffs
+}
+
+func within(got, want, tolerance decimal.Decimal) bool {
+	return got.Sub(want).Abs().LessThanOrEqual(tolerance)
+}
+
+// outcome is what the golden file records of one run.
+type outcome struct {
+	Name       string          `json:"name"`
+	RoundTrips int             `json:"round_trips"`
+	Wins       int             `json:"wins"`
+	PnL        decimal.Decimal `json:"pnl"`
+	// MaxDrawdown is the largest fall of the equity curve from a previous
+	// high, the flat start included
+	MaxDrawdown decimal.Decimal `json:"max_drawdown"`
+	Fills       []backtest.Fill `json:"fills"`
+}
@@ -0,0 +1,220 @@
+//go:build properties
+
+package properties
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/backtest"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/marketdata"
+	"github.com/tradingbothub/platform/internal/strategy"
+)
+
+// backtestConfigs are run over every candle sequence, with slippage so
+// fill prices differ from the signals'.
+var backtestConfigs = []strategy.Config{
+	{Type: strategy.TypeSMACross, Interval: "1m", Quantity: decimal.RequireFromString("0.5"), FastPeriod: 3, SlowPeriod: 8},
+	{Type: strategy.TypeRSI, Interval: "1m", Quantity: decimal.NewFromInt(2), RSIPeriod: 5, Oversold: 35, Overbought: 65},
+}
+
+var backtestFills = backtest.FillModel{SlippageBps: 25}
+
+// candleStep is one candle's close; candles are a minute apart in the
+// order of the steps.
+type candleStep struct {
+	Close float64
+}
+
+func (s candleStep) String() string {
+	return fmt.Sprintf("close %.2f", s.Close)
+}
+
+// candleSteps is a random walk, choppy enough for both strategies to trade.
+func candleSteps(rng *rand.Rand) []candleStep {
+	price := 100 + rng.Float64()*900
+	steps := make([]candleStep, 40+rng.Intn(160))
+	for i := range steps {
+		price = math.Max(1, price*(0.97+rng.Float64()*0.06))
+		steps[i] = candleStep{Close: math.Round(price*100) / 100}
+	}
+	return steps
+}
+
+var candlesStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func candles(steps []candleStep) []marketdata.Candle {
+	candles := make([]marketdata.Candle, len(steps))
+	for i, step := range steps {
+		candles[i] = marketdata.Candle{
+			Time:  candlesStart.Add(time.Duration(i) * time.Minute),
+			Open:  step.Close,
+			High:  step.Close,
+			Low:   step.Close,
+			Close: step.Close,
+		}
+	}
+	return candles
+}
+
+// recorder keeps what a backtest reports while it runs.
+type recorder struct {
+	fills  []backtest.Fill
+	equity []backtest.EquityPoint
+}
+
+func (r *recorder) Fill(fill backtest.Fill) error {
+	r.fills = append(r.fills, fill)
+	return nil
+}
+
+func (r *recorder) Equity(point backtest.EquityPoint) error {
+	r.equity = append(r.equity, point)
+	return nil
+}
+
+func (r *recorder) Progress(done, total int) error {
+	return nil
+}
+
+// simulate runs every config over the candles with seed and passes each
+// result to check.
+func simulate(steps []candleStep, seed int64, check func(cfg strategy.Config, result *backtest.Result, rec *recorder) error) error {
+	for _, cfg := range backtestConfigs {
+		rec := &recorder{}
+		result, err := backtest.Simulate(cfg, candles(steps), candlesStart, backtestFills, seed, rec)
+		if err != nil {
+			return err
+		}
+		if err := check(cfg, result, rec); err != nil {
+			return fmt.Errorf("%s: %w", cfg.Type, err)
+		}
+	}
+	return nil
+}
+
+// checkBacktestPnL checks that a run's PnL is the cash flow of its fills
+// plus the open position at the last close, and that the equity curve
+// ends there.
+func checkBacktestPnL(steps []candleStep) error {
+	return simulate(steps, 1, func(cfg strategy.Config, result *backtest.Result, rec *recorder) error {
+		want := decimal.Zero
+		held := decimal.Zero
+		for _, fill := range result.Fills {
+			flow := fill.FillPrice.Mul(fill.Quantity)
+			if fill.Side == exchange.SideBuy {
+				want = want.Sub(flow)
+				held = held.Add(fill.Quantity)
+			} else {
+				want = want.Add(flow)
+				held = held.Sub(fill.Quantity)
+			}
+		}
+		if len(steps) > 0 {
+			want = want.Add(held.Mul(decimal.NewFromFloat(steps[len(steps)-1].Close)))
+		}
+		if !result.PnL.Equal(want) {
+			return fmt.Errorf("PnL is %s, but the fills and open position add up to %s", result.PnL, want)
+		}
+
+		if len(rec.equity) != len(steps) {
+			return fmt.Errorf("%d equity points for %d candles", len(rec.equity), len(steps))
+		}
+		if len(rec.equity) > 0 && !rec.equity[len(rec.equity)-1].Equity.Equal(result.PnL) {
+			return fmt.Errorf("equity curve ends at %s, but PnL is %s", rec.equity[len(rec.equity)-1].Equity, result.PnL)
+		}
+		return nil
+	})
+}
+
+// checkBacktestFills checks that the long-only bot alternates buys and
+// sells of the configured quantity, that round trips and wins are counted
+// from them, and that slippage is adverse and within the fill model.
+func checkBacktestFills(steps []candleStep) error {
+	bps := decimal.NewFromFloat(backtestFills.SlippageBps).Div(decimal.NewFromInt(10000))
+	return simulate(steps, 1, func(cfg strategy.Config, result *backtest.Result, rec *recorder) error {
+		if !sameFills(result.Fills, rec.fills) {
+			return fmt.Errorf("result has %d fills, the observer saw %d", len(result.Fills), len(rec.fills))
+		}
+
+		sells, wins := 0, 0
+		var entry decimal.Decimal
+		for i, fill := range result.Fills {
+			want := exchange.SideBuy
+			if i%2 == 1 {
+				want = exchange.SideSell
+			}
+			if fill.Side != want {
+				return fmt.Errorf("fill %d is a %s, want a %s", i, fill.Side, want)
+			}
+			if !fill.Quantity.Equal(cfg.Quantity) {
+				return fmt.Errorf("fill %d is for %s, want %s", i, fill.Quantity, cfg.Quantity)
+			}
+
+			slippage := fill.FillPrice.Sub(fill.Price)
+			if fill.Side == exchange.SideSell {
+				slippage = slippage.Neg()
+			}
+			if slippage.IsNegative() || slippage.GreaterThan(fill.Price.Mul(bps).Add(tolerance)) {
+				return fmt.Errorf("fill %d slipped %s from %s", i, slippage, fill.Price)
+			}
+
+			if fill.Side == exchange.SideBuy {
+				entry = fill.FillPrice
+				continue
+			}
+			sells++
+			if fill.FillPrice.GreaterThan(entry) {
+				wins++
+			}
+		}
+		if result.RoundTrips != sells || result.Wins != wins {
+			return fmt.Errorf("%d round trips with %d wins, but the fills have %d and %d",
+				result.RoundTrips, result.Wins, sells, wins)
+		}
+		return nil
+	})
+}
+
+// checkBacktestSeed checks that a seed reproduces its run exactly, and
+// that running without an observer changes nothing.
+func checkBacktestSeed(steps []candleStep) error {
+	for seed := int64(1); seed <= 3; seed++ {
+		err := simulate(steps, seed, func(cfg strategy.Config, result *backtest.Result, rec *recorder) error {
+			again, err := backtest.Run(cfg, candles(steps), candlesStart, backtestFills, seed)
+			if err != nil {
+				return err
+			}
+			same := result.PnL.Equal(again.PnL) && result.RoundTrips == again.RoundTrips &&
+				result.Wins == again.Wins && sameFills(result.Fills, again.Fills)
+			if !same {
+				return fmt.Errorf("seed %d gave PnL %s with %d fills, then %s with %d",
+					seed, result.PnL, len(result.Fills), again.PnL, len(again.Fills))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameFills compares fills by value; decimals that are equal need not be
+// identical.
+func sameFills(a, b []backtest.Fill) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Time.Equal(b[i].Time) || a[i].Side != b[i].Side || a[i].Type != b[i].Type ||
+			!a[i].Price.Equal(b[i].Price) || !a[i].Quantity.Equal(b[i].Quantity) || !a[i].FillPrice.Equal(b[i].FillPrice) {
+			return false
+		}
+	}
+	return true
+}
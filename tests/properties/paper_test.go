@@ -0,0 +1,328 @@
+//go:build properties
+
+package properties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/exchange"
+)
+
+const paperUser = "user-1"
+
+var (
+	paperSymbols = []string{"BTCUSDT", "ETHUSDT"}
+	paperDeposit = decimal.NewFromInt(100000)
+	// tolerance covers the rounding of averaged entry prices
+	tolerance = decimal.New(1, -8)
+)
+
+// paperStep either moves a symbol's price, filling the resting limit orders
+// it crosses, or places an order.
+type paperStep struct {
+	Symbol string
+	// Mark is set when the step only moves the price
+	Mark       decimal.Decimal
+	Side       exchange.Side
+	Type       exchange.OrderType
+	Price      decimal.Decimal
+	Quantity   decimal.Decimal
+	ReduceOnly bool
+	// Fee is charged on the trade if the order fills
+	Fee decimal.Decimal
+}
+
+func (s paperStep) String() string {
+	if s.Side == "" {
+		return fmt.Sprintf("price %s %s", s.Symbol, s.Mark)
+	}
+	desc := fmt.Sprintf("%s %s %s %s", s.Type, s.Side, s.Quantity, s.Symbol)
+	if s.Type == exchange.OrderTypeLimit {
+		desc += " @ " + s.Price.String()
+	}
+	if s.ReduceOnly {
+		desc += " reduce-only"
+	}
+	return desc + " fee " + s.Fee.String()
+}
+
+// paperSteps opens with a price for every symbol, so market orders fill,
+// and then mixes price moves with orders.
+func paperSteps(rng *rand.Rand) []paperStep {
+	marks := make(map[string]decimal.Decimal, len(paperSymbols))
+	var steps []paperStep
+	for _, symbol := range paperSymbols {
+		marks[symbol] = randomDecimal(rng, 1000, 100000, 2)
+		steps = append(steps, paperStep{Symbol: symbol, Mark: marks[symbol]})
+	}
+
+	for n := 5 + rng.Intn(60); n > 0; n-- {
+		symbol := paperSymbols[rng.Intn(len(paperSymbols))]
+		if rng.Intn(3) == 0 {
+			// Prices walk up to 5% at a time, so resting orders get crossed
+			move := decimal.NewFromFloat(0.95 + rng.Float64()*0.1)
+			marks[symbol] = marks[symbol].Mul(move).Round(2)
+			steps = append(steps, paperStep{Symbol: symbol, Mark: marks[symbol]})
+			continue
+		}
+
+		step := paperStep{
+			Symbol:     symbol,
+			Side:       exchange.SideBuy,
+			Type:       exchange.OrderTypeMarket,
+			Quantity:   randomDecimal(rng, 1, 5000, 3),
+			ReduceOnly: rng.Intn(5) == 0,
+			Fee:        randomDecimal(rng, 0, 500, 4),
+		}
+		if rng.Intn(2) == 0 {
+			step.Side = exchange.SideSell
+		}
+		if rng.Intn(3) == 0 {
+			step.Type = exchange.OrderTypeLimit
+			step.Price = marks[symbol].Mul(decimal.NewFromFloat(0.97 + rng.Float64()*0.06)).Round(2)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// randomDecimal draws a decimal in [min, max] hundredths, thousandths, ...
+// depending on places.
+func randomDecimal(rng *rand.Rand, min, max int64, places int32) decimal.Decimal {
+	return decimal.New(min+rng.Int63n(max-min+1), -places)
+}
+
+// paperFill is a trade the paper exchange reported.
+type paperFill struct {
+	Symbol   string
+	Side     exchange.Side
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+	Fee      decimal.Decimal
+}
+
+// signed is the fill's quantity, negative for sales.
+func (f paperFill) signed() decimal.Decimal {
+	if f.Side == exchange.SideSell {
+		return f.Quantity.Neg()
+	}
+	return f.Quantity
+}
+
+// paperRun replays steps against a paper exchange and keeps a model of
+// what its fills add up to, independent of how the exchange nets them.
+type paperRun struct {
+	ctx    context.Context
+	client *exchange.PaperClient
+	// open holds the fees of resting orders by order ID
+	open  map[string]decimal.Decimal
+	marks map[string]decimal.Decimal
+	fills []paperFill
+
+	// step is the one just replayed and before the net positions ahead of
+	// it
+	step   paperStep
+	before map[string]decimal.Decimal
+}
+
+// checkPaper replays the steps and checks invariant after each of them.
+func checkPaper(invariant func(run *paperRun) error) func(steps []paperStep) error {
+	return func(steps []paperStep) error {
+		_, err := replayPaper(steps, invariant)
+		return err
+	}
+}
+
+func replayPaper(steps []paperStep, invariant func(run *paperRun) error) (*paperRun, error) {
+	run := &paperRun{
+		ctx:    context.Background(),
+		client: exchange.NewPaperClient("paper"),
+		open:   make(map[string]decimal.Decimal),
+		marks:  make(map[string]decimal.Decimal),
+	}
+	run.client.Deposit(run.ctx, paperUser, paperDeposit)
+
+	for i, step := range steps {
+		run.step = step
+		run.before = run.net()
+		if err := run.apply(step); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i, err)
+		}
+		if err := invariant(run); err != nil {
+			return nil, fmt.Errorf("after step %d: %w", i, err)
+		}
+	}
+	return run, nil
+}
+
+func (r *paperRun) apply(step paperStep) error {
+	if step.Side == "" {
+		r.client.SetPrice(step.Symbol, step.Mark)
+		r.marks[step.Symbol] = step.Mark
+		return r.collectFills()
+	}
+
+	order, err := r.client.PlaceOrder(r.ctx, paperUser, exchange.OrderRequest{
+		Symbol:     step.Symbol,
+		Side:       step.Side,
+		Type:       step.Type,
+		Price:      step.Price,
+		Quantity:   step.Quantity,
+		ReduceOnly: step.ReduceOnly,
+	})
+	if errors.Is(err, exchange.ErrNoPrice) {
+		// Shrinking can drop the step that priced the symbol
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch order.Status {
+	case exchange.OrderStatusFilled:
+		r.record(order, step.Fee)
+	case exchange.OrderStatusOpen:
+		r.open[order.ID] = step.Fee
+	}
+	return nil
+}
+
+// collectFills records the resting orders a price move filled.
+func (r *paperRun) collectFills() error {
+	ids := make([]string, 0, len(r.open))
+	for id := range r.open {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		order, err := r.client.Order(r.ctx, paperUser, id)
+		if err != nil {
+			return err
+		}
+		if order.Status == exchange.OrderStatusFilled {
+			r.record(order, r.open[id])
+			delete(r.open, id)
+		}
+	}
+	return nil
+}
+
+func (r *paperRun) record(order *exchange.Order, fee decimal.Decimal) {
+	r.fills = append(r.fills, paperFill{
+		Symbol:   order.Symbol,
+		Side:     order.Side,
+		Price:    order.Price,
+		Quantity: order.Filled,
+		Fee:      fee,
+	})
+}
+
+// net is the signed quantity the fills so far add up to, by symbol.
+func (r *paperRun) net() map[string]decimal.Decimal {
+	net := make(map[string]decimal.Decimal)
+	for _, fill := range r.fills {
+		net[fill.Symbol] = net[fill.Symbol].Add(fill.signed())
+	}
+	return net
+}
+
+// cashFlow is what the fills so far paid and received, fees aside.
+func (r *paperRun) cashFlow() decimal.Decimal {
+	cash := decimal.Zero
+	for _, fill := range r.fills {
+		cash = cash.Sub(fill.signed().Mul(fill.Price))
+	}
+	return cash
+}
+
+// pnl is the fills' cash flow plus the net positions marked at the last
+// prices: the result of trading whichever way it is split into realized
+// and unrealized.
+func (r *paperRun) pnl() decimal.Decimal {
+	pnl := r.cashFlow()
+	for symbol, quantity := range r.net() {
+		pnl = pnl.Add(quantity.Mul(r.marks[symbol]))
+	}
+	return pnl
+}
+
+// paperPnL checks that realized PnL, which the exchange books to the
+// balance, plus unrealized PnL on open positions equals the trades' result.
+func paperPnL(run *paperRun) error {
+	account, err := run.client.Account(run.ctx, paperUser)
+	if err != nil {
+		return err
+	}
+	realized := account.Balance.Sub(paperDeposit)
+	if got, want := realized.Add(account.UnrealizedPnL), run.pnl(); got.Sub(want).Abs().GreaterThan(tolerance) {
+		return fmt.Errorf("realized %s + unrealized %s = %s, but the fills add up to %s",
+			realized, account.UnrealizedPnL, got, want)
+	}
+	if !account.Equity.Equal(account.Balance.Add(account.UnrealizedPnL)) {
+		return fmt.Errorf("equity %s is not balance %s + unrealized %s", account.Equity, account.Balance, account.UnrealizedPnL)
+	}
+	return nil
+}
+
+// paperLots checks that positions hold exactly the quantity filled, with
+// no negative or empty positions and no order filled beyond its quantity.
+func paperLots(run *paperRun) error {
+	positions, err := run.client.Positions(run.ctx, paperUser, "")
+	if err != nil {
+		return err
+	}
+	held := make(map[string]decimal.Decimal)
+	for _, position := range positions {
+		if !position.Quantity.IsPositive() {
+			return fmt.Errorf("%s position has quantity %s", position.Symbol, position.Quantity)
+		}
+		if !position.EntryPrice.IsPositive() {
+			return fmt.Errorf("%s position has entry price %s", position.Symbol, position.EntryPrice)
+		}
+		if _, ok := held[position.Symbol]; ok {
+			return fmt.Errorf("%s has more than one position", position.Symbol)
+		}
+		held[position.Symbol] = position.Quantity
+		if position.Side == exchange.SideSell {
+			held[position.Symbol] = position.Quantity.Neg()
+		}
+	}
+	for symbol, quantity := range run.net() {
+		if !held[symbol].Equal(quantity) {
+			return fmt.Errorf("%s position is %s, but the fills net to %s", symbol, held[symbol], quantity)
+		}
+		delete(held, symbol)
+	}
+	for symbol, quantity := range held {
+		return fmt.Errorf("%s position is %s without any fills", symbol, quantity)
+	}
+
+	for _, fill := range run.fills {
+		if !fill.Quantity.IsPositive() {
+			return fmt.Errorf("%s %s filled %s", fill.Side, fill.Symbol, fill.Quantity)
+		}
+	}
+	return nil
+}
+
+// paperReduceOnly checks that reduce-only orders filled on placement never
+// grow or flip a position. Resting ones are capped when placed, not when
+// the price crosses them, so they are left out.
+func paperReduceOnly(run *paperRun) error {
+	step := run.step
+	if !step.ReduceOnly {
+		return nil
+	}
+	before, after := run.before[step.Symbol], run.net()[step.Symbol]
+	if after.Abs().GreaterThan(before.Abs()) {
+		return fmt.Errorf("reduce-only %s grew %s from %s to %s", step.Side, step.Symbol, before, after)
+	}
+	if before.Sign()*after.Sign() < 0 {
+		return fmt.Errorf("reduce-only %s flipped %s from %s to %s", step.Side, step.Symbol, before, after)
+	}
+	return nil
+}
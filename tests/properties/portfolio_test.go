@@ -0,0 +1,121 @@
+//go:build properties
+
+package properties
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/tradingbothub/platform/internal/database"
+	"github.com/tradingbothub/platform/internal/exchange"
+	"github.com/tradingbothub/platform/internal/orders"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPortfolio(t *testing.T) {
+	if *databaseURL == "" {
+		t.Skip("no -database to record trades in")
+	}
+	db, err := database.Connect(*databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every run writes its trades; logging each statement drowns failures
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+
+	check(t, "pnl", sequence(paperSteps, portfolioPnL(db)))
+}
+
+// portfolioPnL records the paper exchange's fills as the trades of a fresh
+// user, has the valuation job mark the holdings the repository built from
+// them at the exchange's last prices, and checks that the portfolio read
+// back reports the exchange's realized and unrealized PnL, less fees, and
+// that its totals add up.
+func portfolioPnL(db *gorm.DB) func(steps []paperStep) error {
+	return func(steps []paperStep) error {
+		run, err := replayPaper(steps, func(*paperRun) error { return nil })
+		if err != nil {
+			return err
+		}
+		account, err := run.client.Account(run.ctx, paperUser)
+		if err != nil {
+			return err
+		}
+
+		userID := uuid.New().String()
+		executedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		trades := make([]orders.Trade, len(run.fills))
+		fees := decimal.Zero
+		for i, fill := range run.fills {
+			trades[i] = orders.Trade{
+				ID:         uuid.New().String(),
+				OrderID:    uuid.New().String(),
+				UserID:     userID,
+				Exchange:   "paper",
+				Symbol:     fill.Symbol,
+				Side:       string(fill.Side),
+				Price:      fill.Price,
+				Quantity:   fill.Quantity,
+				Fee:        fill.Fee,
+				ExecutedAt: executedAt.Add(time.Duration(i) * time.Second),
+			}
+			fees = fees.Add(fill.Fee)
+		}
+		repo := orders.NewRepository(db)
+		if err := repo.CreateTrades(run.ctx, trades); err != nil {
+			return err
+		}
+
+		exchanges := exchange.NewRegistry()
+		exchanges.Register("paper", run.client)
+		if err := orders.NewHoldingsValuer([]*gorm.DB{db}, exchanges).Run(run.ctx); err != nil {
+			return err
+		}
+		list, err := repo.ListHoldings(run.ctx, userID)
+		if err != nil {
+			return err
+		}
+		portfolio := orders.NewPortfolio(list)
+
+		want := account.Balance.Sub(paperDeposit).Add(account.UnrealizedPnL).Sub(fees)
+		if portfolio.PnL.Sub(want).Abs().GreaterThan(tolerance) {
+			return fmt.Errorf("portfolio PnL is %s, but the exchange's PnL less %s fees is %s", portfolio.PnL, fees, want)
+		}
+		if !portfolio.PnL.Equal(portfolio.Cash.Add(portfolio.Value)) {
+			return fmt.Errorf("PnL %s is not cash %s + value %s", portfolio.PnL, portfolio.Cash, portfolio.Value)
+		}
+
+		count := int64(0)
+		net := run.net()
+		var oldest *time.Time
+		for _, h := range list {
+			count += h.Trades
+			if !h.Quantity.Equal(net[h.Symbol]) {
+				return fmt.Errorf("%s holding is %s, but the fills net to %s", h.Symbol, h.Quantity, net[h.Symbol])
+			}
+			if h.Quantity.IsZero() {
+				if !h.Value().IsZero() {
+					return fmt.Errorf("flat %s holding is valued at %s", h.Symbol, h.Value())
+				}
+				continue
+			}
+			if h.ValuedAt == nil {
+				return fmt.Errorf("open %s holding was not valued", h.Symbol)
+			}
+			if oldest == nil || h.ValuedAt.Before(*oldest) {
+				oldest = h.ValuedAt
+			}
+		}
+		if count != int64(len(run.fills)) {
+			return fmt.Errorf("holdings count %d trades for %d fills", count, len(run.fills))
+		}
+		if (oldest == nil) != (portfolio.ValuedAt == nil) || (oldest != nil && !oldest.Equal(*portfolio.ValuedAt)) {
+			return fmt.Errorf("portfolio is valued at %v, but its oldest open holding at %v", portfolio.ValuedAt, oldest)
+		}
+		return nil
+	}
+}